@@ -0,0 +1,91 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"fi-cli/internal/config"
+	"fi-cli/internal/tools"
+)
+
+// askUserEnabled reports whether a run may ask clarifying questions: only a
+// person at a terminal can answer, so JSON output and piped stdin rule it out.
+func askUserEnabled(cfg config.Config) bool {
+	return cfg.Tools.Allows("ask_user") && !cfg.JSON && cfg.JSONStream == "" && isTerminal(os.Stdin) && isTerminal(os.Stderr)
+}
+
+func isTerminal(file *os.File) bool {
+	info, err := file.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// terminalAsk prompts on out and reads one line from in. Stdout stays
+// reserved for the answer.
+func terminalAsk(in *bufio.Reader, out io.Writer) tools.AskFunc {
+	return func(ctx context.Context, question string, options []string) (string, error) {
+		fmt.Fprintf(out, "\n? %s\n", question)
+		if len(options) > 0 {
+			fmt.Fprintf(out, "  (%s)\n", strings.Join(options, " / "))
+		}
+		fmt.Fprint(out, "> ")
+		return readLine(ctx, in, out)
+	}
+}
+
+// readLine reads one trimmed line from in, or gives up when ctx ends. The
+// read it gives up on stays pending, so in must not be read again after that.
+// End of input reads as an empty line.
+func readLine(ctx context.Context, in *bufio.Reader, out io.Writer) (string, error) {
+	line, err := readInputLine(ctx, in, out)
+	if err == io.EOF {
+		return "", nil
+	}
+	return line, err
+}
+
+// readInputLine is readLine that reports end of input as io.EOF.
+func readInputLine(ctx context.Context, in *bufio.Reader, out io.Writer) (string, error) {
+	lines := make(chan string, 1)
+	errs := make(chan error, 1)
+	go func() {
+		line, err := in.ReadString('\n')
+		if err != nil && line == "" {
+			errs <- err
+			return
+		}
+		lines <- line
+	}()
+	select {
+	case <-ctx.Done():
+		fmt.Fprintln(out)
+		return "", ctx.Err()
+	case err := <-errs:
+		return "", err
+	case line := <-lines:
+		return strings.TrimSpace(line), nil
+	}
+}
+
+// followUpEnabled reports whether to offer a follow-up after an answer: only
+// when follow_up is set and the answer went to a person at a terminal.
+func followUpEnabled(cfg config.Config) bool {
+	return cfg.FollowUp > 0 && !cfg.JSON && cfg.JSONStream == "" && isTerminal(os.Stdin) && isTerminal(os.Stdout)
+}
+
+// awaitFollowUp waits up to wait for a follow-up question typed on in. An
+// empty line, end of input, or the wait running out returns "".
+func awaitFollowUp(ctx context.Context, in *bufio.Reader, out io.Writer, wait time.Duration) string {
+	fmt.Fprintf(out, "follow-up (Enter to finish, %s): ", wait)
+	ctx, cancel := context.WithTimeout(ctx, wait)
+	defer cancel()
+	line, err := readLine(ctx, in, out)
+	if err != nil {
+		return ""
+	}
+	return line
+}
@@ -0,0 +1,30 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"io"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestAwaitFollowUp(t *testing.T) {
+	var out bytes.Buffer
+	in := bufio.NewReader(strings.NewReader("  and in docker?\n\n"))
+	if got := awaitFollowUp(context.Background(), in, &out, time.Second); got != "and in docker?" {
+		t.Fatalf("expected the typed follow-up, got %q", got)
+	}
+	if !strings.HasPrefix(out.String(), "follow-up (Enter to finish, 1s): ") {
+		t.Fatalf("unexpected prompt %q", out.String())
+	}
+	if got := awaitFollowUp(context.Background(), in, &out, time.Second); got != "" {
+		t.Fatalf("expected an empty line to finish, got %q", got)
+	}
+
+	pending, _ := io.Pipe()
+	if got := awaitFollowUp(context.Background(), bufio.NewReader(pending), &out, 20*time.Millisecond); got != "" {
+		t.Fatalf("expected the wait to run out, got %q", got)
+	}
+}
@@ -0,0 +1,149 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+
+	"fi-cli/internal/cache"
+	"fi-cli/internal/repo"
+
+	"github.com/spf13/cobra"
+)
+
+// cacheStatus is what `fi cache status --json` prints.
+type cacheStatus struct {
+	Repo        string           `json:"repo"`
+	Fingerprint repo.Fingerprint `json:"fingerprint"`
+	Dir         string           `json:"dir"`
+	Entries     int              `json:"entries"`
+	RepoEntries int              `json:"repo_entries"`
+	Stale       int              `json:"stale"`
+}
+
+func newCacheCmd() *cobra.Command {
+	var repoPath string
+	cmd := &cobra.Command{
+		Use:   "cache",
+		Short: "Inspect and clear cached answers",
+	}
+
+	var asJSON bool
+	status := &cobra.Command{
+		Use:   "status",
+		Short: "Show the repo fingerprint and how many cached answers are still current",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			repoRoot, err := repo.FindRoot(repoPath)
+			if err != nil {
+				return err
+			}
+			repoRoot, _ = filepath.Abs(repoRoot)
+			dir, err := cache.Dir()
+			if err != nil {
+				return err
+			}
+			entries, err := cache.List()
+			if err != nil {
+				return err
+			}
+			fingerprintOf := memoFingerprints()
+			st := cacheStatus{Repo: repoRoot, Fingerprint: fingerprintOf(repoRoot), Dir: dir, Entries: len(entries)}
+			for _, entry := range entries {
+				if entry.Repo == repoRoot {
+					st.RepoEntries++
+				}
+				if entry.Stale(fingerprintOf) != nil {
+					st.Stale++
+				}
+			}
+			if asJSON {
+				enc := json.NewEncoder(cmd.OutOrStdout())
+				enc.SetIndent("", "  ")
+				return enc.Encode(st)
+			}
+			printCacheStatus(cmd.OutOrStdout(), st)
+			return nil
+		},
+	}
+	status.Flags().BoolVar(&asJSON, "json", false, "Print the status as JSON")
+	cmd.AddCommand(status)
+
+	var staleOnly bool
+	clear := &cobra.Command{
+		Use:   "clear",
+		Short: "Remove cached answers",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			entries, err := cache.List()
+			if err != nil {
+				return err
+			}
+			fingerprintOf := memoFingerprints()
+			removed := 0
+			for _, entry := range entries {
+				if staleOnly && entry.Stale(fingerprintOf) == nil {
+					continue
+				}
+				if err := cache.Remove(entry.Key); err != nil {
+					return err
+				}
+				removed++
+			}
+			fmt.Fprintf(cmd.OutOrStdout(), "removed %d of %d cached answers\n", removed, len(entries))
+			return nil
+		},
+	}
+	clear.Flags().BoolVar(&staleOnly, "stale", false, "Only remove answers whose repo changed since they were cached")
+	cmd.AddCommand(clear)
+
+	cmd.PersistentFlags().StringVar(&repoPath, "repo", ".", "Repository path")
+	return cmd
+}
+
+// memoFingerprints fingerprints each repo once, however many entries it has.
+func memoFingerprints() func(string) repo.Fingerprint {
+	seen := map[string]repo.Fingerprint{}
+	return func(repoRoot string) repo.Fingerprint {
+		fp, ok := seen[repoRoot]
+		if !ok {
+			fp = repo.FingerprintOf(repoRoot)
+			seen[repoRoot] = fp
+		}
+		return fp
+	}
+}
+
+func printCacheStatus(w io.Writer, st cacheStatus) {
+	fmt.Fprintln(w, "repo "+st.Repo)
+	fp := st.Fingerprint
+	if fp.Head == "" {
+		fmt.Fprintln(w, "  not a git checkout; answers are not cached")
+	} else {
+		state := "clean"
+		if fp.Dirty {
+			state = "uncommitted changes " + fp.Changes
+		}
+		fmt.Fprintf(w, "  HEAD %s, %s\n", shortHash(fp.Head), state)
+	}
+	if len(fp.Files) > 0 {
+		names := make([]string, 0, len(fp.Files))
+		for _, name := range repo.KeyFiles {
+			if _, ok := fp.Files[name]; ok {
+				names = append(names, name)
+			}
+		}
+		fmt.Fprintf(w, "  key files: %s\n", strings.Join(names, ", "))
+	}
+	fmt.Fprintf(w, "answers %s\n", st.Dir)
+	fmt.Fprintf(w, "  %d cached, %d for this repo, %d stale (fi cache clear --stale removes them)\n", st.Entries, st.RepoEntries, st.Stale)
+}
+
+func shortHash(hash string) string {
+	if len(hash) > 12 {
+		return hash[:12]
+	}
+	return hash
+}
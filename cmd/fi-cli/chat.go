@@ -0,0 +1,185 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"syscall"
+
+	"fi-cli/internal/agent"
+	"fi-cli/internal/tools"
+
+	"github.com/spf13/cobra"
+)
+
+// chatEvidenceExchanges is how many of the latest exchanges keep their tool
+// results in a chat; older ones are replayed as question and answer only.
+const chatEvidenceExchanges = 3
+
+const chatHelp = `/reset     start a new conversation (the repository context is kept)
+/history   list the questions asked in this session
+!!         ask the previous question again
+!<n>       ask question n from /history again
+/exit      leave (also /quit or Ctrl-D)
+Ctrl-C stops the answer in progress.`
+
+func newChatCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "chat",
+		Short: "Ask questions in one session that keeps the conversation and repository context",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cfg, err := loadRunConfig(cmd)
+			if err != nil {
+				return err
+			}
+			if cfg.JSON || cfg.JSONStream != "" {
+				return errors.New("fi-cli chat is interactive; --json and --json-stream are not supported")
+			}
+			stopDiagnostics, err := startDiagnostics(cfg.Pprof, cfg.TraceOut)
+			if err != nil {
+				return err
+			}
+			defer stopDiagnostics()
+			apiKey := requireAPIKey(cfg)
+			logger := buildLogger(cfg.Verbose)
+			defer func() { _ = logger.Sync() }()
+
+			// the repository context is built once and shared by every question
+			env := prepareRun(cfg, apiKey, logger)
+			stdin := bufio.NewReader(os.Stdin)
+			if askUserEnabled(env.cfg) {
+				env.registry = env.registry.With(tools.NewAskUserTool(terminalAsk(stdin, os.Stderr)))
+			}
+			release, err := acquireRunLock(env.cfg, env.repoRoot)
+			if err != nil {
+				return err
+			}
+			defer release()
+
+			ctx, cancel := signal.NotifyContext(context.Background(), syscall.SIGTERM)
+			defer cancel()
+
+			interactive := isTerminal(os.Stdin)
+			if interactive {
+				fmt.Fprintf(os.Stderr, "fi chat in %s (/help for commands)\n", env.repoRoot)
+			}
+			var asked []string
+			for {
+				line, err := promptChat(ctx, stdin, interactive)
+				if err != nil {
+					// end of input, Ctrl-C at the prompt, or SIGTERM
+					return nil
+				}
+				command, question, err := chatInput(line, asked)
+				switch {
+				case err != nil:
+					fmt.Fprintln(os.Stderr, err)
+					continue
+				case command == "/exit":
+					return nil
+				case command == "/reset":
+					env.followUps = nil
+					fmt.Fprintln(os.Stderr, "conversation reset")
+					continue
+				case command == "/history":
+					for i, earlier := range asked {
+						fmt.Fprintf(os.Stderr, "%3d  %s\n", i+1, earlier)
+					}
+					continue
+				case command == "/help":
+					fmt.Fprintln(os.Stderr, chatHelp)
+					continue
+				case question == "":
+					continue
+				}
+				if question != line {
+					fmt.Fprintf(os.Stderr, "re-asking: %s\n", question)
+				}
+				asked = append(asked, question)
+
+				runCtx, stopRun := signal.NotifyContext(ctx, os.Interrupt)
+				result, err := executeRun(runCtx, logger, env, question)
+				interrupted := runCtx.Err() != nil && ctx.Err() == nil
+				stopRun()
+				// setup warnings are reported with the first answer only
+				env.warnings = nil
+				recordQuestion(logger, env.cfg, env.repoRoot, question, result, err)
+				switch {
+				case interrupted:
+					fmt.Fprintln(os.Stderr, "answer stopped")
+				case err != nil:
+					fmt.Fprintln(os.Stderr, err)
+				default:
+					env.followUps = appendChatExchange(env.followUps, result)
+				}
+				if ctx.Err() != nil {
+					return nil
+				}
+			}
+		},
+	}
+	addRunFlags(cmd)
+	return cmd
+}
+
+// promptChat reads the next line of a chat. Ctrl-C while waiting ends the
+// session like end of input does.
+func promptChat(ctx context.Context, in *bufio.Reader, interactive bool) (string, error) {
+	ctx, stop := signal.NotifyContext(ctx, os.Interrupt)
+	defer stop()
+	out := io.Discard
+	if interactive {
+		out = os.Stderr
+		fmt.Fprint(out, "\nfi> ")
+	}
+	return readInputLine(ctx, in, out)
+}
+
+// chatInput resolves a line typed in a chat to a command (/exit, /reset,
+// /history, or /help) or to the question it asks; !! and !<n> recall
+// questions from asked.
+func chatInput(line string, asked []string) (command, question string, err error) {
+	switch {
+	case line == "/exit" || line == "/quit":
+		return "/exit", "", nil
+	case line == "/reset" || line == "/history" || line == "/help":
+		return line, "", nil
+	case strings.HasPrefix(line, "/"):
+		return "", "", fmt.Errorf("unknown command %q (try /help)", strings.Fields(line)[0])
+	case line == reAskArg:
+		if len(asked) == 0 {
+			return "", "", errors.New("no earlier question in this session")
+		}
+		return "", asked[len(asked)-1], nil
+	case strings.HasPrefix(line, "!"):
+		n, err := strconv.Atoi(line[1:])
+		if err != nil {
+			// a question that merely starts with "!"
+			return "", line, nil
+		}
+		if n < 1 || n > len(asked) {
+			return "", "", fmt.Errorf("no question %d in this session (see /history)", n)
+		}
+		return "", asked[n-1], nil
+	}
+	return "", line, nil
+}
+
+// appendChatExchange adds a finished run to the conversation with its tool
+// results, and drops the results of exchanges that are no longer recent.
+func appendChatExchange(exchanges []agent.Exchange, result agent.RunResult) []agent.Exchange {
+	exchange := agent.ExchangeOf(result)
+	exchange.Evidence = agent.EvidenceOf(result)
+	exchanges = append(exchanges, exchange)
+	for i := 0; i < len(exchanges)-chatEvidenceExchanges; i++ {
+		exchanges[i].Evidence = nil
+	}
+	return exchanges
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"testing"
+
+	"fi-cli/internal/agent"
+)
+
+func TestChatInput(t *testing.T) {
+	asked := []string{"how do I run the app?", "and in docker?"}
+	for line, want := range map[string][2]string{
+		"/quit":             {"/exit", ""},
+		"/reset":            {"/reset", ""},
+		"/history":          {"/history", ""},
+		"!!":                {"", "and in docker?"},
+		"!1":                {"", "how do I run the app?"},
+		"!important flags?": {"", "!important flags?"},
+		"where is main?":    {"", "where is main?"},
+	} {
+		command, question, err := chatInput(line, asked)
+		if err != nil || command != want[0] || question != want[1] {
+			t.Fatalf("%q: expected %q %q, got %q %q (%v)", line, want[0], want[1], command, question, err)
+		}
+	}
+	for _, line := range []string{"/rest", "!3", "!0"} {
+		if _, _, err := chatInput(line, asked); err == nil {
+			t.Fatalf("expected %q to be rejected", line)
+		}
+	}
+	if _, _, err := chatInput("!!", nil); err == nil {
+		t.Fatalf("expected !! without an earlier question to be rejected")
+	}
+}
+
+func TestAppendChatExchangeKeepsRecentEvidence(t *testing.T) {
+	var exchanges []agent.Exchange
+	for i := 0; i < chatEvidenceExchanges+1; i++ {
+		result := agent.RunResult{Question: "q", FinalAnswer: "a", ToolCalls: []agent.ToolCallRecord{{ID: "T1", ToolName: "grep", Output: "hit"}}}
+		exchanges = appendChatExchange(exchanges, result)
+	}
+	if exchanges[0].Evidence != nil {
+		t.Fatalf("expected the oldest exchange to drop its evidence")
+	}
+	for _, exchange := range exchanges[1:] {
+		if len(exchange.Evidence) != 1 || exchange.ToolCalls != 1 {
+			t.Fatalf("expected recent exchanges to keep evidence, got %+v", exchange)
+		}
+	}
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"fi-cli/internal/agent"
+
+	"github.com/spf13/cobra"
+)
+
+func newContextCmd() *cobra.Command {
+	var outPath string
+	cmd := &cobra.Command{
+		Use:   "context",
+		Short: "Print the repository and history context a run would send to the model",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cfg, err := loadRunConfig(cmd)
+			if err != nil {
+				return err
+			}
+			stopDiagnostics, err := startDiagnostics(cfg.Pprof, cfg.TraceOut)
+			if err != nil {
+				return err
+			}
+			defer stopDiagnostics()
+			logger := buildLogger(cfg.Verbose)
+			defer func() { _ = logger.Sync() }()
+
+			env := prepareRun(cfg, resolveAPIKey(cfg), logger)
+			snapshot := agent.BuildContextSnapshot(env.cfg, env.repoCtx)
+			snapshot.Warnings = append(env.warnings, snapshot.Warnings...)
+
+			if outPath != "" {
+				payload, err := json.MarshalIndent(snapshot, "", "  ")
+				if err != nil {
+					return err
+				}
+				if err := os.WriteFile(outPath, append(payload, '\n'), 0o644); err != nil {
+					return err
+				}
+				fmt.Fprintf(os.Stdout, "Wrote context snapshot to %s\n", outPath)
+				return nil
+			}
+			if cfg.JSON {
+				enc := json.NewEncoder(os.Stdout)
+				enc.SetIndent("", "  ")
+				return enc.Encode(snapshot)
+			}
+			printContextSnapshot(os.Stdout, snapshot)
+			return nil
+		},
+	}
+	addRunFlags(cmd)
+	cmd.Flags().StringVar(&outPath, "out", "", "Write the snapshot as JSON to this file")
+	return cmd
+}
+
+func printContextSnapshot(w io.Writer, snapshot agent.ContextSnapshot) {
+	bytes := 0
+	for _, message := range snapshot.Messages {
+		bytes += len(message)
+	}
+	fmt.Fprintf(w, "Context for %s (%d messages, %d bytes)\n", snapshot.RepoRoot, len(snapshot.Messages), bytes)
+	fmt.Fprintf(w, "Snippets included: %d | history lines: %d\n", len(snapshot.Snippets), len(snapshot.History))
+	if len(snapshot.Conventions) > 0 {
+		fmt.Fprintf(w, "Conventions: %s\n", strings.Join(snapshot.Conventions, ", "))
+	}
+	if len(snapshot.Glossary) > 0 {
+		fmt.Fprintf(w, "Glossary facts: %d (fi memory list)\n", len(snapshot.Glossary))
+	}
+	for _, warning := range snapshot.Warnings {
+		fmt.Fprintf(w, "warning: %s\n", warning)
+	}
+	for i, message := range snapshot.Messages {
+		fmt.Fprintf(w, "\n=== message %d ===\n%s\n", i+1, message)
+	}
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/pprof"
+	"os"
+	"runtime/trace"
+	"strings"
+	"time"
+)
+
+// startDiagnostics serves pprof on pprofAddr and writes an execution trace
+// to traceOut, each when set. The returned stop function ends both and must be called before
+// the process exits, or the trace file is left unreadable.
+func startDiagnostics(pprofAddr, traceOut string) (func(), error) {
+	var stops []func()
+	stop := func() {
+		for i := len(stops) - 1; i >= 0; i-- {
+			stops[i]()
+		}
+	}
+	if pprofAddr != "" {
+		listener, err := net.Listen("tcp", pprofListenAddr(pprofAddr))
+		if err != nil {
+			return stop, fmt.Errorf("--pprof: %w", err)
+		}
+		server := &http.Server{Handler: pprofMux(), ReadHeaderTimeout: 10 * time.Second}
+		go func() { _ = server.Serve(listener) }()
+		fmt.Fprintf(os.Stderr, "pprof: http://%s/debug/pprof/\n", listener.Addr())
+		stops = append(stops, func() { _ = server.Close() })
+	}
+	if traceOut != "" {
+		file, err := os.Create(traceOut)
+		if err != nil {
+			stop()
+			return func() {}, fmt.Errorf("--trace-out: %w", err)
+		}
+		if err := trace.Start(file); err != nil {
+			_ = file.Close()
+			stop()
+			return func() {}, errors.Join(errors.New("--trace-out: could not start the trace"), err)
+		}
+		stops = append(stops, func() {
+			trace.Stop()
+			_ = file.Close()
+		})
+	}
+	return stop, nil
+}
+
+// pprofListenAddr binds a bare ":port" to localhost: profiles expose
+// memory contents, and nothing about a local run needs them on the network.
+func pprofListenAddr(addr string) string {
+	if strings.HasPrefix(addr, ":") {
+		return "127.0.0.1" + addr
+	}
+	return addr
+}
+
+// pprofMux routes the net/http/pprof handlers under /debug/pprof/.
+func pprofMux() *http.ServeMux {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/debug/pprof/", pprof.Index)
+	mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+	mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
+	mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+	mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+	return mux
+}
@@ -0,0 +1,31 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestDiagnostics(t *testing.T) {
+	if got := pprofListenAddr(":6060"); got != "127.0.0.1:6060" {
+		t.Fatalf("expected a bare port to bind localhost, got %q", got)
+	}
+	if got := pprofListenAddr("0.0.0.0:6060"); got != "0.0.0.0:6060" {
+		t.Fatalf("expected an explicit host to be kept, got %q", got)
+	}
+
+	path := filepath.Join(t.TempDir(), "run.trace")
+	stop, err := startDiagnostics("", path)
+	if err != nil {
+		t.Fatalf("start: %v", err)
+	}
+	stop()
+	data, err := os.ReadFile(path)
+	if err != nil || !strings.HasPrefix(string(data), "go 1.") {
+		t.Fatalf("expected a runtime trace, got %q (%v)", data[:min(len(data), 16)], err)
+	}
+	if _, err := startDiagnostics("", filepath.Join(path, "missing", "run.trace")); err == nil || !strings.HasPrefix(err.Error(), "--trace-out: ") {
+		t.Fatalf("expected a --trace-out error, got %v", err)
+	}
+}
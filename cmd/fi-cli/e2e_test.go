@@ -35,3 +35,49 @@ func TestCLIJSONOutput(t *testing.T) {
 		t.Fatalf("expected final_answer")
 	}
 }
+
+func TestCLIMockScenario(t *testing.T) {
+	fixture := t.TempDir()
+	if err := os.WriteFile(filepath.Join(fixture, "Makefile"), []byte("test:\n\tgo test ./...\n"), 0o644); err != nil {
+		t.Fatalf("failed to write fixture: %v", err)
+	}
+	scenario := filepath.Join(t.TempDir(), "scenario.yaml")
+	script := `steps:
+  - tool_calls:
+      - name: list_files
+        arguments: {path: .}
+  - tool_calls:
+      - name: grep
+        arguments: {pattern: "test:"}
+  - content: "Run make test [Makefile:1][T2]"
+`
+	if err := os.WriteFile(scenario, []byte(script), 0o644); err != nil {
+		t.Fatalf("failed to write scenario: %v", err)
+	}
+
+	cmd := exec.Command("go", "run", "./cmd/fi-cli", "--json", "--repo", fixture, "how do I run the tests?")
+	cmd.Env = append(os.Environ(), "FICLI_MOCK_SCENARIO="+scenario)
+	wd, _ := os.Getwd()
+	cmd.Dir = filepath.Dir(filepath.Dir(wd))
+
+	out, err := cmd.Output()
+	if err != nil {
+		t.Fatalf("command failed: %v", err)
+	}
+	var payload struct {
+		FinalAnswer string `json:"final_answer"`
+		ToolCalls   []struct {
+			ToolName string `json:"tool_name"`
+			Status   string `json:"status"`
+		} `json:"tool_calls"`
+	}
+	if err := json.Unmarshal(out, &payload); err != nil {
+		t.Fatalf("invalid json output: %v", err)
+	}
+	if payload.FinalAnswer != "Run make test [Makefile:1][T2]" {
+		t.Fatalf("unexpected final_answer %q", payload.FinalAnswer)
+	}
+	if len(payload.ToolCalls) != 2 || payload.ToolCalls[0].ToolName != "list_files" || payload.ToolCalls[1].ToolName != "grep" {
+		t.Fatalf("unexpected tool calls: %+v", payload.ToolCalls)
+	}
+}
@@ -0,0 +1,148 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"fi-cli/internal/agent"
+	"fi-cli/internal/config"
+	"fi-cli/internal/history"
+	"fi-cli/internal/i18n"
+	"fi-cli/internal/util"
+
+	"github.com/spf13/cobra"
+	"go.uber.org/zap"
+)
+
+// reAskArg is the question that stands for the previous one, as in a shell.
+const reAskArg = "!!"
+
+// reAsk resolves `fi !! [more words]` to the previous question, followed by
+// any extra words, and says on stderr what is being asked.
+func reAsk(args []string) (string, error) {
+	last, err := history.Last()
+	if err != nil {
+		return "", err
+	}
+	question := strings.Join(append([]string{last.Question}, args[1:]...), " ")
+	fmt.Fprintln(os.Stderr, i18n.Sprintf("re-asking: %s", question))
+	return question, nil
+}
+
+// recordQuestion adds a run's question to the history unless
+// question_history is off. Failing to record never fails the run.
+func recordQuestion(logger *zap.Logger, cfg config.Config, repoRoot, question string, result agent.RunResult, runErr error) {
+	if !cfg.QuestionHistory {
+		return
+	}
+	entry := history.Entry{AskedAt: result.StartedAt, Question: question, Repo: repoRoot, RunID: result.RunID, Status: result.Status}
+	// a cached answer keeps the start time of the run that produced it
+	if entry.AskedAt.IsZero() || result.CachedFrom != "" {
+		entry.AskedAt = time.Now().UTC()
+	}
+	if entry.Status == "" && runErr != nil {
+		entry.Status = "failure"
+	}
+	if err := history.Append(entry); err != nil {
+		logger.Debug("failed to record question", zap.Error(err))
+	}
+}
+
+func newLastCmd() *cobra.Command {
+	var asJSON bool
+	cmd := &cobra.Command{
+		Use:   "last",
+		Short: "Print the last question asked, and where and how its run ended",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			last, err := history.Last()
+			if err != nil {
+				return err
+			}
+			if asJSON {
+				enc := json.NewEncoder(cmd.OutOrStdout())
+				enc.SetIndent("", "  ")
+				return enc.Encode(last)
+			}
+			fmt.Fprintln(cmd.OutOrStdout(), last.Question)
+			fmt.Fprintln(cmd.OutOrStdout(), "  "+entryDetails(last))
+			return nil
+		},
+	}
+	cmd.Flags().BoolVar(&asJSON, "json", false, "Print the entry as JSON")
+	return cmd
+}
+
+func newHistoryCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "history",
+		Short: "Search the questions asked with fi-cli",
+	}
+
+	var limit int
+	var asJSON bool
+	search := &cobra.Command{
+		Use:     "search <term>",
+		Short:   "List past questions containing every word of term, newest first",
+		Example: `  fi history search migrate`,
+		Args:    cobra.MinimumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			matches, err := history.Search(strings.Join(args, " "))
+			if err != nil {
+				return err
+			}
+			if limit > 0 && len(matches) > limit {
+				matches = matches[:limit]
+			}
+			if asJSON {
+				if matches == nil {
+					matches = []history.Entry{}
+				}
+				enc := json.NewEncoder(cmd.OutOrStdout())
+				enc.SetIndent("", "  ")
+				return enc.Encode(matches)
+			}
+			if len(matches) == 0 {
+				return errors.New("no matching questions")
+			}
+			printEntries(cmd.OutOrStdout(), matches)
+			return nil
+		},
+	}
+	search.Flags().IntVarP(&limit, "limit", "n", 20, "Show at most this many matches (0 for all)")
+	search.Flags().BoolVar(&asJSON, "json", false, "Print matches as JSON")
+
+	cmd.AddCommand(search)
+	return cmd
+}
+
+func printEntries(w io.Writer, entries []history.Entry) {
+	for _, entry := range entries {
+		question, _, _ := strings.Cut(entry.Question, "\n")
+		if len(question) > 72 {
+			question = util.CutBytes(question, 72) + util.Ellipsis
+		}
+		fmt.Fprintf(w, "%s  %-8s  %s\n", entry.AskedAt.Local().Format("2006-01-02 15:04"), entry.Status, question)
+	}
+}
+
+// entryDetails is the when, how, and where line `fi last` prints under the
+// question.
+func entryDetails(entry history.Entry) string {
+	parts := []string{entry.AskedAt.Local().Format("2006-01-02 15:04")}
+	if entry.Status != "" {
+		parts = append(parts, entry.Status)
+	}
+	if entry.RunID != "" {
+		parts = append(parts, "run "+entry.RunID)
+	}
+	if entry.Repo != "" {
+		parts = append(parts, entry.Repo)
+	}
+	return strings.Join(parts, "  ")
+}
@@ -1,22 +1,32 @@
 package main
 
 import (
+	"bufio"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"os"
 	"os/signal"
 	"path/filepath"
+	"slices"
 	"strings"
 	"syscall"
+	"time"
 
 	"fi-cli/internal/agent"
+	"fi-cli/internal/cache"
 	"fi-cli/internal/config"
+	"fi-cli/internal/events"
+	"fi-cli/internal/history"
+	"fi-cli/internal/i18n"
 	"fi-cli/internal/llm"
+	"fi-cli/internal/memory"
 	"fi-cli/internal/policy"
 	"fi-cli/internal/render"
 	"fi-cli/internal/repo"
+	"fi-cli/internal/runs"
 	"fi-cli/internal/tools"
 
 	"github.com/spf13/cobra"
@@ -24,6 +34,9 @@ import (
 )
 
 func main() {
+	for _, warning := range i18n.SetLocale(i18n.Detect(), localesDir()) {
+		fmt.Fprintln(os.Stderr, i18n.Sprintf("warning: %s", warning))
+	}
 	root := newRootCmd()
 	if err := root.Execute(); err != nil {
 		fmt.Fprintln(os.Stderr, err)
@@ -42,151 +55,432 @@ func newRootCmd() *cobra.Command {
 			if len(args) == 0 {
 				return cmd.Help()
 			}
-			question := strings.Join(args, " ")
-			cfg, err := config.Load(cmd)
-			if err != nil {
+			if err := checkRootArgs(cmd, args); err != nil {
 				return err
 			}
-			if cfg.Quiet {
-				cfg.NoPlan = true
-				cfg.ShowHeader = false
-				cfg.ShowTools = false
-			}
-			if cfg.Verbose {
-				cfg.ShowTools = true
-			}
-
-			apiKey := os.Getenv("FICLI_API_KEY")
-			if apiKey == "" {
-				apiKey = os.Getenv("OPENROUTER_API_KEY")
-			}
-			if apiKey == "" {
-				apiKey = os.Getenv("OPENAI_API_KEY")
+			question := strings.Join(args, " ")
+			if args[0] == reAskArg {
+				var err error
+				if question, err = reAsk(args); err != nil {
+					return err
+				}
 			}
-			if apiKey == "" {
-				apiKey = cfg.APIKey
+			cfg, err := loadRunConfig(cmd)
+			if err != nil {
+				return err
 			}
-			mockMode := os.Getenv("FICLI_MOCK_LLM") == "1"
-			if apiKey == "" && !mockMode {
-				onboardingPath := config.PreferredConfigPath()
-				fmt.Fprintf(os.Stderr, "fi-cli onboarding required.\n1) Run: fi-cli init\n2) Add api_key in: %s\n3) Run: fi-cli \"your question\"\n", onboardingPath)
-				os.Exit(2)
+			stopDiagnostics, err := startDiagnostics(cfg.Pprof, cfg.TraceOut)
+			if err != nil {
+				return err
 			}
+			defer stopDiagnostics()
+			apiKey := requireAPIKey(cfg)
 
 			logger := buildLogger(cfg.Verbose)
 			defer func() { _ = logger.Sync() }()
 
-			repoRoot, err := repo.FindRoot(cfg.Repo)
-			if err != nil {
-				logger.Warn("failed to find repo root", zap.Error(err))
-				repoRoot = cfg.Repo
-			}
-			repoRoot, _ = filepath.Abs(repoRoot)
-
-			repoCtx, err := repo.BuildContext(repoRoot, repo.Limits{ContextMaxBytes: cfg.ToolLimits.ContextMaxBytes, MaxFileBytes: cfg.ToolLimits.MaxFileBytes})
-			if err != nil {
-				logger.Warn("failed to build repo context", zap.Error(err))
-			}
-
-			grepTool := tools.NewGrepTool()
-			toolList := []tools.Tool{grepTool}
-			if cfg.UnsafeShell || len(cfg.ShellAllowlist) > 0 {
-				toolList = append(toolList, tools.NewShellTool(cfg.ShellAllowlist))
+			env := prepareRun(cfg, apiKey, logger)
+			stdin := bufio.NewReader(os.Stdin)
+			// asking again with !! already chose a new run
+			if similarEnabled(env.cfg) && args[0] != reAskArg {
+				env.prompt = stdin
 			}
-
-			exaKey := os.Getenv("EXA_API_KEY")
-			if exaKey != "" && !cfg.NoWeb {
-				toolList = append(toolList, tools.NewExaTool(exaKey))
-			} else {
-				cfg.NoWeb = true
+			if askUserEnabled(env.cfg) {
+				env.registry = env.registry.With(tools.NewAskUserTool(terminalAsk(stdin, os.Stderr)))
 			}
-
-			registry := tools.NewRegistry(toolList...)
-
-			var client llm.Client
-			if mockMode {
-				client = llm.NewMockClient()
-			} else {
-				client = llm.NewOpenRouterClient(apiKey, cfg.OpenRouterBaseURL, cfg.HTTPReferer, cfg.Title)
+			release, err := acquireRunLock(env.cfg, env.repoRoot)
+			if err != nil {
+				return err
 			}
+			defer release()
 
 			ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
 			defer cancel()
-			ctx, cancel = context.WithTimeout(ctx, cfg.Timeout)
-			defer cancel()
-
-			ag := agent.NewAgent(client, registry, nil, logger, cfg)
-
-			if cfg.JSON {
-				result, err := ag.Run(ctx, question, repoRoot, repoCtx)
-				if cfg.PersistRuns {
-					persistRun(logger, result)
-					// ensure persistence failure doesn't block output
-				}
-				payload, _ := json.MarshalIndent(result, "", "  ")
-				fmt.Fprintln(os.Stdout, string(payload))
-				if err != nil {
-					return err
-				}
-				return nil
-			}
 
-			writer := io.Writer(os.Stdout)
-			var logFile *os.File
-			if cfg.LogFile != "" {
-				logPath := cfg.LogFile
-				if !filepath.IsAbs(logPath) {
-					logPath = filepath.Join(repoRoot, logPath)
-				}
-				file, err := os.Create(logPath)
-				if err != nil {
-					return err
+			result, err := executeRun(ctx, logger, env, question)
+			recordQuestion(logger, env.cfg, env.repoRoot, question, result, err)
+			for err == nil && followUpEnabled(env.cfg) {
+				if question = awaitFollowUp(ctx, stdin, os.Stderr, env.cfg.FollowUp); question == "" {
+					break
 				}
-				logFile = file
-				writer = io.MultiWriter(os.Stdout, logFile)
-			}
-			renderer := render.NewStdoutRenderer(writer, cfg.Verbose, cfg.Quiet, cfg.NoPlan, cfg.ShowHeader, cfg.ShowTools)
-			ag = agent.NewAgent(client, registry, renderer, logger, cfg)
-			runResult, runErr := ag.Run(ctx, question, repoRoot, repoCtx)
-			_ = renderer.Close()
-			if logFile != nil {
-				_ = logFile.Close()
-			}
-			if cfg.PersistRuns {
-				persistRun(logger, runResult)
+				env.followUps = append(env.followUps, agent.ExchangeOf(result))
+				result, err = executeRun(ctx, logger, env, question)
+				recordQuestion(logger, env.cfg, env.repoRoot, question, result, err)
 			}
-			if runErr != nil {
-				return runErr
-			}
-			return nil
+			return err
 		},
 	}
 
+	addRunFlags(cmd)
+	cmd.Flags().String("follow-up", "", "At a terminal, wait this long after the answer for a follow-up question (e.g. 15s)")
+
+	cmd.AddCommand(newInitCmd())
+	cmd.AddCommand(newAboutCmd())
+	cmd.AddCommand(newPolicyCmd())
+	cmd.AddCommand(newChatCmd())
+	cmd.AddCommand(newStdioCmd())
+	cmd.AddCommand(newWatchCmd())
+	cmd.AddCommand(newServeCmd())
+	cmd.AddCommand(newContextCmd())
+	cmd.AddCommand(newRecordSessionCmd())
+	cmd.AddCommand(newPingCmd())
+	cmd.AddCommand(newRememberCmd())
+	cmd.AddCommand(newMemoryCmd())
+	cmd.AddCommand(newRunsCmd())
+	cmd.AddCommand(newLastCmd())
+	cmd.AddCommand(newHistoryCmd())
+	cmd.AddCommand(newCacheCmd())
+	cmd.AddCommand(newVersionCmd())
+	cmd.AddCommand(newReleaseCmd())
+	installSuggestions(cmd)
+
+	return cmd
+}
+
+// addRunFlags registers the flags shared by commands that execute runs.
+func addRunFlags(cmd *cobra.Command) {
 	cmd.Flags().String("model", config.DefaultModel, "Model name")
 	cmd.Flags().String("mode", config.DefaultResponseMode, "Response mode: quick|operator|explain")
+	cmd.Flags().String("lang", "", "Answer language (e.g. French, Arabic)")
+	cmd.Flags().Bool("assess", false, "Append a confidence self-assessment to the answer")
+	cmd.Flags().Bool("brief", false, "One-line answers with a small token budget")
+	cmd.Flags().Bool("detailed", false, "Thorough answers with a larger token budget")
+	cmd.MarkFlagsMutuallyExclusive("brief", "detailed")
 	cmd.Flags().Int("max-steps", config.DefaultMaxSteps, "Maximum tool steps")
 	cmd.Flags().String("repo", ".", "Repository path")
-	cmd.Flags().String("timeout", config.DefaultTimeout.String(), "Timeout (e.g. 60s)")
+	cmd.Flags().String("timeout", config.DefaultTimeout.String(), "Run timeout for planning and tool steps (e.g. 60s)")
+	cmd.Flags().String("request-timeout", config.DefaultReqTimeout.String(), "Timeout for each non-streaming model request")
+	cmd.Flags().String("idle-timeout", config.DefaultIdleTimeout.String(), "Fail a streaming answer after this long without tokens")
+	cmd.Flags().Int("max-answer-tokens", config.DefaultMaxAnswerTokens, "Stop a streamed answer after about this many tokens")
 	cmd.Flags().Bool("unsafe-shell", false, "Allow unsafe shell commands")
 	cmd.Flags().StringSlice("shell-allow", nil, "Allow shell command prefix (repeatable)")
 	cmd.Flags().Bool("plan", false, "Generate and show a short plan")
 	cmd.Flags().Bool("no-web", false, "Disable web search")
+	cmd.Flags().StringSlice("tools", nil, "Restrict the run to these tools (e.g. grep,shell)")
 	cmd.Flags().Bool("no-plan", true, "Disable plan output and generation")
 	cmd.Flags().Bool("show-header", false, "Show header lines")
 	cmd.Flags().Bool("show-tools", true, "Show tool call summaries")
 	cmd.Flags().Bool("no-tools", false, "Hide tool call summaries")
 	cmd.Flags().Bool("quiet", false, "Only print final answer")
+	cmd.Flags().Bool("attribution", false, "With --quiet, still end with one line counting the tool calls behind the answer")
 	cmd.Flags().Bool("json", false, "Output JSON only")
-	cmd.Flags().Bool("verbose", false, "Enable verbose logging")
+	cmd.Flags().String("format", "", "Answer format: text, json, table (aligned columns), or tsv (tab-separated values)")
+	cmd.Flags().String("json-stream", "", "Stream NDJSON on stdout: tools (tool call records, then the plain answer) or full (partial results, then the complete result)")
+	cmd.Flags().CountP("verbose", "v", "Verbose output with tool previews; -vv prints full tool payloads")
 	cmd.Flags().String("log-file", "", "Write plain-text output to a file")
+	cmd.Flags().StringSlice("emit", nil, "Event outputs: stdout,ndjson=<path>,sse=<addr>")
 	cmd.Flags().Int("history-lines", 50, "Number of shell history lines to include")
 	cmd.Flags().Bool("no-history", false, "Disable shell history context")
+	cmd.Flags().Bool("capture-pane", false, "Include the current tmux/screen scrollback as context")
+	cmd.Flags().Bool("no-session", false, "Ignore output recorded by an enclosing record-session shell")
+	cmd.Flags().Bool("cached", false, "Reuse a stored answer for the same question, commit, and settings")
+	cmd.Flags().Bool("no-cache", false, "Bypass the answer cache even if answer_cache is enabled")
+	cmd.Flags().Bool("porcelain", false, "Print the answer as plain text with markdown stripped")
+	cmd.Flags().Bool("html", false, "Print the answer as an HTML fragment")
+	cmd.Flags().String("extract-code", "", "Move fenced code blocks from the answer into files under this directory")
+	cmd.Flags().String("save-snippets", "", "Also save fenced code blocks from the answer as numbered files in this directory")
+	cmd.Flags().String("post-slack", "", "Post the final answer to this Slack channel (SLACK_BOT_TOKEN or SLACK_WEBHOOK_URL)")
+	cmd.Flags().Bool("no-conventions", false, "Skip conventions.md from the repo root and user config directory")
+	cmd.Flags().Bool("no-memory", false, "Skip facts saved with `fi remember` for this repo")
+	cmd.Flags().Bool("pipeline", false, "Answer with researcher, executor, and writer stages (slower; for complex questions)")
+	cmd.Flags().Bool("keep-workspace", false, "Keep the run's temp workspace for tool artifacts instead of removing it (debugging)")
+	cmd.Flags().String("route", config.RouteAuto, "Question route: auto, off, or force lookup, research, code_change, debugging, general")
+	cmd.Flags().Int("consensus", 0, "Answer with this many models from the same evidence and have a judge reconcile them (needs --consensus-models)")
+	cmd.Flags().StringSlice("consensus-models", nil, "Models that answer besides --model in consensus mode (comma-separated)")
+	cmd.Flags().String("judge-model", "", "Model that reconciles consensus answers (default: --model)")
+	cmd.Flags().Bool("no-fast-path", false, "Always plan and use separate tool and answer calls, even for simple questions")
+	cmd.Flags().Bool("no-lock", false, "Skip the per-repo lock for runs with shell enabled")
+	cmd.Flags().StringArray("label", nil, "Tag the run with key=value, e.g. team=payments (repeatable; see `fi-cli runs list`)")
+	cmd.Flags().String("pprof", "", "Serve net/http/pprof on this address during the run (e.g. :6060, bound to localhost)")
+	cmd.Flags().String("trace-out", "", "Write a runtime/trace execution trace of the run to this file")
+}
 
-	cmd.AddCommand(newInitCmd())
-	cmd.AddCommand(newAboutCmd())
-	cmd.AddCommand(newPolicyCmd())
+// loadRunConfig loads config for a run command and applies output overrides.
+func loadRunConfig(cmd *cobra.Command) (config.Config, error) {
+	cfg, err := config.Load(cmd)
+	if err != nil {
+		return cfg, err
+	}
+	if cfg.Quiet {
+		cfg.NoPlan = true
+		cfg.ShowHeader = false
+		cfg.ShowTools = false
+	}
+	if cfg.Verbose {
+		cfg.ShowTools = true
+	}
+	return cfg, nil
+}
 
-	return cmd
+// localesDir holds user translations, next to the preferred config file.
+func localesDir() string {
+	return filepath.Join(filepath.Dir(config.PreferredConfigPath()), "locales")
+}
+
+// errNoAPIKey is returned by commands that need a key but do not onboard.
+func errNoAPIKey() error {
+	return errors.New(i18n.T("api key is not configured; run `fi-cli init`"))
+}
+
+// requireAPIKey returns the configured key, or prints onboarding and exits with code 2.
+func requireAPIKey(cfg config.Config) string {
+	apiKey := resolveAPIKey(cfg)
+	if apiKey == "" && !mockMode() {
+		onboardingPath := config.PreferredConfigPath()
+		fmt.Fprintln(os.Stderr, i18n.Sprintf("fi-cli onboarding required.\n1) Run: fi-cli init\n2) Add api_key in: %s\n3) Run: fi-cli \"your question\"", onboardingPath))
+		os.Exit(2)
+	}
+	return apiKey
+}
+
+// executeRun runs one question and writes JSON or streamed text output.
+func executeRun(ctx context.Context, logger *zap.Logger, env runEnv, question string) (agent.RunResult, error) {
+	cfg := env.cfg
+	var cacheKey string
+	var fingerprint repo.Fingerprint
+	// a follow-up's answer depends on the exchanges before it, not just the question
+	if cfg.AnswerCache && len(env.followUps) == 0 {
+		if fingerprint = repo.FingerprintOf(env.repoRoot); fingerprint.Head == "" {
+			logger.Debug("answer cache disabled outside a git checkout")
+		} else {
+			cacheKey = cache.Key(question, fingerprint, cfg)
+			if entry, ok := cache.Lookup(cacheKey); ok {
+				return printCachedAnswer(env, entry)
+			}
+		}
+	}
+	if env.prompt != nil && len(env.followUps) == 0 {
+		if entries, err := history.Load(); err != nil {
+			logger.Debug("failed to load question history", zap.Error(err))
+		} else if entry, result, ok := findSimilar(entries, question, env.repoRoot, cfg.SimilarThreshold); ok && offerSimilar(ctx, env.prompt, os.Stderr, entry, time.Now()) {
+			result.CachedFrom = result.RunID
+			return printStoredAnswer(env, result, fmt.Sprintf("(from run %s, %s: %s)", result.RunID, entry.AskedAt.Local().Format("2006-01-02 15:04"), entry.Question))
+		}
+	}
+	result, err := runAgent(ctx, logger, env, question)
+	if cacheKey != "" && err == nil {
+		if err := cache.Store(cacheKey, env.repoRoot, fingerprint, result); err != nil {
+			logger.Debug("failed to cache answer", zap.Error(err))
+		}
+	}
+	return result, err
+}
+
+// printCachedAnswer renders a cached run as if it had just finished.
+func printCachedAnswer(env runEnv, entry cache.Entry) (agent.RunResult, error) {
+	result := entry.Result
+	result.CachedFrom = result.RunID
+	return printStoredAnswer(env, result, fmt.Sprintf("(cached from run %s, %s; --no-cache to refresh)", result.RunID, entry.StoredAt.Local().Format("2006-01-02 15:04")))
+}
+
+// printStoredAnswer renders the answer of an earlier run, followed by note
+// unless quiet.
+func printStoredAnswer(env runEnv, result agent.RunResult, note string) (agent.RunResult, error) {
+	if env.cfg.JSON {
+		payload, _ := json.MarshalIndent(result, "", "  ")
+		fmt.Fprintln(os.Stdout, string(payload))
+		return result, nil
+	}
+	if env.cfg.JSONStream == config.JSONStreamFull {
+		payload, _ := json.Marshal(result)
+		fmt.Fprintln(os.Stdout, string(payload))
+		return result, nil
+	}
+	renderer, err := buildRenderers(env.cfg, os.Stdout)
+	if err != nil {
+		return result, err
+	}
+	renderer.Emit(events.Event{Type: events.FinalAnswerReady, Timestamp: time.Now(), Payload: events.FinalAnswerPayload{Answer: result.FinalAnswer}})
+	_ = renderer.Close()
+	if !env.cfg.Quiet {
+		fmt.Fprintln(os.Stdout, note)
+	} else if env.cfg.Attribution {
+		counts := map[string]int{}
+		for _, call := range result.ToolCalls {
+			counts[call.ToolName]++
+		}
+		fmt.Fprintln(os.Stdout, render.Attribution(counts))
+	}
+	return result, nil
+}
+
+func runAgent(ctx context.Context, logger *zap.Logger, env runEnv, question string) (agent.RunResult, error) {
+	cfg := env.cfg
+	if cfg.JSON {
+		renderer, err := buildRenderers(cfg, nil)
+		if err != nil {
+			return agent.RunResult{}, err
+		}
+		ag := agent.NewAgent(env.client, env.registry, renderer, logger, cfg)
+		ag.AddWarnings(env.warnings...)
+		result, err := startAgent(ctx, logger, ag, env, question)
+		_ = renderer.Close()
+		payload, _ := json.MarshalIndent(result, "", "  ")
+		fmt.Fprintln(os.Stdout, string(payload))
+		// ensure persistence and notification failures don't block output
+		saveAndNotify(ctx, logger, cfg, result)
+		return result, err
+	}
+
+	writer := io.Writer(os.Stdout)
+	var logFile *os.File
+	if cfg.LogFile != "" {
+		logPath := cfg.LogFile
+		if !filepath.IsAbs(logPath) {
+			logPath = filepath.Join(env.repoRoot, logPath)
+		}
+		file, err := os.Create(logPath)
+		if err != nil {
+			return agent.RunResult{}, err
+		}
+		logFile = file
+		writer = io.MultiWriter(os.Stdout, logFile)
+	}
+	renderer, err := buildRenderers(cfg, writer)
+	if err != nil {
+		if logFile != nil {
+			_ = logFile.Close()
+		}
+		return agent.RunResult{}, err
+	}
+	ag := agent.NewAgent(env.client, env.registry, renderer, logger, cfg)
+	ag.AddWarnings(env.warnings...)
+	runResult, runErr := startAgent(ctx, logger, ag, env, question)
+	_ = renderer.Close()
+	if cfg.JSONStream == config.JSONStreamFull {
+		// the complete document ends the stream of partial snapshots
+		payload, _ := json.Marshal(runResult)
+		fmt.Fprintln(writer, string(payload))
+	}
+	if logFile != nil {
+		_ = logFile.Close()
+	}
+	saveAndNotify(ctx, logger, cfg, runResult)
+	return runResult, runErr
+}
+
+// startAgent runs question, or continues env.resume, and persists the
+// checkpoint of a run that stops at the timeout.
+func startAgent(ctx context.Context, logger *zap.Logger, ag *agent.Agent, env runEnv, question string) (agent.RunResult, error) {
+	var result agent.RunResult
+	var err error
+	if env.resume != nil {
+		result, err = ag.Continue(ctx, *env.resume, env.repoCtx)
+	} else {
+		ag.FollowUp(env.followUps...)
+		result, err = ag.Run(ctx, question, env.repoRoot, env.repoCtx)
+	}
+	saved := false
+	if result.Checkpoint != nil {
+		if _, saveErr := runs.SaveCheckpoint(*result.Checkpoint); saveErr != nil {
+			logger.Warn("failed to save checkpoint", zap.Error(saveErr))
+		} else {
+			saved = true
+			fmt.Fprintf(os.Stderr, "run %s stopped at the %s timeout after %d steps; resume with: fi-cli runs continue %s\n", result.RunID, env.cfg.Timeout, result.Checkpoint.Step, result.RunID)
+		}
+	}
+	// keep the old checkpoint unless the continued run finished or left a new one
+	if env.resume != nil && (err == nil || saved) {
+		if rmErr := runs.RemoveCheckpoint(env.resume.RunID); rmErr != nil {
+			logger.Debug("failed to remove checkpoint", zap.Error(rmErr))
+		}
+	}
+	return result, err
+}
+
+// buildRenderers parses --emit targets (stdout, ndjson=<path>, sse=<addr>) into
+// a fan-out renderer. Text output defaults to stdout; JSON mode never renders
+// to stdout because it is reserved for the final document.
+func buildRenderers(cfg config.Config, stdout io.Writer) (*render.MultiRenderer, error) {
+	targets := cfg.Emit
+	if len(targets) == 0 && stdout != nil {
+		targets = []string{"stdout"}
+	}
+	var renderers []render.Renderer
+	fail := func(err error) (*render.MultiRenderer, error) {
+		_ = render.NewMultiRenderer(renderers...).Close()
+		return nil, err
+	}
+	for _, target := range targets {
+		kind, value, _ := strings.Cut(target, "=")
+		switch strings.ToLower(strings.TrimSpace(kind)) {
+		case "stdout":
+			switch {
+			case stdout == nil:
+			case cfg.JSONStream == config.JSONStreamTools:
+				renderers = append(renderers, render.NewToolStreamRenderer(stdout))
+			case cfg.JSONStream == config.JSONStreamFull:
+				renderers = append(renderers, render.NewResultStreamRenderer(stdout))
+			default:
+				pipeline := answerPipeline(cfg)
+				text := render.NewStdoutRenderer(stdout, cfg.Verbose, cfg.Quiet, cfg.NoPlan, cfg.ShowHeader, cfg.ShowTools)
+				if cfg.Porcelain || cfg.HTML || tabular(cfg) {
+					text.WithoutPrefix()
+				}
+				if cfg.Attribution {
+					text.WithAttribution()
+				}
+				if cfg.VerboseLevel >= 2 {
+					text.WithFullPayloads()
+				}
+				renderers = append(renderers, render.NewProcessingRenderer(text, pipeline))
+			}
+		case "ndjson":
+			if value == "" {
+				return fail(errors.New("--emit ndjson requires a path (ndjson=events.ndjson)"))
+			}
+			file, err := os.Create(value)
+			if err != nil {
+				return fail(err)
+			}
+			renderers = append(renderers, render.NewNDJSONRenderer(file))
+		case "sse":
+			if value == "" {
+				return fail(errors.New("--emit sse requires an address (sse=:7777)"))
+			}
+			sse, err := render.NewSSERenderer(value)
+			if err != nil {
+				return fail(err)
+			}
+			fmt.Fprintf(os.Stderr, "streaming events at http://%s/events\n", sse.Addr())
+			renderers = append(renderers, sse)
+		default:
+			return fail(fmt.Errorf("unknown --emit target %q (expected stdout, ndjson=<path>, sse=<addr>)", target))
+		}
+	}
+	if cfg.SaveSnippets != "" {
+		// keep stdout clean for --json; the saved paths are a side note
+		snippetOut := stdout
+		if snippetOut == nil || cfg.JSONStream != "" {
+			snippetOut = os.Stderr
+		}
+		renderers = append(renderers, render.NewSnippetRenderer(snippetOut, cfg.SaveSnippets))
+	}
+	return render.NewMultiRenderer(renderers...), nil
+}
+
+// answerPipeline chains the configured answer post-processors: code extraction
+// first, so later steps see the file references, then table extraction for
+// --format table|tsv, then porcelain, then HTML.
+func answerPipeline(cfg config.Config) render.Pipeline {
+	var pipeline render.Pipeline
+	if cfg.ExtractCode != "" {
+		pipeline = append(pipeline, render.ExtractCodeProcessor(cfg.ExtractCode))
+	}
+	if tabular(cfg) {
+		pipeline = append(pipeline, render.TableProcessor(cfg.OutputFormat == config.OutputTSV))
+	}
+	if cfg.Porcelain {
+		pipeline = append(pipeline, render.PorcelainProcessor())
+	}
+	if cfg.HTML {
+		pipeline = append(pipeline, render.HTMLProcessor())
+	}
+	return pipeline
+}
+
+func tabular(cfg config.Config) bool {
+	return cfg.OutputFormat == config.OutputTable || cfg.OutputFormat == config.OutputTSV
 }
 
 func newInitCmd() *cobra.Command {
@@ -200,8 +494,8 @@ func newInitCmd() *cobra.Command {
 			if target == "" {
 				target = config.PreferredConfigPath()
 			} else if !force {
-				fmt.Fprintf(os.Stdout, "Config already exists: %s\n", target)
-				fmt.Fprintln(os.Stdout, "Use --force to overwrite. Next: set api_key and run `fi-cli \"your question\"`.")
+				fmt.Fprintln(os.Stdout, i18n.Sprintf("Config already exists: %s", target))
+				fmt.Fprintln(os.Stdout, i18n.T("Use --force to overwrite. Next: set api_key and run `fi-cli \"your question\"`."))
 				return nil
 			}
 
@@ -215,6 +509,7 @@ api_key: ""
 model: openrouter/pony-alpha
 openrouter_base_url: "https://openrouter.ai/api/v1"
 response_mode: quick
+# answer_language: French
 show_header: false
 show_tools: true
 no_plan: true
@@ -231,11 +526,11 @@ tool_limits:
 				return err
 			}
 
-			fmt.Fprintf(os.Stdout, "Initialized config: %s\n", target)
-			fmt.Fprintln(os.Stdout, "Next steps:")
-			fmt.Fprintln(os.Stdout, "1) Set `api_key` in the config file")
-			fmt.Fprintln(os.Stdout, "2) Recommended shell alias: alias fi='command fi-cli'")
-			fmt.Fprintln(os.Stdout, "3) Run: fi-cli \"what's the tech stack here?\"")
+			fmt.Fprintln(os.Stdout, i18n.Sprintf("Initialized config: %s", target))
+			fmt.Fprintln(os.Stdout, i18n.T("Next steps:"))
+			fmt.Fprintln(os.Stdout, i18n.T("1) Set `api_key` in the config file"))
+			fmt.Fprintln(os.Stdout, i18n.T("2) Recommended shell alias: alias fi='command fi-cli'"))
+			fmt.Fprintln(os.Stdout, i18n.T("3) Run: fi-cli \"what's the tech stack here?\""))
 			return nil
 		},
 	}
@@ -243,6 +538,30 @@ tool_limits:
 	return cmd
 }
 
+func ruleLabel(path string) string {
+	if path == "" {
+		return "/"
+	}
+	return path + "/"
+}
+
+func describeRule(rule policy.PathRule) string {
+	var parts []string
+	if rule.Shell != nil {
+		parts = append(parts, fmt.Sprintf("shell=%t", *rule.Shell))
+	}
+	if rule.Web != nil {
+		parts = append(parts, fmt.Sprintf("web=%t", *rule.Web))
+	}
+	if len(rule.DenyExtensions) > 0 {
+		parts = append(parts, "deny="+strings.Join(rule.DenyExtensions, ","))
+	}
+	if len(parts) == 0 {
+		return ""
+	}
+	return " " + strings.Join(parts, " ")
+}
+
 func newPolicyCmd() *cobra.Command {
 	cmd := &cobra.Command{
 		Use:   "policy",
@@ -264,6 +583,18 @@ func newPolicyCmd() *cobra.Command {
 			for _, entry := range cfg.ShellAllowlist {
 				fmt.Fprintf(os.Stdout, "- %s\n", entry)
 			}
+			repoRoot, err := repo.FindRoot(".")
+			if err != nil {
+				return nil
+			}
+			pathPolicy, err := policy.LoadPathPolicy(repoRoot)
+			if err != nil {
+				return err
+			}
+			fmt.Fprintf(os.Stdout, "path_rules: %d (%s)\n", len(pathPolicy.Rules), filepath.Join(repoRoot, policy.RepoPolicyFile))
+			for _, rule := range pathPolicy.Rules {
+				fmt.Fprintf(os.Stdout, "- %s%s\n", ruleLabel(rule.Path), describeRule(rule))
+			}
 			return nil
 		},
 	})
@@ -301,8 +632,12 @@ func newAboutCmd() *cobra.Command {
 			fmt.Fprintln(os.Stdout, "fi-cli")
 			fmt.Fprintln(os.Stdout, "- Default behavior: read-only repository analysis (grep/context)")
 			fmt.Fprintf(os.Stdout, "- Active shell mode: %s\n", mode)
-			fmt.Fprintf(os.Stdout, "- Tool call caps: grep=%d shell=%d web=%d\n", cfg.ToolLimits.GrepMaxCalls, cfg.ToolLimits.ShellMaxCalls, cfg.ToolLimits.WebMaxCalls)
+			fmt.Fprintf(os.Stdout, "- Tool call caps: grep=%d shell=%d web=%d search=%d inspect=%d\n", cfg.ToolLimits.GrepMaxCalls, cfg.ToolLimits.ShellMaxCalls, cfg.ToolLimits.WebMaxCalls, cfg.ToolLimits.SearchMaxCalls, cfg.ToolLimits.InspectMaxCalls)
 			fmt.Fprintf(os.Stdout, "- Response mode: %s\n", cfg.ResponseMode)
+			fmt.Fprintf(os.Stdout, "- Verbosity: %s\n", cfg.Verbosity)
+			if cfg.AnswerLanguage != "" {
+				fmt.Fprintf(os.Stdout, "- Answer language: %s\n", cfg.AnswerLanguage)
+			}
 			fmt.Fprintln(os.Stdout, "- Config search order:")
 			for _, candidate := range config.ConfigCandidatePaths() {
 				fmt.Fprintf(os.Stdout, "  - %s\n", candidate)
@@ -312,6 +647,215 @@ func newAboutCmd() *cobra.Command {
 	}
 }
 
+// builtinTools lists the tool names accepted by tools.enabled/tools.disabled.
+var builtinTools = []string{"grep", "list_files", "read_file", "archive_list", "archive_read", "data_preview", "api_schema", "env_usage", "docker_analyze", "ci_config", "dep_docs", "shell", "exa_search", "exa_contents", "code_search", "issue_lookup", "ask_user"}
+
+// runEnv bundles the resolved repository, tools, and client for a run.
+type runEnv struct {
+	cfg      config.Config
+	repoRoot string
+	repoCtx  repo.RepoContext
+	registry *tools.Registry
+	client   llm.Client
+	// warnings describe setup degradations the run reports as Warning events.
+	warnings []string
+	// resume continues a checkpointed run instead of starting a new one.
+	resume *agent.Checkpoint
+	// followUps are the earlier exchanges of an inline follow-up chain.
+	followUps []agent.Exchange
+	// prompt reads a person's choice when a similar question was answered
+	// before; nil skips the offer.
+	prompt *bufio.Reader
+}
+
+func resolveAPIKey(cfg config.Config) string {
+	for _, name := range []string{"FICLI_API_KEY", "OPENROUTER_API_KEY", "OPENAI_API_KEY"} {
+		if value := os.Getenv(name); value != "" {
+			return value
+		}
+	}
+	return cfg.APIKey
+}
+
+func mockMode() bool {
+	return os.Getenv("FICLI_MOCK_LLM") == "1" || os.Getenv(llm.ScenarioEnvVar) != ""
+}
+
+func prepareRun(cfg config.Config, apiKey string, logger *zap.Logger) runEnv {
+	var warnings []string
+	repoRoot, err := repo.FindRoot(cfg.Repo)
+	if err != nil {
+		logger.Warn("failed to find repo root", zap.Error(err))
+		repoRoot = cfg.Repo
+	}
+	repoRoot, _ = filepath.Abs(repoRoot)
+
+	repoCtx, err := repo.BuildContext(repoRoot, repo.Limits{ContextMaxBytes: cfg.ToolLimits.ContextMaxBytes, MaxFileBytes: cfg.ToolLimits.MaxFileBytes})
+	if err != nil {
+		logger.Warn("failed to build repo context", zap.Error(err))
+		warnings = append(warnings, fmt.Sprintf("repository context unavailable (%v); the answer relies on tool calls only", err))
+	}
+	if _, err := memory.LoadKnowledge(repoRoot); err != nil && !(cfg.NoMemory && cfg.NoConventions) {
+		warnings = append(warnings, fmt.Sprintf("shared knowledge ignored: %v", err))
+	}
+
+	for _, name := range append(append([]string{}, cfg.Tools.Enabled...), cfg.Tools.Disabled...) {
+		if !slices.Contains(builtinTools, name) {
+			logger.Warn("unknown tool in tools selection", zap.String("tool", name))
+			warnings = append(warnings, fmt.Sprintf("unknown tool %q in tools selection (known: %s)", name, strings.Join(builtinTools, ", ")))
+		}
+	}
+
+	var toolList []tools.Tool
+	if cfg.Tools.Allows("grep") {
+		grep := tools.NewGrepTool()
+		if !grep.HasRipgrep() {
+			warnings = append(warnings, "rg not found on PATH; grep uses a slower built-in search")
+		}
+		toolList = append(toolList, grep)
+	}
+	if cfg.Tools.Allows("list_files") {
+		toolList = append(toolList, tools.NewListFilesTool())
+	}
+	if cfg.Tools.Allows("read_file") {
+		toolList = append(toolList, tools.NewReadFileTool())
+	}
+	if cfg.Tools.Allows("archive_list") {
+		toolList = append(toolList, tools.NewArchiveListTool())
+	}
+	if cfg.Tools.Allows("archive_read") {
+		toolList = append(toolList, tools.NewArchiveReadTool())
+	}
+	if cfg.Tools.Allows("data_preview") {
+		toolList = append(toolList, tools.NewDataPreviewTool())
+	}
+	if cfg.Tools.Allows("api_schema") {
+		toolList = append(toolList, tools.NewAPISchemaTool())
+	}
+	if cfg.Tools.Allows("env_usage") {
+		toolList = append(toolList, tools.NewEnvUsageTool())
+	}
+	if cfg.Tools.Allows("docker_analyze") {
+		toolList = append(toolList, tools.NewDockerAnalyzeTool())
+	}
+	if cfg.Tools.Allows("ci_config") {
+		toolList = append(toolList, tools.NewCIConfigTool())
+	}
+	if cfg.Tools.Allows("dep_docs") {
+		toolList = append(toolList, tools.NewDepDocsTool())
+	}
+	if !cfg.Tools.Allows("shell") {
+		// keep policy, locking, and prompts consistent with the missing tool
+		cfg.UnsafeShell = false
+		cfg.ShellAllowlist = nil
+	}
+	if cfg.UnsafeShell || len(cfg.ShellAllowlist) > 0 {
+		toolList = append(toolList, tools.NewShellTool(cfg.ShellAllowlist))
+	}
+
+	// code_search has its own backend; only an explicit no_web turns it off
+	webAllowed := !cfg.NoWeb
+	exaKey := os.Getenv("EXA_API_KEY")
+	if exaKey != "" && !cfg.NoWeb && cfg.Tools.Allows("exa_search") {
+		toolList = append(toolList, tools.NewExaTool(exaKey))
+		if cfg.Tools.Allows("exa_contents") {
+			toolList = append(toolList, tools.NewExaContentsTool(exaKey))
+		}
+	} else {
+		if exaKey == "" && !cfg.NoWeb && cfg.Tools.Allows("exa_search") {
+			warnings = append(warnings, "web search disabled: EXA_API_KEY is not set")
+		}
+		cfg.NoWeb = true
+	}
+
+	if cfg.CodeSearch != "" && webAllowed && cfg.Tools.Allows("code_search") {
+		if cfg.CodeSearch == tools.CodeSearchGrepApp {
+			toolList = append(toolList, tools.NewGrepAppCodeSearchTool())
+		} else if token := githubToken(); token != "" {
+			toolList = append(toolList, tools.NewGitHubCodeSearchTool(token))
+		} else {
+			warnings = append(warnings, "code_search disabled: GITHUB_TOKEN is not set (or set code_search: grep.app)")
+		}
+	}
+
+	if cfg.IssueTracker != "" && cfg.Tools.Allows("issue_lookup") {
+		if tool, missing := issueLookupTool(cfg.IssueTracker); tool != nil {
+			toolList = append(toolList, tool)
+		} else {
+			warnings = append(warnings, fmt.Sprintf("issue_lookup disabled: %s is not set", missing))
+		}
+	}
+
+	client, err := buildClient(cfg, apiKey)
+	if err != nil {
+		logger.Warn("failed to load mock scenario", zap.Error(err))
+		warnings = append(warnings, fmt.Sprintf("mock scenario unavailable (%v); using the built-in script", err))
+		client = llm.NewMockClient()
+	}
+
+	return runEnv{cfg: cfg, repoRoot: repoRoot, repoCtx: repoCtx, registry: tools.NewRegistry(toolList...), client: client, warnings: warnings}
+}
+
+// issueLookupTool builds issue_lookup for tracker from its environment
+// credentials. When they are incomplete it returns nil and the name of the
+// missing variable.
+func issueLookupTool(tracker string) (tools.Tool, string) {
+	if tracker == tools.IssueTrackerLinear {
+		key := os.Getenv("LINEAR_API_KEY")
+		if key == "" {
+			return nil, "LINEAR_API_KEY"
+		}
+		return tools.NewLinearIssueTool(key), ""
+	}
+	baseURL, token := os.Getenv("JIRA_BASE_URL"), os.Getenv("JIRA_API_TOKEN")
+	switch {
+	case baseURL == "":
+		return nil, "JIRA_BASE_URL"
+	case token == "":
+		return nil, "JIRA_API_TOKEN"
+	}
+	return tools.NewJiraIssueTool(baseURL, os.Getenv("JIRA_EMAIL"), token), ""
+}
+
+func githubToken() string {
+	if token := os.Getenv("GITHUB_TOKEN"); token != "" {
+		return token
+	}
+	return os.Getenv("GH_TOKEN")
+}
+
+// buildClient returns the provider client, or a mock in mock mode. The error
+// reports an unreadable FICLI_MOCK_SCENARIO file.
+func buildClient(cfg config.Config, apiKey string) (llm.Client, error) {
+	if path := os.Getenv(llm.ScenarioEnvVar); path != "" {
+		scenario, err := llm.LoadScenario(path)
+		if err != nil {
+			return nil, err
+		}
+		return llm.NewScenarioClient(scenario), nil
+	}
+	if mockMode() {
+		return llm.NewMockClient(), nil
+	}
+	return llm.NewOpenRouterClient(apiKey, cfg.OpenRouterBaseURL, cfg.HTTPReferer, cfg.Title), nil
+}
+
+// acquireRunLock serializes write-capable runs (shell enabled) within a repo.
+func acquireRunLock(cfg config.Config, repoRoot string) (func(), error) {
+	writeCapable := policy.ResolveShellMode(cfg.UnsafeShell, cfg.ShellAllowlist) != policy.ShellModeReadOnly
+	if !writeCapable || cfg.NoLock {
+		return func() {}, nil
+	}
+	lock, err := repo.AcquireLock(repoRoot)
+	if err != nil {
+		if errors.Is(err, repo.ErrLocked) {
+			return nil, fmt.Errorf("%w; another shell-enabled run is active in %s (use --no-lock to override)", err, repoRoot)
+		}
+		return nil, err
+	}
+	return func() { _ = lock.Release() }, nil
+}
+
 func buildLogger(verbose bool) *zap.Logger {
 	if verbose {
 		logger, _ := zap.NewDevelopment()
@@ -321,24 +865,10 @@ func buildLogger(verbose bool) *zap.Logger {
 	return logger
 }
 
-func persistRun(logger *zap.Logger, result agent.RunResult) {
-	home, err := os.UserHomeDir()
-	if err != nil {
-		logger.Warn("failed to get home dir", zap.Error(err))
-		return
-	}
-	path := filepath.Join(home, ".local", "share", "fi.ashref.tn", "runs")
-	if err := os.MkdirAll(path, 0o755); err != nil {
-		logger.Warn("failed to create run directory", zap.Error(err))
-		return
-	}
-	file := filepath.Join(path, result.RunID+".json")
-	payload, err := json.MarshalIndent(result, "", "  ")
+func persistRun(logger *zap.Logger, result agent.RunResult) string {
+	path, err := runs.Save(result)
 	if err != nil {
-		logger.Warn("failed to marshal run log", zap.Error(err))
-		return
-	}
-	if err := os.WriteFile(file, payload, 0o600); err != nil {
 		logger.Warn("failed to write run log", zap.Error(err))
 	}
+	return path
 }
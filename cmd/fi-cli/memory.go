@@ -0,0 +1,182 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"fi-cli/internal/memory"
+	"fi-cli/internal/repo"
+
+	"github.com/spf13/cobra"
+)
+
+func newRememberCmd() *cobra.Command {
+	var repoPath string
+	var shared bool
+	cmd := &cobra.Command{
+		Use:     "remember <fact>",
+		Short:   "Save a fact about this repo that future runs will be told",
+		Example: `  fi remember "the 'worker' is the queue consumer in services/worker"`,
+		Args:    cobra.MinimumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			repoRoot, err := repo.FindRoot(repoPath)
+			if err != nil {
+				return err
+			}
+			text := strings.Join(args, " ")
+			if shared {
+				if err := memory.ShareText(repoRoot, text); err != nil {
+					return err
+				}
+				fmt.Fprintf(os.Stdout, "added to %s (commit it to share)\n", filepath.Join(repoRoot, memory.KnowledgeFile))
+				return nil
+			}
+			fact, err := memory.Remember(repoRoot, text)
+			if err != nil {
+				return err
+			}
+			fmt.Fprintf(os.Stdout, "remembered #%d for %s\n", fact.ID, repoRoot)
+			return nil
+		},
+	}
+	cmd.Flags().StringVar(&repoPath, "repo", ".", "Repository path")
+	cmd.Flags().BoolVar(&shared, "shared", false, "Write the fact to the repo's "+memory.KnowledgeFile+" instead of local memory")
+	return cmd
+}
+
+func newMemoryCmd() *cobra.Command {
+	var repoPath string
+	cmd := &cobra.Command{
+		Use:   "memory",
+		Short: "Manage facts saved with `fi remember`",
+	}
+	cmd.PersistentFlags().StringVar(&repoPath, "repo", ".", "Repository path")
+
+	var asJSON bool
+	list := &cobra.Command{
+		Use:   "list",
+		Short: "List the shared and local facts for this repo",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			repoRoot, err := repo.FindRoot(repoPath)
+			if err != nil {
+				return err
+			}
+			entries, err := memory.Merged(repoRoot)
+			if err != nil && entries == nil {
+				return err
+			}
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "warning: %v\n", err)
+			}
+			if asJSON {
+				enc := json.NewEncoder(os.Stdout)
+				enc.SetIndent("", "  ")
+				if entries == nil {
+					entries = []memory.Entry{}
+				}
+				return enc.Encode(entries)
+			}
+			printFacts(os.Stdout, repoRoot, entries)
+			return nil
+		},
+	}
+	list.Flags().BoolVar(&asJSON, "json", false, "Print facts as JSON")
+	cmd.AddCommand(list)
+
+	var all bool
+	forget := &cobra.Command{
+		Use:   "forget <id>...",
+		Short: "Remove saved facts by id",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			repoRoot, err := repo.FindRoot(repoPath)
+			if err != nil {
+				return err
+			}
+			if all {
+				if len(args) > 0 {
+					return fmt.Errorf("--all takes no ids")
+				}
+				if err := memory.Clear(repoRoot); err != nil {
+					return err
+				}
+				fmt.Fprintf(os.Stdout, "forgot all facts for %s\n", repoRoot)
+				return nil
+			}
+			if len(args) == 0 {
+				return fmt.Errorf("pass fact ids (see `fi memory list`) or --all")
+			}
+			ids, err := parseFactIDs(args)
+			if err != nil {
+				return err
+			}
+			for _, id := range ids {
+				if err := memory.Forget(repoRoot, id); err != nil {
+					return err
+				}
+				fmt.Fprintf(os.Stdout, "forgot #%d\n", id)
+			}
+			return nil
+		},
+	}
+	forget.Flags().BoolVar(&all, "all", false, "Remove every local fact for this repo")
+	cmd.AddCommand(forget)
+
+	cmd.AddCommand(&cobra.Command{
+		Use:   "share <id>...",
+		Short: "Move local facts into " + memory.KnowledgeFile + " so the team gets them",
+		Args:  cobra.MinimumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			repoRoot, err := repo.FindRoot(repoPath)
+			if err != nil {
+				return err
+			}
+			ids, err := parseFactIDs(args)
+			if err != nil {
+				return err
+			}
+			moved, err := memory.Share(repoRoot, ids...)
+			if err != nil {
+				return err
+			}
+			fmt.Fprintf(os.Stdout, "moved %d facts to %s (commit it to share)\n", len(moved), filepath.Join(repoRoot, memory.KnowledgeFile))
+			return nil
+		},
+	})
+	return cmd
+}
+
+func parseFactIDs(args []string) ([]int, error) {
+	ids := make([]int, 0, len(args))
+	for _, arg := range args {
+		id, err := strconv.Atoi(strings.TrimPrefix(arg, "#"))
+		if err != nil {
+			return nil, fmt.Errorf("invalid fact id %q", arg)
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
+
+func printFacts(w io.Writer, repoRoot string, entries []memory.Entry) {
+	if len(entries) == 0 {
+		fmt.Fprintf(w, "no facts saved for %s (add one with `fi remember`)\n", repoRoot)
+		return
+	}
+	fmt.Fprintf(w, "%d facts for %s\n", len(entries), repoRoot)
+	for _, entry := range entries {
+		label := fmt.Sprintf("#%d", entry.ID)
+		if entry.Shared {
+			label = "repo"
+		}
+		fmt.Fprintf(w, "%-5s %s\n", label, entry.Text)
+		if len(entry.Changed) > 0 {
+			fmt.Fprintf(w, "      (saved before %s changed)\n", strings.Join(entry.Changed, ", "))
+		}
+	}
+}
@@ -0,0 +1,41 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"fi-cli/internal/agent"
+	"fi-cli/internal/config"
+	"fi-cli/internal/notify"
+
+	"go.uber.org/zap"
+)
+
+// notifyTargets returns the configured notification sinks plus the Slack
+// channel from --post-slack, which always posts.
+func notifyTargets(cfg config.Config) []notify.Target {
+	targets := notify.Targets(cfg.Notify)
+	if cfg.PostSlack != "" {
+		targets = append(targets, notify.Target{Type: config.NotifySlack, Sink: notify.SlackChannel(cfg.PostSlack)})
+	}
+	return targets
+}
+
+// saveAndNotify persists result when persist_runs is set or a notification
+// will link to it, then notifies. Failures are printed as warnings: the
+// answer has already been shown.
+func saveAndNotify(ctx context.Context, logger *zap.Logger, cfg config.Config, result agent.RunResult) {
+	targets := notifyTargets(cfg)
+	notifying := notify.Any(targets, result)
+	var path string
+	if cfg.PersistRuns || notifying {
+		path = persistRun(logger, result)
+	}
+	if !notifying {
+		return
+	}
+	for _, err := range notify.Send(ctx, targets, notify.Notification{Result: result, ReportPath: path, ReportURL: cfg.SlackReportURL}) {
+		fmt.Fprintf(os.Stderr, "warning: %v\n", err)
+	}
+}
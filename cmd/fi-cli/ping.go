@@ -0,0 +1,172 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"fi-cli/internal/config"
+	"fi-cli/internal/llm"
+	"fi-cli/internal/tools"
+
+	"github.com/openai/openai-go/v3"
+	"github.com/openai/openai-go/v3/packages/param"
+	"github.com/spf13/cobra"
+)
+
+// pingReport is the result of `fi-cli ping`.
+type pingReport struct {
+	BaseURL        string    `json:"base_url"`
+	Model          string    `json:"model"`
+	Completion     pingCheck `json:"completion"`
+	ToolCalling    pingCheck `json:"tool_calling"`
+	ToolsSupported bool      `json:"tools_supported"`
+}
+
+// pingCheck is one probe request.
+type pingCheck struct {
+	OK        bool      `json:"ok"`
+	LatencyMs int64     `json:"latency_ms"`
+	Usage     llm.Usage `json:"usage"`
+	CostUSD   float64   `json:"cost_usd,omitempty"`
+	Reply     string    `json:"reply,omitempty"`
+	Error     string    `json:"error,omitempty"`
+}
+
+func newPingCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "ping",
+		Short: "Send a minimal completion to the configured provider and check tool calling",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cfg, err := config.Load(cmd)
+			if err != nil {
+				return err
+			}
+			apiKey := resolveAPIKey(cfg)
+			if apiKey == "" && !mockMode() {
+				return errNoAPIKey()
+			}
+			client, err := buildClient(cfg, apiKey)
+			if err != nil {
+				return err
+			}
+			report := runPing(cmd.Context(), cfg, client)
+			if cfg.JSON {
+				payload, _ := json.MarshalIndent(report, "", "  ")
+				fmt.Fprintln(os.Stdout, string(payload))
+			} else {
+				printPingReport(os.Stdout, report)
+			}
+			if !report.Completion.OK {
+				return errors.New("provider check failed")
+			}
+			return nil
+		},
+	}
+	cmd.Flags().String("model", config.DefaultModel, "Model name")
+	cmd.Flags().Bool("json", false, "Emit the report as JSON")
+	return cmd
+}
+
+func runPing(ctx context.Context, cfg config.Config, client llm.Client) pingReport {
+	report := pingReport{BaseURL: cfg.OpenRouterBaseURL, Model: cfg.Model}
+	report.Completion = probe(ctx, cfg, client, llm.Request{
+		Model:     cfg.Model,
+		Messages:  []openai.ChatCompletionMessageParamUnion{openai.UserMessage("Reply with the single word: pong")},
+		MaxTokens: 16,
+	}, func(resp llm.Response) string { return strings.TrimSpace(resp.Content) })
+	if !report.Completion.OK {
+		return report
+	}
+
+	registry := tools.NewRegistry(pingTool{})
+	report.ToolCalling = probe(ctx, cfg, client, llm.Request{
+		Model:      cfg.Model,
+		Messages:   []openai.ChatCompletionMessageParamUnion{openai.UserMessage("Call the echo tool with the text \"ping\".")},
+		Tools:      registry.OpenAITools(),
+		ToolChoice: openai.ChatCompletionToolChoiceOptionUnionParam{OfAuto: param.NewOpt("auto")},
+		MaxTokens:  64,
+	}, func(resp llm.Response) string {
+		if len(resp.ToolCalls) == 0 {
+			return ""
+		}
+		return resp.ToolCalls[0].Name + " " + string(resp.ToolCalls[0].Arguments)
+	})
+	report.ToolsSupported = report.ToolCalling.OK && report.ToolCalling.Reply != ""
+	return report
+}
+
+func probe(ctx context.Context, cfg config.Config, client llm.Client, req llm.Request, reply func(llm.Response) string) pingCheck {
+	if cfg.RequestTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, cfg.RequestTimeout)
+		defer cancel()
+	}
+	start := time.Now()
+	resp, err := client.Create(ctx, req)
+	check := pingCheck{LatencyMs: time.Since(start).Milliseconds()}
+	if err != nil {
+		check.Error = err.Error()
+		return check
+	}
+	check.OK = true
+	check.Usage = resp.Usage
+	check.CostUSD = cfg.Pricing.Cost(resp.Usage.PromptTokens, resp.Usage.CompletionTokens)
+	check.Reply = reply(resp)
+	return check
+}
+
+func printPingReport(w io.Writer, report pingReport) {
+	fmt.Fprintf(w, "provider: %s\n", report.BaseURL)
+	fmt.Fprintf(w, "model: %s\n", report.Model)
+	fmt.Fprintf(w, "completion: %s\n", describeCheck(report.Completion))
+	if !report.Completion.OK {
+		return
+	}
+	switch {
+	case !report.ToolCalling.OK:
+		fmt.Fprintf(w, "tool calling: error (%s)\n", report.ToolCalling.Error)
+	case !report.ToolsSupported:
+		fmt.Fprintf(w, "tool calling: not supported (model answered without calling the tool, %dms)\n", report.ToolCalling.LatencyMs)
+	default:
+		fmt.Fprintf(w, "tool calling: supported (%dms, called %s)\n", report.ToolCalling.LatencyMs, report.ToolCalling.Reply)
+	}
+}
+
+func describeCheck(check pingCheck) string {
+	if !check.OK {
+		return fmt.Sprintf("error after %dms (%s)", check.LatencyMs, check.Error)
+	}
+	parts := []string{fmt.Sprintf("%dms", check.LatencyMs)}
+	if tokens := check.Usage.Total(); tokens > 0 {
+		parts = append(parts, fmt.Sprintf("%d tokens", tokens))
+	}
+	if check.CostUSD > 0 {
+		parts = append(parts, fmt.Sprintf("~$%.6f", check.CostUSD))
+	}
+	return fmt.Sprintf("ok (%s) %q", strings.Join(parts, ", "), check.Reply)
+}
+
+// pingTool is the throwaway tool offered to check tool-calling support; it is
+// never executed.
+type pingTool struct{}
+
+func (pingTool) Name() string        { return "echo" }
+func (pingTool) Description() string { return "Echo the given text back." }
+func (pingTool) Schema() map[string]any {
+	return map[string]any{
+		"type":                 "object",
+		"properties":           map[string]any{"text": map[string]any{"type": "string"}},
+		"required":             []string{"text"},
+		"additionalProperties": false,
+	}
+}
+func (pingTool) Execute(ctx context.Context, input json.RawMessage, meta tools.Meta) (tools.Result, error) {
+	return tools.Result{}, errors.New("echo is a probe tool and does not run")
+}
@@ -0,0 +1,25 @@
+package main
+
+import (
+	"context"
+	"testing"
+
+	"fi-cli/internal/config"
+	"fi-cli/internal/llm"
+)
+
+func TestRunPingReportsToolSupport(t *testing.T) {
+	cfg := config.Config{Model: config.DefaultModel}
+	report := runPing(context.Background(), cfg, llm.NewMockClient())
+	if !report.Completion.OK || report.Completion.Reply == "" {
+		t.Fatalf("expected a completion reply, got %+v", report.Completion)
+	}
+	if !report.ToolsSupported {
+		t.Fatalf("expected tool calling support, got %+v", report.ToolCalling)
+	}
+
+	report = runPing(context.Background(), cfg, llm.NewScenarioClient(llm.Scenario{Steps: []llm.ScenarioStep{{Content: "no tools here"}}}))
+	if report.ToolsSupported {
+		t.Fatalf("expected no tool calling support when the model answers in text")
+	}
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"runtime"
+
+	"fi-cli/internal/session"
+
+	"github.com/spf13/cobra"
+)
+
+func newRecordSessionCmd() *cobra.Command {
+	var maxBytes int
+	cmd := &cobra.Command{
+		Use:   "record-session",
+		Short: "Start a shell whose output is recorded as context for later fi-cli runs",
+		Long: "Starts $SHELL under script(1), teeing terminal output to a rotating, redacted log.\n" +
+			"fi-cli runs inside that shell include the tail of the log as recent terminal output.",
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if os.Getenv(session.EnvVar) != "" {
+				return errors.New("already inside a recorded session")
+			}
+			scriptPath, err := exec.LookPath("script")
+			if err != nil {
+				return errors.New("record-session requires script(1) on PATH")
+			}
+			shell := os.Getenv("SHELL")
+			if shell == "" {
+				shell = "/bin/sh"
+			}
+			path, err := session.NewPath(os.Getpid())
+			if err != nil {
+				return err
+			}
+			log, err := session.Open(path, maxBytes)
+			if err != nil {
+				return err
+			}
+
+			reader, writer, err := os.Pipe()
+			if err != nil {
+				_ = log.Close()
+				return err
+			}
+			// script writes its typescript to the pipe, exposed to it as fd 3
+			var scriptArgs []string
+			if runtime.GOOS == "linux" {
+				scriptArgs = []string{"-q", "-f", "-c", shell, "/dev/fd/3"}
+			} else {
+				scriptArgs = []string{"-q", "-F", "/dev/fd/3", shell}
+			}
+			child := exec.Command(scriptPath, scriptArgs...)
+			child.Stdin, child.Stdout, child.Stderr = os.Stdin, os.Stdout, os.Stderr
+			child.ExtraFiles = []*os.File{writer}
+			child.Env = append(os.Environ(), session.EnvVar+"="+path)
+
+			copied := make(chan struct{})
+			go func() {
+				defer close(copied)
+				_, _ = io.Copy(log, reader)
+			}()
+
+			fmt.Fprintf(os.Stderr, "fi-cli: recording session to %s (exit the shell to stop)\n", path)
+			runErr := child.Run()
+			_ = writer.Close()
+			<-copied
+			_ = reader.Close()
+			if err := log.Close(); err != nil {
+				return err
+			}
+			var exitErr *exec.ExitError
+			if errors.As(runErr, &exitErr) {
+				// the shell's own exit status is not a recording failure
+				return nil
+			}
+			return runErr
+		},
+	}
+	cmd.Flags().IntVar(&maxBytes, "max-bytes", session.DefaultMaxBytes, "Rotate the session log after this many bytes")
+	return cmd
+}
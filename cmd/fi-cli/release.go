@@ -0,0 +1,77 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"fi-cli/internal/release"
+	"fi-cli/internal/version"
+
+	"github.com/spf13/cobra"
+)
+
+// newReleaseCmd holds build tooling for maintainers; it is hidden from help.
+func newReleaseCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:    "release",
+		Short:  "Release tooling for maintainers",
+		Hidden: true,
+	}
+	manifest := &cobra.Command{
+		Use:   "manifest",
+		Short: "Print a Homebrew formula or Scoop manifest for the release archives",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			checksums, _ := cmd.Flags().GetString("checksums")
+			urlBase, _ := cmd.Flags().GetString("url-base")
+			format, _ := cmd.Flags().GetString("format")
+			releaseVersion, _ := cmd.Flags().GetString("version")
+			homepage, _ := cmd.Flags().GetString("homepage")
+			if checksums == "" || urlBase == "" {
+				return errors.New("--checksums and --url-base are required")
+			}
+			file, err := os.Open(checksums)
+			if err != nil {
+				return err
+			}
+			defer file.Close()
+			sums, err := release.ParseChecksums(file)
+			if err != nil {
+				return err
+			}
+			artifacts, err := release.Artifacts(releaseVersion, urlBase, sums)
+			if err != nil {
+				return err
+			}
+			m := release.Manifest{
+				Version:     releaseVersion,
+				Description: "Terminal-native agent for repository Q&A",
+				Homepage:    homepage,
+				License:     "MIT",
+				Artifacts:   artifacts,
+			}
+			var out string
+			switch format {
+			case "brew":
+				out, err = release.Brew(m)
+			case "scoop":
+				out, err = release.Scoop(m)
+			default:
+				return fmt.Errorf("invalid --format %q (expected brew or scoop)", format)
+			}
+			if err != nil {
+				return err
+			}
+			fmt.Fprint(os.Stdout, out)
+			return nil
+		},
+	}
+	manifest.Flags().String("checksums", "", "sha256sum output for the release archives")
+	manifest.Flags().String("url-base", "", "Download URL the archives live under; {version} is replaced")
+	manifest.Flags().String("format", "brew", "Manifest to print: brew or scoop")
+	manifest.Flags().String("version", version.Version, "Release version")
+	manifest.Flags().String("homepage", "https://github.com/Ashref-dev/fi.ashref.tn", "Project homepage")
+	cmd.AddCommand(manifest)
+	return cmd
+}
@@ -0,0 +1,151 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"os"
+	"os/signal"
+	"slices"
+	"strings"
+	"syscall"
+
+	"fi-cli/internal/agent"
+	"fi-cli/internal/config"
+	"fi-cli/internal/runs"
+	"fi-cli/internal/util"
+
+	"github.com/spf13/cobra"
+)
+
+func newRunsCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "runs",
+		Short: "Work with past runs",
+	}
+
+	continueCmd := &cobra.Command{
+		Use:   "continue <run-id>",
+		Short: "Resume a run that stopped at the timeout from its checkpoint",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cp, err := runs.LoadCheckpoint(args[0])
+			if err != nil {
+				return err
+			}
+			cfg, err := loadRunConfig(cmd)
+			if err != nil {
+				return err
+			}
+			stopDiagnostics, err := startDiagnostics(cfg.Pprof, cfg.TraceOut)
+			if err != nil {
+				return err
+			}
+			defer stopDiagnostics()
+			cfg.Repo = cp.RepoRoot
+			cfg.Pipeline = cp.Pipeline
+			if !cmd.Flags().Changed("model") {
+				cfg.Model = cp.Model
+			}
+			if !cmd.Flags().Changed("label") && len(cp.Labels) > 0 {
+				cfg.Labels = cp.Labels
+			}
+			apiKey := requireAPIKey(cfg)
+
+			logger := buildLogger(cfg.Verbose)
+			defer func() { _ = logger.Sync() }()
+
+			env := prepareRun(cfg, apiKey, logger)
+			env.resume = &cp
+			release, err := acquireRunLock(env.cfg, env.repoRoot)
+			if err != nil {
+				return err
+			}
+			defer release()
+
+			ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+			defer cancel()
+
+			_, err = runAgent(ctx, logger, env, cp.Question)
+			return err
+		},
+	}
+	addRunFlags(continueCmd)
+
+	listCmd := &cobra.Command{
+		Use:   "list",
+		Short: "List persisted runs, newest first, optionally filtered by label",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			pairs, _ := cmd.Flags().GetStringArray("label")
+			labels, err := config.ParseLabels(pairs)
+			if err != nil {
+				return err
+			}
+			results, err := runs.List(labels)
+			if err != nil {
+				return err
+			}
+			limit, _ := cmd.Flags().GetInt("limit")
+			if limit > 0 && len(results) > limit {
+				results = results[:limit]
+			}
+			out := cmd.OutOrStdout()
+			if len(results) == 0 {
+				fmt.Fprintln(out, "no runs found (runs are saved with persist_runs: true)")
+				return nil
+			}
+			for _, result := range results {
+				fmt.Fprintln(out, runLine(result))
+			}
+			return nil
+		},
+	}
+	listCmd.Flags().StringArray("label", nil, "Only list runs tagged key=value (repeatable; all must match)")
+	listCmd.Flags().Int("limit", 20, "Maximum runs to list (0 for all)")
+
+	timelineCmd := &cobra.Command{
+		Use:   "timeline <run-id>",
+		Short: "Chart where a persisted run spent its time, as Mermaid or HTML",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			format, _ := cmd.Flags().GetString("format")
+			if format != "mermaid" && format != "html" {
+				return fmt.Errorf("invalid --format %q (expected mermaid or html)", format)
+			}
+			result, err := runs.Load(args[0])
+			if err != nil {
+				return err
+			}
+			spans := runs.Timeline(result)
+			if format == "html" {
+				fmt.Fprint(cmd.OutOrStdout(), runs.TimelineHTML(result, spans))
+			} else {
+				fmt.Fprint(cmd.OutOrStdout(), runs.Mermaid(result, spans))
+			}
+			return nil
+		},
+	}
+	timelineCmd.Flags().String("format", "mermaid", "Output format: mermaid or html")
+
+	cmd.AddCommand(continueCmd, listCmd, timelineCmd)
+	return cmd
+}
+
+// runLine summarizes a persisted run on one line for `runs list`.
+func runLine(result agent.RunResult) string {
+	keys := slices.Sorted(maps.Keys(result.Labels))
+	labels := make([]string, 0, len(keys))
+	for _, key := range keys {
+		labels = append(labels, key+"="+result.Labels[key])
+	}
+	question, _, _ := strings.Cut(strings.TrimSpace(result.Question), "\n")
+	if len(question) > 72 {
+		question = util.CutBytes(question, 72) + util.Ellipsis
+	}
+	line := fmt.Sprintf("%s  %s  %-8s", result.RunID, result.StartedAt.Local().Format("2006-01-02 15:04"), result.Status)
+	if len(labels) > 0 {
+		line += "  [" + strings.Join(labels, " ") + "]"
+	}
+	return line + "  " + question
+}
@@ -0,0 +1,210 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
+
+	"fi-cli/internal/agent"
+	"fi-cli/internal/api"
+	"fi-cli/internal/config"
+	"fi-cli/internal/notify"
+	"fi-cli/internal/render"
+	"fi-cli/internal/runs"
+	"fi-cli/internal/schedule"
+	"fi-cli/internal/webhook"
+
+	"github.com/spf13/cobra"
+	"go.uber.org/zap"
+)
+
+func newServeCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "serve",
+		Short: "Run as a daemon that executes scheduled questions and serves the HTTP API",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cfg, err := config.Load(cmd)
+			if err != nil {
+				return err
+			}
+			apiKey := resolveAPIKey(cfg)
+			if apiKey == "" && !mockMode() {
+				return errNoAPIKey()
+			}
+			logger := buildLogger(cfg.Verbose)
+			defer func() { _ = logger.Sync() }()
+
+			jobs, err := scheduledJobs(cfg, apiKey, logger)
+			if err != nil {
+				return err
+			}
+			if len(jobs) == 0 && cfg.Serve.Listen == "" {
+				return errors.New("nothing to serve; add a `schedules` list or `serve.listen` to the config file")
+			}
+			ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+			defer cancel()
+
+			if cfg.Serve.Listen != "" {
+				listener, err := net.Listen("tcp", cfg.Serve.Listen)
+				if err != nil {
+					return fmt.Errorf("serve.listen: %w", err)
+				}
+				server := &http.Server{Handler: apiServer(cfg, apiKey, logger).Handler(), ReadHeaderTimeout: 10 * time.Second}
+				go func() { _ = server.Serve(listener) }()
+				defer func() { _ = server.Close() }()
+				logger.Info("api listening", zap.String("addr", listener.Addr().String()))
+			}
+
+			scheduler := schedule.New(jobs)
+			scheduler.OnSkip = func(name string) {
+				logger.Warn("schedule still running; skipping activation", zap.String("schedule", name))
+			}
+			logger.Info("serve started", zap.Int("schedules", len(jobs)))
+			scheduler.Run(ctx)
+			return nil
+		},
+	}
+	cmd.Flags().Bool("verbose", false, "Enable verbose logging")
+	cmd.Flags().String("listen", "", "Serve the HTTP API on this address (e.g. 127.0.0.1:7777; needs serve.users)")
+	return cmd
+}
+
+// apiServer builds the HTTP API from serve.users. A user whose token variable
+// is unset is logged and cannot authenticate.
+func apiServer(cfg config.Config, apiKey string, logger *zap.Logger) *api.Server {
+	users := make([]api.User, 0, len(cfg.Serve.Users))
+	for _, entry := range cfg.Serve.Users {
+		user := api.User{Name: entry.Name, Token: os.Getenv(entry.TokenEnv)}
+		if user.Token == "" {
+			logger.Warn("serve user has no token; set its token_env", zap.String("user", entry.Name), zap.String("token_env", entry.TokenEnv))
+		}
+		for _, path := range entry.AllowedPaths {
+			if abs, err := filepath.Abs(expandHome(path)); err == nil {
+				user.AllowedPaths = append(user.AllowedPaths, abs)
+			}
+		}
+		users = append(users, user)
+	}
+	run := func(ctx context.Context, user api.User, repoPath string, params api.RunParams, renderer render.Renderer) (agent.RunResult, error) {
+		return runForUser(ctx, cfg, apiKey, logger.With(zap.String("user", user.Name)), user, repoPath, params, renderer)
+	}
+	load := func(user api.User, id string) (agent.RunResult, error) {
+		return runs.LoadFor(user.Name, id)
+	}
+	return api.NewServer(users, run, load)
+}
+
+// runForUser runs an API question with the user's memory and run logs. The
+// daemon's own shell history and terminal are left out of the context: they
+// belong to whoever started it, not to the caller.
+func runForUser(ctx context.Context, cfg config.Config, apiKey string, logger *zap.Logger, user api.User, repoPath string, params api.RunParams, renderer render.Renderer) (agent.RunResult, error) {
+	runCfg := cfg
+	runCfg.JSON = true
+	runCfg.Repo = repoPath
+	runCfg.User = user.Name
+	runCfg.NoHistory = true
+	runCfg.NoSession = true
+	runCfg.CapturePane = false
+	if params.Mode != "" {
+		runCfg.ResponseMode = params.Mode
+	}
+	env := prepareRun(runCfg, apiKey, logger)
+	// the repo root may sit above the requested path, or above an upload's
+	// workspace when the temp directory is inside a checkout
+	switch {
+	case params.Upload && env.repoRoot != repoPath:
+		return agent.RunResult{}, fmt.Errorf("%w: the uploaded workspace %s is inside the repository %s", api.ErrForbidden, repoPath, env.repoRoot)
+	case !params.Upload && !user.Allows(env.repoRoot):
+		return agent.RunResult{}, fmt.Errorf("%w: repository root %s is not in the paths allowed for %s", api.ErrForbidden, env.repoRoot, user.Name)
+	}
+	release, err := acquireRunLock(env.cfg, env.repoRoot)
+	if err != nil {
+		return agent.RunResult{}, err
+	}
+	defer release()
+
+	ag := agent.NewAgent(env.client, env.registry, renderer, logger, env.cfg)
+	ag.AddWarnings(env.warnings...)
+	ag.FollowUp(params.FollowUps...)
+	result, runErr := ag.Run(ctx, params.Question, env.repoRoot, env.repoCtx)
+	if _, err := runs.SaveFor(user.Name, result); err != nil {
+		logger.Warn("failed to write run log", zap.Error(err))
+	}
+	return result, runErr
+}
+
+func scheduledJobs(cfg config.Config, apiKey string, logger *zap.Logger) ([]schedule.Job, error) {
+	jobs := make([]schedule.Job, 0, len(cfg.Schedules))
+	for i, entry := range cfg.Schedules {
+		name := entry.Name
+		if name == "" {
+			name = fmt.Sprintf("schedule-%d", i+1)
+		}
+		if strings.TrimSpace(entry.Question) == "" {
+			return nil, fmt.Errorf("schedule %s: question is required", name)
+		}
+		spec, err := schedule.Parse(entry.Cron)
+		if err != nil {
+			return nil, fmt.Errorf("schedule %s: %w", name, err)
+		}
+		entry := entry
+		jobs = append(jobs, schedule.Job{Name: name, Spec: spec, Run: func(ctx context.Context) {
+			runScheduled(ctx, cfg, apiKey, logger.With(zap.String("schedule", name)), entry)
+		}})
+	}
+	return jobs, nil
+}
+
+func runScheduled(ctx context.Context, cfg config.Config, apiKey string, logger *zap.Logger, entry config.Schedule) {
+	runCfg := cfg
+	runCfg.JSON = true
+	if entry.Repo != "" {
+		runCfg.Repo = expandHome(entry.Repo)
+	}
+	env := prepareRun(runCfg, apiKey, logger)
+	release, err := acquireRunLock(env.cfg, env.repoRoot)
+	if err != nil {
+		logger.Warn("scheduled run skipped", zap.Error(err))
+		return
+	}
+	defer release()
+
+	ag := agent.NewAgent(env.client, env.registry, nil, logger, env.cfg)
+	ag.AddWarnings(env.warnings...)
+	result, runErr := ag.Run(ctx, entry.Question, env.repoRoot, env.repoCtx)
+	if runErr != nil {
+		logger.Warn("scheduled run failed", zap.Error(runErr))
+	}
+	path, err := runs.Save(result)
+	if err != nil {
+		logger.Warn("failed to write run log", zap.Error(err))
+	} else {
+		logger.Info("scheduled run finished", zap.String("run_id", result.RunID), zap.String("status", result.Status), zap.String("path", path))
+	}
+	if entry.Webhook != "" {
+		if err := webhook.Post(ctx, entry.Webhook, result); err != nil {
+			logger.Warn("webhook delivery failed", zap.Error(err))
+		}
+	}
+	for _, err := range notify.Send(ctx, notify.Targets(env.cfg.Notify), notify.Notification{Result: result, ReportPath: path, ReportURL: env.cfg.SlackReportURL}) {
+		logger.Warn("notification failed", zap.String("schedule", entry.Name), zap.Error(err))
+	}
+}
+
+func expandHome(path string) string {
+	if path == "~" || strings.HasPrefix(path, "~/") {
+		if home, err := os.UserHomeDir(); err == nil {
+			return filepath.Join(home, strings.TrimPrefix(path, "~"))
+		}
+	}
+	return path
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"fi-cli/internal/agent"
+	"fi-cli/internal/config"
+	"fi-cli/internal/history"
+	"fi-cli/internal/i18n"
+	"fi-cli/internal/runs"
+	"fi-cli/internal/similar"
+)
+
+// similarEnabled reports whether to look for a similar past question before
+// a run: only when similar_threshold and the question history are on, and a
+// person at a terminal can choose between the old answer and a new run.
+func similarEnabled(cfg config.Config) bool {
+	return cfg.SimilarThreshold > 0 && cfg.QuestionHistory && !cfg.JSON && cfg.JSONStream == "" && isTerminal(os.Stdin) && isTerminal(os.Stdout)
+}
+
+// findSimilar returns the past question asked in repoRoot that is most
+// similar to question, with its run. Only successful runs whose logs can
+// still be loaded count, so the offer always has an answer to show.
+func findSimilar(entries []history.Entry, question, repoRoot string, threshold float64) (history.Entry, agent.RunResult, bool) {
+	var candidates []history.Entry
+	for _, entry := range entries {
+		if entry.Repo == repoRoot && entry.Status == "success" && entry.RunID != "" {
+			candidates = append(candidates, entry)
+		}
+	}
+	questions := make([]string, len(candidates))
+	for i, entry := range candidates {
+		questions[i] = entry.Question
+	}
+	for _, match := range similar.Rank(question, questions, threshold) {
+		entry := candidates[match.Index]
+		if result, err := runs.Load(entry.RunID); err == nil && result.FinalAnswer != "" {
+			return entry, result, true
+		}
+	}
+	return history.Entry{}, agent.RunResult{}, false
+}
+
+// offerSimilar asks on out whether to show the answer to entry instead of
+// running question again. Only "s" or "show" picks the old answer; Enter,
+// anything else, or end of input re-runs.
+func offerSimilar(ctx context.Context, in *bufio.Reader, out io.Writer, entry history.Entry, now time.Time) bool {
+	fmt.Fprintln(out, i18n.Sprintf("a similar question was answered %s: %s", ago(now.Sub(entry.AskedAt)), entry.Question))
+	fmt.Fprint(out, i18n.Sprintf("show it, or re-run? [s/R] "))
+	line, err := readLine(ctx, in, out)
+	if err != nil {
+		return false
+	}
+	switch strings.ToLower(line) {
+	case "s", "show":
+		return true
+	}
+	return false
+}
+
+// ago says how long ago something happened, in the largest whole unit.
+func ago(d time.Duration) string {
+	plural := func(n int, unit string) string {
+		if n == 1 {
+			return "1 " + unit + " ago"
+		}
+		return fmt.Sprintf("%d %ss ago", n, unit)
+	}
+	switch {
+	case d < time.Minute:
+		return "just now"
+	case d < time.Hour:
+		return plural(int(d/time.Minute), "minute")
+	case d < 24*time.Hour:
+		return plural(int(d/time.Hour), "hour")
+	default:
+		return plural(int(d/(24*time.Hour)), "day")
+	}
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"fi-cli/internal/agent"
+	"fi-cli/internal/history"
+	"fi-cli/internal/runs"
+)
+
+func TestFindSimilar(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	if _, err := runs.Save(agent.RunResult{RunID: "run-tests", FinalAnswer: "Run `go test ./...`."}); err != nil {
+		t.Fatalf("save failed: %v", err)
+	}
+	entries := []history.Entry{
+		{Question: "how do I run the tests?", Repo: "/src/app", RunID: "run-tests", Status: "success"},
+		{Question: "How do I run tests", Repo: "/src/app", RunID: "run-missing", Status: "success"},
+		{Question: "How do I run tests", Repo: "/src/app", RunID: "run-failed", Status: "failure"},
+		{Question: "How do I run tests", Repo: "/src/other", RunID: "run-tests", Status: "success"},
+	}
+	entry, result, ok := findSimilar(entries, "how do I run tests?", "/src/app", 0.65)
+	if !ok || entry.RunID != "run-tests" || result.FinalAnswer != "Run `go test ./...`." {
+		t.Fatalf("expected the loadable run in the same repo, got %+v %+v %v", entry, result, ok)
+	}
+	if _, _, ok := findSimilar(entries, "which port does the API use", "/src/app", 0.65); ok {
+		t.Fatalf("expected no match for an unrelated question")
+	}
+}
+
+func TestOfferSimilar(t *testing.T) {
+	now := time.Date(2026, 10, 14, 12, 0, 0, 0, time.UTC)
+	entry := history.Entry{Question: "how do I run the tests?", AskedAt: now.Add(-50 * time.Hour)}
+	var out bytes.Buffer
+	in := bufio.NewReader(strings.NewReader("s\n\n"))
+	if !offerSimilar(context.Background(), in, &out, entry, now) {
+		t.Fatalf("expected s to show the old answer")
+	}
+	if !strings.HasPrefix(out.String(), "a similar question was answered 2 days ago: how do I run the tests?\nshow it, or re-run? [s/R] ") {
+		t.Fatalf("unexpected prompt %q", out.String())
+	}
+	if offerSimilar(context.Background(), in, &out, entry, now) {
+		t.Fatalf("expected Enter to re-run")
+	}
+	if offerSimilar(context.Background(), in, &out, entry, now) {
+		t.Fatalf("expected end of input to re-run")
+	}
+}
+
+func TestAgo(t *testing.T) {
+	for d, want := range map[time.Duration]string{
+		10 * time.Second: "just now",
+		time.Minute:      "1 minute ago",
+		3 * time.Hour:    "3 hours ago",
+		49 * time.Hour:   "2 days ago",
+	} {
+		if got := ago(d); got != want {
+			t.Fatalf("ago(%v) = %q, want %q", d, got, want)
+		}
+	}
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"fi-cli/internal/agent"
+	"fi-cli/internal/config"
+	"fi-cli/internal/render"
+	"fi-cli/internal/rpc"
+
+	"github.com/spf13/cobra"
+)
+
+func newStdioCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "stdio",
+		Short: "Serve JSON-RPC over stdin/stdout for editor integrations",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cfg, err := config.Load(cmd)
+			if err != nil {
+				return err
+			}
+			apiKey := resolveAPIKey(cfg)
+			if apiKey == "" && !mockMode() {
+				return errNoAPIKey()
+			}
+			logger := buildLogger(false)
+			defer func() { _ = logger.Sync() }()
+
+			run := func(ctx context.Context, params rpc.RunParams, renderer render.Renderer) (any, error) {
+				runCfg := cfg
+				runCfg.JSON = false
+				if params.Repo != "" {
+					runCfg.Repo = params.Repo
+				}
+				if params.Mode != "" {
+					runCfg.ResponseMode = params.Mode
+				}
+				env := prepareRun(runCfg, apiKey, logger)
+				release, err := acquireRunLock(env.cfg, env.repoRoot)
+				if err != nil {
+					return nil, err
+				}
+				defer release()
+				ag := agent.NewAgent(env.client, env.registry, renderer, logger, env.cfg)
+				ag.AddWarnings(env.warnings...)
+				result, err := ag.Run(ctx, params.Question, env.repoRoot, env.repoCtx)
+				if env.cfg.PersistRuns {
+					persistRun(logger, result)
+				}
+				return result, err
+			}
+
+			ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+			defer cancel()
+			return rpc.NewServer(os.Stdin, os.Stdout, run).Serve(ctx)
+		},
+	}
+	cmd.Flags().String("repo", ".", "Default repository path")
+	cmd.Flags().String("model", config.DefaultModel, "Model name")
+	return cmd
+}
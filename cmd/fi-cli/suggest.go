@@ -0,0 +1,155 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+
+	"fi-cli/internal/config"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+// maxFlagDistance is how many edits apart a typed flag and a suggestion may be.
+const maxFlagDistance = 2
+
+// commandLike matches a lone argument that looks like a mistyped subcommand
+// rather than a one-word question.
+var commandLike = regexp.MustCompile(`^[a-z][a-z-]*$`)
+
+// installSuggestions makes unknown flags and subcommands fail with hints:
+// close flag names, config settings that have no flag, flags that belong to
+// another command, and close subcommand names.
+func installSuggestions(root *cobra.Command) {
+	root.SetFlagErrorFunc(flagError)
+	var walk func(cmd *cobra.Command)
+	walk = func(cmd *cobra.Command) {
+		// cobra only defaults this inside its own lookup, not SuggestionsFor
+		cmd.SuggestionsMinimumDistance = 2
+		if cmd.HasSubCommands() && !cmd.Runnable() {
+			cmd.Args = cobra.ArbitraryArgs
+			cmd.RunE = unknownSubcommand
+		}
+		for _, child := range cmd.Commands() {
+			walk(child)
+		}
+	}
+	walk(root)
+}
+
+// unknownSubcommand runs for a command group such as `runs`: bare, it shows
+// help; with an argument, it names the subcommands that were probably meant.
+func unknownSubcommand(cmd *cobra.Command, args []string) error {
+	if len(args) == 0 {
+		return cmd.Help()
+	}
+	return fmt.Errorf("unknown command %q for %q%s\nRun '%s --help' for its subcommands.", args[0], cmd.CommandPath(), didYouMean(cmd.SuggestionsFor(args[0])), cmd.CommandPath())
+}
+
+// checkRootArgs rejects a lone word that is close to a subcommand name, like
+// `fi-cli pign`, instead of sending it to the model as a question.
+func checkRootArgs(cmd *cobra.Command, args []string) error {
+	if len(args) != 1 || !commandLike.MatchString(args[0]) {
+		return nil
+	}
+	suggestions := cmd.SuggestionsFor(args[0])
+	if len(suggestions) == 0 {
+		return nil
+	}
+	return fmt.Errorf("unknown command %q for %q%s\nTo ask it as a question, add a word or a question mark: fi-cli \"%s?\"", args[0], cmd.CommandPath(), didYouMean(suggestions), args[0])
+}
+
+func didYouMean(suggestions []string) string {
+	if len(suggestions) == 0 {
+		return ""
+	}
+	return "\n\nDid you mean this?\n\t" + strings.Join(suggestions, "\n\t") + "\n"
+}
+
+// flagError adds hints to pflag's "unknown flag" errors.
+func flagError(cmd *cobra.Command, err error) error {
+	name, ok := strings.CutPrefix(err.Error(), "unknown flag: --")
+	if !ok {
+		return err
+	}
+	var hints []string
+	if near := closeFlags(cmd.Flags(), name); len(near) > 0 {
+		hints = append(hints, "Did you mean "+strings.Join(near, " or ")+"?")
+	}
+	if key := strings.ReplaceAll(name, "-", "_"); slices.Contains(config.Keys(), key) {
+		env := "FICLI_" + strings.ToUpper(strings.ReplaceAll(key, ".", "_"))
+		hints = append(hints, fmt.Sprintf("%s is a config setting, not a flag: set it in %s or with %s.", key, config.PreferredConfigPath(), env))
+	}
+	if owners := flagOwners(cmd.Root(), name, cmd); len(owners) > 0 {
+		hints = append(hints, fmt.Sprintf("--%s is a flag of %s.", name, strings.Join(owners, ", ")))
+	}
+	if len(hints) == 0 {
+		return fmt.Errorf("%w\nRun '%s --help' for its flags.", err, cmd.CommandPath())
+	}
+	return errors.New(err.Error() + "\n" + strings.Join(hints, "\n"))
+}
+
+// closeFlags returns the flags within maxFlagDistance edits of name, or that
+// start with it, closest first.
+func closeFlags(flags *pflag.FlagSet, name string) []string {
+	type candidate struct {
+		name     string
+		distance int
+	}
+	var candidates []candidate
+	flags.VisitAll(func(flag *pflag.Flag) {
+		if flag.Hidden {
+			return
+		}
+		distance := levenshtein(name, flag.Name)
+		if distance <= maxFlagDistance || strings.HasPrefix(flag.Name, name) {
+			candidates = append(candidates, candidate{flag.Name, distance})
+		}
+	})
+	sort.SliceStable(candidates, func(i, j int) bool { return candidates[i].distance < candidates[j].distance })
+	var out []string
+	for _, c := range candidates[:min(len(candidates), 3)] {
+		out = append(out, "--"+c.name)
+	}
+	return out
+}
+
+// flagOwners lists the commands other than current that define name.
+func flagOwners(root *cobra.Command, name string, current *cobra.Command) []string {
+	var owners []string
+	var walk func(cmd *cobra.Command)
+	walk = func(cmd *cobra.Command) {
+		if cmd != current && cmd.LocalFlags().Lookup(name) != nil {
+			owners = append(owners, fmt.Sprintf("%q", cmd.CommandPath()))
+		}
+		for _, child := range cmd.Commands() {
+			walk(child)
+		}
+	}
+	walk(root)
+	return owners
+}
+
+func levenshtein(a, b string) int {
+	prev := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		curr := make([]int, len(b)+1)
+		curr[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			curr[j] = min(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+		}
+		prev = curr
+	}
+	return prev[len(b)]
+}
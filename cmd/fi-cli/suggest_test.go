@@ -0,0 +1,29 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestUnknownFlagAndCommandHints(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	cases := []struct {
+		args []string
+		want string
+	}{
+		{[]string{"--max-step", "3", "why?"}, "Did you mean --max-steps?"},
+		{[]string{"--persist-runs", "why?"}, "persist_runs is a config setting, not a flag"},
+		{[]string{"ping", "--label", "a=b"}, `--label is a flag of "fi-cli"`},
+		{[]string{"runs", "lsit"}, "Did you mean this?\n\tlist"},
+		{[]string{"pign"}, "Did you mean this?\n\tping"},
+	}
+	for _, tc := range cases {
+		root := newRootCmd()
+		root.SetArgs(tc.args)
+		err := root.Execute()
+		if err == nil || !strings.Contains(err.Error(), tc.want) {
+			t.Fatalf("%v: expected %q in the error, got %v", tc.args, tc.want, err)
+		}
+	}
+}
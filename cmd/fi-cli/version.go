@@ -0,0 +1,66 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"runtime"
+
+	"fi-cli/internal/config"
+	"fi-cli/internal/events"
+	"fi-cli/internal/version"
+
+	"github.com/spf13/cobra"
+)
+
+// versionReport is the result of `fi-cli version`. Editor plugins read it to
+// decide which features the installed binary supports.
+type versionReport struct {
+	Version            string `json:"version"`
+	Commit             string `json:"commit,omitempty"`
+	BuildDate          string `json:"build_date,omitempty"`
+	GoVersion          string `json:"go_version"`
+	EventSchemaVersion int    `json:"event_schema_version"`
+	DefaultModel       string `json:"default_model"`
+}
+
+func newVersionCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "version",
+		Short: "Show the version, build, and event schema of this binary",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			report := buildVersionReport()
+			if asJSON, _ := cmd.Flags().GetBool("json"); asJSON {
+				payload, _ := json.MarshalIndent(report, "", "  ")
+				fmt.Fprintln(os.Stdout, string(payload))
+				return nil
+			}
+			fmt.Fprintf(os.Stdout, "fi-cli %s\n", report.Version)
+			if report.Commit != "" {
+				fmt.Fprintf(os.Stdout, "- Commit: %s\n", report.Commit)
+			}
+			if report.BuildDate != "" {
+				fmt.Fprintf(os.Stdout, "- Built: %s\n", report.BuildDate)
+			}
+			fmt.Fprintf(os.Stdout, "- Go: %s\n", report.GoVersion)
+			fmt.Fprintf(os.Stdout, "- Event schema: %d\n", report.EventSchemaVersion)
+			fmt.Fprintf(os.Stdout, "- Default model: %s\n", report.DefaultModel)
+			return nil
+		},
+	}
+	cmd.Flags().Bool("json", false, "Emit the report as JSON")
+	return cmd
+}
+
+func buildVersionReport() versionReport {
+	commit, date := version.Build()
+	return versionReport{
+		Version:            version.Version,
+		Commit:             commit,
+		BuildDate:          date,
+		GoVersion:          runtime.Version(),
+		EventSchemaVersion: events.SchemaVersion,
+		DefaultModel:       config.DefaultModel,
+	}
+}
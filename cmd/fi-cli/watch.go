@@ -0,0 +1,104 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"fi-cli/internal/watch"
+
+	"github.com/spf13/cobra"
+	"go.uber.org/zap"
+)
+
+func newWatchCmd() *cobra.Command {
+	var debounce time.Duration
+	cmd := &cobra.Command{
+		Use:   "watch <question>",
+		Short: "Re-run a question whenever repository files change",
+		Args:  cobra.MinimumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			question := strings.Join(args, " ")
+			cfg, err := loadRunConfig(cmd)
+			if err != nil {
+				return err
+			}
+			stopDiagnostics, err := startDiagnostics(cfg.Pprof, cfg.TraceOut)
+			if err != nil {
+				return err
+			}
+			defer stopDiagnostics()
+			apiKey := requireAPIKey(cfg)
+			logger := buildLogger(cfg.Verbose)
+			defer func() { _ = logger.Sync() }()
+
+			env := prepareRun(cfg, apiKey, logger)
+			release, err := acquireRunLock(env.cfg, env.repoRoot)
+			if err != nil {
+				return err
+			}
+			defer release()
+
+			ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+			defer cancel()
+
+			watcher, err := watch.New(env.repoRoot, debounce)
+			if err != nil {
+				return err
+			}
+			defer watcher.Close()
+			changes := watcher.Changes(ctx)
+
+			for {
+				runCtx, cancelRun := context.WithCancel(ctx)
+				done := make(chan struct{})
+				go func(env runEnv) {
+					defer close(done)
+					if _, err := executeRun(runCtx, logger, env, question); err != nil && runCtx.Err() == nil {
+						fmt.Fprintln(os.Stderr, err)
+					}
+				}(env)
+
+				var batch []string
+				select {
+				case <-ctx.Done():
+					cancelRun()
+					<-done
+					return nil
+				case next, ok := <-changes:
+					if !ok {
+						// the watcher stopped: let the current run finish, then exit
+						<-done
+						cancelRun()
+						return nil
+					}
+					batch = next
+					// a newer change supersedes an in-flight run
+					cancelRun()
+					<-done
+				}
+				if !cfg.JSON {
+					fmt.Fprintf(os.Stdout, "\n--- changed: %s (re-running) ---\n", summarizeChanges(batch))
+				}
+				logger.Debug("files changed", zap.Strings("paths", batch))
+				// rebuild repo context so snippets reflect the edit
+				env = prepareRun(cfg, apiKey, logger)
+			}
+		},
+	}
+	addRunFlags(cmd)
+	cmd.Flags().DurationVar(&debounce, "debounce", 500*time.Millisecond, "Wait for changes to settle before re-running")
+	return cmd
+}
+
+func summarizeChanges(paths []string) string {
+	const shown = 3
+	if len(paths) <= shown {
+		return strings.Join(paths, ", ")
+	}
+	return fmt.Sprintf("%s and %d more", strings.Join(paths[:shown], ", "), len(paths)-shown)
+}
@@ -5,12 +5,16 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"slices"
 	"strings"
 	"time"
 
 	"fi-cli/internal/config"
 	"fi-cli/internal/events"
+	"fi-cli/internal/guard"
+	"fi-cli/internal/hooks"
 	"fi-cli/internal/llm"
+	"fi-cli/internal/policy"
 	"fi-cli/internal/render"
 	"fi-cli/internal/repo"
 	"fi-cli/internal/tools"
@@ -26,27 +30,51 @@ import (
 
 // RunResult captures run output for JSON mode.
 type RunResult struct {
-	RunID       string           `json:"run_id"`
-	StartedAt   time.Time        `json:"timestamp_start"`
-	FinishedAt  time.Time        `json:"timestamp_end"`
-	RepoRoot    string           `json:"repo_root"`
-	Question    string           `json:"question"`
-	Model       string           `json:"model"`
-	StepsUsed   int              `json:"steps_used"`
-	Status      string           `json:"status"`
-	FinalAnswer string           `json:"final_answer"`
-	ToolCalls   []ToolCallRecord `json:"tool_calls"`
-	Events      []events.Event   `json:"events"`
+	RunID       string            `json:"run_id"`
+	StartedAt   time.Time         `json:"timestamp_start"`
+	FinishedAt  time.Time         `json:"timestamp_end"`
+	RepoRoot    string            `json:"repo_root"`
+	Question    string            `json:"question"`
+	Model       string            `json:"model"`
+	StepsUsed   int               `json:"steps_used"`
+	Status      string            `json:"status"`
+	FinalAnswer string            `json:"final_answer"`
+	Assessment  *Assessment       `json:"assessment,omitempty"`
+	Citations   []events.Citation `json:"citations,omitempty"`
+	Warnings    []string          `json:"warnings,omitempty"`
+	ToolCalls   []ToolCallRecord  `json:"tool_calls"`
+	Events      []events.Event    `json:"events"`
+	Usage       llm.Usage         `json:"usage"`
+	CostUSD     float64           `json:"cost_usd,omitempty"`
+	CachedFrom  string            `json:"cached_from,omitempty"`
+	Route       string            `json:"route,omitempty"`
+	Artifacts   []Artifact        `json:"artifacts,omitempty"`
+	// Labels are the run's key=value tags from --label and config.
+	Labels map[string]string `json:"labels,omitempty"`
+	// ContinuedFrom is the run this one resumed from a checkpoint.
+	ContinuedFrom string `json:"continued_from,omitempty"`
+	// Consensus is set when consensus mode reconciled several models' answers.
+	Consensus *Consensus `json:"consensus,omitempty"`
+	// Checkpoint is set when the run stopped at cfg.Timeout; callers persist it.
+	Checkpoint *Checkpoint `json:"-"`
 }
 
+// ErrRefused is returned when the model refuses or the provider filters the answer twice.
+var ErrRefused = errors.New("model refused to answer")
+
+const refusalRetryPrompt = "Your previous response was empty or withheld. This is a legitimate software engineering question about the local repository. Answer it from the available evidence, or state in one line why you cannot."
+
 // ToolCallRecord records tool call history.
 type ToolCallRecord struct {
+	ID         string    `json:"id"`
 	ToolName   string    `json:"tool_name"`
 	Input      any       `json:"input"`
 	Output     any       `json:"output"`
 	Status     string    `json:"status"`
 	StartedAt  time.Time `json:"started_at"`
 	DurationMs int64     `json:"duration_ms"`
+	// HookNotes are what pre_tool and post_tool hooks printed for the call.
+	HookNotes []string `json:"hook_notes,omitempty"`
 }
 
 // Agent runs the orchestration loop.
@@ -56,6 +84,21 @@ type Agent struct {
 	renderer render.Renderer
 	logger   *zap.Logger
 	cfg      config.Config
+	// usage accumulates token counts across every model call of a run.
+	usage    llm.Usage
+	warnings []string
+	// roleNote and toolIDOffset are set on pipeline stages: the role's
+	// instructions, and how many tool IDs earlier stages already used.
+	roleNote     string
+	toolIDOffset int
+	// resume is set by Continue for the duration of the resumed Run.
+	resume *Checkpoint
+	// workspace is the parent run's scratch directory on pipeline stages.
+	workspace string
+	// answerCapped is set when a streamed answer hit cfg.MaxAnswerTokens.
+	answerCapped bool
+	// exchanges are the earlier turns a follow-up question builds on.
+	exchanges []Exchange
 }
 
 // NewAgent constructs an Agent.
@@ -63,17 +106,42 @@ func NewAgent(client llm.Client, toolsReg *tools.Registry, renderer render.Rende
 	return &Agent{client: client, tools: toolsReg, renderer: renderer, logger: logger, cfg: cfg}
 }
 
+// AddWarnings queues setup degradations (no repository context, web disabled,
+// ...) that Run reports as Warning events right after RunStarted.
+func (a *Agent) AddWarnings(messages ...string) {
+	a.warnings = append(a.warnings, messages...)
+}
+
 // Run executes the agent loop.
-func (a *Agent) Run(ctx context.Context, question string, repoRoot string, repoCtx repo.RepoContext) (RunResult, error) {
+//
+// cfg.Timeout bounds planning and the tool loop. The final streamed answer only
+// inherits ctx cancellation and is instead guarded by cfg.IdleTimeout, so an
+// answer that is still producing tokens is not cut off by the run deadline.
+// The fast path's streamed first step is a loop step and keeps the deadline.
+//
+// The result is named so the on_run_end hook's failure, reported after the
+// return statement, still reaches its warnings and events.
+func (a *Agent) Run(ctx context.Context, question string, repoRoot string, repoCtx repo.RepoContext) (result RunResult, err error) {
+	loopCtx := ctx
+	// pause stops the run clock while a tool waits on the user
+	pause := func() func() { return func() {} }
+	if a.cfg.Timeout > 0 {
+		deadline, cancel := withPausableTimeout(ctx, a.cfg.Timeout)
+		defer cancel()
+		loopCtx, pause = deadline, deadline.Pause
+	}
 	started := time.Now()
 	runID := uuid.NewString()
-	result := RunResult{
+	a.usage = llm.Usage{}
+	a.answerCapped = false
+	result = RunResult{
 		RunID:     runID,
 		StartedAt: started,
 		RepoRoot:  repoRoot,
 		Question:  question,
 		Model:     a.cfg.Model,
 		Status:    "failure",
+		Labels:    a.cfg.Labels,
 	}
 
 	emit := func(event events.Event) {
@@ -83,73 +151,194 @@ func (a *Agent) Run(ctx context.Context, question string, repoRoot string, repoC
 		}
 	}
 
-	emit(events.Event{Type: events.RunStarted, Timestamp: time.Now(), Payload: events.RunStartedPayload{
-		Version:   version.Version,
-		RepoRoot:  repoRoot,
-		Model:     a.cfg.Model,
-		RunID:     runID,
-		StartedAt: started,
-	}})
-
-	var plan []string
-	commandIntent := isCommandIntent(question)
-	if !a.cfg.NoPlan {
-		plan = a.generatePlan(ctx, question, repoCtx)
-		emit(events.Event{Type: events.PlanGenerated, Timestamp: time.Now(), Payload: events.PlanGeneratedPayload{Plan: plan}})
+	pathPolicy, err := policy.LoadPathPolicy(repoRoot)
+	if err != nil {
+		emit(events.Event{Type: events.RunError, Timestamp: time.Now(), Payload: events.RunErrorPayload{Message: err.Error()}})
+		result.FinishedAt = time.Now()
+		return result, err
 	}
+	webEnabled := !a.cfg.NoWeb && pathPolicy.WebAllowed()
+	hook := policy.NewHook(a.cfg.PolicyHook, a.cfg.PolicyHookTimeout)
 
-	messages := []openai.ChatCompletionMessageParamUnion{
-		openai.SystemMessage(systemPrompt(a.cfg.ResponseMode)),
-		openai.DeveloperMessage(developerPrompt(a.tools.Names(), !a.cfg.NoWeb, a.cfg.ShellAllowlist, commandIntent)),
-		openai.DeveloperMessage("Repository context:\n" + repoCtx.Summary()),
+	emit(events.Event{Type: events.RunStarted, Timestamp: time.Now(), Payload: events.RunStartedPayload{
+		Version:       version.Version,
+		SchemaVersion: events.SchemaVersion,
+		RepoRoot:      repoRoot,
+		Model:         a.cfg.Model,
+		RunID:         runID,
+		StartedAt:     started,
+	}})
+	warn := func(message string) {
+		result.Warnings = append(result.Warnings, message)
+		emit(events.Event{Type: events.Warning, Timestamp: time.Now(), Payload: events.WarningPayload{Message: message}})
 	}
-	if !a.cfg.NoPlan && len(plan) > 0 {
-		messages = append(messages, openai.DeveloperMessage("Plan:\n"+formatPlan(plan)))
+	for _, message := range a.warnings {
+		warn(message)
 	}
-	if !a.cfg.NoHistory && a.cfg.HistoryLines > 0 {
-		history := util.LoadShellHistory(a.cfg.HistoryLines)
-		if len(history) > 0 {
-			messages = append(messages, openai.DeveloperMessage("Recent shell history (most recent last):\n- "+strings.Join(history, "\n- ")))
+	if command := a.cfg.Hooks.OnRunStart; command != "" {
+		if _, err := hooks.Run(ctx, command, result, a.cfg.Hooks.Timeout); err != nil {
+			warn(fmt.Sprintf("on_run_start hook failed: %v", err))
 		}
 	}
-	messages = append(messages, openai.UserMessage(question))
+	if command := a.cfg.Hooks.OnRunEnd; command != "" {
+		// runs after every return below, with the result as returned
+		defer func() {
+			// a cancelled run is still reported, within the hook timeout
+			if _, err := hooks.Run(context.WithoutCancel(ctx), command, result, a.cfg.Hooks.Timeout); err != nil {
+				message := fmt.Sprintf("on_run_end hook failed: %v", err)
+				a.logger.Warn(message)
+				warn(message)
+			}
+		}()
+	}
+	workspace, cleanup := a.openWorkspace(runID, warn)
+	defer cleanup()
 
-	toolsDefs := a.tools.OpenAITools()
+	var state loopState
+	if a.resume != nil {
+		state = a.resumeState(*a.resume, &result, emit)
+	} else {
+		state = a.startState(loopCtx, question, repoRoot, repoCtx, webEnabled, workspace, &result, emit, warn)
+	}
+	messages := state.messages
+	registry := state.registry
+	fastPath := state.fastPath
+
+	toolsDefs := registry.OpenAITools()
 	toolChoice := openai.ChatCompletionToolChoiceOptionUnionParam{}
 	if len(toolsDefs) > 0 {
 		toolChoice = openai.ChatCompletionToolChoiceOptionUnionParam{OfAuto: param.NewOpt("auto")}
 	}
 
-	steps := 0
-	toolUsage := map[string]int{}
+	steps := state.steps
+	toolUsage := state.toolUsage
+	var touched touchedSet
+	var repeats repeatTracker
+	retriedRefusal := false
+	recovered := map[string]bool{}
+	failures := toolFailures{}
+	finish := func(status string, answer string) {
+		result.FinalAnswer = strings.TrimSpace(answer)
+		result.Status = status
+		result.StepsUsed = steps
+		for _, artifact := range result.Artifacts {
+			result.StepsUsed += artifact.StepsUsed
+		}
+		result.Citations = linkCitations(result.FinalAnswer, result.ToolCalls)
+		emit(events.Event{Type: events.FinalAnswerReady, Timestamp: time.Now(), Payload: events.FinalAnswerPayload{Answer: result.FinalAnswer, Citations: result.Citations}})
+		if a.answerCapped {
+			warn(fmt.Sprintf("answer stopped at max_answer_tokens (%d); raise max_answer_tokens or ask a narrower question", a.cfg.MaxAnswerTokens))
+		}
+		if a.cfg.SelfAssess {
+			assessment := a.assess(ctx, messages, result.FinalAnswer)
+			result.Assessment = &assessment
+			emit(events.Event{Type: events.AssessmentReady, Timestamp: time.Now(), Payload: events.AssessmentPayload{
+				Confidence:       assessment.Confidence,
+				EvidenceCoverage: assessment.EvidenceCoverage,
+				UnverifiedClaims: assessment.UnverifiedClaims,
+			}})
+		}
+		result.FinishedAt = time.Now()
+		result.Usage = a.usage
+		result.CostUSD = a.cfg.Pricing.Cost(a.usage.PromptTokens, a.usage.CompletionTokens)
+		emit(events.Event{Type: events.RunFinished, Timestamp: time.Now(), Payload: events.RunFinishedPayload{
+			Status:           result.Status,
+			FinishedAt:       result.FinishedAt,
+			StepsUsed:        result.StepsUsed,
+			ToolCalls:        toolCallCounts(result.ToolCalls),
+			DurationMs:       result.FinishedAt.Sub(started).Milliseconds(),
+			PromptTokens:     result.Usage.PromptTokens,
+			CompletionTokens: result.Usage.CompletionTokens,
+			CostUSD:          result.CostUSD,
+		}})
+	}
 	for steps < a.cfg.MaxSteps {
 		steps++
-		response, err := a.client.Create(ctx, llm.Request{Model: a.cfg.Model, Messages: messages, Tools: toolsDefs, ToolChoice: toolChoice})
+		if paths := touched.drain(); len(paths) > 0 {
+			messages = append(messages, openai.DeveloperMessage(a.refreshMessage(repoRoot, paths)))
+		}
+		req := llm.Request{Model: a.cfg.Model, Messages: messages, Tools: toolsDefs, ToolChoice: toolChoice, MaxTokens: maxTokensFor(a.cfg.Verbosity)}
+		var response llm.Response
+		var streamed string
+		streamedStep := fastPath && steps == 1 && !a.cfg.JSON
+		if streamedStep {
+			// a loop step like any other, so the run deadline bounds it
+			response, streamed, err = a.streamResumable(loopCtx, req, emit)
+		} else {
+			response, err = a.create(loopCtx, req)
+		}
 		if err != nil {
 			a.logger.Error("model request failed", zap.Error(err))
-			emit(events.Event{Type: events.RunError, Timestamp: time.Now(), Payload: events.RunErrorPayload{Message: err.Error()}})
+			if errors.Is(loopCtx.Err(), context.DeadlineExceeded) && ctx.Err() == nil {
+				// the step that timed out is retried on continue
+				result.Checkpoint = a.checkpoint(result, steps-1, messages, toolUsage)
+				steps--
+				warn(fmt.Sprintf("run timeout (%s) reached after %d steps; answering from the evidence gathered so far", a.cfg.Timeout, steps))
+				finish("partial", a.timeoutAnswer(ctx, messages, toolsDefs, emit))
+				return result, fmt.Errorf("%w after %s", ErrTimedOut, a.cfg.Timeout)
+			}
+			kind, recovery := classifyModelError(err)
+			if ctx.Err() != nil {
+				kind, recovery = events.ErrorKindCancelled, ""
+			}
+			if recovery == recoverShrinkContext && !shrinkContext(messages) {
+				recovery = recoverRetryStep
+			}
+			// a partly streamed step cannot be replayed without repeating output
+			if recovery != "" && !recovered[recovery] && streamed == "" {
+				recovered[recovery] = true
+				emit(events.Event{Type: events.RunError, Timestamp: time.Now(), Payload: events.RunErrorPayload{Message: err.Error(), Kind: kind, Severity: events.SeverityRecoverable, Recovery: recovery}})
+				steps--
+				if delay := rateLimitDelay(err); recovery == recoverRetryStep && delay > 0 {
+					// a rate limit retried at once is refused again
+					select {
+					case <-time.After(delay):
+					case <-loopCtx.Done():
+					}
+				}
+				continue
+			}
+			emit(events.Event{Type: events.RunError, Timestamp: time.Now(), Payload: events.RunErrorPayload{Message: err.Error(), Kind: kind, Severity: events.SeverityFatal}})
 			result.Status = "failure"
 			result.StepsUsed = steps
 			result.FinishedAt = time.Now()
 			return result, err
 		}
 
+		if response.Refused() || response.Empty() {
+			a.logger.Warn("model returned refusal or empty response", zap.String("finish_reason", response.FinishReason))
+			if !retriedRefusal {
+				retriedRefusal = true
+				messages = append(messages, openai.DeveloperMessage(refusalRetryPrompt))
+				continue
+			}
+			reason := "The model returned an empty response."
+			if response.Refused() {
+				reason = "The model declined to answer or the provider filtered the response."
+				if refusal := strings.TrimSpace(response.Refusal); refusal != "" {
+					reason += " " + refusal
+				}
+			}
+			finish("refused", reason)
+			return result, ErrRefused
+		}
+
 		if len(response.ToolCalls) == 0 {
 			finalAnswer := strings.TrimSpace(response.Content)
-			if !a.cfg.JSON {
-				streamed, err := a.streamFinal(ctx, llm.Request{Model: a.cfg.Model, Messages: messages, Tools: toolsDefs, ToolChoice: toolChoice}, emit)
+			if a.consensusEnabled() {
+				// the judge's answer cannot stream: it arrives as a function call
+				finalAnswer, result.Consensus = a.consensus(ctx, messages, toolsDefs, finalAnswer, warn)
+			} else if streamedStep {
+				finalAnswer = streamed
+			} else if !a.cfg.JSON {
+				streamed, err := a.streamFinal(ctx, llm.Request{Model: a.cfg.Model, Messages: messages, Tools: toolsDefs, ToolChoice: toolChoice, MaxTokens: maxTokensFor(a.cfg.Verbosity)}, emit)
 				if err != nil {
 					a.logger.Error("streaming failed", zap.Error(err))
 				} else if strings.TrimSpace(streamed) != "" {
 					finalAnswer = streamed
 				}
 			}
-			result.FinalAnswer = strings.TrimSpace(finalAnswer)
-			result.Status = "success"
-			result.StepsUsed = steps
-			result.FinishedAt = time.Now()
-			emit(events.Event{Type: events.FinalAnswerReady, Timestamp: time.Now(), Payload: events.FinalAnswerPayload{Answer: result.FinalAnswer}})
-			emit(events.Event{Type: events.RunFinished, Timestamp: time.Now(), Payload: events.RunFinishedPayload{Status: result.Status, FinishedAt: result.FinishedAt}})
+			finish("success", finalAnswer)
 			return result, nil
 		}
 
@@ -168,60 +357,133 @@ func (a *Agent) Run(ctx context.Context, question string, repoRoot string, repoC
 			})
 		}
 		assistant := openai.ChatCompletionAssistantMessageParam{ToolCalls: toolCallParams}
+		if content := strings.TrimSpace(response.Content); content != "" {
+			assistant.Content = openai.ChatCompletionAssistantMessageParamContentUnion{OfString: param.NewOpt(content)}
+		}
 		messages = append(messages, openai.ChatCompletionMessageParamUnion{OfAssistant: &assistant})
 
+		// notes about dropped tools, hooks, and repeated calls follow the tool
+		// results they explain
+		var dropped, hookNotes []string
 		for _, call := range response.ToolCalls {
-			if !a.withinToolBudget(call.Name, toolUsage) {
-				err := fmt.Errorf("tool call limit reached for %s", call.Name)
-				payload := map[string]any{"error": err.Error(), "duration_ms": 0}
-				record := ToolCallRecord{ToolName: call.Name, Input: sanitizeInput(call.Arguments), Output: payload, Status: "error", StartedAt: time.Now(), DurationMs: 0}
+			id := toolResultID(a.toolIDOffset + len(result.ToolCalls))
+			if loopCtx.Err() != nil {
+				// past the run deadline: answer the remaining calls without running them
+				payload := a.skipToolCall(&result, emit, id, call, errors.New("skipped: run timeout reached"))
+				messages = append(messages, openai.ToolMessage(toolResultMessage(id, a.guardToolOutput(loopCtx, call.Name, payload)), call.ID))
+				continue
+			}
+			if err := a.checkToolBudget(call.Name, toolUsage); err != nil {
+				payload := map[string]any{"error": err.Error(), "budget_exhausted": true, "duration_ms": 0}
+				record := ToolCallRecord{ID: id, ToolName: call.Name, Input: sanitizeInput(call.Arguments), Output: payload, Status: "error", StartedAt: time.Now(), DurationMs: 0}
 				result.ToolCalls = append(result.ToolCalls, record)
-				emit(events.Event{Type: events.ToolCallFailed, Timestamp: time.Now(), Payload: events.ToolCallFinishedPayload{ToolName: call.Name, Status: "error", Preview: err.Error(), DurationMs: 0, LineCount: 1, ByteCount: len(err.Error()), Truncated: false}})
+				emit(events.Event{Type: events.ToolCallFailed, Timestamp: time.Now(), Payload: events.ToolCallFinishedPayload{ID: id, ToolName: call.Name, Status: "error", Preview: err.Error(), DurationMs: 0, LineCount: 1, ByteCount: len(err.Error()), Truncated: false}})
 				payloadBytes, _ := json.Marshal(payload)
-				messages = append(messages, openai.ToolMessage(string(payloadBytes), call.ID))
+				messages = append(messages, openai.ToolMessage(toolResultMessage(id, string(payloadBytes)), call.ID))
 				continue
 			}
 
-			tool, ok := a.tools.Get(call.Name)
+			tool, ok := registry.Get(call.Name)
 			if !ok {
-				err := fmt.Errorf("unknown tool: %s", call.Name)
-				emit(events.Event{Type: events.ToolCallFailed, Timestamp: time.Now(), Payload: events.ToolCallFinishedPayload{ToolName: call.Name, Status: "error", Preview: err.Error(), DurationMs: 0, LineCount: 1, ByteCount: len(err.Error())}})
-				payloadBytes, _ := json.Marshal(map[string]string{"error": err.Error()})
-				messages = append(messages, openai.ToolMessage(string(payloadBytes), call.ID))
+				// the name comes from the model, so the error is guarded like output
+				payload := a.skipToolCall(&result, emit, id, call, fmt.Errorf("unknown tool: %s", call.Name))
+				messages = append(messages, openai.ToolMessage(toolResultMessage(id, a.guardToolOutput(loopCtx, call.Name, payload)), call.ID))
 				continue
 			}
 			inputSanitized := sanitizeInput(call.Arguments)
 			start := time.Now()
-			emit(events.Event{Type: events.ToolCallStarted, Timestamp: start, Payload: events.ToolCallStartedPayload{ToolName: call.Name, Input: inputSanitized, StartedAt: start}})
+			emit(events.Event{Type: events.ToolCallStarted, Timestamp: start, Payload: events.ToolCallStartedPayload{ID: id, ToolName: call.Name, Input: inputSanitized, StartedAt: start}})
 
-			meta := tools.Meta{RepoRoot: repoRoot, UnsafeShell: a.cfg.UnsafeShell, ToolTimeoutSeconds: 10}
+			meta := tools.Meta{RepoRoot: repoRoot, UnsafeShell: a.cfg.UnsafeShell, ToolTimeoutSeconds: 10, Policy: pathPolicy, Workspace: workspace, PreviewLines: a.cfg.Render.PreviewLines, PreviewBytes: a.cfg.Render.PreviewBytes}
+			var policyErr error
 			switch call.Name {
 			case "grep":
 				meta.MaxResults = a.cfg.ToolLimits.GrepMaxResults
 				meta.MaxBytes = a.cfg.ToolLimits.GrepMaxBytes
+			case "list_files", "archive_list":
+				meta.MaxResults = a.cfg.ToolLimits.ListMaxEntries
+			case "read_file", "archive_read", "data_preview", "dep_docs":
+				meta.MaxBytes = a.cfg.ToolLimits.MaxFileBytes
+			case "env_usage":
+				meta.MaxResults = a.cfg.ToolLimits.ListMaxEntries
+			case "api_schema":
+				meta.MaxResults = a.cfg.ToolLimits.ListMaxEntries
+				meta.MaxBytes = a.cfg.ToolLimits.MaxFileBytes
 			case "shell":
 				meta.MaxBytes = a.cfg.ToolLimits.ShellMaxBytes
-			case "exa_search":
+			case "exa_search", "exa_contents", "code_search", "issue_lookup":
 				meta.MaxBytes = a.cfg.ToolLimits.WebMaxBytes
+				if !pathPolicy.WebAllowed() {
+					policyErr = fmt.Errorf("web access is disabled by %s", policy.RepoPolicyFile)
+				}
+			}
+			if policyErr == nil && hook != nil {
+				if decision := hook.Evaluate(loopCtx, a.hookInput(call.Name, call.Arguments, repoRoot)); !decision.Allow {
+					policyErr = hookDenial(decision)
+				}
+			}
+			var notes []string
+			if policyErr == nil && len(a.cfg.Hooks.PreTool) > 0 {
+				verdict := hooks.RunTool(loopCtx, a.cfg.Hooks.PreTool, hooks.ToolCall{Hook: "pre_tool", Tool: call.Name, Arguments: hookArguments(call.Arguments), Repo: repoRoot, RunID: result.RunID}, a.cfg.Hooks.Timeout)
+				notes = verdict.Notes
+				if verdict.Blocked {
+					policyErr = fmt.Errorf("blocked by a pre_tool hook: %s", verdict.Reason)
+				}
 			}
 
-			res, err := tool.Execute(ctx, call.Arguments, meta)
+			var res tools.Result
+			err := policyErr
+			if err == nil {
+				resume := func() {}
+				if call.Name == "ask_user" {
+					resume = pause()
+				}
+				res, err = tool.Execute(loopCtx, call.Arguments, meta)
+				resume()
+			}
+			if err == nil && len(a.cfg.Hooks.PostTool) > 0 {
+				verdict := hooks.RunTool(loopCtx, a.cfg.Hooks.PostTool, hooks.ToolCall{Hook: "post_tool", Tool: call.Name, Arguments: hookArguments(call.Arguments), Repo: repoRoot, RunID: result.RunID, Status: "success", Output: res.Payload}, a.cfg.Hooks.Timeout)
+				notes = append(notes, verdict.Notes...)
+				switch {
+				case verdict.Blocked:
+					policyErr = fmt.Errorf("result withheld by a post_tool hook: %s", verdict.Reason)
+					err = policyErr
+				case verdict.Output != nil:
+					res.Payload = verdict.Output
+					res.Preview = meta.Preview(string(verdict.Output))
+				}
+			}
+			for _, note := range notes {
+				hookNotes = append(hookNotes, fmt.Sprintf("Hook note on %s (%s): %s", id, call.Name, note))
+			}
 			toolUsage[call.Name]++
 			duration := time.Since(start).Milliseconds()
+			drop := failures.record(call.Name, err != nil)
 			if err != nil {
 				payload := map[string]any{"error": err.Error(), "duration_ms": duration}
-				record := ToolCallRecord{ToolName: call.Name, Input: inputSanitized, Output: payload, Status: "error", StartedAt: start, DurationMs: duration}
+				record := ToolCallRecord{ID: id, ToolName: call.Name, Input: inputSanitized, Output: payload, Status: "error", StartedAt: start, DurationMs: duration, HookNotes: notes}
 				result.ToolCalls = append(result.ToolCalls, record)
-				emit(events.Event{Type: events.ToolCallFailed, Timestamp: time.Now(), Payload: events.ToolCallFinishedPayload{ToolName: call.Name, Status: "error", Preview: err.Error(), DurationMs: duration, LineCount: 1, ByteCount: len(err.Error()), Truncated: false}})
+				emit(events.Event{Type: events.ToolCallFailed, Timestamp: time.Now(), Payload: events.ToolCallFinishedPayload{ID: id, ToolName: call.Name, Status: "error", Preview: err.Error(), DurationMs: duration, LineCount: 1, ByteCount: len(err.Error()), Truncated: false}})
 				payloadBytes, _ := json.Marshal(payload)
-				messages = append(messages, openai.ToolMessage(string(payloadBytes), call.ID))
+				messages = append(messages, openai.ToolMessage(toolResultMessage(id, string(payloadBytes)), call.ID))
+				if drop {
+					registry = registry.Without(call.Name)
+					toolsDefs = registry.OpenAITools()
+					if len(toolsDefs) == 0 {
+						toolChoice = openai.ChatCompletionToolChoiceOptionUnionParam{}
+					}
+					emit(events.Event{Type: events.RunError, Timestamp: time.Now(), Payload: events.RunErrorPayload{Message: err.Error(), Kind: events.ErrorKindTool, Severity: events.SeverityRecoverable, Recovery: recoverDropTool}})
+					dropped = append(dropped, droppedToolMessage(call.Name, err))
+				}
 				continue
 			}
 			res.DurationMs = duration
-			record := ToolCallRecord{ToolName: call.Name, Input: inputSanitized, Output: res.Payload, Status: "success", StartedAt: start, DurationMs: duration}
+			touched.add(repoRoot, res.TouchedFiles)
+			record := ToolCallRecord{ID: id, ToolName: call.Name, Input: inputSanitized, Output: res.Payload, Status: "success", StartedAt: start, DurationMs: duration, HookNotes: notes}
 			result.ToolCalls = append(result.ToolCalls, record)
 
-			emit(events.Event{Type: events.ToolCallFinished, Timestamp: time.Now(), Payload: events.ToolCallFinishedPayload{
+			finished := events.ToolCallFinishedPayload{
+				ID:         id,
 				ToolName:   call.Name,
 				Status:     "success",
 				Output:     res.Payload,
@@ -230,10 +492,21 @@ func (a *Agent) Run(ctx context.Context, question string, repoRoot string, repoC
 				ByteCount:  res.ByteCount,
 				Truncated:  res.Truncated,
 				DurationMs: duration,
-			}})
+			}
+			// the diff quotes tool output, so it travels with the result
+			var repeatNote []string
+			if diff, repeated := repeats.observe(call.Name, call.Arguments, id, res.Payload); repeated {
+				finished.RepeatOf = diff.repeatOf
+				finished.Diff = diff.summary()
+				repeatNote = append(repeatNote, diff.message(id, call.Name))
+			}
+			emit(events.Event{Type: events.ToolCallFinished, Timestamp: time.Now(), Payload: finished})
 
 			payloadBytes, _ := json.Marshal(res.Payload)
-			messages = append(messages, openai.ToolMessage(string(payloadBytes), call.ID))
+			messages = append(messages, openai.ToolMessage(toolResultMessage(id, a.guardToolOutput(loopCtx, call.Name, string(payloadBytes), repeatNote...)), call.ID))
+		}
+		for _, note := range slices.Concat(dropped, hookNotes) {
+			messages = append(messages, openai.DeveloperMessage(note))
 		}
 	}
 
@@ -242,7 +515,7 @@ func (a *Agent) Run(ctx context.Context, question string, repoRoot string, repoC
 	messages = append(messages, openai.DeveloperMessage(warning))
 	finalAnswer := "Max steps reached; unable to complete."
 	if !a.cfg.JSON {
-		streamed, err := a.streamFinal(ctx, llm.Request{Model: a.cfg.Model, Messages: messages, Tools: toolsDefs, ToolChoice: toolChoice}, emit)
+		streamed, err := a.streamFinal(ctx, llm.Request{Model: a.cfg.Model, Messages: messages, Tools: toolsDefs, ToolChoice: toolChoice, MaxTokens: maxTokensFor(a.cfg.Verbosity)}, emit)
 		if err == nil && strings.TrimSpace(streamed) != "" {
 			finalAnswer = streamed
 		}
@@ -250,82 +523,117 @@ func (a *Agent) Run(ctx context.Context, question string, repoRoot string, repoC
 	if !strings.Contains(strings.ToLower(finalAnswer), "max steps") {
 		finalAnswer = "Max steps reached. " + finalAnswer
 	}
-	result.FinalAnswer = strings.TrimSpace(finalAnswer)
-	result.Status = "partial"
-	result.StepsUsed = steps
-	result.FinishedAt = time.Now()
-	emit(events.Event{Type: events.FinalAnswerReady, Timestamp: time.Now(), Payload: events.FinalAnswerPayload{Answer: result.FinalAnswer}})
-	emit(events.Event{Type: events.RunFinished, Timestamp: time.Now(), Payload: events.RunFinishedPayload{Status: result.Status, FinishedAt: result.FinishedAt}})
+	finish("partial", finalAnswer)
 	return result, errors.New("max steps reached")
 }
 
-func (a *Agent) generatePlan(ctx context.Context, question string, repoCtx repo.RepoContext) []string {
-	messages := []openai.ChatCompletionMessageParamUnion{
-		openai.SystemMessage(systemPrompt(a.cfg.ResponseMode)),
-		openai.DeveloperMessage(planPrompt()),
-		openai.DeveloperMessage("Repository context:\n" + repoCtx.Summary()),
-		openai.UserMessage(question),
+// loopState is what the tool loop starts from: built fresh for a new run, or
+// restored from a checkpoint by Continue.
+type loopState struct {
+	messages  []openai.ChatCompletionMessageParamUnion
+	registry  *tools.Registry
+	fastPath  bool
+	steps     int
+	toolUsage map[string]int
+}
+
+// startState routes the question, runs pipeline stages and the plan, and
+// builds the initial messages of a new run.
+// skipToolCall records a call answered with err instead of being run, and
+// returns the JSON payload for its tool message.
+func (a *Agent) skipToolCall(result *RunResult, emit func(events.Event), id string, call llm.ToolCall, err error) string {
+	payload := map[string]any{"error": err.Error(), "duration_ms": 0}
+	record := ToolCallRecord{ID: id, ToolName: call.Name, Input: sanitizeInput(call.Arguments), Output: payload, Status: "error", StartedAt: time.Now(), DurationMs: 0}
+	result.ToolCalls = append(result.ToolCalls, record)
+	emit(events.Event{Type: events.ToolCallFailed, Timestamp: time.Now(), Payload: events.ToolCallFinishedPayload{ID: id, ToolName: call.Name, Status: "error", Preview: err.Error(), DurationMs: 0, LineCount: 1, ByteCount: len(err.Error()), Truncated: false}})
+	payloadBytes, _ := json.Marshal(payload)
+	return string(payloadBytes)
+}
+
+func (a *Agent) startState(loopCtx context.Context, question string, repoRoot string, repoCtx repo.RepoContext, webEnabled bool, workspace string, result *RunResult, emit func(events.Event), warn func(string)) loopState {
+	route, routeSource := a.routeQuestion(loopCtx, question)
+	profile := routeProfiles[route]
+	registry := a.tools.Without(profile.hide...)
+	if a.cfg.Pipeline {
+		// the writer composes from the stage artifacts and has no tools
+		registry = tools.NewRegistry()
 	}
-	resp, err := a.client.Create(ctx, llm.Request{Model: a.cfg.Model, Messages: messages})
-	if err != nil {
-		return []string{"Review repository context", "Run focused searches", "Summarize evidence with citations"}
+	result.Route = route
+	emit(events.Event{Type: events.QuestionRouted, Timestamp: time.Now(), Payload: events.QuestionRoutedPayload{Route: route, Source: routeSource, Tools: registry.Names()}})
+
+	if a.cfg.Pipeline {
+		artifacts, records := a.runStages(loopCtx, question, repoRoot, repoCtx, workspace, emit)
+		result.Artifacts = artifacts
+		result.ToolCalls = append(result.ToolCalls, records...)
 	}
-	return parsePlan(resp.Content)
-}
 
-func parsePlan(text string) []string {
-	var plan []string
-	for _, line := range strings.Split(text, "\n") {
-		line = strings.TrimSpace(line)
-		line = strings.TrimLeft(line, "-*")
-		line = strings.TrimSpace(line)
-		if line == "" {
-			continue
-		}
-		if len(plan) < 8 {
-			plan = append(plan, line)
-		}
+	var plan []events.PlanStep
+	commandIntent := isCommandIntent(question)
+	// The fast path skips the plan call and streams the first step, so a
+	// question the model can answer without tools costs one round trip.
+	fastPath := a.cfg.Pipeline || a.cfg.FastPath && (isSimpleQuestion(question) || route == config.RouteLookup)
+	if a.consensusEnabled() {
+		// consensus answers come from the tool loop's final, non-streamed step
+		fastPath = false
 	}
-	if len(plan) < 3 {
-		plan = append(plan, "Review repository context", "Run targeted tool calls", "Produce cited answer")
+	if !a.cfg.NoPlan && !fastPath && !profile.skipPlan {
+		plan = a.generatePlan(loopCtx, question, repoCtx, registry.Names())
+		emit(events.Event{Type: events.PlanGenerated, Timestamp: time.Now(), Payload: events.PlanGeneratedPayload{Plan: planTitles(plan), Steps: plan}})
 	}
-	return plan
-}
 
-func formatPlan(plan []string) string {
-	var b strings.Builder
-	for _, item := range plan {
-		b.WriteString("- ")
-		b.WriteString(item)
-		b.WriteString("\n")
+	messages := []openai.ChatCompletionMessageParamUnion{
+		openai.SystemMessage(systemPrompt(a.cfg.ResponseMode, a.cfg.AnswerLanguage, a.cfg.Verbosity, a.cfg.OutputFormat)),
+		openai.DeveloperMessage(developerPrompt(registry.Names(), webEnabled && registry.Has("exa_search"), a.cfg.ShellAllowlist, commandIntent, tools.DetectCapabilities()) + routeNote(profile) + rolePrompt(a.roleNote) + conventionsPrompt(a.cfg, loadConventions(a.cfg, repoRoot))),
+		openai.DeveloperMessage(repoContextMessage(a.cfg, repoCtx)),
+	}
+	if facts := loadGlossary(a.cfg, repoRoot); len(facts) > 0 {
+		messages = append(messages, openai.DeveloperMessage(glossaryMessage(a.cfg, facts)))
 	}
-	return strings.TrimSpace(b.String())
+	if a.cfg.InjectionGuard.Mode != guard.ModeOff {
+		messages = append(messages, openai.DeveloperMessage(untrustedOutputPrompt))
+	}
+	if len(plan) > 0 {
+		messages = append(messages, openai.DeveloperMessage("Plan:\n"+formatPlan(plan)))
+	}
+	if history := LoadHistory(a.cfg, repoRoot); len(history) > 0 {
+		messages = append(messages, openai.DeveloperMessage(historyMessage(history)))
+	}
+	if source, text, err := LoadTerminalOutput(loopCtx, a.cfg); err != nil {
+		a.logger.Warn("terminal output unavailable", zap.Error(err))
+		warn("terminal output unavailable: " + err.Error())
+	} else if text != "" {
+		messages = append(messages, openai.DeveloperMessage(terminalMessage(source, text)))
+	}
+	if a.cfg.Pipeline {
+		messages = append(messages, openai.DeveloperMessage(writerNote+"\n\n"+artifactsMessage(result.Artifacts)))
+	}
+	messages = append(messages, a.exchangeMessages()...)
+	messages = append(messages, openai.UserMessage(question))
+
+	return loopState{messages: messages, registry: registry, fastPath: fastPath, toolUsage: map[string]int{}}
 }
 
-func (a *Agent) streamFinal(ctx context.Context, req llm.Request, emit func(events.Event)) (string, error) {
-	var builder strings.Builder
-	_, err := a.client.Stream(ctx, req, func(delta string) {
-		emit(events.Event{Type: events.ModelDelta, Timestamp: time.Now(), Payload: events.ModelDeltaPayload{Delta: delta}})
-		builder.WriteString(delta)
-	})
-	if err != nil {
-		return builder.String(), err
+// create issues a non-streaming request bounded by cfg.RequestTimeout.
+func (a *Agent) create(ctx context.Context, req llm.Request) (llm.Response, error) {
+	if a.cfg.RequestTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, a.cfg.RequestTimeout)
+		defer cancel()
 	}
-	return builder.String(), nil
+	resp, err := a.client.Create(ctx, req)
+	a.usage.Add(resp.Usage)
+	return resp, err
 }
 
-func (a *Agent) withinToolBudget(toolName string, usage map[string]int) bool {
-	current := usage[toolName]
-	switch toolName {
-	case "grep":
-		return current < a.cfg.ToolLimits.GrepMaxCalls
-	case "shell":
-		return current < a.cfg.ToolLimits.ShellMaxCalls
-	case "exa_search":
-		return current < a.cfg.ToolLimits.WebMaxCalls
-	default:
-		return true
+func toolCallCounts(records []ToolCallRecord) map[string]int {
+	if len(records) == 0 {
+		return nil
+	}
+	counts := map[string]int{}
+	for _, record := range records {
+		counts[record.ToolName]++
 	}
+	return counts
 }
 
 func sanitizeInput(args json.RawMessage) any {
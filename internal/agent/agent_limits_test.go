@@ -3,13 +3,26 @@ package agent
 import (
 	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
 	"testing"
+	"time"
+	"unicode/utf8"
 
 	"fi-cli/internal/config"
+	"fi-cli/internal/events"
 	"fi-cli/internal/llm"
 	"fi-cli/internal/repo"
 	"fi-cli/internal/tools"
 
+	"github.com/openai/openai-go/v3"
 	"go.uber.org/zap"
 )
 
@@ -71,3 +84,1027 @@ func TestAgentToolCallBudget(t *testing.T) {
 		t.Fatalf("second call should fail with budget error")
 	}
 }
+
+func TestAgentRecordsUnknownAndSkippedCalls(t *testing.T) {
+	args, _ := json.Marshal(map[string]any{"pattern": "abc"})
+	client := &recordingClient{sequenceClient: sequenceClient{responses: []llm.Response{
+		{ToolCalls: []llm.ToolCall{{ID: "c1", Name: "nope", Arguments: args}, {ID: "c2", Name: "grep", Arguments: args}}},
+		{Content: "final"},
+	}}}
+	cfg := config.Config{Model: config.DefaultModel, MaxSteps: 3, JSON: true, NoPlan: true, NoHistory: true}
+	result, err := NewAgent(client, tools.NewRegistry(fakeTool{}), nil, zap.NewNop(), cfg).Run(context.Background(), "find pattern", "/tmp", repo.RepoContext{RepoRoot: "/tmp"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(result.ToolCalls) != 2 || result.ToolCalls[0].ID != "T1" || result.ToolCalls[0].Status != "error" || result.ToolCalls[1].ID != "T2" {
+		t.Fatalf("expected the unknown call recorded as T1 before T2, got %+v", result.ToolCalls)
+	}
+	var unknown string
+	for _, message := range client.requests[1].Messages {
+		if message.OfTool != nil && message.OfTool.ToolCallID == "c1" {
+			unknown = message.OfTool.Content.OfString.Value
+		}
+	}
+	if !strings.HasPrefix(unknown, "[T1]\n<untrusted_output") || !strings.Contains(unknown, "unknown tool: nope") {
+		t.Fatalf("expected a cited, guarded unknown-tool result, got %q", unknown)
+	}
+
+	// calls still pending when the run deadline passes are recorded as skipped
+	slow := &sequenceClient{responses: []llm.Response{{ToolCalls: []llm.ToolCall{{ID: "c1", Name: "slow", Arguments: args}, {ID: "c2", Name: "grep", Arguments: args}}}}}
+	cfg.Timeout = 50 * time.Millisecond
+	result, _ = NewAgent(slow, tools.NewRegistry(slowTool{}, fakeTool{}), nil, zap.NewNop(), cfg).Run(context.Background(), "find pattern", "/tmp", repo.RepoContext{RepoRoot: "/tmp"})
+	if len(result.ToolCalls) != 2 || result.ToolCalls[1].ID != "T2" || !strings.Contains(fmt.Sprint(result.ToolCalls[1].Output), "skipped: run timeout reached") {
+		t.Fatalf("expected the pending call recorded as skipped, got %+v", result.ToolCalls)
+	}
+}
+
+type slowTool struct{ fakeTool }
+
+func (slowTool) Name() string { return "slow" }
+func (slowTool) Execute(ctx context.Context, input json.RawMessage, meta tools.Meta) (tools.Result, error) {
+	<-ctx.Done()
+	return tools.Result{}, ctx.Err()
+}
+
+type listTool struct{ fakeTool }
+
+func (listTool) Name() string { return "list_files" }
+
+func TestAgentCategoryBudget(t *testing.T) {
+	args, _ := json.Marshal(map[string]any{"pattern": "abc"})
+	client := &sequenceClient{
+		responses: []llm.Response{
+			{ToolCalls: []llm.ToolCall{{ID: "c1", Name: "grep", Arguments: args}, {ID: "c2", Name: "list_files", Arguments: args}}},
+			{ToolCalls: []llm.ToolCall{{ID: "c3", Name: "grep", Arguments: args}}},
+			{Content: "final"},
+		},
+	}
+	cfg := config.Config{
+		Model:      config.DefaultModel,
+		MaxSteps:   5,
+		JSON:       true,
+		NoPlan:     true,
+		NoHistory:  true,
+		ToolLimits: config.ToolLimits{GrepMaxResults: 10, GrepMaxBytes: 1024, GrepMaxCalls: 5, SearchMaxCalls: 2},
+	}
+	ag := NewAgent(client, tools.NewRegistry(fakeTool{}, listTool{}), nil, zap.NewNop(), cfg)
+	result, err := ag.Run(context.Background(), "find pattern", "/tmp", repo.RepoContext{RepoRoot: "/tmp"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(result.ToolCalls) != 3 || result.ToolCalls[1].Status != "success" || result.ToolCalls[2].Status != "error" {
+		t.Fatalf("expected the third search call to hit the budget, got %+v", result.ToolCalls)
+	}
+	payload := result.ToolCalls[2].Output.(map[string]any)
+	if payload["budget_exhausted"] != true || !strings.Contains(payload["error"].(string), "search budget exhausted: 2 of 2") {
+		t.Fatalf("unexpected budget error: %+v", payload)
+	}
+}
+
+func TestWebToolsShareOneBudget(t *testing.T) {
+	ag := NewAgent(nil, tools.NewRegistry(), nil, zap.NewNop(), config.Config{ToolLimits: config.ToolLimits{WebMaxCalls: 3}})
+	usage := map[string]int{"exa_search": 1, "code_search": 1, "issue_lookup": 1}
+	err := ag.checkToolBudget("exa_contents", usage)
+	if err == nil || !strings.Contains(err.Error(), "web budget exhausted: 3 of 3") {
+		t.Fatalf("expected the web tools to share web_max_calls, got %v", err)
+	}
+	if err := ag.checkToolBudget("exa_contents", map[string]int{"exa_search": 2}); err != nil {
+		t.Fatalf("expected a call under the shared cap to pass, got %v", err)
+	}
+}
+
+func TestAgentRefusalRetriesOnceThenRefuses(t *testing.T) {
+	client := &sequenceClient{
+		responses: []llm.Response{
+			{FinishReason: "content_filter"},
+			{Refusal: "I can't help with that."},
+		},
+	}
+	cfg := config.Config{Model: config.DefaultModel, MaxSteps: 5, JSON: true, NoPlan: true, NoHistory: true}
+	ag := NewAgent(client, tools.NewRegistry(fakeTool{}), nil, zap.NewNop(), cfg)
+	result, err := ag.Run(context.Background(), "question", "/tmp", repo.RepoContext{RepoRoot: "/tmp"})
+	if !errors.Is(err, ErrRefused) {
+		t.Fatalf("expected ErrRefused, got %v", err)
+	}
+	if result.Status != "refused" {
+		t.Fatalf("expected refused status, got %s", result.Status)
+	}
+	if result.StepsUsed != 2 {
+		t.Fatalf("expected one retry, got %d steps", result.StepsUsed)
+	}
+}
+
+func TestAgentEmptyResponseRecovers(t *testing.T) {
+	client := &sequenceClient{responses: []llm.Response{{}, {Content: "answer"}}}
+	cfg := config.Config{Model: config.DefaultModel, MaxSteps: 5, JSON: true, NoPlan: true, NoHistory: true}
+	ag := NewAgent(client, tools.NewRegistry(fakeTool{}), nil, zap.NewNop(), cfg)
+	result, err := ag.Run(context.Background(), "question", "/tmp", repo.RepoContext{RepoRoot: "/tmp"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result.Status != "success" || result.FinalAnswer != "answer" {
+		t.Fatalf("expected recovered answer, got %s %q", result.Status, result.FinalAnswer)
+	}
+}
+
+type stallingClient struct{ sequenceClient }
+
+func (c *stallingClient) Stream(ctx context.Context, req llm.Request, onDelta func(string)) (llm.Response, error) {
+	onDelta("partial")
+	<-ctx.Done()
+	return llm.Response{}, ctx.Err()
+}
+
+func TestStreamFinalIdleTimeout(t *testing.T) {
+	cfg := config.Config{Model: config.DefaultModel, IdleTimeout: 20 * time.Millisecond}
+	ag := NewAgent(&stallingClient{}, tools.NewRegistry(), nil, zap.NewNop(), cfg)
+	streamed, err := ag.streamFinal(context.Background(), llm.Request{}, func(events.Event) {})
+	if err == nil || !strings.Contains(err.Error(), "idle") {
+		t.Fatalf("expected idle timeout error, got %v", err)
+	}
+	if streamed != "partial" {
+		t.Fatalf("expected partial output to be kept, got %q", streamed)
+	}
+}
+
+type droppingClient struct {
+	sequenceClient
+	streams int
+}
+
+func (c *droppingClient) Stream(ctx context.Context, req llm.Request, onDelta func(string)) (llm.Response, error) {
+	c.streams++
+	if c.streams == 1 {
+		onDelta("Run make ")
+		return llm.Response{}, errors.New("connection reset")
+	}
+	onDelta("make test to run the suite.")
+	return llm.Response{}, nil
+}
+
+func TestStreamFinalResumesAndStitches(t *testing.T) {
+	client := &droppingClient{}
+	ag := NewAgent(client, tools.NewRegistry(), nil, zap.NewNop(), config.Config{Model: config.DefaultModel})
+	resumed := 0
+	streamed, err := ag.streamFinal(context.Background(), llm.Request{}, func(event events.Event) {
+		if event.Type == events.StreamResumed {
+			resumed++
+		}
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if resumed != 1 {
+		t.Fatalf("expected one resume event, got %d", resumed)
+	}
+	if streamed != "Run make test to run the suite." {
+		t.Fatalf("expected stitched answer, got %q", streamed)
+	}
+}
+
+type ramblingClient struct{ sequenceClient }
+
+func (c *ramblingClient) Stream(ctx context.Context, req llm.Request, onDelta func(string)) (llm.Response, error) {
+	for ctx.Err() == nil {
+		onDelta("more and more ")
+	}
+	return llm.Response{}, ctx.Err()
+}
+
+func TestAgentStopsAtMaxAnswerTokens(t *testing.T) {
+	cfg := config.Config{Model: config.DefaultModel, MaxSteps: 2, NoPlan: true, NoHistory: true, MaxAnswerTokens: 10}
+	ag := NewAgent(&ramblingClient{}, tools.NewRegistry(), nil, zap.NewNop(), cfg)
+	result, err := ag.Run(context.Background(), "question", "/tmp", repo.RepoContext{RepoRoot: "/tmp"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(result.FinalAnswer) > 40 || !strings.HasPrefix(result.FinalAnswer, "more and more") {
+		t.Fatalf("expected answer cut at about 40 bytes, got %q", result.FinalAnswer)
+	}
+	if len(result.Warnings) != 1 || !strings.Contains(result.Warnings[0], "max_answer_tokens (10)") {
+		t.Fatalf("expected max_answer_tokens warning, got %v", result.Warnings)
+	}
+}
+
+type touchingTool struct{ root string }
+
+func (f touchingTool) Name() string        { return "edit_file" }
+func (f touchingTool) Description() string { return "fake editing tool" }
+func (f touchingTool) Schema() map[string]any {
+	return map[string]any{"type": "object", "properties": map[string]any{}}
+}
+func (f touchingTool) Execute(ctx context.Context, input json.RawMessage, meta tools.Meta) (tools.Result, error) {
+	if err := os.WriteFile(filepath.Join(f.root, "main.go"), []byte("package main // edited"), 0o644); err != nil {
+		return tools.Result{}, err
+	}
+	return tools.Result{ToolName: f.Name(), Payload: map[string]any{"ok": true}, TouchedFiles: []string{"main.go", "../outside.go"}}, nil
+}
+
+type recordingClient struct {
+	sequenceClient
+	requests []llm.Request
+}
+
+func (c *recordingClient) Create(ctx context.Context, req llm.Request) (llm.Response, error) {
+	c.requests = append(c.requests, req)
+	return c.sequenceClient.Create(ctx, req)
+}
+
+func TestAgentRefreshesTouchedFiles(t *testing.T) {
+	root := t.TempDir()
+	client := &recordingClient{sequenceClient: sequenceClient{responses: []llm.Response{
+		{ToolCalls: []llm.ToolCall{{ID: "c1", Name: "edit_file", Arguments: json.RawMessage(`{}`)}}},
+		{Content: "final"},
+	}}}
+	cfg := config.Config{Model: config.DefaultModel, MaxSteps: 3, JSON: true, NoPlan: true, NoHistory: true, ToolLimits: config.ToolLimits{MaxFileBytes: 1024}}
+	ag := NewAgent(client, tools.NewRegistry(touchingTool{root: root}), nil, zap.NewNop(), cfg)
+	if _, err := ag.Run(context.Background(), "edit it", root, repo.RepoContext{RepoRoot: root}); err != nil {
+		t.Fatalf("run failed: %v", err)
+	}
+	if len(client.requests) != 2 {
+		t.Fatalf("expected 2 model requests, got %d", len(client.requests))
+	}
+	payload, _ := json.Marshal(client.requests[1].Messages)
+	if !strings.Contains(string(payload), "--- main.go ---") || !strings.Contains(string(payload), "package main // edited") {
+		t.Fatalf("expected refreshed snippet in second request: %s", payload)
+	}
+	if strings.Contains(string(payload), "outside.go") {
+		t.Fatalf("expected paths outside the repo to be ignored")
+	}
+}
+
+func TestAgentFollowUpReplaysExchanges(t *testing.T) {
+	root := t.TempDir()
+	client := &recordingClient{sequenceClient: sequenceClient{responses: []llm.Response{
+		{ToolCalls: []llm.ToolCall{{ID: "c1", Name: "edit_file", Arguments: json.RawMessage(`{}`)}}},
+		{Content: "final"},
+	}}}
+	cfg := config.Config{Model: config.DefaultModel, MaxSteps: 3, JSON: true, NoPlan: true, NoHistory: true, ToolLimits: config.ToolLimits{MaxFileBytes: 1024}}
+	ag := NewAgent(client, tools.NewRegistry(touchingTool{root: root}), nil, zap.NewNop(), cfg)
+	ag.FollowUp(ExchangeOf(RunResult{Question: "how do I run the app?", FinalAnswer: "make run [T1][T2]", ToolCalls: make([]ToolCallRecord, 2)}))
+	result, err := ag.Run(context.Background(), "and in docker?", root, repo.RepoContext{RepoRoot: root})
+	if err != nil {
+		t.Fatalf("run failed: %v", err)
+	}
+	if len(result.ToolCalls) != 1 || result.ToolCalls[0].ID != "T3" {
+		t.Fatalf("expected tool IDs to continue after the exchange, got %+v", result.ToolCalls)
+	}
+	messages := client.requests[0].Messages
+	n := len(messages)
+	if n < 4 || messages[n-4].OfUser == nil || messages[n-3].OfAssistant == nil || messages[n-2].OfDeveloper == nil || messages[n-1].OfUser == nil {
+		t.Fatalf("expected the exchange, the follow-up note, then the question at the end of the request")
+	}
+	if got := messages[n-3].OfAssistant.Content.OfString.Value; got != "make run [T1][T2]" {
+		t.Fatalf("expected the earlier answer replayed, got %q", got)
+	}
+}
+
+func TestAgentFollowUpReplaysEvidence(t *testing.T) {
+	root := t.TempDir()
+	client := &recordingClient{sequenceClient: sequenceClient{responses: []llm.Response{{Content: "final [T1]"}}}}
+	cfg := config.Config{Model: config.DefaultModel, MaxSteps: 3, JSON: true, NoPlan: true, NoHistory: true, InjectionGuard: config.InjectionGuard{Mode: "flag"}, ToolLimits: config.ToolLimits{MaxFileBytes: 1024}}
+	ag := NewAgent(client, tools.NewRegistry(touchingTool{root: root}), nil, zap.NewNop(), cfg)
+	earlier := RunResult{Question: "where is the port set?", FinalAnswer: "in config.go [T1]", ToolCalls: []ToolCallRecord{{ID: "T1", ToolName: "grep", Input: map[string]any{"pattern": "PORT"}, Output: "config.go:12: PORT=8080", Status: "success"}}}
+	exchange := ExchangeOf(earlier)
+	exchange.Evidence = EvidenceOf(earlier)
+	if want := (EvidenceCall{ID: "T1", ToolName: "grep", Arguments: `{"pattern":"PORT"}`, Output: `"config.go:12: PORT=8080"`}); len(exchange.Evidence) != 1 || exchange.Evidence[0] != want {
+		t.Fatalf("unexpected evidence %+v", exchange.Evidence)
+	}
+	for _, mode := range []string{"flag", "off"} {
+		client.requests, client.index = nil, 0
+		ag.cfg.InjectionGuard.Mode = mode
+		ag.FollowUp(exchange)
+		if _, err := ag.Run(context.Background(), "can I change it?", root, repo.RepoContext{RepoRoot: root}); err != nil {
+			t.Fatalf("run failed: %v", err)
+		}
+		// question, tool call, tool result, answer, note, follow-up question
+		messages := client.requests[0].Messages
+		n := len(messages)
+		if n < 6 || messages[n-5].OfAssistant == nil || messages[n-4].OfTool == nil || messages[n-3].OfAssistant == nil {
+			t.Fatalf("mode %s: expected the evidence replayed as a tool call and result ahead of the earlier answer", mode)
+		}
+		if calls := messages[n-5].OfAssistant.ToolCalls; len(calls) != 1 || calls[0].OfFunction.ID != "replay_T1" || calls[0].OfFunction.Function.Arguments != `{"pattern":"PORT"}` {
+			t.Fatalf("mode %s: unexpected replayed call %+v", mode, calls)
+		}
+		if got := messages[n-4].OfTool.Content.OfString.Value; !strings.HasPrefix(got, "[T1]\n<untrusted_output") || !strings.Contains(got, "PORT=8080") {
+			t.Fatalf("mode %s: expected wrapped evidence, got %q", mode, got)
+		}
+		for _, message := range messages {
+			if message.OfDeveloper != nil && strings.Contains(message.OfDeveloper.Content.OfString.Value, "PORT=8080") {
+				t.Fatalf("mode %s: expected no evidence in a developer message", mode)
+			}
+		}
+		if got := messages[n-2].OfDeveloper.Content.OfString.Value; got != evidenceNote {
+			t.Fatalf("mode %s: expected the evidence note, got %q", mode, got)
+		}
+	}
+}
+
+func TestAgentStructuredPlan(t *testing.T) {
+	root := t.TempDir()
+	plan := `{"steps":[{"title":"**Find** the handler","tools":["edit_file","shell","edit_file"]},{"title":"  ","tools":[]},{"title":"Answer","tools":[]}]}`
+	client := &recordingClient{sequenceClient: sequenceClient{responses: []llm.Response{
+		{ToolCalls: []llm.ToolCall{{ID: "p1", Name: planToolName, Arguments: json.RawMessage(plan)}}},
+		{Content: "final"},
+	}}}
+	cfg := config.Config{Model: config.DefaultModel, MaxSteps: 3, JSON: true, NoHistory: true, Router: config.Router{Mode: config.RouteOff}, ToolLimits: config.ToolLimits{MaxFileBytes: 1024}}
+	ag := NewAgent(client, tools.NewRegistry(touchingTool{root: root}), nil, zap.NewNop(), cfg)
+	result, err := ag.Run(context.Background(), "where is the refund handler registered", root, repo.RepoContext{RepoRoot: root})
+	if err != nil {
+		t.Fatalf("run failed: %v", err)
+	}
+	forced := client.requests[0].ToolChoice.OfFunctionToolChoice
+	if forced == nil || forced.Function.Name != planToolName || len(client.requests[0].Tools) != 1 {
+		t.Fatalf("expected the plan request to force %s", planToolName)
+	}
+	var payload events.PlanGeneratedPayload
+	for _, event := range result.Events {
+		if p, ok := event.Payload.(events.PlanGeneratedPayload); ok {
+			payload = p
+		}
+	}
+	want := []events.PlanStep{{Title: "Find the handler", Tools: []string{"edit_file"}}, {Title: "Answer"}}
+	if !slices.EqualFunc(payload.Steps, want, func(a, b events.PlanStep) bool { return a.Title == b.Title && slices.Equal(a.Tools, b.Tools) }) {
+		t.Fatalf("unexpected plan steps %+v", payload.Steps)
+	}
+	if !slices.Equal(payload.Plan, []string{"Find the handler", "Answer"}) {
+		t.Fatalf("unexpected plan titles %q", payload.Plan)
+	}
+	messages, _ := json.Marshal(client.requests[1].Messages)
+	if !strings.Contains(string(messages), `Plan:\n- Find the handler (tools: edit_file)\n- Answer`) {
+		t.Fatalf("expected the plan with tool hints in the loop request: %s", messages)
+	}
+}
+
+// modelClient answers by model name, so consensus tests can tell who said what.
+type modelClient struct {
+	sequenceClient
+	mu      sync.Mutex
+	answers map[string]llm.Response
+	judged  llm.Request
+}
+
+func (c *modelClient) Create(ctx context.Context, req llm.Request) (llm.Response, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if req.ToolChoice.OfFunctionToolChoice != nil {
+		c.judged = req
+	}
+	if resp, ok := c.answers[req.Model]; ok {
+		return resp, nil
+	}
+	return llm.Response{}, errors.New("unknown model " + req.Model)
+}
+
+func TestAgentConsensus(t *testing.T) {
+	verdict := `{"answer":"The migration is reversible [T1].","agreements":["a down migration exists"],"disagreements":["Answer 3 says it drops a column"," "]}`
+	client := &modelClient{answers: map[string]llm.Response{
+		"primary": {Content: "Reversible."},
+		"peer-a":  {Content: "Reversible, see down.sql."},
+		"judge":   {ToolCalls: []llm.ToolCall{{ID: "j1", Name: judgeToolName, Arguments: json.RawMessage(verdict)}}},
+	}}
+	cfg := config.Config{Model: "primary", MaxSteps: 3, JSON: true, NoPlan: true, NoHistory: true, FastPath: true,
+		Consensus: config.Consensus{Answers: 3, Models: []string{"peer-a", "peer-b"}, Judge: "judge"}}
+	ag := NewAgent(client, tools.NewRegistry(), nil, zap.NewNop(), cfg)
+	result, err := ag.Run(context.Background(), "is the migration reversible?", t.TempDir(), repo.RepoContext{})
+	if err != nil {
+		t.Fatalf("run failed: %v", err)
+	}
+	want := "The migration is reversible [T1].\n\nModels agree:\n- a down migration exists\n\nModels disagree:\n- Answer 3 says it drops a column"
+	if result.FinalAnswer != want {
+		t.Fatalf("got %q, want %q", result.FinalAnswer, want)
+	}
+	answers := result.Consensus.Answers
+	if len(answers) != 3 || answers[0].Answer != "Reversible." || answers[1].Answer == "" || answers[2].Error == "" {
+		t.Fatalf("expected every model's answer or error recorded, got %+v", answers)
+	}
+	payload, _ := json.Marshal(client.judged.Messages)
+	if !strings.Contains(string(payload), "### Answer 2 (peer-a)") || strings.Contains(string(payload), "### Answer 3") {
+		t.Fatalf("expected the judge to see only the answers that arrived: %s", payload)
+	}
+
+	client.answers["judge"] = llm.Response{}
+	delete(client.answers, "peer-a")
+	result, _ = NewAgent(client, tools.NewRegistry(), nil, zap.NewNop(), cfg).Run(context.Background(), "is the migration reversible?", t.TempDir(), repo.RepoContext{})
+	if result.FinalAnswer != "Reversible." || len(result.Warnings) != 1 || !strings.Contains(result.Warnings[0], "no other model answered") {
+		t.Fatalf("expected the primary answer with a warning, got %q %q", result.FinalAnswer, result.Warnings)
+	}
+}
+
+type countingClient struct {
+	sequenceClient
+	creates int
+	streams int
+}
+
+func (c *countingClient) Create(ctx context.Context, req llm.Request) (llm.Response, error) {
+	c.creates++
+	return c.sequenceClient.Create(ctx, req)
+}
+
+func (c *countingClient) Stream(ctx context.Context, req llm.Request, onDelta func(string)) (llm.Response, error) {
+	c.streams++
+	return c.sequenceClient.Stream(ctx, req, onDelta)
+}
+
+func TestAgentFastPathAnswersInOneCall(t *testing.T) {
+	cfg := config.Config{
+		Model:      config.DefaultModel,
+		MaxSteps:   5,
+		FastPath:   true,
+		NoHistory:  true,
+		ToolLimits: config.ToolLimits{GrepMaxResults: 10, GrepMaxBytes: 1024, ContextMaxBytes: 4096, MaxFileBytes: 1024, GrepMaxCalls: 1},
+	}
+	client := &countingClient{}
+	ag := NewAgent(client, tools.NewRegistry(fakeTool{}), nil, zap.NewNop(), cfg)
+	result, err := ag.Run(context.Background(), "where is the config loaded?", "/tmp", repo.RepoContext{RepoRoot: "/tmp"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result.FinalAnswer != "done" {
+		t.Fatalf("unexpected answer %q", result.FinalAnswer)
+	}
+	if client.creates != 0 || client.streams != 1 {
+		t.Fatalf("expected a single streamed call, got %d creates and %d streams", client.creates, client.streams)
+	}
+
+	client = &countingClient{}
+	ag = NewAgent(client, tools.NewRegistry(fakeTool{}), nil, zap.NewNop(), cfg)
+	if _, err := ag.Run(context.Background(), "review the design of the config package and plan a refactor", "/tmp", repo.RepoContext{RepoRoot: "/tmp"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if client.creates != 2 || client.streams != 1 {
+		t.Fatalf("expected plan, step, and stream calls, got %d creates and %d streams", client.creates, client.streams)
+	}
+}
+
+// stallingStream's first stream sends nothing until the run deadline cancels
+// it; later ones answer.
+type stallingStream struct {
+	sequenceClient
+	streams int
+}
+
+func (c *stallingStream) Stream(ctx context.Context, req llm.Request, onDelta func(string)) (llm.Response, error) {
+	c.streams++
+	if c.streams == 1 {
+		<-ctx.Done()
+		return llm.Response{}, ctx.Err()
+	}
+	return c.sequenceClient.Stream(ctx, req, onDelta)
+}
+
+func TestAgentFastPathStepKeepsTimeout(t *testing.T) {
+	cfg := config.Config{Model: config.DefaultModel, MaxSteps: 5, FastPath: true, NoHistory: true, Timeout: 50 * time.Millisecond}
+	client := &stallingStream{sequenceClient: sequenceClient{responses: []llm.Response{{Content: "So far: nothing gathered."}}}}
+	ag := NewAgent(client, tools.NewRegistry(fakeTool{}), nil, zap.NewNop(), cfg)
+	done := make(chan error, 1)
+	go func() {
+		_, err := ag.Run(context.Background(), "where is the config loaded?", "/tmp", repo.RepoContext{RepoRoot: "/tmp"})
+		done <- err
+	}()
+	select {
+	case err := <-done:
+		if !errors.Is(err, ErrTimedOut) {
+			t.Fatalf("expected the streamed step to time out, got %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("the streamed fast-path step ignored the run timeout")
+	}
+}
+
+type fakeShell struct{}
+
+func (fakeShell) Name() string        { return "shell" }
+func (fakeShell) Description() string { return "fake shell" }
+func (fakeShell) Schema() map[string]any {
+	return map[string]any{"type": "object", "properties": map[string]any{"command": map[string]any{"type": "string"}}, "required": []string{"command"}}
+}
+func (fakeShell) Execute(ctx context.Context, input json.RawMessage, meta tools.Meta) (tools.Result, error) {
+	return tools.Result{ToolName: "shell", Payload: map[string]any{"exit_code": 0, "stdout": "ok"}, Preview: "ok", LineCount: 1, ByteCount: 2}, nil
+}
+
+func TestAgentPipelineStages(t *testing.T) {
+	grepArgs, _ := json.Marshal(map[string]any{"pattern": "Load"})
+	shellArgs, _ := json.Marshal(map[string]any{"command": "go test ./internal/config"})
+	client := &sequenceClient{responses: []llm.Response{
+		{ToolCalls: []llm.ToolCall{{ID: "c1", Name: "grep", Arguments: grepArgs}}},
+		{Content: "- config is loaded in config.go [T1]\nTo verify:\n- go test ./internal/config"},
+		{ToolCalls: []llm.ToolCall{{ID: "c2", Name: "shell", Arguments: shellArgs}}},
+		{Content: "- go test ./internal/config: exit 0 [T2]"},
+		{Content: "Config loads in config.go [T1], and its tests pass [T2]."},
+	}}
+	journal := filepath.Join(t.TempDir(), "journal")
+	cfg := config.Config{Model: config.DefaultModel, MaxSteps: 4, NoHistory: true, JSON: true, Pipeline: true, ShellAllowlist: []string{"go test"}, Hooks: config.Hooks{OnRunStart: "echo start >> " + journal, OnRunEnd: "echo end >> " + journal}}
+	ag := NewAgent(client, tools.NewRegistry(fakeTool{}, fakeShell{}), nil, zap.NewNop(), cfg)
+	result, err := ag.Run(context.Background(), "is config loading tested?", "/tmp", repo.RepoContext{RepoRoot: "/tmp"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if data, _ := os.ReadFile(journal); string(data) != "start\nend\n" {
+		t.Fatalf("expected run hooks once for the whole pipeline, got %q", data)
+	}
+	if len(result.Artifacts) != 2 || result.Artifacts[0].Role != RoleResearcher || result.Artifacts[1].Role != RoleExecutor || result.Artifacts[1].ToolCalls != 1 {
+		t.Fatalf("unexpected artifacts: %+v", result.Artifacts)
+	}
+	if len(result.ToolCalls) != 2 || result.ToolCalls[0].ID != "T1" || result.ToolCalls[1].ID != "T2" {
+		t.Fatalf("expected stage tool calls numbered across stages, got %+v", result.ToolCalls)
+	}
+	if len(result.Citations) != 2 || result.Citations[1].ToolName != "shell" {
+		t.Fatalf("expected the writer's citations to resolve to stage tool calls, got %+v", result.Citations)
+	}
+	stages := 0
+	for _, event := range result.Events {
+		if event.Type == events.StageCompleted {
+			stages++
+		}
+	}
+	if stages != 2 || result.FinalAnswer != "Config loads in config.go [T1], and its tests pass [T2]." {
+		t.Fatalf("unexpected stages %d or answer %q", stages, result.FinalAnswer)
+	}
+}
+
+// deadlineClient answers the first request with a tool call, blocks on the
+// second until the run deadline cancels it, and answers the rest.
+type deadlineClient struct {
+	sequenceClient
+	calls int
+}
+
+func (c *deadlineClient) Create(ctx context.Context, req llm.Request) (llm.Response, error) {
+	c.calls++
+	switch c.calls {
+	case 1:
+		return c.sequenceClient.Create(ctx, req)
+	case 2:
+		<-ctx.Done()
+		return llm.Response{}, ctx.Err()
+	default:
+		return llm.Response{Content: "So far: config loads in config.go [T1]."}, nil
+	}
+}
+
+func TestAgentCheckpointsAtTimeoutAndContinues(t *testing.T) {
+	args, _ := json.Marshal(map[string]any{"pattern": "Load"})
+	cfg := config.Config{Model: config.DefaultModel, MaxSteps: 5, NoHistory: true, JSON: true, Timeout: 50 * time.Millisecond, ToolLimits: config.ToolLimits{GrepMaxCalls: 3}}
+	stalling := &deadlineClient{sequenceClient: sequenceClient{responses: []llm.Response{{ToolCalls: []llm.ToolCall{{ID: "c1", Name: "grep", Arguments: args}}}}}}
+	ag := NewAgent(stalling, tools.NewRegistry(fakeTool{}), nil, zap.NewNop(), cfg)
+	result, err := ag.Run(context.Background(), "where is config loaded?", "/tmp", repo.RepoContext{RepoRoot: "/tmp"})
+	if !errors.Is(err, ErrTimedOut) || result.Checkpoint == nil {
+		t.Fatalf("expected a timeout with a checkpoint, got err=%v checkpoint=%v", err, result.Checkpoint)
+	}
+	if result.Status != "partial" || result.FinalAnswer != "So far: config loads in config.go [T1]." || len(result.Warnings) != 1 {
+		t.Fatalf("expected a partial answer from the gathered evidence, got %s %q %v", result.Status, result.FinalAnswer, result.Warnings)
+	}
+	cp := *result.Checkpoint
+	if cp.Step != 1 || len(cp.ToolCalls) != 1 || cp.ToolUsage["grep"] != 1 || cp.Question != "where is config loaded?" {
+		t.Fatalf("unexpected checkpoint: %+v", cp)
+	}
+
+	resumed := &sequenceClient{responses: []llm.Response{{Content: "Loaded in config.go [T1]."}}}
+	ag = NewAgent(resumed, tools.NewRegistry(fakeTool{}), nil, zap.NewNop(), cfg)
+	result, err = ag.Continue(context.Background(), cp, repo.RepoContext{RepoRoot: "/tmp"})
+	if err != nil {
+		t.Fatalf("continue failed: %v", err)
+	}
+	if result.ContinuedFrom != cp.RunID || result.StepsUsed != 2 || len(result.ToolCalls) != 1 || len(result.Citations) != 1 || result.Citations[0].ToolCall != 0 {
+		t.Fatalf("unexpected continued run: %+v", result)
+	}
+}
+
+func TestAgentAskUserPausesTimeout(t *testing.T) {
+	ask := func(ctx context.Context, question string, options []string) (string, error) {
+		select {
+		case <-time.After(150 * time.Millisecond):
+			return "staging", nil
+		case <-ctx.Done():
+			return "", ctx.Err()
+		}
+	}
+	args, _ := json.Marshal(map[string]any{"question": "Which environment?"})
+	client := &sequenceClient{
+		responses: []llm.Response{
+			{ToolCalls: []llm.ToolCall{{ID: "c1", Name: "ask_user", Arguments: args}}},
+			{ToolCalls: []llm.ToolCall{{ID: "c2", Name: "ask_user", Arguments: args}}},
+			{Content: "final"},
+		},
+	}
+	cfg := config.Config{Model: config.DefaultModel, MaxSteps: 5, JSON: true, NoPlan: true, NoHistory: true, Timeout: 100 * time.Millisecond, ToolLimits: config.ToolLimits{AskUserMaxCalls: 1}}
+	ag := NewAgent(client, tools.NewRegistry(tools.NewAskUserTool(ask)), nil, zap.NewNop(), cfg)
+	result, err := ag.Run(context.Background(), "deploy it", "/tmp", repo.RepoContext{RepoRoot: "/tmp"})
+	if err != nil {
+		t.Fatalf("expected the wait not to count against the timeout, got %v", err)
+	}
+	if len(result.ToolCalls) != 2 || result.ToolCalls[0].Status != "success" || result.ToolCalls[1].Status != "error" {
+		t.Fatalf("expected one answered question and one refused, got %+v", result.ToolCalls)
+	}
+}
+
+type failingClient struct {
+	sequenceClient
+	errs []error
+}
+
+func (c *failingClient) Create(ctx context.Context, req llm.Request) (llm.Response, error) {
+	if len(c.errs) > 0 {
+		err := c.errs[0]
+		c.errs = c.errs[1:]
+		if err != nil {
+			return llm.Response{}, err
+		}
+	}
+	return c.sequenceClient.Create(ctx, req)
+}
+
+func runErrors(result RunResult) []events.RunErrorPayload {
+	var payloads []events.RunErrorPayload
+	for _, event := range result.Events {
+		if payload, ok := event.Payload.(events.RunErrorPayload); ok {
+			payloads = append(payloads, payload)
+		}
+	}
+	return payloads
+}
+
+func TestAgentRecoversFromProviderErrors(t *testing.T) {
+	cfg := config.Config{Model: config.DefaultModel, MaxSteps: 5, JSON: true, NoPlan: true, NoHistory: true}
+
+	client := &failingClient{errs: []error{errors.New("connection reset by peer")}, sequenceClient: sequenceClient{responses: []llm.Response{{Content: "answer"}}}}
+	result, err := NewAgent(client, tools.NewRegistry(), nil, zap.NewNop(), cfg).Run(context.Background(), "question", "/tmp", repo.RepoContext{RepoRoot: "/tmp"})
+	if err != nil || result.FinalAnswer != "answer" || result.StepsUsed != 1 {
+		t.Fatalf("expected the retried step to answer, got %v %+v", err, result)
+	}
+	if errs := runErrors(result); len(errs) != 1 || errs[0].Severity != events.SeverityRecoverable || errs[0].Recovery != recoverRetryStep || errs[0].Kind != events.ErrorKindProvider {
+		t.Fatalf("unexpected run errors: %+v", errs)
+	}
+
+	client = &failingClient{errs: []error{errors.New("upstream 502"), errors.New("upstream 502")}}
+	result, err = NewAgent(client, tools.NewRegistry(), nil, zap.NewNop(), cfg).Run(context.Background(), "question", "/tmp", repo.RepoContext{RepoRoot: "/tmp"})
+	if err == nil || result.Status != "failure" {
+		t.Fatalf("expected the second failure to be fatal, got %v %s", err, result.Status)
+	}
+	if errs := runErrors(result); len(errs) != 2 || errs[1].Severity != events.SeverityFatal {
+		t.Fatalf("unexpected run errors: %+v", errs)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	client = &failingClient{errs: []error{context.Canceled}}
+	result, _ = NewAgent(client, tools.NewRegistry(), nil, zap.NewNop(), cfg).Run(ctx, "question", "/tmp", repo.RepoContext{RepoRoot: "/tmp"})
+	if errs := runErrors(result); len(errs) != 1 || errs[0].Kind != events.ErrorKindCancelled || errs[0].Severity != events.SeverityFatal {
+		t.Fatalf("expected a fatal cancellation, got %+v", errs)
+	}
+}
+
+func TestAgentBacksOffOnRateLimits(t *testing.T) {
+	limited := func(header http.Header) error {
+		return &openai.Error{StatusCode: http.StatusTooManyRequests, Request: &http.Request{Method: http.MethodPost, URL: &url.URL{}}, Response: &http.Response{StatusCode: http.StatusTooManyRequests, Header: header}}
+	}
+	for _, tc := range []struct {
+		header http.Header
+		want   time.Duration
+	}{
+		{http.Header{"Retry-After-Ms": {"150"}}, 150 * time.Millisecond},
+		{http.Header{"Retry-After": {"3"}}, 3 * time.Second},
+		{http.Header{"Retry-After": {"3600"}}, maxRateLimitWait},
+		{http.Header{}, rateLimitBackoff},
+	} {
+		if got := rateLimitDelay(limited(tc.header)); got != tc.want {
+			t.Fatalf("rateLimitDelay(%v) = %s, want %s", tc.header, got, tc.want)
+		}
+	}
+	if got := rateLimitDelay(errors.New("upstream 502")); got != 0 {
+		t.Fatalf("expected no wait for other errors, got %s", got)
+	}
+
+	cfg := config.Config{Model: config.DefaultModel, MaxSteps: 5, JSON: true, NoPlan: true, NoHistory: true}
+	client := &failingClient{errs: []error{limited(http.Header{"Retry-After-Ms": {"100"}})}, sequenceClient: sequenceClient{responses: []llm.Response{{Content: "answer"}}}}
+	started := time.Now()
+	result, err := NewAgent(client, tools.NewRegistry(), nil, zap.NewNop(), cfg).Run(context.Background(), "question", "/tmp", repo.RepoContext{RepoRoot: "/tmp"})
+	if err != nil || result.FinalAnswer != "answer" {
+		t.Fatalf("expected the retried step to answer, got %v %+v", err, result)
+	}
+	if waited := time.Since(started); waited < 100*time.Millisecond {
+		t.Fatalf("expected the retry to wait for Retry-After-Ms, it came after %s", waited)
+	}
+}
+
+func TestAgentShrinksContextOnOverflow(t *testing.T) {
+	args, _ := json.Marshal(map[string]any{"pattern": "abc"})
+	responses := []llm.Response{}
+	for i := 0; i < 3; i++ {
+		responses = append(responses, llm.Response{ToolCalls: []llm.ToolCall{{ID: "c", Name: "grep", Arguments: args}}})
+	}
+	responses = append(responses, llm.Response{Content: "answer"})
+	var seen []llm.Request
+	client := &recordingFailClient{failingClient: failingClient{errs: []error{nil, nil, nil, errors.New("This model's maximum context length is 8192 tokens")}, sequenceClient: sequenceClient{responses: responses}}, seen: &seen}
+	cfg := config.Config{Model: config.DefaultModel, MaxSteps: 6, JSON: true, NoPlan: true, NoHistory: true, ToolLimits: config.ToolLimits{GrepMaxCalls: 5}}
+	result, err := NewAgent(client, tools.NewRegistry(bigTool{}), nil, zap.NewNop(), cfg).Run(context.Background(), "question", "/tmp", repo.RepoContext{RepoRoot: "/tmp"})
+	if err != nil || result.FinalAnswer != "answer" {
+		t.Fatalf("expected an answer after shrinking, got %v %+v", err, result)
+	}
+	if errs := runErrors(result); len(errs) != 1 || errs[0].Recovery != recoverShrinkContext {
+		t.Fatalf("unexpected run errors: %+v", errs)
+	}
+	var results []string
+	for _, message := range seen[len(seen)-1].Messages {
+		if message.OfTool != nil {
+			results = append(results, message.OfTool.Content.OfString.Value)
+		}
+	}
+	first := results[len(results)-3]
+	if len(first) > shrunkToolBytes+200 || !strings.Contains(first, "</untrusted_output>\n... [cut to fit the context window") || !utf8.ValidString(first) {
+		t.Fatalf("expected the oldest tool result cut inside its wrapper, got %d bytes", len(first))
+	}
+	if last := results[len(results)-1]; len(last) < 4000 {
+		t.Fatalf("expected the latest tool result to stay whole, got %d bytes", len(last))
+	}
+}
+
+type recordingFailClient struct {
+	failingClient
+	seen *[]llm.Request
+}
+
+func (c *recordingFailClient) Create(ctx context.Context, req llm.Request) (llm.Response, error) {
+	*c.seen = append(*c.seen, req)
+	return c.failingClient.Create(ctx, req)
+}
+
+type bigTool struct{ fakeTool }
+
+func (bigTool) Execute(ctx context.Context, input json.RawMessage, meta tools.Meta) (tools.Result, error) {
+	return tools.Result{ToolName: "grep", Payload: map[string]any{"matches": strings.Repeat("é", 2500)}}, nil
+}
+
+type brokenTool struct{ fakeTool }
+
+func (brokenTool) Execute(ctx context.Context, input json.RawMessage, meta tools.Meta) (tools.Result, error) {
+	return tools.Result{}, errors.New("rg crashed")
+}
+
+func TestAgentDropsRepeatedlyFailingTool(t *testing.T) {
+	args, _ := json.Marshal(map[string]any{"pattern": "abc"})
+	var responses []llm.Response
+	for i := 0; i < maxToolFailures; i++ {
+		responses = append(responses, llm.Response{ToolCalls: []llm.ToolCall{{ID: "c", Name: "grep", Arguments: args}}})
+	}
+	responses = append(responses, llm.Response{Content: "answer"})
+	var seen []llm.Request
+	client := &recordingFailClient{failingClient: failingClient{sequenceClient: sequenceClient{responses: responses}}, seen: &seen}
+	cfg := config.Config{Model: config.DefaultModel, MaxSteps: 6, JSON: true, NoPlan: true, NoHistory: true, ToolLimits: config.ToolLimits{GrepMaxCalls: 5}}
+	result, err := NewAgent(client, tools.NewRegistry(brokenTool{}), nil, zap.NewNop(), cfg).Run(context.Background(), "question", "/tmp", repo.RepoContext{RepoRoot: "/tmp"})
+	if err != nil || result.FinalAnswer != "answer" {
+		t.Fatalf("expected an answer without the tool, got %v %+v", err, result)
+	}
+	if errs := runErrors(result); len(errs) != 1 || errs[0].Kind != events.ErrorKindTool || errs[0].Recovery != recoverDropTool {
+		t.Fatalf("unexpected run errors: %+v", errs)
+	}
+	if last := seen[len(seen)-1]; len(last.Tools) != 0 || last.Messages[len(last.Messages)-1].OfDeveloper == nil {
+		t.Fatalf("expected the last request without tools and with a note, got %d tools", len(last.Tools))
+	}
+}
+
+func TestAgentDropsRepeatedlyRefusedTool(t *testing.T) {
+	args, _ := json.Marshal(map[string]any{"pattern": "abc"})
+	var responses []llm.Response
+	for i := 0; i < maxToolFailures; i++ {
+		responses = append(responses, llm.Response{ToolCalls: []llm.ToolCall{{ID: "c", Name: "grep", Arguments: args}}})
+	}
+	responses = append(responses, llm.Response{Content: "answer"})
+	client := &sequenceClient{responses: responses}
+	cfg := config.Config{Model: config.DefaultModel, MaxSteps: 6, JSON: true, NoPlan: true, NoHistory: true, ToolLimits: config.ToolLimits{GrepMaxCalls: 5}, Hooks: config.Hooks{PreTool: []config.ToolHook{{Command: "echo 'grep is off today' >&2; exit 1"}}}}
+	result, err := NewAgent(client, tools.NewRegistry(fakeTool{}), nil, zap.NewNop(), cfg).Run(context.Background(), "question", "/tmp", repo.RepoContext{RepoRoot: "/tmp"})
+	if err != nil || result.FinalAnswer != "answer" {
+		t.Fatalf("expected an answer without the tool, got %v %+v", err, result)
+	}
+	if errs := runErrors(result); len(errs) != 1 || errs[0].Recovery != recoverDropTool {
+		t.Fatalf("expected refusals to count toward dropping the tool, got %+v", errs)
+	}
+}
+
+func TestAgentPolicyHookBlocksCalls(t *testing.T) {
+	logger := zap.NewNop()
+	allowed, _ := json.Marshal(map[string]any{"pattern": "abc"})
+	denied, _ := json.Marshal(map[string]any{"pattern": "secret"})
+	client := &sequenceClient{
+		responses: []llm.Response{
+			{ToolCalls: []llm.ToolCall{{ID: "c1", Name: "grep", Arguments: allowed}, {ID: "c2", Name: "grep", Arguments: denied}}},
+			{Content: "final"},
+		},
+	}
+	cfg := config.Config{
+		Model:      config.DefaultModel,
+		MaxSteps:   5,
+		JSON:       true,
+		NoPlan:     true,
+		NoHistory:  true,
+		PolicyHook: `if grep -q secret; then echo '{"allow": false, "reason": "no secret hunting"}'; else echo true; fi`,
+		ToolLimits: config.ToolLimits{GrepMaxResults: 10, GrepMaxBytes: 1024, ShellMaxBytes: 1024, WebMaxBytes: 1024, ContextMaxBytes: 4096, MaxFileBytes: 1024, GrepMaxCalls: 5, ShellMaxCalls: 1, WebMaxCalls: 1},
+	}
+	ag := NewAgent(client, tools.NewRegistry(fakeTool{}), nil, logger, cfg)
+	result, err := ag.Run(context.Background(), "find pattern", "/tmp", repo.RepoContext{RepoRoot: "/tmp"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(result.ToolCalls) != 2 || result.ToolCalls[0].Status != "success" || result.ToolCalls[1].Status != "error" {
+		t.Fatalf("expected the second call to be blocked, got %+v", result.ToolCalls)
+	}
+	payload, _ := json.Marshal(result.ToolCalls[1].Output)
+	if !strings.Contains(string(payload), "blocked by the policy hook: no secret hunting") {
+		t.Fatalf("expected the hook's reason, got %s", payload)
+	}
+}
+
+func TestAgentToolHooks(t *testing.T) {
+	logger := zap.NewNop()
+	args, _ := json.Marshal(map[string]any{"pattern": "abc"})
+	client := &sequenceClient{
+		responses: []llm.Response{
+			{ToolCalls: []llm.ToolCall{{ID: "c1", Name: "grep", Arguments: args}, {ID: "c2", Name: "list_files", Arguments: []byte(`{}`)}}},
+			{Content: "final"},
+		},
+	}
+	cfg := config.Config{
+		Model:     config.DefaultModel,
+		MaxSteps:  5,
+		JSON:      true,
+		NoPlan:    true,
+		NoHistory: true,
+		Hooks: config.Hooks{
+			PreTool:  []config.ToolHook{{Tools: []string{"list_files"}, Command: "echo 'listing is off today' >&2; exit 1"}},
+			PostTool: []config.ToolHook{{Tools: []string{"grep"}, Command: `echo '{"note": "sanitized", "output": {"matches": []}}'`}},
+		},
+		ToolLimits: config.ToolLimits{GrepMaxResults: 10, GrepMaxBytes: 1024, ShellMaxBytes: 1024, WebMaxBytes: 1024, ContextMaxBytes: 4096, MaxFileBytes: 1024, ListMaxEntries: 10, GrepMaxCalls: 5, ShellMaxCalls: 1, WebMaxCalls: 1},
+	}
+	ag := NewAgent(client, tools.NewRegistry(fakeTool{}, listTool{}), nil, logger, cfg)
+	result, err := ag.Run(context.Background(), "find pattern", "/tmp", repo.RepoContext{RepoRoot: "/tmp"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(result.ToolCalls) != 2 {
+		t.Fatalf("expected 2 tool call records, got %d", len(result.ToolCalls))
+	}
+	grep, list := result.ToolCalls[0], result.ToolCalls[1]
+	if output, _ := json.Marshal(grep.Output); grep.Status != "success" || string(output) != `{"matches":[]}` || len(grep.HookNotes) != 1 {
+		t.Fatalf("expected the post_tool hook to replace grep output, got %+v (%s)", grep, output)
+	}
+	if output, _ := json.Marshal(list.Output); list.Status != "error" || !strings.Contains(string(output), "blocked by a pre_tool hook: listing is off today") {
+		t.Fatalf("expected the pre_tool hook to block list_files, got %s", output)
+	}
+}
+
+func TestAgentRunLifecycleHooks(t *testing.T) {
+	dir := t.TempDir()
+	startFile, endFile := filepath.Join(dir, "start.json"), filepath.Join(dir, "end.json")
+	cfg := config.Config{
+		Model:     config.DefaultModel,
+		MaxSteps:  5,
+		JSON:      true,
+		NoPlan:    true,
+		NoHistory: true,
+		Hooks:     config.Hooks{OnRunStart: "cat > " + startFile, OnRunEnd: "cat > " + endFile},
+	}
+	ag := NewAgent(&sequenceClient{responses: []llm.Response{{Content: "final"}}}, tools.NewRegistry(fakeTool{}), nil, zap.NewNop(), cfg)
+	result, err := ag.Run(context.Background(), "what is this?", "/tmp", repo.RepoContext{RepoRoot: "/tmp"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var started, ended RunResult
+	for path, into := range map[string]*RunResult{startFile: &started, endFile: &ended} {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatalf("hook did not run: %v", err)
+		}
+		if err := json.Unmarshal(data, into); err != nil {
+			t.Fatalf("hook input is not a RunResult: %v", err)
+		}
+	}
+	if started.RunID != result.RunID || started.Question != "what is this?" || started.FinalAnswer != "" {
+		t.Fatalf("unexpected on_run_start input: %+v", started)
+	}
+	if ended.RunID != result.RunID || ended.Status != "success" || ended.FinalAnswer != "final" {
+		t.Fatalf("unexpected on_run_end input: %+v", ended)
+	}
+
+	cfg.Hooks = config.Hooks{OnRunStart: "echo no journal >&2; exit 1"}
+	ag = NewAgent(&sequenceClient{responses: []llm.Response{{Content: "final"}}}, tools.NewRegistry(fakeTool{}), nil, zap.NewNop(), cfg)
+	result, err = ag.Run(context.Background(), "what is this?", "/tmp", repo.RepoContext{RepoRoot: "/tmp"})
+	if err != nil || result.Status != "success" {
+		t.Fatalf("a failing hook must not stop the run: %v", err)
+	}
+	if !slices.Contains(result.Warnings, "on_run_start hook failed: no journal") {
+		t.Fatalf("expected a warning, got %v", result.Warnings)
+	}
+
+	cfg.Hooks = config.Hooks{OnRunEnd: "echo journal full >&2; exit 1"}
+	ag = NewAgent(&sequenceClient{responses: []llm.Response{{Content: "final"}}}, tools.NewRegistry(fakeTool{}), nil, zap.NewNop(), cfg)
+	result, _ = ag.Run(context.Background(), "what is this?", "/tmp", repo.RepoContext{RepoRoot: "/tmp"})
+	if !slices.Contains(result.Warnings, "on_run_end hook failed: journal full") {
+		t.Fatalf("expected the on_run_end failure in the result, got %v", result.Warnings)
+	}
+	if last := result.Events[len(result.Events)-1]; last.Type != events.Warning {
+		t.Fatalf("expected the on_run_end warning as the last event, got %s", last.Type)
+	}
+}
+
+// changingTool is a grep whose results change between calls, as after an
+// edit.
+type changingTool struct {
+	fakeTool
+	results [][]string
+	calls   *int
+}
+
+func (c changingTool) Execute(ctx context.Context, input json.RawMessage, meta tools.Meta) (tools.Result, error) {
+	matches := c.results[min(*c.calls, len(c.results)-1)]
+	*c.calls++
+	return tools.Result{ToolName: "grep", Payload: map[string]any{"matches": matches, "duration_ms": *c.calls}}, nil
+}
+
+func TestAgentDiffsRepeatedCalls(t *testing.T) {
+	args, _ := json.Marshal(map[string]any{"pattern": "retry"})
+	other, _ := json.Marshal(map[string]any{"pattern": "backoff"})
+	client := &recordingClient{sequenceClient: sequenceClient{responses: []llm.Response{
+		{ToolCalls: []llm.ToolCall{{ID: "c1", Name: "grep", Arguments: args}}},
+		{ToolCalls: []llm.ToolCall{{ID: "c2", Name: "grep", Arguments: args}, {ID: "c3", Name: "grep", Arguments: other}}},
+		{ToolCalls: []llm.ToolCall{{ID: "c4", Name: "grep", Arguments: args}}},
+		{Content: "final"},
+	}}}
+	calls := 0
+	tool := changingTool{calls: &calls, results: [][]string{
+		{"a.go:1:retry()", "b.go:2:retry(3)"},
+		{"a.go:1:retry()", "b.go:2:retry(5)", "c.go:9:retry()"},
+	}}
+	cfg := config.Config{Model: config.DefaultModel, MaxSteps: 5, JSON: true, NoPlan: true, NoHistory: true, ToolLimits: config.ToolLimits{GrepMaxCalls: 5}}
+	ag := NewAgent(client, tools.NewRegistry(tool), nil, zap.NewNop(), cfg)
+	result, err := ag.Run(context.Background(), "where do we retry?", "/tmp", repo.RepoContext{RepoRoot: "/tmp"})
+	if err != nil {
+		t.Fatalf("run failed: %v", err)
+	}
+	var finished []events.ToolCallFinishedPayload
+	for _, event := range result.Events {
+		if payload, ok := event.Payload.(events.ToolCallFinishedPayload); ok && event.Type == events.ToolCallFinished {
+			finished = append(finished, payload)
+		}
+	}
+	if len(finished) != 4 {
+		t.Fatalf("expected 4 finished calls, got %d", len(finished))
+	}
+	want := []string{"- matches: b.go:2:retry(3)", "+ matches: b.go:2:retry(5)", "+ matches: c.go:9:retry()"}
+	if finished[1].RepeatOf != "T1" || !slices.Equal(finished[1].Diff, want) {
+		t.Fatalf("expected T2 to diff against T1, got %q %q", finished[1].RepeatOf, finished[1].Diff)
+	}
+	if finished[2].RepeatOf != "" {
+		t.Fatalf("a call with other arguments is not a repeat, got %q", finished[2].RepeatOf)
+	}
+	if finished[3].RepeatOf != "T2" || len(finished[3].Diff) != 0 {
+		t.Fatalf("expected T4 to match T2, got %q %q", finished[3].RepeatOf, finished[3].Diff)
+	}
+
+	// the diff quotes tool output, so it stays in T2's wrapped tool result
+	third := client.requests[2].Messages
+	result2 := third[len(third)-2].OfTool
+	if result2 == nil || !strings.HasPrefix(result2.Content.OfString.Value, "[T2]\n<untrusted_output") ||
+		!strings.Contains(result2.Content.OfString.Value, "T2 repeats T1 (grep with the same arguments). Changed lines since T1 (- before, + now):\n- matches: b.go:2:retry(3)\n") {
+		t.Fatalf("expected the diff inside T2's tool message: %+v", third[len(third)-2])
+	}
+	for _, message := range third {
+		if message.OfDeveloper != nil && strings.Contains(message.OfDeveloper.Content.OfString.Value, "repeats") {
+			t.Fatalf("expected no developer message quoting the diff, got %q", message.OfDeveloper.Content.OfString.Value)
+		}
+	}
+	last, _ := json.Marshal(client.requests[3].Messages)
+	if !strings.Contains(string(last), "T4 repeats T2 (grep with the same arguments): the result is unchanged.") {
+		t.Fatalf("expected an unchanged note in the last request: %s", last)
+	}
+}
+
+func TestRepeatTrackerNormalizesArguments(t *testing.T) {
+	var repeats repeatTracker
+	args := func(fields map[string]any) json.RawMessage {
+		data, _ := json.Marshal(fields)
+		return data
+	}
+	grep := map[string]any{"matches": []string{"a.go:1:retry()"}}
+	repeats.observe("grep", args(map[string]any{"pattern": "retry", "paths": []string{"./internal/"}}), "T1", grep)
+	if diff, repeated := repeats.observe("grep", args(map[string]any{"pattern": "retry", "paths": []string{"internal"}}), "T2", grep); !repeated || diff.repeatOf != "T1" {
+		t.Fatalf("expected ./internal/ and internal to be one path, got %+v %v", diff, repeated)
+	}
+
+	read := func(start int, lines ...string) map[string]any {
+		return map[string]any{"path": "a.go", "start_line": start, "end_line": start + len(lines) - 1, "content": strings.Join(lines, "\n") + "\n"}
+	}
+	repeats.observe("read_file", args(map[string]any{"path": "a.go", "start_line": 1, "end_line": 3}), "T3", read(1, "package a", "", "func A() {}"))
+	diff, repeated := repeats.observe("read_file", args(map[string]any{"path": "./a.go", "start_line": 3, "end_line": 4}), "T4", read(3, "func A() int {}", "func B() {}"))
+	if !repeated || diff.repeatOf != "T3" || !slices.Equal(diff.changes, []string{"- 3: func A() {}", "+ 3: func A() int {}"}) {
+		t.Fatalf("expected overlapping ranges compared on line 3, got %+v %v", diff, repeated)
+	}
+	if msg := diff.message("T4", "read_file"); !strings.Contains(msg, "compared on lines 3-3") {
+		t.Fatalf("expected the overlap in the note, got %q", msg)
+	}
+	if _, repeated := repeats.observe("read_file", args(map[string]any{"path": "a.go", "start_line": 10, "end_line": 12}), "T5", read(10, "x", "y", "z")); repeated {
+		t.Fatalf("ranges that do not overlap are not repeats")
+	}
+}
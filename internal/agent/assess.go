@@ -0,0 +1,84 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"regexp"
+	"strings"
+
+	"fi-cli/internal/llm"
+
+	"github.com/openai/openai-go/v3"
+	"go.uber.org/zap"
+)
+
+// Assessment is the model's self-reported confidence in its final answer.
+type Assessment struct {
+	Confidence       float64  `json:"confidence"`
+	EvidenceCoverage float64  `json:"evidence_coverage"`
+	UnverifiedClaims []string `json:"unverified_claims"`
+}
+
+var citationPattern = regexp.MustCompile(`\[(T\d+|tool:[^\]]+|[^\]\s]+:\d+[^\]]*)\]`)
+
+const assessPrompt = `Assess the final answer you just gave. Return only JSON of the form {"confidence": <number 0-1>, "unverified_claims": ["..."]}.
+- confidence: how likely the answer is correct and complete given the evidence gathered.
+- unverified_claims: statements in the answer not backed by tool output or repository context (empty list if none).`
+
+func (a *Agent) assess(ctx context.Context, messages []openai.ChatCompletionMessageParamUnion, answer string) Assessment {
+	assessment := Assessment{EvidenceCoverage: evidenceCoverage(answer), UnverifiedClaims: []string{}}
+	history := append([]openai.ChatCompletionMessageParamUnion{}, messages...)
+	history = append(history, openai.AssistantMessage(answer), openai.DeveloperMessage(assessPrompt))
+	resp, err := a.create(ctx, llm.Request{Model: a.cfg.Model, Messages: history, MaxTokens: 400})
+	if err != nil {
+		a.logger.Warn("self-assessment failed", zap.Error(err))
+		return assessment
+	}
+	parsed, ok := parseAssessment(resp.Content)
+	if !ok {
+		a.logger.Warn("self-assessment returned invalid JSON")
+		return assessment
+	}
+	assessment.Confidence = parsed.Confidence
+	if parsed.UnverifiedClaims != nil {
+		assessment.UnverifiedClaims = parsed.UnverifiedClaims
+	}
+	return assessment
+}
+
+func parseAssessment(text string) (Assessment, bool) {
+	start := strings.Index(text, "{")
+	end := strings.LastIndex(text, "}")
+	if start == -1 || end <= start {
+		return Assessment{}, false
+	}
+	var parsed Assessment
+	if err := json.Unmarshal([]byte(text[start:end+1]), &parsed); err != nil {
+		return Assessment{}, false
+	}
+	if parsed.Confidence < 0 {
+		parsed.Confidence = 0
+	}
+	if parsed.Confidence > 1 {
+		parsed.Confidence = 1
+	}
+	return parsed, true
+}
+
+// evidenceCoverage is the share of non-empty answer lines carrying at least one citation.
+func evidenceCoverage(answer string) float64 {
+	total, cited := 0, 0
+	for _, line := range strings.Split(answer, "\n") {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		total++
+		if citationPattern.MatchString(line) {
+			cited++
+		}
+	}
+	if total == 0 {
+		return 0
+	}
+	return float64(cited) / float64(total)
+}
@@ -0,0 +1,80 @@
+package agent
+
+import "fmt"
+
+// Tool categories share one call budget per run, on top of the per-tool caps.
+const (
+	categorySearch  = "search"
+	categoryInspect = "inspect"
+	categoryShell   = "shell"
+	categoryWeb     = "web"
+)
+
+var toolCategories = map[string]string{
+	"grep":           categorySearch,
+	"list_files":     categorySearch,
+	"read_file":      categorySearch,
+	"archive_list":   categoryInspect,
+	"archive_read":   categoryInspect,
+	"data_preview":   categoryInspect,
+	"api_schema":     categoryInspect,
+	"env_usage":      categoryInspect,
+	"docker_analyze": categoryInspect,
+	"ci_config":      categoryInspect,
+	"dep_docs":       categoryInspect,
+	"shell":          categoryShell,
+	"exa_search":     categoryWeb,
+	"exa_contents":   categoryWeb,
+	"code_search":    categoryWeb,
+	"issue_lookup":   categoryWeb,
+}
+
+// budgetError is returned to the model in place of a tool result once a cap
+// is spent, so it answers from the evidence it has instead of retrying.
+type budgetError struct {
+	category string
+	tool     string
+	limit    int
+}
+
+func (e budgetError) Error() string {
+	if e.category == "" {
+		return fmt.Sprintf("tool call limit reached for %s (%d calls this run); answer from the evidence gathered so far", e.tool, e.limit)
+	}
+	return fmt.Sprintf("%s budget exhausted: %d of %d %s calls used this run; answer from the evidence gathered so far", e.category, e.limit, e.limit, e.category)
+}
+
+// checkToolBudget reports whether another call to toolName fits both its own
+// cap and its category's. usage counts calls per tool name.
+func (a *Agent) checkToolBudget(toolName string, usage map[string]int) error {
+	limits := a.cfg.ToolLimits
+	perTool := map[string]int{"grep": limits.GrepMaxCalls, "shell": limits.ShellMaxCalls, "ask_user": limits.AskUserMaxCalls}
+	if limit, ok := perTool[toolName]; ok && usage[toolName] >= limit {
+		return budgetError{tool: toolName, limit: limit}
+	}
+	category := toolCategories[toolName]
+	var limit int
+	switch category {
+	case categorySearch:
+		limit = limits.SearchMaxCalls
+	case categoryInspect:
+		limit = limits.InspectMaxCalls
+	case categoryWeb:
+		limit = limits.WebMaxCalls
+	}
+	// shell is capped per tool only. Load fills in every category cap, so a
+	// zero one only comes from a Config built directly and leaves it uncapped.
+	if limit <= 0 {
+		return nil
+	}
+	used := 0
+	for name, count := range usage {
+		if toolCategories[name] == category {
+			used += count
+		}
+	}
+	if used >= limit {
+		return budgetError{category: category, tool: toolName, limit: limit}
+	}
+	return nil
+}
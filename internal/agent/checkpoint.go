@@ -0,0 +1,86 @@
+package agent
+
+import (
+	"context"
+	"maps"
+	"time"
+
+	"fi-cli/internal/config"
+	"fi-cli/internal/events"
+	"fi-cli/internal/llm"
+	"fi-cli/internal/repo"
+	"fi-cli/internal/tools"
+
+	"github.com/openai/openai-go/v3"
+)
+
+// Checkpoint is the loop state of a run that hit cfg.Timeout, saved so the
+// run can be continued instead of restarted.
+type Checkpoint struct {
+	RunID     string                                   `json:"run_id"`
+	CreatedAt time.Time                                `json:"created_at"`
+	RepoRoot  string                                   `json:"repo_root"`
+	Question  string                                   `json:"question"`
+	Model     string                                   `json:"model"`
+	Route     string                                   `json:"route,omitempty"`
+	Pipeline  bool                                     `json:"pipeline,omitempty"`
+	Labels    map[string]string                        `json:"labels,omitempty"`
+	Step      int                                      `json:"step"`
+	Messages  []openai.ChatCompletionMessageParamUnion `json:"messages"`
+	ToolCalls []ToolCallRecord                         `json:"tool_calls"`
+	ToolUsage map[string]int                           `json:"tool_usage"`
+	Artifacts []Artifact                               `json:"artifacts,omitempty"`
+	Usage     llm.Usage                                `json:"usage"`
+}
+
+// Continue resumes a checkpointed run: the loop picks up at the step that
+// timed out, with the saved messages, tool records, and budgets. Setup
+// warnings, routing, planning, and pipeline stages are not repeated.
+func (a *Agent) Continue(ctx context.Context, cp Checkpoint, repoCtx repo.RepoContext) (RunResult, error) {
+	a.resume = &cp
+	defer func() { a.resume = nil }()
+	return a.Run(ctx, cp.Question, cp.RepoRoot, repoCtx)
+}
+
+// checkpoint captures the loop state after steps completed steps.
+func (a *Agent) checkpoint(result RunResult, steps int, messages []openai.ChatCompletionMessageParamUnion, toolUsage map[string]int) *Checkpoint {
+	return &Checkpoint{
+		RunID:     result.RunID,
+		CreatedAt: time.Now(),
+		RepoRoot:  result.RepoRoot,
+		Question:  result.Question,
+		Model:     a.cfg.Model,
+		Route:     result.Route,
+		Pipeline:  a.cfg.Pipeline,
+		Labels:    result.Labels,
+		Step:      steps,
+		Messages:  messages,
+		ToolCalls: result.ToolCalls,
+		ToolUsage: maps.Clone(toolUsage),
+		Artifacts: result.Artifacts,
+		Usage:     a.usage,
+	}
+}
+
+// resumeState restores the loop state saved in cp.
+func (a *Agent) resumeState(cp Checkpoint, result *RunResult, emit func(events.Event)) loopState {
+	registry := a.tools.Without(routeProfiles[cp.Route].hide...)
+	if cp.Pipeline {
+		registry = tools.NewRegistry()
+	}
+	result.Route = cp.Route
+	result.Artifacts = cp.Artifacts
+	result.ToolCalls = append(result.ToolCalls, cp.ToolCalls...)
+	result.ContinuedFrom = cp.RunID
+	a.usage = cp.Usage
+	toolUsage := cp.ToolUsage
+	if toolUsage == nil {
+		toolUsage = map[string]int{}
+	}
+	route := cp.Route
+	if route == "" {
+		route = config.RouteGeneral
+	}
+	emit(events.Event{Type: events.QuestionRouted, Timestamp: time.Now(), Payload: events.QuestionRoutedPayload{Route: route, Source: "checkpoint", Tools: registry.Names()}})
+	return loopState{messages: cp.Messages, registry: registry, steps: cp.Step, toolUsage: toolUsage}
+}
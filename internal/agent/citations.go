@@ -0,0 +1,45 @@
+package agent
+
+import (
+	"fmt"
+	"regexp"
+
+	"fi-cli/internal/events"
+)
+
+var toolCitationPattern = regexp.MustCompile(`\[(T\d+)\]`)
+
+// toolResultID is the citation ID of the tool call after the first count.
+func toolResultID(count int) string {
+	return fmt.Sprintf("T%d", count+1)
+}
+
+// toolResultMessage prefixes a tool message with its citation ID.
+func toolResultMessage(id, content string) string {
+	return "[" + id + "]\n" + content
+}
+
+// linkCitations resolves each distinct [T<n>] marker in answer to its tool
+// call record, in order of first appearance.
+func linkCitations(answer string, records []ToolCallRecord) []events.Citation {
+	var citations []events.Citation
+	seen := map[string]bool{}
+	for _, match := range toolCitationPattern.FindAllStringSubmatch(answer, -1) {
+		id := match[1]
+		if seen[id] {
+			continue
+		}
+		seen[id] = true
+		citation := events.Citation{ID: id, ToolCall: -1}
+		for i, record := range records {
+			if record.ID == id {
+				citation.ToolName = record.ToolName
+				citation.Input = record.Input
+				citation.ToolCall = i
+				break
+			}
+		}
+		citations = append(citations, citation)
+	}
+	return citations
+}
@@ -0,0 +1,210 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+
+	"fi-cli/internal/llm"
+
+	"github.com/openai/openai-go/v3"
+	"github.com/openai/openai-go/v3/packages/param"
+	"github.com/openai/openai-go/v3/shared"
+	"go.uber.org/zap"
+)
+
+// Consensus records how the models answered in consensus mode and what the
+// judge made of their answers.
+type Consensus struct {
+	Judge         string        `json:"judge"`
+	Answers       []ModelAnswer `json:"answers"`
+	Agreements    []string      `json:"agreements"`
+	Disagreements []string      `json:"disagreements"`
+}
+
+// ModelAnswer is one model's answer, or why it has none.
+type ModelAnswer struct {
+	Model  string `json:"model"`
+	Answer string `json:"answer,omitempty"`
+	Error  string `json:"error,omitempty"`
+}
+
+// judgeToolName is the function the judge request forces, so agreements and
+// disagreements come back as lists.
+const judgeToolName = "submit_consensus"
+
+const judgePrompt = `You are the judge. Several models answered the question above from the same evidence; their answers follow. Call ` + judgeToolName + ` with:
+- answer: the reconciled answer, in the usual answer format with citations. Prefer claims the evidence supports; where the answers conflict and the evidence cannot settle it, say so.
+- agreements: the main points every answer makes, one short line each.
+- disagreements: the points where answers differ, naming the answers (e.g. "Answer 2 says the migration is reversible; answers 1 and 3 say it drops a column").`
+
+func (a *Agent) consensusEnabled() bool {
+	return a.cfg.Consensus.Answers > 1
+}
+
+// consensus asks the consensus models for their answer from the evidence in
+// messages, then has the judge reconcile them with primary, the run model's
+// answer. Without at least one other answer, or when the judge fails, the
+// primary answer stands and warn says why.
+func (a *Agent) consensus(ctx context.Context, messages []openai.ChatCompletionMessageParamUnion, toolsDefs []openai.ChatCompletionToolUnionParam, primary string, warn func(string)) (string, *Consensus) {
+	record := &Consensus{Judge: a.cfg.Consensus.Judge, Answers: []ModelAnswer{{Model: a.cfg.Model, Answer: primary}}}
+	if record.Judge == "" {
+		record.Judge = a.cfg.Model
+	}
+	peers := a.peerAnswers(ctx, messages, toolsDefs)
+	record.Answers = append(record.Answers, peers...)
+	answered := 0
+	for _, answer := range record.Answers {
+		if answer.Answer != "" {
+			answered++
+		}
+	}
+	if answered < 2 {
+		warn("consensus: no other model answered; showing the answer of " + a.cfg.Model)
+		return primary, record
+	}
+
+	var candidates strings.Builder
+	for i, answer := range record.Answers {
+		if answer.Answer != "" {
+			fmt.Fprintf(&candidates, "\n\n### Answer %d (%s)\n%s", i+1, answer.Model, answer.Answer)
+		}
+	}
+	judged := append(slices.Clone(messages), openai.DeveloperMessage(judgePrompt+candidates.String()))
+	forced := openai.ChatCompletionToolChoiceOptionUnionParam{OfFunctionToolChoice: &openai.ChatCompletionNamedToolChoiceParam{
+		Function: openai.ChatCompletionNamedToolChoiceFunctionParam{Name: judgeToolName},
+	}}
+	resp, err := a.create(ctx, llm.Request{Model: record.Judge, Messages: judged, Tools: append(slices.Clone(toolsDefs), judgeTool()), ToolChoice: forced, MaxTokens: maxTokensFor(a.cfg.Verbosity)})
+	if err != nil {
+		a.logger.Warn("consensus judge failed", zap.Error(err))
+		warn(fmt.Sprintf("consensus: judge %s failed (%v); showing the answer of %s", record.Judge, err, a.cfg.Model))
+		return primary, record
+	}
+	verdict, ok := parseVerdict(resp.ToolCalls)
+	if !ok {
+		if content := strings.TrimSpace(resp.Content); content != "" {
+			return content, record
+		}
+		warn(fmt.Sprintf("consensus: judge %s returned no verdict; showing the answer of %s", record.Judge, a.cfg.Model))
+		return primary, record
+	}
+	record.Agreements, record.Disagreements = verdict.Agreements, verdict.Disagreements
+	return consensusAnswer(verdict), record
+}
+
+// peerAnswers asks every consensus model at once. Tools stay declared, since
+// the messages hold tool calls, but none may be called.
+func (a *Agent) peerAnswers(ctx context.Context, messages []openai.ChatCompletionMessageParamUnion, toolsDefs []openai.ChatCompletionToolUnionParam) []ModelAnswer {
+	models := a.cfg.Consensus.Models
+	answers := make([]ModelAnswer, len(models))
+	usage := make([]llm.Usage, len(models))
+	var wg sync.WaitGroup
+	for i, model := range models {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			req := llm.Request{Model: model, Messages: messages, Tools: toolsDefs, MaxTokens: maxTokensFor(a.cfg.Verbosity)}
+			if len(toolsDefs) > 0 {
+				req.ToolChoice = openai.ChatCompletionToolChoiceOptionUnionParam{OfAuto: param.NewOpt("none")}
+			}
+			reqCtx := ctx
+			if a.cfg.RequestTimeout > 0 {
+				var cancel context.CancelFunc
+				reqCtx, cancel = context.WithTimeout(ctx, a.cfg.RequestTimeout)
+				defer cancel()
+			}
+			resp, err := a.client.Create(reqCtx, req)
+			usage[i] = resp.Usage
+			answers[i] = ModelAnswer{Model: model, Answer: strings.TrimSpace(resp.Content)}
+			switch {
+			case err != nil:
+				answers[i] = ModelAnswer{Model: model, Error: err.Error()}
+			case answers[i].Answer == "":
+				answers[i].Error = "empty answer"
+			}
+		}()
+	}
+	wg.Wait()
+	// the usage of every model is priced at the run model's rates
+	for _, u := range usage {
+		a.usage.Add(u)
+	}
+	return answers
+}
+
+type verdict struct {
+	Answer        string   `json:"answer"`
+	Agreements    []string `json:"agreements"`
+	Disagreements []string `json:"disagreements"`
+}
+
+func parseVerdict(calls []llm.ToolCall) (verdict, bool) {
+	for _, call := range calls {
+		if call.Name != judgeToolName {
+			continue
+		}
+		var v verdict
+		if err := json.Unmarshal(call.Arguments, &v); err != nil || strings.TrimSpace(v.Answer) == "" {
+			return verdict{}, false
+		}
+		v.Agreements, v.Disagreements = nonEmpty(v.Agreements), nonEmpty(v.Disagreements)
+		return v, true
+	}
+	return verdict{}, false
+}
+
+// consensusAnswer is the judge's answer followed by what the models agreed
+// and disagreed on.
+func consensusAnswer(v verdict) string {
+	var b strings.Builder
+	b.WriteString(strings.TrimSpace(v.Answer))
+	for _, section := range []struct {
+		title  string
+		points []string
+	}{{"Models agree:", v.Agreements}, {"Models disagree:", v.Disagreements}} {
+		if len(section.points) == 0 {
+			continue
+		}
+		b.WriteString("\n\n" + section.title)
+		for _, point := range section.points {
+			b.WriteString("\n- " + point)
+		}
+	}
+	return b.String()
+}
+
+func judgeTool() openai.ChatCompletionToolUnionParam {
+	list := map[string]any{"type": "array", "items": map[string]any{"type": "string"}}
+	return openai.ChatCompletionToolUnionParam{
+		OfFunction: &openai.ChatCompletionFunctionToolParam{
+			Function: shared.FunctionDefinitionParam{
+				Name:        judgeToolName,
+				Description: param.NewOpt("Submit the reconciled answer and where the answers agree and differ."),
+				Parameters: map[string]any{
+					"type": "object",
+					"properties": map[string]any{
+						"answer":        map[string]any{"type": "string"},
+						"agreements":    list,
+						"disagreements": list,
+					},
+					"required":             []string{"answer", "agreements", "disagreements"},
+					"additionalProperties": false,
+				},
+				Strict: param.NewOpt(true),
+			},
+		},
+	}
+}
+
+func nonEmpty(lines []string) []string {
+	out := []string{}
+	for _, line := range lines {
+		if line = strings.TrimSpace(line); line != "" {
+			out = append(out, line)
+		}
+	}
+	return out
+}
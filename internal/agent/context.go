@@ -0,0 +1,106 @@
+package agent
+
+import (
+	"context"
+	"os"
+	"strings"
+
+	"fi-cli/internal/config"
+	"fi-cli/internal/repo"
+	"fi-cli/internal/session"
+	"fi-cli/internal/util"
+)
+
+// ContextSnapshot is the question-independent context a run sends to the model.
+type ContextSnapshot struct {
+	RepoRoot    string   `json:"repo_root"`
+	Repository  string   `json:"repository"`
+	Snippets    []string `json:"snippets"`
+	Warnings    []string `json:"warnings,omitempty"`
+	History     []string `json:"history"`
+	Terminal    string   `json:"terminal,omitempty"`
+	Conventions []string `json:"conventions,omitempty"`
+	Glossary    []string `json:"glossary,omitempty"`
+	Messages    []string `json:"messages"`
+}
+
+// BuildContextSnapshot returns the repository and history context exactly as
+// Run would send it for cfg.
+func BuildContextSnapshot(cfg config.Config, repoCtx repo.RepoContext) ContextSnapshot {
+	snapshot := ContextSnapshot{
+		RepoRoot:   repoCtx.RepoRoot,
+		Repository: repoCtx.Summary(),
+		Snippets:   []string{},
+		Warnings:   repoCtx.Warnings,
+		History:    LoadHistory(cfg, repoCtx.RepoRoot),
+	}
+	if snapshot.History == nil {
+		snapshot.History = []string{}
+	}
+	for _, snip := range repoCtx.Snippets {
+		snapshot.Snippets = append(snapshot.Snippets, snip.Path)
+	}
+	snapshot.Messages = append(snapshot.Messages, repoContextMessage(cfg, repoCtx))
+	if docs := loadConventions(cfg, repoCtx.RepoRoot); len(docs) > 0 {
+		for _, doc := range docs {
+			snapshot.Conventions = append(snapshot.Conventions, doc.Path)
+		}
+		snapshot.Messages = append(snapshot.Messages, strings.TrimSpace(conventionsPrompt(cfg, docs)))
+	}
+	if facts := loadGlossary(cfg, repoCtx.RepoRoot); len(facts) > 0 {
+		for _, fact := range facts {
+			snapshot.Glossary = append(snapshot.Glossary, fact.Text)
+		}
+		snapshot.Messages = append(snapshot.Messages, glossaryMessage(cfg, facts))
+	}
+	if len(snapshot.History) > 0 {
+		snapshot.Messages = append(snapshot.Messages, historyMessage(snapshot.History))
+	}
+	if source, text, err := LoadTerminalOutput(context.Background(), cfg); err != nil {
+		snapshot.Warnings = append(snapshot.Warnings, "terminal output: "+err.Error())
+	} else if text != "" {
+		snapshot.Terminal = text
+		snapshot.Messages = append(snapshot.Messages, terminalMessage(source, text))
+	}
+	return snapshot
+}
+
+// LoadTerminalOutput returns recent terminal output: the tmux/screen pane
+// with --capture-pane, otherwise the log of an enclosing record-session shell.
+func LoadTerminalOutput(ctx context.Context, cfg config.Config) (string, string, error) {
+	if cfg.CapturePane {
+		text, source, err := util.CapturePane(ctx, cfg.ToolLimits.PaneMaxBytes)
+		return source + " scrollback", text, err
+	}
+	path := os.Getenv(session.EnvVar)
+	if path == "" || cfg.NoSession {
+		return "", "", nil
+	}
+	text, err := session.Tail(path, cfg.ToolLimits.PaneMaxBytes)
+	return "recorded session", text, err
+}
+
+// LoadHistory returns the shell history lines a run in repoRoot includes for cfg.
+func LoadHistory(cfg config.Config, repoRoot string) []string {
+	if cfg.NoHistory || cfg.HistoryLines <= 0 {
+		return nil
+	}
+	return util.LoadHistory(util.HistoryOptions{
+		MaxLines: cfg.HistoryLines,
+		Sources:  cfg.HistorySources,
+		Exclude:  cfg.HistoryExclude,
+		Dir:      repoRoot,
+	})
+}
+
+func repoContextMessage(cfg config.Config, repoCtx repo.RepoContext) string {
+	return "Repository context:\n" + guardRepoText(cfg.InjectionGuard, repoCtx.Summary())
+}
+
+func historyMessage(lines []string) string {
+	return "Recent shell history (most recent last):\n- " + strings.Join(lines, "\n- ")
+}
+
+func terminalMessage(source, text string) string {
+	return "Recent terminal output (" + source + ", most recent last):\n" + text
+}
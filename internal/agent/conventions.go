@@ -0,0 +1,103 @@
+package agent
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"fi-cli/internal/config"
+	"fi-cli/internal/memory"
+	"fi-cli/internal/util"
+)
+
+const (
+	// conventionsFile is read from the user config directory and the repo
+	// root and appended to the developer prompt.
+	conventionsFile     = "conventions.md"
+	maxConventionsBytes = 8 * 1024
+)
+
+type conventionsDoc struct {
+	Path string
+	Text string
+	Repo bool
+}
+
+// loadConventions returns the user-level and repo-level conventions, in that
+// order, so repository rules read last and take precedence. The repo level is
+// conventions.md followed by the conventions section of .fi/knowledge.yaml.
+func loadConventions(cfg config.Config, repoRoot string) []conventionsDoc {
+	if cfg.NoConventions {
+		return nil
+	}
+	var docs []conventionsDoc
+	repoPath := ""
+	if repoRoot != "" {
+		repoPath = filepath.Join(repoRoot, conventionsFile)
+	}
+	for _, base := range config.ConfigSearchBases() {
+		path := filepath.Join(base, conventionsFile)
+		if path == repoPath {
+			continue
+		}
+		if doc, ok := readConventions(path); ok {
+			docs = append(docs, doc)
+			break
+		}
+	}
+	if repoPath != "" {
+		repoFS := knowledgeFS(repoRoot)
+		if data, err := repoFS.ReadFile(conventionsFile); err == nil {
+			if doc, ok := conventionsText(repoPath, data); ok {
+				doc.Repo = true
+				docs = append(docs, doc)
+			}
+		}
+		if k, err := memory.LoadKnowledgeFrom(repoFS); err == nil && k.Conventions != "" {
+			text := k.Conventions
+			if len(text) > maxConventionsBytes {
+				text = util.CutBytes(text, maxConventionsBytes) + "\n[truncated]"
+			}
+			docs = append(docs, conventionsDoc{Path: filepath.Join(repoRoot, memory.KnowledgeFile), Text: text, Repo: true})
+		}
+	}
+	return docs
+}
+
+func readConventions(path string) (conventionsDoc, bool) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return conventionsDoc{}, false
+	}
+	return conventionsText(path, data)
+}
+
+func conventionsText(path string, data []byte) (conventionsDoc, bool) {
+	text := strings.TrimSpace(string(data))
+	if text == "" {
+		return conventionsDoc{}, false
+	}
+	if len(text) > maxConventionsBytes {
+		text = util.CutBytes(text, maxConventionsBytes) + "\n[truncated]"
+	}
+	return conventionsDoc{Path: path, Text: text}, true
+}
+
+// conventionsPrompt renders docs as a section appended to the developer prompt.
+// Repo files are guarded like other repository text; the user file is trusted.
+func conventionsPrompt(cfg config.Config, docs []conventionsDoc) string {
+	if len(docs) == 0 {
+		return ""
+	}
+	var b strings.Builder
+	b.WriteString("\n\nTeam conventions (follow them when relevant, e.g. for commit style, branch names, and deployment steps; repository conventions override user conventions):")
+	for _, doc := range docs {
+		text := doc.Text
+		if doc.Repo {
+			text = guardRepoText(cfg.InjectionGuard, text)
+		}
+		fmt.Fprintf(&b, "\n\n# %s\n%s", doc.Path, text)
+	}
+	return b.String()
+}
@@ -0,0 +1,84 @@
+package agent
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// pausableDeadline is a run deadline whose clock stops while the run waits on
+// the user (ask_user), so a clarifying question does not spend cfg.Timeout.
+// Once it expires, Err reports context.DeadlineExceeded like WithTimeout.
+type pausableDeadline struct {
+	context.Context
+	done chan struct{}
+	stop func() bool
+
+	mu        sync.Mutex
+	err       error
+	timer     *time.Timer
+	remaining time.Duration
+	since     time.Time
+	paused    bool
+}
+
+func withPausableTimeout(parent context.Context, timeout time.Duration) (*pausableDeadline, context.CancelFunc) {
+	d := &pausableDeadline{Context: parent, done: make(chan struct{}), remaining: timeout, since: time.Now()}
+	// both callbacks run on their own goroutines and take d.mu in expire, so
+	// hold it until the fields they read are set
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.timer = time.AfterFunc(timeout, func() { d.expire(context.DeadlineExceeded) })
+	d.stop = context.AfterFunc(parent, func() { d.expire(parent.Err()) })
+	return d, func() { d.expire(context.Canceled) }
+}
+
+func (d *pausableDeadline) expire(err error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.err != nil {
+		return
+	}
+	d.err = err
+	d.timer.Stop()
+	d.stop()
+	close(d.done)
+}
+
+func (d *pausableDeadline) Done() <-chan struct{} { return d.done }
+
+func (d *pausableDeadline) Err() error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.err
+}
+
+func (d *pausableDeadline) Deadline() (time.Time, bool) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.paused {
+		return time.Now().Add(d.remaining), true
+	}
+	return d.since.Add(d.remaining), true
+}
+
+// Pause stops the clock until the returned resume func is called.
+func (d *pausableDeadline) Pause() (resume func()) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.paused || d.err != nil || !d.timer.Stop() {
+		return func() {}
+	}
+	d.paused = true
+	d.remaining -= time.Since(d.since)
+	return func() {
+		d.mu.Lock()
+		defer d.mu.Unlock()
+		if d.err != nil {
+			return
+		}
+		d.paused = false
+		d.since = time.Now()
+		d.timer.Reset(max(d.remaining, 0))
+	}
+}
@@ -0,0 +1,143 @@
+package agent
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+
+	"fi-cli/internal/guard"
+	"fi-cli/internal/util"
+
+	"github.com/openai/openai-go/v3"
+	"github.com/openai/openai-go/v3/shared/constant"
+)
+
+// Exchange is an earlier question and answer that a follow-up builds on.
+type Exchange struct {
+	Question string
+	Answer   string
+	// ToolCalls is how many tool IDs the exchange's run used.
+	ToolCalls int
+	// Evidence is the run's tool results, from EvidenceOf. Only a session
+	// that still holds them sets it.
+	Evidence []EvidenceCall
+}
+
+// EvidenceCall is one tool call of an earlier run, replayed to a follow-up
+// as a tool call and its result.
+type EvidenceCall struct {
+	ID        string
+	ToolName  string
+	Arguments string
+	// Output is the result as JSON, guarded and truncated when replayed.
+	Output string
+}
+
+// ExchangeOf returns the exchange a finished run adds to a follow-up chain.
+func ExchangeOf(result RunResult) Exchange {
+	return Exchange{Question: result.Question, Answer: result.FinalAnswer, ToolCalls: len(result.ToolCalls)}
+}
+
+// Evidence digests are bounded per tool call and per exchange.
+const (
+	evidenceCallBytes = 1500
+	evidenceMaxBytes  = 16 << 10
+)
+
+// EvidenceOf keeps a run's tool results, so a follow-up in the same session
+// can cite them under their tool IDs without calling the tools again. Calls
+// past evidenceMaxBytes are left out.
+func EvidenceOf(result RunResult) []EvidenceCall {
+	var evidence []EvidenceCall
+	size := 0
+	for _, call := range result.ToolCalls {
+		entry := EvidenceCall{ID: call.ID, ToolName: call.ToolName, Arguments: evidenceJSON(call.Input), Output: evidenceJSON(call.Output)}
+		// recorded inputs are already JSON text
+		if input, ok := call.Input.(string); ok && json.Valid([]byte(input)) {
+			entry.Arguments = input
+		}
+		size += len(entry.Arguments) + min(len(entry.Output), evidenceCallBytes)
+		if size > evidenceMaxBytes {
+			break
+		}
+		evidence = append(evidence, entry)
+	}
+	return evidence
+}
+
+// evidenceJSON encodes value without escaping "<" and "&", so the guard
+// scans replayed results as the model first saw them.
+func evidenceJSON(value any) string {
+	var b bytes.Buffer
+	encoder := json.NewEncoder(&b)
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(value); err != nil {
+		return "null"
+	}
+	return strings.TrimSuffix(b.String(), "\n")
+}
+
+const followUpNote = "The next question follows up on the conversation above. Resolve references such as \"it\" or \"that\" against it. Its tool results are no longer available: call tools again for any evidence you cite."
+
+const evidenceNote = "The next question follows up on the conversation above. Resolve references such as \"it\" or \"that\" against it. Earlier tool results are replayed, shortened, under their IDs: cite them again where they still answer the question, and call tools for anything they do not cover."
+
+// FollowUp makes Run answer its question as a follow-up to exchanges, oldest
+// first. They are replayed ahead of the question, and tool IDs continue after
+// the ones their answers cite.
+func (a *Agent) FollowUp(exchanges ...Exchange) {
+	a.exchanges = exchanges
+	a.toolIDOffset = 0
+	for _, exchange := range exchanges {
+		a.toolIDOffset += exchange.ToolCalls
+	}
+}
+
+// exchangeMessages replays the follow-up chain as user and assistant turns.
+// An exchange's evidence is replayed as the assistant's tool calls and their
+// tool-role results, never in a developer message.
+func (a *Agent) exchangeMessages() []openai.ChatCompletionMessageParamUnion {
+	if len(a.exchanges) == 0 {
+		return nil
+	}
+	var messages []openai.ChatCompletionMessageParamUnion
+	note := followUpNote
+	for _, exchange := range a.exchanges {
+		messages = append(messages, openai.UserMessage(exchange.Question))
+		if len(exchange.Evidence) > 0 {
+			calls := make([]openai.ChatCompletionMessageToolCallUnionParam, 0, len(exchange.Evidence))
+			for _, call := range exchange.Evidence {
+				calls = append(calls, openai.ChatCompletionMessageToolCallUnionParam{
+					OfFunction: &openai.ChatCompletionMessageFunctionToolCallParam{
+						ID:       "replay_" + call.ID,
+						Function: openai.ChatCompletionMessageFunctionToolCallFunctionParam{Name: call.ToolName, Arguments: call.Arguments},
+						Type:     constant.Function("function"),
+					},
+				})
+			}
+			messages = append(messages, openai.ChatCompletionMessageParamUnion{OfAssistant: &openai.ChatCompletionAssistantMessageParam{ToolCalls: calls}})
+			for _, call := range exchange.Evidence {
+				messages = append(messages, openai.ToolMessage(toolResultMessage(call.ID, a.wrapForReplay(call.ToolName, call.Output)), "replay_"+call.ID))
+			}
+			note = evidenceNote
+		}
+		messages = append(messages, openai.AssistantMessage(exchange.Answer))
+	}
+	return append(messages, openai.DeveloperMessage(note))
+}
+
+// wrapForReplay shortens a replayed tool result and wraps it like a fresh
+// one, minus the classifier: it was screened when the tool first returned
+// it. The wrapper stays with injection_guard.mode off too: the model did not
+// ask for this output in the current run.
+func (a *Agent) wrapForReplay(toolName, output string) string {
+	mode := a.cfg.InjectionGuard.Mode
+	var findings []guard.Finding
+	if mode != guard.ModeOff {
+		findings = guard.ScanJSON(output)
+		if len(findings) > 0 && mode == guard.ModeNeutralize {
+			output = guard.NeutralizeJSON(output)
+		}
+	}
+	output, _ = util.TruncateBytes(output, evidenceCallBytes)
+	return guard.Wrap(toolName, output, findings, mode == guard.ModeNeutralize)
+}
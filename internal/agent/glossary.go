@@ -0,0 +1,79 @@
+package agent
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"fi-cli/internal/config"
+	"fi-cli/internal/memory"
+	"fi-cli/internal/policy"
+	"fi-cli/internal/tools"
+)
+
+// maxGlossaryFacts caps how many remembered facts go into the prompt. Shared
+// facts are kept first; of the local ones, the most recent win.
+const maxGlossaryFacts = 50
+
+// loadGlossary returns the facts shared in .fi/knowledge.yaml followed by the
+// ones remembered locally with `fi remember`. Unreadable stores yield what
+// could be read rather than failing the run.
+func loadGlossary(cfg config.Config, repoRoot string) []memory.Entry {
+	if cfg.NoMemory || repoRoot == "" {
+		return nil
+	}
+	entries, _ := memory.MergedFrom(knowledgeFS(repoRoot), cfg.User, repoRoot)
+	if len(entries) <= maxGlossaryFacts {
+		return entries
+	}
+	// shared facts come first in entries
+	shared := 0
+	for shared < len(entries) && entries[shared].Shared {
+		shared++
+	}
+	if shared >= maxGlossaryFacts {
+		return entries[:maxGlossaryFacts]
+	}
+	local := entries[shared:]
+	return slices.Concat(entries[:shared], local[len(local)-(maxGlossaryFacts-shared):])
+}
+
+// knowledgeFS reads .fi/knowledge.yaml under the same path rules as the
+// tools, so a symlink cannot pull a denied file into the prompt. A policy
+// that fails to load already fails the run; the denylist applies regardless.
+func knowledgeFS(repoRoot string) *tools.RepoFS {
+	pathPolicy, _ := policy.LoadPathPolicy(repoRoot)
+	return tools.NewRepoFS(repoRoot, pathPolicy)
+}
+
+// glossaryMessage renders remembered facts as a developer message. Local facts
+// come from the user and are trusted; shared facts are repository text and
+// are guarded like it.
+func glossaryMessage(cfg config.Config, entries []memory.Entry) string {
+	var shared, local strings.Builder
+	for _, entry := range entries {
+		if entry.Shared {
+			fmt.Fprintf(&shared, "\n- %s", entry.Text)
+		} else {
+			fmt.Fprintf(&local, "\n- %s%s", entry.Text, changedNote(entry.Changed))
+		}
+	}
+	var b strings.Builder
+	b.WriteString("Project glossary (facts about this repository; treat them as true unless the code clearly contradicts them):")
+	if shared.Len() > 0 {
+		fmt.Fprintf(&b, "\n\n# shared in %s\n%s", memory.KnowledgeFile, guardRepoText(cfg.InjectionGuard, strings.TrimPrefix(shared.String(), "\n")))
+	}
+	if local.Len() > 0 {
+		fmt.Fprintf(&b, "\n\n# remembered by the user\n%s", strings.TrimPrefix(local.String(), "\n"))
+	}
+	return b.String()
+}
+
+// changedNote warns that a fact predates changes to the files it may
+// describe.
+func changedNote(changed []string) string {
+	if len(changed) == 0 {
+		return ""
+	}
+	return " (saved before " + strings.Join(changed, ", ") + " changed; check it against the code)"
+}
@@ -0,0 +1,48 @@
+package agent
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"os/user"
+
+	"fi-cli/internal/policy"
+)
+
+// hookInput describes a tool call to the configured policy hook.
+func (a *Agent) hookInput(toolName string, arguments json.RawMessage, repoRoot string) policy.HookInput {
+	input := policy.HookInput{
+		Tool:      toolName,
+		Arguments: hookArguments(arguments),
+		Repo:      repoRoot,
+		Config: policy.HookConfig{
+			Model:          a.cfg.Model,
+			UnsafeShell:    a.cfg.UnsafeShell,
+			ShellAllowlist: a.cfg.ShellAllowlist,
+			NoWeb:          a.cfg.NoWeb,
+			Labels:         a.cfg.Labels,
+		},
+	}
+	if current, err := user.Current(); err == nil {
+		input.User = current.Username
+	}
+	input.Home, _ = os.UserHomeDir()
+	return input
+}
+
+// hookArguments returns a call's arguments as valid JSON for hook input; a
+// model can send malformed arguments, which tools reject on their own.
+func hookArguments(arguments json.RawMessage) json.RawMessage {
+	if len(arguments) == 0 || !json.Valid(arguments) {
+		return json.RawMessage("{}")
+	}
+	return arguments
+}
+
+// hookDenial is the error the model sees when the policy hook refuses a call.
+func hookDenial(decision policy.HookDecision) error {
+	if decision.Reason == "" {
+		return errors.New("blocked by the policy hook")
+	}
+	return errors.New("blocked by the policy hook: " + decision.Reason)
+}
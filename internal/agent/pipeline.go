@@ -0,0 +1,114 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"fi-cli/internal/config"
+	"fi-cli/internal/events"
+	"fi-cli/internal/repo"
+)
+
+const (
+	RoleResearcher = "researcher"
+	RoleExecutor   = "executor"
+)
+
+const researcherNote = `Pipeline role: researcher. Gather the evidence needed to answer the question with grep, list_files, and exa_search. Do not write the final answer. Reply with a findings list: one bullet per fact with its citation ([path:line] and the tool ID), then a "To verify:" list of commands or tests that would confirm the findings. Leave that list empty if nothing needs running.`
+
+const executorNote = `Pipeline role: executor. Run the commands or tests that confirm or refute the researcher's findings below, staying within the shell allowlist. Do not write the final answer. Reply with one entry per command: the command, its exit status, the key output lines, and its tool ID.`
+
+const writerNote = `Pipeline role: writer. The researcher's and executor's artifacts below are your evidence; you have no tools. Compose the final answer from them, keep their citations (including tool IDs), and say what remains unverified.`
+
+// Artifact is the structured output one pipeline stage hands to the next.
+type Artifact struct {
+	Role      string `json:"role"`
+	Status    string `json:"status"`
+	Content   string `json:"content"`
+	StepsUsed int    `json:"steps_used"`
+	ToolCalls int    `json:"tool_calls"`
+}
+
+// stageRenderer forwards a stage's tool activity to the parent run, so it
+// renders and is recorded as part of it. Setup warnings are the parent's.
+type stageRenderer struct {
+	emit func(events.Event)
+}
+
+func (s stageRenderer) Emit(event events.Event) {
+	switch event.Type {
+	case events.ToolCallStarted, events.ToolCallFinished, events.ToolCallFailed, events.StreamResumed:
+		s.emit(event)
+	}
+}
+
+func (s stageRenderer) Close() error {
+	return nil
+}
+
+// runStages runs the researcher and, when shell is available, the executor
+// as sub-agents. It returns their artifacts and tool call records; the caller
+// then acts as the writer.
+func (a *Agent) runStages(ctx context.Context, question string, repoRoot string, repoCtx repo.RepoContext, workspace string, emit func(events.Event)) ([]Artifact, []ToolCallRecord) {
+	var artifacts []Artifact
+	var records []ToolCallRecord
+	add := func(artifact Artifact) {
+		artifacts = append(artifacts, artifact)
+		emit(events.Event{Type: events.StageCompleted, Timestamp: time.Now(), Payload: events.StagePayload{Role: artifact.Role, Status: artifact.Status, StepsUsed: artifact.StepsUsed, ToolCalls: artifact.ToolCalls}})
+	}
+	run := func(role string, note string, hide ...string) {
+		cfg := a.cfg
+		cfg.Pipeline = false
+		cfg.JSON = true
+		cfg.NoPlan = true
+		cfg.FastPath = false
+		cfg.SelfAssess = false
+		cfg.Router = config.Router{Mode: config.RouteOff}
+		// run hooks belong to the parent run, whose usage includes the stages
+		cfg.Hooks.OnRunStart, cfg.Hooks.OnRunEnd = "", ""
+		stage := NewAgent(a.client, a.tools.Without(hide...), stageRenderer{emit: emit}, a.logger, cfg)
+		stage.roleNote = note
+		stage.toolIDOffset = a.toolIDOffset + len(records)
+		stage.workspace = workspace
+		result, err := stage.Run(ctx, question, repoRoot, repoCtx)
+		a.usage.Add(result.Usage)
+		artifact := Artifact{Role: role, Status: result.Status, Content: result.FinalAnswer, StepsUsed: result.StepsUsed, ToolCalls: len(result.ToolCalls)}
+		if err != nil && strings.TrimSpace(artifact.Content) == "" {
+			artifact.Content = "stage failed: " + err.Error()
+		}
+		records = append(records, result.ToolCalls...)
+		add(artifact)
+	}
+
+	// stages never ask the user: the parent's run clock keeps going under them
+	run(RoleResearcher, researcherNote, "shell", "ask_user")
+	switch {
+	case !a.tools.Has("shell"):
+		add(Artifact{Role: RoleExecutor, Status: "skipped", Content: "The shell tool is not enabled, so no commands were run."})
+	case ctx.Err() != nil:
+		add(Artifact{Role: RoleExecutor, Status: "skipped", Content: "The run deadline passed before any commands were run."})
+	default:
+		run(RoleExecutor, executorNote+"\n\n"+artifactsMessage(artifacts), "exa_search", "list_files", "ask_user")
+	}
+	return artifacts, records
+}
+
+// rolePrompt renders a pipeline stage's instructions for the developer prompt.
+func rolePrompt(note string) string {
+	if note == "" {
+		return ""
+	}
+	return "\n\n" + note
+}
+
+// artifactsMessage renders stage artifacts for the next stage's prompt.
+func artifactsMessage(artifacts []Artifact) string {
+	var b strings.Builder
+	b.WriteString("Pipeline artifacts:")
+	for _, artifact := range artifacts {
+		fmt.Fprintf(&b, "\n\n## %s (%s, %d steps, %d tool calls)\n%s", artifact.Role, artifact.Status, artifact.StepsUsed, artifact.ToolCalls, strings.TrimSpace(artifact.Content))
+	}
+	return b.String()
+}
@@ -0,0 +1,237 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"regexp"
+	"slices"
+	"strings"
+
+	"fi-cli/internal/events"
+	"fi-cli/internal/llm"
+	"fi-cli/internal/render"
+	"fi-cli/internal/repo"
+
+	"github.com/openai/openai-go/v3"
+	"github.com/openai/openai-go/v3/packages/param"
+	"github.com/openai/openai-go/v3/shared"
+)
+
+const maxPlanSteps = 8
+
+// planMarker matches the list markers models put before steps: bullets,
+// "1." or "1)", "(1)", "Step 1:", and task-list boxes after any of them.
+var planMarker = regexp.MustCompile(`^(?:[-*+•]|\d+[.)]|\(\d+\)|(?i:step)\s*\d+\s*[:.)-]?)\s+(?:\[[ xX]\]\s+)?`)
+
+type planLine struct {
+	indent int
+	marked bool
+	text   string
+}
+
+// parsePlan turns a model's plan into at most maxPlanSteps steps. Bulleted,
+// numbered, and "Step N:" lists are read without their markers; nested items
+// are folded into their parent step, and wrapped lines into the step they
+// continue. Headings, fences, and lead-ins such as "Plan:" are dropped. With
+// no list markers at all, every line is a step.
+func parsePlan(text string) []string {
+	var lines []planLine
+	listed := false
+	for _, raw := range strings.Split(strings.ReplaceAll(text, "\t", "    "), "\n") {
+		trimmed := strings.TrimSpace(raw)
+		if trimmed == "" || strings.HasPrefix(trimmed, "```") || strings.HasPrefix(trimmed, "#") || isRule(trimmed) {
+			continue
+		}
+		line := planLine{indent: len(raw) - len(strings.TrimLeft(raw, " "))}
+		if marker := planMarker.FindString(trimmed); marker != "" {
+			line.marked = true
+			trimmed = trimmed[len(marker):]
+			listed = true
+		}
+		if line.text = cleanStep(trimmed); line.text != "" {
+			lines = append(lines, line)
+		}
+	}
+
+	var steps []string
+	var children [][]string
+	top := -1
+	for _, line := range lines {
+		switch {
+		case !listed:
+			steps = append(steps, line.text)
+			children = append(children, nil)
+		case !line.marked:
+			// only indented lines continue a step; others are prose around the list
+			if len(steps) == 0 || line.indent <= top || isLeadIn(line.text) {
+				continue
+			}
+			last := len(steps) - 1
+			if n := len(children[last]); n > 0 {
+				children[last][n-1] += " " + line.text
+			} else {
+				steps[last] += " " + line.text
+			}
+		case top < 0 || line.indent <= top:
+			top = line.indent
+			steps = append(steps, line.text)
+			children = append(children, nil)
+		default:
+			last := len(steps) - 1
+			children[last] = append(children[last], line.text)
+		}
+	}
+
+	var plan []string
+	for i, step := range steps {
+		if len(children[i]) > 0 {
+			step = strings.TrimSuffix(step, ":") + ": " + strings.Join(children[i], "; ")
+		}
+		if len(plan) < maxPlanSteps {
+			plan = append(plan, step)
+		}
+	}
+	if len(plan) < 3 {
+		plan = append(plan, "Review repository context", "Run targeted tool calls", "Produce cited answer")
+	}
+	return plan
+}
+
+// cleanStep strips markdown from a step and collapses its whitespace.
+func cleanStep(text string) string {
+	return strings.Join(strings.Fields(render.StripMarkdown(text)), " ")
+}
+
+// isLeadIn reports whether an unmarked line introduces the list, like "Plan:"
+// or "Here is my plan:".
+func isLeadIn(text string) bool {
+	return strings.HasSuffix(text, ":")
+}
+
+func isRule(line string) bool {
+	return len(line) >= 3 && strings.Trim(line, "-*_ ") == ""
+}
+
+// planToolName is the function the plan request forces, so the plan arrives
+// as JSON instead of free text.
+const planToolName = "submit_plan"
+
+var defaultPlan = []string{"Review repository context", "Run focused searches", "Summarize evidence with citations"}
+
+func (a *Agent) generatePlan(ctx context.Context, question string, repoCtx repo.RepoContext, toolNames []string) []events.PlanStep {
+	messages := []openai.ChatCompletionMessageParamUnion{
+		openai.SystemMessage(systemPrompt(a.cfg.ResponseMode, a.cfg.AnswerLanguage, a.cfg.Verbosity, a.cfg.OutputFormat)),
+		openai.DeveloperMessage(planPrompt(a.cfg.AnswerLanguage)),
+		openai.DeveloperMessage(repoContextMessage(a.cfg, repoCtx)),
+		openai.UserMessage(question),
+	}
+	forced := openai.ChatCompletionToolChoiceOptionUnionParam{OfFunctionToolChoice: &openai.ChatCompletionNamedToolChoiceParam{
+		Function: openai.ChatCompletionNamedToolChoiceFunctionParam{Name: planToolName},
+	}}
+	resp, err := a.create(ctx, llm.Request{Model: a.cfg.Model, Messages: messages, Tools: []openai.ChatCompletionToolUnionParam{planTool(toolNames)}, ToolChoice: forced})
+	if err != nil {
+		return planSteps(defaultPlan)
+	}
+	if steps := planFromCalls(resp.ToolCalls, toolNames); len(steps) > 0 {
+		return steps
+	}
+	// a provider that ignores the forced call answers in text
+	return planSteps(parsePlan(resp.Content))
+}
+
+// planTool declares submit_plan. Tool hints are limited to the run's tools.
+func planTool(toolNames []string) openai.ChatCompletionToolUnionParam {
+	hint := map[string]any{"type": "string"}
+	if len(toolNames) > 0 {
+		hint["enum"] = toolNames
+	}
+	return openai.ChatCompletionToolUnionParam{
+		OfFunction: &openai.ChatCompletionFunctionToolParam{
+			Function: shared.FunctionDefinitionParam{
+				Name:        planToolName,
+				Description: param.NewOpt("Submit the plan for answering the question."),
+				Parameters: map[string]any{
+					"type": "object",
+					"properties": map[string]any{
+						"steps": map[string]any{
+							"type": "array",
+							"items": map[string]any{
+								"type": "object",
+								"properties": map[string]any{
+									"title": map[string]any{"type": "string"},
+									"tools": map[string]any{"type": "array", "items": hint},
+								},
+								"required":             []string{"title", "tools"},
+								"additionalProperties": false,
+							},
+						},
+					},
+					"required":             []string{"steps"},
+					"additionalProperties": false,
+				},
+				Strict: param.NewOpt(true),
+			},
+		},
+	}
+}
+
+// planFromCalls reads the plan from a submit_plan call. Titles are cleaned
+// like parsed steps, unknown or repeated tool hints are dropped, and at most
+// maxPlanSteps steps are kept. It returns nil when there is no usable call.
+func planFromCalls(calls []llm.ToolCall, toolNames []string) []events.PlanStep {
+	for _, call := range calls {
+		if call.Name != planToolName {
+			continue
+		}
+		var input struct {
+			Steps []events.PlanStep `json:"steps"`
+		}
+		if err := json.Unmarshal(call.Arguments, &input); err != nil {
+			return nil
+		}
+		var steps []events.PlanStep
+		for _, step := range input.Steps {
+			title := cleanStep(step.Title)
+			if title == "" || len(steps) == maxPlanSteps {
+				continue
+			}
+			var hints []string
+			for _, tool := range step.Tools {
+				if slices.Contains(toolNames, tool) && !slices.Contains(hints, tool) {
+					hints = append(hints, tool)
+				}
+			}
+			steps = append(steps, events.PlanStep{Title: title, Tools: hints})
+		}
+		return steps
+	}
+	return nil
+}
+
+func planSteps(titles []string) []events.PlanStep {
+	steps := make([]events.PlanStep, len(titles))
+	for i, title := range titles {
+		steps[i] = events.PlanStep{Title: title}
+	}
+	return steps
+}
+
+func planTitles(steps []events.PlanStep) []string {
+	titles := make([]string, len(steps))
+	for i, step := range steps {
+		titles[i] = step.Title
+	}
+	return titles
+}
+
+// formatPlan lists the steps for the model, with their tool hints.
+func formatPlan(steps []events.PlanStep) string {
+	lines := make([]string, len(steps))
+	for i, step := range steps {
+		lines[i] = "- " + step.Title
+		if len(step.Tools) > 0 {
+			lines[i] += " (tools: " + strings.Join(step.Tools, ", ") + ")"
+		}
+	}
+	return strings.Join(lines, "\n")
+}
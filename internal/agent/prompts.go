@@ -3,9 +3,12 @@ package agent
 import (
 	"fmt"
 	"strings"
+
+	"fi-cli/internal/config"
+	"fi-cli/internal/tools"
 )
 
-func systemPrompt(responseMode string) string {
+func systemPrompt(responseMode string, language string, verbosity string, format string) string {
 	modeGuidance := "Keep final responses concise and practical."
 	switch strings.ToLower(strings.TrimSpace(responseMode)) {
 	case "operator":
@@ -25,12 +28,16 @@ Requirements:
 - When the user asks for a command or how to do something, prioritize finding the exact command(s) in repo files and return them clearly.
 - If evidence is missing, say so explicitly and explain what would be needed.
 - Never invent file paths or dependencies.
-- Cite evidence inline using [path:line] for file evidence and [tool:<name>] for tool outputs.
-- %s`, modeGuidance))
+- Cite evidence inline using [path:line] for file evidence and the tool result ID (e.g. [T2]) for tool outputs; every tool result starts with its ID. When a tool surfaced a file, cite both, e.g. [src/auth.go:42][T2].
+- %s%s%s%s`, modeGuidance, verbosityDirective(verbosity), languageDirective(language), formatDirective(format)))
 }
 
-func developerPrompt(toolNames []string, webEnabled bool, shellAllowlist []string, commandIntent bool) string {
-	webNote := "Web search is available via exa_search."
+func developerPrompt(toolNames []string, webEnabled bool, shellAllowlist []string, commandIntent bool, caps tools.Capabilities) string {
+	webNote := "Web search is available via exa_search. For a framework or library, pass its official documentation domains in include_domains (e.g. [\"react.dev\"]) rather than searching the whole web."
+	webNote += " For the latest version, a recent release, or recent CVEs, stale results are wrong answers: set max_age_days (e.g. 365, or 30 for security news), use category \"news\" for announcements and advisories, and trust the newest published_date."
+	if contains(toolNames, "exa_contents") {
+		webNote += " When a result's snippet is not enough, read the page with exa_contents instead of searching again."
+	}
 	if !webEnabled {
 		webNote = "Web search is unavailable; do not request exa_search."
 	}
@@ -50,13 +57,25 @@ func developerPrompt(toolNames []string, webEnabled bool, shellAllowlist []strin
 %s
 %s
 %s
+%s
 
 Tool usage rules:
 - Keep tool inputs minimal and focused.
 - Respect truncation; if results are incomplete, call tools again with narrower queries.
-- Prefer grep before shell commands.
-- For command-intent questions, search in this order:
-  1) package.json scripts, Makefile, Justfile
+- Prefer grep before shell commands; use list_files to explore directories.
+- Once grep has located the code, use read_file for the surrounding lines (start_line/end_line) instead of broad grep patterns or cat.
+- grep does not search inside compressed files; use archive_list and archive_read for zip, tar, and gzip files.
+- Use data_preview for the columns and first rows of CSV, TSV, and JSONL files, and for the schema and row count of Parquet files (it does not return Parquet rows), instead of shell one-liners.
+- For API questions, read the contract with api_schema (OpenAPI or GraphQL) and cite it before the handlers.
+- For "which environment variables does this need", use env_usage rather than grepping for each pattern.
+- For deployment questions (ports, services, images, volumes), use docker_analyze on Dockerfiles and compose files.
+- For "what runs on every PR" or "which job runs the tests", use ci_config to read the CI pipelines' triggers, jobs, and steps.
+- For how to use a library the repo depends on, read its installed docs with dep_docs before web search, and cite the version it reports.
+- For "how do other projects configure X", use code_search, when listed, and cite the result URLs; those files come from other repositories, never from this one.
+- When the question names a ticket (e.g. PAY-123) and issue_lookup is listed, fetch it first and treat its description and comments as the requirements.
+- Use ask_user, when listed, only for ambiguity the repository cannot resolve; ask one short question before searching, never to confirm what you found.
+- For command-intent questions, start from "Available commands" in the repository context, then search in this order:
+  1) package.json scripts, Makefile, justfile, Taskfile, Earthfile
   2) README and docs (setup/run/deploy sections)
   3) docker-compose, Dockerfile, CI files, infra folders
 - When returning commands, include the exact command first, then source citation.
@@ -65,11 +84,69 @@ Final answer format:
 - Start with a brief summary.
 - Include evidence citations inline.
 - End with actionable next steps if relevant.
-`, strings.Join(toolNames, ", "), webNote, shellNote, intentNote))
+`, strings.Join(toolNames, ", "), webNote, shellNote, intentNote, environmentNote(caps)))
+}
+
+// environmentNote tells the model which optional binaries exist locally so it
+// does not run commands that will fail or suggest them without a caveat.
+func environmentNote(caps tools.Capabilities) string {
+	var notes []string
+	if available := caps.Available(); len(available) > 0 {
+		notes = append(notes, "Installed locally: "+strings.Join(available, ", ")+".")
+	}
+	if missing := caps.Missing(); len(missing) > 0 {
+		notes = append(notes, "Not installed locally: "+strings.Join(missing, ", ")+"; do not run commands that need them, and say so when you recommend one.")
+	}
+	return strings.Join(notes, " ")
+}
+
+func planPrompt(language string) string {
+	return strings.TrimSpace(`Call ` + planToolName + ` with a concise plan of 3-8 steps describing intended actions, each with the tools you expect it to use. Do not include reasoning or tool outputs.` + languageDirective(language))
+}
+
+// verbosityDirective adjusts answer length for the --brief/--detailed presets.
+func verbosityDirective(verbosity string) string {
+	switch verbosity {
+	case config.VerbosityBrief:
+		return "\n- Brief mode: answer in one or two lines (a single command or fact with its citation). No preamble or next steps."
+	case config.VerbosityDetailed:
+		return "\n- Detailed mode overrides the concise default: give a thorough, structured answer with rationale, relevant trade-offs, and citations for each claim."
+	default:
+		return ""
+	}
+}
+
+// formatDirective asks for a markdown table when --format table or tsv will
+// extract one from the answer.
+func formatDirective(format string) string {
+	switch format {
+	case config.OutputTable, config.OutputTSV:
+		return "\n- Table format overrides the answer format rules: answer with one markdown pipe table (header row, separator row, one row per item) and nothing else. Keep one fact per cell, escape any | inside a cell as \\|, and put citations in a last Source column."
+	default:
+		return ""
+	}
+}
+
+// maxTokensFor caps output tokens per verbosity preset; 0 leaves the provider default.
+func maxTokensFor(verbosity string) int {
+	switch verbosity {
+	case config.VerbosityBrief:
+		return 512
+	case config.VerbosityDetailed:
+		return 4096
+	default:
+		return 0
+	}
 }
 
-func planPrompt() string {
-	return strings.TrimSpace(`Generate a concise plan of 3-8 bullets describing intended actions. Do not include reasoning or tool outputs.`)
+// languageDirective asks for answers, plans, and tool summaries in the
+// configured language while keeping machine-readable text verbatim.
+func languageDirective(language string) string {
+	language = strings.TrimSpace(language)
+	if language == "" {
+		return ""
+	}
+	return fmt.Sprintf("\n- Write the answer, plan, and any summaries of tool results in %s. Keep code, commands, file paths, and citations verbatim.", language)
 }
 
 func contains(list []string, target string) bool {
@@ -81,6 +158,26 @@ func contains(list []string, target string) bool {
 	return false
 }
 
+// isSimpleQuestion reports whether a question is short and single-part enough
+// to skip planning and let the first model call answer it directly.
+func isSimpleQuestion(question string) bool {
+	question = strings.TrimSpace(question)
+	if question == "" || strings.Contains(question, "\n") || strings.Count(question, "?") > 1 {
+		return false
+	}
+	words := strings.Fields(strings.ToLower(question))
+	if len(words) > 20 || strings.Contains(strings.ToLower(question), "step by step") {
+		return false
+	}
+	complex := []string{"plan", "refactor", "compare", "design", "architecture", "migrate", "audit", "review"}
+	for _, word := range words {
+		if contains(complex, strings.Trim(word, ".,;:!?\"'`")) {
+			return false
+		}
+	}
+	return true
+}
+
 func isCommandIntent(question string) bool {
 	query := strings.ToLower(question)
 	keywords := []string{
@@ -0,0 +1,133 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"fi-cli/internal/events"
+	"fi-cli/internal/guard"
+
+	"github.com/openai/openai-go/v3"
+)
+
+// Recovery strategies for recoverable errors. Each is attempted at most once
+// per run; a second failure of the same kind is fatal.
+const (
+	recoverRetryStep     = "retry_step"
+	recoverShrinkContext = "shrink_context"
+	recoverDropTool      = "drop_tool"
+)
+
+const (
+	// maxToolFailures consecutive errors from one tool drop it from the run.
+	maxToolFailures = 3
+	// keptToolResults is how many recent tool results shrinkContext leaves whole.
+	keptToolResults = 2
+	shrunkToolBytes = 512
+	// rateLimitBackoff is the wait before retrying a 429 without Retry-After;
+	// maxRateLimitWait caps the wait a Retry-After header asks for.
+	rateLimitBackoff = 2 * time.Second
+	maxRateLimitWait = 30 * time.Second
+)
+
+var contextLengthPhrases = []string{"context length", "context_length", "context window", "maximum context", "too many tokens", "prompt is too long", "reduce the length"}
+
+// classifyModelError picks the error kind of a failed model request and the
+// recovery worth attempting, or "" when retrying cannot help.
+func classifyModelError(err error) (kind string, recovery string) {
+	if errors.Is(err, context.Canceled) {
+		return events.ErrorKindCancelled, ""
+	}
+	message := strings.ToLower(err.Error())
+	for _, phrase := range contextLengthPhrases {
+		if strings.Contains(message, phrase) {
+			return events.ErrorKindProvider, recoverShrinkContext
+		}
+	}
+	var apiErr *openai.Error
+	if errors.As(err, &apiErr) {
+		switch apiErr.StatusCode {
+		case http.StatusRequestEntityTooLarge:
+			return events.ErrorKindProvider, recoverShrinkContext
+		case http.StatusBadRequest, http.StatusUnauthorized, http.StatusPaymentRequired, http.StatusForbidden, http.StatusNotFound:
+			// bad key, no credits, unknown model: the same request fails again
+			return events.ErrorKindProvider, ""
+		}
+	}
+	return events.ErrorKindProvider, recoverRetryStep
+}
+
+// rateLimitDelay is how long to wait before retrying err: the provider's
+// Retry-After-Ms or Retry-After for a 429, rateLimitBackoff when it sends
+// neither, and 0 for other errors.
+func rateLimitDelay(err error) time.Duration {
+	var apiErr *openai.Error
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusTooManyRequests {
+		return 0
+	}
+	if apiErr.Response == nil {
+		return rateLimitBackoff
+	}
+	header := apiErr.Response.Header
+	if ms, err := strconv.ParseFloat(header.Get("Retry-After-Ms"), 64); err == nil && ms >= 0 {
+		return min(time.Duration(ms*float64(time.Millisecond)), maxRateLimitWait)
+	}
+	value := header.Get("Retry-After")
+	if seconds, err := strconv.ParseFloat(value, 64); err == nil && seconds >= 0 {
+		return min(time.Duration(seconds*float64(time.Second)), maxRateLimitWait)
+	}
+	if at, err := http.ParseTime(value); err == nil {
+		return min(max(time.Until(at), 0), maxRateLimitWait)
+	}
+	return rateLimitBackoff
+}
+
+// shrinkContext cuts all but the last keptToolResults tool results to a short
+// head, so a request that overflowed the model's context fits on retry. It
+// reports whether anything was cut.
+func shrinkContext(messages []openai.ChatCompletionMessageParamUnion) bool {
+	shrunk := false
+	kept := 0
+	for i := len(messages) - 1; i >= 0; i-- {
+		tool := messages[i].OfTool
+		if tool == nil || !tool.Content.OfString.Valid() {
+			continue
+		}
+		if kept < keptToolResults {
+			kept++
+			continue
+		}
+		content := tool.Content.OfString.Value
+		if len(content) <= shrunkToolBytes {
+			continue
+		}
+		tool.Content.OfString.Value = guard.Cut(content, shrunkToolBytes) + "\n... [cut to fit the context window; call the tool again if this result matters]"
+		shrunk = true
+	}
+	return shrunk
+}
+
+// toolFailures counts consecutive errors per tool, policy and hook refusals
+// included, so a model that keeps asking for refused calls loses the tool; a
+// success resets it.
+type toolFailures map[string]int
+
+// record notes a call's outcome and reports whether the tool failed often
+// enough in a row to be dropped.
+func (f toolFailures) record(name string, failed bool) bool {
+	if !failed {
+		delete(f, name)
+		return false
+	}
+	f[name]++
+	return f[name] >= maxToolFailures
+}
+
+func droppedToolMessage(name string, err error) string {
+	return fmt.Sprintf("The %s tool failed %d times in a row (last error: %s). It is removed for the rest of this run; continue with the other tools or answer from the evidence gathered so far.", name, maxToolFailures, err)
+}
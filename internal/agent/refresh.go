@@ -0,0 +1,70 @@
+package agent
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"fi-cli/internal/repo"
+)
+
+// maxRefreshFiles bounds how many touched files are re-read per step.
+const maxRefreshFiles = 8
+
+// touchedSet collects files reported by tools until the next model step.
+type touchedSet struct {
+	order []string
+	seen  map[string]bool
+}
+
+func (t *touchedSet) add(repoRoot string, paths []string) {
+	if t.seen == nil {
+		t.seen = map[string]bool{}
+	}
+	for _, path := range paths {
+		if filepath.IsAbs(path) {
+			rel, err := filepath.Rel(repoRoot, path)
+			if err != nil || strings.HasPrefix(rel, "..") {
+				continue
+			}
+			path = rel
+		}
+		path = filepath.Clean(path)
+		if path == "." || strings.HasPrefix(path, "..") || t.seen[path] {
+			continue
+		}
+		t.seen[path] = true
+		t.order = append(t.order, path)
+	}
+}
+
+// drain returns the collected paths and resets the set.
+func (t *touchedSet) drain() []string {
+	paths := t.order
+	t.order = nil
+	t.seen = nil
+	return paths
+}
+
+// refreshMessage re-reads touched files so later steps reason over current
+// contents rather than the snapshot taken before the run.
+func (a *Agent) refreshMessage(repoRoot string, paths []string) string {
+	var b strings.Builder
+	b.WriteString("Files changed during this run (current contents supersede the repository context):\n")
+	for i, path := range paths {
+		if i == maxRefreshFiles {
+			fmt.Fprintf(&b, "--- %d more changed files not shown ---\n", len(paths)-maxRefreshFiles)
+			break
+		}
+		snip := repo.LoadSnippet(repoRoot, path, a.cfg.ToolLimits.MaxFileBytes)
+		switch {
+		case snip.Omitted != "":
+			fmt.Fprintf(&b, "--- %s (%s) ---\n", snip.Path, snip.Omitted)
+		case snip.Truncated:
+			fmt.Fprintf(&b, "--- %s (truncated) ---\n%s\n", snip.Path, snip.Snippet)
+		default:
+			fmt.Fprintf(&b, "--- %s ---\n%s\n", snip.Path, snip.Snippet)
+		}
+	}
+	return guardRepoText(a.cfg.InjectionGuard, strings.TrimRight(b.String(), "\n"))
+}
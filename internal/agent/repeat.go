@@ -0,0 +1,253 @@
+package agent
+
+import (
+	"encoding/json"
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+)
+
+const (
+	// maxDiffLines bounds the changed lines shown for a repeated call.
+	maxDiffLines = 40
+	// maxDiffCells bounds the line-by-line comparison of two results.
+	maxDiffCells = 1 << 20
+)
+
+// repeatTracker remembers each successful call's result by tool and
+// arguments, so a second identical call (grep after an edit, git status
+// again) can be shown as a diff against the first. Paths are compared
+// cleaned, and two line-range reads of a file repeat where they overlap.
+type repeatTracker struct {
+	last map[string]priorResult
+}
+
+type priorResult struct {
+	id    string
+	lines []string
+	span  *lineSpan
+}
+
+// lineSpan is the file lines a read_file result covers.
+type lineSpan struct {
+	start, end int
+	lines      []string
+}
+
+// resultDiff compares a repeated call's result with the previous one.
+type resultDiff struct {
+	repeatOf string
+	changes  []string
+	// tooLarge is set when the results were too long to compare
+	tooLarge bool
+	// overlap is the first and last line two different ranges share
+	overlap [2]int
+}
+
+// observe records a result and, when the same call ran before, returns how
+// its result changed.
+func (t *repeatTracker) observe(toolName string, args json.RawMessage, id string, payload any) (resultDiff, bool) {
+	if t.last == nil {
+		t.last = map[string]priorResult{}
+	}
+	key := repeatKey(toolName, args)
+	value := decodeResult(payload)
+	var lines []string
+	flattenResult("", value, &lines)
+	span := spanOf(value)
+	prior, repeated := t.last[key]
+	t.last[key] = priorResult{id: id, lines: lines, span: span}
+	if !repeated {
+		return resultDiff{}, false
+	}
+	diff := resultDiff{repeatOf: prior.id}
+	before, after := prior.lines, lines
+	if prior.span != nil && span != nil && (prior.span.start != span.start || prior.span.end != span.end) {
+		first, last := max(prior.span.start, span.start), min(prior.span.end, span.end)
+		if first > last {
+			return resultDiff{}, false
+		}
+		diff.overlap = [2]int{first, last}
+		before, after = prior.span.between(first, last), span.between(first, last)
+	}
+	if len(before)*len(after) > maxDiffCells {
+		diff.tooLarge = true
+		return diff, true
+	}
+	diff.changes = diffLines(before, after)
+	return diff, true
+}
+
+// repeatKey identifies a call by tool and arguments, with paths cleaned and
+// line ranges left out; observe compares ranges itself.
+func repeatKey(toolName string, args json.RawMessage) string {
+	var fields map[string]any
+	if err := json.Unmarshal(args, &fields); err != nil {
+		return toolName + " " + string(args)
+	}
+	for key, value := range fields {
+		switch v := value.(type) {
+		case string:
+			if key == "path" {
+				fields[key] = cleanArgPath(v)
+			}
+		case []any:
+			if key == "paths" {
+				for i, item := range v {
+					if p, ok := item.(string); ok {
+						v[i] = cleanArgPath(p)
+					}
+				}
+			}
+		}
+	}
+	delete(fields, "start_line")
+	delete(fields, "end_line")
+	data, _ := json.Marshal(fields)
+	return toolName + " " + string(data)
+}
+
+// cleanArgPath makes "./internal/", "internal", and "internal/." one path.
+func cleanArgPath(p string) string {
+	return path.Clean(strings.ReplaceAll(strings.TrimSpace(p), "\\", "/"))
+}
+
+// spanOf returns the lines a line-range read covers, or nil for results
+// without start_line, end_line, and content.
+func spanOf(value any) *lineSpan {
+	fields, _ := value.(map[string]any)
+	start, okStart := fields["start_line"].(float64)
+	end, okEnd := fields["end_line"].(float64)
+	content, okContent := fields["content"].(string)
+	if !okStart || !okEnd || !okContent {
+		return nil
+	}
+	return &lineSpan{start: int(start), end: int(end), lines: strings.Split(strings.TrimRight(content, "\n"), "\n")}
+}
+
+// between returns lines first to last, numbered.
+func (s *lineSpan) between(first, last int) []string {
+	var lines []string
+	for n := first; n <= last && n-s.start < len(s.lines); n++ {
+		lines = append(lines, fmt.Sprintf("%d: %s", n, s.lines[n-s.start]))
+	}
+	return lines
+}
+
+// summary is the diff as shown in verbose output, one line per change.
+func (d resultDiff) summary() []string {
+	switch {
+	case d.tooLarge:
+		return []string{"(too long to compare)"}
+	case len(d.changes) == 0:
+		return nil
+	case len(d.changes) > maxDiffLines:
+		return append(d.changes[:maxDiffLines:maxDiffLines], fmt.Sprintf("... %d more changed lines", len(d.changes)-maxDiffLines))
+	}
+	return d.changes
+}
+
+// message tells the model how a repeated call's result differs from the
+// earlier one, so it need not compare the two itself.
+func (d resultDiff) message(id, toolName string) string {
+	what := toolName + " with the same arguments"
+	if d.overlap[0] > 0 {
+		what = fmt.Sprintf("%s of the same file; compared on lines %d-%d, which both read", toolName, d.overlap[0], d.overlap[1])
+	}
+	switch {
+	case d.tooLarge:
+		return fmt.Sprintf("%s repeats %s (%s); the results are too long to compare.", id, d.repeatOf, what)
+	case len(d.changes) == 0:
+		return fmt.Sprintf("%s repeats %s (%s): the result is unchanged.", id, d.repeatOf, what)
+	}
+	return fmt.Sprintf("%s repeats %s (%s). Changed lines since %s (- before, + now):\n%s", id, d.repeatOf, what, d.repeatOf, strings.Join(d.summary(), "\n"))
+}
+
+// decodeResult round-trips a tool payload through JSON, so results compare
+// as the model sees them.
+func decodeResult(payload any) any {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return nil
+	}
+	var value any
+	if err := json.Unmarshal(data, &value); err != nil {
+		return nil
+	}
+	return value
+}
+
+// flattenResult flattens a decoded tool payload into comparable lines: each
+// string value is split into lines prefixed by its field ("stdout: ok").
+// Durations are left out, since they differ on every call.
+func flattenResult(prefix string, value any, lines *[]string) {
+	label := ""
+	if prefix != "" {
+		label = prefix + ": "
+	}
+	switch v := value.(type) {
+	case map[string]any:
+		keys := make([]string, 0, len(v))
+		for key := range v {
+			if key != "duration_ms" {
+				keys = append(keys, key)
+			}
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			name := key
+			if prefix != "" {
+				name = prefix + "." + key
+			}
+			flattenResult(name, v[key], lines)
+		}
+	case []any:
+		for _, item := range v {
+			flattenResult(prefix, item, lines)
+		}
+	case string:
+		for _, line := range strings.Split(strings.TrimRight(v, "\n"), "\n") {
+			*lines = append(*lines, label+line)
+		}
+	case nil:
+	default:
+		*lines = append(*lines, label+fmt.Sprint(v))
+	}
+}
+
+// diffLines returns the lines removed from before ("- ") and added in after
+// ("+ "), in order, from a longest common subsequence of the two.
+func diffLines(before, after []string) []string {
+	n, m := len(before), len(after)
+	// common[i][j] is the LCS length of before[i:] and after[j:]
+	common := make([][]int, n+1)
+	for i := range common {
+		common[i] = make([]int, m+1)
+	}
+	for i := n - 1; i >= 0; i-- {
+		for j := m - 1; j >= 0; j-- {
+			if before[i] == after[j] {
+				common[i][j] = common[i+1][j+1] + 1
+			} else {
+				common[i][j] = max(common[i+1][j], common[i][j+1])
+			}
+		}
+	}
+	var changes []string
+	i, j := 0, 0
+	for i < n || j < m {
+		switch {
+		case i < n && j < m && before[i] == after[j]:
+			i++
+			j++
+		case i < n && (j == m || common[i+1][j] >= common[i][j+1]):
+			changes = append(changes, "- "+before[i])
+			i++
+		default:
+			changes = append(changes, "+ "+after[j])
+			j++
+		}
+	}
+	return changes
+}
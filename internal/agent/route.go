@@ -0,0 +1,150 @@
+package agent
+
+import (
+	"context"
+	"slices"
+	"strings"
+
+	"fi-cli/internal/config"
+	"fi-cli/internal/llm"
+
+	"github.com/openai/openai-go/v3"
+	"go.uber.org/zap"
+)
+
+const routerPrompt = `Classify the user's question about a code repository. Reply with exactly one word:
+lookup - a single fact, file location, or command that is already in the repository
+research - needs external documentation, versions, or comparisons with other projects
+code_change - asks to add, change, or remove code
+debugging - an error, failing test, crash, or unexpected behavior to diagnose
+general - anything else`
+
+// routeProfile is what a route changes about a run.
+type routeProfile struct {
+	// skipPlan answers without a plan call, like the fast path.
+	skipPlan bool
+	// hide lists tools the route does not expose to the model.
+	hide []string
+	// note is appended to the developer prompt.
+	note string
+}
+
+var routeProfiles = map[string]routeProfile{
+	config.RouteLookup: {
+		skipPlan: true,
+		hide:     []string{"shell", "exa_search", "exa_contents", "code_search"},
+		note:     "Lookup question: answer from the repository context, or with one or two focused grep/list_files calls. Keep it short.",
+	},
+	config.RouteResearch: {
+		note: "Research question: combine repository evidence with exa_search, when it is available, for external documentation, versions, and known issues. Cite URLs for web sources.",
+	},
+	config.RouteCodeChange: {
+		hide: []string{"exa_search", "exa_contents"},
+		note: "Code-change question: find every file that has to change and read enough surrounding code to match its style. You cannot edit files; answer with a unified diff per file, citing the lines you change.",
+	},
+	config.RouteDebugging: {
+		note: "Debugging question: start from the error text and recent terminal output, locate where the error originates, and when shell is available reproduce it (run the failing test, read the logs) before proposing a fix.",
+	},
+	config.RouteGeneral: {},
+}
+
+var (
+	debuggingWords  = []string{"error", "errors", "fail", "fails", "failing", "failed", "failure", "panic", "panics", "exception", "traceback", "crash", "crashes", "segfault", "bug", "broken", "debug", "flaky", "hangs"}
+	debuggingPhrase = []string{"stack trace", "doesn't work", "does not work", "not working", "why does", "why is"}
+	changeWords     = []string{"add", "implement", "refactor", "rename", "rewrite", "remove", "delete", "replace", "update", "change", "modify", "fix", "extract", "migrate"}
+	changePhrase    = []string{"write a", "write the", "create a", "make it", "make the", "how would i change", "how do i add"}
+	researchWords   = []string{"latest", "documentation", "changelog", "upstream", "alternatives", "alternative", "cve", "deprecated", "recommended", "vs", "versus"}
+	researchPhrase  = []string{"best practice", "release notes", "docs for", "on the web", "compared to", "newer version"}
+	lookupStarts    = []string{"where", "what", "which", "who", "show", "list", "find", "how do i run", "how to run", "how do i start", "how do i build", "how do i test"}
+)
+
+// routeNote renders the route's prompt guidance for the developer prompt.
+func routeNote(profile routeProfile) string {
+	if profile.note == "" {
+		return ""
+	}
+	return "\n\n" + profile.note
+}
+
+// classifyQuestion routes question by keyword rules. Debugging wins over code
+// change, so "fix the failing test" is diagnosed before it is patched.
+func classifyQuestion(question string) string {
+	query := strings.ToLower(strings.TrimSpace(question))
+	var words []string
+	for _, word := range strings.Fields(query) {
+		words = append(words, strings.Trim(word, ".,;:!?()\"'`"))
+	}
+	matches := func(list []string, phrases []string) bool {
+		for _, word := range words {
+			if slices.Contains(list, word) {
+				return true
+			}
+		}
+		for _, phrase := range phrases {
+			if strings.Contains(query, phrase) {
+				return true
+			}
+		}
+		return false
+	}
+	switch {
+	case matches(debuggingWords, debuggingPhrase):
+		return config.RouteDebugging
+	case matches(changeWords, changePhrase):
+		return config.RouteCodeChange
+	case matches(researchWords, researchPhrase):
+		return config.RouteResearch
+	}
+	if isSimpleQuestion(question) {
+		for _, start := range lookupStarts {
+			if strings.HasPrefix(query, start) {
+				return config.RouteLookup
+			}
+		}
+	}
+	return config.RouteGeneral
+}
+
+// routeQuestion picks the run's route: forced by router.mode, from the rules,
+// or from the optional classifier when the rules find nothing specific. The
+// second value names the source for the QuestionRouted event.
+func (a *Agent) routeQuestion(ctx context.Context, question string) (string, string) {
+	switch mode := a.cfg.Router.Mode; mode {
+	case config.RouteOff:
+		return config.RouteGeneral, "off"
+	case config.RouteAuto, "":
+	default:
+		return mode, "config"
+	}
+	route := classifyQuestion(question)
+	if route != config.RouteGeneral || !a.cfg.Router.Classifier {
+		return route, "rules"
+	}
+	if classified, ok := a.classifyRoute(ctx, question); ok {
+		return classified, "classifier"
+	}
+	return route, "rules"
+}
+
+// classifyRoute asks a cheap model for the route of question.
+func (a *Agent) classifyRoute(ctx context.Context, question string) (string, bool) {
+	model := a.cfg.Router.ClassifierModel
+	if model == "" {
+		model = a.cfg.Model
+	}
+	resp, err := a.create(ctx, llm.Request{
+		Model:     model,
+		Messages:  []openai.ChatCompletionMessageParamUnion{openai.SystemMessage(routerPrompt), openai.UserMessage(question)},
+		MaxTokens: 4,
+	})
+	if err != nil {
+		a.logger.Debug("route classifier failed", zap.Error(err))
+		return "", false
+	}
+	answer := strings.ToLower(strings.Trim(strings.TrimSpace(resp.Content), ".\"'`"))
+	answer = strings.ReplaceAll(answer, "-", "_")
+	if !slices.Contains(config.Routes, answer) {
+		return "", false
+	}
+	return answer, true
+}
@@ -0,0 +1,172 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"fi-cli/internal/events"
+	"fi-cli/internal/llm"
+	"fi-cli/internal/util"
+
+	"github.com/openai/openai-go/v3"
+	"go.uber.org/zap"
+)
+
+const (
+	maxStreamResumes = 2
+	resumePrompt     = "The previous response was interrupted. Continue exactly where it stopped; do not repeat text that was already written."
+	// resumeOverlapWindow bounds how much of a resumed stream is held back
+	// while checking whether the model repeated the already-printed tail.
+	resumeOverlapWindow = 256
+	// answerBytesPerToken estimates tokens from streamed bytes for the
+	// max_answer_tokens guard; providers only report usage once a stream ends.
+	answerBytesPerToken = 4
+)
+
+// streamFinal streams the final answer. When the connection drops mid-answer it
+// re-requests a continuation and stitches it onto what was already printed,
+// emitting StreamResumed for each retry.
+func (a *Agent) streamFinal(ctx context.Context, req llm.Request, emit func(events.Event)) (string, error) {
+	_, printed, err := a.streamResumable(ctx, req, emit)
+	return printed, err
+}
+
+// streamResumable is streamFinal that also returns the response of the first
+// attempt to produce one, so a tool-enabled stream can surface tool calls.
+// Once the answer passes about cfg.MaxAnswerTokens it cancels the stream and
+// returns what was printed; Run then warns that the answer was cut.
+func (a *Agent) streamResumable(ctx context.Context, req llm.Request, emit func(events.Event)) (llm.Response, string, error) {
+	ctx, stop := context.WithCancel(ctx)
+	defer stop()
+	limit := a.cfg.MaxAnswerTokens * answerBytesPerToken
+	capped := false
+	var printed strings.Builder
+	onDelta := func(delta string) {
+		if capped {
+			return
+		}
+		if limit > 0 && printed.Len()+len(delta) > limit {
+			delta = util.CutBytes(delta, limit-printed.Len())
+			capped = true
+			stop()
+		}
+		if delta == "" {
+			return
+		}
+		emit(events.Event{Type: events.ModelDelta, Timestamp: time.Now(), Payload: events.ModelDeltaPayload{Delta: delta}})
+		printed.WriteString(delta)
+	}
+
+	response, err := a.streamOnce(ctx, req, onDelta)
+	for attempt := 1; err != nil && attempt <= maxStreamResumes && ctx.Err() == nil; attempt++ {
+		a.logger.Warn("final answer stream interrupted; resuming", zap.Error(err), zap.Int("attempt", attempt))
+		emit(events.Event{Type: events.StreamResumed, Timestamp: time.Now(), Payload: events.StreamResumedPayload{
+			Attempt:      attempt,
+			PrintedBytes: printed.Len(),
+			Reason:       err.Error(),
+		}})
+
+		resumeReq := req
+		prefix := printed.String()
+		if prefix != "" {
+			resumeReq.Messages = append(append([]openai.ChatCompletionMessageParamUnion{}, req.Messages...),
+				openai.AssistantMessage(prefix),
+				openai.DeveloperMessage(resumePrompt),
+			)
+		}
+		stitcher := &overlapStitcher{tail: tailOf(prefix, resumeOverlapWindow), emit: onDelta}
+		var resumed llm.Response
+		resumed, err = a.streamOnce(ctx, resumeReq, stitcher.write)
+		stitcher.flush()
+		if prefix == "" {
+			response = resumed
+		}
+	}
+	if capped {
+		a.answerCapped = true
+		err = nil
+	}
+	return response, printed.String(), err
+}
+
+// streamOnce runs a single streaming request, failing fast when no delta
+// arrives within cfg.IdleTimeout while letting an active stream run to completion.
+func (a *Agent) streamOnce(ctx context.Context, req llm.Request, onDelta func(string)) (llm.Response, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	var idled atomic.Bool
+	var watchdog *time.Timer
+	if a.cfg.IdleTimeout > 0 {
+		watchdog = time.AfterFunc(a.cfg.IdleTimeout, func() {
+			idled.Store(true)
+			cancel()
+		})
+		defer watchdog.Stop()
+	}
+
+	response, err := a.client.Stream(ctx, req, func(delta string) {
+		if watchdog != nil {
+			watchdog.Reset(a.cfg.IdleTimeout)
+		}
+		onDelta(delta)
+	})
+	a.usage.Add(response.Usage)
+	if err != nil && idled.Load() {
+		err = fmt.Errorf("stream idle for %s: %w", a.cfg.IdleTimeout, err)
+	}
+	return response, err
+}
+
+// overlapStitcher drops a resumed stream's leading text when it repeats the
+// tail of what was already printed.
+type overlapStitcher struct {
+	tail    string
+	emit    func(string)
+	pending strings.Builder
+	done    bool
+}
+
+func (s *overlapStitcher) write(delta string) {
+	if s.done {
+		s.emit(delta)
+		return
+	}
+	s.pending.WriteString(delta)
+	if s.pending.Len() >= len(s.tail) {
+		s.flush()
+	}
+}
+
+func (s *overlapStitcher) flush() {
+	if s.done {
+		return
+	}
+	s.done = true
+	if rest := trimOverlap(s.tail, s.pending.String()); rest != "" {
+		s.emit(rest)
+	}
+}
+
+// trimOverlap removes the longest prefix of next that is also a suffix of printed.
+func trimOverlap(printed, next string) string {
+	limit := len(printed)
+	if len(next) < limit {
+		limit = len(next)
+	}
+	for size := limit; size > 0; size-- {
+		if strings.HasSuffix(printed, next[:size]) {
+			return next[size:]
+		}
+	}
+	return next
+}
+
+func tailOf(text string, n int) string {
+	if len(text) <= n {
+		return text
+	}
+	return text[len(text)-n:]
+}
@@ -0,0 +1,61 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"fi-cli/internal/events"
+	"fi-cli/internal/llm"
+
+	"github.com/openai/openai-go/v3"
+	"github.com/openai/openai-go/v3/packages/param"
+)
+
+// ErrTimedOut is returned, wrapped with the limit, when cfg.Timeout stops the
+// tool loop. The run still finishes with a partial answer.
+var ErrTimedOut = errors.New("run timed out")
+
+// timeoutGrace bounds the answer written after the run deadline.
+const timeoutGrace = 15 * time.Second
+
+const timeoutAnswerPrompt = "The run time limit was reached, so no more tools can run. Write a brief answer (a few lines) from the evidence gathered so far, keep its citations, and say what is still unverified."
+
+// timeoutAnswer asks for a brief answer from the evidence in messages once the
+// run deadline has passed. It runs on ctx with its own short grace budget and
+// falls back to a fixed note when the model cannot answer in time.
+func (a *Agent) timeoutAnswer(ctx context.Context, messages []openai.ChatCompletionMessageParamUnion, toolsDefs []openai.ChatCompletionToolUnionParam, emit func(events.Event)) string {
+	fallback := fmt.Sprintf("The run hit its %s time limit before an answer was ready.", a.cfg.Timeout)
+	graceCtx, cancel := context.WithTimeout(ctx, timeoutGrace)
+	defer cancel()
+	req := llm.Request{
+		Model:     a.cfg.Model,
+		Messages:  append(messages, openai.DeveloperMessage(timeoutAnswerPrompt)),
+		Tools:     toolsDefs,
+		MaxTokens: maxTokensFor(a.cfg.Verbosity),
+	}
+	if len(toolsDefs) > 0 {
+		req.ToolChoice = openai.ChatCompletionToolChoiceOptionUnionParam{OfAuto: param.NewOpt("none")}
+	}
+	var answer string
+	if a.cfg.JSON {
+		response, err := a.create(graceCtx, req)
+		if err != nil {
+			a.logger.Debug("timeout answer failed")
+			return fallback
+		}
+		answer = response.Content
+	} else {
+		streamed, err := a.streamFinal(graceCtx, req, emit)
+		if err != nil && strings.TrimSpace(streamed) == "" {
+			return fallback
+		}
+		answer = streamed
+	}
+	if strings.TrimSpace(answer) == "" {
+		return fallback
+	}
+	return answer
+}
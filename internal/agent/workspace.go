@@ -0,0 +1,32 @@
+package agent
+
+import (
+	"fmt"
+	"os"
+
+	"go.uber.org/zap"
+)
+
+// openWorkspace creates the run's scratch directory for tool artifacts and
+// returns it with a cleanup func. Pipeline stages share their parent's
+// workspace, so only the run that created it removes it. With
+// cfg.KeepWorkspace the directory is left in place and reported through warn.
+func (a *Agent) openWorkspace(runID string, warn func(string)) (string, func()) {
+	if a.workspace != "" {
+		return a.workspace, func() {}
+	}
+	dir, err := os.MkdirTemp("", "fi-run-"+runID[:8]+"-")
+	if err != nil {
+		warn(fmt.Sprintf("no run workspace: %v", err))
+		return "", func() {}
+	}
+	if a.cfg.KeepWorkspace {
+		warn("run workspace kept at " + dir)
+		return dir, func() {}
+	}
+	return dir, func() {
+		if err := os.RemoveAll(dir); err != nil {
+			a.logger.Debug("remove run workspace", zap.String("dir", dir), zap.Error(err))
+		}
+	}
+}
@@ -0,0 +1,257 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"fi-cli/internal/agent"
+	"fi-cli/internal/events"
+)
+
+// ModelPrefix starts the model name of an OpenAI-compatible request:
+// "fi:<repo path>", or just "fi" for a user with a single allowed path.
+const ModelPrefix = "fi"
+
+type chatMessage struct {
+	Role    string          `json:"role"`
+	Content json.RawMessage `json:"content"`
+}
+
+type chatRequest struct {
+	Model    string        `json:"model"`
+	Messages []chatMessage `json:"messages"`
+	Stream   bool          `json:"stream"`
+}
+
+// text returns a message's content, which clients send as a string or as a
+// list of parts; only text parts are kept.
+func (m chatMessage) text() string {
+	var text string
+	if err := json.Unmarshal(m.Content, &text); err == nil {
+		return text
+	}
+	var parts []struct {
+		Type string `json:"type"`
+		Text string `json:"text"`
+	}
+	_ = json.Unmarshal(m.Content, &parts)
+	var texts []string
+	for _, part := range parts {
+		if part.Type == "text" {
+			texts = append(texts, part.Text)
+		}
+	}
+	return strings.Join(texts, "\n")
+}
+
+// chatRun turns a conversation into a question and the follow-up exchanges
+// before it. System messages are dropped: fi brings its own instructions.
+func chatRun(messages []chatMessage) (string, []agent.Exchange, error) {
+	var turns []chatMessage
+	for _, message := range messages {
+		if message.Role == "user" || message.Role == "assistant" {
+			turns = append(turns, message)
+		}
+	}
+	if len(turns) == 0 || turns[len(turns)-1].Role != "user" {
+		return "", nil, errors.New("the last message must come from the user")
+	}
+	var exchanges []agent.Exchange
+	var pending []string
+	for _, turn := range turns[:len(turns)-1] {
+		if turn.Role == "user" {
+			pending = append(pending, turn.text())
+			continue
+		}
+		exchanges = append(exchanges, agent.Exchange{Question: strings.Join(pending, "\n"), Answer: turn.text()})
+		pending = nil
+	}
+	question := strings.Join(append(pending, turns[len(turns)-1].text()), "\n")
+	if strings.TrimSpace(question) == "" {
+		return "", nil, errors.New("the last user message is empty")
+	}
+	return question, exchanges, nil
+}
+
+// modelRepo maps a model name to the repository it asks about.
+func modelRepo(user User, model string) (string, error) {
+	if model == ModelPrefix {
+		if len(user.AllowedPaths) != 1 {
+			return "", fmt.Errorf("model %q needs a repo: use %s:<path>", model, ModelPrefix)
+		}
+		return user.AllowedPaths[0], nil
+	}
+	repo, ok := strings.CutPrefix(model, ModelPrefix+":")
+	if !ok || repo == "" {
+		return "", fmt.Errorf("unknown model %q (expected %s or %s:<repo path>)", model, ModelPrefix, ModelPrefix)
+	}
+	return repo, nil
+}
+
+func (s *Server) listModels(w http.ResponseWriter, r *http.Request, user User) {
+	type model struct {
+		ID      string `json:"id"`
+		Object  string `json:"object"`
+		Created int64  `json:"created"`
+		OwnedBy string `json:"owned_by"`
+	}
+	var ids []string
+	if len(user.AllowedPaths) == 1 {
+		ids = append(ids, ModelPrefix)
+	}
+	for _, path := range user.AllowedPaths {
+		ids = append(ids, ModelPrefix+":"+path)
+	}
+	models := make([]model, 0, len(ids))
+	for _, id := range ids {
+		models = append(models, model{ID: id, Object: "model", OwnedBy: "fi"})
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"object": "list", "data": models})
+}
+
+func (s *Server) chatCompletions(w http.ResponseWriter, r *http.Request, user User) {
+	var req chatRequest
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxRequestBytes)).Decode(&req); err != nil {
+		writeChatError(w, http.StatusBadRequest, "invalid request body: "+err.Error())
+		return
+	}
+	question, exchanges, err := chatRun(req.Messages)
+	if err != nil {
+		writeChatError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	repo, err := modelRepo(user, req.Model)
+	if err != nil {
+		writeChatError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if !user.Allows(repo) {
+		writeChatError(w, http.StatusForbidden, "repo "+repo+" is not in the paths allowed for "+user.Name)
+		return
+	}
+	params := RunParams{Question: question, Repo: repo, FollowUps: exchanges}
+	created := time.Now().Unix()
+
+	if !req.Stream {
+		result, err := s.run(r.Context(), user, repo, params, nil)
+		switch {
+		case errors.Is(err, ErrForbidden):
+			writeChatError(w, http.StatusForbidden, err.Error())
+		case err != nil && result.FinalAnswer == "":
+			writeChatError(w, http.StatusInternalServerError, err.Error())
+		default:
+			writeJSON(w, http.StatusOK, map[string]any{
+				"id":      completionID(result.RunID),
+				"object":  "chat.completion",
+				"created": created,
+				"model":   req.Model,
+				"choices": []map[string]any{{"index": 0, "message": map[string]string{"role": "assistant", "content": result.FinalAnswer}, "finish_reason": "stop"}},
+				"usage":   map[string]int{"prompt_tokens": result.Usage.PromptTokens, "completion_tokens": result.Usage.CompletionTokens, "total_tokens": result.Usage.PromptTokens + result.Usage.CompletionTokens},
+			})
+		}
+		return
+	}
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeChatError(w, http.StatusInternalServerError, "streaming unsupported")
+		return
+	}
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	stream := &chatStream{w: w, flusher: flusher, model: req.Model, created: created}
+	result, err := s.run(r.Context(), user, repo, params, stream)
+	stream.finish(result, err)
+}
+
+// chatStream sends the answer's deltas as chat.completion.chunk events. An
+// answer that was not streamed, such as one from the fast path, goes out as
+// one chunk at the end.
+type chatStream struct {
+	w       http.ResponseWriter
+	flusher http.Flusher
+	model   string
+	created int64
+
+	mu     sync.Mutex
+	id     string
+	opened bool
+	sent   bool
+}
+
+func (c *chatStream) Emit(event events.Event) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	switch payload := event.Payload.(type) {
+	case events.RunStartedPayload:
+		c.id = completionID(payload.RunID)
+	case events.ModelDeltaPayload:
+		if payload.Delta != "" {
+			c.chunk(map[string]string{"content": payload.Delta}, nil)
+			c.sent = true
+		}
+	}
+}
+
+func (c *chatStream) Close() error { return nil }
+
+func (c *chatStream) finish(result agent.RunResult, err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if !c.sent {
+		content := result.FinalAnswer
+		if content == "" && err != nil {
+			content = "fi could not answer: " + err.Error()
+		}
+		c.chunk(map[string]string{"content": content}, nil)
+	}
+	stop := "stop"
+	c.chunk(map[string]string{}, &stop)
+	fmt.Fprint(c.w, "data: [DONE]\n\n")
+	c.flusher.Flush()
+}
+
+// chunk writes one event; the first also carries the assistant role.
+func (c *chatStream) chunk(delta map[string]string, finishReason *string) {
+	if !c.opened {
+		delta["role"] = "assistant"
+		c.opened = true
+	}
+	if c.id == "" {
+		c.id = completionID("")
+	}
+	payload, _ := json.Marshal(map[string]any{
+		"id":      c.id,
+		"object":  "chat.completion.chunk",
+		"created": c.created,
+		"model":   c.model,
+		"choices": []map[string]any{{"index": 0, "delta": delta, "finish_reason": finishReason}},
+	})
+	fmt.Fprintf(c.w, "data: %s\n\n", payload)
+	c.flusher.Flush()
+}
+
+func completionID(runID string) string {
+	if runID == "" {
+		return "chatcmpl-fi"
+	}
+	return "chatcmpl-" + runID
+}
+
+// writeChatError answers in the OpenAI error shape that chat clients parse.
+func writeChatError(w http.ResponseWriter, status int, message string) {
+	kind := "invalid_request_error"
+	switch status {
+	case http.StatusForbidden:
+		kind = "permission_error"
+	case http.StatusInternalServerError:
+		kind = "server_error"
+	}
+	writeJSON(w, status, map[string]any{"error": map[string]string{"message": message, "type": kind}})
+}
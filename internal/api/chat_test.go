@@ -0,0 +1,120 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"fi-cli/internal/agent"
+	"fi-cli/internal/events"
+	"fi-cli/internal/llm"
+	"fi-cli/internal/render"
+)
+
+func TestChatCompletions(t *testing.T) {
+	repo := t.TempDir()
+	var got RunParams
+	run := func(ctx context.Context, user User, path string, params RunParams, renderer render.Renderer) (agent.RunResult, error) {
+		got = params
+		if renderer != nil {
+			renderer.Emit(events.Event{Type: events.RunStarted, Timestamp: time.Now(), Payload: events.RunStartedPayload{RunID: "r7"}})
+			for _, delta := range []string{"Retries ", "live in retry.go [T1]."} {
+				renderer.Emit(events.Event{Type: events.ModelDelta, Payload: events.ModelDeltaPayload{Delta: delta}})
+			}
+		}
+		return agent.RunResult{RunID: "r7", FinalAnswer: "Retries live in retry.go [T1].", Usage: llm.Usage{PromptTokens: 10, CompletionTokens: 4}}, nil
+	}
+	server := httptest.NewServer(NewServer([]User{{Name: "alice", Token: "t", AllowedPaths: []string{repo}}}, run, nil).Handler())
+	defer server.Close()
+	post := func(body string) (*http.Response, string) {
+		t.Helper()
+		req, _ := http.NewRequest("POST", server.URL+"/v1/chat/completions", strings.NewReader(body))
+		req.Header.Set("Authorization", "Bearer t")
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		b, _ := io.ReadAll(resp.Body)
+		return resp, string(b)
+	}
+
+	resp, body := post(`{"model":"fi","messages":[{"role":"system","content":"be terse"},{"role":"user","content":"where are retries?"},{"role":"assistant","content":"In retry.go."},{"role":"user","content":[{"type":"text","text":"and the backoff?"}]}]}`)
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected 200, got %d %s", resp.StatusCode, body)
+	}
+	var completion struct {
+		ID      string `json:"id"`
+		Choices []struct {
+			Message struct {
+				Content string `json:"content"`
+			} `json:"message"`
+		} `json:"choices"`
+		Usage struct {
+			TotalTokens int `json:"total_tokens"`
+		} `json:"usage"`
+	}
+	_ = json.Unmarshal([]byte(body), &completion)
+	if completion.ID != "chatcmpl-r7" || completion.Choices[0].Message.Content != "Retries live in retry.go [T1]." || completion.Usage.TotalTokens != 14 {
+		t.Fatalf("unexpected completion %s", body)
+	}
+	if got.Question != "and the backoff?" || got.Repo != repo || len(got.FollowUps) != 1 || got.FollowUps[0].Question != "where are retries?" || got.FollowUps[0].Answer != "In retry.go." {
+		t.Fatalf("unexpected run params %+v", got)
+	}
+
+	resp, body = post(`{"model":"fi:` + repo + `","stream":true,"messages":[{"role":"user","content":"where are retries?"}]}`)
+	if resp.Header.Get("Content-Type") != "text/event-stream" {
+		t.Fatalf("expected an event stream, got %q", resp.Header.Get("Content-Type"))
+	}
+	var content strings.Builder
+	var chunks int
+	for _, line := range strings.Split(body, "\n") {
+		data, ok := strings.CutPrefix(line, "data: ")
+		if !ok || data == "[DONE]" {
+			continue
+		}
+		var chunk struct {
+			ID      string `json:"id"`
+			Choices []struct {
+				Delta struct {
+					Content string `json:"content"`
+				} `json:"delta"`
+			} `json:"choices"`
+		}
+		if err := json.Unmarshal([]byte(data), &chunk); err != nil || chunk.ID != "chatcmpl-r7" {
+			t.Fatalf("unexpected chunk %q (%v)", data, err)
+		}
+		content.WriteString(chunk.Choices[0].Delta.Content)
+		chunks++
+	}
+	if content.String() != "Retries live in retry.go [T1]." || chunks != 3 || !strings.HasSuffix(body, "data: [DONE]\n\n") {
+		t.Fatalf("unexpected stream %q", body)
+	}
+
+	for request, want := range map[string]int{
+		`{"model":"gpt-4o","messages":[{"role":"user","content":"q"}]}`:      http.StatusBadRequest,
+		`{"model":"fi:/etc","messages":[{"role":"user","content":"q"}]}`:     http.StatusForbidden,
+		`{"model":"fi","messages":[{"role":"assistant","content":"hello"}]}`: http.StatusBadRequest,
+	} {
+		if resp, body := post(request); resp.StatusCode != want || !strings.Contains(body, `"message"`) {
+			t.Fatalf("%s: expected %d with an OpenAI error, got %d %s", request, want, resp.StatusCode, body)
+		}
+	}
+
+	req, _ := http.NewRequest("GET", server.URL+"/v1/models", nil)
+	req.Header.Set("Authorization", "Bearer t")
+	models, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer models.Body.Close()
+	b, _ := io.ReadAll(models.Body)
+	if !strings.Contains(string(b), `"id":"fi"`) || !strings.Contains(string(b), `"id":"fi:`+repo+`"`) {
+		t.Fatalf("unexpected models %s", b)
+	}
+}
@@ -0,0 +1,211 @@
+// Package api is the HTTP API of `fi-cli serve`: authenticated users start
+// runs against the repositories they are allowed to read and fetch their own
+// run logs.
+package api
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"net/http"
+	"path/filepath"
+	"strings"
+
+	"fi-cli/internal/agent"
+	"fi-cli/internal/render"
+)
+
+// maxRequestBytes bounds a request body.
+const maxRequestBytes = 1 << 20
+
+// ErrForbidden marks a run refused because its repository is outside the
+// user's allowed paths.
+var ErrForbidden = errors.New("forbidden")
+
+// User is an API caller. AllowedPaths are absolute.
+type User struct {
+	Name         string
+	Token        string
+	AllowedPaths []string
+}
+
+// Allows reports whether path lies in one of the user's allowed paths, after
+// resolving symlinks on both sides.
+func (u User) Allows(path string) bool {
+	resolved, err := realPath(path)
+	if err != nil {
+		return false
+	}
+	for _, allowed := range u.AllowedPaths {
+		root, err := realPath(allowed)
+		if err != nil {
+			continue
+		}
+		rel, err := filepath.Rel(root, resolved)
+		if err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			return true
+		}
+	}
+	return false
+}
+
+func realPath(path string) (string, error) {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return "", err
+	}
+	return filepath.EvalSymlinks(abs)
+}
+
+// RunParams is the body of POST /v1/runs. Repo may be left out when the user
+// has a single allowed path. FollowUps carry the earlier turns of a chat, and
+// Upload marks a run in an uploaded workspace.
+type RunParams struct {
+	Question  string           `json:"question"`
+	Repo      string           `json:"repo,omitempty"`
+	Mode      string           `json:"mode,omitempty"`
+	FollowUps []agent.Exchange `json:"-"`
+	Upload    bool             `json:"-"`
+}
+
+// RunFunc executes a run for user in repo, which Allows has accepted or which
+// holds an upload, emitting events through renderer when it is not nil.
+type RunFunc func(ctx context.Context, user User, repo string, params RunParams, renderer render.Renderer) (agent.RunResult, error)
+
+// LoadFunc reads one of the user's persisted runs.
+type LoadFunc func(user User, id string) (agent.RunResult, error)
+
+// Server routes:
+//   - POST /v1/runs: runs a question and answers with the RunResult. A
+//     multipart body uploads the repository instead of naming one.
+//   - GET /v1/runs/{id}: returns one of the caller's runs.
+//   - POST /v1/chat/completions and GET /v1/models: the OpenAI-compatible
+//     facade, where the model names the repository.
+//
+// Every request needs "Authorization: Bearer <token>". Users never see each
+// other's runs: a run ID from another user is not found.
+type Server struct {
+	users []User
+	run   RunFunc
+	load  LoadFunc
+}
+
+// NewServer constructs the API. Users without a token cannot authenticate.
+func NewServer(users []User, run RunFunc, load LoadFunc) *Server {
+	return &Server{users: users, run: run, load: load}
+}
+
+// Handler returns the API routes.
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /v1/runs", s.authed(s.startRun))
+	mux.HandleFunc("GET /v1/runs/{id}", s.authed(s.getRun))
+	mux.HandleFunc("POST /v1/chat/completions", s.authed(s.chatCompletions))
+	mux.HandleFunc("GET /v1/models", s.authed(s.listModels))
+	return mux
+}
+
+func (s *Server) authed(next func(http.ResponseWriter, *http.Request, User)) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		user, ok := s.authenticate(r)
+		if !ok {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="fi"`)
+			writeError(w, http.StatusUnauthorized, "missing or unknown bearer token")
+			return
+		}
+		next(w, r, user)
+	}
+}
+
+// authenticate compares hashes of the tokens in constant time, checking
+// every user so timing does not reveal which one matched.
+func (s *Server) authenticate(r *http.Request) (User, bool) {
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !ok || token == "" {
+		return User{}, false
+	}
+	sum := sha256.Sum256([]byte(token))
+	var match User
+	found := false
+	for _, user := range s.users {
+		if user.Token == "" {
+			continue
+		}
+		want := sha256.Sum256([]byte(user.Token))
+		if subtle.ConstantTimeCompare(sum[:], want[:]) == 1 {
+			match, found = user, true
+		}
+	}
+	return match, found
+}
+
+func (s *Server) startRun(w http.ResponseWriter, r *http.Request, user User) {
+	if strings.HasPrefix(r.Header.Get("Content-Type"), "multipart/form-data") {
+		s.startUploadRun(w, r, user)
+		return
+	}
+	var params RunParams
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxRequestBytes)).Decode(&params); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body: "+err.Error())
+		return
+	}
+	if strings.TrimSpace(params.Question) == "" {
+		writeError(w, http.StatusBadRequest, "question is required")
+		return
+	}
+	repo := params.Repo
+	if repo == "" {
+		if len(user.AllowedPaths) != 1 {
+			writeError(w, http.StatusBadRequest, "repo is required")
+			return
+		}
+		repo = user.AllowedPaths[0]
+	}
+	if !filepath.IsAbs(repo) {
+		writeError(w, http.StatusBadRequest, "repo must be an absolute path")
+		return
+	}
+	if !user.Allows(repo) {
+		writeError(w, http.StatusForbidden, "repo "+repo+" is not in the paths allowed for "+user.Name)
+		return
+	}
+	result, err := s.run(r.Context(), user, repo, params, nil)
+	writeRun(w, result, err)
+}
+
+func writeRun(w http.ResponseWriter, result agent.RunResult, err error) {
+	switch {
+	case errors.Is(err, ErrForbidden):
+		writeError(w, http.StatusForbidden, err.Error())
+	case err != nil && result.RunID == "":
+		writeError(w, http.StatusInternalServerError, err.Error())
+	default:
+		// a run that started reports its own failure in its status
+		writeJSON(w, http.StatusOK, result)
+	}
+}
+
+func (s *Server) getRun(w http.ResponseWriter, r *http.Request, user User) {
+	result, err := s.load(user, r.PathValue("id"))
+	switch {
+	case errors.Is(err, fs.ErrNotExist):
+		writeError(w, http.StatusNotFound, "no such run")
+	case err != nil:
+		writeError(w, http.StatusBadRequest, err.Error())
+	default:
+		writeJSON(w, http.StatusOK, result)
+	}
+}
+
+func writeJSON(w http.ResponseWriter, status int, payload any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(payload)
+}
+
+func writeError(w http.ResponseWriter, status int, message string) {
+	writeJSON(w, status, map[string]string{"error": message})
+}
@@ -0,0 +1,99 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"fi-cli/internal/agent"
+	"fi-cli/internal/render"
+)
+
+func TestServerAuthAndIsolation(t *testing.T) {
+	root := t.TempDir()
+	work := filepath.Join(root, "work")
+	other := filepath.Join(root, "other")
+	for _, dir := range []string{filepath.Join(work, "app"), other} {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.Symlink(other, filepath.Join(work, "escape")); err != nil {
+		t.Fatal(err)
+	}
+	saved := map[string]agent.RunResult{}
+	run := func(ctx context.Context, user User, repo string, params RunParams, renderer render.Renderer) (agent.RunResult, error) {
+		result := agent.RunResult{RunID: "run-" + user.Name, Question: params.Question, Status: "success"}
+		saved[user.Name+"/"+result.RunID] = result
+		return result, nil
+	}
+	load := func(user User, id string) (agent.RunResult, error) {
+		result, ok := saved[user.Name+"/"+id]
+		if !ok {
+			return agent.RunResult{}, fs.ErrNotExist
+		}
+		return result, nil
+	}
+	server := httptest.NewServer(NewServer([]User{
+		{Name: "alice", Token: "a-token", AllowedPaths: []string{work}},
+		{Name: "bob", Token: "b-token", AllowedPaths: []string{other, work}},
+		{Name: "carol", AllowedPaths: []string{work}},
+	}, run, load).Handler())
+	defer server.Close()
+
+	call := func(method, path, token, body string) (int, map[string]any) {
+		t.Helper()
+		req, _ := http.NewRequest(method, server.URL+path, strings.NewReader(body))
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		var payload map[string]any
+		_ = json.NewDecoder(resp.Body).Decode(&payload)
+		return resp.StatusCode, payload
+	}
+
+	if status, _ := call("POST", "/v1/runs", "", `{"question":"q"}`); status != http.StatusUnauthorized {
+		t.Fatalf("expected 401 without a token, got %d", status)
+	}
+	if status, _ := call("POST", "/v1/runs", "wrong", `{"question":"q"}`); status != http.StatusUnauthorized {
+		t.Fatalf("expected 401 for an unknown token, got %d", status)
+	}
+	status, payload := call("POST", "/v1/runs", "a-token", `{"question":"why?"}`)
+	if status != http.StatusOK || payload["run_id"] != "run-alice" {
+		t.Fatalf("expected alice's run in her only allowed path, got %d %v", status, payload)
+	}
+	for body, want := range map[string]int{
+		fmt.Sprintf(`{"question":"q","repo":%q}`, filepath.Join(work, "app")):    http.StatusOK,
+		fmt.Sprintf(`{"question":"q","repo":%q}`, other):                         http.StatusForbidden,
+		fmt.Sprintf(`{"question":"q","repo":%q}`, filepath.Join(work, "escape")): http.StatusForbidden,
+		fmt.Sprintf(`{"question":"q","repo":%q}`, work+"/../other"):              http.StatusForbidden,
+		`{"question":"q","repo":"work"}`:                                         http.StatusBadRequest,
+		`{"question":" "}`:                                                       http.StatusBadRequest,
+	} {
+		if status, payload := call("POST", "/v1/runs", "a-token", body); status != want {
+			t.Fatalf("%s: expected %d, got %d %v", body, want, status, payload)
+		}
+	}
+	if status, _ := call("POST", "/v1/runs", "b-token", `{"question":"q"}`); status != http.StatusBadRequest {
+		t.Fatalf("expected a repo to be required with two allowed paths, got %d", status)
+	}
+
+	if status, payload := call("GET", "/v1/runs/run-alice", "a-token", ""); status != http.StatusOK || payload["question"] != "q" {
+		t.Fatalf("expected alice's run, got %d %v", status, payload)
+	}
+	if status, _ := call("GET", "/v1/runs/run-alice", "b-token", ""); status != http.StatusNotFound {
+		t.Fatalf("expected bob not to see alice's run, got %d", status)
+	}
+}
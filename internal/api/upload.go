@@ -0,0 +1,149 @@
+package api
+
+import (
+	"archive/tar"
+	"bufio"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	// maxUploadBytes bounds an uploaded repository, both the request body and
+	// the extracted files.
+	maxUploadBytes = 256 << 20
+	// maxUploadFiles bounds how many files an upload may extract.
+	maxUploadFiles = 50000
+)
+
+// startUploadRun runs against a repository uploaded as the "repo" part of a
+// multipart form, a tar or tar.gz archive. It is extracted to a temp
+// workspace that is removed when the run ends; "question" and "mode" are
+// form fields.
+func (s *Server) startUploadRun(w http.ResponseWriter, r *http.Request, user User) {
+	r.Body = http.MaxBytesReader(w, r.Body, maxUploadBytes)
+	reader, err := r.MultipartReader()
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid multipart body: "+err.Error())
+		return
+	}
+	workspace, err := os.MkdirTemp("", "fi-upload-")
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	defer os.RemoveAll(workspace)
+
+	params := RunParams{Upload: true}
+	uploaded := false
+	for {
+		part, err := reader.NextPart()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "invalid multipart body: "+err.Error())
+			return
+		}
+		switch part.FormName() {
+		case "question", "mode":
+			value, _ := io.ReadAll(io.LimitReader(part, maxRequestBytes))
+			if part.FormName() == "question" {
+				params.Question = string(value)
+			} else {
+				params.Mode = strings.TrimSpace(string(value))
+			}
+		case "repo":
+			if err := extractArchive(part, workspace); err != nil {
+				writeError(w, http.StatusBadRequest, "invalid repo archive: "+err.Error())
+				return
+			}
+			uploaded = true
+		}
+		_ = part.Close()
+	}
+	if strings.TrimSpace(params.Question) == "" {
+		writeError(w, http.StatusBadRequest, "question is required")
+		return
+	}
+	if !uploaded {
+		writeError(w, http.StatusBadRequest, "repo archive is required")
+		return
+	}
+	result, err := s.run(r.Context(), user, workspace, params, nil)
+	writeRun(w, result, err)
+}
+
+// extractArchive unpacks a tar or gzip-compressed tar into dir. Entries that
+// would land outside dir are refused; symlinks, hard links, and devices are
+// skipped, so nothing in the workspace points outside it.
+func extractArchive(r io.Reader, dir string) error {
+	buffered := bufio.NewReader(r)
+	var src io.Reader = buffered
+	if magic, _ := buffered.Peek(2); len(magic) == 2 && magic[0] == 0x1f && magic[1] == 0x8b {
+		gz, err := gzip.NewReader(buffered)
+		if err != nil {
+			return err
+		}
+		defer gz.Close()
+		src = gz
+	}
+	archive := tar.NewReader(src)
+	var total int64
+	files := 0
+	for {
+		header, err := archive.Next()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if !filepath.IsLocal(header.Name) {
+			return fmt.Errorf("unsafe path %q", header.Name)
+		}
+		target := filepath.Join(dir, filepath.FromSlash(header.Name))
+		switch header.Typeflag {
+		case tar.TypeDir:
+			if err := os.MkdirAll(target, 0o755); err != nil {
+				return err
+			}
+		case tar.TypeReg:
+			files++
+			total += header.Size
+			if files > maxUploadFiles {
+				return fmt.Errorf("more than %d files", maxUploadFiles)
+			}
+			if total > maxUploadBytes {
+				return fmt.Errorf("more than %d bytes once extracted", maxUploadBytes)
+			}
+			if err := writeArchiveFile(target, header, archive); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+func writeArchiveFile(target string, header *tar.Header, r io.Reader) error {
+	if err := os.MkdirAll(filepath.Dir(target), 0o755); err != nil {
+		return err
+	}
+	mode := os.FileMode(0o644)
+	if header.Mode&0o111 != 0 {
+		mode = 0o755
+	}
+	file, err := os.OpenFile(target, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, mode)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(file, io.LimitReader(r, header.Size)); err != nil {
+		_ = file.Close()
+		return err
+	}
+	return file.Close()
+}
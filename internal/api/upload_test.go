@@ -0,0 +1,101 @@
+package api
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"fi-cli/internal/agent"
+	"fi-cli/internal/render"
+)
+
+func tarball(t *testing.T, entries []tar.Header) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	archive := tar.NewWriter(gz)
+	for _, header := range entries {
+		body := "content of " + header.Name
+		if header.Typeflag == tar.TypeReg {
+			header.Size = int64(len(body))
+		}
+		if err := archive.WriteHeader(&header); err != nil {
+			t.Fatal(err)
+		}
+		if header.Typeflag == tar.TypeReg {
+			_, _ = archive.Write([]byte(body))
+		}
+	}
+	_ = archive.Close()
+	_ = gz.Close()
+	return buf.Bytes()
+}
+
+func TestUploadRun(t *testing.T) {
+	var workspace string
+	var seen []string
+	run := func(ctx context.Context, user User, repo string, params RunParams, renderer render.Renderer) (agent.RunResult, error) {
+		workspace = repo
+		_ = filepath.WalkDir(repo, func(path string, d os.DirEntry, err error) error {
+			if rel, _ := filepath.Rel(repo, path); !d.IsDir() {
+				seen = append(seen, filepath.ToSlash(rel))
+			}
+			return nil
+		})
+		if !params.Upload || params.Question != "what does main do?" {
+			t.Errorf("unexpected params %+v", params)
+		}
+		return agent.RunResult{RunID: "u1", Status: "success"}, nil
+	}
+	server := httptest.NewServer(NewServer([]User{{Name: "ci", Token: "t", AllowedPaths: []string{t.TempDir()}}}, run, nil).Handler())
+	defer server.Close()
+	upload := func(archive []byte) (int, string) {
+		t.Helper()
+		var body bytes.Buffer
+		form := multipart.NewWriter(&body)
+		_ = form.WriteField("question", "what does main do?")
+		part, _ := form.CreateFormFile("repo", "workspace.tar.gz")
+		_, _ = part.Write(archive)
+		_ = form.Close()
+		req, _ := http.NewRequest("POST", server.URL+"/v1/runs", &body)
+		req.Header.Set("Content-Type", form.FormDataContentType())
+		req.Header.Set("Authorization", "Bearer t")
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		b, _ := io.ReadAll(resp.Body)
+		return resp.StatusCode, string(b)
+	}
+
+	status, body := upload(tarball(t, []tar.Header{
+		{Name: "./", Typeflag: tar.TypeDir, Mode: 0o755},
+		{Name: "cmd/main.go", Typeflag: tar.TypeReg, Mode: 0o644},
+		{Name: "run.sh", Typeflag: tar.TypeReg, Mode: 0o755},
+		{Name: "passwd", Typeflag: tar.TypeSymlink, Linkname: "/etc/passwd"},
+	}))
+	if status != http.StatusOK || !strings.Contains(body, `"run_id":"u1"`) {
+		t.Fatalf("expected the run, got %d %s", status, body)
+	}
+	if strings.Join(seen, ",") != "cmd/main.go,run.sh" {
+		t.Fatalf("expected only the regular files, got %v", seen)
+	}
+	if _, err := os.Stat(workspace); !os.IsNotExist(err) {
+		t.Fatalf("expected the workspace to be removed, got %v", err)
+	}
+
+	status, body = upload(tarball(t, []tar.Header{{Name: "../escape.txt", Typeflag: tar.TypeReg}}))
+	if status != http.StatusBadRequest || !strings.Contains(body, "unsafe path") {
+		t.Fatalf("expected an unsafe path to be refused, got %d %s", status, body)
+	}
+}
@@ -0,0 +1,156 @@
+package cache
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"fi-cli/internal/agent"
+	"fi-cli/internal/config"
+	"fi-cli/internal/repo"
+)
+
+// Entry is a cached answer and the run that produced it.
+type Entry struct {
+	Key  string `json:"key"`
+	Repo string `json:"repo,omitempty"`
+	// Fingerprint is the state of Repo the answer was derived from.
+	Fingerprint repo.Fingerprint `json:"fingerprint"`
+	StoredAt    time.Time        `json:"stored_at"`
+	Result      agent.RunResult  `json:"result"`
+}
+
+// Dir returns the directory where cached answers are stored.
+func Dir() (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, ".local", "share", "fi.ashref.tn", "cache", "answers"), nil
+}
+
+// Key derives the cache key from the question, the repo fingerprint (HEAD and
+// any uncommitted changes), and the settings that change what an answer
+// looks like. Shell history and terminal output are deliberately excluded:
+// they change with every command.
+func Key(question string, fingerprint repo.Fingerprint, cfg config.Config) string {
+	settings, _ := json.Marshal(struct {
+		Model          string
+		ResponseMode   string
+		AnswerLanguage string
+		Verbosity      string
+		SelfAssess     bool
+		NoWeb          bool
+		UnsafeShell    bool
+		ShellAllowlist []string
+		Tools          config.ToolSelection
+		MaxSteps       int
+		// omitted when unset so keys stored before they existed keep matching
+		Tabular   bool              `json:",omitempty"`
+		Consensus *config.Consensus `json:",omitempty"`
+	}{cfg.Model, cfg.ResponseMode, cfg.AnswerLanguage, cfg.Verbosity, cfg.SelfAssess, cfg.NoWeb, cfg.UnsafeShell, cfg.ShellAllowlist, cfg.Tools, cfg.MaxSteps, cfg.OutputFormat == config.OutputTable || cfg.OutputFormat == config.OutputTSV, consensusKey(cfg)})
+	normalized := strings.ToLower(strings.Join(strings.Fields(question), " "))
+	sum := sha256.Sum256([]byte(normalized + "\x00" + fingerprint.Sum() + "\x00" + string(settings)))
+	return hex.EncodeToString(sum[:])
+}
+
+func consensusKey(cfg config.Config) *config.Consensus {
+	if cfg.Consensus.Answers < 2 {
+		return nil
+	}
+	return &cfg.Consensus
+}
+
+// Lookup returns the cached entry for key, if any.
+func Lookup(key string) (Entry, bool) {
+	var entry Entry
+	dir, err := Dir()
+	if err != nil {
+		return entry, false
+	}
+	data, err := os.ReadFile(filepath.Join(dir, key+".json"))
+	if err != nil {
+		return entry, false
+	}
+	if err := json.Unmarshal(data, &entry); err != nil || entry.Key != key {
+		return Entry{}, false
+	}
+	return entry, true
+}
+
+// Store saves a successful run under key, with the fingerprint of repoRoot
+// it was answered against.
+func Store(key, repoRoot string, fingerprint repo.Fingerprint, result agent.RunResult) error {
+	if result.Status != "success" {
+		return errors.New("only successful runs are cached")
+	}
+	dir, err := Dir()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+	payload, err := json.MarshalIndent(Entry{Key: key, Repo: repoRoot, Fingerprint: fingerprint, StoredAt: time.Now(), Result: result}, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(dir, key+".json"), payload, 0o600)
+}
+
+// List returns every cached entry, skipping unreadable files.
+func List() ([]Entry, error) {
+	dir, err := Dir()
+	if err != nil {
+		return nil, err
+	}
+	files, err := os.ReadDir(dir)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var entries []Entry
+	for _, file := range files {
+		key, ok := strings.CutSuffix(file.Name(), ".json")
+		if file.IsDir() || !ok {
+			continue
+		}
+		if entry, ok := Lookup(key); ok {
+			entries = append(entries, entry)
+		}
+	}
+	return entries, nil
+}
+
+// Remove deletes the cached entry under key.
+func Remove(key string) error {
+	dir, err := Dir()
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(filepath.Join(dir, key+".json")); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+// Stale reports what changed in the entry's repo since it was cached, or
+// nil while the entry is current. Entries cached before fingerprints were
+// recorded, or for a repo that is gone, are always stale.
+func (e Entry) Stale(current func(repoRoot string) repo.Fingerprint) []string {
+	if e.Repo == "" {
+		return []string{"no fingerprint"}
+	}
+	if _, err := os.Stat(e.Repo); err != nil {
+		return []string{"repo missing"}
+	}
+	return e.Fingerprint.Changed(current(e.Repo))
+}
@@ -0,0 +1,91 @@
+package cache
+
+import (
+	"strings"
+	"testing"
+
+	"fi-cli/internal/agent"
+	"fi-cli/internal/config"
+	"fi-cli/internal/repo"
+)
+
+func TestKeyNormalizesQuestion(t *testing.T) {
+	cfg := config.Config{Model: "m"}
+	abc := repo.Fingerprint{Head: "abc"}
+	if Key("How do I run  tests", abc, cfg) != Key("how do i run tests", abc, cfg) {
+		t.Fatalf("expected whitespace and case to be ignored")
+	}
+	if Key("how do i run tests", abc, cfg) == Key("how do i run tests", repo.Fingerprint{Head: "def"}, cfg) {
+		t.Fatalf("expected HEAD to change the key")
+	}
+	dirty := repo.Fingerprint{Head: "abc", Dirty: true, Changes: "1234"}
+	if Key("q", abc, cfg) == Key("q", dirty, cfg) || Key("q", dirty, cfg) == Key("q", repo.Fingerprint{Head: "abc", Dirty: true, Changes: "5678"}, cfg) {
+		t.Fatalf("expected uncommitted changes to change the key")
+	}
+	other := cfg
+	other.Verbosity = config.VerbosityBrief
+	if Key("q", abc, cfg) == Key("q", abc, other) {
+		t.Fatalf("expected config to change the key")
+	}
+}
+
+func TestStoreAndLookup(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	key := Key("q", repo.Fingerprint{Head: "abc"}, config.Config{})
+	if _, ok := Lookup(key); ok {
+		t.Fatalf("expected miss")
+	}
+	fp := repo.Fingerprint{Head: "abc"}
+	if err := Store(key, "/src/app", fp, agent.RunResult{RunID: "r1", Status: "failure"}); err == nil {
+		t.Fatalf("expected failed runs to be rejected")
+	}
+	if err := Store(key, "/src/app", fp, agent.RunResult{RunID: "r1", Status: "success", FinalAnswer: "make test"}); err != nil {
+		t.Fatalf("store: %v", err)
+	}
+	entry, ok := Lookup(key)
+	if !ok || entry.Result.FinalAnswer != "make test" {
+		t.Fatalf("unexpected entry: %+v", entry)
+	}
+}
+
+func TestListStaleAndRemove(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	root := t.TempDir()
+	current := repo.Fingerprint{Head: "abc", Files: map[string]string{"go.mod": "1"}}
+	for key, fp := range map[string]repo.Fingerprint{
+		"current": current,
+		"moved":   {Head: "old", Files: map[string]string{"go.mod": "0", "go.sum": "2"}},
+	} {
+		if err := Store(key, root, fp, agent.RunResult{RunID: key, Status: "success"}); err != nil {
+			t.Fatalf("store: %v", err)
+		}
+	}
+	entries, err := List()
+	if err != nil || len(entries) != 2 {
+		t.Fatalf("expected two entries, got %d (%v)", len(entries), err)
+	}
+	fingerprintOf := func(string) repo.Fingerprint { return current }
+	for _, entry := range entries {
+		stale := entry.Stale(fingerprintOf)
+		switch entry.Key {
+		case "current":
+			if stale != nil {
+				t.Fatalf("expected the current entry to be fresh, got %v", stale)
+			}
+		case "moved":
+			if strings.Join(stale, ",") != "HEAD,go.mod,go.sum" {
+				t.Fatalf("unexpected changes %v", stale)
+			}
+		}
+	}
+	if stale := (Entry{Key: "old"}).Stale(fingerprintOf); len(stale) == 0 {
+		t.Fatalf("expected an entry without a fingerprint to be stale")
+	}
+
+	if err := Remove("moved"); err != nil {
+		t.Fatalf("remove: %v", err)
+	}
+	if _, ok := Lookup("moved"); ok {
+		t.Fatalf("expected the removed entry to be gone")
+	}
+}
@@ -1,9 +1,14 @@
 package config
 
 import (
+	"errors"
 	"fmt"
+	"maps"
 	"os"
 	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
 	"strings"
 	"time"
 
@@ -16,6 +21,8 @@ const (
 	DefaultModel        = "openrouter/pony-alpha"
 	DefaultMaxSteps     = 8
 	DefaultTimeout      = 60 * time.Second
+	DefaultReqTimeout   = 45 * time.Second
+	DefaultIdleTimeout  = 20 * time.Second
 	DefaultBaseURL      = "https://openrouter.ai/api/v1"
 	DefaultResponseMode = "quick"
 	DefaultMaxContext   = 80 * 1024
@@ -24,19 +31,222 @@ const (
 	DefaultShellBytes   = 20 * 1024
 	DefaultWebBytes     = 30 * 1024
 	DefaultMaxFileSize  = 32 * 1024
+	DefaultPaneBytes    = 16 * 1024
+	DefaultListEntries  = 500
+	// DefaultSearchMaxCalls and DefaultInspectMaxCalls are the per-run
+	// category budgets; see ToolLimits.
+	DefaultSearchMaxCalls  = 60
+	DefaultInspectMaxCalls = 20
+	// DefaultMaxAnswerTokens caps a streamed answer well above the detailed
+	// verbosity budget, so it only stops a model that keeps going.
+	DefaultMaxAnswerTokens = 8192
+	// DefaultPreviewLines and DefaultPreviewBytes bound the tool output
+	// preview printed under each call with --verbose.
+	DefaultPreviewLines = 12
+	DefaultPreviewBytes = 2000
+	// DefaultSimilarThreshold is how similar a past question must be before
+	// fi-cli offers its answer instead of a new run; rephrasings score above
+	// it, related but different questions below.
+	DefaultSimilarThreshold = 0.65
+
+	VerbosityBrief    = "brief"
+	VerbosityNormal   = "normal"
+	VerbosityDetailed = "detailed"
+
+	OutputText  = "text"
+	OutputJSON  = "json"
+	OutputTable = "table"
+	OutputTSV   = "tsv"
+
+	JSONStreamTools = "tools"
+	JSONStreamFull  = "full"
+
+	RouteAuto       = "auto"
+	RouteOff        = "off"
+	RouteLookup     = "lookup"
+	RouteResearch   = "research"
+	RouteCodeChange = "code_change"
+	RouteDebugging  = "debugging"
+	RouteGeneral    = "general"
 )
 
+// Routes lists the question routes a run can be forced into with router.mode.
+var Routes = []string{RouteLookup, RouteResearch, RouteCodeChange, RouteDebugging, RouteGeneral}
+
 // ToolLimits controls max output sizes for tools and context.
 type ToolLimits struct {
-	GrepMaxResults  int `mapstructure:"grep_max_results"`
-	GrepMaxBytes    int `mapstructure:"grep_max_bytes"`
-	ShellMaxBytes   int `mapstructure:"shell_max_bytes"`
-	WebMaxBytes     int `mapstructure:"web_max_bytes"`
-	GrepMaxCalls    int `mapstructure:"grep_max_calls"`
-	ShellMaxCalls   int `mapstructure:"shell_max_calls"`
-	WebMaxCalls     int `mapstructure:"web_max_calls"`
+	GrepMaxResults int `mapstructure:"grep_max_results"`
+	GrepMaxBytes   int `mapstructure:"grep_max_bytes"`
+	ShellMaxBytes  int `mapstructure:"shell_max_bytes"`
+	WebMaxBytes    int `mapstructure:"web_max_bytes"`
+	GrepMaxCalls   int `mapstructure:"grep_max_calls"`
+	ShellMaxCalls  int `mapstructure:"shell_max_calls"`
+	// WebMaxCalls caps the web tools together: exa_search, exa_contents,
+	// code_search, and issue_lookup.
+	WebMaxCalls int `mapstructure:"web_max_calls"`
+	// SearchMaxCalls and InspectMaxCalls cap whole tool categories per run:
+	// grep, list_files, and read_file, and the structured-file tools
+	// (archives, data, schemas, env, docker, CI).
+	SearchMaxCalls  int `mapstructure:"search_max_calls"`
+	InspectMaxCalls int `mapstructure:"inspect_max_calls"`
+	// AskUserMaxCalls caps clarifying questions (ask_user) per run.
+	AskUserMaxCalls int `mapstructure:"ask_user_max_calls"`
 	ContextMaxBytes int `mapstructure:"context_max_bytes"`
 	MaxFileBytes    int `mapstructure:"max_file_bytes"`
+	PaneMaxBytes    int `mapstructure:"pane_max_bytes"`
+	ListMaxEntries  int `mapstructure:"list_max_entries"`
+}
+
+// Pricing is the model's price in USD per million tokens, used to estimate the
+// cost shown in the run footer.
+type Pricing struct {
+	InputPerMTok  float64 `mapstructure:"input_per_mtok"`
+	OutputPerMTok float64 `mapstructure:"output_per_mtok"`
+}
+
+// Cost estimates the price of a run's token usage; zero when unpriced.
+func (p Pricing) Cost(promptTokens, completionTokens int) float64 {
+	return (float64(promptTokens)*p.InputPerMTok + float64(completionTokens)*p.OutputPerMTok) / 1e6
+}
+
+// ToolSelection restricts which tools a run may register.
+type ToolSelection struct {
+	Enabled  []string `mapstructure:"enabled"`
+	Disabled []string `mapstructure:"disabled"`
+}
+
+// Allows reports whether the named tool passes the enabled/disabled lists.
+// An empty enabled list allows every tool that is not disabled.
+func (s ToolSelection) Allows(name string) bool {
+	for _, disabled := range s.Disabled {
+		if strings.EqualFold(disabled, name) {
+			return false
+		}
+	}
+	if len(s.Enabled) == 0 {
+		return true
+	}
+	for _, enabled := range s.Enabled {
+		if strings.EqualFold(enabled, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// Router controls how questions are classified into routes that pick tools
+// and prompt guidance. Mode is auto, off, or a route name to force it.
+type Router struct {
+	Mode            string `mapstructure:"mode"`
+	Classifier      bool   `mapstructure:"classifier"`
+	ClassifierModel string `mapstructure:"classifier_model"`
+}
+
+// Consensus asks several models for the answer from the same evidence and
+// has a judge reconcile them. Answers is how many models answer, the run's
+// model first and then Models in order; fewer than 2 turns it off. Judge
+// defaults to the run's model.
+type Consensus struct {
+	Answers int      `mapstructure:"answers"`
+	Models  []string `mapstructure:"models"`
+	Judge   string   `mapstructure:"judge_model"`
+}
+
+// Render controls how tool activity is printed: the size of the output
+// preview shown under each call with --verbose.
+type Render struct {
+	PreviewLines int `mapstructure:"preview_lines"`
+	PreviewBytes int `mapstructure:"preview_bytes"`
+}
+
+// InjectionGuard controls handling of instruction-like content in tool
+// outputs and repository files.
+type InjectionGuard struct {
+	Mode            string `mapstructure:"mode"`
+	Classifier      bool   `mapstructure:"classifier"`
+	ClassifierModel string `mapstructure:"classifier_model"`
+}
+
+// Schedule is a question run on a cron spec by `fi-cli serve`.
+type Schedule struct {
+	Name     string `mapstructure:"name"`
+	Cron     string `mapstructure:"cron"`
+	Question string `mapstructure:"question"`
+	Repo     string `mapstructure:"repo"`
+	Webhook  string `mapstructure:"webhook"`
+}
+
+// Serve configures the HTTP API of `fi-cli serve`. Each user authenticates
+// with the bearer token held in the TokenEnv environment variable and may
+// only run against repositories under AllowedPaths.
+type Serve struct {
+	Listen string      `mapstructure:"listen"`
+	Users  []ServeUser `mapstructure:"users"`
+}
+
+// ServeUser is one API caller of `fi-cli serve`.
+type ServeUser struct {
+	Name         string   `mapstructure:"name"`
+	TokenEnv     string   `mapstructure:"token_env"`
+	AllowedPaths []string `mapstructure:"allowed_paths"`
+}
+
+// serveUserName keeps user names usable as directory names.
+var serveUserName = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]*$`)
+
+// Notification sink types.
+const (
+	NotifyDesktop = "desktop"
+	NotifyWebhook = "webhook"
+	NotifySlack   = "slack"
+	NotifyMatrix  = "matrix"
+	NotifyEmail   = "email"
+)
+
+// NotifySink is a target notified when a run finishes. URL is the webhook
+// URL or the Matrix homeserver; Channel is a Slack channel and Room a Matrix
+// room ID. Email sinks use SMTPHost (host:port), From, To, and Username.
+// Secrets come from the environment, never the config file. OnlyFailures and
+// MinDuration filter which runs notify.
+type NotifySink struct {
+	Type         string        `mapstructure:"type"`
+	URL          string        `mapstructure:"url"`
+	Channel      string        `mapstructure:"channel"`
+	Room         string        `mapstructure:"room"`
+	SMTPHost     string        `mapstructure:"smtp_host"`
+	From         string        `mapstructure:"from"`
+	To           []string      `mapstructure:"to"`
+	Username     string        `mapstructure:"username"`
+	OnlyFailures bool          `mapstructure:"only_failures"`
+	MinDuration  time.Duration `mapstructure:"-"`
+}
+
+// ToolHook runs Command around calls to Tools, or to every tool when Tools
+// is empty.
+type ToolHook struct {
+	Tools   []string `mapstructure:"tools"`
+	Command string   `mapstructure:"command"`
+}
+
+// Hooks are user commands run around tool calls and runs. OnRunStart and
+// OnRunEnd read the RunResult as JSON. Timeout bounds each command.
+type Hooks struct {
+	PreTool    []ToolHook    `mapstructure:"pre_tool"`
+	PostTool   []ToolHook    `mapstructure:"post_tool"`
+	OnRunStart string        `mapstructure:"on_run_start"`
+	OnRunEnd   string        `mapstructure:"on_run_end"`
+	Timeout    time.Duration `mapstructure:"-"`
+}
+
+type rawHooks struct {
+	Hooks   `mapstructure:",squash"`
+	Timeout string `mapstructure:"timeout"`
+}
+
+// rawNotifySink reads min_duration as a string, like the other durations.
+type rawNotifySink struct {
+	NotifySink  `mapstructure:",squash"`
+	MinDuration string `mapstructure:"min_duration"`
 }
 
 // Config holds runtime configuration values.
@@ -46,6 +256,9 @@ type Config struct {
 	Repo              string
 	APIKey            string
 	Timeout           time.Duration
+	RequestTimeout    time.Duration
+	IdleTimeout       time.Duration
+	MaxAnswerTokens   int
 	UnsafeShell       bool
 	ShellAllowlist    []string
 	NoWeb             bool
@@ -54,47 +267,141 @@ type Config struct {
 	ShowTools         bool
 	NoTools           bool
 	ResponseMode      string
+	AnswerLanguage    string
+	Verbosity         string
+	SelfAssess        bool
 	Quiet             bool
 	JSON              bool
 	Verbose           bool
+	VerboseLevel      int
 	LogFile           string
+	Emit              []string
+	JSONStream        string
 	HistoryLines      int
 	NoHistory         bool
+	HistorySources    []string
+	HistoryExclude    []string
+	CapturePane       bool
+	NoSession         bool
+	AnswerCache       bool
+	FastPath          bool
+	NoConventions     bool
+	NoMemory          bool
+	Pipeline          bool
+	KeepWorkspace     bool
+	Attribution       bool
 	OutputFormat      string
 	PersistRuns       bool
+	QuestionHistory   bool
+	FollowUp          time.Duration
+	SimilarThreshold  float64
+	NoLock            bool
 	OpenRouterBaseURL string
 	HTTPReferer       string
 	Title             string
 	ToolLimits        ToolLimits
+	Tools             ToolSelection
+	Pricing           Pricing
+	Porcelain         bool
+	HTML              bool
+	ExtractCode       string
+	SaveSnippets      string
+	InjectionGuard    InjectionGuard
+	Router            Router
+	Consensus         Consensus
+	Render            Render
+	Schedules         []Schedule
+	Serve             Serve
+	// User is the serve API user a run belongs to; empty for local runs.
+	User              string
+	Labels            map[string]string
+	IssueTracker      string
+	CodeSearch        string
+	PostSlack         string
+	SlackReportURL    string
+	Notify            []NotifySink
+	PolicyHook        string
+	PolicyHookTimeout time.Duration
+	Hooks             Hooks
+	Pprof             string
+	TraceOut          string
 }
 
 type rawConfig struct {
-	Model              string     `mapstructure:"model"`
-	MaxSteps           int        `mapstructure:"max_steps"`
-	Repo               string     `mapstructure:"repo"`
-	APIKey             string     `mapstructure:"api_key"`
-	Timeout            string     `mapstructure:"timeout"`
-	UnsafeShell        bool       `mapstructure:"unsafe_shell"`
-	UnsafeShellDefault bool       `mapstructure:"unsafe_shell_default"`
-	ShellAllowlist     []string   `mapstructure:"shell_allowlist"`
-	NoWeb              bool       `mapstructure:"no_web"`
-	NoPlan             bool       `mapstructure:"no_plan"`
-	ShowHeader         bool       `mapstructure:"show_header"`
-	ShowTools          bool       `mapstructure:"show_tools"`
-	NoTools            bool       `mapstructure:"no_tools"`
-	ResponseMode       string     `mapstructure:"response_mode"`
-	Quiet              bool       `mapstructure:"quiet"`
-	JSON               bool       `mapstructure:"json"`
-	Verbose            bool       `mapstructure:"verbose"`
-	LogFile            string     `mapstructure:"log_file"`
-	HistoryLines       int        `mapstructure:"history_lines"`
-	NoHistory          bool       `mapstructure:"no_history"`
-	OutputFormat       string     `mapstructure:"output_format"`
-	PersistRuns        bool       `mapstructure:"persist_runs"`
-	OpenRouterBaseURL  string     `mapstructure:"openrouter_base_url"`
-	HTTPReferer        string     `mapstructure:"http_referer"`
-	Title              string     `mapstructure:"title"`
-	ToolLimits         ToolLimits `mapstructure:"tool_limits"`
+	Model              string            `mapstructure:"model"`
+	MaxSteps           int               `mapstructure:"max_steps"`
+	Repo               string            `mapstructure:"repo"`
+	APIKey             string            `mapstructure:"api_key"`
+	Timeout            string            `mapstructure:"timeout"`
+	RequestTimeout     string            `mapstructure:"request_timeout"`
+	IdleTimeout        string            `mapstructure:"idle_timeout"`
+	MaxAnswerTokens    int               `mapstructure:"max_answer_tokens"`
+	UnsafeShell        bool              `mapstructure:"unsafe_shell"`
+	UnsafeShellDefault bool              `mapstructure:"unsafe_shell_default"`
+	ShellAllowlist     []string          `mapstructure:"shell_allowlist"`
+	NoWeb              bool              `mapstructure:"no_web"`
+	NoPlan             bool              `mapstructure:"no_plan"`
+	ShowHeader         bool              `mapstructure:"show_header"`
+	ShowTools          bool              `mapstructure:"show_tools"`
+	NoTools            bool              `mapstructure:"no_tools"`
+	ResponseMode       string            `mapstructure:"response_mode"`
+	AnswerLanguage     string            `mapstructure:"answer_language"`
+	Verbosity          string            `mapstructure:"verbosity"`
+	SelfAssess         bool              `mapstructure:"self_assess"`
+	Quiet              bool              `mapstructure:"quiet"`
+	JSON               bool              `mapstructure:"json"`
+	Verbose            bool              `mapstructure:"verbose"`
+	LogFile            string            `mapstructure:"log_file"`
+	Emit               []string          `mapstructure:"emit"`
+	JSONStream         string            `mapstructure:"json_stream"`
+	HistoryLines       int               `mapstructure:"history_lines"`
+	NoHistory          bool              `mapstructure:"no_history"`
+	HistorySources     []string          `mapstructure:"history_sources"`
+	HistoryExclude     []string          `mapstructure:"history_exclude"`
+	CapturePane        bool              `mapstructure:"capture_pane"`
+	NoSession          bool              `mapstructure:"no_session"`
+	AnswerCache        bool              `mapstructure:"answer_cache"`
+	NoCache            bool              `mapstructure:"no_cache"`
+	FastPath           bool              `mapstructure:"fast_path"`
+	NoFastPath         bool              `mapstructure:"no_fast_path"`
+	NoConventions      bool              `mapstructure:"no_conventions"`
+	NoMemory           bool              `mapstructure:"no_memory"`
+	Pipeline           bool              `mapstructure:"pipeline"`
+	KeepWorkspace      bool              `mapstructure:"keep_workspace"`
+	Attribution        bool              `mapstructure:"attribution"`
+	OutputFormat       string            `mapstructure:"output_format"`
+	PersistRuns        bool              `mapstructure:"persist_runs"`
+	QuestionHistory    bool              `mapstructure:"question_history"`
+	FollowUp           string            `mapstructure:"follow_up"`
+	SimilarThreshold   float64           `mapstructure:"similar_threshold"`
+	NoLock             bool              `mapstructure:"no_lock"`
+	OpenRouterBaseURL  string            `mapstructure:"openrouter_base_url"`
+	HTTPReferer        string            `mapstructure:"http_referer"`
+	Title              string            `mapstructure:"title"`
+	ToolLimits         ToolLimits        `mapstructure:"tool_limits"`
+	Tools              ToolSelection     `mapstructure:"tools"`
+	Pricing            Pricing           `mapstructure:"pricing"`
+	Porcelain          bool              `mapstructure:"porcelain"`
+	HTML               bool              `mapstructure:"html"`
+	ExtractCode        string            `mapstructure:"extract_code"`
+	SaveSnippets       string            `mapstructure:"save_snippets"`
+	InjectionGuard     InjectionGuard    `mapstructure:"injection_guard"`
+	Router             Router            `mapstructure:"router"`
+	Consensus          Consensus         `mapstructure:"consensus"`
+	Render             Render            `mapstructure:"render"`
+	Schedules          []Schedule        `mapstructure:"schedules"`
+	Serve              Serve             `mapstructure:"serve"`
+	Labels             map[string]string `mapstructure:"labels"`
+	IssueTracker       string            `mapstructure:"issue_tracker"`
+	CodeSearch         string            `mapstructure:"code_search"`
+	PostSlack          string            `mapstructure:"post_slack"`
+	SlackReportURL     string            `mapstructure:"slack_report_url"`
+	Notify             []rawNotifySink   `mapstructure:"notify"`
+	PolicyHook         string            `mapstructure:"policy_hook"`
+	PolicyHookTimeout  string            `mapstructure:"policy_hook_timeout"`
+	Hooks              rawHooks          `mapstructure:"hooks"`
+	Pprof              string            `mapstructure:"pprof"`
+	TraceOut           string            `mapstructure:"trace_out"`
 }
 
 // Load resolves configuration from defaults, config files, env, and flags.
@@ -107,6 +414,9 @@ func Load(cmd *cobra.Command) (Config, error) {
 	v.SetDefault("model", DefaultModel)
 	v.SetDefault("max_steps", DefaultMaxSteps)
 	v.SetDefault("timeout", DefaultTimeout.String())
+	v.SetDefault("request_timeout", DefaultReqTimeout.String())
+	v.SetDefault("idle_timeout", DefaultIdleTimeout.String())
+	v.SetDefault("max_answer_tokens", DefaultMaxAnswerTokens)
 	v.SetDefault("repo", ".")
 	v.SetDefault("api_key", "")
 	v.SetDefault("unsafe_shell", false)
@@ -118,14 +428,40 @@ func Load(cmd *cobra.Command) (Config, error) {
 	v.SetDefault("show_tools", true)
 	v.SetDefault("no_tools", false)
 	v.SetDefault("response_mode", DefaultResponseMode)
+	v.SetDefault("answer_language", "")
+	v.SetDefault("verbosity", VerbosityNormal)
+	v.SetDefault("self_assess", false)
 	v.SetDefault("quiet", false)
 	v.SetDefault("json", false)
 	v.SetDefault("verbose", false)
 	v.SetDefault("log_file", "")
+	v.SetDefault("emit", []string{})
+	v.SetDefault("json_stream", "")
 	v.SetDefault("history_lines", 50)
 	v.SetDefault("no_history", false)
-	v.SetDefault("output_format", "text")
+	v.SetDefault("history_sources", []string{})
+	v.SetDefault("history_exclude", []string{})
+	v.SetDefault("capture_pane", false)
+	v.SetDefault("no_session", false)
+	v.SetDefault("answer_cache", false)
+	v.SetDefault("no_cache", false)
+	v.SetDefault("fast_path", true)
+	v.SetDefault("no_fast_path", false)
+	v.SetDefault("no_conventions", false)
+	v.SetDefault("no_memory", false)
+	v.SetDefault("pipeline", false)
+	v.SetDefault("keep_workspace", false)
+	v.SetDefault("attribution", false)
+	v.SetDefault("porcelain", false)
+	v.SetDefault("html", false)
+	v.SetDefault("extract_code", "")
+	v.SetDefault("save_snippets", "")
+	v.SetDefault("output_format", OutputText)
 	v.SetDefault("persist_runs", false)
+	v.SetDefault("question_history", true)
+	v.SetDefault("follow_up", "")
+	v.SetDefault("similar_threshold", DefaultSimilarThreshold)
+	v.SetDefault("no_lock", false)
 	v.SetDefault("openrouter_base_url", DefaultBaseURL)
 	v.SetDefault("tool_limits.grep_max_results", DefaultGrepLines)
 	v.SetDefault("tool_limits.grep_max_bytes", DefaultGrepBytes)
@@ -134,14 +470,43 @@ func Load(cmd *cobra.Command) (Config, error) {
 	v.SetDefault("tool_limits.grep_max_calls", 30)
 	v.SetDefault("tool_limits.shell_max_calls", 30)
 	v.SetDefault("tool_limits.web_max_calls", 30)
+	v.SetDefault("tool_limits.search_max_calls", DefaultSearchMaxCalls)
+	v.SetDefault("tool_limits.inspect_max_calls", DefaultInspectMaxCalls)
+	v.SetDefault("tool_limits.ask_user_max_calls", 1)
 	v.SetDefault("tool_limits.context_max_bytes", DefaultMaxContext)
 	v.SetDefault("tool_limits.max_file_bytes", DefaultMaxFileSize)
+	v.SetDefault("tool_limits.pane_max_bytes", DefaultPaneBytes)
+	v.SetDefault("tool_limits.list_max_entries", DefaultListEntries)
+	v.SetDefault("tools.enabled", []string{})
+	v.SetDefault("tools.disabled", []string{})
+	v.SetDefault("injection_guard.mode", "flag")
+	v.SetDefault("injection_guard.classifier", false)
+	v.SetDefault("router.mode", RouteAuto)
+	v.SetDefault("router.classifier", false)
+	v.SetDefault("consensus.answers", 0)
+	v.SetDefault("consensus.models", []string{})
+	v.SetDefault("consensus.judge_model", "")
+	v.SetDefault("render.preview_lines", DefaultPreviewLines)
+	v.SetDefault("render.preview_bytes", DefaultPreviewBytes)
+	v.SetDefault("issue_tracker", "")
+	v.SetDefault("code_search", "")
+	v.SetDefault("post_slack", "")
+	v.SetDefault("slack_report_url", "")
+	v.SetDefault("policy_hook", "")
+	v.SetDefault("policy_hook_timeout", "")
+	v.SetDefault("hooks.timeout", "")
+	v.SetDefault("pprof", "")
+	v.SetDefault("trace_out", "")
 
 	if cmd != nil {
 		_ = v.BindPFlag("model", cmd.Flags().Lookup("model"))
 		_ = v.BindPFlag("max_steps", cmd.Flags().Lookup("max-steps"))
 		_ = v.BindPFlag("repo", cmd.Flags().Lookup("repo"))
 		_ = v.BindPFlag("timeout", cmd.Flags().Lookup("timeout"))
+		_ = v.BindPFlag("request_timeout", cmd.Flags().Lookup("request-timeout"))
+		_ = v.BindPFlag("idle_timeout", cmd.Flags().Lookup("idle-timeout"))
+		_ = v.BindPFlag("follow_up", cmd.Flags().Lookup("follow-up"))
+		_ = v.BindPFlag("max_answer_tokens", cmd.Flags().Lookup("max-answer-tokens"))
 		_ = v.BindPFlag("unsafe_shell", cmd.Flags().Lookup("unsafe-shell"))
 		_ = v.BindPFlag("no_web", cmd.Flags().Lookup("no-web"))
 		_ = v.BindPFlag("no_plan", cmd.Flags().Lookup("no-plan"))
@@ -149,13 +514,41 @@ func Load(cmd *cobra.Command) (Config, error) {
 		_ = v.BindPFlag("show_tools", cmd.Flags().Lookup("show-tools"))
 		_ = v.BindPFlag("no_tools", cmd.Flags().Lookup("no-tools"))
 		_ = v.BindPFlag("response_mode", cmd.Flags().Lookup("mode"))
+		_ = v.BindPFlag("answer_language", cmd.Flags().Lookup("lang"))
+		_ = v.BindPFlag("self_assess", cmd.Flags().Lookup("assess"))
 		_ = v.BindPFlag("quiet", cmd.Flags().Lookup("quiet"))
 		_ = v.BindPFlag("json", cmd.Flags().Lookup("json"))
-		_ = v.BindPFlag("verbose", cmd.Flags().Lookup("verbose"))
+		_ = v.BindPFlag("output_format", cmd.Flags().Lookup("format"))
 		_ = v.BindPFlag("log_file", cmd.Flags().Lookup("log-file"))
+		_ = v.BindPFlag("emit", cmd.Flags().Lookup("emit"))
+		_ = v.BindPFlag("tools.enabled", cmd.Flags().Lookup("tools"))
+		_ = v.BindPFlag("json_stream", cmd.Flags().Lookup("json-stream"))
 		_ = v.BindPFlag("history_lines", cmd.Flags().Lookup("history-lines"))
 		_ = v.BindPFlag("no_history", cmd.Flags().Lookup("no-history"))
+		_ = v.BindPFlag("capture_pane", cmd.Flags().Lookup("capture-pane"))
+		_ = v.BindPFlag("no_session", cmd.Flags().Lookup("no-session"))
+		_ = v.BindPFlag("answer_cache", cmd.Flags().Lookup("cached"))
+		_ = v.BindPFlag("no_cache", cmd.Flags().Lookup("no-cache"))
+		_ = v.BindPFlag("no_fast_path", cmd.Flags().Lookup("no-fast-path"))
+		_ = v.BindPFlag("no_conventions", cmd.Flags().Lookup("no-conventions"))
+		_ = v.BindPFlag("no_memory", cmd.Flags().Lookup("no-memory"))
+		_ = v.BindPFlag("router.mode", cmd.Flags().Lookup("route"))
+		_ = v.BindPFlag("consensus.answers", cmd.Flags().Lookup("consensus"))
+		_ = v.BindPFlag("consensus.models", cmd.Flags().Lookup("consensus-models"))
+		_ = v.BindPFlag("consensus.judge_model", cmd.Flags().Lookup("judge-model"))
+		_ = v.BindPFlag("pipeline", cmd.Flags().Lookup("pipeline"))
+		_ = v.BindPFlag("keep_workspace", cmd.Flags().Lookup("keep-workspace"))
+		_ = v.BindPFlag("attribution", cmd.Flags().Lookup("attribution"))
+		_ = v.BindPFlag("porcelain", cmd.Flags().Lookup("porcelain"))
+		_ = v.BindPFlag("html", cmd.Flags().Lookup("html"))
+		_ = v.BindPFlag("extract_code", cmd.Flags().Lookup("extract-code"))
+		_ = v.BindPFlag("save_snippets", cmd.Flags().Lookup("save-snippets"))
+		_ = v.BindPFlag("post_slack", cmd.Flags().Lookup("post-slack"))
 		_ = v.BindPFlag("shell_allowlist", cmd.Flags().Lookup("shell-allow"))
+		_ = v.BindPFlag("no_lock", cmd.Flags().Lookup("no-lock"))
+		_ = v.BindPFlag("pprof", cmd.Flags().Lookup("pprof"))
+		_ = v.BindPFlag("trace_out", cmd.Flags().Lookup("trace-out"))
+		_ = v.BindPFlag("serve.listen", cmd.Flags().Lookup("listen"))
 	}
 
 	if seconds := os.Getenv("FICLI_TIMEOUT_SECONDS"); seconds != "" {
@@ -167,6 +560,18 @@ func Load(cmd *cobra.Command) (Config, error) {
 	if baseURL := os.Getenv("FICLI_OPENROUTER_BASE_URL"); baseURL != "" {
 		v.Set("openrouter_base_url", baseURL)
 	}
+	if sources := os.Getenv("FICLI_HISTORY_SOURCES"); sources != "" {
+		v.Set("history_sources", splitCSV(sources))
+	}
+	if exclude := os.Getenv("FICLI_HISTORY_EXCLUDE"); exclude != "" {
+		v.Set("history_exclude", splitCSV(exclude))
+	}
+	if enabled := os.Getenv("FICLI_TOOLS"); enabled != "" {
+		v.Set("tools.enabled", splitCSV(enabled))
+	}
+	if models := os.Getenv("FICLI_CONSENSUS_MODELS"); models != "" {
+		v.Set("consensus.models", splitCSV(models))
+	}
 	if allowlist := os.Getenv("FICLI_SHELL_ALLOWLIST"); allowlist != "" {
 		v.Set("shell_allowlist", splitCSV(allowlist))
 	}
@@ -187,13 +592,25 @@ func Load(cmd *cobra.Command) (Config, error) {
 		return Config{}, err
 	}
 
-	timeout := DefaultTimeout
-	if raw.Timeout != "" {
-		parsed, err := time.ParseDuration(raw.Timeout)
-		if err != nil {
-			return Config{}, fmt.Errorf("invalid timeout duration: %w", err)
-		}
-		timeout = parsed
+	timeout, err := parseDuration("timeout", raw.Timeout, DefaultTimeout)
+	if err != nil {
+		return Config{}, err
+	}
+	requestTimeout, err := parseDuration("request_timeout", raw.RequestTimeout, DefaultReqTimeout)
+	if err != nil {
+		return Config{}, err
+	}
+	idleTimeout, err := parseDuration("idle_timeout", raw.IdleTimeout, DefaultIdleTimeout)
+	if err != nil {
+		return Config{}, err
+	}
+	followUp, err := parseDuration("follow_up", raw.FollowUp, 0)
+	if err != nil {
+		return Config{}, err
+	}
+
+	if raw.SimilarThreshold < 0 || raw.SimilarThreshold > 1 {
+		return Config{}, fmt.Errorf("invalid similar_threshold %v (expected 0 to turn it off, or up to 1)", raw.SimilarThreshold)
 	}
 
 	unsafeShell := raw.UnsafeShell
@@ -210,6 +627,39 @@ func Load(cmd *cobra.Command) (Config, error) {
 		}
 	}
 
+	verbosity := normalizeVerbosity(raw.Verbosity)
+	if cmd != nil && cmd.Flags().Changed("brief") && cmd.Flags().Lookup("brief").Value.String() == "true" {
+		verbosity = VerbosityBrief
+	}
+	if cmd != nil && cmd.Flags().Changed("detailed") && cmd.Flags().Lookup("detailed").Value.String() == "true" {
+		verbosity = VerbosityDetailed
+	}
+
+	// --label pairs add to and override the labels set in config
+	labels := map[string]string{}
+	for key, value := range raw.Labels {
+		if key = strings.TrimSpace(key); key != "" {
+			labels[key] = strings.TrimSpace(value)
+		}
+	}
+	if cmd != nil && cmd.Flags().Changed("label") {
+		pairs, _ := cmd.Flags().GetStringArray("label")
+		flagged, err := ParseLabels(pairs)
+		if err != nil {
+			return Config{}, err
+		}
+		maps.Copy(labels, flagged)
+	}
+
+	// verbose is a count on the root command (-vv) and a bool elsewhere
+	verboseLevel := 0
+	if raw.Verbose {
+		verboseLevel = 1
+	}
+	if cmd != nil && cmd.Flags().Changed("verbose") {
+		verboseLevel = verboseFlagLevel(cmd.Flags().Lookup("verbose").Value.String())
+	}
+
 	showTools := raw.ShowTools
 	if cmd != nil && cmd.Flags().Changed("show-tools") {
 		showTools = v.GetBool("show_tools")
@@ -218,19 +668,102 @@ func Load(cmd *cobra.Command) (Config, error) {
 		showTools = false
 	}
 
+	outputFormat := strings.ToLower(strings.TrimSpace(raw.OutputFormat))
+	switch outputFormat {
+	case "":
+		outputFormat = OutputText
+	case OutputText, OutputJSON, OutputTable, OutputTSV:
+	default:
+		return Config{}, fmt.Errorf("invalid output_format %q (expected text, json, table, or tsv)", raw.OutputFormat)
+	}
 	jsonOutput := raw.JSON
 	if cmd != nil && cmd.Flags().Changed("json") {
 		jsonOutput = v.GetBool("json")
-	} else if strings.EqualFold(raw.OutputFormat, "json") {
+	} else if outputFormat == OutputJSON {
 		jsonOutput = true
 	}
 
+	jsonStream := strings.ToLower(strings.TrimSpace(raw.JSONStream))
+	switch jsonStream {
+	case "", JSONStreamTools, JSONStreamFull:
+	default:
+		return Config{}, fmt.Errorf("invalid json_stream %q (expected %q or %q)", raw.JSONStream, JSONStreamTools, JSONStreamFull)
+	}
+
+	issueTracker := strings.ToLower(strings.TrimSpace(raw.IssueTracker))
+	switch issueTracker {
+	case "", "jira", "linear":
+	default:
+		return Config{}, fmt.Errorf("invalid issue_tracker %q (expected jira or linear)", raw.IssueTracker)
+	}
+	codeSearch := strings.ToLower(strings.TrimSpace(raw.CodeSearch))
+	switch codeSearch {
+	case "", "github", "grep.app":
+	default:
+		return Config{}, fmt.Errorf("invalid code_search %q (expected github or grep.app)", raw.CodeSearch)
+	}
+
+	notify, err := normalizeNotify(raw.Notify)
+	if err != nil {
+		return Config{}, err
+	}
+	serve, err := normalizeServe(raw.Serve)
+	if err != nil {
+		return Config{}, err
+	}
+	policyHookTimeout, err := parseDuration("policy_hook_timeout", raw.PolicyHookTimeout, 0)
+	if err != nil {
+		return Config{}, err
+	}
+	hooks, err := normalizeHooks(raw.Hooks)
+	if err != nil {
+		return Config{}, err
+	}
+
+	guardMode := strings.ToLower(strings.TrimSpace(raw.InjectionGuard.Mode))
+	switch guardMode {
+	case "":
+		guardMode = "flag"
+	case "off", "flag", "neutralize":
+	default:
+		return Config{}, fmt.Errorf("invalid injection_guard.mode %q (expected off, flag, or neutralize)", raw.InjectionGuard.Mode)
+	}
+
+	consensus, err := normalizeConsensus(raw.Consensus)
+	if err != nil {
+		return Config{}, err
+	}
+	routerMode := strings.ToLower(strings.TrimSpace(strings.ReplaceAll(raw.Router.Mode, "-", "_")))
+	if routerMode == "" {
+		routerMode = RouteAuto
+	}
+	if routerMode != RouteAuto && routerMode != RouteOff && !slices.Contains(Routes, routerMode) {
+		return Config{}, fmt.Errorf("invalid router.mode %q (expected auto, off, or one of %s)", raw.Router.Mode, strings.Join(Routes, ", "))
+	}
+
+	historySources := normalizeAllowlist(raw.HistorySources)
+	for i, source := range historySources {
+		source = strings.ToLower(source)
+		switch source {
+		case "zsh", "bash", "fish", "atuin":
+		default:
+			return Config{}, fmt.Errorf("invalid history source %q (expected zsh, bash, fish, or atuin)", source)
+		}
+		historySources[i] = source
+	}
+	if raw.Pricing.InputPerMTok < 0 || raw.Pricing.OutputPerMTok < 0 {
+		return Config{}, fmt.Errorf("invalid pricing: prices must not be negative")
+	}
+
 	cfg := Config{
 		Model:             raw.Model,
 		MaxSteps:          raw.MaxSteps,
 		Repo:              raw.Repo,
 		APIKey:            strings.TrimSpace(raw.APIKey),
 		Timeout:           timeout,
+		RequestTimeout:    requestTimeout,
+		IdleTimeout:       idleTimeout,
+		MaxAnswerTokens:   raw.MaxAnswerTokens,
 		UnsafeShell:       unsafeShell,
 		ShellAllowlist:    normalizeAllowlist(raw.ShellAllowlist),
 		NoWeb:             raw.NoWeb,
@@ -239,18 +772,62 @@ func Load(cmd *cobra.Command) (Config, error) {
 		ShowTools:         showTools,
 		NoTools:           raw.NoTools,
 		ResponseMode:      normalizeResponseMode(raw.ResponseMode),
+		AnswerLanguage:    strings.TrimSpace(raw.AnswerLanguage),
+		Verbosity:         verbosity,
+		SelfAssess:        raw.SelfAssess,
 		Quiet:             raw.Quiet,
 		JSON:              jsonOutput,
-		Verbose:           raw.Verbose,
+		Verbose:           verboseLevel > 0,
+		VerboseLevel:      verboseLevel,
 		LogFile:           raw.LogFile,
+		Emit:              normalizeAllowlist(raw.Emit),
+		JSONStream:        jsonStream,
 		HistoryLines:      raw.HistoryLines,
 		NoHistory:         raw.NoHistory,
-		OutputFormat:      raw.OutputFormat,
+		HistorySources:    historySources,
+		HistoryExclude:    normalizeAllowlist(raw.HistoryExclude),
+		CapturePane:       raw.CapturePane,
+		NoSession:         raw.NoSession,
+		AnswerCache:       raw.AnswerCache && !raw.NoCache,
+		FastPath:          raw.FastPath && !raw.NoFastPath,
+		NoConventions:     raw.NoConventions,
+		NoMemory:          raw.NoMemory,
+		Pipeline:          raw.Pipeline,
+		KeepWorkspace:     raw.KeepWorkspace,
+		Attribution:       raw.Attribution,
+		OutputFormat:      outputFormat,
 		PersistRuns:       raw.PersistRuns,
+		QuestionHistory:   raw.QuestionHistory,
+		FollowUp:          followUp,
+		SimilarThreshold:  raw.SimilarThreshold,
+		NoLock:            raw.NoLock,
 		OpenRouterBaseURL: raw.OpenRouterBaseURL,
 		HTTPReferer:       raw.HTTPReferer,
 		Title:             raw.Title,
 		ToolLimits:        raw.ToolLimits,
+		InjectionGuard:    InjectionGuard{Mode: guardMode, Classifier: raw.InjectionGuard.Classifier, ClassifierModel: strings.TrimSpace(raw.InjectionGuard.ClassifierModel)},
+		Router:            Router{Mode: routerMode, Classifier: raw.Router.Classifier, ClassifierModel: strings.TrimSpace(raw.Router.ClassifierModel)},
+		Consensus:         consensus,
+		Render:            raw.Render,
+		Tools:             ToolSelection{Enabled: normalizeAllowlist(raw.Tools.Enabled), Disabled: normalizeAllowlist(raw.Tools.Disabled)},
+		Pricing:           raw.Pricing,
+		Porcelain:         raw.Porcelain,
+		HTML:              raw.HTML,
+		ExtractCode:       strings.TrimSpace(raw.ExtractCode),
+		SaveSnippets:      strings.TrimSpace(raw.SaveSnippets),
+		Schedules:         raw.Schedules,
+		Serve:             serve,
+		Labels:            labels,
+		IssueTracker:      issueTracker,
+		CodeSearch:        codeSearch,
+		PostSlack:         strings.TrimSpace(raw.PostSlack),
+		SlackReportURL:    strings.TrimSpace(raw.SlackReportURL),
+		Notify:            notify,
+		PolicyHook:        strings.TrimSpace(raw.PolicyHook),
+		PolicyHookTimeout: policyHookTimeout,
+		Hooks:             hooks,
+		Pprof:             strings.TrimSpace(raw.Pprof),
+		TraceOut:          strings.TrimSpace(raw.TraceOut),
 	}
 
 	if cfg.Model == "" {
@@ -262,6 +839,15 @@ func Load(cmd *cobra.Command) (Config, error) {
 	if cfg.Timeout <= 0 {
 		cfg.Timeout = DefaultTimeout
 	}
+	if cfg.RequestTimeout <= 0 {
+		cfg.RequestTimeout = DefaultReqTimeout
+	}
+	if cfg.IdleTimeout <= 0 {
+		cfg.IdleTimeout = DefaultIdleTimeout
+	}
+	if cfg.MaxAnswerTokens <= 0 {
+		cfg.MaxAnswerTokens = DefaultMaxAnswerTokens
+	}
 	if cfg.OpenRouterBaseURL == "" {
 		cfg.OpenRouterBaseURL = DefaultBaseURL
 	}
@@ -272,6 +858,13 @@ func Load(cmd *cobra.Command) (Config, error) {
 		cfg.ResponseMode = DefaultResponseMode
 	}
 
+	if cfg.Render.PreviewLines <= 0 {
+		cfg.Render.PreviewLines = DefaultPreviewLines
+	}
+	if cfg.Render.PreviewBytes <= 0 {
+		cfg.Render.PreviewBytes = DefaultPreviewBytes
+	}
+
 	if cfg.ToolLimits.ContextMaxBytes <= 0 {
 		cfg.ToolLimits.ContextMaxBytes = DefaultMaxContext
 	}
@@ -290,6 +883,12 @@ func Load(cmd *cobra.Command) (Config, error) {
 	if cfg.ToolLimits.MaxFileBytes <= 0 {
 		cfg.ToolLimits.MaxFileBytes = DefaultMaxFileSize
 	}
+	if cfg.ToolLimits.PaneMaxBytes <= 0 {
+		cfg.ToolLimits.PaneMaxBytes = DefaultPaneBytes
+	}
+	if cfg.ToolLimits.ListMaxEntries <= 0 {
+		cfg.ToolLimits.ListMaxEntries = DefaultListEntries
+	}
 	if cfg.ToolLimits.GrepMaxCalls <= 0 {
 		cfg.ToolLimits.GrepMaxCalls = 30
 	}
@@ -299,6 +898,15 @@ func Load(cmd *cobra.Command) (Config, error) {
 	if cfg.ToolLimits.WebMaxCalls <= 0 {
 		cfg.ToolLimits.WebMaxCalls = 30
 	}
+	if cfg.ToolLimits.SearchMaxCalls <= 0 {
+		cfg.ToolLimits.SearchMaxCalls = DefaultSearchMaxCalls
+	}
+	if cfg.ToolLimits.InspectMaxCalls <= 0 {
+		cfg.ToolLimits.InspectMaxCalls = DefaultInspectMaxCalls
+	}
+	if cfg.ToolLimits.AskUserMaxCalls <= 0 {
+		cfg.ToolLimits.AskUserMaxCalls = 1
+	}
 
 	return cfg, nil
 }
@@ -373,6 +981,172 @@ func PreferredConfigPath() string {
 	return filepath.Join(home, ".config", "fi.ashref.tn", "config.yaml")
 }
 
+// normalizeServe checks that every API user has a unique name, a token
+// variable, and at least one allowed path.
+func normalizeServe(raw Serve) (Serve, error) {
+	out := Serve{Listen: strings.TrimSpace(raw.Listen), Users: make([]ServeUser, 0, len(raw.Users))}
+	seen := map[string]bool{}
+	for i, user := range raw.Users {
+		user.Name = strings.TrimSpace(user.Name)
+		user.TokenEnv = strings.TrimSpace(user.TokenEnv)
+		switch {
+		case !serveUserName.MatchString(user.Name):
+			return Serve{}, fmt.Errorf("invalid serve.users[%d].name %q (expected letters, digits, '.', '_' or '-')", i, user.Name)
+		case seen[user.Name]:
+			return Serve{}, fmt.Errorf("invalid serve.users[%d]: duplicate name %q", i, user.Name)
+		case user.TokenEnv == "":
+			return Serve{}, fmt.Errorf("invalid serve.users[%d]: %s needs token_env", i, user.Name)
+		case len(user.AllowedPaths) == 0:
+			return Serve{}, fmt.Errorf("invalid serve.users[%d]: %s needs allowed_paths", i, user.Name)
+		}
+		seen[user.Name] = true
+		out.Users = append(out.Users, user)
+	}
+	if out.Listen != "" && len(out.Users) == 0 {
+		return Serve{}, errors.New("invalid serve: listen needs at least one entry in serve.users")
+	}
+	return out, nil
+}
+
+// normalizeNotify lowercases sink types and checks that each sink has the
+// fields its type needs.
+func normalizeNotify(raw []rawNotifySink) ([]NotifySink, error) {
+	out := make([]NotifySink, 0, len(raw))
+	for i, entry := range raw {
+		sink := entry.NotifySink
+		minDuration, err := parseDuration(fmt.Sprintf("notify[%d].min_duration", i), entry.MinDuration, 0)
+		if err != nil {
+			return nil, err
+		}
+		sink.MinDuration = minDuration
+		sink.Type = strings.ToLower(strings.TrimSpace(sink.Type))
+		var missing string
+		switch sink.Type {
+		case NotifyDesktop:
+		case NotifyWebhook:
+			if sink.URL == "" {
+				missing = "url"
+			}
+		case NotifySlack:
+			if sink.Channel == "" {
+				missing = "channel"
+			}
+		case NotifyMatrix:
+			if sink.URL == "" {
+				missing = "url"
+			} else if sink.Room == "" {
+				missing = "room"
+			}
+		case NotifyEmail:
+			switch {
+			case sink.SMTPHost == "":
+				missing = "smtp_host"
+			case sink.From == "":
+				missing = "from"
+			case len(sink.To) == 0:
+				missing = "to"
+			}
+		default:
+			return nil, fmt.Errorf("invalid notify[%d].type %q (expected desktop, webhook, slack, matrix, or email)", i, sink.Type)
+		}
+		if missing != "" {
+			return nil, fmt.Errorf("invalid notify[%d]: %s sink needs %s", i, sink.Type, missing)
+		}
+		if sink.MinDuration < 0 {
+			return nil, fmt.Errorf("invalid notify[%d].min_duration: must not be negative", i)
+		}
+		out = append(out, sink)
+	}
+	return out, nil
+}
+
+// normalizeHooks parses the hook timeout and lowercases tool names. A hook
+// without a command is an error.
+func normalizeHooks(raw rawHooks) (Hooks, error) {
+	hooks := raw.Hooks
+	timeout, err := parseDuration("hooks.timeout", raw.Timeout, 0)
+	if err != nil {
+		return Hooks{}, err
+	}
+	hooks.Timeout = timeout
+	hooks.OnRunStart = strings.TrimSpace(hooks.OnRunStart)
+	hooks.OnRunEnd = strings.TrimSpace(hooks.OnRunEnd)
+	for _, stage := range []struct {
+		name string
+		list []ToolHook
+	}{{"pre_tool", hooks.PreTool}, {"post_tool", hooks.PostTool}} {
+		list, name := stage.list, stage.name
+		for i := range list {
+			list[i].Command = strings.TrimSpace(list[i].Command)
+			if list[i].Command == "" {
+				return Hooks{}, fmt.Errorf("invalid hooks.%s[%d]: command is required", name, i)
+			}
+			list[i].Tools = normalizeAllowlist(list[i].Tools)
+		}
+	}
+	return hooks, nil
+}
+
+// ParseLabels parses key=value run labels, as given to --label.
+func ParseLabels(pairs []string) (map[string]string, error) {
+	labels := make(map[string]string, len(pairs))
+	for _, pair := range pairs {
+		key, value, ok := strings.Cut(pair, "=")
+		key = strings.TrimSpace(key)
+		if !ok || key == "" {
+			return nil, fmt.Errorf("invalid label %q (expected key=value)", pair)
+		}
+		labels[key] = strings.TrimSpace(value)
+	}
+	return labels, nil
+}
+
+// verboseFlagLevel reads a --verbose flag value: a count from repeated -v,
+// or true/false from commands that take it as a bool.
+func verboseFlagLevel(value string) int {
+	if level, err := strconv.Atoi(value); err == nil {
+		return level
+	}
+	if enabled, _ := strconv.ParseBool(value); enabled {
+		return 1
+	}
+	return 0
+}
+
+// normalizeConsensus trims the model names and checks there are enough of
+// them for the answers asked for.
+func normalizeConsensus(raw Consensus) (Consensus, error) {
+	consensus := Consensus{Answers: raw.Answers, Judge: strings.TrimSpace(raw.Judge)}
+	if consensus.Answers < 0 {
+		return Consensus{}, fmt.Errorf("invalid consensus.answers %d (expected 0 to turn it off, or 2 or more)", raw.Answers)
+	}
+	for _, model := range raw.Models {
+		if model = strings.TrimSpace(model); model != "" {
+			consensus.Models = append(consensus.Models, model)
+		}
+	}
+	if consensus.Answers < 2 {
+		consensus.Answers = 0
+		return consensus, nil
+	}
+	if need := consensus.Answers - 1; len(consensus.Models) < need {
+		return Consensus{}, fmt.Errorf("consensus of %d answers needs %d consensus.models besides the run's model, got %d", consensus.Answers, need, len(consensus.Models))
+	}
+	consensus.Models = consensus.Models[:consensus.Answers-1]
+	return consensus, nil
+}
+
+func parseDuration(name string, value string, fallback time.Duration) (time.Duration, error) {
+	if value == "" {
+		return fallback, nil
+	}
+	parsed, err := time.ParseDuration(value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid %s duration: %w", name, err)
+	}
+	return parsed, nil
+}
+
 func splitCSV(input string) []string {
 	parts := strings.Split(input, ",")
 	out := make([]string, 0, len(parts))
@@ -417,6 +1191,17 @@ func normalizeResponseMode(mode string) string {
 	}
 }
 
+func normalizeVerbosity(value string) string {
+	switch strings.ToLower(strings.TrimSpace(value)) {
+	case VerbosityBrief:
+		return VerbosityBrief
+	case VerbosityDetailed:
+		return VerbosityDetailed
+	default:
+		return VerbosityNormal
+	}
+}
+
 func uniqStrings(values []string) []string {
 	seen := make(map[string]struct{}, len(values))
 	out := make([]string, 0, len(values))
@@ -1,6 +1,12 @@
 package config
 
-import "testing"
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
 
 func TestLoadDefaultsToolCallCaps(t *testing.T) {
 	t.Setenv("HOME", t.TempDir())
@@ -21,4 +27,165 @@ func TestLoadDefaultsToolCallCaps(t *testing.T) {
 	if cfg.ToolLimits.WebMaxCalls != 30 {
 		t.Fatalf("expected web max calls 30, got %d", cfg.ToolLimits.WebMaxCalls)
 	}
+	if cfg.ToolLimits.SearchMaxCalls != DefaultSearchMaxCalls || cfg.ToolLimits.InspectMaxCalls != DefaultInspectMaxCalls {
+		t.Fatalf("expected default category budgets, got %+v", cfg.ToolLimits)
+	}
+	if cfg.Render.PreviewLines != DefaultPreviewLines || cfg.Render.PreviewBytes != DefaultPreviewBytes || cfg.VerboseLevel != 0 {
+		t.Fatalf("expected default render settings, got %+v level %d", cfg.Render, cfg.VerboseLevel)
+	}
+	if cfg.MaxAnswerTokens != DefaultMaxAnswerTokens {
+		t.Fatalf("expected max answer tokens %d, got %d", DefaultMaxAnswerTokens, cfg.MaxAnswerTokens)
+	}
+	if cfg.SimilarThreshold != DefaultSimilarThreshold {
+		t.Fatalf("expected similar threshold %v, got %v", DefaultSimilarThreshold, cfg.SimilarThreshold)
+	}
+
+	t.Setenv("FICLI_SIMILAR_THRESHOLD", "1.5")
+	if _, err := Load(nil); err == nil || !strings.Contains(err.Error(), "similar_threshold") {
+		t.Fatalf("expected an out-of-range similar_threshold to be rejected, got %v", err)
+	}
+	t.Setenv("FICLI_SIMILAR_THRESHOLD", "")
+
+	t.Setenv("FICLI_CODE_SEARCH", "Grep.App")
+	if cfg, err := Load(nil); err != nil || cfg.CodeSearch != "grep.app" {
+		t.Fatalf("expected code_search grep.app, got %q (%v)", cfg.CodeSearch, err)
+	}
+	t.Setenv("FICLI_CODE_SEARCH", "sourcegraph")
+	if _, err := Load(nil); err == nil || !strings.Contains(err.Error(), "invalid code_search") {
+		t.Fatalf("expected an unknown code_search backend to be rejected, got %v", err)
+	}
+}
+
+func TestLoadSchedules(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("XDG_CONFIG_HOME", filepath.Join(home, ".config"))
+	dir := filepath.Join(home, ".config", "fi.ashref.tn")
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		t.Fatalf("mkdir failed: %v", err)
+	}
+	content := "schedules:\n  - name: nightly\n    cron: \"0 2 * * *\"\n    question: summarize CI\n    webhook: https://example.com/hook\n"
+	if err := os.WriteFile(filepath.Join(dir, "config.yaml"), []byte(content), 0o600); err != nil {
+		t.Fatalf("write failed: %v", err)
+	}
+
+	cfg, err := Load(nil)
+	if err != nil {
+		t.Fatalf("load failed: %v", err)
+	}
+	if len(cfg.Schedules) != 1 || cfg.Schedules[0].Cron != "0 2 * * *" || cfg.Schedules[0].Webhook == "" {
+		t.Fatalf("unexpected schedules: %+v", cfg.Schedules)
+	}
+}
+
+func TestLoadServeUsers(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("XDG_CONFIG_HOME", filepath.Join(home, ".config"))
+	dir := filepath.Join(home, ".config", "fi.ashref.tn")
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		t.Fatalf("mkdir failed: %v", err)
+	}
+	path := filepath.Join(dir, "config.yaml")
+	content := "serve:\n  listen: 127.0.0.1:7777\n  users:\n    - name: alice\n      token_env: FI_TOKEN_ALICE\n      allowed_paths: [~/work]\n"
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatalf("write failed: %v", err)
+	}
+	cfg, err := Load(nil)
+	if err != nil {
+		t.Fatalf("load failed: %v", err)
+	}
+	if cfg.Serve.Listen != "127.0.0.1:7777" || len(cfg.Serve.Users) != 1 || cfg.Serve.Users[0].TokenEnv != "FI_TOKEN_ALICE" || len(cfg.Serve.Users[0].AllowedPaths) != 1 {
+		t.Fatalf("unexpected serve config: %+v", cfg.Serve)
+	}
+
+	for content, want := range map[string]string{
+		"serve:\n  users:\n    - name: ../bob\n      token_env: T\n      allowed_paths: [/srv]\n":                                                                "invalid serve.users[0].name",
+		"serve:\n  users:\n    - name: bob\n      token_env: T\n      allowed_paths: [/srv]\n    - name: bob\n      token_env: U\n      allowed_paths: [/srv]\n": "duplicate name",
+		"serve:\n  users:\n    - name: bob\n      allowed_paths: [/srv]\n":                                                                                       "needs token_env",
+		"serve:\n  listen: :7777\n": "at least one",
+	} {
+		if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+			t.Fatalf("write failed: %v", err)
+		}
+		if _, err := Load(nil); err == nil || !strings.Contains(err.Error(), want) {
+			t.Fatalf("expected %q, got %v", want, err)
+		}
+	}
+}
+
+func TestLoadNotifySinks(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("XDG_CONFIG_HOME", filepath.Join(home, ".config"))
+	dir := filepath.Join(home, ".config", "fi.ashref.tn")
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		t.Fatalf("mkdir failed: %v", err)
+	}
+	path := filepath.Join(dir, "config.yaml")
+	content := "notify:\n  - type: Desktop\n    min_duration: 2m\n  - type: email\n    smtp_host: smtp.example.com:587\n    from: fi@example.com\n    to: [oncall@example.com]\n    only_failures: true\n"
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatalf("write failed: %v", err)
+	}
+
+	cfg, err := Load(nil)
+	if err != nil {
+		t.Fatalf("load failed: %v", err)
+	}
+	if len(cfg.Notify) != 2 || cfg.Notify[0].Type != NotifyDesktop || cfg.Notify[0].MinDuration != 2*time.Minute {
+		t.Fatalf("unexpected notify sinks: %+v", cfg.Notify)
+	}
+	if !cfg.Notify[1].OnlyFailures || len(cfg.Notify[1].To) != 1 {
+		t.Fatalf("unexpected email sink: %+v", cfg.Notify[1])
+	}
+
+	if err := os.WriteFile(path, []byte("notify:\n  - type: matrix\n    url: https://matrix.example.org\n"), 0o600); err != nil {
+		t.Fatalf("write failed: %v", err)
+	}
+	if _, err := Load(nil); err == nil || !strings.Contains(err.Error(), "matrix sink needs room") {
+		t.Fatalf("expected a missing room error, got %v", err)
+	}
+}
+
+func TestNormalizeConsensus(t *testing.T) {
+	got, err := normalizeConsensus(Consensus{Answers: 2, Models: []string{" a/one ", "", "b/two"}})
+	if err != nil || len(got.Models) != 1 || got.Models[0] != "a/one" {
+		t.Fatalf("expected the first model kept, got %+v (%v)", got, err)
+	}
+	if _, err := normalizeConsensus(Consensus{Answers: 3, Models: []string{"a/one"}}); err == nil || !strings.Contains(err.Error(), "needs 2 consensus.models") {
+		t.Fatalf("expected too few models to be rejected, got %v", err)
+	}
+	if got, err := normalizeConsensus(Consensus{Answers: 1}); err != nil || got.Answers != 0 {
+		t.Fatalf("expected one answer to turn consensus off, got %+v (%v)", got, err)
+	}
+}
+
+func TestParseLabels(t *testing.T) {
+	labels, err := ParseLabels([]string{"team=payments", " ticket = PAY-12 ", "note=a=b"})
+	if err != nil {
+		t.Fatalf("parse failed: %v", err)
+	}
+	if labels["team"] != "payments" || labels["ticket"] != "PAY-12" || labels["note"] != "a=b" {
+		t.Fatalf("unexpected labels: %v", labels)
+	}
+	for _, bad := range []string{"team", "=x"} {
+		if _, err := ParseLabels([]string{bad}); err == nil {
+			t.Fatalf("expected %q to be rejected", bad)
+		}
+	}
+}
+
+func TestToolSelectionAllows(t *testing.T) {
+	all := ToolSelection{}
+	if !all.Allows("grep") || !all.Allows("shell") {
+		t.Fatalf("expected empty selection to allow all tools")
+	}
+	noWeb := ToolSelection{Disabled: []string{"exa_search"}}
+	if noWeb.Allows("exa_search") || !noWeb.Allows("grep") {
+		t.Fatalf("expected disabled list to block only exa_search")
+	}
+	onlyGrep := ToolSelection{Enabled: []string{"grep", "shell"}, Disabled: []string{"shell"}}
+	if !onlyGrep.Allows("GREP") || onlyGrep.Allows("shell") || onlyGrep.Allows("exa_search") {
+		t.Fatalf("expected enabled list with disabled override")
+	}
 }
@@ -0,0 +1,40 @@
+package config
+
+import (
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// Keys lists the config file settings, with nested ones dotted
+// (tool_limits.grep_max_calls). Settings that hold lists of entries, such
+// as schedules, are listed by their own key only.
+func Keys() []string {
+	var keys []string
+	collectKeys(reflect.TypeOf(rawConfig{}), "", &keys)
+	sort.Strings(keys)
+	return keys
+}
+
+func collectKeys(t reflect.Type, prefix string, keys *[]string) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		tag := field.Tag.Get("mapstructure")
+		if tag == "-" {
+			continue
+		}
+		name, opts, _ := strings.Cut(tag, ",")
+		if opts == "squash" {
+			collectKeys(field.Type, prefix, keys)
+			continue
+		}
+		if name == "" {
+			continue
+		}
+		if field.Type.Kind() == reflect.Struct {
+			collectKeys(field.Type, prefix+name+".", keys)
+			continue
+		}
+		*keys = append(*keys, prefix+name)
+	}
+}
@@ -2,6 +2,10 @@ package events
 
 import "time"
 
+// SchemaVersion is the version of the event payloads below. It changes when
+// a field is removed or changes meaning, not when one is added.
+const SchemaVersion = 1
+
 // Type represents an emitted event type.
 type Type string
 
@@ -12,9 +16,14 @@ const (
 	ToolCallFinished Type = "ToolCallFinished"
 	ToolCallFailed   Type = "ToolCallFailed"
 	ModelDelta       Type = "ModelStreamingDelta"
+	StreamResumed    Type = "StreamResumed"
 	FinalAnswerReady Type = "FinalAnswerReady"
+	AssessmentReady  Type = "AssessmentReady"
 	RunFinished      Type = "RunFinished"
 	RunError         Type = "RunError"
+	Warning          Type = "Warning"
+	QuestionRouted   Type = "QuestionRouted"
+	StageCompleted   Type = "StageCompleted"
 )
 
 // Event is the common envelope for renderer events.
@@ -26,20 +35,46 @@ type Event struct {
 
 // RunStartedPayload is emitted at the beginning of a run.
 type RunStartedPayload struct {
-	Version   string    `json:"version"`
-	RepoRoot  string    `json:"repo_root"`
-	Model     string    `json:"model"`
-	RunID     string    `json:"run_id"`
-	StartedAt time.Time `json:"started_at"`
+	Version       string    `json:"version"`
+	SchemaVersion int       `json:"schema_version"`
+	RepoRoot      string    `json:"repo_root"`
+	Model         string    `json:"model"`
+	RunID         string    `json:"run_id"`
+	StartedAt     time.Time `json:"started_at"`
+}
+
+// QuestionRoutedPayload records how the question was classified and which
+// tools the run exposes as a result.
+type QuestionRoutedPayload struct {
+	Route  string   `json:"route"`
+	Source string   `json:"source"`
+	Tools  []string `json:"tools"`
+}
+
+// StagePayload summarizes a finished pipeline stage.
+type StagePayload struct {
+	Role      string `json:"role"`
+	Status    string `json:"status"`
+	StepsUsed int    `json:"steps_used"`
+	ToolCalls int    `json:"tool_calls"`
 }
 
-// PlanGeneratedPayload contains the model plan.
+// PlanGeneratedPayload contains the model plan. Plan holds the step titles;
+// Steps adds the tools each step expects to use.
 type PlanGeneratedPayload struct {
-	Plan []string `json:"plan"`
+	Plan  []string   `json:"plan"`
+	Steps []PlanStep `json:"steps,omitempty"`
+}
+
+// PlanStep is one step of a plan and the tools the model expects it to use.
+type PlanStep struct {
+	Title string   `json:"title"`
+	Tools []string `json:"tools,omitempty"`
 }
 
 // ToolCallStartedPayload marks tool call start.
 type ToolCallStartedPayload struct {
+	ID        string    `json:"id,omitempty"`
 	ToolName  string    `json:"tool_name"`
 	Input     any       `json:"input"`
 	StartedAt time.Time `json:"started_at"`
@@ -47,6 +82,7 @@ type ToolCallStartedPayload struct {
 
 // ToolCallFinishedPayload marks tool call end.
 type ToolCallFinishedPayload struct {
+	ID         string `json:"id,omitempty"`
 	ToolName   string `json:"tool_name"`
 	Status     string `json:"status"`
 	Output     any    `json:"output"`
@@ -55,6 +91,10 @@ type ToolCallFinishedPayload struct {
 	ByteCount  int    `json:"byte_count"`
 	Truncated  bool   `json:"truncated"`
 	DurationMs int64  `json:"duration_ms"`
+	// RepeatOf names the earlier call with the same tool and arguments, and
+	// Diff lists the result lines removed ("- ") and added ("+ ") since.
+	RepeatOf string   `json:"repeat_of,omitempty"`
+	Diff     []string `json:"diff,omitempty"`
 }
 
 // ModelDeltaPayload is streamed as tokens arrive.
@@ -62,18 +102,69 @@ type ModelDeltaPayload struct {
 	Delta string `json:"delta"`
 }
 
+// StreamResumedPayload records a retried final-answer stream.
+type StreamResumedPayload struct {
+	Attempt      int    `json:"attempt"`
+	PrintedBytes int    `json:"printed_bytes"`
+	Reason       string `json:"reason"`
+}
+
 // FinalAnswerPayload is emitted when final answer is ready.
 type FinalAnswerPayload struct {
-	Answer string `json:"answer"`
+	Answer    string     `json:"answer"`
+	Citations []Citation `json:"citations,omitempty"`
+}
+
+// Citation links a [T<n>] marker in the final answer to the tool call it cites.
+// ToolCall indexes the run's tool call records and is -1 when no call has the ID.
+type Citation struct {
+	ID       string `json:"id"`
+	ToolName string `json:"tool_name,omitempty"`
+	Input    any    `json:"input,omitempty"`
+	ToolCall int    `json:"tool_call_index"`
+}
+
+// AssessmentPayload carries the model's self-assessment of its final answer.
+type AssessmentPayload struct {
+	Confidence       float64  `json:"confidence"`
+	EvidenceCoverage float64  `json:"evidence_coverage"`
+	UnverifiedClaims []string `json:"unverified_claims"`
 }
 
 // RunFinishedPayload closes the run.
 type RunFinishedPayload struct {
-	Status     string    `json:"status"`
-	FinishedAt time.Time `json:"finished_at"`
+	Status           string         `json:"status"`
+	FinishedAt       time.Time      `json:"finished_at"`
+	StepsUsed        int            `json:"steps_used"`
+	ToolCalls        map[string]int `json:"tool_calls,omitempty"`
+	DurationMs       int64          `json:"duration_ms"`
+	PromptTokens     int            `json:"prompt_tokens,omitempty"`
+	CompletionTokens int            `json:"completion_tokens,omitempty"`
+	CostUSD          float64        `json:"cost_usd,omitempty"`
 }
 
-// RunErrorPayload records a run error.
-type RunErrorPayload struct {
+// WarningPayload reports a degradation that does not stop the run, such as a
+// missing search key or repository context that could not be built.
+type WarningPayload struct {
 	Message string `json:"message"`
 }
+
+// Error kinds and severities for RunErrorPayload.
+const (
+	ErrorKindProvider  = "provider"
+	ErrorKindTool      = "tool"
+	ErrorKindCancelled = "cancelled"
+
+	SeverityFatal       = "fatal"
+	SeverityRecoverable = "recoverable"
+)
+
+// RunErrorPayload records a run error. Recoverable errors name the recovery
+// the agent attempts (retry_step, shrink_context, drop_tool); fatal ones end
+// the run.
+type RunErrorPayload struct {
+	Message  string `json:"message"`
+	Kind     string `json:"kind,omitempty"`
+	Severity string `json:"severity,omitempty"`
+	Recovery string `json:"recovery,omitempty"`
+}
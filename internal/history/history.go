@@ -0,0 +1,155 @@
+// Package history keeps the questions asked with fi-cli, one JSON line per
+// question, so they can be re-asked and searched like shell history. It is
+// separate from persisted runs, which are opt-in and much larger.
+package history
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// MaxEntries caps how many questions are kept; the oldest are dropped first.
+const MaxEntries = 1000
+
+// ErrEmpty is returned by Last when no question has been asked yet.
+var ErrEmpty = errors.New("no questions in history yet")
+
+// Entry is one asked question and how its run ended.
+type Entry struct {
+	AskedAt  time.Time `json:"asked_at"`
+	Question string    `json:"question"`
+	Repo     string    `json:"repo,omitempty"`
+	RunID    string    `json:"run_id,omitempty"`
+	Status   string    `json:"status,omitempty"`
+}
+
+// Path returns the history file.
+func Path() (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, ".local", "share", "fi.ashref.tn", "history.jsonl"), nil
+}
+
+// Append adds entry to the history, rewriting the file without its oldest
+// entries once it holds more than MaxEntries.
+func Append(entry Entry) error {
+	entry.Question = strings.TrimSpace(entry.Question)
+	if entry.Question == "" {
+		return errors.New("question is empty")
+	}
+	p, err := Path()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+		return err
+	}
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	file, err := os.OpenFile(p, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o600)
+	if err != nil {
+		return err
+	}
+	if _, err := file.Write(append(line, '\n')); err != nil {
+		_ = file.Close()
+		return err
+	}
+	if err := file.Close(); err != nil {
+		return err
+	}
+	entries, err := Load()
+	if err != nil || len(entries) <= MaxEntries {
+		return err
+	}
+	return rewrite(p, entries[len(entries)-MaxEntries:])
+}
+
+// Load returns the history, oldest first. Lines that do not parse are
+// skipped, so a torn write loses only its own entry.
+func Load() ([]Entry, error) {
+	p, err := Path()
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(p)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var entries []Entry
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, 0, 64*1024), 1<<20)
+	for scanner.Scan() {
+		var entry Entry
+		if json.Unmarshal(scanner.Bytes(), &entry) == nil && entry.Question != "" {
+			entries = append(entries, entry)
+		}
+	}
+	return entries, scanner.Err()
+}
+
+// Last returns the most recent entry.
+func Last() (Entry, error) {
+	entries, err := Load()
+	if err != nil {
+		return Entry{}, err
+	}
+	if len(entries) == 0 {
+		return Entry{}, ErrEmpty
+	}
+	return entries[len(entries)-1], nil
+}
+
+// Search returns the entries whose question contains every word of term,
+// ignoring case, newest first.
+func Search(term string) ([]Entry, error) {
+	entries, err := Load()
+	if err != nil {
+		return nil, err
+	}
+	words := strings.Fields(strings.ToLower(term))
+	var matches []Entry
+	for i := len(entries) - 1; i >= 0; i-- {
+		question := strings.ToLower(entries[i].Question)
+		matched := true
+		for _, word := range words {
+			if !strings.Contains(question, word) {
+				matched = false
+				break
+			}
+		}
+		if matched {
+			matches = append(matches, entries[i])
+		}
+	}
+	return matches, nil
+}
+
+func rewrite(p string, entries []Entry) error {
+	var b bytes.Buffer
+	for _, entry := range entries {
+		line, err := json.Marshal(entry)
+		if err != nil {
+			return err
+		}
+		b.Write(append(line, '\n'))
+	}
+	tmp := p + ".tmp"
+	if err := os.WriteFile(tmp, b.Bytes(), 0o600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, p)
+}
@@ -0,0 +1,62 @@
+package history
+
+import (
+	"errors"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestAppendLastAndSearch(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	if _, err := Last(); !errors.Is(err, ErrEmpty) {
+		t.Fatalf("expected ErrEmpty, got %v", err)
+	}
+	asked := time.Date(2026, 10, 1, 9, 0, 0, 0, time.UTC)
+	for i, question := range []string{"how do I run the tests?", "which port does the API use", "How do I run the API locally?"} {
+		if err := Append(Entry{AskedAt: asked.Add(time.Duration(i) * time.Minute), Question: question, Repo: "/src/app", Status: "success"}); err != nil {
+			t.Fatalf("append failed: %v", err)
+		}
+	}
+	if err := Append(Entry{Question: "  "}); err == nil {
+		t.Fatalf("expected an empty question to be rejected")
+	}
+
+	last, err := Last()
+	if err != nil || last.Question != "How do I run the API locally?" {
+		t.Fatalf("unexpected last entry %+v (%v)", last, err)
+	}
+	matches, err := Search("run how")
+	if err != nil {
+		t.Fatalf("search failed: %v", err)
+	}
+	if len(matches) != 2 || matches[0].Question != "How do I run the API locally?" || matches[1].Question != "how do I run the tests?" {
+		t.Fatalf("expected both run questions newest first, got %+v", matches)
+	}
+}
+
+func TestAppendTrimsToMaxEntries(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	p, _ := Path()
+	for i := 0; i < MaxEntries; i++ {
+		if err := Append(Entry{Question: "old"}); err != nil {
+			t.Fatalf("append failed: %v", err)
+		}
+	}
+	f, err := os.OpenFile(p, os.O_APPEND|os.O_WRONLY, 0o600)
+	if err != nil {
+		t.Fatalf("open failed: %v", err)
+	}
+	_, _ = f.WriteString("{torn\n")
+	_ = f.Close()
+	if err := Append(Entry{Question: "new"}); err != nil {
+		t.Fatalf("append failed: %v", err)
+	}
+	entries, err := Load()
+	if err != nil {
+		t.Fatalf("load failed: %v", err)
+	}
+	if len(entries) != MaxEntries || entries[len(entries)-1].Question != "new" {
+		t.Fatalf("expected %d entries ending in the newest, got %d", MaxEntries, len(entries))
+	}
+}
@@ -0,0 +1,115 @@
+// Package hooks runs user-configured commands around tool calls and runs.
+package hooks
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os/exec"
+	"slices"
+	"strings"
+	"time"
+
+	"fi-cli/internal/config"
+)
+
+// DefaultTimeout bounds one hook command.
+const DefaultTimeout = 10 * time.Second
+
+// Run runs command with sh -c, writing input as JSON to its stdin, and
+// returns what it printed. A non-zero exit or a timeout is an error that
+// carries the first line of stderr.
+func Run(ctx context.Context, command string, input any, timeout time.Duration) (string, error) {
+	payload, err := json.Marshal(input)
+	if err != nil {
+		return "", err
+	}
+	if timeout <= 0 {
+		timeout = DefaultTimeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	cmd := exec.CommandContext(ctx, "sh", "-c", command)
+	cmd.Stdin = bytes.NewReader(payload)
+	// a killed sh can leave children holding stdout open
+	cmd.WaitDelay = time.Second
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			return "", fmt.Errorf("timed out after %s", timeout)
+		}
+		if detail, _, _ := strings.Cut(strings.TrimSpace(stderr.String()), "\n"); detail != "" {
+			return "", errors.New(detail)
+		}
+		return "", err
+	}
+	return strings.TrimSpace(stdout.String()), nil
+}
+
+// ToolCall is what pre_tool and post_tool hooks read on stdin. Output and
+// Status are set for post_tool hooks only.
+type ToolCall struct {
+	Hook      string          `json:"hook"`
+	Tool      string          `json:"tool"`
+	Arguments json.RawMessage `json:"arguments"`
+	Repo      string          `json:"repo"`
+	RunID     string          `json:"run_id"`
+	Status    string          `json:"status,omitempty"`
+	Output    any             `json:"output,omitempty"`
+}
+
+// Verdict is the combined effect of the hooks run for one call. A hook that
+// prints a JSON object may set "note" and, after a call, "output" to replace
+// the result the model sees; any other output is taken as a note.
+type Verdict struct {
+	Blocked bool
+	Reason  string
+	Notes   []string
+	Output  json.RawMessage
+}
+
+// RunTool runs every hook in list that matches call.Tool, in order. The
+// first hook that fails blocks the call and stops the rest.
+func RunTool(ctx context.Context, list []config.ToolHook, call ToolCall, timeout time.Duration) Verdict {
+	var verdict Verdict
+	for _, hook := range list {
+		if len(hook.Tools) > 0 && !slices.Contains(hook.Tools, call.Tool) {
+			continue
+		}
+		if verdict.Output != nil {
+			call.Output = verdict.Output
+		}
+		stdout, err := Run(ctx, hook.Command, call, timeout)
+		if err != nil {
+			verdict.Blocked = true
+			verdict.Reason = err.Error()
+			return verdict
+		}
+		note, output := parseHookOutput(stdout)
+		if note != "" {
+			verdict.Notes = append(verdict.Notes, note)
+		}
+		if output != nil && call.Hook == "post_tool" {
+			verdict.Output = output
+		}
+	}
+	return verdict
+}
+
+func parseHookOutput(stdout string) (string, json.RawMessage) {
+	if !strings.HasPrefix(stdout, "{") {
+		return stdout, nil
+	}
+	var parsed struct {
+		Note   string          `json:"note"`
+		Output json.RawMessage `json:"output"`
+	}
+	if err := json.Unmarshal([]byte(stdout), &parsed); err != nil {
+		return stdout, nil
+	}
+	return parsed.Note, parsed.Output
+}
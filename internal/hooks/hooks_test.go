@@ -0,0 +1,54 @@
+package hooks
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"fi-cli/internal/config"
+)
+
+func TestRunToolHooks(t *testing.T) {
+	list := []config.ToolHook{
+		{Tools: []string{"shell"}, Command: `grep -q '"command":"rm' && { echo "no deletes" >&2; exit 1; }; echo "ticket OPS-1 opened"`},
+		{Command: "echo audited"},
+	}
+	call := ToolCall{Hook: "pre_tool", Tool: "shell", Arguments: []byte(`{"command":"make test"}`)}
+	verdict := RunTool(context.Background(), list, call, time.Second)
+	if verdict.Blocked || strings.Join(verdict.Notes, "|") != "ticket OPS-1 opened|audited" {
+		t.Fatalf("unexpected verdict %+v", verdict)
+	}
+
+	call.Arguments = []byte(`{"command":"rm -rf build"}`)
+	verdict = RunTool(context.Background(), list, call, time.Second)
+	if !verdict.Blocked || verdict.Reason != "no deletes" || len(verdict.Notes) != 0 {
+		t.Fatalf("expected the shell hook to block, got %+v", verdict)
+	}
+
+	call.Tool = "grep"
+	verdict = RunTool(context.Background(), list, call, time.Second)
+	if verdict.Blocked || len(verdict.Notes) != 1 {
+		t.Fatalf("expected only the catch-all hook for grep, got %+v", verdict)
+	}
+}
+
+func TestRunToolHooksReplaceOutput(t *testing.T) {
+	list := []config.ToolHook{
+		{Command: `echo '{"note": "redacted emails", "output": {"matches": ["REDACTED"]}}'`},
+		{Command: `grep -q 'REDACTED' && echo saw-replacement`},
+	}
+	call := ToolCall{Hook: "post_tool", Tool: "grep", Arguments: []byte(`{}`), Status: "success", Output: map[string]any{"matches": []string{"dev@example.com"}}}
+	verdict := RunTool(context.Background(), list, call, time.Second)
+	if string(verdict.Output) != `{"matches": ["REDACTED"]}` {
+		t.Fatalf("expected replaced output, got %s", verdict.Output)
+	}
+	if strings.Join(verdict.Notes, "|") != "redacted emails|saw-replacement" {
+		t.Fatalf("expected later hooks to see the replacement, got %+v", verdict.Notes)
+	}
+
+	call.Hook = "pre_tool"
+	if verdict := RunTool(context.Background(), list[:1], call, time.Second); verdict.Output != nil {
+		t.Fatalf("pre_tool hooks must not replace output")
+	}
+}
@@ -0,0 +1,142 @@
+// Package i18n translates user-facing messages. Messages are keyed by their
+// English text, so code reads as before and English needs no catalog. A
+// translation is a JSON object from English format strings to translated
+// ones, in locales/<lang>.json here (built in) or in the user's locales
+// directory, which wins.
+package i18n
+
+import (
+	"embed"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+)
+
+//go:embed locales
+var builtin embed.FS
+
+var (
+	mu      sync.RWMutex
+	catalog map[string]string
+	active  = "en"
+)
+
+// Detect returns the message locale from LC_ALL, LC_MESSAGES, or LANG, in
+// POSIX order, as a lowercase language tag like "fr" or "pt_br". It returns
+// "en" when none is set or the value is C or POSIX.
+func Detect() string {
+	for _, name := range []string{"LC_ALL", "LC_MESSAGES", "LANG"} {
+		value := os.Getenv(name)
+		if value == "" {
+			continue
+		}
+		value, _, _ = strings.Cut(value, ".")
+		value, _, _ = strings.Cut(value, "@")
+		if value == "C" || value == "POSIX" {
+			return "en"
+		}
+		return strings.ToLower(strings.ReplaceAll(value, "-", "_"))
+	}
+	return "en"
+}
+
+// SetLocale loads the catalog for locale, trying the full tag and then its
+// language ("pt_br", then "pt"), built-in catalogs first and then userDir.
+// English, or a locale with no catalog, leaves messages untranslated. The
+// returned warnings name catalog files or entries that were skipped.
+func SetLocale(locale, userDir string) []string {
+	var candidates []string
+	if locale != "" && locale != "en" {
+		if language, _, ok := strings.Cut(locale, "_"); ok {
+			candidates = append(candidates, language)
+		}
+		candidates = append(candidates, locale)
+	}
+	merged := map[string]string{}
+	var warnings []string
+	for _, name := range candidates {
+		if data, err := builtin.ReadFile("locales/" + name + ".json"); err == nil {
+			warnings = append(warnings, merge(merged, "built-in "+name+".json", data)...)
+		}
+		if userDir == "" {
+			continue
+		}
+		path := filepath.Join(userDir, name+".json")
+		if data, err := os.ReadFile(path); err == nil {
+			warnings = append(warnings, merge(merged, path, data)...)
+		}
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	catalog, active = merged, "en"
+	if len(merged) > 0 {
+		active = locale
+	}
+	return warnings
+}
+
+// Locale returns the locale whose catalog is loaded, or "en".
+func Locale() string {
+	mu.RLock()
+	defer mu.RUnlock()
+	return active
+}
+
+func merge(into map[string]string, source string, data []byte) []string {
+	var entries map[string]string
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return []string{fmt.Sprintf("translation %s: %v", source, err)}
+	}
+	var warnings []string
+	for message, translated := range entries {
+		if err := Check(message, translated); err != nil {
+			warnings = append(warnings, fmt.Sprintf("translation %s: %v", source, err))
+			continue
+		}
+		into[message] = translated
+	}
+	return warnings
+}
+
+// verb matches a fmt verb with its flags, width, and precision.
+var verb = regexp.MustCompile(`%[-+# 0]*[0-9*]*(?:\.[0-9*]*)?[a-zA-Z%]`)
+
+// Check reports whether translated uses the same fmt verbs, in the same
+// order, as message, so a translation can never garble the arguments.
+func Check(message, translated string) error {
+	if !slices.Equal(verb.FindAllString(message, -1), verb.FindAllString(translated, -1)) {
+		return fmt.Errorf("%q: translation %q must use the same %% verbs in the same order", message, translated)
+	}
+	return nil
+}
+
+// T returns the translation of message, or message itself.
+func T(message string) string {
+	mu.RLock()
+	defer mu.RUnlock()
+	if translated, ok := catalog[message]; ok && translated != "" {
+		return translated
+	}
+	return message
+}
+
+// Sprintf translates format and then formats it like fmt.Sprintf.
+func Sprintf(format string, args ...any) string {
+	return fmt.Sprintf(T(format), args...)
+}
+
+// Plural picks one or other by n, translates it, and formats it with n, so
+// both forms take n as their first verb ("%d step", "%d steps"). Catalogs
+// translate each form separately; languages with more plural forms than two
+// make do with these.
+func Plural(n int, one, other string) string {
+	if n == 1 {
+		return Sprintf(one, n)
+	}
+	return Sprintf(other, n)
+}
@@ -0,0 +1,75 @@
+package i18n
+
+import (
+	"encoding/json"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestDetect(t *testing.T) {
+	cases := []struct{ all, messages, lang, want string }{
+		{"", "", "", "en"},
+		{"", "", "fr_FR.UTF-8", "fr_fr"},
+		{"", "de_DE@euro", "fr_FR.UTF-8", "de_de"},
+		{"C", "", "fr_FR.UTF-8", "en"},
+		{"pt-BR", "", "", "pt_br"},
+	}
+	for _, tc := range cases {
+		t.Setenv("LC_ALL", tc.all)
+		t.Setenv("LC_MESSAGES", tc.messages)
+		t.Setenv("LANG", tc.lang)
+		if got := Detect(); got != tc.want {
+			t.Fatalf("LC_ALL=%q LC_MESSAGES=%q LANG=%q: got %q, want %q", tc.all, tc.messages, tc.lang, got, tc.want)
+		}
+	}
+}
+
+func TestUserCatalog(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name string, entries map[string]string) {
+		data, _ := json.Marshal(entries)
+		if err := os.WriteFile(filepath.Join(dir, name), data, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("pt.json", map[string]string{"Plan:": "Plano:", "%d step": "%d passo", "%d steps": "%d passos", "Started: %s": "Início: %d"})
+	write("pt_br.json", map[string]string{"Plan:": "Planejamento:"})
+	t.Cleanup(func() { SetLocale("en", "") })
+
+	warnings := SetLocale("pt_br", dir)
+	if len(warnings) != 1 || !strings.Contains(warnings[0], `"Started: %s"`) {
+		t.Fatalf("expected one warning for the mismatched verb, got %v", warnings)
+	}
+	if Locale() != "pt_br" {
+		t.Fatalf("expected pt_br to be active, got %q", Locale())
+	}
+	if got := T("Plan:"); got != "Planejamento:" {
+		t.Fatalf("expected the regional entry to win, got %q", got)
+	}
+	if got := Plural(3, "%d step", "%d steps"); got != "3 passos" {
+		t.Fatalf("expected the language entry, got %q", got)
+	}
+	if got := Sprintf("Started: %s", "now"); got != "Started: now" {
+		t.Fatalf("expected the rejected entry to stay English, got %q", got)
+	}
+
+	SetLocale("ja", dir)
+	if Locale() != "en" || T("Plan:") != "Plan:" {
+		t.Fatalf("expected English for a locale with no catalog, got %q", Locale())
+	}
+}
+
+// TestBuiltinCatalogs checks the shipped translations the way SetLocale
+// does, so a broken entry fails here instead of being skipped at run time.
+func TestBuiltinCatalogs(t *testing.T) {
+	paths, _ := fs.Glob(builtin, "locales/*.json")
+	for _, path := range paths {
+		data, _ := builtin.ReadFile(path)
+		if warnings := merge(map[string]string{}, path, data); len(warnings) > 0 {
+			t.Errorf("%s", strings.Join(warnings, "\n"))
+		}
+	}
+}
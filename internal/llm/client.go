@@ -3,6 +3,7 @@ package llm
 import (
 	"context"
 	"encoding/json"
+	"strings"
 
 	"github.com/openai/openai-go/v3"
 )
@@ -14,10 +15,40 @@ type ToolCall struct {
 	Arguments json.RawMessage
 }
 
+// Usage counts the tokens a request consumed, as reported by the provider.
+type Usage struct {
+	PromptTokens     int `json:"prompt_tokens"`
+	CompletionTokens int `json:"completion_tokens"`
+}
+
+// Add accumulates other into u.
+func (u *Usage) Add(other Usage) {
+	u.PromptTokens += other.PromptTokens
+	u.CompletionTokens += other.CompletionTokens
+}
+
+// Total returns prompt plus completion tokens.
+func (u Usage) Total() int {
+	return u.PromptTokens + u.CompletionTokens
+}
+
 // Response represents a model response.
 type Response struct {
-	Content   string
-	ToolCalls []ToolCall
+	Content      string
+	ToolCalls    []ToolCall
+	FinishReason string
+	Refusal      string
+	Usage        Usage
+}
+
+// Refused reports whether the provider filtered or the model declined the response.
+func (r Response) Refused() bool {
+	return r.FinishReason == "content_filter" || strings.TrimSpace(r.Refusal) != ""
+}
+
+// Empty reports whether the response carries neither content nor tool calls.
+func (r Response) Empty() bool {
+	return len(r.ToolCalls) == 0 && strings.TrimSpace(r.Content) == ""
 }
 
 // Request is a simplified chat completion request.
@@ -26,6 +57,7 @@ type Request struct {
 	Messages   []openai.ChatCompletionMessageParamUnion
 	Tools      []openai.ChatCompletionToolUnionParam
 	ToolChoice openai.ChatCompletionToolChoiceOptionUnionParam
+	MaxTokens  int
 }
 
 // Client is an LLM client interface.
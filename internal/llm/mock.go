@@ -2,45 +2,80 @@ package llm
 
 import (
 	"context"
-	"encoding/json"
+	"fmt"
 	"sync"
 )
 
-// MockClient is a deterministic client for tests and demos.
+// MockClient is a deterministic client for tests and demos that plays back a
+// Scenario.
 type MockClient struct {
-	mu        sync.Mutex
-	toolCalls int
+	mu          sync.Mutex
+	scenario    Scenario
+	step        int
+	calls       int
+	lastContent string
 }
 
-// NewMockClient returns a simple mock.
+// NewMockClient returns a mock running the built-in script.
 func NewMockClient() *MockClient {
-	return &MockClient{}
+	return NewScenarioClient(defaultScenario)
+}
+
+// NewScenarioClient returns a mock that plays back scenario.
+func NewScenarioClient(scenario Scenario) *MockClient {
+	return &MockClient{scenario: scenario}
 }
 
 func (m *MockClient) Create(ctx context.Context, req Request) (Response, error) {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
-	// Plan generation calls usually do not include tools.
-	if len(req.Tools) == 0 {
-		return Response{Content: "- Review repository context\n- Use grep to find signals\n- Summarize findings with citations"}, nil
+	// Plan generation calls have no tools, or force the plan tool; the
+	// scripted plan comes back as text, as from a provider that ignores it.
+	if len(req.Tools) == 0 || req.ToolChoice.OfFunctionToolChoice != nil {
+		return Response{Content: m.scenario.Plan}, nil
 	}
-
-	m.toolCalls++
-	if m.toolCalls == 1 {
-		args, _ := json.Marshal(map[string]any{"pattern": "FICLI", "case_sensitive": false, "max_results": 20})
-		return Response{ToolCalls: []ToolCall{{ID: "call_1", Name: "grep", Arguments: args}}}, nil
-	}
-	return Response{Content: "Summary: Mock response based on tool results. [tool:grep]\nNext steps: Review the referenced files for details."}, nil
+	return m.next()
 }
 
 func (m *MockClient) Stream(ctx context.Context, req Request, onDelta func(string)) (Response, error) {
 	m.mu.Lock()
 	defer m.mu.Unlock()
-	content := "Summary: Mock response based on tool results. [tool:grep]\nNext steps: Review the referenced files for details."
-	resp := Response{Content: content}
-	if onDelta != nil {
+	var resp Response
+	if len(req.Tools) > 0 && m.step == 0 {
+		// A streamed first step (the fast path) plays the first scripted step.
+		var err error
+		if resp, err = m.next(); err != nil || len(resp.ToolCalls) > 0 {
+			return resp, err
+		}
+	} else {
+		resp = Response{Content: m.scenario.Answer}
+		if resp.Content == "" {
+			resp.Content = m.lastContent
+		}
+	}
+	if onDelta != nil && resp.Content != "" {
 		onDelta(resp.Content)
 	}
 	return resp, nil
 }
+
+// next plays the next scripted step, repeating the last one past the end.
+func (m *MockClient) next() (Response, error) {
+	if len(m.scenario.Steps) == 0 {
+		return Response{Content: "done"}, nil
+	}
+	index := m.step
+	if index >= len(m.scenario.Steps) {
+		index = len(m.scenario.Steps) - 1
+	}
+	m.step++
+	resp, err := m.scenario.Steps[index].response(func() string {
+		m.calls++
+		return fmt.Sprintf("call_%d", m.calls)
+	})
+	if err == nil && resp.Content != "" {
+		m.lastContent = resp.Content
+	}
+	return resp, err
+}
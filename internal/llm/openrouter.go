@@ -41,6 +41,9 @@ func (c *OpenRouterClient) Create(ctx context.Context, req Request) (Response, e
 		ToolChoice:  req.ToolChoice,
 		Temperature: param.NewOpt(0.2),
 	}
+	if req.MaxTokens > 0 {
+		params.MaxTokens = param.NewOpt(int64(req.MaxTokens))
+	}
 	resp, err := c.client.Chat.Completions.New(ctx, params)
 	if err != nil {
 		return Response{}, err
@@ -50,17 +53,29 @@ func (c *OpenRouterClient) Create(ctx context.Context, req Request) (Response, e
 
 func (c *OpenRouterClient) Stream(ctx context.Context, req Request, onDelta func(string)) (Response, error) {
 	params := openai.ChatCompletionNewParams{
-		Model:       shared.ChatModel(req.Model),
-		Messages:    req.Messages,
-		Tools:       req.Tools,
-		ToolChoice:  req.ToolChoice,
-		Temperature: param.NewOpt(0.2),
+		Model:         shared.ChatModel(req.Model),
+		Messages:      req.Messages,
+		Tools:         req.Tools,
+		ToolChoice:    req.ToolChoice,
+		Temperature:   param.NewOpt(0.2),
+		StreamOptions: openai.ChatCompletionStreamOptionsParam{IncludeUsage: param.NewOpt(true)},
+	}
+	if req.MaxTokens > 0 {
+		params.MaxTokens = param.NewOpt(int64(req.MaxTokens))
 	}
 	stream := c.client.Chat.Completions.NewStreaming(ctx, params)
 	var builder strings.Builder
+	var response Response
+	var acc openai.ChatCompletionAccumulator
 	for stream.Next() {
 		chunk := stream.Current()
+		acc.AddChunk(chunk)
+		response.Usage.Add(Usage{PromptTokens: int(chunk.Usage.PromptTokens), CompletionTokens: int(chunk.Usage.CompletionTokens)})
 		for _, choice := range chunk.Choices {
+			if choice.FinishReason != "" {
+				response.FinishReason = choice.FinishReason
+			}
+			response.Refusal += choice.Delta.Refusal
 			delta := choice.Delta.Content
 			if delta != "" {
 				builder.WriteString(delta)
@@ -73,7 +88,11 @@ func (c *OpenRouterClient) Stream(ctx context.Context, req Request, onDelta func
 	if err := stream.Err(); err != nil {
 		return Response{}, err
 	}
-	return Response{Content: builder.String()}, nil
+	response.Content = builder.String()
+	if len(acc.Choices) > 0 {
+		response.ToolCalls = toolCalls(acc.Choices[0].Message)
+	}
+	return response, nil
 }
 
 func parseChatCompletion(resp *openai.ChatCompletion) (Response, error) {
@@ -81,18 +100,24 @@ func parseChatCompletion(resp *openai.ChatCompletion) (Response, error) {
 		return Response{}, fmt.Errorf("empty response")
 	}
 	msg := resp.Choices[0].Message
-	response := Response{Content: msg.Content}
+	response := Response{Content: msg.Content, FinishReason: resp.Choices[0].FinishReason, Refusal: msg.Refusal}
+	response.ToolCalls = toolCalls(msg)
+	response.Usage = Usage{PromptTokens: int(resp.Usage.PromptTokens), CompletionTokens: int(resp.Usage.CompletionTokens)}
+	return response, nil
+}
+
+func toolCalls(msg openai.ChatCompletionMessage) []ToolCall {
+	var calls []ToolCall
 	for _, toolCall := range msg.ToolCalls {
 		if toolCall.Type != "function" {
 			continue
 		}
 		fn := toolCall.AsFunction()
-		args := json.RawMessage(fn.Function.Arguments)
-		response.ToolCalls = append(response.ToolCalls, ToolCall{
+		calls = append(calls, ToolCall{
 			ID:        fn.ID,
 			Name:      fn.Function.Name,
-			Arguments: args,
+			Arguments: json.RawMessage(fn.Function.Arguments),
 		})
 	}
-	return response, nil
+	return calls
 }
@@ -0,0 +1,104 @@
+package llm
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+
+	"go.yaml.in/yaml/v3"
+)
+
+// ScenarioEnvVar selects a scenario file for the mock client.
+const ScenarioEnvVar = "FICLI_MOCK_SCENARIO"
+
+// Scenario scripts the mock client's responses:
+//
+//	plan: |
+//	  - Find the test target
+//	steps:
+//	  - tool_calls:
+//	      - name: grep
+//	        arguments: {pattern: "test:"}
+//	  - content: "Run make test [Makefile:3][T1]"
+//	answer: "Run `make test` [Makefile:3][T1]"
+//
+// Each tool-enabled request consumes the next step; requests past the end
+// repeat the last one. The streamed final answer is Answer, or the content of
+// the most recent content step when Answer is empty.
+type Scenario struct {
+	Plan   string         `yaml:"plan"`
+	Steps  []ScenarioStep `yaml:"steps"`
+	Answer string         `yaml:"answer"`
+}
+
+// ScenarioStep is one scripted model response.
+type ScenarioStep struct {
+	Content   string             `yaml:"content"`
+	ToolCalls []ScenarioToolCall `yaml:"tool_calls"`
+	Refusal   string             `yaml:"refusal"`
+	// Error makes the request fail with this message.
+	Error string `yaml:"error"`
+}
+
+// ScenarioToolCall is a scripted tool call; Arguments are sent as JSON.
+type ScenarioToolCall struct {
+	Name      string         `yaml:"name"`
+	Arguments map[string]any `yaml:"arguments"`
+}
+
+// defaultScenario is the script the mock runs without a scenario file: one
+// grep call, then a cited answer.
+var defaultScenario = Scenario{
+	Plan: "- Review repository context\n- Use grep to find signals\n- Summarize findings with citations",
+	Steps: []ScenarioStep{
+		{ToolCalls: []ScenarioToolCall{{Name: "grep", Arguments: map[string]any{"pattern": "FICLI", "case_sensitive": false, "max_results": 20}}}},
+		{Content: mockAnswer},
+	},
+	Answer: mockAnswer,
+}
+
+const mockAnswer = "Summary: Mock response based on tool results. [T1]\nNext steps: Review the referenced files for details."
+
+// LoadScenario reads and validates a scenario file.
+func LoadScenario(path string) (Scenario, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Scenario{}, err
+	}
+	var scenario Scenario
+	if err := yaml.Unmarshal(data, &scenario); err != nil {
+		return Scenario{}, fmt.Errorf("parse scenario %s: %w", path, err)
+	}
+	if len(scenario.Steps) == 0 {
+		return Scenario{}, fmt.Errorf("scenario %s has no steps", path)
+	}
+	for i, step := range scenario.Steps {
+		for _, call := range step.ToolCalls {
+			if call.Name == "" {
+				return Scenario{}, fmt.Errorf("scenario %s: step %d has a tool call without a name", path, i+1)
+			}
+		}
+	}
+	return scenario, nil
+}
+
+// response converts a step into a model response; call IDs are numbered
+// across the whole scenario.
+func (s ScenarioStep) response(nextID func() string) (Response, error) {
+	if s.Error != "" {
+		return Response{}, errors.New(s.Error)
+	}
+	resp := Response{Content: s.Content, Refusal: s.Refusal}
+	for _, call := range s.ToolCalls {
+		args, err := json.Marshal(call.Arguments)
+		if err != nil {
+			return Response{}, err
+		}
+		if call.Arguments == nil {
+			args = []byte("{}")
+		}
+		resp.ToolCalls = append(resp.ToolCalls, ToolCall{ID: nextID(), Name: call.Name, Arguments: args})
+	}
+	return resp, nil
+}
@@ -0,0 +1,188 @@
+package memory
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"fi-cli/internal/repo"
+
+	"go.yaml.in/yaml/v3"
+)
+
+// KnowledgeFile is the repo-committed knowledge file, relative to the repo
+// root. Facts in it are shared with everyone who clones the repo.
+const KnowledgeFile = ".fi/knowledge.yaml"
+
+// Knowledge is the content of .fi/knowledge.yaml.
+type Knowledge struct {
+	Facts       []string `yaml:"facts,omitempty"`
+	Conventions string   `yaml:"conventions,omitempty"`
+}
+
+// Entry is a fact from either the shared file or the local store. Shared
+// facts have no ID; they are managed by editing the file.
+type Entry struct {
+	ID     int    `json:"id,omitempty"`
+	Text   string `json:"text"`
+	Shared bool   `json:"shared"`
+	// Changed lists the key files changed since a local fact was saved.
+	Changed []string `json:"changed,omitempty"`
+}
+
+// LoadKnowledge reads .fi/knowledge.yaml from repoRoot. A missing file yields
+// empty knowledge.
+func LoadKnowledge(repoRoot string) (Knowledge, error) {
+	return parseKnowledge(os.ReadFile(filepath.Join(repoRoot, KnowledgeFile)))
+}
+
+// LoadKnowledgeFrom is LoadKnowledge reading through a repository file
+// system, so a run applies its path rules to the file.
+func LoadKnowledgeFrom(fsys fs.ReadFileFS) (Knowledge, error) {
+	return parseKnowledge(fsys.ReadFile(KnowledgeFile))
+}
+
+func parseKnowledge(data []byte, err error) (Knowledge, error) {
+	var k Knowledge
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return k, nil
+		}
+		return k, err
+	}
+	if err := yaml.Unmarshal(data, &k); err != nil {
+		return k, fmt.Errorf("parse %s: %w", KnowledgeFile, err)
+	}
+	facts := k.Facts[:0]
+	for _, fact := range k.Facts {
+		if fact = strings.Join(strings.Fields(fact), " "); fact != "" {
+			facts = append(facts, fact)
+		}
+	}
+	k.Facts = facts
+	k.Conventions = strings.TrimSpace(k.Conventions)
+	return k, nil
+}
+
+// ShareText appends facts to .fi/knowledge.yaml, skipping ones already there.
+func ShareText(repoRoot string, texts ...string) error {
+	k, err := LoadKnowledge(repoRoot)
+	if err != nil {
+		return err
+	}
+	seen := map[string]bool{}
+	for _, fact := range k.Facts {
+		seen[factKey(fact)] = true
+	}
+	for _, text := range texts {
+		text = strings.Join(strings.Fields(text), " ")
+		if text == "" {
+			return errors.New("fact is empty")
+		}
+		if !seen[factKey(text)] {
+			seen[factKey(text)] = true
+			k.Facts = append(k.Facts, text)
+		}
+	}
+	p := filepath.Join(repoRoot, KnowledgeFile)
+	if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+		return err
+	}
+	var buf bytes.Buffer
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	if err := enc.Encode(k); err != nil {
+		return err
+	}
+	return os.WriteFile(p, buf.Bytes(), 0o644)
+}
+
+// Share moves local facts into .fi/knowledge.yaml so they are committed with
+// the repo, and returns the facts that were moved.
+func Share(repoRoot string, ids ...int) ([]Fact, error) {
+	store, err := Load(repoRoot)
+	if err != nil {
+		return nil, err
+	}
+	byID := map[int]Fact{}
+	for _, fact := range store.Facts {
+		byID[fact.ID] = fact
+	}
+	var moved []Fact
+	var texts []string
+	for _, id := range ids {
+		fact, ok := byID[id]
+		if !ok {
+			return nil, fmt.Errorf("%w: %d", ErrNotFound, id)
+		}
+		moved = append(moved, fact)
+		texts = append(texts, fact.Text)
+	}
+	if err := ShareText(repoRoot, texts...); err != nil {
+		return nil, err
+	}
+	for _, fact := range moved {
+		if err := Forget(repoRoot, fact.ID); err != nil {
+			return moved, err
+		}
+	}
+	return moved, nil
+}
+
+// Merged returns the shared facts followed by the local ones. A local fact
+// that repeats a shared one (ignoring case and spacing) is dropped, and the
+// key files changed since each local fact was saved are listed with it.
+func Merged(repoRoot string) ([]Entry, error) {
+	return MergedFor("", repoRoot)
+}
+
+// MergedFor is Merged with the private facts of a `fi-cli serve` API user.
+func MergedFor(user, repoRoot string) ([]Entry, error) {
+	k, kerr := LoadKnowledge(repoRoot)
+	return merged(k, kerr, user, repoRoot)
+}
+
+// MergedFrom is MergedFor with the shared file read through fsys.
+func MergedFrom(fsys fs.ReadFileFS, user, repoRoot string) ([]Entry, error) {
+	k, kerr := LoadKnowledgeFrom(fsys)
+	return merged(k, kerr, user, repoRoot)
+}
+
+func merged(k Knowledge, kerr error, user, repoRoot string) ([]Entry, error) {
+	store, err := LoadFor(user, repoRoot)
+	if err != nil {
+		return nil, err
+	}
+	var entries []Entry
+	seen := map[string]bool{}
+	for _, fact := range k.Facts {
+		if !seen[factKey(fact)] {
+			seen[factKey(fact)] = true
+			entries = append(entries, Entry{Text: fact, Shared: true})
+		}
+	}
+	var current map[string]string
+	for _, fact := range store.Facts {
+		if seen[factKey(fact.Text)] {
+			continue
+		}
+		entry := Entry{ID: fact.ID, Text: fact.Text}
+		// facts saved before key files were recorded are never flagged
+		if fact.Files != nil {
+			if current == nil {
+				current = keyFiles(repoRoot)
+			}
+			entry.Changed = repo.ChangedFiles(fact.Files, current)
+		}
+		entries = append(entries, entry)
+	}
+	return entries, kerr
+}
+
+func factKey(text string) string {
+	return strings.ToLower(strings.Join(strings.Fields(text), " "))
+}
@@ -0,0 +1,162 @@
+package memory
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"fi-cli/internal/repo"
+)
+
+// MaxFacts caps how many facts a repo keeps; the oldest are dropped first.
+const MaxFacts = 200
+
+// Fact is a user-provided statement about a repository.
+type Fact struct {
+	ID        int       `json:"id"`
+	Text      string    `json:"text"`
+	CreatedAt time.Time `json:"created_at"`
+	// Files hashes the repo's key files when the fact was saved, so a fact
+	// can be flagged once the project it describes changes.
+	Files map[string]string `json:"files,omitempty"`
+}
+
+// Store is the memory of one repository.
+type Store struct {
+	RepoRoot string `json:"repo_root"`
+	NextID   int    `json:"next_id"`
+	Facts    []Fact `json:"facts"`
+}
+
+// ErrNotFound is returned when forgetting a fact id the store does not have.
+var ErrNotFound = errors.New("no such fact")
+
+// Dir returns the directory where per-repo memory is stored.
+func Dir() (string, error) {
+	return UserDir("")
+}
+
+// UserDir returns the memory directory of a `fi-cli serve` API user, or the
+// local one for "".
+func UserDir(user string) (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	base := filepath.Join(home, ".local", "share", "fi.ashref.tn")
+	if user != "" {
+		base = filepath.Join(base, "users", user)
+	}
+	return filepath.Join(base, "memory"), nil
+}
+
+// path names a repo's store after a hash of its root, so moving the checkout
+// starts a fresh memory rather than reading another repo's.
+func path(user, repoRoot string) (string, error) {
+	dir, err := UserDir(user)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256([]byte(filepath.Clean(repoRoot)))
+	return filepath.Join(dir, hex.EncodeToString(sum[:8])+".json"), nil
+}
+
+// Load reads the store for repoRoot; a repo with no memory yields an empty store.
+func Load(repoRoot string) (Store, error) {
+	return LoadFor("", repoRoot)
+}
+
+// LoadFor is Load for a `fi-cli serve` API user.
+func LoadFor(user, repoRoot string) (Store, error) {
+	store := Store{RepoRoot: repoRoot, NextID: 1}
+	p, err := path(user, repoRoot)
+	if err != nil {
+		return store, err
+	}
+	payload, err := os.ReadFile(p)
+	if errors.Is(err, os.ErrNotExist) {
+		return store, nil
+	}
+	if err != nil {
+		return store, err
+	}
+	if err := json.Unmarshal(payload, &store); err != nil {
+		return store, fmt.Errorf("read memory %s: %w", p, err)
+	}
+	return store, nil
+}
+
+// Remember appends a fact to the repo's store and returns it.
+func Remember(repoRoot, text string) (Fact, error) {
+	text = strings.Join(strings.Fields(text), " ")
+	if text == "" {
+		return Fact{}, errors.New("fact is empty")
+	}
+	store, err := Load(repoRoot)
+	if err != nil {
+		return Fact{}, err
+	}
+	fact := Fact{ID: store.NextID, Text: text, CreatedAt: time.Now().UTC(), Files: keyFiles(repoRoot)}
+	store.NextID++
+	store.Facts = append(store.Facts, fact)
+	if len(store.Facts) > MaxFacts {
+		store.Facts = store.Facts[len(store.Facts)-MaxFacts:]
+	}
+	return fact, save(store)
+}
+
+// Forget removes the fact with id from the repo's store.
+func Forget(repoRoot string, id int) error {
+	store, err := Load(repoRoot)
+	if err != nil {
+		return err
+	}
+	for i, fact := range store.Facts {
+		if fact.ID == id {
+			store.Facts = append(store.Facts[:i], store.Facts[i+1:]...)
+			return save(store)
+		}
+	}
+	return fmt.Errorf("%w: %d", ErrNotFound, id)
+}
+
+// Clear removes every fact for the repo.
+func Clear(repoRoot string) error {
+	p, err := path("", repoRoot)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(p); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+// keyFiles hashes the key files a fact depends on. The knowledge file is left
+// out: sharing another fact does not make this one stale.
+func keyFiles(repoRoot string) map[string]string {
+	files := repo.KeyFileHashes(repoRoot)
+	delete(files, KnowledgeFile)
+	return files
+}
+
+func save(store Store) error {
+	p, err := path("", store.RepoRoot)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+		return err
+	}
+	payload, err := json.MarshalIndent(store, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(p, payload, 0o600)
+}
@@ -0,0 +1,127 @@
+package memory
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestRememberListForget(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	repoRoot := "/work/service"
+
+	first, err := Remember(repoRoot, "  the 'worker' is the queue consumer\tin services/worker ")
+	if err != nil {
+		t.Fatalf("remember failed: %v", err)
+	}
+	if first.ID != 1 || first.Text != "the 'worker' is the queue consumer in services/worker" {
+		t.Fatalf("unexpected fact: %+v", first)
+	}
+	if _, err := Remember(repoRoot, "deploys go through the release branch"); err != nil {
+		t.Fatalf("remember failed: %v", err)
+	}
+	if _, err := Remember(repoRoot, "   "); err == nil {
+		t.Fatalf("expected an empty fact to be rejected")
+	}
+
+	if err := Forget(repoRoot, first.ID); err != nil {
+		t.Fatalf("forget failed: %v", err)
+	}
+	if err := Forget(repoRoot, first.ID); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("expected ErrNotFound, got %v", err)
+	}
+	store, err := Load(repoRoot)
+	if err != nil {
+		t.Fatalf("load failed: %v", err)
+	}
+	if len(store.Facts) != 1 || store.Facts[0].ID != 2 {
+		t.Fatalf("unexpected facts after forget: %+v", store.Facts)
+	}
+	if other, _ := Load("/work/other"); len(other.Facts) != 0 {
+		t.Fatalf("memory leaked across repos: %+v", other.Facts)
+	}
+}
+
+func TestMergedForSkipsLocalFacts(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	repoRoot := t.TempDir()
+	if _, err := Remember(repoRoot, "the local user's note"); err != nil {
+		t.Fatalf("remember failed: %v", err)
+	}
+	entries, err := MergedFor("alice", repoRoot)
+	if err != nil || len(entries) != 0 {
+		t.Fatalf("expected alice to see none of the local facts, got %+v (%v)", entries, err)
+	}
+	if entries, _ := Merged(repoRoot); len(entries) != 1 {
+		t.Fatalf("expected the local fact, got %+v", entries)
+	}
+}
+
+func TestMergedPrefersSharedKnowledge(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	repoRoot := t.TempDir()
+
+	if err := ShareText(repoRoot, "Deploys go through the release branch"); err != nil {
+		t.Fatalf("share failed: %v", err)
+	}
+	if _, err := Remember(repoRoot, "deploys go  through the release branch"); err != nil {
+		t.Fatal(err)
+	}
+	local, err := Remember(repoRoot, "the 'worker' is the queue consumer")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	entries, err := Merged(repoRoot)
+	if err != nil {
+		t.Fatalf("merged failed: %v", err)
+	}
+	if len(entries) != 2 || !entries[0].Shared || entries[1].ID != local.ID {
+		t.Fatalf("expected shared fact then the distinct local one, got %+v", entries)
+	}
+
+	if _, err := Share(repoRoot, local.ID); err != nil {
+		t.Fatalf("share by id failed: %v", err)
+	}
+	k, err := LoadKnowledge(repoRoot)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(k.Facts) != 2 || k.Facts[1] != "the 'worker' is the queue consumer" {
+		t.Fatalf("unexpected shared facts: %+v", k.Facts)
+	}
+	store, _ := Load(repoRoot)
+	for _, fact := range store.Facts {
+		if fact.ID == local.ID {
+			t.Fatalf("shared fact should leave the local store")
+		}
+	}
+}
+
+func TestMergedFlagsFactsAfterKeyFilesChange(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	repoRoot := t.TempDir()
+	gomod := filepath.Join(repoRoot, "go.mod")
+	if err := os.WriteFile(gomod, []byte("module app\n\ngo 1.22\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Remember(repoRoot, "the app targets go 1.22"); err != nil {
+		t.Fatal(err)
+	}
+	if err := ShareText(repoRoot, "deploys go through the release branch"); err != nil {
+		t.Fatal(err)
+	}
+	entries, err := Merged(repoRoot)
+	if err != nil || len(entries) != 2 || entries[1].Changed != nil {
+		t.Fatalf("expected sharing a fact not to flag a local one, got %+v (%v)", entries, err)
+	}
+
+	if err := os.WriteFile(gomod, []byte("module app\n\ngo 1.24\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	entries, _ = Merged(repoRoot)
+	if len(entries) != 2 || len(entries[1].Changed) != 1 || entries[1].Changed[0] != "go.mod" {
+		t.Fatalf("expected the local fact to be flagged after go.mod changed, got %+v", entries)
+	}
+}
@@ -0,0 +1,138 @@
+// Package notify tells people that a run finished, through the sinks listed
+// under notify: in the config file.
+package notify
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"fi-cli/internal/agent"
+	"fi-cli/internal/config"
+	"fi-cli/internal/util"
+)
+
+// Notification is a finished run and where its report lives. ReportURL is a
+// template with {run_id}; without it, sinks point at ReportPath.
+type Notification struct {
+	Result     agent.RunResult
+	ReportPath string
+	ReportURL  string
+}
+
+// Sink delivers a notification to one destination.
+type Sink interface {
+	Send(ctx context.Context, n Notification) error
+}
+
+// Target is a sink and the filters that pick which runs reach it.
+type Target struct {
+	Type         string
+	Sink         Sink
+	OnlyFailures bool
+	MinDuration  time.Duration
+}
+
+// Targets builds a target for each configured sink.
+func Targets(sinks []config.NotifySink) []Target {
+	targets := make([]Target, 0, len(sinks))
+	for _, sink := range sinks {
+		targets = append(targets, Target{Type: sink.Type, Sink: newSink(sink), OnlyFailures: sink.OnlyFailures, MinDuration: sink.MinDuration})
+	}
+	return targets
+}
+
+func newSink(sink config.NotifySink) Sink {
+	switch sink.Type {
+	case config.NotifyWebhook:
+		return webhookSink{url: sink.URL}
+	case config.NotifySlack:
+		return slackSink{channel: sink.Channel}
+	case config.NotifyMatrix:
+		return matrixSink{homeserver: strings.TrimRight(sink.URL, "/"), room: sink.Room}
+	case config.NotifyEmail:
+		return emailSink{host: sink.SMTPHost, from: sink.From, to: sink.To, username: sink.Username}
+	default:
+		return desktopSink{}
+	}
+}
+
+// Wants reports whether result passes the target's filters.
+func (t Target) Wants(result agent.RunResult) bool {
+	if t.OnlyFailures && !Failed(result) {
+		return false
+	}
+	return runDuration(result) >= t.MinDuration
+}
+
+// Any reports whether at least one target wants result.
+func Any(targets []Target, result agent.RunResult) bool {
+	for _, target := range targets {
+		if target.Wants(result) {
+			return true
+		}
+	}
+	return false
+}
+
+// Send delivers n to every target that wants it and returns one error per
+// failed delivery, prefixed with the sink type.
+func Send(ctx context.Context, targets []Target, n Notification) []error {
+	var errs []error
+	for _, target := range targets {
+		if !target.Wants(n.Result) {
+			continue
+		}
+		if err := target.Sink.Send(ctx, n); err != nil {
+			errs = append(errs, fmt.Errorf("notify %s: %w", target.Type, err))
+		}
+	}
+	return errs
+}
+
+// Failed reports whether the run ended without a successful answer.
+func Failed(result agent.RunResult) bool {
+	return result.Status != "success"
+}
+
+func runDuration(result agent.RunResult) time.Duration {
+	if result.StartedAt.IsZero() || result.FinishedAt.IsZero() {
+		return 0
+	}
+	return result.FinishedAt.Sub(result.StartedAt)
+}
+
+// Subject is a one-line summary: status, duration, and the question.
+func Subject(result agent.RunResult) string {
+	question, _, _ := strings.Cut(strings.TrimSpace(result.Question), "\n")
+	question, _ = util.TruncateBytes(question, 120)
+	return fmt.Sprintf("fi-cli %s (%s): %s", result.Status, runDuration(result).Round(time.Second), question)
+}
+
+// reportLink returns the report's URL built from template ({run_id} is
+// replaced), or else the local run log path.
+func (n Notification) reportLink() string {
+	if n.ReportURL != "" {
+		return strings.ReplaceAll(n.ReportURL, "{run_id}", n.Result.RunID)
+	}
+	return n.ReportPath
+}
+
+// PlainText is the body used by sinks without markup: the answer, or the
+// failure, followed by the report location.
+func PlainText(n Notification, maxBytes int) string {
+	body := strings.TrimSpace(n.Result.FinalAnswer)
+	if body == "" {
+		body = fmt.Sprintf("The run finished with status %s and no answer.", n.Result.Status)
+		if len(n.Result.Warnings) > 0 {
+			body += "\n" + strings.Join(n.Result.Warnings, "\n")
+		}
+	}
+	body, _ = util.TruncateBytes(body, maxBytes)
+	text := Subject(n.Result) + "\n\n" + body + "\n\nrun " + n.Result.RunID
+	if link := n.reportLink(); link != "" {
+		text += ": " + link
+	}
+	return text
+}
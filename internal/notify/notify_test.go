@@ -0,0 +1,185 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/textproto"
+	"strings"
+	"testing"
+	"time"
+
+	"fi-cli/internal/agent"
+	"fi-cli/internal/config"
+	"fi-cli/internal/util"
+)
+
+func testResult(status string, took time.Duration) agent.RunResult {
+	start := time.Date(2026, 10, 1, 9, 0, 0, 0, time.UTC)
+	return agent.RunResult{RunID: "run-1", Question: "why do refunds retry?\nsecond line", Status: status, FinalAnswer: "Because of <retry> & backoff.", StartedAt: start, FinishedAt: start.Add(took)}
+}
+
+func TestTargetFilters(t *testing.T) {
+	targets := Targets([]config.NotifySink{
+		{Type: config.NotifyDesktop},
+		{Type: config.NotifyWebhook, URL: "https://example.com", OnlyFailures: true},
+		{Type: config.NotifyEmail, MinDuration: 2 * time.Minute},
+	})
+	cases := []struct {
+		result agent.RunResult
+		want   []bool
+	}{
+		{testResult("success", 30*time.Second), []bool{true, false, false}},
+		{testResult("failure", 30*time.Second), []bool{true, true, false}},
+		{testResult("success", 3*time.Minute), []bool{true, false, true}},
+	}
+	for _, tc := range cases {
+		for i, target := range targets {
+			if got := target.Wants(tc.result); got != tc.want[i] {
+				t.Fatalf("%s run of %s: target %s wants=%v, expected %v", tc.result.Status, runDuration(tc.result), target.Type, got, tc.want[i])
+			}
+		}
+	}
+}
+
+func TestSlackText(t *testing.T) {
+	text := SlackText(Notification{Result: testResult("success", time.Second), ReportURL: "https://ci.example.com/runs/{run_id}.json"})
+	if !strings.HasPrefix(text, "*why do refunds retry?*\n\n") {
+		t.Fatalf("expected the first question line as a header, got %q", text)
+	}
+	if !strings.Contains(text, "Because of &lt;retry&gt; &amp; backoff.") {
+		t.Fatalf("expected escaped answer, got %q", text)
+	}
+	if !strings.HasSuffix(text, "Report: <https://ci.example.com/runs/run-1.json|run run-1>") {
+		t.Fatalf("expected a report link, got %q", text)
+	}
+}
+
+func TestMatrixSink(t *testing.T) {
+	var path, body string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPut || r.Header.Get("Authorization") != "Bearer mx-token" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		path = r.URL.EscapedPath()
+		var event map[string]string
+		_ = json.NewDecoder(r.Body).Decode(&event)
+		body = event["body"]
+		_, _ = w.Write([]byte(`{"event_id":"$1"}`))
+	}))
+	defer server.Close()
+
+	t.Setenv(MatrixTokenEnv, "mx-token")
+	targets := Targets([]config.NotifySink{{Type: config.NotifyMatrix, URL: server.URL + "/", Room: "!ops:example.org"}})
+	if errs := Send(context.Background(), targets, Notification{Result: testResult("failure", time.Minute), ReportPath: "/tmp/run-1.json"}); len(errs) != 0 {
+		t.Fatalf("send failed: %v", errs)
+	}
+	if !strings.HasPrefix(path, "/_matrix/client/v3/rooms/%21ops:example.org/send/m.room.message/") {
+		t.Fatalf("unexpected path %q", path)
+	}
+	if !strings.HasPrefix(body, "fi-cli failure (1m0s): why do refunds retry?") || !strings.HasSuffix(body, "run run-1: /tmp/run-1.json") {
+		t.Fatalf("unexpected body %q", body)
+	}
+
+	t.Setenv(MatrixTokenEnv, "")
+	errs := Send(context.Background(), targets, Notification{Result: testResult("failure", time.Minute)})
+	if len(errs) != 1 || !strings.Contains(errs[0].Error(), "notify matrix: MATRIX_ACCESS_TOKEN is not set") {
+		t.Fatalf("expected a missing-token error, got %v", errs)
+	}
+}
+
+func TestEmailMessage(t *testing.T) {
+	msg := string(emailMessage("fi@example.com", []string{"a@example.com", "b@example.com"}, Notification{Result: testResult("success", time.Second)}))
+	for _, want := range []string{"From: fi@example.com\r\n", "To: a@example.com, b@example.com\r\n", "Subject: fi-cli success (1s): why do refunds retry?\r\n", "\r\n\r\nfi-cli success"} {
+		if !strings.Contains(msg, want) {
+			t.Fatalf("message missing %q:\n%s", want, msg)
+		}
+	}
+}
+
+func TestSlackTextTruncatesBeforeEscaping(t *testing.T) {
+	result := testResult("success", time.Second)
+	result.FinalAnswer = strings.Repeat("a&", slackMaxTextBytes)
+	text := SlackText(Notification{Result: result})
+	answer, _, _ := strings.Cut(strings.TrimPrefix(text, "*why do refunds retry?*\n\n"), "\n\nReport:")
+	if len(answer) > slackMaxTextBytes || !strings.HasSuffix(answer, util.Ellipsis) {
+		t.Fatalf("expected an answer truncated to %d bytes, got %d", slackMaxTextBytes, len(answer))
+	}
+	if strings.Count(answer, "&") != strings.Count(answer, "&amp;") {
+		t.Fatalf("expected whole entities only, got a tail of %q", answer[len(answer)-12:])
+	}
+}
+
+// smtpServer accepts one connection and speaks just enough SMTP to take a
+// message; with stall set it never sends its greeting.
+func smtpServer(t *testing.T, stall bool) (string, <-chan string) {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = listener.Close() })
+	received := make(chan string, 1)
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		if stall {
+			_, _ = io.Copy(io.Discard, conn)
+			return
+		}
+		text := textproto.NewConn(conn)
+		_ = text.PrintfLine("220 localhost ready")
+		for {
+			line, err := text.ReadLine()
+			if err != nil {
+				return
+			}
+			switch verb, _, _ := strings.Cut(line, " "); strings.ToUpper(verb) {
+			case "EHLO", "HELO":
+				_ = text.PrintfLine("250 localhost")
+			case "DATA":
+				_ = text.PrintfLine("354 go ahead")
+				body, _ := text.ReadDotLines()
+				received <- strings.Join(body, "\n")
+				_ = text.PrintfLine("250 queued")
+			case "QUIT":
+				_ = text.PrintfLine("221 bye")
+				return
+			default:
+				_ = text.PrintfLine("250 ok")
+			}
+		}
+	}()
+	return listener.Addr().String(), received
+}
+
+func TestEmailSinkDelivers(t *testing.T) {
+	addr, received := smtpServer(t, false)
+	sink := emailSink{host: addr, from: "fi@example.com", to: []string{"a@example.com"}}
+	if err := sink.Send(context.Background(), Notification{Result: testResult("success", time.Second)}); err != nil {
+		t.Fatalf("send failed: %v", err)
+	}
+	if body := <-received; !strings.Contains(body, "Subject: fi-cli success (1s): why do refunds retry?") {
+		t.Fatalf("unexpected message %q", body)
+	}
+}
+
+func TestEmailSinkRespectsContext(t *testing.T) {
+	addr, _ := smtpServer(t, true)
+	sink := emailSink{host: addr, from: "fi@example.com", to: []string{"a@example.com"}}
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	started := time.Now()
+	err := sink.Send(ctx, Notification{Result: testResult("success", time.Second)})
+	if !errors.Is(err, context.DeadlineExceeded) || time.Since(started) > 5*time.Second {
+		t.Fatalf("expected the stalled server to time out with ctx, got %v after %s", err, time.Since(started))
+	}
+}
@@ -0,0 +1,294 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net"
+	"net/http"
+	"net/smtp"
+	"net/url"
+	"os"
+	"os/exec"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+
+	"fi-cli/internal/util"
+	"fi-cli/internal/webhook"
+)
+
+// Environment variables holding sink secrets.
+const (
+	MatrixTokenEnv  = "MATRIX_ACCESS_TOKEN"
+	SMTPPasswordEnv = "SMTP_PASSWORD"
+)
+
+const (
+	// slackMaxTextBytes keeps a posted answer under Slack's 40,000 character
+	// message limit, leaving room for the header and report link.
+	slackMaxTextBytes = 36000
+	// desktopMaxBytes keeps a desktop notification to a glanceable summary.
+	desktopMaxBytes = 200
+	// messageMaxBytes bounds the answer in Matrix and email messages.
+	messageMaxBytes = 64 * 1024
+	// sendTimeout bounds one delivery to Matrix or an SMTP server.
+	sendTimeout = 15 * time.Second
+)
+
+// SlackChannel returns a sink that posts to a Slack channel, as --post-slack
+// does.
+func SlackChannel(channel string) Sink {
+	return slackSink{channel: channel}
+}
+
+// webhookSink POSTs the RunResult JSON, as scheduled runs do.
+type webhookSink struct {
+	url string
+}
+
+func (s webhookSink) Send(ctx context.Context, n Notification) error {
+	return webhook.Post(ctx, s.url, n.Result)
+}
+
+type slackSink struct {
+	channel string
+}
+
+func (s slackSink) Send(ctx context.Context, n Notification) error {
+	return webhook.PostSlack(ctx, webhook.SlackMessage{Channel: s.channel, Text: SlackText(n)})
+}
+
+// slackEscaper escapes the characters Slack reserves for links and mentions.
+var slackEscaper = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;")
+
+// SlackText formats the question, the answer, and the report link as mrkdwn.
+func SlackText(n Notification) string {
+	result := n.Result
+	var b strings.Builder
+	if question, _, _ := strings.Cut(strings.TrimSpace(result.Question), "\n"); question != "" {
+		fmt.Fprintf(&b, "*%s*\n\n", slackEscaper.Replace(question))
+	}
+	if answer := strings.TrimSpace(result.FinalAnswer); answer != "" {
+		b.WriteString(slackEscapeWithin(answer, slackMaxTextBytes))
+	} else {
+		fmt.Fprintf(&b, "_The run finished with status %s and no answer._", result.Status)
+	}
+	switch {
+	case n.ReportURL != "":
+		fmt.Fprintf(&b, "\n\nReport: <%s|run %s>", n.reportLink(), result.RunID)
+	case n.ReportPath != "":
+		fmt.Fprintf(&b, "\n\nReport: run %s (`%s`)", result.RunID, n.ReportPath)
+	default:
+		fmt.Fprintf(&b, "\n\nReport: run %s", result.RunID)
+	}
+	return b.String()
+}
+
+// slackEscapeWithin escapes text and truncates it to maxBytes. It cuts the
+// text before escaping, so the ellipsis never splits an entity like &amp;.
+func slackEscapeWithin(text string, maxBytes int) string {
+	if escaped := slackEscaper.Replace(text); len(escaped) <= maxBytes {
+		return escaped
+	}
+	budget := maxBytes - len(util.Ellipsis)
+	size, end := 0, 0
+	for ; end < len(text); end++ {
+		width := 1
+		switch text[end] {
+		case '&':
+			width = len("&amp;")
+		case '<', '>':
+			width = len("&lt;")
+		}
+		if size+width > budget {
+			break
+		}
+		size += width
+	}
+	return slackEscaper.Replace(util.CutBytes(text, end)) + util.Ellipsis
+}
+
+// matrixSink sends an m.text message to a room with MATRIX_ACCESS_TOKEN.
+type matrixSink struct {
+	homeserver string
+	room       string
+}
+
+func (s matrixSink) Send(ctx context.Context, n Notification) error {
+	token := os.Getenv(MatrixTokenEnv)
+	if token == "" {
+		return fmt.Errorf("%s is not set", MatrixTokenEnv)
+	}
+	body, err := json.Marshal(map[string]string{"msgtype": "m.text", "body": PlainText(n, messageMaxBytes)})
+	if err != nil {
+		return err
+	}
+	txnID := "fi-" + n.Result.RunID + "-" + strconv.FormatInt(time.Now().UnixNano(), 36)
+	endpoint := fmt.Sprintf("%s/_matrix/client/v3/rooms/%s/send/m.room.message/%s", s.homeserver, url.PathEscape(s.room), url.PathEscape(txnID))
+	ctx, cancel := context.WithTimeout(ctx, sendTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+token)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		b, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("matrix send failed: %s: %s", resp.Status, string(b))
+	}
+	return nil
+}
+
+// emailSink sends a plain-text message over SMTP. With a username it
+// authenticates with SMTP_PASSWORD; net/smtp only sends credentials over TLS
+// or to localhost. Delivery is bounded by ctx and sendTimeout.
+type emailSink struct {
+	host     string
+	from     string
+	to       []string
+	username string
+}
+
+func (s emailSink) Send(ctx context.Context, n Notification) error {
+	hostname, _, err := net.SplitHostPort(s.host)
+	if err != nil {
+		return fmt.Errorf("smtp_host %q must be host:port", s.host)
+	}
+	var auth smtp.Auth
+	if s.username != "" {
+		auth = smtp.PlainAuth("", s.username, os.Getenv(SMTPPasswordEnv), hostname)
+	}
+	ctx, cancel := context.WithTimeout(ctx, sendTimeout)
+	defer cancel()
+	return sendMail(ctx, s.host, hostname, auth, s.from, s.to, emailMessage(s.from, s.to, n))
+}
+
+// sendMail is smtp.SendMail with a context: the connection is dialed with
+// ctx and closed when ctx ends, which fails whatever exchange is in progress.
+func sendMail(ctx context.Context, addr, hostname string, auth smtp.Auth, from string, to []string, msg []byte) error {
+	for _, address := range append([]string{from}, to...) {
+		// as smtp.SendMail does, refuse addresses that would inject commands
+		if strings.ContainsAny(address, "\r\n") {
+			return fmt.Errorf("smtp address %q contains a line break", address)
+		}
+	}
+	conn, err := (&net.Dialer{}).DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = conn.SetDeadline(deadline)
+	}
+	stop := context.AfterFunc(ctx, func() { _ = conn.Close() })
+	defer stop()
+
+	c, err := smtp.NewClient(conn, hostname)
+	if err == nil {
+		defer c.Close()
+		err = deliver(c, hostname, auth, from, to, msg)
+	}
+	if err != nil && ctx.Err() != nil {
+		return fmt.Errorf("smtp: %w", ctx.Err())
+	}
+	// the conn deadline is ctx's, and can fire just before ctx reports it
+	var netErr net.Error
+	if _, ok := ctx.Deadline(); ok && (errors.Is(err, os.ErrDeadlineExceeded) || errors.As(err, &netErr) && netErr.Timeout()) {
+		return fmt.Errorf("smtp: %w", context.DeadlineExceeded)
+	}
+	return err
+}
+
+func deliver(c *smtp.Client, hostname string, auth smtp.Auth, from string, to []string, msg []byte) error {
+	if ok, _ := c.Extension("STARTTLS"); ok {
+		if err := c.StartTLS(&tls.Config{ServerName: hostname}); err != nil {
+			return err
+		}
+	}
+	if auth != nil {
+		if ok, _ := c.Extension("AUTH"); !ok {
+			return errors.New("smtp server does not support AUTH")
+		}
+		if err := c.Auth(auth); err != nil {
+			return err
+		}
+	}
+	if err := c.Mail(from); err != nil {
+		return err
+	}
+	for _, rcpt := range to {
+		if err := c.Rcpt(rcpt); err != nil {
+			return err
+		}
+	}
+	w, err := c.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(msg); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	return c.Quit()
+}
+
+func emailMessage(from string, to []string, n Notification) []byte {
+	var b strings.Builder
+	fmt.Fprintf(&b, "From: %s\r\n", from)
+	fmt.Fprintf(&b, "To: %s\r\n", strings.Join(to, ", "))
+	fmt.Fprintf(&b, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", Subject(n.Result)))
+	fmt.Fprintf(&b, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	b.WriteString("MIME-Version: 1.0\r\nContent-Type: text/plain; charset=UTF-8\r\nContent-Transfer-Encoding: 8bit\r\n\r\n")
+	b.WriteString(strings.ReplaceAll(PlainText(n, messageMaxBytes), "\n", "\r\n"))
+	b.WriteString("\r\n")
+	return []byte(b.String())
+}
+
+// desktopSink shows a notification with notify-send (Linux) or osascript
+// (macOS).
+type desktopSink struct{}
+
+func (desktopSink) Send(ctx context.Context, n Notification) error {
+	title := Subject(n.Result)
+	body := strings.TrimSpace(n.Result.FinalAnswer)
+	if body == "" {
+		body = "No answer; see run " + n.Result.RunID
+	}
+	body, _ = util.TruncateBytes(util.StripANSI(body), desktopMaxBytes)
+	var cmd *exec.Cmd
+	switch runtime.GOOS {
+	case "linux", "freebsd", "openbsd":
+		cmd = exec.CommandContext(ctx, "notify-send", "--app-name=fi-cli", title, body)
+	case "darwin":
+		script := fmt.Sprintf("display notification %s with title %s", appleScriptString(body), appleScriptString(title))
+		cmd = exec.CommandContext(ctx, "osascript", "-e", script)
+	default:
+		return errors.New("desktop notifications are not supported on " + runtime.GOOS)
+	}
+	if output, err := cmd.CombinedOutput(); err != nil {
+		if errors.Is(err, exec.ErrNotFound) {
+			return fmt.Errorf("%s not found on PATH", cmd.Args[0])
+		}
+		return fmt.Errorf("%s failed: %v: %s", cmd.Args[0], err, strings.TrimSpace(string(output)))
+	}
+	return nil
+}
+
+func appleScriptString(text string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(text) + `"`
+}
@@ -0,0 +1,99 @@
+package policy
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"fi-cli/internal/hooks"
+)
+
+// DefaultHookTimeout bounds one policy hook evaluation.
+const DefaultHookTimeout = 5 * time.Second
+
+// HookInput is what a policy hook sees about a tool call, as JSON on stdin.
+type HookInput struct {
+	Tool      string          `json:"tool"`
+	Arguments json.RawMessage `json:"arguments"`
+	Repo      string          `json:"repo"`
+	User      string          `json:"user,omitempty"`
+	Home      string          `json:"home,omitempty"`
+	Config    HookConfig      `json:"config"`
+}
+
+// HookConfig is the part of the user's configuration a hook can decide on.
+type HookConfig struct {
+	Model          string            `json:"model"`
+	UnsafeShell    bool              `json:"unsafe_shell"`
+	ShellAllowlist []string          `json:"shell_allowlist"`
+	NoWeb          bool              `json:"no_web"`
+	Labels         map[string]string `json:"labels,omitempty"`
+}
+
+// HookDecision is a hook's verdict. Reason is shown to the model on a denial.
+type HookDecision struct {
+	Allow  bool   `json:"allow"`
+	Reason string `json:"reason,omitempty"`
+}
+
+// Hook runs an external policy command before each tool call. The command,
+// run with sh -c, reads a HookInput and prints a HookDecision, or a bare
+// true/false, so rule engines with a CLI plug in directly, for example
+// `opa eval -I -d policy.rego -f raw data.fi.decision`. No engine is
+// embedded: cel-go and OPA are not dependencies, so each evaluation is a
+// process.
+type Hook struct {
+	command string
+	timeout time.Duration
+}
+
+// NewHook returns a hook for command, or nil when command is empty.
+func NewHook(command string, timeout time.Duration) *Hook {
+	if strings.TrimSpace(command) == "" {
+		return nil
+	}
+	if timeout <= 0 {
+		timeout = DefaultHookTimeout
+	}
+	return &Hook{command: command, timeout: timeout}
+}
+
+// Evaluate runs the hook for input. It fails closed: a hook that errors,
+// times out, or prints something unreadable denies the call.
+func (h *Hook) Evaluate(ctx context.Context, input HookInput) HookDecision {
+	stdout, err := hooks.Run(ctx, h.command, input, h.timeout)
+	if err != nil {
+		return HookDecision{Reason: "policy hook failed: " + err.Error()}
+	}
+	decision, err := parseDecision([]byte(stdout))
+	if err != nil {
+		return HookDecision{Reason: "policy hook output: " + err.Error()}
+	}
+	return decision
+}
+
+func parseDecision(output []byte) (HookDecision, error) {
+	output = bytes.TrimSpace(output)
+	switch string(output) {
+	case "true":
+		return HookDecision{Allow: true}, nil
+	case "false":
+		return HookDecision{}, nil
+	}
+	var decision struct {
+		Allow  *bool  `json:"allow"`
+		Reason string `json:"reason"`
+	}
+	if err := json.Unmarshal(output, &decision); err != nil || decision.Allow == nil {
+		return HookDecision{}, fmt.Errorf("expected true, false, or {\"allow\": bool}, got %q", firstLine(string(output)))
+	}
+	return HookDecision{Allow: *decision.Allow, Reason: decision.Reason}, nil
+}
+
+func firstLine(text string) string {
+	line, _, _ := strings.Cut(text, "\n")
+	return line
+}
@@ -0,0 +1,37 @@
+package policy
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestHookEvaluate(t *testing.T) {
+	input := HookInput{Tool: "shell", Arguments: json.RawMessage(`{"command":"make deploy"}`), Repo: "/home/dev/scratch", Home: "/home/dev"}
+	cases := []struct {
+		name    string
+		command string
+		allow   bool
+		reason  string
+	}{
+		{"bare true", "echo true", true, ""},
+		{"bare false", "echo false", false, ""},
+		{"json reads stdin", `case "$(cat)" in *'"repo":"/home/dev/work/'*) echo '{"allow":true}';; *) echo '{"allow":false,"reason":"shell only under ~/work"}';; esac`, false, "shell only under ~/work"},
+		{"failing hook denies", "echo broken >&2; exit 3", false, "policy hook failed: broken"},
+		{"unreadable output denies", "echo maybe", false, "policy hook output: expected true, false"},
+		{"slow hook denies", "sleep 5", false, "policy hook failed: timed out after 200ms"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			decision := NewHook(tc.command, 200*time.Millisecond).Evaluate(context.Background(), input)
+			if decision.Allow != tc.allow || !strings.HasPrefix(decision.Reason, tc.reason) {
+				t.Fatalf("unexpected decision %+v", decision)
+			}
+		})
+	}
+	if NewHook("  ", 0) != nil {
+		t.Fatalf("expected no hook for an empty command")
+	}
+}
@@ -0,0 +1,148 @@
+package policy
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"go.yaml.in/yaml/v3"
+)
+
+// RepoPolicyFile is the repository-local policy file, relative to the repo root.
+const RepoPolicyFile = ".fi.yaml"
+
+// PathRule scopes tool restrictions to a directory of the repository.
+// Unset fields inherit from less specific rules.
+type PathRule struct {
+	Path           string   `yaml:"path"`
+	Shell          *bool    `yaml:"shell,omitempty"`
+	Web            *bool    `yaml:"web,omitempty"`
+	DenyExtensions []string `yaml:"deny_extensions,omitempty"`
+}
+
+// PathPolicy holds the path-scoped rules from .fi.yaml.
+type PathPolicy struct {
+	Rules []PathRule `yaml:"policies"`
+}
+
+// LoadPathPolicy reads .fi.yaml from repoRoot. A missing file yields an empty policy.
+func LoadPathPolicy(repoRoot string) (PathPolicy, error) {
+	var p PathPolicy
+	data, err := os.ReadFile(filepath.Join(repoRoot, RepoPolicyFile))
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return p, nil
+		}
+		return p, err
+	}
+	if err := yaml.Unmarshal(data, &p); err != nil {
+		return p, fmt.Errorf("parse %s: %w", RepoPolicyFile, err)
+	}
+	for i := range p.Rules {
+		p.Rules[i].Path = normalizeRulePath(p.Rules[i].Path)
+		for j, ext := range p.Rules[i].DenyExtensions {
+			ext = strings.ToLower(strings.TrimSpace(ext))
+			if ext != "" && !strings.HasPrefix(ext, ".") {
+				ext = "." + ext
+			}
+			p.Rules[i].DenyExtensions[j] = ext
+		}
+	}
+	return p, nil
+}
+
+// ShellAllowed reports whether shell commands may run in dir (repo-relative).
+// The most specific rule that sets shell wins.
+func (p PathPolicy) ShellAllowed(dir string) (bool, string) {
+	rule, ok := p.mostSpecific(dir, func(r PathRule) bool { return r.Shell != nil })
+	if !ok || *rule.Shell {
+		return true, ""
+	}
+	return false, fmt.Sprintf("shell is disabled under %s by %s", displayPath(rule.Path), RepoPolicyFile)
+}
+
+// WebAllowed reports whether web tools are allowed. Web access is not tied to
+// a path, so only the root rule (path "" or ".") applies.
+func (p PathPolicy) WebAllowed() bool {
+	for _, rule := range p.Rules {
+		if rule.Path == "" && rule.Web != nil {
+			return *rule.Web
+		}
+	}
+	return true
+}
+
+// DeniesFile reports whether a repo-relative file may not be read because of
+// a deny_extensions rule covering it.
+func (p PathPolicy) DeniesFile(rel string) bool {
+	rel = normalizeRulePath(rel)
+	ext := strings.ToLower(path.Ext(rel))
+	if ext == "" {
+		return false
+	}
+	for _, rule := range p.Rules {
+		if !covers(rule.Path, rel) {
+			continue
+		}
+		for _, denied := range rule.DenyExtensions {
+			if denied == ext {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// DenyGlobs returns ripgrep exclusion globs for the deny_extensions rules.
+func (p PathPolicy) DenyGlobs() []string {
+	var globs []string
+	for _, rule := range p.Rules {
+		for _, ext := range rule.DenyExtensions {
+			if rule.Path == "" {
+				globs = append(globs, "!*"+ext)
+			} else {
+				globs = append(globs, "!"+rule.Path+"/**/*"+ext)
+			}
+		}
+	}
+	return globs
+}
+
+func (p PathPolicy) mostSpecific(rel string, match func(PathRule) bool) (PathRule, bool) {
+	rel = normalizeRulePath(rel)
+	var best PathRule
+	found := false
+	for _, rule := range p.Rules {
+		if !match(rule) || !covers(rule.Path, rel) {
+			continue
+		}
+		if !found || len(rule.Path) > len(best.Path) {
+			best = rule
+			found = true
+		}
+	}
+	return best, found
+}
+
+func covers(rulePath, rel string) bool {
+	return rulePath == "" || rel == rulePath || strings.HasPrefix(rel, rulePath+"/")
+}
+
+func normalizeRulePath(p string) string {
+	p = path.Clean(filepath.ToSlash(strings.TrimSpace(p)))
+	p = strings.Trim(p, "/")
+	if p == "." {
+		return ""
+	}
+	return p
+}
+
+func displayPath(p string) string {
+	if p == "" {
+		return "the repository root"
+	}
+	return p + "/"
+}
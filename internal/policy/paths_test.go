@@ -0,0 +1,41 @@
+package policy
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLoadPathPolicy(t *testing.T) {
+	root := t.TempDir()
+	content := "policies:\n  - path: /\n    web: false\n    deny_extensions: [sql]\n  - path: infra/\n    shell: false\n  - path: infra/sandbox\n    shell: true\n"
+	if err := os.WriteFile(filepath.Join(root, RepoPolicyFile), []byte(content), 0o600); err != nil {
+		t.Fatalf("write policy: %v", err)
+	}
+	p, err := LoadPathPolicy(root)
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	if p.WebAllowed() {
+		t.Fatalf("expected web to be disabled at the root")
+	}
+	if ok, reason := p.ShellAllowed("infra/prod"); ok || reason == "" {
+		t.Fatalf("expected shell to be denied under infra/")
+	}
+	if ok, _ := p.ShellAllowed("infra/sandbox/x"); !ok {
+		t.Fatalf("expected the more specific rule to re-enable shell")
+	}
+	if ok, _ := p.ShellAllowed("infrastructure"); !ok {
+		t.Fatalf("expected prefix match to respect path segments")
+	}
+	if !p.DeniesFile("db/schema.SQL") || p.DeniesFile("db/schema.go") {
+		t.Fatalf("unexpected deny_extensions result")
+	}
+}
+
+func TestLoadPathPolicyMissing(t *testing.T) {
+	p, err := LoadPathPolicy(t.TempDir())
+	if err != nil || len(p.Rules) != 0 || !p.WebAllowed() {
+		t.Fatalf("expected empty policy, got %+v, %v", p, err)
+	}
+}
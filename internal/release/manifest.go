@@ -0,0 +1,173 @@
+// Package release generates package manager manifests for a fi-cli release
+// from its archives' checksums.
+package release
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strings"
+	"text/template"
+)
+
+// archiveName matches the release archives: fi-cli_<version>_<os>_<arch>
+// with .tar.gz for macOS and Linux and .zip for Windows.
+var archiveName = regexp.MustCompile(`^fi-cli_([^_]+)_(darwin|linux|windows)_(amd64|arm64)\.(tar\.gz|zip)$`)
+
+// Artifact is one downloadable archive of a release.
+type Artifact struct {
+	OS     string
+	Arch   string
+	URL    string
+	SHA256 string
+}
+
+// Manifest describes a release to the package managers.
+type Manifest struct {
+	Version     string
+	Description string
+	Homepage    string
+	License     string
+	Artifacts   []Artifact
+}
+
+// ParseChecksums reads `sha256sum` output, one "<hex>  <file>" per line, and
+// returns the hashes by file name.
+func ParseChecksums(r io.Reader) (map[string]string, error) {
+	sums := map[string]string{}
+	scanner := bufio.NewScanner(r)
+	for line := 1; scanner.Scan(); line++ {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 {
+			continue
+		}
+		if len(fields) != 2 || len(fields[0]) != 64 {
+			return nil, fmt.Errorf("checksums line %d: expected \"<sha256>  <file>\"", line)
+		}
+		sums[strings.TrimPrefix(fields[1], "*")] = strings.ToLower(fields[0])
+	}
+	return sums, scanner.Err()
+}
+
+// Artifacts returns the archives of version found in sums, with URLs under
+// urlBase. "{version}" in urlBase is replaced with version.
+func Artifacts(version, urlBase string, sums map[string]string) ([]Artifact, error) {
+	urlBase = strings.TrimSuffix(strings.ReplaceAll(urlBase, "{version}", version), "/")
+	var artifacts []Artifact
+	for name, sum := range sums {
+		match := archiveName.FindStringSubmatch(name)
+		if match == nil || match[1] != version {
+			continue
+		}
+		artifacts = append(artifacts, Artifact{OS: match[2], Arch: match[3], URL: urlBase + "/" + name, SHA256: sum})
+	}
+	if len(artifacts) == 0 {
+		return nil, fmt.Errorf("no fi-cli_%s_<os>_<arch> archives in the checksums", version)
+	}
+	sort.Slice(artifacts, func(i, j int) bool {
+		if artifacts[i].OS != artifacts[j].OS {
+			return artifacts[i].OS < artifacts[j].OS
+		}
+		return artifacts[i].Arch < artifacts[j].Arch
+	})
+	return artifacts, nil
+}
+
+func (m Manifest) artifact(os, arch string) *Artifact {
+	for i := range m.Artifacts {
+		if m.Artifacts[i].OS == os && m.Artifacts[i].Arch == arch {
+			return &m.Artifacts[i]
+		}
+	}
+	return nil
+}
+
+var brewTemplate = template.Must(template.New("brew").Parse(`class FiCli < Formula
+  desc {{printf "%q" .Description}}
+  homepage {{printf "%q" .Homepage}}
+  version {{printf "%q" .Version}}
+  license {{printf "%q" .License}}
+{{range .Platforms}}{{if or .ARM .Intel}}
+  on_{{.Name}} do
+{{- with .ARM}}
+    if Hardware::CPU.arm?
+      url {{printf "%q" .URL}}
+      sha256 {{printf "%q" .SHA256}}
+    end
+{{- end}}
+{{- with .Intel}}
+    if Hardware::CPU.intel?
+      url {{printf "%q" .URL}}
+      sha256 {{printf "%q" .SHA256}}
+    end
+{{- end}}
+  end
+{{end}}{{end}}
+  def install
+    bin.install "fi-cli"
+  end
+
+  test do
+    system "#{bin}/fi-cli", "version"
+  end
+end
+`))
+
+type brewPlatform struct {
+	Name  string
+	ARM   *Artifact
+	Intel *Artifact
+}
+
+// Brew renders a Homebrew formula for the macOS and Linux archives.
+func Brew(m Manifest) (string, error) {
+	if m.artifact("darwin", "arm64") == nil && m.artifact("darwin", "amd64") == nil &&
+		m.artifact("linux", "arm64") == nil && m.artifact("linux", "amd64") == nil {
+		return "", fmt.Errorf("no macOS or Linux archives for a Homebrew formula")
+	}
+	var out bytes.Buffer
+	err := brewTemplate.Execute(&out, struct {
+		Manifest
+		Platforms []brewPlatform
+	}{m, []brewPlatform{
+		{"macos", m.artifact("darwin", "arm64"), m.artifact("darwin", "amd64")},
+		{"linux", m.artifact("linux", "arm64"), m.artifact("linux", "amd64")},
+	}})
+	return out.String(), err
+}
+
+type scoopArch struct {
+	URL  string `json:"url"`
+	Hash string `json:"hash"`
+}
+
+// Scoop renders a Scoop manifest for the Windows archives.
+func Scoop(m Manifest) (string, error) {
+	architecture := map[string]scoopArch{}
+	if a := m.artifact("windows", "amd64"); a != nil {
+		architecture["64bit"] = scoopArch{a.URL, a.SHA256}
+	}
+	if a := m.artifact("windows", "arm64"); a != nil {
+		architecture["arm64"] = scoopArch{a.URL, a.SHA256}
+	}
+	if len(architecture) == 0 {
+		return "", fmt.Errorf("no Windows archives for a Scoop manifest")
+	}
+	var out bytes.Buffer
+	encoder := json.NewEncoder(&out)
+	encoder.SetEscapeHTML(false)
+	encoder.SetIndent("", "  ")
+	err := encoder.Encode(struct {
+		Version      string               `json:"version"`
+		Description  string               `json:"description"`
+		Homepage     string               `json:"homepage"`
+		License      string               `json:"license"`
+		Architecture map[string]scoopArch `json:"architecture"`
+		Bin          string               `json:"bin"`
+	}{m.Version, m.Description, m.Homepage, m.License, architecture, "fi-cli.exe"})
+	return out.String(), err
+}
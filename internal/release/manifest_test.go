@@ -0,0 +1,72 @@
+package release
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+const testChecksums = `1111111111111111111111111111111111111111111111111111111111111111  fi-cli_0.6.0_darwin_arm64.tar.gz
+2222222222222222222222222222222222222222222222222222222222222222  fi-cli_0.6.0_linux_amd64.tar.gz
+3333333333333333333333333333333333333333333333333333333333333333 *fi-cli_0.6.0_windows_amd64.zip
+4444444444444444444444444444444444444444444444444444444444444444  fi-cli_0.5.0_linux_amd64.tar.gz
+5555555555555555555555555555555555555555555555555555555555555555  checksums.sig
+`
+
+func testManifest(t *testing.T) Manifest {
+	t.Helper()
+	sums, err := ParseChecksums(strings.NewReader(testChecksums))
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	artifacts, err := Artifacts("0.6.0", "https://example.com/download/v{version}/", sums)
+	if err != nil {
+		t.Fatalf("artifacts: %v", err)
+	}
+	if len(artifacts) != 3 {
+		t.Fatalf("expected the three 0.6.0 archives, got %+v", artifacts)
+	}
+	return Manifest{Version: "0.6.0", Description: "Q&A", Homepage: "https://example.com", License: "MIT", Artifacts: artifacts}
+}
+
+func TestBrew(t *testing.T) {
+	formula, err := Brew(testManifest(t))
+	if err != nil {
+		t.Fatalf("brew: %v", err)
+	}
+	for _, want := range []string{
+		`url "https://example.com/download/v0.6.0/fi-cli_0.6.0_darwin_arm64.tar.gz"`,
+		`sha256 "2222222222222222222222222222222222222222222222222222222222222222"`,
+		"  on_linux do\n    if Hardware::CPU.intel?",
+	} {
+		if !strings.Contains(formula, want) {
+			t.Fatalf("formula missing %q:\n%s", want, formula)
+		}
+	}
+	if strings.Contains(formula, "windows") {
+		t.Fatalf("formula should not list Windows archives:\n%s", formula)
+	}
+}
+
+func TestScoop(t *testing.T) {
+	manifest, err := Scoop(testManifest(t))
+	if err != nil {
+		t.Fatalf("scoop: %v", err)
+	}
+	var parsed struct {
+		Description  string
+		Architecture map[string]struct{ URL, Hash string }
+	}
+	if err := json.Unmarshal([]byte(manifest), &parsed); err != nil {
+		t.Fatalf("invalid JSON: %v\n%s", err, manifest)
+	}
+	if parsed.Description != "Q&A" || parsed.Architecture["64bit"].Hash != strings.Repeat("3", 64) || len(parsed.Architecture) != 1 {
+		t.Fatalf("unexpected manifest:\n%s", manifest)
+	}
+}
+
+func TestParseChecksumsRejectsOtherFormats(t *testing.T) {
+	if _, err := ParseChecksums(strings.NewReader("SHA256 (fi-cli.tar.gz) = abc\n")); err == nil {
+		t.Fatal("expected an error for BSD-style output")
+	}
+}
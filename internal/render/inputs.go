@@ -0,0 +1,150 @@
+package render
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"fi-cli/internal/util"
+)
+
+// FormatToolInput renders a tool call's arguments for humans: the fields that
+// matter for each built-in tool, redacted, on one line. Unknown tools fall back
+// to sorted key=value pairs.
+func FormatToolInput(toolName string, input any) string {
+	args := toolArgs(input)
+	if args == nil {
+		return util.RedactSecrets(fmt.Sprint(input))
+	}
+	var parts []string
+	switch toolName {
+	case "grep":
+		parts = append(parts, strconv.Quote(stringArg(args, "pattern")))
+		if paths := listArg(args, "paths"); paths != "" {
+			parts = append(parts, "in "+paths)
+		}
+		if globs := listArg(args, "glob"); globs != "" {
+			parts = append(parts, "glob "+globs)
+		}
+		if b, _ := args["case_sensitive"].(bool); b {
+			parts = append(parts, "case-sensitive")
+		}
+	case "shell":
+		parts = append(parts, "$ "+stringArg(args, "command"))
+		if cwd := stringArg(args, "cwd"); cwd != "" {
+			parts = append(parts, "(in "+cwd+")")
+		}
+	case "code_search":
+		parts = append(parts, strconv.Quote(stringArg(args, "query")))
+		if language := stringArg(args, "language"); language != "" {
+			parts = append(parts, language)
+		}
+		if repo := stringArg(args, "repo"); repo != "" {
+			parts = append(parts, "in "+repo)
+		}
+		if path := stringArg(args, "path"); path != "" {
+			parts = append(parts, "path "+path)
+		}
+	case "read_file":
+		parts = append(parts, stringArg(args, "path"))
+		startLine, _ := args["start_line"].(float64)
+		endLine, _ := args["end_line"].(float64)
+		switch {
+		case endLine > 0:
+			parts = append(parts, fmt.Sprintf("lines %d-%d", max(int(startLine), 1), int(endLine)))
+		case startLine > 0:
+			parts = append(parts, fmt.Sprintf("from line %d", int(startLine)))
+		}
+		offset, hasOffset := args["offset"].(float64)
+		if length, _ := args["length"].(float64); length > 0 {
+			parts = append(parts, fmt.Sprintf("bytes %d+%d", int(offset), int(length)))
+		} else if hasOffset {
+			parts = append(parts, fmt.Sprintf("from byte %d", int(offset)))
+		}
+	case "exa_contents":
+		parts = append(parts, stringArg(args, "url"))
+	case "exa_search", "ask_user":
+		key := "query"
+		if toolName == "ask_user" {
+			key = "question"
+		}
+		parts = append(parts, strconv.Quote(stringArg(args, key)))
+		if page, _ := args["page"].(float64); page > 1 {
+			parts = append(parts, "page "+strconv.Itoa(int(page)))
+		}
+		if category := stringArg(args, "category"); category != "" {
+			parts = append(parts, category)
+		}
+		if since := stringArg(args, "start_published_date"); since != "" {
+			parts = append(parts, "since "+since)
+		}
+		if days, _ := args["max_age_days"].(float64); days > 0 {
+			parts = append(parts, "last "+strconv.Itoa(int(days))+"d")
+		}
+		if until := stringArg(args, "end_published_date"); until != "" {
+			parts = append(parts, "until "+until)
+		}
+		if domains := listArg(args, "include_domains"); domains != "" {
+			parts = append(parts, "on "+domains)
+		}
+		if domains := listArg(args, "exclude_domains"); domains != "" {
+			parts = append(parts, "not on "+domains)
+		}
+	case "list_files":
+		path := stringArg(args, "path")
+		if path == "" {
+			path = "."
+		}
+		parts = append(parts, path)
+		if glob := stringArg(args, "glob"); glob != "" {
+			parts = append(parts, "glob "+glob)
+		}
+		if b, _ := args["recursive"].(bool); b {
+			parts = append(parts, "recursive")
+		}
+	default:
+		keys := make([]string, 0, len(args))
+		for key := range args {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			value, _ := json.Marshal(args[key])
+			parts = append(parts, key+"="+string(value))
+		}
+	}
+	return util.RedactSecrets(strings.Join(parts, " "))
+}
+
+// toolArgs decodes the sanitized input the agent records, which is either a
+// JSON string or an already-decoded object.
+func toolArgs(input any) map[string]any {
+	switch value := input.(type) {
+	case map[string]any:
+		return value
+	case string:
+		var args map[string]any
+		if err := json.Unmarshal([]byte(value), &args); err == nil {
+			return args
+		}
+	}
+	return nil
+}
+
+func stringArg(args map[string]any, key string) string {
+	value, _ := args[key].(string)
+	return value
+}
+
+func listArg(args map[string]any, key string) string {
+	items, _ := args[key].([]any)
+	var values []string
+	for _, item := range items {
+		if text, ok := item.(string); ok && text != "" {
+			values = append(values, text)
+		}
+	}
+	return strings.Join(values, ",")
+}
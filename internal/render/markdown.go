@@ -0,0 +1,205 @@
+package render
+
+import (
+	"fmt"
+	"html"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// CodeBlock is a fenced code block in an answer. Start and End are byte
+// offsets of the whole block, fences included.
+type CodeBlock struct {
+	Lang  string
+	Code  string
+	Start int
+	End   int
+}
+
+// CodeBlocks returns the fenced (```) code blocks in text, in order. An
+// unterminated fence runs to the end of the text.
+func CodeBlocks(text string) []CodeBlock {
+	var blocks []CodeBlock
+	var current *CodeBlock
+	var code strings.Builder
+	offset := 0
+	for _, line := range strings.SplitAfter(text, "\n") {
+		trimmed := strings.TrimSpace(line)
+		switch {
+		case current == nil && strings.HasPrefix(trimmed, "```"):
+			current = &CodeBlock{Lang: strings.ToLower(strings.TrimSpace(strings.TrimPrefix(trimmed, "```"))), Start: offset}
+			code.Reset()
+		case current != nil && trimmed == "```":
+			current.Code = code.String()
+			current.End = offset + len(line)
+			blocks = append(blocks, *current)
+			current = nil
+		case current != nil:
+			code.WriteString(line)
+		}
+		offset += len(line)
+	}
+	if current != nil {
+		current.Code = code.String()
+		current.End = len(text)
+		blocks = append(blocks, *current)
+	}
+	return blocks
+}
+
+var codeExtensions = map[string]string{
+	"bash": "sh", "sh": "sh", "shell": "sh", "zsh": "sh", "console": "sh",
+	"go": "go", "python": "py", "py": "py", "javascript": "js", "js": "js",
+	"typescript": "ts", "ts": "ts", "tsx": "tsx", "jsx": "jsx", "json": "json",
+	"yaml": "yaml", "yml": "yaml", "toml": "toml", "sql": "sql", "rust": "rs",
+	"ruby": "rb", "java": "java", "dockerfile": "Dockerfile", "makefile": "mk",
+	"make": "mk", "html": "html", "css": "css", "diff": "diff", "ini": "ini",
+	"hcl": "tf", "terraform": "tf", "xml": "xml", "markdown": "md", "md": "md",
+}
+
+// codeExtension maps a fence language to a file extension, defaulting to txt.
+func codeExtension(lang string) string {
+	if ext, ok := codeExtensions[lang]; ok {
+		return ext
+	}
+	return "txt"
+}
+
+var snippetNamePattern = regexp.MustCompile(`^snippet-(\d+)\.`)
+
+// SaveCodeBlocks writes each block to dir as snippet-<n>.<ext> and returns the
+// paths written. Numbering continues after the snippets already in dir, so
+// earlier runs are not overwritten. Shell snippets are made executable.
+func SaveCodeBlocks(dir string, blocks []CodeBlock) ([]string, error) {
+	if len(blocks) == 0 {
+		return nil, nil
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	next := 1
+	if entries, err := os.ReadDir(dir); err == nil {
+		for _, entry := range entries {
+			if match := snippetNamePattern.FindStringSubmatch(entry.Name()); match != nil {
+				if n, _ := strconv.Atoi(match[1]); n >= next {
+					next = n + 1
+				}
+			}
+		}
+	}
+	paths := make([]string, 0, len(blocks))
+	for i, block := range blocks {
+		ext := codeExtension(block.Lang)
+		name := fmt.Sprintf("snippet-%d.%s", next+i, ext)
+		mode := os.FileMode(0o644)
+		if ext == "sh" {
+			mode = 0o755
+		}
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(block.Code), mode); err != nil {
+			return paths, err
+		}
+		paths = append(paths, path)
+	}
+	return paths, nil
+}
+
+var (
+	headingPattern    = regexp.MustCompile(`^#{1,6}\s+`)
+	emphasisPattern   = regexp.MustCompile(`(\*\*|__)(.+?)(\*\*|__)`)
+	inlineCodePattern = regexp.MustCompile("`([^`]+)`")
+	linkPattern       = regexp.MustCompile(`\[([^\]]+)\]\((https?://[^)\s]+)\)`)
+)
+
+// StripMarkdown renders text as plain text: fences and heading markers are
+// dropped, emphasis and inline code unwrapped, and links shown as "text (url)".
+// Citations such as [path:12][T1] are kept.
+func StripMarkdown(text string) string {
+	var out []string
+	inFence := false
+	for _, line := range strings.Split(text, "\n") {
+		if strings.HasPrefix(strings.TrimSpace(line), "```") {
+			inFence = !inFence
+			continue
+		}
+		if !inFence {
+			line = headingPattern.ReplaceAllString(line, "")
+			line = strings.TrimPrefix(line, "> ")
+			line = linkPattern.ReplaceAllString(line, "$1 ($2)")
+			line = emphasisPattern.ReplaceAllString(line, "$2")
+			line = inlineCodePattern.ReplaceAllString(line, "$1")
+		}
+		out = append(out, line)
+	}
+	return strings.Join(out, "\n")
+}
+
+// MarkdownToHTML converts the subset of markdown answers use (headings,
+// paragraphs, bullet lists, fenced code, inline code, emphasis, links) into an
+// HTML fragment. All text is escaped.
+func MarkdownToHTML(text string) string {
+	var b strings.Builder
+	var paragraph []string
+	inList := false
+	flush := func() {
+		if len(paragraph) > 0 {
+			b.WriteString("<p>" + strings.Join(paragraph, "<br>\n") + "</p>\n")
+			paragraph = nil
+		}
+		if inList {
+			b.WriteString("</ul>\n")
+			inList = false
+		}
+	}
+	lines := strings.Split(text, "\n")
+	for i := 0; i < len(lines); i++ {
+		line := lines[i]
+		trimmed := strings.TrimSpace(line)
+		switch {
+		case strings.HasPrefix(trimmed, "```"):
+			flush()
+			lang := strings.TrimSpace(strings.TrimPrefix(trimmed, "```"))
+			var code []string
+			for i++; i < len(lines) && strings.TrimSpace(lines[i]) != "```"; i++ {
+				code = append(code, lines[i])
+			}
+			class := ""
+			if lang != "" {
+				class = ` class="language-` + html.EscapeString(lang) + `"`
+			}
+			b.WriteString("<pre><code" + class + ">" + html.EscapeString(strings.Join(code, "\n")) + "</code></pre>\n")
+		case trimmed == "":
+			flush()
+		case headingPattern.MatchString(trimmed):
+			flush()
+			level := len(trimmed) - len(strings.TrimLeft(trimmed, "#"))
+			fmt.Fprintf(&b, "<h%d>%s</h%d>\n", level, inlineHTML(headingPattern.ReplaceAllString(trimmed, "")), level)
+		case strings.HasPrefix(trimmed, "- ") || strings.HasPrefix(trimmed, "* "):
+			if len(paragraph) > 0 {
+				flush()
+			}
+			if !inList {
+				b.WriteString("<ul>\n")
+				inList = true
+			}
+			b.WriteString("<li>" + inlineHTML(trimmed[2:]) + "</li>\n")
+		default:
+			if inList {
+				flush()
+			}
+			paragraph = append(paragraph, inlineHTML(trimmed))
+		}
+	}
+	flush()
+	return strings.TrimRight(b.String(), "\n")
+}
+
+func inlineHTML(text string) string {
+	text = html.EscapeString(text)
+	text = inlineCodePattern.ReplaceAllString(text, "<code>$1</code>")
+	text = emphasisPattern.ReplaceAllString(text, "<strong>$2</strong>")
+	return linkPattern.ReplaceAllString(text, `<a href="$2">$1</a>`)
+}
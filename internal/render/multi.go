@@ -0,0 +1,44 @@
+package render
+
+import (
+	"errors"
+
+	"fi-cli/internal/events"
+)
+
+// MultiRenderer fans events out to several renderers in order.
+type MultiRenderer struct {
+	renderers []Renderer
+}
+
+// NewMultiRenderer combines renderers, dropping nil entries.
+func NewMultiRenderer(renderers ...Renderer) *MultiRenderer {
+	out := make([]Renderer, 0, len(renderers))
+	for _, r := range renderers {
+		if r != nil {
+			out = append(out, r)
+		}
+	}
+	return &MultiRenderer{renderers: out}
+}
+
+func (m *MultiRenderer) Emit(event events.Event) {
+	for _, r := range m.renderers {
+		r.Emit(event)
+	}
+}
+
+func (m *MultiRenderer) Close() error {
+	var errs []error
+	for _, r := range m.renderers {
+		if err := r.Close(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Len reports how many renderers are attached.
+func (m *MultiRenderer) Len() int {
+	return len(m.renderers)
+}
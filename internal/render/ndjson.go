@@ -0,0 +1,35 @@
+package render
+
+import (
+	"encoding/json"
+	"io"
+	"sync"
+
+	"fi-cli/internal/events"
+)
+
+// NDJSONRenderer writes one JSON-encoded event per line.
+type NDJSONRenderer struct {
+	mu  sync.Mutex
+	w   io.Writer
+	enc *json.Encoder
+}
+
+// NewNDJSONRenderer creates a renderer that encodes events to w. If w is an
+// io.Closer it is closed with the renderer.
+func NewNDJSONRenderer(w io.Writer) *NDJSONRenderer {
+	return &NDJSONRenderer{w: w, enc: json.NewEncoder(w)}
+}
+
+func (r *NDJSONRenderer) Emit(event events.Event) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	_ = r.enc.Encode(event)
+}
+
+func (r *NDJSONRenderer) Close() error {
+	if closer, ok := r.w.(io.Closer); ok {
+		return closer.Close()
+	}
+	return nil
+}
@@ -0,0 +1,109 @@
+package render
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"fi-cli/internal/events"
+)
+
+// AnswerProcessor transforms the final answer before it is rendered.
+type AnswerProcessor interface {
+	Process(answer string) (string, error)
+}
+
+// AnswerProcessorFunc adapts a function to AnswerProcessor.
+type AnswerProcessorFunc func(answer string) (string, error)
+
+func (f AnswerProcessorFunc) Process(answer string) (string, error) { return f(answer) }
+
+// Pipeline chains processors; each receives the previous one's output.
+type Pipeline []AnswerProcessor
+
+func (p Pipeline) Process(answer string) (string, error) {
+	for _, processor := range p {
+		next, err := processor.Process(answer)
+		if err != nil {
+			return answer, err
+		}
+		answer = next
+	}
+	return answer, nil
+}
+
+// PorcelainProcessor strips markdown for scripts and plain terminals.
+func PorcelainProcessor() AnswerProcessor {
+	return AnswerProcessorFunc(func(answer string) (string, error) {
+		return StripMarkdown(answer), nil
+	})
+}
+
+// HTMLProcessor converts the answer to an HTML fragment.
+func HTMLProcessor() AnswerProcessor {
+	return AnswerProcessorFunc(func(answer string) (string, error) {
+		return MarkdownToHTML(answer), nil
+	})
+}
+
+// ExtractCodeProcessor moves fenced code blocks into files under dir and
+// leaves a "[code: <path>]" reference in their place.
+func ExtractCodeProcessor(dir string) AnswerProcessor {
+	return AnswerProcessorFunc(func(answer string) (string, error) {
+		blocks := CodeBlocks(answer)
+		paths, err := SaveCodeBlocks(dir, blocks)
+		if err != nil {
+			return answer, err
+		}
+		var b strings.Builder
+		last := 0
+		for i, block := range blocks {
+			b.WriteString(answer[last:block.Start])
+			fmt.Fprintf(&b, "[code: %s]\n", paths[i])
+			last = block.End
+		}
+		b.WriteString(answer[last:])
+		return b.String(), nil
+	})
+}
+
+// ProcessingRenderer runs the final answer through a pipeline before passing
+// it on. Streamed deltas are held back, since the processed answer replaces
+// them; a failing processor leaves the answer as is and emits a Warning.
+type ProcessingRenderer struct {
+	mu       sync.Mutex
+	next     Renderer
+	pipeline Pipeline
+}
+
+// NewProcessingRenderer wraps next; with an empty pipeline it returns next.
+func NewProcessingRenderer(next Renderer, pipeline Pipeline) Renderer {
+	if len(pipeline) == 0 {
+		return next
+	}
+	return &ProcessingRenderer{next: next, pipeline: pipeline}
+}
+
+func (r *ProcessingRenderer) Emit(event events.Event) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	switch event.Type {
+	case events.ModelDelta:
+		return
+	case events.FinalAnswerReady:
+		if payload, ok := event.Payload.(events.FinalAnswerPayload); ok {
+			answer, err := r.pipeline.Process(payload.Answer)
+			if err != nil {
+				r.next.Emit(events.Event{Type: events.Warning, Timestamp: time.Now(), Payload: events.WarningPayload{Message: "answer post-processing failed: " + err.Error()}})
+			}
+			payload.Answer = answer
+			event.Payload = payload
+		}
+	}
+	r.next.Emit(event)
+}
+
+func (r *ProcessingRenderer) Close() error {
+	return r.next.Close()
+}
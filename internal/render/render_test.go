@@ -0,0 +1,288 @@
+package render
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"fi-cli/internal/events"
+)
+
+func TestMultiRendererFansOut(t *testing.T) {
+	var text, ndjson bytes.Buffer
+	multi := NewMultiRenderer(NewStdoutRenderer(&text, false, false, true, false, true), nil, NewNDJSONRenderer(&ndjson))
+	if multi.Len() != 2 {
+		t.Fatalf("expected nil renderer to be dropped")
+	}
+	multi.Emit(events.Event{Type: events.FinalAnswerReady, Timestamp: time.Now(), Payload: events.FinalAnswerPayload{Answer: "done"}})
+	if err := multi.Close(); err != nil {
+		t.Fatalf("close failed: %v", err)
+	}
+	if !strings.Contains(text.String(), "fi: done") {
+		t.Fatalf("expected stdout rendering, got %q", text.String())
+	}
+	var decoded map[string]any
+	if err := json.Unmarshal(ndjson.Bytes(), &decoded); err != nil || decoded["type"] != string(events.FinalAnswerReady) {
+		t.Fatalf("expected NDJSON event, got %q", ndjson.String())
+	}
+}
+
+func TestSSERendererReplaysToLateSubscriber(t *testing.T) {
+	sse, err := NewSSERenderer("127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen failed: %v", err)
+	}
+	sse.Emit(events.Event{Type: events.RunStarted, Timestamp: time.Now(), Payload: events.RunStartedPayload{RunID: "r1"}})
+
+	resp, err := http.Get("http://" + sse.Addr() + "/events")
+	if err != nil {
+		t.Fatalf("get failed: %v", err)
+	}
+	defer resp.Body.Close()
+	go func() {
+		sse.Emit(events.Event{Type: events.RunFinished, Timestamp: time.Now(), Payload: events.RunFinishedPayload{Status: "success"}})
+		_ = sse.Close()
+	}()
+
+	var types []string
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() {
+		if line := scanner.Text(); strings.HasPrefix(line, "event: ") {
+			types = append(types, strings.TrimPrefix(line, "event: "))
+		}
+	}
+	if strings.Join(types, ",") != "RunStarted,RunFinished" {
+		t.Fatalf("unexpected event sequence: %v", types)
+	}
+}
+
+func TestToolStreamRendererFiltersEvents(t *testing.T) {
+	var out bytes.Buffer
+	r := NewToolStreamRenderer(&out)
+	r.Emit(events.Event{Type: events.RunStarted, Timestamp: time.Now(), Payload: events.RunStartedPayload{}})
+	r.Emit(events.Event{Type: events.ToolCallStarted, Timestamp: time.Now(), Payload: events.ToolCallStartedPayload{ToolName: "grep"}})
+	r.Emit(events.Event{Type: events.ModelDelta, Timestamp: time.Now(), Payload: events.ModelDeltaPayload{Delta: "partial"}})
+	r.Emit(events.Event{Type: events.FinalAnswerReady, Timestamp: time.Now(), Payload: events.FinalAnswerPayload{Answer: "the answer"}})
+
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("expected tool record and answer, got %q", out.String())
+	}
+	if !strings.Contains(lines[0], `"ToolCallStarted"`) || lines[1] != "the answer" {
+		t.Fatalf("unexpected output: %q", out.String())
+	}
+}
+
+func TestResultStreamRendererSnapshots(t *testing.T) {
+	var out bytes.Buffer
+	r := NewResultStreamRenderer(&out)
+	now := time.Now()
+	r.Emit(events.Event{Type: events.RunStarted, Timestamp: now, Payload: events.RunStartedPayload{RunID: "r1", Model: "m"}})
+	r.Emit(events.Event{Type: events.ToolCallStarted, Timestamp: now, Payload: events.ToolCallStartedPayload{ID: "T1", ToolName: "grep", Input: `{"pattern":"x"}`, StartedAt: now}})
+	r.Emit(events.Event{Type: events.StageCompleted, Timestamp: now, Payload: events.StagePayload{Role: "researcher"}})
+	r.Emit(events.Event{Type: events.ToolCallFinished, Timestamp: now, Payload: events.ToolCallFinishedPayload{ID: "T1", ToolName: "grep", Status: "success", Output: map[string]any{"matches": []string{"a.go:1:x"}}, DurationMs: 7}})
+	r.Emit(events.Event{Type: events.ToolCallFailed, Timestamp: now, Payload: events.ToolCallFinishedPayload{ID: "T2", ToolName: "shell", Status: "error", Preview: "budget exhausted"}})
+	r.Emit(events.Event{Type: events.FinalAnswerReady, Timestamp: now, Payload: events.FinalAnswerPayload{Answer: "done [T1]"}})
+
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != 5 {
+		t.Fatalf("expected one snapshot per event that changes the result, got %d:\n%s", len(lines), out.String())
+	}
+	type snapshot struct {
+		Partial     bool   `json:"partial"`
+		RunID       string `json:"run_id"`
+		Status      string `json:"status"`
+		FinalAnswer string `json:"final_answer"`
+		ToolCalls   []struct {
+			ID         string         `json:"id"`
+			Status     string         `json:"status"`
+			DurationMs int64          `json:"duration_ms"`
+			Output     map[string]any `json:"output"`
+		} `json:"tool_calls"`
+	}
+	var snapshots []snapshot
+	for _, line := range lines {
+		var s snapshot
+		if err := json.Unmarshal([]byte(line), &s); err != nil {
+			t.Fatalf("invalid snapshot %q: %v", line, err)
+		}
+		snapshots = append(snapshots, s)
+	}
+	if running := snapshots[1].ToolCalls; len(running) != 1 || running[0].Status != "running" {
+		t.Fatalf("expected T1 running in the second snapshot, got %+v", running)
+	}
+	last := snapshots[4]
+	if !last.Partial || last.RunID != "r1" || last.Status != "running" || last.FinalAnswer != "done [T1]" || len(last.ToolCalls) != 2 {
+		t.Fatalf("unexpected last snapshot: %+v", last)
+	}
+	if last.ToolCalls[0].DurationMs != 7 || last.ToolCalls[1].Output["error"] != "budget exhausted" {
+		t.Fatalf("unexpected tool calls: %+v", last.ToolCalls)
+	}
+}
+
+func TestStdoutRendererStripsEscapes(t *testing.T) {
+	var out bytes.Buffer
+	r := NewStdoutRenderer(&out, true, false, true, false, true)
+	r.Emit(events.Event{Type: events.ToolCallFinished, Timestamp: time.Now(), Payload: events.ToolCallFinishedPayload{ToolName: "shell", Status: "success", Preview: "\x1b[32mok\x1b[0m"}})
+	r.Emit(events.Event{Type: events.ModelDelta, Timestamp: time.Now(), Payload: events.ModelDeltaPayload{Delta: "Tests \x1b[3"}})
+	r.Emit(events.Event{Type: events.ModelDelta, Timestamp: time.Now(), Payload: events.ModelDeltaPayload{Delta: "1mfail\x1b[0m\x1b]0;x\x07"}})
+	r.Emit(events.Event{Type: events.FinalAnswerReady, Timestamp: time.Now(), Payload: events.FinalAnswerPayload{Answer: "Tests fail"}})
+
+	if strings.Contains(out.String(), "\x1b") {
+		t.Fatalf("expected escapes stripped, got %q", out.String())
+	}
+	if !strings.Contains(out.String(), "fi: Tests fail\n") {
+		t.Fatalf("expected streamed answer, got %q", out.String())
+	}
+}
+
+func TestStdoutRendererFullPayloads(t *testing.T) {
+	var out bytes.Buffer
+	r := NewStdoutRenderer(&out, true, false, true, false, true).WithFullPayloads()
+	r.Emit(events.Event{Type: events.ToolCallFinished, Timestamp: time.Now(), Payload: events.ToolCallFinishedPayload{ToolName: "grep", Status: "success", Preview: "a.go:1:x", Output: map[string]any{"matches": []string{"a.go:1:x", "b.go:2:y"}}}})
+
+	if strings.Contains(out.String(), "preview:") || !strings.Contains(out.String(), "output:\n  {\n    \"matches\": [") || !strings.Contains(out.String(), `"b.go:2:y"`) {
+		t.Fatalf("expected the full payload instead of the preview, got %q", out.String())
+	}
+}
+
+func TestFormatToolInput(t *testing.T) {
+	cases := []struct {
+		tool  string
+		input any
+		want  string
+	}{
+		{"grep", `{"pattern":"func main","glob":["*.go"],"max_results":20}`, `"func main" glob *.go`},
+		{"shell", `{"command":"make test","cwd":"api"}`, "$ make test (in api)"},
+		{"list_files", map[string]any{"recursive": true}, ". recursive"},
+		{"read_file", `{"path":"cmd/main.go","start_line":40,"end_line":80}`, "cmd/main.go lines 40-80"},
+		{"read_file", `{"path":"server.log","offset":4096,"length":512}`, "server.log bytes 4096+512"},
+		{"exa_search", `{"query":"go 1.24 release","page":2,"category":"news","start_published_date":"2025-01-01"}`, `"go 1.24 release" page 2 news since 2025-01-01`},
+		{"exa_contents", `{"url":"https://go.dev/doc/go1.24"}`, "https://go.dev/doc/go1.24"},
+		{"code_search", `{"query":"retryablehttp.NewClient","language":"Go","repo":"hashicorp/vault","path":"sdk"}`, `"retryablehttp.NewClient" Go in hashicorp/vault path sdk`},
+		{"exa_search", `{"query":"context cancellation","include_domains":["go.dev","pkg.go.dev"]}`, `"context cancellation" on go.dev,pkg.go.dev`},
+		{"exa_search", `{"query":"openssl CVE","category":"news","max_age_days":30}`, `"openssl CVE" news last 30d`},
+		{"custom", `{"b":1,"a":"x"}`, `a="x" b=1`},
+	}
+	for _, tc := range cases {
+		if got := FormatToolInput(tc.tool, tc.input); got != tc.want {
+			t.Errorf("%s: got %q, want %q", tc.tool, got, tc.want)
+		}
+	}
+	if got := FormatToolInput("shell", `{"command":"curl -u token=abc123 example.com"}`); strings.Contains(got, "abc123") {
+		t.Fatalf("expected secret to be redacted, got %q", got)
+	}
+}
+
+func TestRunFooter(t *testing.T) {
+	var out bytes.Buffer
+	renderer := NewStdoutRenderer(&out, false, false, true, false, true)
+	renderer.Emit(events.Event{Type: events.RunFinished, Timestamp: time.Now(), Payload: events.RunFinishedPayload{
+		Status:           "partial",
+		StepsUsed:        3,
+		ToolCalls:        map[string]int{"shell": 1, "grep": 2},
+		DurationMs:       1450,
+		PromptTokens:     4000,
+		CompletionTokens: 210,
+		CostUSD:          0.0042,
+	}})
+	want := "(partial, 3 steps, tools: grep 2, shell 1, 1.4s, 4210 tokens, ~$0.0042)\n"
+	if out.String() != want {
+		t.Fatalf("got %q, want %q", out.String(), want)
+	}
+
+	out.Reset()
+	NewStdoutRenderer(&out, false, true, true, false, true).Emit(events.Event{Type: events.RunFinished, Payload: events.RunFinishedPayload{Status: "success"}})
+	if out.Len() != 0 {
+		t.Fatalf("expected no footer in quiet mode, got %q", out.String())
+	}
+
+	out.Reset()
+	quiet := NewStdoutRenderer(&out, false, true, true, false, true).WithAttribution()
+	quiet.Emit(events.Event{Type: events.RunFinished, Payload: events.RunFinishedPayload{Status: "success", ToolCalls: map[string]int{"shell": 1, "grep": 2}}})
+	if want := "(3 tool calls: grep×2, shell×1)\n"; out.String() != want {
+		t.Fatalf("got %q, want %q", out.String(), want)
+	}
+	if got := Attribution(nil); got != "(no tool calls)" {
+		t.Fatalf("unexpected attribution without tools: %q", got)
+	}
+}
+
+func TestAnswerPipeline(t *testing.T) {
+	answer := "## Run\nUse **make**:\n```bash\nmake test\n```\nSee `Makefile` [Makefile:1][T1]."
+	dir := t.TempDir()
+	pipeline := Pipeline{ExtractCodeProcessor(dir), PorcelainProcessor()}
+	got, err := pipeline.Process(answer)
+	if err != nil {
+		t.Fatalf("pipeline failed: %v", err)
+	}
+	saved := filepath.Join(dir, "snippet-1.sh")
+	want := "Run\nUse make:\n[code: " + saved + "]\nSee Makefile [Makefile:1][T1]."
+	if got != want {
+		t.Fatalf("got %q, want %q", got, want)
+	}
+	if data, err := os.ReadFile(saved); err != nil || string(data) != "make test\n" {
+		t.Fatalf("expected extracted snippet, got %q (%v)", data, err)
+	}
+
+	html := MarkdownToHTML("- a <b>\n- `c`")
+	if html != "<ul>\n<li>a &lt;b&gt;</li>\n<li><code>c</code></li>\n</ul>" {
+		t.Fatalf("unexpected html %q", html)
+	}
+}
+
+func TestTableProcessor(t *testing.T) {
+	answer := "Found these:\n\n| Variable | Default | Source |\n|---|:-:|---|\n| `PORT` | 8080 | [main.go:12][T1] |\n| **DB_URL** | a \\| b\n\nDone."
+	got, err := TableProcessor(false).Process(answer)
+	if err != nil {
+		t.Fatalf("table failed: %v", err)
+	}
+	want := "Variable  Default  Source\nPORT      8080     [main.go:12][T1]\nDB_URL    a | b"
+	if got != want {
+		t.Fatalf("got %q, want %q", got, want)
+	}
+	got, err = TableProcessor(true).Process(answer)
+	if err != nil || got != "Variable\tDefault\tSource\nPORT\t8080\t[main.go:12][T1]\nDB_URL\ta | b\t" {
+		t.Fatalf("unexpected tsv %q (%v)", got, err)
+	}
+
+	for _, bad := range []string{"no table here", "| a | b |\n|---|---|\n| 1 | 2 | 3 |", "| a |\n|---|\n"} {
+		if got, err := TableProcessor(false).Process(bad); err == nil || got != bad {
+			t.Fatalf("expected %q to be rejected unchanged, got %q (%v)", bad, got, err)
+		}
+	}
+}
+
+func TestProcessingRendererReplacesStream(t *testing.T) {
+	var out bytes.Buffer
+	renderer := NewProcessingRenderer(NewStdoutRenderer(&out, false, false, true, false, true), Pipeline{PorcelainProcessor()})
+	renderer.Emit(events.Event{Type: events.ModelDelta, Payload: events.ModelDeltaPayload{Delta: "**bold**"}})
+	renderer.Emit(events.Event{Type: events.FinalAnswerReady, Payload: events.FinalAnswerPayload{Answer: "**bold**"}})
+	if out.String() != "fi: bold\n" {
+		t.Fatalf("expected only the processed answer, got %q", out.String())
+	}
+}
+
+func TestSnippetRendererNumbersAfterExisting(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "snippet-4.go"), []byte("package old\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	var out bytes.Buffer
+	renderer := NewSnippetRenderer(&out, dir)
+	renderer.Emit(events.Event{Type: events.FinalAnswerReady, Payload: events.FinalAnswerPayload{Answer: "Config:\n```yaml\nport: 8080\n```\nThen:\n```\nmake run\n```"}})
+	for _, name := range []string{"snippet-5.yaml", "snippet-6.txt"} {
+		if _, err := os.Stat(filepath.Join(dir, name)); err != nil {
+			t.Fatalf("expected %s: %v", name, err)
+		}
+	}
+	if !strings.Contains(out.String(), "snippet-5.yaml (yaml)") || !strings.Contains(out.String(), "snippet-6.txt (text)") {
+		t.Fatalf("expected saved paths, got %q", out.String())
+	}
+}
@@ -0,0 +1,118 @@
+package render
+
+import (
+	"encoding/json"
+	"io"
+	"sync"
+	"time"
+
+	"fi-cli/internal/events"
+)
+
+// snapshotDeltaInterval spaces the snapshots written while the answer
+// streams in.
+const snapshotDeltaInterval = 250 * time.Millisecond
+
+// ResultStreamRenderer writes a partial run result as one NDJSON line each
+// time the run advances: a tool call starts or ends, a warning arrives, the
+// answer grows. Snapshots use the field names of the final --json document
+// and carry "partial": true; the caller writes the complete document last.
+type ResultStreamRenderer struct {
+	mu        sync.Mutex
+	enc       *json.Encoder
+	snapshot  resultSnapshot
+	calls     map[string]int
+	lastDelta time.Time
+}
+
+type resultSnapshot struct {
+	Partial     bool                      `json:"partial"`
+	RunID       string                    `json:"run_id"`
+	StartedAt   time.Time                 `json:"timestamp_start"`
+	RepoRoot    string                    `json:"repo_root"`
+	Model       string                    `json:"model"`
+	Status      string                    `json:"status"`
+	Route       string                    `json:"route,omitempty"`
+	Plan        []string                  `json:"plan,omitempty"`
+	FinalAnswer string                    `json:"final_answer"`
+	Assessment  *events.AssessmentPayload `json:"assessment,omitempty"`
+	Citations   []events.Citation         `json:"citations,omitempty"`
+	Warnings    []string                  `json:"warnings,omitempty"`
+	ToolCalls   []snapshotToolCall        `json:"tool_calls"`
+}
+
+// snapshotToolCall mirrors agent.ToolCallRecord; Status is "running" until
+// the call ends.
+type snapshotToolCall struct {
+	ID         string    `json:"id"`
+	ToolName   string    `json:"tool_name"`
+	Input      any       `json:"input"`
+	Output     any       `json:"output,omitempty"`
+	Status     string    `json:"status"`
+	StartedAt  time.Time `json:"started_at"`
+	DurationMs int64     `json:"duration_ms"`
+}
+
+// NewResultStreamRenderer creates a renderer of partial run results.
+func NewResultStreamRenderer(w io.Writer) *ResultStreamRenderer {
+	return &ResultStreamRenderer{
+		enc:      json.NewEncoder(w),
+		snapshot: resultSnapshot{Partial: true, Status: "running", ToolCalls: []snapshotToolCall{}},
+		calls:    map[string]int{},
+	}
+}
+
+func (r *ResultStreamRenderer) Emit(event events.Event) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	s := &r.snapshot
+	switch payload := event.Payload.(type) {
+	case events.RunStartedPayload:
+		s.RunID, s.StartedAt, s.RepoRoot, s.Model = payload.RunID, payload.StartedAt, payload.RepoRoot, payload.Model
+	case events.QuestionRoutedPayload:
+		s.Route = payload.Route
+	case events.PlanGeneratedPayload:
+		s.Plan = payload.Plan
+	case events.ToolCallStartedPayload:
+		r.calls[payload.ID] = len(s.ToolCalls)
+		s.ToolCalls = append(s.ToolCalls, snapshotToolCall{ID: payload.ID, ToolName: payload.ToolName, Input: payload.Input, Status: "running", StartedAt: payload.StartedAt})
+	case events.ToolCallFinishedPayload:
+		index, started := r.calls[payload.ID]
+		if !started || payload.ID == "" {
+			// refused before it started, e.g. by the budget
+			index = len(s.ToolCalls)
+			s.ToolCalls = append(s.ToolCalls, snapshotToolCall{ID: payload.ID, ToolName: payload.ToolName, StartedAt: event.Timestamp})
+		}
+		call := &s.ToolCalls[index]
+		call.Status, call.DurationMs, call.Output = payload.Status, payload.DurationMs, payload.Output
+		if payload.Status != "success" {
+			call.Output = map[string]string{"error": payload.Preview}
+		}
+	case events.ModelDeltaPayload:
+		s.FinalAnswer += payload.Delta
+		if time.Since(r.lastDelta) < snapshotDeltaInterval {
+			return
+		}
+		r.lastDelta = time.Now()
+	case events.FinalAnswerPayload:
+		s.FinalAnswer, s.Citations = payload.Answer, payload.Citations
+	case events.AssessmentPayload:
+		s.Assessment = &payload
+	case events.WarningPayload:
+		s.Warnings = append(s.Warnings, payload.Message)
+	case events.RunErrorPayload:
+		if payload.Severity == events.SeverityRecoverable {
+			return
+		}
+		s.Status = "failure"
+	default:
+		// stage summaries, stream resumes, and the finish add nothing the
+		// final document does not
+		return
+	}
+	_ = r.enc.Encode(s)
+}
+
+func (r *ResultStreamRenderer) Close() error {
+	return nil
+}
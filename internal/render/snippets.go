@@ -0,0 +1,54 @@
+package render
+
+import (
+	"fmt"
+	"io"
+	"sync"
+
+	"fi-cli/internal/events"
+)
+
+// SnippetRenderer saves the fenced code blocks of the final answer into dir and
+// prints the paths it wrote. It leaves the answer itself to other renderers.
+type SnippetRenderer struct {
+	mu  sync.Mutex
+	w   io.Writer
+	dir string
+}
+
+// NewSnippetRenderer creates a renderer that saves answer snippets under dir.
+func NewSnippetRenderer(w io.Writer, dir string) *SnippetRenderer {
+	return &SnippetRenderer{w: w, dir: dir}
+}
+
+func (r *SnippetRenderer) Emit(event events.Event) {
+	if event.Type != events.FinalAnswerReady {
+		return
+	}
+	payload, ok := event.Payload.(events.FinalAnswerPayload)
+	if !ok {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	blocks := CodeBlocks(payload.Answer)
+	paths, err := SaveCodeBlocks(r.dir, blocks)
+	if err != nil {
+		fmt.Fprintf(r.w, "warning: saving snippets failed: %v\n", err)
+	}
+	if len(paths) == 0 {
+		return
+	}
+	fmt.Fprintln(r.w, "saved snippets:")
+	for i, path := range paths {
+		lang := blocks[i].Lang
+		if lang == "" {
+			lang = "text"
+		}
+		fmt.Fprintf(r.w, "  %s (%s)\n", path, lang)
+	}
+}
+
+func (r *SnippetRenderer) Close() error {
+	return nil
+}
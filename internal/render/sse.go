@@ -0,0 +1,103 @@
+package render
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"fi-cli/internal/events"
+)
+
+// SSERenderer serves run events as Server-Sent Events on GET /events.
+// Subscribers that connect late receive the events emitted so far first.
+type SSERenderer struct {
+	server   *http.Server
+	listener net.Listener
+
+	mu     sync.Mutex
+	cond   *sync.Cond
+	events [][]byte
+	closed bool
+}
+
+// NewSSERenderer starts listening on addr (e.g. ":7777").
+func NewSSERenderer(addr string) (*SSERenderer, error) {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+	r := &SSERenderer{listener: listener}
+	r.cond = sync.NewCond(&r.mu)
+	mux := http.NewServeMux()
+	mux.HandleFunc("/events", r.handle)
+	r.server = &http.Server{Handler: mux, ReadHeaderTimeout: 5 * time.Second}
+	go func() { _ = r.server.Serve(listener) }()
+	return r, nil
+}
+
+// Addr returns the bound listen address.
+func (r *SSERenderer) Addr() string {
+	return r.listener.Addr().String()
+}
+
+func (r *SSERenderer) Emit(event events.Event) {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return
+	}
+	frame := []byte(fmt.Sprintf("event: %s\ndata: %s\n\n", event.Type, data))
+	r.mu.Lock()
+	r.events = append(r.events, frame)
+	r.mu.Unlock()
+	r.cond.Broadcast()
+}
+
+func (r *SSERenderer) handle(w http.ResponseWriter, req *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming unsupported", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	stop := context.AfterFunc(req.Context(), r.cond.Broadcast)
+	defer stop()
+	sent := 0
+	for {
+		r.mu.Lock()
+		for sent == len(r.events) && !r.closed && req.Context().Err() == nil {
+			r.cond.Wait()
+		}
+		pending := r.events[sent:]
+		closed := r.closed
+		r.mu.Unlock()
+		for _, frame := range pending {
+			if _, err := w.Write(frame); err != nil {
+				return
+			}
+		}
+		sent += len(pending)
+		flusher.Flush()
+		if closed || req.Context().Err() != nil {
+			return
+		}
+	}
+}
+
+// Close ends open streams after flushing remaining events and stops the server.
+func (r *SSERenderer) Close() error {
+	r.mu.Lock()
+	r.closed = true
+	r.mu.Unlock()
+	r.cond.Broadcast()
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	return r.server.Shutdown(ctx)
+}
@@ -1,12 +1,16 @@
 package render
 
 import (
+	"encoding/json"
 	"fmt"
 	"io"
+	"sort"
 	"strings"
 	"sync"
 
 	"fi-cli/internal/events"
+	"fi-cli/internal/i18n"
+	"fi-cli/internal/util"
 )
 
 // StdoutRenderer streams events to a plain text writer.
@@ -18,9 +22,14 @@ type StdoutRenderer struct {
 	noPlan             bool
 	showHeader         bool
 	showTools          bool
+	attribution        bool
+	fullPayloads       bool
 	printedFinalHeader bool
 	sawDelta           bool
 	endedWithNewline   bool
+	// filter strips escape sequences from the streamed answer, which may
+	// echo tool output verbatim.
+	filter util.TerminalFilter
 }
 
 // NewStdoutRenderer creates a renderer for plain text streaming.
@@ -28,6 +37,27 @@ func NewStdoutRenderer(w io.Writer, verbose bool, quiet bool, noPlan bool, showH
 	return &StdoutRenderer{w: w, verbose: verbose, quiet: quiet, noPlan: noPlan, showHeader: showHeader, showTools: showTools}
 }
 
+// WithoutPrefix drops the "fi: " answer prefix, for output that other tools
+// consume (porcelain text, HTML).
+func (r *StdoutRenderer) WithoutPrefix() *StdoutRenderer {
+	r.printedFinalHeader = true
+	return r
+}
+
+// WithAttribution keeps a one-line tool call count (see Attribution) after
+// the answer in quiet mode, where the footer is dropped.
+func (r *StdoutRenderer) WithAttribution() *StdoutRenderer {
+	r.attribution = true
+	return r
+}
+
+// WithFullPayloads prints each tool call's complete output as indented JSON
+// instead of its preview in verbose mode (-vv).
+func (r *StdoutRenderer) WithFullPayloads() *StdoutRenderer {
+	r.fullPayloads = true
+	return r
+}
+
 func (r *StdoutRenderer) Emit(event events.Event) {
 	r.mu.Lock()
 	defer r.mu.Unlock()
@@ -38,17 +68,41 @@ func (r *StdoutRenderer) Emit(event events.Event) {
 			if r.quiet || !r.showHeader {
 				return
 			}
-			fmt.Fprintf(r.w, "fi-cli v%s | repo: %s | model: %s | run: %s\n", payload.Version, payload.RepoRoot, payload.Model, payload.RunID)
-			fmt.Fprintf(r.w, "Started: %s\n", payload.StartedAt.Format("2006-01-02T15:04:05Z07:00"))
+			fmt.Fprintln(r.w, i18n.Sprintf("fi-cli v%s | repo: %s | model: %s | run: %s", payload.Version, payload.RepoRoot, payload.Model, payload.RunID))
+			fmt.Fprintln(r.w, i18n.Sprintf("Started: %s", payload.StartedAt.Format("2006-01-02T15:04:05Z07:00")))
+		}
+	case events.QuestionRouted:
+		if payload, ok := event.Payload.(events.QuestionRoutedPayload); ok {
+			if r.quiet || !r.verbose {
+				return
+			}
+			fmt.Fprintln(r.w, i18n.Sprintf("route: %s (%s) | tools: %s", payload.Route, payload.Source, strings.Join(payload.Tools, ", ")))
+		}
+	case events.StageCompleted:
+		if payload, ok := event.Payload.(events.StagePayload); ok {
+			if r.quiet {
+				return
+			}
+			fmt.Fprintln(r.w, i18n.Sprintf("stage: %s %s (%d steps, %d tool calls)", payload.Role, payload.Status, payload.StepsUsed, payload.ToolCalls))
 		}
 	case events.PlanGenerated:
 		if payload, ok := event.Payload.(events.PlanGeneratedPayload); ok {
 			if r.quiet || r.noPlan {
 				return
 			}
-			fmt.Fprintln(r.w, "\nPlan:")
-			for _, item := range payload.Plan {
-				fmt.Fprintf(r.w, "- %s\n", item)
+			fmt.Fprintln(r.w, "\n"+i18n.T("Plan:"))
+			if len(payload.Steps) == 0 {
+				for _, item := range payload.Plan {
+					fmt.Fprintf(r.w, "- %s\n", item)
+				}
+				return
+			}
+			for _, step := range payload.Steps {
+				if len(step.Tools) > 0 {
+					fmt.Fprintf(r.w, "- %s (%s)\n", step.Title, strings.Join(step.Tools, ", "))
+				} else {
+					fmt.Fprintf(r.w, "- %s\n", step.Title)
+				}
 			}
 		}
 	case events.ToolCallStarted:
@@ -56,8 +110,8 @@ func (r *StdoutRenderer) Emit(event events.Event) {
 			if r.quiet || !r.showTools || !r.verbose {
 				return
 			}
-			fmt.Fprintf(r.w, "tool: %s start\n", payload.ToolName)
-			fmt.Fprintf(r.w, "input: %v\n", payload.Input)
+			fmt.Fprintln(r.w, i18n.Sprintf("tool: %s start", payload.ToolName))
+			fmt.Fprintln(r.w, i18n.Sprintf("input: %s", FormatToolInput(payload.ToolName, payload.Input)))
 		}
 	case events.ToolCallFinished, events.ToolCallFailed:
 		if payload, ok := event.Payload.(events.ToolCallFinishedPayload); ok {
@@ -72,12 +126,28 @@ func (r *StdoutRenderer) Emit(event events.Event) {
 			}
 			trunc := ""
 			if payload.Truncated {
-				trunc = ", truncated"
+				trunc = i18n.T(", truncated")
+			}
+			fmt.Fprintln(r.w, i18n.Sprintf("tool: %s %s (%dms, %d lines, %d bytes%s)", payload.ToolName, status, payload.DurationMs, payload.LineCount, payload.ByteCount, trunc))
+			if r.verbose && payload.RepeatOf != "" {
+				if len(payload.Diff) == 0 {
+					fmt.Fprintln(r.w, i18n.Sprintf("unchanged since %s", payload.RepeatOf))
+				} else {
+					fmt.Fprintln(r.w, i18n.Sprintf("diff since %s:", payload.RepeatOf))
+					for _, line := range payload.Diff {
+						fmt.Fprintf(r.w, "  %s\n", util.SanitizeTerminal(line))
+					}
+				}
+			}
+			if r.verbose && r.fullPayloads && payload.Output != nil {
+				if data, err := json.MarshalIndent(payload.Output, "  ", "  "); err == nil {
+					fmt.Fprintf(r.w, "%s\n  %s\n", i18n.T("output:"), util.SanitizeTerminal(string(data)))
+					return
+				}
 			}
-			fmt.Fprintf(r.w, "tool: %s %s (%dms, %d lines, %d bytes%s)\n", payload.ToolName, status, payload.DurationMs, payload.LineCount, payload.ByteCount, trunc)
 			if r.verbose && payload.Preview != "" {
-				fmt.Fprintln(r.w, "preview:")
-				for _, line := range strings.Split(payload.Preview, "\n") {
+				fmt.Fprintln(r.w, i18n.T("preview:"))
+				for _, line := range strings.Split(util.SanitizeTerminal(payload.Preview), "\n") {
 					fmt.Fprintf(r.w, "  %s\n", line)
 				}
 			}
@@ -88,31 +158,157 @@ func (r *StdoutRenderer) Emit(event events.Event) {
 				fmt.Fprint(r.w, "fi: ")
 				r.printedFinalHeader = true
 			}
-			if payload.Delta != "" {
-				fmt.Fprint(r.w, payload.Delta)
+			if text := r.filter.Write(payload.Delta); text != "" {
+				fmt.Fprint(r.w, text)
 				r.sawDelta = true
-				r.endedWithNewline = strings.HasSuffix(payload.Delta, "\n")
+				r.endedWithNewline = strings.HasSuffix(text, "\n")
+			}
+		}
+	case events.StreamResumed:
+		if payload, ok := event.Payload.(events.StreamResumedPayload); ok {
+			if r.verbose {
+				fmt.Fprintln(r.w, "\n"+i18n.Sprintf("(stream interrupted: %s; resuming, attempt %d)", payload.Reason, payload.Attempt))
 			}
 		}
 	case events.FinalAnswerReady:
 		if payload, ok := event.Payload.(events.FinalAnswerPayload); ok {
+			if text := r.filter.Flush(); text != "" {
+				fmt.Fprint(r.w, text)
+				r.sawDelta = true
+				r.endedWithNewline = strings.HasSuffix(text, "\n")
+			}
 			if r.sawDelta {
 				if !r.endedWithNewline {
 					fmt.Fprintln(r.w)
 				}
+			} else {
+				if !r.printedFinalHeader {
+					fmt.Fprint(r.w, "fi: ")
+					r.printedFinalHeader = true
+				}
+				fmt.Fprintln(r.w, util.SanitizeTerminal(payload.Answer))
+			}
+			if !r.quiet && r.showTools {
+				r.writeCitations(payload.Citations)
+			}
+		}
+	case events.AssessmentReady:
+		if payload, ok := event.Payload.(events.AssessmentPayload); ok {
+			if r.quiet {
 				return
 			}
-			if !r.printedFinalHeader {
-				fmt.Fprint(r.w, "fi: ")
-				r.printedFinalHeader = true
+			fmt.Fprintln(r.w, i18n.Sprintf("confidence: %.2f | evidence coverage: %.0f%% | unverified claims: %d", payload.Confidence, payload.EvidenceCoverage*100, len(payload.UnverifiedClaims)))
+			if r.verbose {
+				for _, claim := range payload.UnverifiedClaims {
+					fmt.Fprintf(r.w, "  - %s\n", claim)
+				}
 			}
-			fmt.Fprintln(r.w, payload.Answer)
+		}
+	case events.RunFinished:
+		if payload, ok := event.Payload.(events.RunFinishedPayload); ok {
+			if r.quiet {
+				if r.attribution {
+					fmt.Fprintln(r.w, Attribution(payload.ToolCalls))
+				}
+				return
+			}
+			fmt.Fprintln(r.w, runFooter(payload))
+		}
+	case events.Warning:
+		if payload, ok := event.Payload.(events.WarningPayload); ok {
+			if r.quiet {
+				return
+			}
+			fmt.Fprintln(r.w, i18n.Sprintf("warning: %s", payload.Message))
 		}
 	case events.RunError:
 		if payload, ok := event.Payload.(events.RunErrorPayload); ok {
-			fmt.Fprintf(r.w, "\nError: %s\n", payload.Message)
+			if payload.Severity == events.SeverityRecoverable {
+				if !r.quiet {
+					fmt.Fprintln(r.w, i18n.Sprintf("warning: %s error, recovering (%s): %s", payload.Kind, strings.ReplaceAll(payload.Recovery, "_", " "), payload.Message))
+				}
+				return
+			}
+			fmt.Fprintln(r.w, "\n"+i18n.Sprintf("Error: %s", payload.Message))
+		}
+	}
+}
+
+// runFooter summarizes a finished run on one line, e.g.
+// "(2 steps, tools: grep 1, 1.4s, 5210 tokens, ~$0.0042)".
+func runFooter(payload events.RunFinishedPayload) string {
+	var parts []string
+	if payload.Status != "" && payload.Status != "success" {
+		parts = append(parts, payload.Status)
+	}
+	parts = append(parts, i18n.Plural(payload.StepsUsed, "%d step", "%d steps"))
+	if len(payload.ToolCalls) > 0 {
+		names := make([]string, 0, len(payload.ToolCalls))
+		for name := range payload.ToolCalls {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		calls := make([]string, 0, len(names))
+		for _, name := range names {
+			calls = append(calls, fmt.Sprintf("%s %d", name, payload.ToolCalls[name]))
 		}
+		parts = append(parts, i18n.Sprintf("tools: %s", strings.Join(calls, ", ")))
+	}
+	parts = append(parts, fmt.Sprintf("%.1fs", float64(payload.DurationMs)/1000))
+	if tokens := payload.PromptTokens + payload.CompletionTokens; tokens > 0 {
+		parts = append(parts, i18n.Sprintf("%d tokens", tokens))
+	}
+	if payload.CostUSD > 0 {
+		parts = append(parts, fmt.Sprintf("~$%.4f", payload.CostUSD))
+	}
+	return "(" + strings.Join(parts, ", ") + ")"
+}
+
+// Attribution counts the tool calls behind an answer, most used first, e.g.
+// "(3 tool calls: grep×2, shell×1)".
+func Attribution(toolCalls map[string]int) string {
+	total := 0
+	names := make([]string, 0, len(toolCalls))
+	for name, count := range toolCalls {
+		total += count
+		names = append(names, name)
+	}
+	if total == 0 {
+		return i18n.T("(no tool calls)")
+	}
+	sort.Slice(names, func(i, j int) bool {
+		if toolCalls[names[i]] != toolCalls[names[j]] {
+			return toolCalls[names[i]] > toolCalls[names[j]]
+		}
+		return names[i] < names[j]
+	})
+	calls := make([]string, 0, len(names))
+	for _, name := range names {
+		calls = append(calls, fmt.Sprintf("%s×%d", name, toolCalls[name]))
+	}
+	return "(" + i18n.Plural(total, "%d tool call", "%d tool calls") + ": " + strings.Join(calls, ", ") + ")"
+}
+
+// writeCitations lists the tool call behind each [T<n>] marker in the answer.
+func (r *StdoutRenderer) writeCitations(citations []events.Citation) {
+	if len(citations) == 0 {
+		return
+	}
+	fmt.Fprintln(r.w, i18n.T("sources:"))
+	for _, citation := range citations {
+		if citation.ToolCall < 0 {
+			fmt.Fprintf(r.w, "  [%s] %s\n", citation.ID, i18n.T("(no matching tool call)"))
+			continue
+		}
+		fmt.Fprintf(r.w, "  [%s] %s %s\n", citation.ID, citation.ToolName, truncate(FormatToolInput(citation.ToolName, citation.Input), 100))
+	}
+}
+
+func truncate(text string, limit int) string {
+	if len(text) <= limit {
+		return text
 	}
+	return util.CutBytes(text, limit) + "..."
 }
 
 func (r *StdoutRenderer) Close() error {
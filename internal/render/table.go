@@ -0,0 +1,135 @@
+package render
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+// Table is a markdown pipe table with markdown stripped from its cells.
+type Table struct {
+	Header []string
+	Rows   [][]string
+}
+
+var tableSeparatorPattern = regexp.MustCompile(`^\|?\s*:?-+:?\s*(\|\s*:?-+:?\s*)*\|?$`)
+
+// ParseTable reads the first pipe table in text. Short rows are padded to the
+// header's width; a row with more cells than the header is an error, since its
+// columns can no longer be told apart.
+func ParseTable(text string) (Table, error) {
+	lines := strings.Split(text, "\n")
+	for i := 0; i+1 < len(lines); i++ {
+		header := strings.TrimSpace(lines[i])
+		if !strings.Contains(header, "|") || !tableSeparatorPattern.MatchString(strings.TrimSpace(lines[i+1])) {
+			continue
+		}
+		table := Table{Header: tableCells(header)}
+		for n, name := range table.Header {
+			if name == "" {
+				return Table{}, fmt.Errorf("table column %d has no header", n+1)
+			}
+		}
+		for _, line := range lines[i+2:] {
+			line = strings.TrimSpace(line)
+			if !strings.Contains(line, "|") {
+				break
+			}
+			row := tableCells(line)
+			if len(row) > len(table.Header) {
+				return Table{}, fmt.Errorf("table row %d has %d cells for %d columns", len(table.Rows)+1, len(row), len(table.Header))
+			}
+			for len(row) < len(table.Header) {
+				row = append(row, "")
+			}
+			table.Rows = append(table.Rows, row)
+		}
+		if len(table.Rows) == 0 {
+			return Table{}, errors.New("table has no rows")
+		}
+		return table, nil
+	}
+	return Table{}, errors.New("answer has no markdown table")
+}
+
+// tableCells splits a table line on pipes that are not escaped as \|.
+func tableCells(line string) []string {
+	line = strings.TrimPrefix(line, "|")
+	if strings.HasSuffix(line, "|") && !strings.HasSuffix(line, `\|`) {
+		line = strings.TrimSuffix(line, "|")
+	}
+	var cells []string
+	var cell strings.Builder
+	for i := 0; i < len(line); i++ {
+		switch {
+		case line[i] == '\\' && i+1 < len(line) && line[i+1] == '|':
+			cell.WriteByte('|')
+			i++
+		case line[i] == '|':
+			cells = append(cells, tableCell(cell.String()))
+			cell.Reset()
+		default:
+			cell.WriteByte(line[i])
+		}
+	}
+	return append(cells, tableCell(cell.String()))
+}
+
+func tableCell(text string) string {
+	text = strings.ReplaceAll(text, "<br>", " ")
+	return strings.Join(strings.Fields(StripMarkdown(text)), " ")
+}
+
+// Aligned renders the table as space-padded columns.
+func (t Table) Aligned() string {
+	widths := make([]int, len(t.Header))
+	for _, row := range append([][]string{t.Header}, t.Rows...) {
+		for i, cell := range row {
+			widths[i] = max(widths[i], utf8.RuneCountInString(cell))
+		}
+	}
+	var b strings.Builder
+	for _, row := range append([][]string{t.Header}, t.Rows...) {
+		var line strings.Builder
+		for i, cell := range row {
+			line.WriteString(cell)
+			if i < len(row)-1 {
+				line.WriteString(strings.Repeat(" ", widths[i]-utf8.RuneCountInString(cell)+2))
+			}
+		}
+		b.WriteString(strings.TrimRight(line.String(), " ") + "\n")
+	}
+	return strings.TrimSuffix(b.String(), "\n")
+}
+
+// TSV renders the table as tab-separated values with a header line. Tabs
+// inside cells become spaces.
+func (t Table) TSV() string {
+	var b strings.Builder
+	for _, row := range append([][]string{t.Header}, t.Rows...) {
+		cells := make([]string, len(row))
+		for i, cell := range row {
+			cells[i] = strings.ReplaceAll(cell, "\t", " ")
+		}
+		b.WriteString(strings.Join(cells, "\t") + "\n")
+	}
+	return strings.TrimSuffix(b.String(), "\n")
+}
+
+// TableProcessor replaces the answer with its first markdown table, printed
+// as aligned columns or, for tsv, as tab-separated values. Text around the
+// table is dropped.
+func TableProcessor(tsv bool) AnswerProcessor {
+	return AnswerProcessorFunc(func(answer string) (string, error) {
+		table, err := ParseTable(answer)
+		if err != nil {
+			return answer, err
+		}
+		if tsv {
+			return table.TSV(), nil
+		}
+		return table.Aligned(), nil
+	})
+}
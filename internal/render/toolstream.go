@@ -0,0 +1,44 @@
+package render
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sync"
+
+	"fi-cli/internal/events"
+	"fi-cli/internal/util"
+)
+
+// ToolStreamRenderer writes only tool call, warning, and error events as NDJSON,
+// then the plain final answer, for wrappers that audit tool use without parsing
+// every event.
+type ToolStreamRenderer struct {
+	mu  sync.Mutex
+	w   io.Writer
+	enc *json.Encoder
+}
+
+// NewToolStreamRenderer creates a tool-only NDJSON renderer.
+func NewToolStreamRenderer(w io.Writer) *ToolStreamRenderer {
+	return &ToolStreamRenderer{w: w, enc: json.NewEncoder(w)}
+}
+
+func (r *ToolStreamRenderer) Emit(event events.Event) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	switch event.Type {
+	case events.ToolCallStarted, events.ToolCallFinished, events.ToolCallFailed:
+		_ = r.enc.Encode(event)
+	case events.FinalAnswerReady:
+		if payload, ok := event.Payload.(events.FinalAnswerPayload); ok {
+			fmt.Fprintln(r.w, util.SanitizeTerminal(payload.Answer))
+		}
+	case events.RunError, events.Warning:
+		_ = r.enc.Encode(event)
+	}
+}
+
+func (r *ToolStreamRenderer) Close() error {
+	return nil
+}
@@ -0,0 +1,45 @@
+package repo
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// apiSchemaDirs are where API contracts usually live, relative to the root.
+var apiSchemaDirs = []string{".", "api", "docs", "spec", "specs", "schema", "openapi", "graphql"}
+
+var apiSchemaNames = []string{
+	"openapi.yaml", "openapi.yml", "openapi.json",
+	"swagger.yaml", "swagger.yml", "swagger.json",
+	"schema.graphql", "schema.graphqls", "schema.gql",
+}
+
+// FindAPISchemas returns repo-relative paths (slash-separated) of OpenAPI and
+// GraphQL schema files in the usual locations, in search order.
+func FindAPISchemas(repoRoot string) []string {
+	var found []string
+	for _, dir := range apiSchemaDirs {
+		entries, err := os.ReadDir(filepath.Join(repoRoot, dir))
+		if err != nil {
+			continue
+		}
+		for _, entry := range entries {
+			if entry.IsDir() || !isAPISchemaName(entry.Name()) {
+				continue
+			}
+			found = append(found, filepath.ToSlash(filepath.Join(dir, entry.Name())))
+		}
+	}
+	return found
+}
+
+func isAPISchemaName(name string) bool {
+	lower := strings.ToLower(name)
+	for _, candidate := range apiSchemaNames {
+		if lower == candidate {
+			return true
+		}
+	}
+	return strings.HasSuffix(lower, ".openapi.yaml") || strings.HasSuffix(lower, ".openapi.json")
+}
@@ -0,0 +1,145 @@
+package repo
+
+import (
+	"bytes"
+	"math"
+	"path"
+	"path/filepath"
+	"strings"
+	"unicode/utf8"
+)
+
+// Content kinds that are omitted from prompt snippets.
+const (
+	KindBinary      = "binary"
+	KindMinified    = "minified"
+	KindGenerated   = "generated"
+	KindHighEntropy = "high-entropy"
+)
+
+var lockfiles = map[string]bool{
+	"package-lock.json": true,
+	"yarn.lock":         true,
+	"pnpm-lock.yaml":    true,
+	"go.sum":            true,
+	"cargo.lock":        true,
+	"poetry.lock":       true,
+	"composer.lock":     true,
+	"gemfile.lock":      true,
+	"bun.lockb":         true,
+}
+
+var generatedSuffixes = []string{".min.js", ".min.css", ".js.map", ".css.map", ".pb.go", "_pb2.py", ".g.dart", ".designer.cs"}
+
+var generatedMarkers = [][]byte{
+	[]byte("code generated"),
+	[]byte("do not edit"),
+	[]byte("@generated"),
+	[]byte("autogenerated"),
+	[]byte("auto-generated"),
+}
+
+// ClassifyContent reports why a file should not be snippeted (one of the
+// Kind constants), or "" if its contents are useful prose or source. rel is
+// the repo-relative path; data may be a prefix of the file.
+func ClassifyContent(rel string, data []byte) string {
+	base := strings.ToLower(filepath.Base(rel))
+	if lockfiles[base] {
+		return KindGenerated
+	}
+	for _, suffix := range generatedSuffixes {
+		if strings.HasSuffix(base, suffix) {
+			return KindGenerated
+		}
+	}
+	if strings.Contains(base, ".generated.") || strings.HasSuffix(strings.TrimSuffix(base, path.Ext(base)), "_generated") {
+		return KindGenerated
+	}
+	if len(data) == 0 {
+		return ""
+	}
+	if bytes.IndexByte(data, 0) != -1 || !validUTF8Prefix(data) {
+		return KindBinary
+	}
+	if hasGeneratedHeader(data) {
+		return KindGenerated
+	}
+	if isMinified(data) {
+		return KindMinified
+	}
+	if isHighEntropy(data) {
+		return KindHighEntropy
+	}
+	return ""
+}
+
+// validUTF8Prefix tolerates a rune cut off by a byte limit.
+func validUTF8Prefix(data []byte) bool {
+	for i := 0; i < utf8.UTFMax && len(data) > 0; i++ {
+		if utf8.Valid(data) {
+			return true
+		}
+		data = data[:len(data)-1]
+	}
+	return utf8.Valid(data)
+}
+
+func hasGeneratedHeader(data []byte) bool {
+	head := data
+	for i, n := 0, 0; i < len(data); i++ {
+		if data[i] == '\n' {
+			n++
+			if n == 5 {
+				head = data[:i]
+				break
+			}
+		}
+	}
+	head = bytes.ToLower(head)
+	for _, marker := range generatedMarkers {
+		if bytes.Contains(head, marker) {
+			return true
+		}
+	}
+	return false
+}
+
+// isMinified flags bundles: long lines with little line structure.
+func isMinified(data []byte) bool {
+	if len(data) < 1024 {
+		return false
+	}
+	lines := bytes.Split(data, []byte("\n"))
+	longest := 0
+	for _, line := range lines {
+		if len(line) > longest {
+			longest = len(line)
+		}
+	}
+	return longest > 1000 || len(data)/len(lines) > 300
+}
+
+// isHighEntropy flags embedded base64/compressed blobs, which carry no
+// readable signal but cost many tokens.
+func isHighEntropy(data []byte) bool {
+	if len(data) < 512 {
+		return false
+	}
+	var counts [256]int
+	spaces := 0
+	for _, b := range data {
+		counts[b]++
+		if b == ' ' || b == '\n' || b == '\t' {
+			spaces++
+		}
+	}
+	entropy := 0.0
+	for _, c := range counts {
+		if c == 0 {
+			continue
+		}
+		p := float64(c) / float64(len(data))
+		entropy -= p * math.Log2(p)
+	}
+	return entropy > 5.5 && float64(spaces)/float64(len(data)) < 0.02
+}
@@ -0,0 +1,418 @@
+package repo
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+
+	"fi-cli/internal/util"
+
+	"go.yaml.in/yaml/v3"
+)
+
+const (
+	// maxCommandsPerSource caps how many targets one file contributes.
+	maxCommandsPerSource = 40
+	maxCommandBody       = 120
+)
+
+// Command is one runnable entry point: a Makefile, justfile, Taskfile, or
+// Earthfile target, or a package script.
+type Command struct {
+	// Run is how the command is invoked, e.g. "make build" or "pnpm test".
+	Run string
+	// Source is the repo-relative file that defines it.
+	Source string
+	Line   int
+	// Body is the first line of what the command runs (or its prerequisites).
+	Body string
+}
+
+// commandSource parses one kind of command file at the repo root.
+type commandSource struct {
+	names []string
+	parse func(repoRoot, path string) []Command
+}
+
+var commandSources = []commandSource{
+	{names: []string{"GNUmakefile", "makefile", "Makefile"}, parse: parseMakefile},
+	{names: []string{"package.json"}, parse: parsePackageScripts},
+	{names: []string{"justfile", "Justfile", ".justfile"}, parse: parseJustfile},
+	{names: []string{"Taskfile.yml", "taskfile.yml", "Taskfile.yaml", "taskfile.yaml", "Taskfile.dist.yml", "Taskfile.dist.yaml"}, parse: parseTaskfile},
+	{names: []string{"Earthfile"}, parse: parseEarthfile},
+}
+
+// FindCommands enumerates the commands defined by the root's task-runner
+// files (see commandSources), in file order.
+func FindCommands(repoRoot string) []Command {
+	var commands []Command
+	for _, source := range commandSources {
+		for _, name := range source.names {
+			path := filepath.Join(repoRoot, name)
+			if info, err := os.Stat(path); err != nil || info.IsDir() || IsDenylisted(path) {
+				continue
+			}
+			found := source.parse(repoRoot, path)
+			if len(found) > maxCommandsPerSource {
+				found = found[:maxCommandsPerSource]
+			}
+			commands = append(commands, found...)
+			// each runner reads only the first of its file names
+			break
+		}
+	}
+	return commands
+}
+
+var (
+	makeTargetPattern = regexp.MustCompile(`^([A-Za-z0-9_./-][A-Za-z0-9_./ -]*?)\s*:([^=].*)?$`)
+	makeHelpPattern   = regexp.MustCompile(`\s##\s*(.+)$`)
+)
+
+func parseMakefile(repoRoot, path string) []Command {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil
+	}
+	defer file.Close()
+
+	source := filepath.Base(path)
+	var commands []Command
+	// deps holds each target's prerequisites, the body of last resort
+	var deps []string
+	index := map[string]int{}
+	var current []int
+	scanner := bufio.NewScanner(file)
+	for lineNo := 1; scanner.Scan(); lineNo++ {
+		line := scanner.Text()
+		if strings.HasPrefix(line, "\t") {
+			for _, i := range current {
+				if commands[i].Body == "" {
+					commands[i].Body = strings.TrimLeft(strings.TrimSpace(line), "@-+")
+				}
+			}
+			current = nil
+			continue
+		}
+		current = nil
+		match := makeTargetPattern.FindStringSubmatch(line)
+		// ::= assignments and target-specific variables are not rules
+		if match == nil || strings.HasPrefix(match[2], ":=") || strings.Contains(match[2], "=") && !strings.Contains(match[2], "##") {
+			continue
+		}
+		rest := strings.TrimPrefix(match[2], ":")
+		help := ""
+		if m := makeHelpPattern.FindStringSubmatchIndex(rest); m != nil {
+			help, rest = strings.TrimSpace(rest[m[2]:m[3]]), rest[:m[0]]
+		}
+		prereqs, _, _ := strings.Cut(rest, "#")
+		prereqs, recipe, _ := strings.Cut(prereqs, ";")
+		for _, target := range strings.Fields(match[1]) {
+			// special targets (.PHONY) and pattern rules are not entry points
+			if strings.HasPrefix(target, ".") || strings.Contains(target, "%") {
+				continue
+			}
+			i, seen := index[target]
+			if !seen {
+				i = len(commands)
+				index[target] = i
+				commands = append(commands, Command{Run: "make " + target, Source: source, Line: lineNo})
+				deps = append(deps, "")
+			}
+			if commands[i].Body == "" {
+				commands[i].Body = help
+			}
+			if commands[i].Body == "" {
+				commands[i].Body = strings.TrimSpace(recipe)
+			}
+			if deps[i] == "" {
+				deps[i] = strings.Join(strings.Fields(prereqs), " ")
+			}
+			current = append(current, i)
+		}
+	}
+	return finishCommands(commands, deps)
+}
+
+func parsePackageScripts(repoRoot, path string) []Command {
+	raw := readFileLimited(path, 1<<20)
+	var data struct {
+		PackageManager string            `json:"packageManager"`
+		Scripts        map[string]string `json:"scripts"`
+	}
+	if raw == "" || json.Unmarshal([]byte(raw), &data) != nil {
+		return nil
+	}
+	runner := packageRunner(repoRoot, data.PackageManager)
+	var commands []Command
+	for name, body := range data.Scripts {
+		commands = append(commands, Command{Run: runner + " " + name, Source: "package.json", Line: jsonKeyLine(raw, name), Body: commandBody(body)})
+	}
+	sort.Slice(commands, func(i, j int) bool { return commands[i].Line < commands[j].Line })
+	return commands
+}
+
+// packageRunner picks the package manager's run command from packageManager
+// or the lockfile present.
+func packageRunner(repoRoot, packageManager string) string {
+	manager, _, _ := strings.Cut(packageManager, "@")
+	if manager == "" {
+		for _, lock := range []struct{ file, manager string }{{"pnpm-lock.yaml", "pnpm"}, {"yarn.lock", "yarn"}, {"bun.lockb", "bun"}, {"bun.lock", "bun"}} {
+			if _, err := os.Stat(filepath.Join(repoRoot, lock.file)); err == nil {
+				manager = lock.manager
+				break
+			}
+		}
+	}
+	switch manager {
+	case "pnpm", "yarn", "bun":
+		return manager
+	}
+	return "npm run"
+}
+
+// jsonKeyLine finds the line of a script's key in the raw package.json, for
+// citations and for keeping the file's order.
+func jsonKeyLine(raw, key string) int {
+	quoted, _ := json.Marshal(key)
+	scripts := strings.Index(raw, `"scripts"`)
+	if scripts < 0 {
+		return 0
+	}
+	offset := strings.Index(raw[scripts:], string(quoted)+":")
+	if offset < 0 {
+		offset = strings.Index(raw[scripts:], string(quoted))
+	}
+	if offset < 0 {
+		return 0
+	}
+	return strings.Count(raw[:scripts+offset], "\n") + 1
+}
+
+func commandBody(body string) string {
+	body = strings.TrimSpace(body)
+	if first, _, multi := strings.Cut(body, "\n"); multi {
+		body = strings.TrimSpace(first) + " ..."
+	}
+	if len(body) > maxCommandBody {
+		body = util.CutBytes(body, maxCommandBody) + "..."
+	}
+	return util.RedactSecrets(body)
+}
+
+// String renders the command for the context summary.
+func (c Command) String() string {
+	text := fmt.Sprintf("%s (%s:%d)", c.Run, c.Source, c.Line)
+	if c.Body != "" {
+		text += ": " + c.Body
+	}
+	return text
+}
+
+var (
+	justRecipePattern = regexp.MustCompile(`^@?([A-Za-z_][A-Za-z0-9_-]*)(\s[^:]*)?:(.*)$`)
+	justKeywords      = []string{"set", "alias", "export", "import", "mod"}
+)
+
+// parseJustfile lists just recipes. A recipe's doc comment (the comment line
+// right above it) is preferred as its body; private recipes are skipped.
+func parseJustfile(repoRoot, path string) []Command {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil
+	}
+	defer file.Close()
+
+	source := filepath.Base(path)
+	var commands []Command
+	var deps []string
+	current := -1
+	doc, private := "", false
+	scanner := bufio.NewScanner(file)
+	for lineNo := 1; scanner.Scan(); lineNo++ {
+		line := scanner.Text()
+		if strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t") {
+			body := strings.TrimLeft(strings.TrimSpace(line), "@-")
+			if current >= 0 && commands[current].Body == "" && body != "" && !strings.HasPrefix(body, "#") {
+				commands[current].Body = body
+			}
+			continue
+		}
+		current = -1
+		trimmed := strings.TrimSpace(line)
+		switch {
+		case trimmed == "":
+			doc, private = "", false
+			continue
+		case strings.HasPrefix(trimmed, "#"):
+			if !strings.HasPrefix(trimmed, "#!") {
+				doc = strings.TrimSpace(strings.TrimPrefix(trimmed, "#"))
+			}
+			continue
+		case strings.HasPrefix(trimmed, "["):
+			private = private || strings.Contains(trimmed, "private")
+			continue
+		}
+		match := justRecipePattern.FindStringSubmatch(line)
+		keyword, _, _ := strings.Cut(trimmed, " ")
+		// := assignments and settings are not recipes
+		if match != nil && !strings.HasPrefix(match[3], "=") && !slices.Contains(justKeywords, keyword) && !private && !strings.HasPrefix(match[1], "_") {
+			current = len(commands)
+			commands = append(commands, Command{Run: "just " + match[1], Source: source, Line: lineNo, Body: doc})
+			deps = append(deps, strings.Join(strings.Fields(match[3]), " "))
+		}
+		doc, private = "", false
+	}
+	return finishCommands(commands, deps)
+}
+
+// parseTaskfile lists go-task tasks, skipping internal ones. The body is the
+// task's desc, else its first command, else its deps.
+func parseTaskfile(repoRoot, path string) []Command {
+	raw := readFileLimited(path, 1<<20)
+	var doc yaml.Node
+	if raw == "" || yaml.Unmarshal([]byte(raw), &doc) != nil || len(doc.Content) == 0 {
+		return nil
+	}
+	tasks := yamlValue(doc.Content[0], "tasks")
+	if tasks == nil || tasks.Kind != yaml.MappingNode {
+		return nil
+	}
+	source := filepath.Base(path)
+	var commands []Command
+	var deps []string
+	for i := 0; i+1 < len(tasks.Content); i += 2 {
+		key, task := tasks.Content[i], tasks.Content[i+1]
+		command := Command{Run: "task " + key.Value, Source: source, Line: key.Line}
+		var taskDeps []string
+		switch task.Kind {
+		case yaml.ScalarNode:
+			command.Body = task.Value
+		case yaml.SequenceNode:
+			command.Body = taskCommand(task)
+		case yaml.MappingNode:
+			if internal := yamlValue(task, "internal"); internal != nil && internal.Value == "true" {
+				continue
+			}
+			if desc := yamlValue(task, "desc"); desc != nil {
+				command.Body = desc.Value
+			}
+			if command.Body == "" {
+				command.Body = taskCommand(yamlValue(task, "cmds"))
+			}
+			if cmd := yamlValue(task, "cmd"); command.Body == "" && cmd != nil {
+				command.Body = cmd.Value
+			}
+			if list := yamlValue(task, "deps"); list != nil && list.Kind == yaml.SequenceNode {
+				for _, dep := range list.Content {
+					if name := yamlValue(dep, "task"); name != nil {
+						dep = name
+					}
+					taskDeps = append(taskDeps, dep.Value)
+				}
+			}
+		}
+		commands = append(commands, command)
+		deps = append(deps, strings.Join(taskDeps, " "))
+	}
+	return finishCommands(commands, deps)
+}
+
+// taskCommand renders the first entry of a Taskfile cmds list, which is a
+// shell line or a {cmd: ...} / {task: ...} mapping.
+func taskCommand(cmds *yaml.Node) string {
+	if cmds == nil || cmds.Kind != yaml.SequenceNode || len(cmds.Content) == 0 {
+		return ""
+	}
+	first := cmds.Content[0]
+	if cmd := yamlValue(first, "cmd"); cmd != nil {
+		return cmd.Value
+	}
+	if task := yamlValue(first, "task"); task != nil {
+		return "task " + task.Value
+	}
+	return first.Value
+}
+
+func yamlValue(node *yaml.Node, key string) *yaml.Node {
+	if node == nil || node.Kind != yaml.MappingNode {
+		return nil
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			return node.Content[i+1]
+		}
+	}
+	return nil
+}
+
+var earthTargetPattern = regexp.MustCompile(`^([a-z][A-Za-z0-9.-]*):\s*$`)
+
+// parseEarthfile lists Earthly targets. A target's doc comment is preferred
+// as its body, else its first instruction after FROM.
+func parseEarthfile(repoRoot, path string) []Command {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil
+	}
+	defer file.Close()
+
+	var commands []Command
+	var from []string
+	current := -1
+	doc := ""
+	scanner := bufio.NewScanner(file)
+	for lineNo := 1; scanner.Scan(); lineNo++ {
+		line := scanner.Text()
+		trimmed := strings.TrimSpace(line)
+		if trimmed != "" && (strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t")) {
+			if current < 0 || commands[current].Body != "" || strings.HasPrefix(trimmed, "#") {
+				continue
+			}
+			if instruction, _, _ := strings.Cut(trimmed, " "); strings.EqualFold(instruction, "FROM") {
+				from[current] = trimmed
+				continue
+			}
+			commands[current].Body = trimmed
+			continue
+		}
+		if strings.HasPrefix(trimmed, "#") {
+			doc = strings.TrimSpace(strings.TrimPrefix(trimmed, "#"))
+			continue
+		}
+		if match := earthTargetPattern.FindStringSubmatch(line); match != nil {
+			current = len(commands)
+			commands = append(commands, Command{Run: "earthly +" + match[1], Source: "Earthfile", Line: lineNo, Body: doc})
+			from = append(from, "")
+		} else if trimmed != "" {
+			// top-level VERSION, ARG, and IMPORT lines end the previous target
+			current = -1
+		}
+		doc = ""
+	}
+	for i := range commands {
+		if commands[i].Body == "" {
+			commands[i].Body = from[i]
+		}
+		commands[i].Body = commandBody(commands[i].Body)
+	}
+	return commands
+}
+
+// finishCommands falls back to a command's dependencies for its body and
+// shortens every body to one line.
+func finishCommands(commands []Command, deps []string) []Command {
+	for i := range commands {
+		if commands[i].Body == "" && deps[i] != "" {
+			commands[i].Body = "depends on " + deps[i]
+		}
+		commands[i].Body = commandBody(commands[i].Body)
+	}
+	return commands
+}
@@ -23,17 +23,27 @@ type FileSnippet struct {
 	Path      string
 	Snippet   string
 	Truncated bool
+	// Omitted names the content kind (see ClassifyContent) when the file was
+	// summarized instead of snippeted.
+	Omitted string
+	Size    int64
 }
 
 // RepoContext summarizes repository metadata for prompting.
 type RepoContext struct {
 	RepoRoot            string
 	TopLevel            []string
+	TopLevelDirs        map[string]bool
 	KeyFiles            map[string]bool
 	FrameworkIndicators map[string]bool
 	Snippets            []FileSnippet
-	Warnings            []string
-	Bytes               int
+	// APISchemas lists OpenAPI and GraphQL contracts found by FindAPISchemas.
+	APISchemas []string
+	// Commands lists the Makefile targets and package scripts found by
+	// FindCommands.
+	Commands []Command
+	Warnings []string
+	Bytes    int
 }
 
 // BuildContext gathers repo metadata and file snippets.
@@ -42,12 +52,16 @@ func BuildContext(repoRoot string, limits Limits) (RepoContext, error) {
 		RepoRoot:            repoRoot,
 		KeyFiles:            map[string]bool{},
 		FrameworkIndicators: map[string]bool{},
+		TopLevelDirs:        map[string]bool{},
 	}
 
 	entries, err := os.ReadDir(repoRoot)
 	if err == nil {
 		for _, entry := range entries {
 			ctx.TopLevel = append(ctx.TopLevel, entry.Name())
+			if entry.IsDir() {
+				ctx.TopLevelDirs[entry.Name()] = true
+			}
 		}
 		sort.Strings(ctx.TopLevel)
 	}
@@ -143,6 +157,18 @@ func BuildContext(repoRoot string, limits Limits) (RepoContext, error) {
 		_ = ctx.addSnippet(path, readFileLimited(path, limits.MaxFileBytes), limits)
 	}
 
+	// top-level notebooks are often a data repo's entry point; they are
+	// listed only when present so other repos' summaries stay unchanged
+	if matches, _ := filepath.Glob(filepath.Join(repoRoot, "*.ipynb")); len(matches) > 0 {
+		ctx.KeyFiles["*.ipynb"] = true
+		for _, match := range matches[:min(len(matches), maxNotebookSnippets)] {
+			_ = ctx.addSnippet(match, readFileLimited(match, limits.MaxFileBytes), limits)
+		}
+	}
+
+	ctx.APISchemas = FindAPISchemas(repoRoot)
+	ctx.Commands = FindCommands(repoRoot)
+
 	if ctx.KeyFiles[".env.example"] {
 		ctx.Warnings = append(ctx.Warnings, "Detected .env.example but contents are redacted by denylist policy.")
 	}
@@ -155,6 +181,14 @@ func (c *RepoContext) addSnippet(path string, raw string, limits Limits) error {
 		return nil
 	}
 	rel, _ := filepath.Rel(c.RepoRoot, path)
+	if kind := ClassifyContent(rel, []byte(raw)); kind != "" {
+		var size int64
+		if info, err := os.Stat(path); err == nil {
+			size = info.Size()
+		}
+		c.Snippets = append(c.Snippets, FileSnippet{Path: rel, Omitted: kind, Size: size})
+		return nil
+	}
 	redacted := util.RedactSecrets(raw)
 	truncated := false
 	if limits.ContextMaxBytes > 0 {
@@ -163,7 +197,7 @@ func (c *RepoContext) addSnippet(path string, raw string, limits Limits) error {
 			return nil
 		}
 		if len(redacted) > remaining {
-			redacted = redacted[:remaining]
+			redacted, _ = util.TruncateBytes(redacted, remaining)
 			truncated = true
 		}
 		c.Bytes += len(redacted)
@@ -172,23 +206,59 @@ func (c *RepoContext) addSnippet(path string, raw string, limits Limits) error {
 	return nil
 }
 
+// LoadSnippet re-reads one repo-relative file with the same denylist,
+// classification, and redaction rules as BuildContext. A missing file yields
+// a snippet marked Omitted "deleted".
+func LoadSnippet(repoRoot, rel string, maxBytes int) FileSnippet {
+	path := filepath.Join(repoRoot, rel)
+	if IsDenylisted(path) {
+		return FileSnippet{Path: rel, Omitted: "denylisted"}
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return FileSnippet{Path: rel, Omitted: "deleted"}
+	}
+	var raw string
+	var truncated bool
+	if IsNotebook(path) {
+		raw, truncated = readNotebook(path, snippetLimit(maxBytes))
+	} else {
+		raw = readFileLimited(path, maxBytes)
+		truncated = int64(len(raw)) < info.Size()
+	}
+	if kind := ClassifyContent(rel, []byte(raw)); kind != "" {
+		return FileSnippet{Path: rel, Omitted: kind, Size: info.Size()}
+	}
+	return FileSnippet{Path: rel, Snippet: util.RedactSecrets(raw), Truncated: truncated, Size: info.Size()}
+}
+
+// readFileLimited reads up to maxBytes of path. Notebooks are rendered as
+// source and markdown first, since their raw JSON is mostly outputs.
 func readFileLimited(path string, maxBytes int) string {
 	if IsDenylisted(path) {
 		return ""
 	}
+	limit := snippetLimit(maxBytes)
+	if IsNotebook(path) {
+		rendered, _ := readNotebook(path, limit)
+		return rendered
+	}
 	file, err := os.Open(path)
 	if err != nil {
 		return ""
 	}
 	defer file.Close()
 
-	limit := maxBytes
-	if limit <= 0 {
-		limit = 32 * 1024
-	}
 	buf := make([]byte, limit)
 	n, _ := file.Read(buf)
-	return string(buf[:n])
+	return util.TrimPartialRune(string(buf[:n]))
+}
+
+func snippetLimit(maxBytes int) int {
+	if maxBytes <= 0 {
+		return 32 * 1024
+	}
+	return maxBytes
 }
 
 func readFirstLines(path string, maxLines int, maxBytes int) string {
@@ -249,11 +319,7 @@ func (c RepoContext) Summary() string {
 	b.WriteString(fmt.Sprintf("Repo root: %s\n", c.RepoRoot))
 	if len(c.TopLevel) > 0 {
 		b.WriteString("Top-level entries:\n")
-		for _, entry := range c.TopLevel {
-			b.WriteString("- ")
-			b.WriteString(entry)
-			b.WriteString("\n")
-		}
+		c.writeTopLevel(&b)
 	}
 	if len(c.KeyFiles) > 0 {
 		b.WriteString("Key files:\n")
@@ -277,10 +343,30 @@ func (c RepoContext) Summary() string {
 			b.WriteString(fmt.Sprintf("- %s: %t\n", k, c.FrameworkIndicators[k]))
 		}
 	}
+	if len(c.APISchemas) > 0 {
+		b.WriteString("API schemas:\n")
+		for _, schema := range c.APISchemas {
+			b.WriteString("- ")
+			b.WriteString(schema)
+			b.WriteString("\n")
+		}
+	}
+	if len(c.Commands) > 0 {
+		b.WriteString("Available commands:\n")
+		for _, command := range c.Commands {
+			b.WriteString("- ")
+			b.WriteString(command.String())
+			b.WriteString("\n")
+		}
+	}
 	if len(c.Snippets) > 0 {
 		b.WriteString("Snippets:\n")
 		for _, snip := range c.Snippets {
 			b.WriteString(fmt.Sprintf("--- %s", snip.Path))
+			if snip.Omitted != "" {
+				b.WriteString(fmt.Sprintf(" (%s, %d bytes; contents omitted) ---\n", snip.Omitted, snip.Size))
+				continue
+			}
 			if snip.Truncated {
 				b.WriteString(" (truncated)")
 			}
@@ -299,3 +385,67 @@ func (c RepoContext) Summary() string {
 	}
 	return b.String()
 }
+
+// maxTopLevelEntries caps the top-level listing; larger repos (vendored trees)
+// are sampled with directories first and the rest counted by extension.
+const maxTopLevelEntries = 60
+
+func (c RepoContext) writeTopLevel(b *strings.Builder) {
+	if len(c.TopLevel) <= maxTopLevelEntries {
+		for _, entry := range c.TopLevel {
+			b.WriteString("- ")
+			b.WriteString(entry)
+			b.WriteString("\n")
+		}
+		return
+	}
+	var dirs, files []string
+	for _, entry := range c.TopLevel {
+		if c.TopLevelDirs[entry] {
+			dirs = append(dirs, entry)
+		} else {
+			files = append(files, entry)
+		}
+	}
+	ordered := append(dirs, files...)
+	for _, entry := range ordered[:maxTopLevelEntries] {
+		b.WriteString("- ")
+		b.WriteString(entry)
+		if c.TopLevelDirs[entry] {
+			b.WriteString("/")
+		}
+		b.WriteString("\n")
+	}
+
+	hiddenDirs := 0
+	byExt := map[string]int{}
+	for _, entry := range ordered[maxTopLevelEntries:] {
+		if c.TopLevelDirs[entry] {
+			hiddenDirs++
+			continue
+		}
+		ext := strings.ToLower(filepath.Ext(entry))
+		if ext == "" {
+			ext = "(none)"
+		}
+		byExt[ext]++
+	}
+	exts := make([]string, 0, len(byExt))
+	for ext := range byExt {
+		exts = append(exts, ext)
+	}
+	sort.Slice(exts, func(i, j int) bool {
+		if byExt[exts[i]] != byExt[exts[j]] {
+			return byExt[exts[i]] > byExt[exts[j]]
+		}
+		return exts[i] < exts[j]
+	})
+	parts := make([]string, 0, len(exts)+1)
+	if hiddenDirs > 0 {
+		parts = append(parts, fmt.Sprintf("%d directories", hiddenDirs))
+	}
+	for _, ext := range exts {
+		parts = append(parts, fmt.Sprintf("%s: %d", ext, byExt[ext]))
+	}
+	b.WriteString(fmt.Sprintf("- ... %d more entries (%s); call list_files for the full listing\n", len(c.TopLevel)-maxTopLevelEntries, strings.Join(parts, ", ")))
+}
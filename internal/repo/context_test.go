@@ -1,8 +1,10 @@
 package repo
 
 import (
+	"fmt"
 	"os"
 	"path/filepath"
+	"reflect"
 	"strings"
 	"testing"
 )
@@ -64,3 +66,241 @@ func mustWriteFile(t *testing.T, path, content string) {
 		t.Fatalf("write failed: %v", err)
 	}
 }
+
+func TestClassifyContent(t *testing.T) {
+	cases := []struct {
+		rel  string
+		data string
+		want string
+	}{
+		{"main.go", "package main\n\nfunc main() {}\n", ""},
+		{"yarn.lock", "# yarn lockfile v1\n", KindGenerated},
+		{"dist/app.min.js", "x", KindGenerated},
+		{"api.go", "// Code generated by protoc-gen-go. DO NOT EDIT.\npackage api\n", KindGenerated},
+		{"bundle.js", strings.Repeat("var a=1;", 400), KindMinified},
+		{"logo.png", "\x89PNG\r\n\x1a\n\x00\x00", KindBinary},
+		{"blob.txt", highEntropyText(2048), KindHighEntropy},
+	}
+	for _, tc := range cases {
+		if got := ClassifyContent(tc.rel, []byte(tc.data)); got != tc.want {
+			t.Errorf("ClassifyContent(%q) = %q, want %q", tc.rel, got, tc.want)
+		}
+	}
+}
+
+func TestBuildContextOmitsLockfiles(t *testing.T) {
+	root := t.TempDir()
+	mustWriteFile(t, filepath.Join(root, "package-lock.json"), `{"lockfileVersion": 3}`)
+	ctx, err := BuildContext(root, Limits{ContextMaxBytes: 4096, MaxFileBytes: 1024})
+	if err != nil {
+		t.Fatalf("build context failed: %v", err)
+	}
+	if len(ctx.Snippets) != 1 || ctx.Snippets[0].Omitted != KindGenerated || ctx.Snippets[0].Snippet != "" {
+		t.Fatalf("expected lockfile to be summarized, got %+v", ctx.Snippets)
+	}
+	if !strings.Contains(ctx.Summary(), "package-lock.json (generated") {
+		t.Fatalf("expected omission note in summary")
+	}
+}
+
+func highEntropyText(n int) string {
+	const alphabet = "ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789+/"
+	var b strings.Builder
+	state := uint32(7)
+	for i := 0; i < n; i++ {
+		state = state*1103515245 + 12345
+		b.WriteByte(alphabet[(state>>16)%64])
+		if i%76 == 75 {
+			b.WriteByte('\n')
+		}
+	}
+	return b.String()
+}
+
+func TestSummarySamplesLargeTopLevel(t *testing.T) {
+	ctx := RepoContext{RepoRoot: "/repo", TopLevelDirs: map[string]bool{"vendor": true}}
+	ctx.TopLevel = append(ctx.TopLevel, "vendor")
+	for i := 0; i < 100; i++ {
+		ctx.TopLevel = append(ctx.TopLevel, fmt.Sprintf("file%03d.go", i))
+	}
+	ctx.TopLevel = append(ctx.TopLevel, "notes.md")
+	summary := ctx.Summary()
+	if !strings.Contains(summary, "- vendor/\n") {
+		t.Fatalf("expected directories to be listed first")
+	}
+	if !strings.Contains(summary, "42 more entries (.go: 41, .md: 1)") {
+		t.Fatalf("expected extension counts for hidden entries, got:\n%s", summary)
+	}
+}
+
+func TestNotebookSnippetsStripOutputs(t *testing.T) {
+	root := t.TempDir()
+	mustWriteFile(t, filepath.Join(root, "analysis.ipynb"), `{
+ "cells": [
+  {"cell_type": "markdown", "metadata": {}, "source": ["# Churn analysis\n", "Loads events."]},
+  {"cell_type": "code", "execution_count": 1, "metadata": {}, "outputs": [{"output_type": "display_data", "data": {"image/png": "`+strings.Repeat("iVBORw0KGgo", 2000)+`"}}], "source": "df = load_events()\ndf.head()"}
+ ],
+ "metadata": {"kernelspec": {"language": "python", "name": "python3"}},
+ "nbformat": 4,
+ "nbformat_minor": 5
+}`)
+	ctx, err := BuildContext(root, Limits{ContextMaxBytes: 8192, MaxFileBytes: 4096})
+	if err != nil {
+		t.Fatalf("build context: %v", err)
+	}
+	if !ctx.KeyFiles["*.ipynb"] || len(ctx.Snippets) != 1 {
+		t.Fatalf("expected one notebook snippet, got %+v", ctx.Snippets)
+	}
+	want := "# notebook language: python\n# %% [markdown]\n# Churn analysis\nLoads events.\n\n# %% [code]\ndf = load_events()\ndf.head()\n# (1 output(s) omitted)\n"
+	if got := ctx.Snippets[0].Snippet; got != want {
+		t.Fatalf("unexpected notebook snippet: %q", got)
+	}
+
+	snip := LoadSnippet(root, "analysis.ipynb", 30)
+	if !snip.Truncated || len(snip.Snippet) != 30 {
+		t.Fatalf("expected a truncated rendered snippet, got %+v", snip)
+	}
+}
+
+func TestBuildContextListsAPISchemas(t *testing.T) {
+	root := t.TempDir()
+	mustWriteFile(t, filepath.Join(root, "docs", "openapi.yaml"), "openapi: 3.0.0\n")
+	mustWriteFile(t, filepath.Join(root, "schema.graphql"), "type Query { ok: Boolean }\n")
+	mustWriteFile(t, filepath.Join(root, "docs", "guide.md"), "# Guide\n")
+	ctx, err := BuildContext(root, Limits{ContextMaxBytes: 4096, MaxFileBytes: 1024})
+	if err != nil {
+		t.Fatalf("build context: %v", err)
+	}
+	if strings.Join(ctx.APISchemas, ",") != "schema.graphql,docs/openapi.yaml" {
+		t.Fatalf("unexpected API schemas: %v", ctx.APISchemas)
+	}
+	if !strings.Contains(ctx.Summary(), "API schemas:\n- schema.graphql\n- docs/openapi.yaml\n") {
+		t.Fatalf("expected the schemas in the summary:\n%s", ctx.Summary())
+	}
+}
+
+func TestBuildContextListsCommands(t *testing.T) {
+	root := t.TempDir()
+	mustWriteFile(t, filepath.Join(root, "Makefile"), `GO ?= go
+BIN := bin/app
+.PHONY: build test lint
+all: build test
+
+build: ## Build the binary
+	$(GO) build -o $(BIN) ./cmd/app
+
+test:
+	@$(GO) test ./...
+	$(GO) vet ./...
+
+lint: VERBOSE = 1
+lint:
+	-golangci-lint run
+
+%.o: %.c
+	cc -c $<
+`)
+	mustWriteFile(t, filepath.Join(root, "package.json"), `{
+  "name": "web",
+  "scripts": {
+    "dev": "vite",
+    "build": "tsc && vite build"
+  }
+}`)
+	mustWriteFile(t, filepath.Join(root, "pnpm-lock.yaml"), "lockfileVersion: '9.0'\n")
+
+	ctx, err := BuildContext(root, Limits{ContextMaxBytes: 4096, MaxFileBytes: 1024})
+	if err != nil {
+		t.Fatalf("build context: %v", err)
+	}
+	want := []Command{
+		{Run: "make all", Source: "Makefile", Line: 4, Body: "depends on build test"},
+		{Run: "make build", Source: "Makefile", Line: 6, Body: "Build the binary"},
+		{Run: "make test", Source: "Makefile", Line: 9, Body: "$(GO) test ./..."},
+		{Run: "make lint", Source: "Makefile", Line: 14, Body: "golangci-lint run"},
+		{Run: "pnpm dev", Source: "package.json", Line: 4, Body: "vite"},
+		{Run: "pnpm build", Source: "package.json", Line: 5, Body: "tsc && vite build"},
+	}
+	if !reflect.DeepEqual(ctx.Commands, want) {
+		t.Fatalf("unexpected commands:\n%+v", ctx.Commands)
+	}
+	if !strings.Contains(ctx.Summary(), "Available commands:\n- make all (Makefile:4): depends on build test\n") {
+		t.Fatalf("expected the commands in the summary:\n%s", ctx.Summary())
+	}
+}
+
+func TestFindCommandsTaskRunners(t *testing.T) {
+	root := t.TempDir()
+	mustWriteFile(t, filepath.Join(root, "justfile"), `set dotenv-load
+version := "1.0"
+alias b := build
+
+default: build test
+
+# Build the binary
+build target='app':
+    go build -o bin/{{target}} ./cmd/{{target}}
+
+test *args:
+    @go test {{args}} ./...
+
+[private]
+helper:
+    echo hidden
+
+_secret:
+    echo hidden
+`)
+	mustWriteFile(t, filepath.Join(root, "Taskfile.yml"), `version: '3'
+tasks:
+  build:
+    desc: Build everything
+    cmds:
+      - go build ./...
+  test:
+    deps: [build]
+    cmds:
+      - cmd: go test ./...
+  ci:
+    deps:
+      - task: lint
+      - test
+  lint: golangci-lint run
+  setup:
+    internal: true
+    cmds: [go mod download]
+`)
+	mustWriteFile(t, filepath.Join(root, "Earthfile"), `VERSION 0.8
+FROM golang:1.24
+WORKDIR /src
+
+deps:
+    COPY go.mod go.sum ./
+    RUN go mod download
+
+# Builds the binary into ./bin
+build:
+    FROM +deps
+    RUN go build -o bin/app ./cmd/app
+    SAVE ARTIFACT bin/app
+
+base:
+    FROM alpine:3.20
+`)
+
+	want := []Command{
+		{Run: "just default", Source: "justfile", Line: 5, Body: "depends on build test"},
+		{Run: "just build", Source: "justfile", Line: 8, Body: "Build the binary"},
+		{Run: "just test", Source: "justfile", Line: 11, Body: "go test {{args}} ./..."},
+		{Run: "task build", Source: "Taskfile.yml", Line: 3, Body: "Build everything"},
+		{Run: "task test", Source: "Taskfile.yml", Line: 7, Body: "go test ./..."},
+		{Run: "task ci", Source: "Taskfile.yml", Line: 11, Body: "depends on lint test"},
+		{Run: "task lint", Source: "Taskfile.yml", Line: 15, Body: "golangci-lint run"},
+		{Run: "earthly +deps", Source: "Earthfile", Line: 5, Body: "COPY go.mod go.sum ./"},
+		{Run: "earthly +build", Source: "Earthfile", Line: 10, Body: "Builds the binary into ./bin"},
+		{Run: "earthly +base", Source: "Earthfile", Line: 15, Body: "FROM alpine:3.20"},
+	}
+	if got := FindCommands(root); !reflect.DeepEqual(got, want) {
+		t.Fatalf("unexpected commands:\n%+v", got)
+	}
+}
@@ -0,0 +1,151 @@
+package repo
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+)
+
+// KeyFiles are the files, relative to the repo root, whose changes most
+// often make earlier answers and facts wrong: manifests, lockfiles, build
+// entry points, and the shared knowledge file.
+var KeyFiles = []string{
+	"go.mod", "go.sum",
+	"package.json", "package-lock.json", "pnpm-lock.yaml", "yarn.lock",
+	"Cargo.toml", "Cargo.lock",
+	"pyproject.toml", "requirements.txt", "poetry.lock",
+	"Gemfile.lock", "pom.xml", "build.gradle",
+	"Makefile", "Dockerfile", "docker-compose.yml",
+	".fi/knowledge.yaml",
+}
+
+// Fingerprint identifies the state of a repository that cached artifacts
+// were derived from.
+type Fingerprint struct {
+	Head  string `json:"head,omitempty"`
+	Dirty bool   `json:"dirty,omitempty"`
+	// Changes hashes the uncommitted changes, so two different dirty trees
+	// at the same HEAD differ.
+	Changes string `json:"changes,omitempty"`
+	// Files hashes the key files present in the repo.
+	Files map[string]string `json:"files,omitempty"`
+}
+
+// FingerprintOf reads the fingerprint of repoRoot. Outside git only the key
+// files are hashed.
+func FingerprintOf(repoRoot string) Fingerprint {
+	fp := Fingerprint{Head: Head(repoRoot), Files: KeyFileHashes(repoRoot)}
+	if fp.Head == "" {
+		return fp
+	}
+	status := gitOutput(repoRoot, "status", "--porcelain", "-z", "--untracked-files=all")
+	if len(status) == 0 {
+		return fp
+	}
+	fp.Dirty = true
+	sum := sha256.New()
+	sum.Write(status)
+	sum.Write(gitOutput(repoRoot, "diff", "HEAD", "--no-ext-diff", "--binary"))
+	// git diff leaves out untracked files, so their edits show in size and mtime
+	for _, name := range untrackedPaths(status) {
+		if info, err := os.Lstat(filepath.Join(repoRoot, filepath.FromSlash(name))); err == nil && info.Mode().IsRegular() {
+			fmt.Fprintf(sum, "%s\x00%d\x00%d\x00", name, info.Size(), info.ModTime().UnixNano())
+		}
+	}
+	fp.Changes = hex.EncodeToString(sum.Sum(nil))[:16]
+	return fp
+}
+
+// untrackedPaths returns the untracked files in `git status --porcelain -z`
+// output.
+func untrackedPaths(status []byte) []string {
+	var paths []string
+	fields := strings.Split(strings.TrimSuffix(string(status), "\x00"), "\x00")
+	for i := 0; i < len(fields); i++ {
+		entry := fields[i]
+		if len(entry) < 4 {
+			continue
+		}
+		if strings.ContainsAny(entry[:2], "RC") {
+			// a rename or copy is followed by its source path
+			i++
+			continue
+		}
+		if entry[:2] == "??" {
+			paths = append(paths, entry[3:])
+		}
+	}
+	return paths
+}
+
+// KeyFileHashes returns a short content hash of each key file in repoRoot.
+func KeyFileHashes(repoRoot string) map[string]string {
+	files := map[string]string{}
+	for _, name := range KeyFiles {
+		data, err := os.ReadFile(filepath.Join(repoRoot, filepath.FromSlash(name)))
+		if err != nil {
+			continue
+		}
+		sum := sha256.Sum256(data)
+		files[name] = hex.EncodeToString(sum[:8])
+	}
+	return files
+}
+
+// Sum is a stable digest of the whole fingerprint; it changes whenever any
+// part of it does.
+func (f Fingerprint) Sum() string {
+	// json.Marshal sorts map keys, so equal fingerprints encode equally
+	payload, _ := json.Marshal(f)
+	sum := sha256.Sum256(payload)
+	return hex.EncodeToString(sum[:])
+}
+
+// Changed lists what differs between f and current: "HEAD",
+// "uncommitted changes", and the key files added, removed, or edited.
+func (f Fingerprint) Changed(current Fingerprint) []string {
+	var changed []string
+	if f.Head != current.Head {
+		changed = append(changed, "HEAD")
+	}
+	if f.Changes != current.Changes {
+		changed = append(changed, "uncommitted changes")
+	}
+	return append(changed, ChangedFiles(f.Files, current.Files)...)
+}
+
+// ChangedFiles lists the key files whose hashes differ between was and now,
+// sorted.
+func ChangedFiles(was, now map[string]string) []string {
+	var changed []string
+	for name, hash := range was {
+		if now[name] != hash {
+			changed = append(changed, name)
+		}
+	}
+	for name := range now {
+		if _, ok := was[name]; !ok {
+			changed = append(changed, name)
+		}
+	}
+	slices.Sort(changed)
+	return changed
+}
+
+func gitOutput(repoRoot string, args ...string) []byte {
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	out, err := exec.CommandContext(ctx, "git", append([]string{"-C", repoRoot}, args...)...).Output()
+	if err != nil {
+		return nil
+	}
+	return out
+}
@@ -0,0 +1,81 @@
+package repo
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestFingerprintChanges(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+	root := t.TempDir()
+	git := func(args ...string) {
+		t.Helper()
+		cmd := exec.Command("git", append([]string{"-C", root, "-c", "user.name=t", "-c", "user.email=t@example.com"}, args...)...)
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v: %v\n%s", args, err, out)
+		}
+	}
+	write := func(name, content string) {
+		t.Helper()
+		if err := os.WriteFile(filepath.Join(root, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if fp := FingerprintOf(root); fp.Head != "" || len(fp.Files) != 0 {
+		t.Fatalf("expected an empty fingerprint outside git, got %+v", fp)
+	}
+	git("init", "-q")
+	write("go.mod", "module app\n")
+	write("main.go", "package main\n")
+	git("add", ".")
+	git("commit", "-qm", "init")
+
+	clean := FingerprintOf(root)
+	if clean.Head == "" || clean.Dirty || clean.Files["go.mod"] == "" {
+		t.Fatalf("unexpected clean fingerprint %+v", clean)
+	}
+	if again := FingerprintOf(root); again.Sum() != clean.Sum() || again.Changed(clean) != nil {
+		t.Fatalf("expected an unchanged repo to fingerprint the same")
+	}
+
+	write("main.go", "package main\n\nfunc main() {}\n")
+	dirty := FingerprintOf(root)
+	if !dirty.Dirty || dirty.Sum() == clean.Sum() || !slices.Equal(clean.Changed(dirty), []string{"uncommitted changes"}) {
+		t.Fatalf("expected an edit to dirty the fingerprint, got %+v (%v)", dirty, clean.Changed(dirty))
+	}
+	write("main.go", "package main\n\nfunc main() { println() }\n")
+	if other := FingerprintOf(root); other.Changes == dirty.Changes {
+		t.Fatalf("expected a different edit to change the fingerprint")
+	}
+
+	write("notes.txt", "draft\n")
+	untracked := FingerprintOf(root)
+	write("notes.txt", "draft, edited\n")
+	if edited := FingerprintOf(root); edited.Changes == untracked.Changes {
+		t.Fatalf("expected an edit to an untracked file to change the fingerprint")
+	}
+	if err := os.Remove(filepath.Join(root, "notes.txt")); err != nil {
+		t.Fatal(err)
+	}
+
+	write("go.mod", "module app\n\ngo 1.24\n")
+	git("commit", "-qam", "bump")
+	bumped := FingerprintOf(root)
+	if !slices.Equal(clean.Changed(bumped), []string{"HEAD", "go.mod"}) {
+		t.Fatalf("unexpected changes %v", clean.Changed(bumped))
+	}
+}
+
+func TestChangedFiles(t *testing.T) {
+	was := map[string]string{"go.mod": "1", "go.sum": "2", "Makefile": "3"}
+	now := map[string]string{"go.mod": "1", "go.sum": "9", "Dockerfile": "4"}
+	if got := ChangedFiles(was, now); !slices.Equal(got, []string{"Dockerfile", "Makefile", "go.sum"}) {
+		t.Fatalf("unexpected changed files %v", got)
+	}
+}
@@ -0,0 +1,19 @@
+package repo
+
+import (
+	"context"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// Head returns the commit hash checked out in repoRoot, or "" outside git.
+func Head(repoRoot string) string {
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	out, err := exec.CommandContext(ctx, "git", "-C", repoRoot, "rev-parse", "HEAD").Output()
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(out))
+}
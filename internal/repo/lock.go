@@ -0,0 +1,68 @@
+package repo
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// ErrLocked is returned when another process holds the repository run lock.
+var ErrLocked = errors.New("repository is locked by another fi run")
+
+// Lock is an advisory lock on <repo>/.fi/lock held for the duration of a run.
+type Lock struct {
+	file *os.File
+	path string
+}
+
+// LockPath returns the lock file location for a repository.
+func LockPath(repoRoot string) string {
+	return filepath.Join(repoRoot, ".fi", "lock")
+}
+
+// AcquireLock takes the repository run lock without blocking. When the lock is
+// held elsewhere the returned error wraps ErrLocked and names the holder's PID.
+func AcquireLock(repoRoot string) (*Lock, error) {
+	path := LockPath(repoRoot)
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, err
+	}
+	file, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	if err := lockFile(file); err != nil {
+		holder := readHolder(file)
+		_ = file.Close()
+		if errors.Is(err, ErrLocked) && holder != "" {
+			return nil, fmt.Errorf("%w (pid %s)", ErrLocked, holder)
+		}
+		return nil, err
+	}
+	_ = file.Truncate(0)
+	_, _ = file.WriteAt([]byte(strconv.Itoa(os.Getpid())+"\n"), 0)
+	return &Lock{file: file, path: path}, nil
+}
+
+// Release drops the lock. It is safe to call on a nil Lock.
+func (l *Lock) Release() error {
+	if l == nil || l.file == nil {
+		return nil
+	}
+	_ = l.file.Truncate(0)
+	err := unlockFile(l.file)
+	if closeErr := l.file.Close(); err == nil {
+		err = closeErr
+	}
+	l.file = nil
+	return err
+}
+
+func readHolder(file *os.File) string {
+	buf := make([]byte, 32)
+	n, _ := file.ReadAt(buf, 0)
+	return strings.TrimSpace(string(buf[:n]))
+}
@@ -0,0 +1,14 @@
+//go:build !unix
+
+package repo
+
+import "os"
+
+// Advisory locking is only implemented on unix; elsewhere runs proceed unlocked.
+func lockFile(file *os.File) error {
+	return nil
+}
+
+func unlockFile(file *os.File) error {
+	return nil
+}
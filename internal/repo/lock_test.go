@@ -0,0 +1,27 @@
+//go:build unix
+
+package repo
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestAcquireLockExclusive(t *testing.T) {
+	root := t.TempDir()
+	first, err := AcquireLock(root)
+	if err != nil {
+		t.Fatalf("first lock failed: %v", err)
+	}
+	if _, err := AcquireLock(root); !errors.Is(err, ErrLocked) {
+		t.Fatalf("expected ErrLocked, got %v", err)
+	}
+	if err := first.Release(); err != nil {
+		t.Fatalf("release failed: %v", err)
+	}
+	second, err := AcquireLock(root)
+	if err != nil {
+		t.Fatalf("expected lock after release: %v", err)
+	}
+	_ = second.Release()
+}
@@ -0,0 +1,137 @@
+package repo
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"fi-cli/internal/util"
+)
+
+// maxNotebookBytes caps the raw .ipynb file read before rendering. Outputs
+// (images, tables) are most of a notebook's size, so the rendered source is
+// far smaller than the file.
+const maxNotebookBytes = 16 << 20
+
+// maxNotebookSnippets caps how many top-level notebooks BuildContext snippets.
+const maxNotebookSnippets = 2
+
+// IsNotebook reports whether path is a Jupyter notebook.
+func IsNotebook(path string) bool {
+	return strings.EqualFold(filepath.Ext(path), ".ipynb")
+}
+
+type notebook struct {
+	Cells    []notebookCell `json:"cells"`
+	Metadata struct {
+		Kernelspec struct {
+			Language string `json:"language"`
+		} `json:"kernelspec"`
+		LanguageInfo struct {
+			Name string `json:"name"`
+		} `json:"language_info"`
+	} `json:"metadata"`
+	// Worksheets holds the cells of nbformat 3 notebooks.
+	Worksheets []struct {
+		Cells []notebookCell `json:"cells"`
+	} `json:"worksheets"`
+}
+
+type notebookCell struct {
+	CellType string            `json:"cell_type"`
+	Source   notebookText      `json:"source"`
+	Input    notebookText      `json:"input"`
+	Outputs  []json.RawMessage `json:"outputs"`
+}
+
+// notebookText is a cell source, stored either as one string or as lines.
+type notebookText string
+
+func (t *notebookText) UnmarshalJSON(data []byte) error {
+	var text string
+	if err := json.Unmarshal(data, &text); err == nil {
+		*t = notebookText(text)
+		return nil
+	}
+	var lines []string
+	if err := json.Unmarshal(data, &lines); err != nil {
+		return err
+	}
+	*t = notebookText(strings.Join(lines, ""))
+	return nil
+}
+
+// RenderNotebook renders an .ipynb file as readable source: each cell is a
+// "# %% [code]" or "# %% [markdown]" block (the percent format editors
+// understand), and outputs are dropped with a count left in their place.
+func RenderNotebook(data []byte) (string, error) {
+	var nb notebook
+	if err := json.Unmarshal(data, &nb); err != nil {
+		return "", fmt.Errorf("parse notebook: %w", err)
+	}
+	cells := nb.Cells
+	for _, sheet := range nb.Worksheets {
+		cells = append(cells, sheet.Cells...)
+	}
+	if cells == nil {
+		return "", errors.New("parse notebook: no cells")
+	}
+	language := nb.Metadata.Kernelspec.Language
+	if language == "" {
+		language = nb.Metadata.LanguageInfo.Name
+	}
+
+	var b strings.Builder
+	if language != "" {
+		fmt.Fprintf(&b, "# notebook language: %s\n", language)
+	}
+	for i, cell := range cells {
+		source := strings.TrimRight(string(cell.Source), "\n")
+		if source == "" {
+			source = strings.TrimRight(string(cell.Input), "\n")
+		}
+		kind := cell.CellType
+		switch kind {
+		case "heading":
+			kind = "markdown"
+		case "":
+			kind = "code"
+		}
+		if i > 0 {
+			b.WriteString("\n")
+		}
+		fmt.Fprintf(&b, "# %%%% [%s]\n", kind)
+		if source != "" {
+			b.WriteString(source)
+			b.WriteString("\n")
+		}
+		if n := len(cell.Outputs); n > 0 {
+			fmt.Fprintf(&b, "# (%d output(s) omitted)\n", n)
+		}
+	}
+	return b.String(), nil
+}
+
+// readNotebook renders the notebook at path, capped at maxBytes of output.
+// It returns "" for files it cannot read or parse.
+func readNotebook(path string, maxBytes int) (string, bool) {
+	info, err := os.Stat(path)
+	if err != nil || info.Size() > maxNotebookBytes {
+		return "", false
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", false
+	}
+	rendered, err := RenderNotebook(data)
+	if err != nil {
+		return "", false
+	}
+	if maxBytes > 0 && len(rendered) > maxBytes {
+		return util.CutBytes(rendered, maxBytes), true
+	}
+	return rendered, false
+}
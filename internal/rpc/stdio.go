@@ -0,0 +1,226 @@
+package rpc
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"sync"
+
+	"fi-cli/internal/events"
+	"fi-cli/internal/render"
+)
+
+const (
+	codeParseError     = -32700
+	codeInvalidRequest = -32600
+	codeMethodNotFound = -32601
+	codeInvalidParams  = -32602
+	codeRunFailed      = -32000
+)
+
+// Request is a JSON-RPC 2.0 request. Requests without an ID are notifications.
+type Request struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id,omitempty"`
+	Method  string          `json:"method"`
+	Params  json.RawMessage `json:"params,omitempty"`
+}
+
+// Response is a JSON-RPC 2.0 response.
+type Response struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id"`
+	Result  any             `json:"result,omitempty"`
+	Error   *Error          `json:"error,omitempty"`
+}
+
+// Notification is a server-initiated message without an ID.
+type Notification struct {
+	JSONRPC string `json:"jsonrpc"`
+	Method  string `json:"method"`
+	Params  any    `json:"params"`
+}
+
+// Error is a JSON-RPC 2.0 error object.
+type Error struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+	Data    any    `json:"data,omitempty"`
+}
+
+// RunParams are the parameters of the "run" method.
+type RunParams struct {
+	Question string `json:"question"`
+	Repo     string `json:"repo,omitempty"`
+	Mode     string `json:"mode,omitempty"`
+}
+
+// CancelParams are the parameters of the "cancel" method.
+type CancelParams struct {
+	ID json.RawMessage `json:"id"`
+}
+
+// EventParams wraps a run event streamed as an "event" notification.
+type EventParams struct {
+	ID    json.RawMessage `json:"id"`
+	Event events.Event    `json:"event"`
+}
+
+// RunFunc executes a single run, emitting events through renderer.
+type RunFunc func(ctx context.Context, params RunParams, renderer render.Renderer) (any, error)
+
+// Server speaks line-delimited JSON-RPC 2.0 over a reader/writer pair.
+//
+// Methods:
+//   - run: starts a run; events stream as "event" notifications and the
+//     response arrives when the run finishes.
+//   - cancel: cancels an in-flight run by the id of its "run" request.
+//   - shutdown: cancels all runs and stops serving.
+//
+// On EOF the server stops reading but lets in-flight runs finish.
+type Server struct {
+	in  io.Reader
+	out io.Writer
+	run RunFunc
+
+	writeMu sync.Mutex
+	runsMu  sync.Mutex
+	runs    map[string]context.CancelFunc
+	wg      sync.WaitGroup
+}
+
+// NewServer constructs a stdio JSON-RPC server.
+func NewServer(in io.Reader, out io.Writer, run RunFunc) *Server {
+	return &Server{in: in, out: out, run: run, runs: map[string]context.CancelFunc{}}
+}
+
+// Serve reads requests until EOF, shutdown, or ctx cancellation.
+func (s *Server) Serve(ctx context.Context) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	scanner := bufio.NewScanner(s.in)
+	scanner.Buffer(make([]byte, 64*1024), 4*1024*1024)
+	for scanner.Scan() {
+		line := scanner.Bytes()
+		if len(line) == 0 {
+			continue
+		}
+		var req Request
+		if err := json.Unmarshal(line, &req); err != nil {
+			s.reply(nil, nil, &Error{Code: codeParseError, Message: err.Error()})
+			continue
+		}
+		if req.Method == "shutdown" {
+			cancel()
+			s.wg.Wait()
+			s.reply(req.ID, map[string]bool{"ok": true}, nil)
+			return nil
+		}
+		s.dispatch(ctx, req)
+	}
+	s.wg.Wait()
+	if err := scanner.Err(); err != nil && !errors.Is(err, io.EOF) {
+		return err
+	}
+	return nil
+}
+
+func (s *Server) dispatch(ctx context.Context, req Request) {
+	switch req.Method {
+	case "run":
+		if len(req.ID) == 0 {
+			s.reply(nil, nil, &Error{Code: codeInvalidRequest, Message: "run requires an id"})
+			return
+		}
+		var params RunParams
+		if err := json.Unmarshal(req.Params, &params); err != nil || params.Question == "" {
+			s.reply(req.ID, nil, &Error{Code: codeInvalidParams, Message: "question is required"})
+			return
+		}
+		s.startRun(ctx, req.ID, params)
+	case "cancel":
+		var params CancelParams
+		if err := json.Unmarshal(req.Params, &params); err != nil || len(params.ID) == 0 {
+			s.reply(req.ID, nil, &Error{Code: codeInvalidParams, Message: "id is required"})
+			return
+		}
+		s.runsMu.Lock()
+		cancel, ok := s.runs[string(params.ID)]
+		s.runsMu.Unlock()
+		if ok {
+			cancel()
+		}
+		if len(req.ID) > 0 {
+			s.reply(req.ID, map[string]bool{"cancelled": ok}, nil)
+		}
+	default:
+		if len(req.ID) > 0 {
+			s.reply(req.ID, nil, &Error{Code: codeMethodNotFound, Message: "unknown method: " + req.Method})
+		}
+	}
+}
+
+func (s *Server) startRun(ctx context.Context, id json.RawMessage, params RunParams) {
+	key := string(id)
+	runCtx, cancel := context.WithCancel(ctx)
+	s.runsMu.Lock()
+	if _, exists := s.runs[key]; exists {
+		s.runsMu.Unlock()
+		cancel()
+		s.reply(id, nil, &Error{Code: codeInvalidRequest, Message: "duplicate run id"})
+		return
+	}
+	s.runs[key] = cancel
+	s.runsMu.Unlock()
+
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		defer func() {
+			s.runsMu.Lock()
+			delete(s.runs, key)
+			s.runsMu.Unlock()
+			cancel()
+		}()
+		result, err := s.run(runCtx, params, &eventSink{server: s, id: id})
+		if err != nil {
+			s.reply(id, nil, &Error{Code: codeRunFailed, Message: err.Error(), Data: result})
+			return
+		}
+		s.reply(id, result, nil)
+	}()
+}
+
+func (s *Server) reply(id json.RawMessage, result any, rpcErr *Error) {
+	if len(id) == 0 {
+		id = json.RawMessage("null")
+	}
+	s.write(Response{JSONRPC: "2.0", ID: id, Result: result, Error: rpcErr})
+}
+
+func (s *Server) write(message any) {
+	payload, err := json.Marshal(message)
+	if err != nil {
+		return
+	}
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+	_, _ = s.out.Write(append(payload, '\n'))
+}
+
+// eventSink forwards run events as notifications tagged with the request id.
+type eventSink struct {
+	server *Server
+	id     json.RawMessage
+}
+
+func (e *eventSink) Emit(event events.Event) {
+	e.server.write(Notification{JSONRPC: "2.0", Method: "event", Params: EventParams{ID: e.id, Event: event}})
+}
+
+func (e *eventSink) Close() error {
+	return nil
+}
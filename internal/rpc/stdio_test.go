@@ -0,0 +1,99 @@
+package rpc
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"fi-cli/internal/events"
+	"fi-cli/internal/render"
+)
+
+func TestServerRunStreamsEventsThenResponds(t *testing.T) {
+	input := strings.NewReader(`{"jsonrpc":"2.0","id":1,"method":"run","params":{"question":"where is main?"}}` + "\n")
+	var out bytes.Buffer
+	server := NewServer(input, &out, func(ctx context.Context, params RunParams, renderer render.Renderer) (any, error) {
+		renderer.Emit(events.Event{Type: events.ModelDelta, Timestamp: time.Now(), Payload: events.ModelDeltaPayload{Delta: "hi"}})
+		return map[string]string{"final_answer": params.Question}, nil
+	})
+	if err := server.Serve(context.Background()); err != nil {
+		t.Fatalf("serve failed: %v", err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("expected event and response lines, got %d: %s", len(lines), out.String())
+	}
+	var note map[string]any
+	if err := json.Unmarshal([]byte(lines[0]), &note); err != nil || note["method"] != "event" {
+		t.Fatalf("expected event notification, got %s", lines[0])
+	}
+	var resp Response
+	if err := json.Unmarshal([]byte(lines[1]), &resp); err != nil {
+		t.Fatalf("invalid response: %v", err)
+	}
+	if string(resp.ID) != "1" || resp.Error != nil {
+		t.Fatalf("unexpected response: %s", lines[1])
+	}
+}
+
+func TestServerCancelStopsRun(t *testing.T) {
+	reader, writer := io.Pipe()
+	var out safeBuffer
+	started := make(chan struct{})
+	server := NewServer(reader, &out, func(ctx context.Context, params RunParams, renderer render.Renderer) (any, error) {
+		close(started)
+		<-ctx.Done()
+		return nil, ctx.Err()
+	})
+	done := make(chan error, 1)
+	go func() { done <- server.Serve(context.Background()) }()
+
+	_, _ = writer.Write([]byte(`{"jsonrpc":"2.0","id":"a","method":"run","params":{"question":"q"}}` + "\n"))
+	<-started
+	_, _ = writer.Write([]byte(`{"jsonrpc":"2.0","id":2,"method":"cancel","params":{"id":"a"}}` + "\n"))
+	_ = writer.Close()
+	if err := <-done; err != nil {
+		t.Fatalf("serve failed: %v", err)
+	}
+	if !strings.Contains(out.String(), `"cancelled":true`) {
+		t.Fatalf("expected cancel acknowledgement, got %s", out.String())
+	}
+	if !strings.Contains(out.String(), `"code":-32000`) {
+		t.Fatalf("expected cancelled run to fail, got %s", out.String())
+	}
+}
+
+func TestServerUnknownMethod(t *testing.T) {
+	input := strings.NewReader(`{"jsonrpc":"2.0","id":7,"method":"nope"}` + "\n")
+	var out bytes.Buffer
+	server := NewServer(input, &out, nil)
+	if err := server.Serve(context.Background()); err != nil {
+		t.Fatalf("serve failed: %v", err)
+	}
+	if !strings.Contains(out.String(), `"code":-32601`) {
+		t.Fatalf("expected method not found, got %s", out.String())
+	}
+}
+
+type safeBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *safeBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *safeBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
@@ -0,0 +1,73 @@
+package runs
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+
+	"fi-cli/internal/agent"
+)
+
+const checkpointSuffix = ".checkpoint.json"
+
+func checkpointPath(id string) (string, error) {
+	if id == "" || filepath.Base(id) != id {
+		return "", errors.New("invalid run id")
+	}
+	dir, err := Dir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, id+checkpointSuffix), nil
+}
+
+// SaveCheckpoint writes a timed-out run's loop state as
+// <run_id>.checkpoint.json and returns its path.
+func SaveCheckpoint(cp agent.Checkpoint) (string, error) {
+	path, err := checkpointPath(cp.RunID)
+	if err != nil {
+		return "", err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return "", err
+	}
+	payload, err := json.Marshal(cp)
+	if err != nil {
+		return "", err
+	}
+	if err := os.WriteFile(path, payload, 0o600); err != nil {
+		return "", err
+	}
+	return path, nil
+}
+
+// LoadCheckpoint reads the checkpoint of run id.
+func LoadCheckpoint(id string) (agent.Checkpoint, error) {
+	var cp agent.Checkpoint
+	path, err := checkpointPath(id)
+	if err != nil {
+		return cp, err
+	}
+	payload, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return cp, errors.New("no checkpoint for run " + id + " (only runs that hit the timeout are checkpointed)")
+	}
+	if err != nil {
+		return cp, err
+	}
+	err = json.Unmarshal(payload, &cp)
+	return cp, err
+}
+
+// RemoveCheckpoint deletes the checkpoint of run id, if any.
+func RemoveCheckpoint(id string) error {
+	path, err := checkpointPath(id)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return nil
+}
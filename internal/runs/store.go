@@ -0,0 +1,124 @@
+package runs
+
+import (
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"fi-cli/internal/agent"
+)
+
+// Dir returns the directory where run logs are persisted.
+func Dir() (string, error) {
+	return UserDir("")
+}
+
+// UserDir returns the run log directory of a `fi-cli serve` API user, or the
+// local one for "".
+func UserDir(user string) (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	base := filepath.Join(home, ".local", "share", "fi.ashref.tn")
+	if user != "" {
+		base = filepath.Join(base, "users", user)
+	}
+	return filepath.Join(base, "runs"), nil
+}
+
+// Save writes a run result as <run_id>.json and returns its path.
+func Save(result agent.RunResult) (string, error) {
+	return SaveFor("", result)
+}
+
+// SaveFor is Save into a `fi-cli serve` API user's run logs.
+func SaveFor(user string, result agent.RunResult) (string, error) {
+	if result.RunID == "" {
+		return "", errors.New("run result has no run_id")
+	}
+	dir, err := UserDir(user)
+	if err != nil {
+		return "", err
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return "", err
+	}
+	payload, err := json.MarshalIndent(result, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	path := filepath.Join(dir, result.RunID+".json")
+	if err := os.WriteFile(path, payload, 0o600); err != nil {
+		return "", err
+	}
+	return path, nil
+}
+
+// List returns the persisted runs carrying every label in labels, newest
+// first. Unreadable run logs are skipped.
+func List(labels map[string]string) ([]agent.RunResult, error) {
+	dir, err := Dir()
+	if err != nil {
+		return nil, err
+	}
+	entries, err := os.ReadDir(dir)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var results []agent.RunResult
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasSuffix(name, ".json") || strings.HasSuffix(name, checkpointSuffix) {
+			continue
+		}
+		result, err := Load(strings.TrimSuffix(name, ".json"))
+		if err != nil || !hasLabels(result.Labels, labels) {
+			continue
+		}
+		results = append(results, result)
+	}
+	sort.SliceStable(results, func(i, j int) bool {
+		return results[i].StartedAt.After(results[j].StartedAt)
+	})
+	return results, nil
+}
+
+func hasLabels(have map[string]string, want map[string]string) bool {
+	for key, value := range want {
+		if got, ok := have[key]; !ok || got != value {
+			return false
+		}
+	}
+	return true
+}
+
+// Load reads a persisted run by id.
+func Load(id string) (agent.RunResult, error) {
+	return LoadFor("", id)
+}
+
+// LoadFor reads a run from a `fi-cli serve` API user's run logs.
+func LoadFor(user, id string) (agent.RunResult, error) {
+	var result agent.RunResult
+	dir, err := UserDir(user)
+	if err != nil {
+		return result, err
+	}
+	if filepath.Base(id) != id {
+		return result, errors.New("invalid run id")
+	}
+	payload, err := os.ReadFile(filepath.Join(dir, id+".json"))
+	if err != nil {
+		return result, err
+	}
+	err = json.Unmarshal(payload, &result)
+	return result, err
+}
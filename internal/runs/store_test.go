@@ -0,0 +1,102 @@
+package runs
+
+import (
+	"testing"
+	"time"
+
+	"fi-cli/internal/agent"
+
+	"github.com/openai/openai-go/v3"
+)
+
+func TestSaveAndLoad(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	if _, err := Save(agent.RunResult{RunID: "abc", Question: "q", Status: "success"}); err != nil {
+		t.Fatalf("save failed: %v", err)
+	}
+	loaded, err := Load("abc")
+	if err != nil {
+		t.Fatalf("load failed: %v", err)
+	}
+	if loaded.Question != "q" || loaded.Status != "success" {
+		t.Fatalf("unexpected run: %+v", loaded)
+	}
+	if _, err := Load("../abc"); err == nil {
+		t.Fatalf("expected path traversal to be rejected")
+	}
+}
+
+func TestSaveForKeepsUsersApart(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	if _, err := SaveFor("alice", agent.RunResult{RunID: "r1", Question: "q"}); err != nil {
+		t.Fatalf("save failed: %v", err)
+	}
+	if loaded, err := LoadFor("alice", "r1"); err != nil || loaded.Question != "q" {
+		t.Fatalf("expected alice's run, got %+v (%v)", loaded, err)
+	}
+	if _, err := LoadFor("bob", "r1"); err == nil {
+		t.Fatalf("expected bob not to see alice's run")
+	}
+	if _, err := Load("r1"); err == nil {
+		t.Fatalf("expected the local run logs not to hold alice's run")
+	}
+}
+
+func TestListFiltersByLabel(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	now := time.Now()
+	saved := []agent.RunResult{
+		{RunID: "old", StartedAt: now.Add(-time.Hour), Labels: map[string]string{"team": "payments", "ticket": "PAY-1"}},
+		{RunID: "new", StartedAt: now, Labels: map[string]string{"team": "payments"}},
+		{RunID: "other", StartedAt: now, Labels: map[string]string{"team": "search"}},
+	}
+	for _, result := range saved {
+		if _, err := Save(result); err != nil {
+			t.Fatalf("save failed: %v", err)
+		}
+	}
+	if _, err := SaveCheckpoint(agent.Checkpoint{RunID: "new"}); err != nil {
+		t.Fatalf("save checkpoint failed: %v", err)
+	}
+
+	listed, err := List(map[string]string{"team": "payments"})
+	if err != nil {
+		t.Fatalf("list failed: %v", err)
+	}
+	if len(listed) != 2 || listed[0].RunID != "new" || listed[1].RunID != "old" {
+		t.Fatalf("expected payments runs newest first, got %+v", listed)
+	}
+	if listed, _ := List(map[string]string{"team": "payments", "ticket": "PAY-1"}); len(listed) != 1 || listed[0].RunID != "old" {
+		t.Fatalf("expected every label to match, got %+v", listed)
+	}
+	if all, _ := List(nil); len(all) != 3 {
+		t.Fatalf("expected all runs without a filter, got %d", len(all))
+	}
+}
+
+func TestCheckpointRoundTrip(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	cp := agent.Checkpoint{
+		RunID:     "abc",
+		Question:  "q",
+		Step:      2,
+		Messages:  []openai.ChatCompletionMessageParamUnion{openai.SystemMessage("sys"), openai.ToolMessage("[T1]\n{}", "call_1")},
+		ToolUsage: map[string]int{"grep": 1},
+	}
+	if _, err := SaveCheckpoint(cp); err != nil {
+		t.Fatalf("save failed: %v", err)
+	}
+	loaded, err := LoadCheckpoint("abc")
+	if err != nil {
+		t.Fatalf("load failed: %v", err)
+	}
+	if loaded.Step != 2 || len(loaded.Messages) != 2 || loaded.Messages[1].OfTool == nil || loaded.Messages[1].OfTool.ToolCallID != "call_1" {
+		t.Fatalf("unexpected checkpoint: %+v", loaded)
+	}
+	if err := RemoveCheckpoint("abc"); err != nil {
+		t.Fatalf("remove failed: %v", err)
+	}
+	if _, err := LoadCheckpoint("abc"); err == nil {
+		t.Fatalf("expected the checkpoint to be gone")
+	}
+}
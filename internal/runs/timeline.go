@@ -0,0 +1,155 @@
+package runs
+
+import (
+	"fmt"
+	"html"
+	"sort"
+	"strings"
+	"time"
+
+	"fi-cli/internal/agent"
+	"fi-cli/internal/events"
+)
+
+// minModelGap is the shortest idle stretch between tool calls that a
+// timeline attributes to a model call rather than to bookkeeping.
+const minModelGap = 50 * time.Millisecond
+
+// Span kinds of a timeline.
+const (
+	SpanModel = "model"
+	SpanTool  = "tool"
+)
+
+// Span is one bar of a run timeline.
+type Span struct {
+	Kind   string
+	Label  string
+	Start  time.Time
+	End    time.Time
+	Failed bool
+}
+
+// Timeline reconstructs where a run spent its time. Tool calls come from
+// their records; the stretches between them, and from the last one to the
+// answer, are the model calls, since only the model runs there.
+func Timeline(result agent.RunResult) []Span {
+	var spans []Span
+	for _, call := range result.ToolCalls {
+		if call.DurationMs <= 0 {
+			continue
+		}
+		spans = append(spans, Span{
+			Kind:   SpanTool,
+			Label:  call.ID + " " + call.ToolName,
+			Start:  call.StartedAt,
+			End:    call.StartedAt.Add(time.Duration(call.DurationMs) * time.Millisecond),
+			Failed: call.Status != "success",
+		})
+	}
+	sort.SliceStable(spans, func(i, j int) bool { return spans[i].Start.Before(spans[j].Start) })
+
+	answered := result.FinishedAt
+	for _, event := range result.Events {
+		if event.Type == events.FinalAnswerReady && !event.Timestamp.IsZero() {
+			answered = event.Timestamp
+		}
+	}
+	cursor := result.StartedAt
+	var timeline []Span
+	step := 0
+	model := func(until time.Time) {
+		if until.Sub(cursor) >= minModelGap {
+			step++
+			timeline = append(timeline, Span{Kind: SpanModel, Label: fmt.Sprintf("model %d", step), Start: cursor, End: until})
+		}
+	}
+	for _, span := range spans {
+		model(span.Start)
+		timeline = append(timeline, span)
+		if span.End.After(cursor) {
+			cursor = span.End
+		}
+	}
+	model(answered)
+	if cursor.Before(answered) {
+		cursor = answered
+	}
+	if result.FinishedAt.Sub(cursor) >= minModelGap {
+		timeline = append(timeline, Span{Kind: SpanModel, Label: "assessment", Start: cursor, End: result.FinishedAt})
+	}
+	return timeline
+}
+
+// Mermaid renders a timeline as a Mermaid Gantt chart, with times in
+// milliseconds from the start of the run.
+func Mermaid(result agent.RunResult, spans []Span) string {
+	var b strings.Builder
+	b.WriteString("gantt\n")
+	fmt.Fprintf(&b, "    title %s\n", mermaidText(fmt.Sprintf("fi-cli run %s (%s)", result.RunID, runDuration(result))))
+	b.WriteString("    dateFormat x\n")
+	b.WriteString("    axisFormat %M:%S\n")
+	for _, kind := range []string{SpanModel, SpanTool} {
+		section := false
+		for i, span := range spans {
+			if span.Kind != kind {
+				continue
+			}
+			if !section {
+				fmt.Fprintf(&b, "    section %s\n", kind)
+				section = true
+			}
+			tag := ""
+			if span.Failed {
+				tag = "crit, "
+			}
+			start := span.Start.Sub(result.StartedAt).Milliseconds()
+			end := span.End.Sub(result.StartedAt).Milliseconds()
+			fmt.Fprintf(&b, "    %s :%ss%d, %d, %d\n", mermaidText(fmt.Sprintf("%s %s", span.Label, span.End.Sub(span.Start).Round(time.Millisecond))), tag, i+1, start, end)
+		}
+	}
+	return b.String()
+}
+
+// mermaidText drops the characters that end a Mermaid task name.
+func mermaidText(text string) string {
+	return strings.NewReplacer(":", " ", ";", " ", "#", " ", "\n", " ").Replace(text)
+}
+
+// TimelineHTML renders a timeline as a self-contained HTML page of bars
+// scaled to the run's duration.
+func TimelineHTML(result agent.RunResult, spans []Span) string {
+	total := result.FinishedAt.Sub(result.StartedAt)
+	if total <= 0 {
+		total = time.Millisecond
+	}
+	percent := func(d time.Duration) float64 { return 100 * float64(d) / float64(total) }
+	var b strings.Builder
+	title := html.EscapeString(fmt.Sprintf("fi-cli run %s", result.RunID))
+	fmt.Fprintf(&b, "<!doctype html>\n<html><head><meta charset=\"utf-8\"><title>%s</title>\n", title)
+	b.WriteString(`<style>
+body{font:14px system-ui,sans-serif;margin:2em}
+.row{display:flex;align-items:center;margin:2px 0}
+.label{width:16em;flex:none;overflow:hidden;white-space:nowrap;text-overflow:ellipsis}
+.track{position:relative;flex:1;height:1.2em;background:#f2f2f2}
+.bar{position:absolute;top:0;bottom:0;min-width:1px}
+.model{background:#6b8fd6}.tool{background:#5bb381}.failed{background:#d9534f}
+</style></head><body>
+`)
+	fmt.Fprintf(&b, "<h1>%s</h1>\n<p>%s &middot; %s &middot; %s</p>\n", title, html.EscapeString(result.Question), html.EscapeString(result.Status), runDuration(result))
+	for _, span := range spans {
+		class := span.Kind
+		if span.Failed {
+			class = "failed"
+		}
+		length := span.End.Sub(span.Start)
+		fmt.Fprintf(&b, "<div class=\"row\"><div class=\"label\">%s</div><div class=\"track\"><div class=\"bar %s\" style=\"left:%.2f%%;width:%.2f%%\" title=\"%s\"></div></div></div>\n",
+			html.EscapeString(span.Label+" "+length.Round(time.Millisecond).String()), class, percent(span.Start.Sub(result.StartedAt)), percent(length), html.EscapeString(length.Round(time.Millisecond).String()))
+	}
+	b.WriteString("</body></html>\n")
+	return b.String()
+}
+
+func runDuration(result agent.RunResult) time.Duration {
+	return result.FinishedAt.Sub(result.StartedAt).Round(time.Millisecond)
+}
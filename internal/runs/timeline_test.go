@@ -0,0 +1,59 @@
+package runs
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"fi-cli/internal/agent"
+	"fi-cli/internal/events"
+)
+
+func timelineRun() agent.RunResult {
+	start := time.Date(2026, 10, 1, 9, 0, 0, 0, time.UTC)
+	at := func(ms int) time.Time { return start.Add(time.Duration(ms) * time.Millisecond) }
+	return agent.RunResult{
+		RunID:      "run-1",
+		Question:   "why <slow>?",
+		Status:     "success",
+		StartedAt:  start,
+		FinishedAt: at(9000),
+		ToolCalls: []agent.ToolCallRecord{
+			{ID: "T1", ToolName: "grep", Status: "success", StartedAt: at(2000), DurationMs: 500},
+			{ID: "T2", ToolName: "shell", Status: "error", StartedAt: at(2510), DurationMs: 1490},
+			{ID: "T3", ToolName: "grep", Status: "error", StartedAt: at(4000), DurationMs: 0},
+			{ID: "T4", ToolName: "grep", Status: "success", StartedAt: at(6000), DurationMs: 1000},
+		},
+		Events: []events.Event{{Type: events.FinalAnswerReady, Timestamp: at(8500)}},
+	}
+}
+
+func TestTimeline(t *testing.T) {
+	spans := Timeline(timelineRun())
+	var got []string
+	for _, span := range spans {
+		got = append(got, span.Label+" "+span.End.Sub(span.Start).String())
+	}
+	want := "model 1 2s|T1 grep 500ms|T2 shell 1.49s|model 2 2s|T4 grep 1s|model 3 1.5s|assessment 500ms"
+	if strings.Join(got, "|") != want {
+		t.Fatalf("unexpected spans:\n%s\nwant:\n%s", strings.Join(got, "|"), want)
+	}
+	if !spans[2].Failed || spans[1].Failed {
+		t.Fatalf("expected only the failed shell call to be marked: %+v", spans)
+	}
+}
+
+func TestTimelineFormats(t *testing.T) {
+	result := timelineRun()
+	spans := Timeline(result)
+	chart := Mermaid(result, spans)
+	for _, want := range []string{"gantt\n", "dateFormat x\n", "    section model\n    model 1 2s :s1, 0, 2000\n", "    T2 shell 1.49s :crit, s3, 2510, 4000\n"} {
+		if !strings.Contains(chart, want) {
+			t.Fatalf("mermaid chart missing %q:\n%s", want, chart)
+		}
+	}
+	page := TimelineHTML(result, spans)
+	if !strings.Contains(page, "why &lt;slow&gt;?") || !strings.Contains(page, `class="bar failed" style="left:27.89%;width:16.56%"`) {
+		t.Fatalf("unexpected html:\n%s", page)
+	}
+}
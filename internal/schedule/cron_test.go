@@ -0,0 +1,61 @@
+package schedule
+
+import (
+	"context"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestParseAndNext(t *testing.T) {
+	base := time.Date(2026, 3, 10, 14, 37, 0, 0, time.UTC) // Tuesday
+	cases := []struct {
+		expr string
+		want time.Time
+	}{
+		{"*/15 * * * *", time.Date(2026, 3, 10, 14, 45, 0, 0, time.UTC)},
+		{"0 2 * * *", time.Date(2026, 3, 11, 2, 0, 0, 0, time.UTC)},
+		{"@hourly", time.Date(2026, 3, 10, 15, 0, 0, 0, time.UTC)},
+		{"30 9 * * 1-5", time.Date(2026, 3, 11, 9, 30, 0, 0, time.UTC)},
+		{"0 0 1 * *", time.Date(2026, 4, 1, 0, 0, 0, 0, time.UTC)},
+		{"0 12 * * 7", time.Date(2026, 3, 15, 12, 0, 0, 0, time.UTC)},
+	}
+	for _, tc := range cases {
+		spec, err := Parse(tc.expr)
+		if err != nil {
+			t.Fatalf("parse %q: %v", tc.expr, err)
+		}
+		if got := spec.Next(base); !got.Equal(tc.want) {
+			t.Fatalf("%q: expected %s, got %s", tc.expr, tc.want, got)
+		}
+	}
+}
+
+func TestParseRejectsInvalid(t *testing.T) {
+	for _, expr := range []string{"", "* * * *", "61 * * * *", "*/0 * * * *", "a * * * *", "5-2 * * * *"} {
+		if _, err := Parse(expr); err == nil {
+			t.Fatalf("expected %q to be rejected", expr)
+		}
+	}
+}
+
+func TestParseRejectsNeverFiring(t *testing.T) {
+	for _, expr := range []string{"0 0 30 2 *", "0 0 31 4,6,9,11 *"} {
+		if _, err := Parse(expr); err == nil || !strings.Contains(err.Error(), "never fires") {
+			t.Fatalf("expected %q to be rejected as never firing, got %v", expr, err)
+		}
+	}
+}
+
+func TestSchedulerParksJobsWithoutActivation(t *testing.T) {
+	var runs atomic.Int32
+	// the zero Spec matches nothing, so Next is always zero
+	s := New([]Job{{Name: "never", Run: func(context.Context) { runs.Add(1) }}})
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	s.Run(ctx)
+	if n := runs.Load(); n != 0 {
+		t.Fatalf("expected a job with no activation to stay parked, ran %d times", n)
+	}
+}
@@ -0,0 +1,99 @@
+package schedule
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Job is a named task fired on a cron spec.
+type Job struct {
+	Name string
+	Spec Spec
+	Run  func(ctx context.Context)
+}
+
+// Scheduler fires jobs at their next activation. A job still running when it
+// comes due again is skipped for that activation rather than stacked, and a
+// job with no activation left is parked.
+type Scheduler struct {
+	jobs    []Job
+	now     func() time.Time
+	mu      sync.Mutex
+	running map[string]bool
+	// OnSkip is called when an activation is skipped because the job is still running.
+	OnSkip func(name string)
+}
+
+// New builds a scheduler for jobs.
+func New(jobs []Job) *Scheduler {
+	return &Scheduler{jobs: jobs, now: time.Now, running: map[string]bool{}}
+}
+
+// Run blocks until ctx is done, then waits for in-flight jobs.
+func (s *Scheduler) Run(ctx context.Context) {
+	var wg sync.WaitGroup
+	defer wg.Wait()
+	if len(s.jobs) == 0 {
+		<-ctx.Done()
+		return
+	}
+	next := make([]time.Time, len(s.jobs))
+	for i, job := range s.jobs {
+		next[i] = job.Spec.Next(s.now())
+	}
+	for {
+		earliest := -1
+		for i := range next {
+			if !next[i].IsZero() && (earliest < 0 || next[i].Before(next[earliest])) {
+				earliest = i
+			}
+		}
+		if earliest < 0 {
+			<-ctx.Done()
+			return
+		}
+		timer := time.NewTimer(time.Until(next[earliest]))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+		now := s.now()
+		for i, job := range s.jobs {
+			if next[i].IsZero() || next[i].After(now) {
+				continue
+			}
+			next[i] = job.Spec.Next(now)
+			if !s.begin(job.Name) {
+				if s.OnSkip != nil {
+					s.OnSkip(job.Name)
+				}
+				continue
+			}
+			wg.Add(1)
+			go func(job Job) {
+				defer wg.Done()
+				defer s.end(job.Name)
+				job.Run(ctx)
+			}(job)
+		}
+	}
+}
+
+func (s *Scheduler) begin(name string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.running[name] {
+		return false
+	}
+	s.running[name] = true
+	return true
+}
+
+func (s *Scheduler) end(name string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.running, name)
+}
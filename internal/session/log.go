@@ -0,0 +1,133 @@
+package session
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"fi-cli/internal/util"
+)
+
+// EnvVar names the session log of the enclosing `fi-cli record-session` shell.
+const EnvVar = "FICLI_SESSION_LOG"
+
+// DefaultMaxBytes is the size at which a session log rotates.
+const DefaultMaxBytes = 1024 * 1024
+
+// Dir returns the directory where session logs are written.
+func Dir() (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, ".local", "share", "fi.ashref.tn", "sessions"), nil
+}
+
+// NewPath returns a fresh log path for a session owned by pid.
+func NewPath(pid int) (string, error) {
+	dir, err := Dir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, fmt.Sprintf("session-%d.log", pid)), nil
+}
+
+// Log is a line-buffered writer that strips escape sequences, redacts
+// secrets, and rotates to <path>.1 once it exceeds maxBytes.
+type Log struct {
+	mu       sync.Mutex
+	path     string
+	maxBytes int
+	file     *os.File
+	size     int
+	pending  []byte
+}
+
+// Open creates (or truncates) the log at path.
+func Open(path string, maxBytes int) (*Log, error) {
+	if maxBytes <= 0 {
+		maxBytes = DefaultMaxBytes
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return nil, err
+	}
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0o600)
+	if err != nil {
+		return nil, err
+	}
+	return &Log{path: path, maxBytes: maxBytes, file: file}, nil
+}
+
+// Write buffers raw terminal output and appends each completed line.
+func (l *Log) Write(p []byte) (int, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.pending = append(l.pending, p...)
+	for {
+		idx := bytes.IndexByte(l.pending, '\n')
+		if idx == -1 {
+			break
+		}
+		if err := l.writeLine(l.pending[:idx]); err != nil {
+			return len(p), err
+		}
+		l.pending = l.pending[idx+1:]
+	}
+	return len(p), nil
+}
+
+// Close flushes any partial line and closes the file.
+func (l *Log) Close() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if len(l.pending) > 0 {
+		_ = l.writeLine(l.pending)
+		l.pending = nil
+	}
+	return l.file.Close()
+}
+
+func (l *Log) writeLine(raw []byte) error {
+	line := util.RedactSecrets(util.StripANSI(string(bytes.TrimRight(raw, "\r")))) + "\n"
+	if l.size+len(line) > l.maxBytes && l.size > 0 {
+		if err := l.rotate(); err != nil {
+			return err
+		}
+	}
+	n, err := l.file.WriteString(line)
+	l.size += n
+	return err
+}
+
+func (l *Log) rotate() error {
+	if err := l.file.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(l.path, l.path+".1"); err != nil {
+		return err
+	}
+	file, err := os.OpenFile(l.path, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0o600)
+	if err != nil {
+		return err
+	}
+	l.file = file
+	l.size = 0
+	return nil
+}
+
+// Tail returns the last maxBytes of a session log, including the rotated
+// segment when the current one is short.
+func Tail(path string, maxBytes int) (string, error) {
+	current, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	if maxBytes > 0 && len(current) < maxBytes {
+		if previous, err := os.ReadFile(path + ".1"); err == nil {
+			current = append(previous, current...)
+		}
+	}
+	return util.TailBytes(string(bytes.TrimRight(current, "\n")), maxBytes), nil
+}
@@ -0,0 +1,35 @@
+package session
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLogRedactsStripsAndRotates(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "session.log")
+	log, err := Open(path, 64)
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	if _, err := log.Write([]byte("\x1b[31merror:\x1b[0m build failed\r\nexport API_KEY=supersecret\n")); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	if _, err := log.Write([]byte(strings.Repeat("x", 40) + "\npartial")); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	if err := log.Close(); err != nil {
+		t.Fatalf("close: %v", err)
+	}
+
+	tail, err := Tail(path, 1024)
+	if err != nil {
+		t.Fatalf("tail: %v", err)
+	}
+	if strings.Contains(tail, "\x1b") || strings.Contains(tail, "supersecret") {
+		t.Fatalf("expected stripped and redacted output, got %q", tail)
+	}
+	if !strings.Contains(tail, "error: build failed") || !strings.HasSuffix(tail, "partial") {
+		t.Fatalf("expected both rotated and current segments, got %q", tail)
+	}
+}
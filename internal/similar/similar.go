@@ -0,0 +1,126 @@
+// Package similar tells when two questions ask the same thing. Questions are
+// embedded locally by hashing their words and word trigrams into a fixed-size
+// vector, so no model or network call is involved.
+package similar
+
+import (
+	"hash/fnv"
+	"math"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// Dims is the size of an embedding.
+const Dims = 512
+
+// trigramWeight is how much the trigrams of a word count next to the word
+// itself; they let "migrate" and "migration" overlap.
+const trigramWeight = 0.5
+
+// Vector is a unit-length question embedding.
+type Vector [Dims]float64
+
+var stopwords = map[string]bool{}
+
+func init() {
+	for _, word := range strings.Fields(`a an and are can could do does for from how i in is it me my of on or our should the there this to we what when where which who why will with would you your`) {
+		stopwords[word] = true
+	}
+}
+
+// Embed returns the embedding of text. Text with no content words embeds to
+// the zero vector, which is similar to nothing.
+func Embed(text string) Vector {
+	var v Vector
+	for _, word := range words(text) {
+		add(&v, "w:"+word, 1)
+		padded := "^" + word + "$"
+		runes := []rune(padded)
+		for i := 0; i+3 <= len(runes); i++ {
+			add(&v, "t:"+string(runes[i:i+3]), trigramWeight)
+		}
+	}
+	var norm float64
+	for _, x := range v {
+		norm += x * x
+	}
+	if norm == 0 {
+		return v
+	}
+	norm = math.Sqrt(norm)
+	for i := range v {
+		v[i] /= norm
+	}
+	return v
+}
+
+// Cosine returns the cosine similarity of two embeddings, from -1 to 1.
+func Cosine(a, b Vector) float64 {
+	var dot float64
+	for i := range a {
+		dot += a[i] * b[i]
+	}
+	return dot
+}
+
+// Match is a candidate and how similar it is to the question.
+type Match struct {
+	Index int
+	Score float64
+}
+
+// Rank scores each candidate against question and returns those scoring at
+// least threshold, most similar first; ties keep the later candidate first.
+func Rank(question string, candidates []string, threshold float64) []Match {
+	target := Embed(question)
+	var matches []Match
+	for i, candidate := range candidates {
+		if score := Cosine(target, Embed(candidate)); score >= threshold {
+			matches = append(matches, Match{Index: i, Score: score})
+		}
+	}
+	sort.SliceStable(matches, func(i, j int) bool {
+		if matches[i].Score != matches[j].Score {
+			return matches[i].Score > matches[j].Score
+		}
+		return matches[i].Index > matches[j].Index
+	})
+	return matches
+}
+
+// words splits text into lowercased content words with common suffixes
+// removed.
+func words(text string) []string {
+	var out []string
+	for _, word := range strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	}) {
+		if stopwords[word] {
+			continue
+		}
+		out = append(out, stem(word))
+	}
+	return out
+}
+
+func stem(word string) string {
+	for _, suffix := range []string{"ing", "ed", "es", "s"} {
+		if len(word) > len(suffix)+3 && strings.HasSuffix(word, suffix) {
+			return strings.TrimSuffix(word, suffix)
+		}
+	}
+	return word
+}
+
+// add hashes feature into v; a second hash bit picks the sign so collisions
+// cancel out on average instead of inflating similarity.
+func add(v *Vector, feature string, weight float64) {
+	h := fnv.New64a()
+	_, _ = h.Write([]byte(feature))
+	sum := h.Sum64()
+	if sum>>63 == 1 {
+		weight = -weight
+	}
+	v[sum%Dims] += weight
+}
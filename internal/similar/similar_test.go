@@ -0,0 +1,26 @@
+package similar
+
+import "testing"
+
+func TestRank(t *testing.T) {
+	candidates := []string{
+		"where is auth implemented?",
+		"How do I run tests",
+		"how do I run the linter?",
+		"how can I run the test suite locally",
+	}
+	matches := Rank("how do I run the tests?", candidates, 0.65)
+	if len(matches) != 2 || matches[0].Index != 1 || matches[1].Index != 3 {
+		t.Fatalf("expected the two rephrasings, closest first, got %+v", matches)
+	}
+	if matches[0].Score < 0.99 {
+		t.Fatalf("expected a near-identical question to score about 1, got %v", matches[0].Score)
+	}
+
+	if got := Cosine(Embed("is the users migration reversible"), Embed("can the users migration be reverted")); got < 0.65 {
+		t.Fatalf("expected stemmed and trigram overlap to match, got %v", got)
+	}
+	if got := Rank("what is this?", candidates, 0.1); len(got) != 0 {
+		t.Fatalf("expected a question of stopwords to match nothing, got %+v", got)
+	}
+}
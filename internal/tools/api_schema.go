@@ -0,0 +1,437 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"path"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"fi-cli/internal/repo"
+	"fi-cli/internal/util"
+
+	"go.yaml.in/yaml/v3"
+)
+
+// maxSchemaRefs bounds how many $ref targets api_schema appends to an
+// OpenAPI definition.
+const maxSchemaRefs = 10
+
+var httpMethods = []string{"get", "put", "post", "delete", "patch", "options", "head", "trace"}
+
+type APISchemaTool struct{}
+
+// NewAPISchemaTool constructs a tool that reads OpenAPI and GraphQL contracts.
+func NewAPISchemaTool() *APISchemaTool {
+	return &APISchemaTool{}
+}
+
+func (a *APISchemaTool) Name() string { return "api_schema" }
+
+func (a *APISchemaTool) Description() string {
+	return "Read an OpenAPI (YAML/JSON) or GraphQL schema. Without name, list its endpoints, operations, and types with line numbers. With name (\"GET /users/{id}\", an operationId, a path, a schema or type name, or Query.field), return that definition's source lines plus the OpenAPI $refs it uses. path defaults to the first schema in the repo context."
+}
+
+func (a *APISchemaTool) Schema() map[string]any {
+	return map[string]any{
+		"type": "object",
+		"properties": map[string]any{
+			"path": map[string]any{"type": "string"},
+			"name": map[string]any{"type": "string"},
+		},
+		"required":             []string{},
+		"additionalProperties": false,
+	}
+}
+
+type apiSchemaInput struct {
+	Path string `json:"path"`
+	Name string `json:"name"`
+}
+
+type apiSchemaOutput struct {
+	Path       string   `json:"path"`
+	Kind       string   `json:"kind"`
+	Entries    []string `json:"entries,omitempty"`
+	Definition string   `json:"definition,omitempty"`
+	Truncated  bool     `json:"truncated"`
+	DurationMs int64    `json:"duration_ms"`
+}
+
+// schemaBlock is a named definition and its 1-based source line range.
+type schemaBlock struct {
+	label string
+	start int
+	end   int
+	// refs are the local $ref pointers the block uses (OpenAPI only).
+	refs []string
+}
+
+func (a *APISchemaTool) Execute(ctx context.Context, input json.RawMessage, meta Meta) (Result, error) {
+	var args apiSchemaInput
+	if len(input) > 0 {
+		if err := json.Unmarshal(input, &args); err != nil {
+			return Result{}, err
+		}
+	}
+	fsys := meta.FS()
+	if strings.TrimSpace(args.Path) == "" {
+		found := repo.FindAPISchemas(fsys.Root())
+		if len(found) == 0 {
+			return Result{}, errors.New("no openapi.yaml, swagger.json, or schema.graphql found; pass path")
+		}
+		args.Path = found[0]
+	}
+	name, err := fsys.Name(args.Path)
+	if err != nil {
+		return Result{}, err
+	}
+	data, err := fsys.ReadFile(name)
+	if err != nil {
+		return Result{}, err
+	}
+	// the YAML decoder also counts a bare \r as a line break
+	lines := strings.Split(lineEndings.Replace(string(data)), "\n")
+
+	start := time.Now()
+	output := apiSchemaOutput{Path: name}
+	var blocks []schemaBlock
+	var index *openAPIIndex
+	switch ext := strings.ToLower(path.Ext(name)); ext {
+	case ".graphql", ".graphqls", ".gql":
+		output.Kind = "graphql"
+		blocks = graphQLBlocks(lines)
+	default:
+		output.Kind = "openapi"
+		index, err = indexOpenAPI(data, lines)
+		if err != nil {
+			return Result{}, fmt.Errorf("%s: %w", name, err)
+		}
+		blocks = index.blocks
+	}
+
+	var text string
+	if strings.TrimSpace(args.Name) == "" {
+		limit := meta.MaxResults
+		if limit <= 0 {
+			limit = DefaultListEntries
+		}
+		for _, block := range blocks {
+			if len(output.Entries) == limit {
+				output.Truncated = true
+				break
+			}
+			output.Entries = append(output.Entries, fmt.Sprintf("%s (line %d)", block.label, block.start))
+		}
+		text = strings.Join(output.Entries, "\n")
+	} else {
+		block, ok := findSchemaBlock(blocks, args.Name)
+		if !ok {
+			return Result{}, fmt.Errorf("%s not found in %s; call api_schema without name to list definitions", args.Name, name)
+		}
+		sections := []schemaBlock{block}
+		if index != nil {
+			sections = append(sections, index.resolveRefs(block)...)
+		}
+		var b strings.Builder
+		for i, section := range sections {
+			if i > 0 {
+				b.WriteString("\n")
+			}
+			fmt.Fprintf(&b, "%s (%s:%d-%d)\n", section.label, name, section.start, section.end)
+			for n := section.start; n <= section.end && n <= len(lines); n++ {
+				fmt.Fprintf(&b, "%d: %s\n", n, lines[n-1])
+			}
+		}
+		output.Definition, output.Truncated = util.TruncateBytes(util.RedactSecrets(b.String()), meta.MaxBytes)
+		text = output.Definition
+	}
+	output.DurationMs = time.Since(start).Milliseconds()
+	return Result{ToolName: a.Name(), Payload: output, Preview: meta.Preview(text), LineCount: strings.Count(text, "\n") + 1, ByteCount: len(text), Truncated: output.Truncated, DurationMs: output.DurationMs}, nil
+}
+
+// findSchemaBlock matches name against block labels: the full label
+// ("GET /users/{id}", "type User"), its last word (an operationId, a schema or
+// type name), or a path, case-insensitively.
+func findSchemaBlock(blocks []schemaBlock, name string) (schemaBlock, bool) {
+	want := strings.ToLower(strings.TrimSpace(name))
+	want = strings.TrimPrefix(strings.TrimPrefix(want, "#/components/schemas/"), "#/definitions/")
+	for _, match := range []func(schemaBlock) bool{
+		func(b schemaBlock) bool {
+			label := strings.ToLower(b.label)
+			return label == want || strings.HasPrefix(label, want+" ")
+		},
+		func(b schemaBlock) bool {
+			fields := strings.Fields(strings.ToLower(b.label))
+			return len(fields) > 1 && fields[len(fields)-1] == want
+		},
+		func(b schemaBlock) bool {
+			fields := strings.Fields(strings.ToLower(b.label))
+			return len(fields) > 1 && fields[1] == want
+		},
+	} {
+		for _, block := range blocks {
+			if match(block) {
+				return block, true
+			}
+		}
+	}
+	return schemaBlock{}, false
+}
+
+// openAPIIndex holds an OpenAPI document's operations and component schemas.
+type openAPIIndex struct {
+	root   *yaml.Node
+	lines  []string
+	blocks []schemaBlock
+}
+
+func indexOpenAPI(data []byte, lines []string) (*openAPIIndex, error) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("parse openapi: %w", err)
+	}
+	if len(doc.Content) == 0 || doc.Content[0].Kind != yaml.MappingNode {
+		return nil, errors.New("parse openapi: expected a mapping at the top level")
+	}
+	index := &openAPIIndex{root: doc.Content[0], lines: lines}
+	if mappingValue(index.root, "openapi") == nil && mappingValue(index.root, "swagger") == nil {
+		return nil, errors.New("not an OpenAPI document: no openapi or swagger key")
+	}
+	if paths := mappingValue(index.root, "paths"); paths != nil && paths.Kind == yaml.MappingNode {
+		for i := 0; i+1 < len(paths.Content); i += 2 {
+			route, item := paths.Content[i], paths.Content[i+1]
+			if item.Kind != yaml.MappingNode {
+				continue
+			}
+			for j := 0; j+1 < len(item.Content); j += 2 {
+				method, op := item.Content[j], item.Content[j+1]
+				if !slices.Contains(httpMethods, strings.ToLower(method.Value)) {
+					continue
+				}
+				label := strings.ToUpper(method.Value) + " " + route.Value
+				if id := mappingValue(op, "operationId"); id != nil && id.Value != "" {
+					label += " " + id.Value
+				}
+				index.blocks = append(index.blocks, index.block(label, method, op))
+			}
+		}
+	}
+	for _, container := range [][]string{{"components", "schemas"}, {"definitions"}} {
+		schemas := index.root
+		for _, key := range container {
+			schemas = mappingValue(schemas, key)
+		}
+		if schemas == nil || schemas.Kind != yaml.MappingNode {
+			continue
+		}
+		for i := 0; i+1 < len(schemas.Content); i += 2 {
+			index.blocks = append(index.blocks, index.block("schema "+schemas.Content[i].Value, schemas.Content[i], schemas.Content[i+1]))
+		}
+	}
+	return index, nil
+}
+
+// lineEndings turns \r\n and bare \r line endings into \n.
+var lineEndings = strings.NewReplacer("\r\n", "\n", "\r", "\n")
+
+// block spans key's line through the last line indented deeper than key. For
+// pretty-printed JSON the closing bracket at the key's indentation is kept.
+func (x *openAPIIndex) block(label string, key, value *yaml.Node) schemaBlock {
+	if key.Line < 1 || key.Line > len(x.lines) {
+		// only if the decoder and lines disagree on line breaks
+		line := min(max(key.Line, 1), len(x.lines))
+		return schemaBlock{label: label, start: line, end: line}
+	}
+	indent := leadingSpaces(x.lines[key.Line-1])
+	end := key.Line
+	for n := key.Line + 1; n <= len(x.lines); n++ {
+		line := x.lines[n-1]
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" {
+			continue
+		}
+		if leadingSpaces(line) <= indent {
+			if strings.HasPrefix(trimmed, "}") || strings.HasPrefix(trimmed, "]") {
+				end = n
+			}
+			break
+		}
+		end = n
+	}
+	return schemaBlock{label: label, start: key.Line, end: end, refs: collectRefs(value, nil)}
+}
+
+// resolveRefs returns the blocks for block's local $refs, following refs of
+// refs breadth first up to maxSchemaRefs.
+func (x *openAPIIndex) resolveRefs(block schemaBlock) []schemaBlock {
+	var out []schemaBlock
+	seen := map[string]bool{}
+	queue := append([]string{}, block.refs...)
+	for len(queue) > 0 && len(out) < maxSchemaRefs {
+		ref := queue[0]
+		queue = queue[1:]
+		if seen[ref] {
+			continue
+		}
+		seen[ref] = true
+		key, value := x.pointer(ref)
+		if key == nil {
+			continue
+		}
+		resolved := x.block(ref, key, value)
+		out = append(out, resolved)
+		queue = append(queue, resolved.refs...)
+	}
+	return out
+}
+
+// pointer resolves a local JSON pointer ("#/components/schemas/User") to its
+// key and value nodes.
+func (x *openAPIIndex) pointer(ref string) (*yaml.Node, *yaml.Node) {
+	if !strings.HasPrefix(ref, "#/") {
+		return nil, nil
+	}
+	var key *yaml.Node
+	node := x.root
+	for _, part := range strings.Split(strings.TrimPrefix(ref, "#/"), "/") {
+		part = strings.ReplaceAll(strings.ReplaceAll(part, "~1", "/"), "~0", "~")
+		key, node = mappingEntry(node, part)
+		if key == nil {
+			return nil, nil
+		}
+	}
+	return key, node
+}
+
+// mappingEntry returns the key and value nodes for key in a mapping node.
+func mappingEntry(node *yaml.Node, key string) (*yaml.Node, *yaml.Node) {
+	if node == nil || node.Kind != yaml.MappingNode {
+		return nil, nil
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			return node.Content[i], node.Content[i+1]
+		}
+	}
+	return nil, nil
+}
+
+func mappingValue(node *yaml.Node, key string) *yaml.Node {
+	_, value := mappingEntry(node, key)
+	return value
+}
+
+// collectRefs appends the $ref values under node, in document order.
+func collectRefs(node *yaml.Node, refs []string) []string {
+	if node == nil {
+		return refs
+	}
+	if node.Kind == yaml.MappingNode {
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			if node.Content[i].Value == "$ref" && node.Content[i+1].Kind == yaml.ScalarNode {
+				refs = append(refs, node.Content[i+1].Value)
+			}
+		}
+	}
+	for _, child := range node.Content {
+		refs = collectRefs(child, refs)
+	}
+	return refs
+}
+
+func leadingSpaces(line string) int {
+	return len(line) - len(strings.TrimLeft(line, " \t"))
+}
+
+var graphQLDefinition = regexp.MustCompile(`^\s*(?:extend\s+)?(type|input|enum|interface|union|scalar|schema|directive)\b\s*(@?[A-Za-z_]\w*)?`)
+
+var graphQLRootTypes = []string{"Query", "Mutation", "Subscription"}
+
+// graphQLBlocks finds the top-level definitions of a GraphQL SDL file by
+// brace depth, and lists the fields of the root operation types separately
+// as Query.field blocks. Descriptions and comments are skipped.
+func graphQLBlocks(lines []string) []schemaBlock {
+	var blocks []schemaBlock
+	inDescription := false
+	for n := 0; n < len(lines); n++ {
+		line := lines[n]
+		if strings.Count(line, `"""`)%2 == 1 {
+			inDescription = !inDescription
+			continue
+		}
+		if inDescription {
+			continue
+		}
+		match := graphQLDefinition.FindStringSubmatch(line)
+		if match == nil {
+			continue
+		}
+		kind, name := match[1], match[2]
+		label := kind
+		if name != "" && kind != "schema" {
+			label += " " + name
+		}
+		end, fields := graphQLBlockEnd(lines, n)
+		blocks = append(blocks, schemaBlock{label: label, start: n + 1, end: end + 1})
+		if kind == "type" && slices.Contains(graphQLRootTypes, name) {
+			for _, field := range fields {
+				blocks = append(blocks, schemaBlock{label: "field " + name + "." + field.name, start: field.line + 1, end: field.end + 1})
+			}
+		}
+		n = end
+	}
+	return blocks
+}
+
+type graphQLField struct {
+	name      string
+	line, end int
+}
+
+var graphQLFieldName = regexp.MustCompile(`^\s*([A-Za-z_]\w*)\s*[(:]`)
+
+// graphQLBlockEnd returns the 0-based last line of the definition starting
+// at line start, and the fields declared directly inside its braces.
+func graphQLBlockEnd(lines []string, start int) (int, []graphQLField) {
+	depth, opened := 0, false
+	var fields []graphQLField
+	for n := start; n < len(lines); n++ {
+		line := lines[n]
+		if i := strings.Index(line, "#"); i >= 0 {
+			line = line[:i]
+		}
+		if depth == 1 && n > start {
+			if match := graphQLFieldName.FindStringSubmatch(line); match != nil {
+				fields = append(fields, graphQLField{name: match[1], line: n, end: n})
+			}
+		}
+		for _, r := range line {
+			switch r {
+			case '{', '(':
+				depth++
+				opened = opened || r == '{'
+			case '}', ')':
+				depth--
+			}
+		}
+		if len(fields) > 0 && depth >= 1 {
+			fields[len(fields)-1].end = n
+		}
+		if opened && depth <= 0 {
+			return n, fields
+		}
+		if !opened && depth == 0 && n+1 < len(lines) {
+			// scalars and unions have no body; a union may continue on lines that start with |
+			next := strings.TrimSpace(lines[n+1])
+			if !strings.HasPrefix(next, "|") && !strings.HasPrefix(next, "{") && !strings.HasPrefix(next, "=") {
+				return n, fields
+			}
+		}
+	}
+	return len(lines) - 1, fields
+}
@@ -0,0 +1,118 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const testOpenAPI = `openapi: 3.0.3
+info:
+  title: Users
+  version: "1"
+paths:
+  /users/{id}:
+    get:
+      operationId: getUser
+      responses:
+        "200":
+          description: ok
+          content:
+            application/json:
+              schema:
+                $ref: "#/components/schemas/User"
+    delete:
+      operationId: deleteUser
+      responses:
+        "204":
+          description: gone
+components:
+  schemas:
+    User:
+      type: object
+      properties:
+        team:
+          $ref: "#/components/schemas/Team"
+    Team:
+      type: object
+`
+
+const testGraphQL = `"""
+The root query.
+"""
+type Query {
+  user(id: ID!): User
+  teams(
+    first: Int
+  ): [Team!]!
+}
+
+type User {
+  id: ID!
+}
+
+scalar Time
+`
+
+func TestAPISchema(t *testing.T) {
+	repoRoot := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(repoRoot, "api"), 0o755); err != nil {
+		t.Fatalf("failed to create dir: %v", err)
+	}
+	// api/mac.yaml ends its lines with a bare \r, which YAML also counts
+	for name, body := range map[string]string{"api/openapi.yaml": testOpenAPI, "api/mac.yaml": strings.ReplaceAll(testOpenAPI, "\n", "\r"), "schema.graphql": testGraphQL} {
+		if err := os.WriteFile(filepath.Join(repoRoot, name), []byte(body), 0o644); err != nil {
+			t.Fatalf("failed to write %s: %v", name, err)
+		}
+	}
+	tool := NewAPISchemaTool()
+	meta := Meta{RepoRoot: repoRoot, MaxBytes: 8192}
+	run := func(args map[string]any) apiSchemaOutput {
+		t.Helper()
+		input, _ := json.Marshal(args)
+		res, err := tool.Execute(context.Background(), input, meta)
+		if err != nil {
+			t.Fatalf("api_schema %v: %v", args, err)
+		}
+		return res.Payload.(apiSchemaOutput)
+	}
+
+	listing := run(map[string]any{"path": "api/openapi.yaml"})
+	want := []string{"GET /users/{id} getUser (line 7)", "DELETE /users/{id} deleteUser (line 16)", "schema User (line 23)", "schema Team (line 28)"}
+	if strings.Join(listing.Entries, "|") != strings.Join(want, "|") {
+		t.Fatalf("unexpected openapi listing: %q", listing.Entries)
+	}
+
+	if mac := run(map[string]any{"path": "api/mac.yaml"}); strings.Join(mac.Entries, "|") != strings.Join(want, "|") {
+		t.Fatalf("expected bare \\r line endings to number lines alike, got %q", mac.Entries)
+	}
+	if def := run(map[string]any{"path": "api/mac.yaml", "name": "schema Team"}).Definition; !strings.Contains(def, "28:     Team:") {
+		t.Fatalf("unexpected definition from bare \\r line endings:\n%s", def)
+	}
+
+	def := run(map[string]any{"path": "api/openapi.yaml", "name": "GET /users/{id}"}).Definition
+	for _, fragment := range []string{"(api/openapi.yaml:7-15)", "8:       operationId: getUser", "#/components/schemas/User (api/openapi.yaml:23-27)", "#/components/schemas/Team (api/openapi.yaml:28-29)"} {
+		if !strings.Contains(def, fragment) {
+			t.Fatalf("definition missing %q:\n%s", fragment, def)
+		}
+	}
+	if strings.Contains(def, "deleteUser") {
+		t.Fatalf("definition ran into the next operation:\n%s", def)
+	}
+
+	// without path, the root schema.graphql is found before api/openapi.yaml
+	graph := run(map[string]any{})
+	if graph.Kind != "graphql" || graph.Path != "schema.graphql" {
+		t.Fatalf("expected the root graphql schema by default, got %+v", graph)
+	}
+	want = []string{"type Query (line 4)", "field Query.user (line 5)", "field Query.teams (line 6)", "type User (line 11)", "scalar Time (line 15)"}
+	if strings.Join(graph.Entries, "|") != strings.Join(want, "|") {
+		t.Fatalf("unexpected graphql listing: %q", graph.Entries)
+	}
+	if def := run(map[string]any{"name": "Query.teams"}).Definition; !strings.Contains(def, "(schema.graphql:6-8)") {
+		t.Fatalf("unexpected field definition:\n%s", def)
+	}
+}
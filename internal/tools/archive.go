@@ -0,0 +1,323 @@
+package tools
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"strings"
+	"time"
+
+	"fi-cli/internal/util"
+)
+
+// maxArchiveExpansion bounds how many decompressed bytes one call may read
+// while scanning an archive, so a compression bomb fails instead of hanging.
+const maxArchiveExpansion = 256 << 20
+
+// DefaultArchiveReadBytes bounds archive_read output when no limit is configured.
+const DefaultArchiveReadBytes = 32 * 1024
+
+var errArchiveExpansion = fmt.Errorf("archive expands beyond %d bytes", maxArchiveExpansion)
+
+const archiveFormats = "zip (also jar), tar, tar.gz/tgz, or a single gzip file"
+
+type archiveEntry struct {
+	Name string
+	Size int64
+	Dir  bool
+}
+
+// archiveFormat picks the format from the file name.
+func archiveFormat(name string) (string, error) {
+	lower := strings.ToLower(name)
+	switch {
+	case strings.HasSuffix(lower, ".zip"), strings.HasSuffix(lower, ".jar"):
+		return "zip", nil
+	case strings.HasSuffix(lower, ".tar.gz"), strings.HasSuffix(lower, ".tgz"):
+		return "tar.gz", nil
+	case strings.HasSuffix(lower, ".tar"):
+		return "tar", nil
+	case strings.HasSuffix(lower, ".gz"):
+		return "gzip", nil
+	}
+	return "", fmt.Errorf("unsupported archive %s: expected %s", name, archiveFormats)
+}
+
+// walkArchive calls visit for each entry of the archive at the repo path p,
+// opened through RepoFS. visit reads the entry from r if it needs the
+// content and returns true to stop.
+func walkArchive(ctx context.Context, fsys *RepoFS, p string, visit func(entry archiveEntry, r io.Reader) (bool, error)) (string, error) {
+	name, err := fsys.Name(p)
+	if err != nil {
+		return "", err
+	}
+	format, err := archiveFormat(name)
+	if err != nil {
+		return "", err
+	}
+	data, err := fsys.ReadFile(name)
+	if err != nil {
+		return "", err
+	}
+	budget := &expansionReader{}
+	switch format {
+	case "zip":
+		zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+		if err != nil {
+			return "", fmt.Errorf("read %s: %w", name, err)
+		}
+		for _, file := range zr.File {
+			if ctx.Err() != nil {
+				return format, ctx.Err()
+			}
+			entry := archiveEntry{Name: file.Name, Size: int64(file.UncompressedSize64), Dir: file.FileInfo().IsDir()}
+			rc, err := file.Open()
+			if err != nil {
+				return format, fmt.Errorf("read %s: %s: %w", name, file.Name, err)
+			}
+			budget.r = rc
+			stop, err := visit(entry, budget)
+			rc.Close()
+			if err != nil || stop {
+				return format, err
+			}
+		}
+		return format, nil
+	case "gzip":
+		gz, err := gzip.NewReader(bytes.NewReader(data))
+		if err != nil {
+			return "", fmt.Errorf("read %s: %w", name, err)
+		}
+		defer gz.Close()
+		entryName := gz.Name
+		if entryName == "" {
+			entryName = strings.TrimSuffix(path.Base(name), path.Ext(name))
+		}
+		budget.r = gz
+		_, err = visit(archiveEntry{Name: entryName, Size: -1}, budget)
+		return format, err
+	}
+
+	var r io.Reader = bytes.NewReader(data)
+	if format == "tar.gz" {
+		gz, err := gzip.NewReader(r)
+		if err != nil {
+			return "", fmt.Errorf("read %s: %w", name, err)
+		}
+		defer gz.Close()
+		r = gz
+	}
+	budget.r = r
+	tr := tar.NewReader(budget)
+	for {
+		if ctx.Err() != nil {
+			return format, ctx.Err()
+		}
+		header, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			return format, nil
+		}
+		if err != nil {
+			return format, fmt.Errorf("read %s: %w", name, err)
+		}
+		entry := archiveEntry{Name: header.Name, Size: header.Size, Dir: header.Typeflag == tar.TypeDir}
+		if stop, err := visit(entry, tr); err != nil || stop {
+			return format, err
+		}
+	}
+}
+
+// expansionReader fails once more than maxArchiveExpansion bytes were read
+// through it across all entries.
+type expansionReader struct {
+	r    io.Reader
+	read int64
+}
+
+func (e *expansionReader) Read(p []byte) (int, error) {
+	n, err := e.r.Read(p)
+	e.read += int64(n)
+	if e.read > maxArchiveExpansion {
+		return n, errArchiveExpansion
+	}
+	return n, err
+}
+
+type ArchiveListTool struct{}
+
+// NewArchiveListTool constructs a tool that lists zip/tar/gzip entries.
+func NewArchiveListTool() *ArchiveListTool {
+	return &ArchiveListTool{}
+}
+
+func (a *ArchiveListTool) Name() string { return "archive_list" }
+
+func (a *ArchiveListTool) Description() string {
+	return "List the entries of an archive in the repository (" + archiveFormats + "), with their uncompressed sizes. Directories end with /."
+}
+
+func (a *ArchiveListTool) Schema() map[string]any {
+	return map[string]any{
+		"type": "object",
+		"properties": map[string]any{
+			"path":        map[string]any{"type": "string"},
+			"max_results": map[string]any{"type": "integer", "minimum": 1},
+		},
+		"required":             []string{"path"},
+		"additionalProperties": false,
+	}
+}
+
+type archiveListInput struct {
+	Path       string `json:"path"`
+	MaxResults int    `json:"max_results"`
+}
+
+type archiveListOutput struct {
+	Format     string   `json:"format"`
+	Entries    []string `json:"entries"`
+	Total      int      `json:"total"`
+	Truncated  bool     `json:"truncated"`
+	DurationMs int64    `json:"duration_ms"`
+}
+
+func (a *ArchiveListTool) Execute(ctx context.Context, input json.RawMessage, meta Meta) (Result, error) {
+	var args archiveListInput
+	if err := json.Unmarshal(input, &args); err != nil {
+		return Result{}, err
+	}
+	if strings.TrimSpace(args.Path) == "" {
+		return Result{}, errors.New("path is required")
+	}
+	limit := args.MaxResults
+	if limit <= 0 || (meta.MaxResults > 0 && limit > meta.MaxResults) {
+		limit = meta.MaxResults
+	}
+	if limit <= 0 {
+		limit = DefaultListEntries
+	}
+
+	start := time.Now()
+	var entries []string
+	total := 0
+	format, err := walkArchive(ctx, meta.FS(), args.Path, func(entry archiveEntry, _ io.Reader) (bool, error) {
+		total++
+		if len(entries) < limit {
+			switch {
+			case entry.Dir:
+				entries = append(entries, strings.TrimSuffix(entry.Name, "/")+"/")
+			case entry.Size < 0:
+				entries = append(entries, entry.Name)
+			default:
+				entries = append(entries, fmt.Sprintf("%s\t%d", entry.Name, entry.Size))
+			}
+		}
+		return false, nil
+	})
+	if err != nil {
+		return Result{}, err
+	}
+
+	output := archiveListOutput{Format: format, Entries: entries, Total: total, Truncated: total > len(entries), DurationMs: time.Since(start).Milliseconds()}
+	if output.Entries == nil {
+		output.Entries = []string{}
+	}
+	text := strings.Join(entries, "\n")
+	return Result{ToolName: a.Name(), Payload: output, Preview: meta.Preview(text), LineCount: len(entries), ByteCount: len(text), Truncated: output.Truncated, DurationMs: output.DurationMs}, nil
+}
+
+type ArchiveReadTool struct{}
+
+// NewArchiveReadTool constructs a tool that reads one text entry of an archive.
+func NewArchiveReadTool() *ArchiveReadTool {
+	return &ArchiveReadTool{}
+}
+
+func (a *ArchiveReadTool) Name() string { return "archive_read" }
+
+func (a *ArchiveReadTool) Description() string {
+	return "Read one text entry of an archive in the repository (" + archiveFormats + "). Use archive_list for entry names; a single gzip file has one entry."
+}
+
+func (a *ArchiveReadTool) Schema() map[string]any {
+	return map[string]any{
+		"type": "object",
+		"properties": map[string]any{
+			"path":  map[string]any{"type": "string"},
+			"entry": map[string]any{"type": "string"},
+		},
+		"required":             []string{"path", "entry"},
+		"additionalProperties": false,
+	}
+}
+
+type archiveReadInput struct {
+	Path  string `json:"path"`
+	Entry string `json:"entry"`
+}
+
+type archiveReadOutput struct {
+	Entry      string `json:"entry"`
+	Content    string `json:"content"`
+	Truncated  bool   `json:"truncated"`
+	DurationMs int64  `json:"duration_ms"`
+}
+
+func (a *ArchiveReadTool) Execute(ctx context.Context, input json.RawMessage, meta Meta) (Result, error) {
+	var args archiveReadInput
+	if err := json.Unmarshal(input, &args); err != nil {
+		return Result{}, err
+	}
+	if strings.TrimSpace(args.Path) == "" || strings.TrimSpace(args.Entry) == "" {
+		return Result{}, errors.New("path and entry are required")
+	}
+	maxBytes := meta.MaxBytes
+	if maxBytes <= 0 {
+		maxBytes = DefaultArchiveReadBytes
+	}
+	want := strings.TrimPrefix(path.Clean("/"+args.Entry), "/")
+	fsys := meta.FS()
+	if !fsys.Allowed(want) {
+		return Result{}, fmt.Errorf("%s: %w", args.Entry, ErrFileDenied)
+	}
+
+	start := time.Now()
+	var content []byte
+	found := false
+	_, err := walkArchive(ctx, fsys, args.Path, func(entry archiveEntry, r io.Reader) (bool, error) {
+		if strings.TrimPrefix(path.Clean("/"+entry.Name), "/") != want {
+			return false, nil
+		}
+		if entry.Dir {
+			return true, fmt.Errorf("%s is a directory", args.Entry)
+		}
+		found = true
+		var err error
+		content, err = io.ReadAll(io.LimitReader(r, int64(maxBytes)+1))
+		return true, err
+	})
+	if err != nil {
+		return Result{}, err
+	}
+	if !found {
+		return Result{}, fmt.Errorf("entry %s not found in %s", args.Entry, args.Path)
+	}
+	if bytes.IndexByte(content, 0) >= 0 {
+		return Result{}, fmt.Errorf("entry %s is binary", args.Entry)
+	}
+
+	text, truncated := util.TruncateBytes(util.RedactSecrets(string(content)), maxBytes)
+	output := archiveReadOutput{Entry: want, Content: text, Truncated: truncated, DurationMs: time.Since(start).Milliseconds()}
+	lines := strings.Count(text, "\n")
+	if text != "" && !strings.HasSuffix(text, "\n") {
+		lines++
+	}
+	return Result{ToolName: a.Name(), Payload: output, Preview: meta.Preview(text), LineCount: lines, ByteCount: len(text), Truncated: truncated, DurationMs: output.DurationMs}, nil
+}
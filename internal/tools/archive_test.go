@@ -0,0 +1,95 @@
+package tools
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeArchives(t *testing.T, repoRoot string) {
+	t.Helper()
+	files := map[string]string{"fixtures/config.yaml": "port: 8080\n", ".env": "TOKEN=1\n"}
+
+	var zipBuf bytes.Buffer
+	zw := zip.NewWriter(&zipBuf)
+	for name, body := range files {
+		w, _ := zw.Create(name)
+		_, _ = w.Write([]byte(body))
+	}
+	_ = zw.Close()
+
+	var tgzBuf bytes.Buffer
+	gz := gzip.NewWriter(&tgzBuf)
+	tw := tar.NewWriter(gz)
+	_ = tw.WriteHeader(&tar.Header{Name: "fixtures/", Typeflag: tar.TypeDir, Mode: 0o755})
+	for name, body := range files {
+		_ = tw.WriteHeader(&tar.Header{Name: name, Mode: 0o644, Size: int64(len(body))})
+		_, _ = tw.Write([]byte(body))
+	}
+	_ = tw.Close()
+	_ = gz.Close()
+
+	var gzBuf bytes.Buffer
+	single := gzip.NewWriter(&gzBuf)
+	_, _ = single.Write([]byte("line one\nline two\n"))
+	_ = single.Close()
+
+	for name, data := range map[string][]byte{"bundle.zip": zipBuf.Bytes(), "bundle.tgz": tgzBuf.Bytes(), "server.log.gz": gzBuf.Bytes()} {
+		if err := os.WriteFile(filepath.Join(repoRoot, name), data, 0o644); err != nil {
+			t.Fatalf("failed to write %s: %v", name, err)
+		}
+	}
+}
+
+func TestArchiveListAndRead(t *testing.T) {
+	repoRoot := t.TempDir()
+	writeArchives(t, repoRoot)
+	meta := Meta{RepoRoot: repoRoot, MaxResults: 10, MaxBytes: 1024}
+	list, read := NewArchiveListTool(), NewArchiveReadTool()
+
+	for _, archive := range []string{"bundle.zip", "bundle.tgz"} {
+		input, _ := json.Marshal(map[string]any{"path": archive})
+		res, err := list.Execute(context.Background(), input, meta)
+		if err != nil {
+			t.Fatalf("list %s: %v", archive, err)
+		}
+		if !strings.Contains(strings.Join(res.Payload.(archiveListOutput).Entries, "\n"), "fixtures/config.yaml\t11") {
+			t.Fatalf("list %s: missing entry in %v", archive, res.Payload)
+		}
+
+		input, _ = json.Marshal(map[string]any{"path": archive, "entry": "./fixtures/config.yaml"})
+		res, err = read.Execute(context.Background(), input, meta)
+		if err != nil {
+			t.Fatalf("read %s: %v", archive, err)
+		}
+		if got := res.Payload.(archiveReadOutput).Content; got != "port: 8080\n" {
+			t.Fatalf("read %s: got %q", archive, got)
+		}
+
+		input, _ = json.Marshal(map[string]any{"path": archive, "entry": ".env"})
+		if _, err := read.Execute(context.Background(), input, meta); err == nil {
+			t.Fatalf("read %s: expected a denylisted entry to be refused", archive)
+		}
+	}
+
+	input, _ := json.Marshal(map[string]any{"path": "server.log.gz", "entry": "server.log"})
+	res, err := read.Execute(context.Background(), input, Meta{RepoRoot: repoRoot, MaxBytes: 8})
+	if err != nil {
+		t.Fatalf("read gzip: %v", err)
+	}
+	if out := res.Payload.(archiveReadOutput); out.Content != "line …" || !out.Truncated {
+		t.Fatalf("expected a truncated gzip entry, got %+v", out)
+	}
+
+	input, _ = json.Marshal(map[string]any{"path": "../bundle.zip"})
+	if _, err := list.Execute(context.Background(), input, meta); err == nil {
+		t.Fatalf("expected a path outside the repo to be refused")
+	}
+}
@@ -0,0 +1,78 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+)
+
+// AskFunc shows a clarifying question to the user and returns their reply.
+// An empty reply means the user skipped it.
+type AskFunc func(ctx context.Context, question string, options []string) (string, error)
+
+type AskUserTool struct {
+	ask AskFunc
+}
+
+// NewAskUserTool constructs a tool that asks the user through ask. It is only
+// registered for interactive terminal runs.
+func NewAskUserTool(ask AskFunc) *AskUserTool {
+	return &AskUserTool{ask: ask}
+}
+
+func (t *AskUserTool) Name() string { return "ask_user" }
+
+func (t *AskUserTool) Description() string {
+	return "Ask the user one short clarifying question and wait for the reply. Use it only when the question is ambiguous in a way the repository cannot resolve (which service, which environment, which of two meanings), before searching. options lists suggested answers. Limited to one question per run."
+}
+
+func (t *AskUserTool) Schema() map[string]any {
+	return map[string]any{
+		"type": "object",
+		"properties": map[string]any{
+			"question": map[string]any{"type": "string"},
+			"options":  map[string]any{"type": "array", "items": map[string]any{"type": "string"}},
+		},
+		"required":             []string{"question"},
+		"additionalProperties": false,
+	}
+}
+
+type askUserInput struct {
+	Question string   `json:"question"`
+	Options  []string `json:"options"`
+}
+
+type askUserOutput struct {
+	Question   string `json:"question"`
+	Answer     string `json:"answer"`
+	Note       string `json:"note,omitempty"`
+	DurationMs int64  `json:"duration_ms"`
+}
+
+func (t *AskUserTool) Execute(ctx context.Context, input json.RawMessage, meta Meta) (Result, error) {
+	var args askUserInput
+	if err := json.Unmarshal(input, &args); err != nil {
+		return Result{}, err
+	}
+	args.Question = strings.TrimSpace(args.Question)
+	if args.Question == "" {
+		return Result{}, errors.New("question is required")
+	}
+	start := time.Now()
+	answer, err := t.ask(ctx, args.Question, args.Options)
+	if err != nil {
+		return Result{}, err
+	}
+	output := askUserOutput{Question: args.Question, Answer: strings.TrimSpace(answer), DurationMs: time.Since(start).Milliseconds()}
+	if output.Answer == "" {
+		output.Note = "The user skipped the question; proceed with the most likely reading and state that assumption in the answer."
+	}
+	text := output.Answer
+	if text == "" {
+		text = "(no answer)"
+	}
+	return Result{ToolName: t.Name(), Payload: output, Preview: meta.Preview(text), LineCount: 1, ByteCount: len(text), DurationMs: output.DurationMs}, nil
+}
@@ -0,0 +1,39 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestAskUserReturnsReply(t *testing.T) {
+	var asked []string
+	tool := NewAskUserTool(func(ctx context.Context, question string, options []string) (string, error) {
+		asked = append([]string{question}, options...)
+		return " staging \n", nil
+	})
+	res, err := tool.Execute(context.Background(), json.RawMessage(`{"question":"Which environment?","options":["staging","production"]}`), Meta{})
+	if err != nil {
+		t.Fatalf("execute: %v", err)
+	}
+	if !reflect.DeepEqual(asked, []string{"Which environment?", "staging", "production"}) {
+		t.Fatalf("unexpected prompt: %v", asked)
+	}
+	output := res.Payload.(askUserOutput)
+	if output.Answer != "staging" || output.Note != "" {
+		t.Fatalf("unexpected output: %+v", output)
+	}
+
+	skip := NewAskUserTool(func(ctx context.Context, question string, options []string) (string, error) { return "", nil })
+	res, err = skip.Execute(context.Background(), json.RawMessage(`{"question":"Which one?"}`), Meta{})
+	if err != nil {
+		t.Fatalf("execute: %v", err)
+	}
+	if output := res.Payload.(askUserOutput); output.Answer != "" || output.Note == "" {
+		t.Fatalf("expected a skip note, got %+v", output)
+	}
+	if _, err := skip.Execute(context.Background(), json.RawMessage(`{"question":"  "}`), Meta{}); err == nil {
+		t.Fatal("expected an error for an empty question")
+	}
+}
@@ -0,0 +1,88 @@
+package tools
+
+import (
+	"bytes"
+	"encoding/hex"
+	"fmt"
+	"unicode/utf8"
+
+	"fi-cli/internal/util"
+)
+
+const (
+	// binarySniffBytes is how much output is inspected to decide it is binary.
+	binarySniffBytes = 8000
+	// binaryDumpBytes is how much of binary output is hexdumped for the model.
+	binaryDumpBytes = 256
+	// binaryMagicBytes is how many leading bytes are reported as the magic.
+	binaryMagicBytes = 8
+)
+
+// binaryOutput describes command output that was replaced by a hexdump.
+type binaryOutput struct {
+	Size  int    `json:"size"`
+	Magic string `json:"magic"`
+	Type  string `json:"type,omitempty"`
+}
+
+var binarySignatures = []struct {
+	prefix []byte
+	name   string
+}{
+	{[]byte("\x89PNG\r\n\x1a\n"), "png image"},
+	{[]byte("\xff\xd8\xff"), "jpeg image"},
+	{[]byte("GIF8"), "gif image"},
+	{[]byte("%PDF-"), "pdf document"},
+	{[]byte("PK\x03\x04"), "zip archive"},
+	{[]byte("\x1f\x8b"), "gzip data"},
+	{[]byte("BZh"), "bzip2 data"},
+	{[]byte("\xfd7zXZ\x00"), "xz data"},
+	{[]byte("\x28\xb5\x2f\xfd"), "zstd data"},
+	{[]byte("\x7fELF"), "elf executable"},
+	{[]byte("\xcf\xfa\xed\xfe"), "mach-o executable"},
+	{[]byte("MZ"), "windows executable"},
+	{[]byte("\x00asm"), "wasm module"},
+	{[]byte("SQLite format 3\x00"), "sqlite database"},
+}
+
+// isBinaryOutput reports whether data looks like binary rather than text:
+// it has a NUL byte, is not UTF-8, or is mostly control bytes. Escape
+// sequences and backspaces count as text, since terminals emit them.
+func isBinaryOutput(data []byte) bool {
+	sample := data[:min(len(data), binarySniffBytes)]
+	if len(sample) == 0 {
+		return false
+	}
+	if bytes.IndexByte(sample, 0) != -1 {
+		return true
+	}
+	if !utf8.ValidString(util.TrimPartialRune(string(sample))) {
+		return true
+	}
+	control := 0
+	for _, b := range sample {
+		if b < 0x20 && b != '\t' && b != '\n' && b != '\r' && b != '\f' && b != '\b' && b != 0x1b {
+			control++
+		}
+	}
+	return control*10 > len(sample)
+}
+
+// describeBinary returns metadata for binary output and the text that
+// replaces it: a one-line summary and a hexdump of the first bytes.
+func describeBinary(data []byte) (binaryOutput, string) {
+	info := binaryOutput{Size: len(data), Magic: hex.EncodeToString(data[:min(len(data), binaryMagicBytes)])}
+	for _, sig := range binarySignatures {
+		if bytes.HasPrefix(data, sig.prefix) {
+			info.Type = sig.name
+			break
+		}
+	}
+	kind := info.Type
+	if kind == "" {
+		kind = "unknown type"
+	}
+	dumped := data[:min(len(data), binaryDumpBytes)]
+	text := fmt.Sprintf("[binary output: %d bytes, %s, magic %s; hexdump of the first %d bytes]\n%s", info.Size, kind, info.Magic, len(dumped), hex.Dump(dumped))
+	return info, text
+}
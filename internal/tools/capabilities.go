@@ -0,0 +1,51 @@
+package tools
+
+import (
+	"os/exec"
+	"sync"
+)
+
+// Capabilities records which optional binaries are on PATH, so prompts and
+// tool schemas only advertise what can actually run.
+type Capabilities struct {
+	Ripgrep bool
+	Git     bool
+	Node    bool
+	Docker  bool
+}
+
+var detectOnce = sync.OnceValue(func() Capabilities {
+	has := func(name string) bool {
+		_, err := exec.LookPath(name)
+		return err == nil
+	}
+	return Capabilities{Ripgrep: has("rg"), Git: has("git"), Node: has("node"), Docker: has("docker")}
+})
+
+// DetectCapabilities probes PATH once per process.
+func DetectCapabilities() Capabilities {
+	return detectOnce()
+}
+
+// Available lists the binaries that were found, by command name.
+func (c Capabilities) Available() []string {
+	return c.names(true)
+}
+
+// Missing lists the binaries that were not found, by command name.
+func (c Capabilities) Missing() []string {
+	return c.names(false)
+}
+
+func (c Capabilities) names(present bool) []string {
+	var names []string
+	for _, entry := range []struct {
+		name  string
+		found bool
+	}{{"rg", c.Ripgrep}, {"git", c.Git}, {"node", c.Node}, {"docker", c.Docker}} {
+		if entry.found == present {
+			names = append(names, entry.name)
+		}
+	}
+	return names
+}
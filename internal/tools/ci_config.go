@@ -0,0 +1,485 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"path"
+	"slices"
+	"strings"
+	"time"
+
+	"fi-cli/internal/util"
+
+	"go.yaml.in/yaml/v3"
+)
+
+// maxStepText caps how much of a step's command ci_config returns.
+const maxStepText = 120
+
+// gitlabReserved are top-level .gitlab-ci.yml keys that are not jobs.
+var gitlabReserved = []string{"stages", "variables", "include", "default", "workflow", "image", "services", "before_script", "after_script", "cache", "types"}
+
+type CIConfigTool struct{}
+
+// NewCIConfigTool constructs a tool that parses CI pipeline definitions.
+func NewCIConfigTool() *CIConfigTool {
+	return &CIConfigTool{}
+}
+
+func (c *CIConfigTool) Name() string { return "ci_config" }
+
+func (c *CIConfigTool) Description() string {
+	return "Parse CI pipelines (.github/workflows/*.yml, .gitlab-ci.yml, .circleci/config.yml) into triggers, jobs (runner or image, needs, conditions), and steps, with line numbers. Answers questions like \"what runs on every PR\". path selects one file; job filters by job name."
+}
+
+func (c *CIConfigTool) Schema() map[string]any {
+	return map[string]any{
+		"type": "object",
+		"properties": map[string]any{
+			"path": map[string]any{"type": "string"},
+			"job":  map[string]any{"type": "string"},
+		},
+		"required":             []string{},
+		"additionalProperties": false,
+	}
+}
+
+type ciConfigInput struct {
+	Path string `json:"path"`
+	Job  string `json:"job"`
+}
+
+type ciJob struct {
+	Name   string   `json:"name"`
+	Line   int      `json:"line"`
+	Stage  string   `json:"stage,omitempty"`
+	RunsOn string   `json:"runs_on,omitempty"`
+	Needs  []string `json:"needs,omitempty"`
+	// When holds the job's own conditions (if, rules, only/except, filters).
+	When  []string `json:"when,omitempty"`
+	Steps []string `json:"steps,omitempty"`
+}
+
+type ciPipeline struct {
+	Path     string   `json:"path"`
+	Provider string   `json:"provider"`
+	Name     string   `json:"name,omitempty"`
+	Triggers []string `json:"triggers,omitempty"`
+	Stages   []string `json:"stages,omitempty"`
+	Jobs     []ciJob  `json:"jobs"`
+	Error    string   `json:"error,omitempty"`
+}
+
+type ciConfigOutput struct {
+	Pipelines  []ciPipeline `json:"pipelines"`
+	DurationMs int64        `json:"duration_ms"`
+}
+
+func (c *CIConfigTool) Execute(ctx context.Context, input json.RawMessage, meta Meta) (Result, error) {
+	var args ciConfigInput
+	if len(input) > 0 {
+		if err := json.Unmarshal(input, &args); err != nil {
+			return Result{}, err
+		}
+	}
+	fsys := meta.FS()
+	var files []string
+	if strings.TrimSpace(args.Path) != "" {
+		name, err := fsys.Name(args.Path)
+		if err != nil {
+			return Result{}, err
+		}
+		files = []string{name}
+	} else {
+		files = findCIFiles(fsys)
+		if len(files) == 0 {
+			return Result{}, errors.New("no CI configuration found (.github/workflows, .gitlab-ci.yml, .circleci/config.yml)")
+		}
+	}
+
+	start := time.Now()
+	output := ciConfigOutput{}
+	for _, name := range files {
+		pipeline := ciPipeline{Path: name, Provider: ciProvider(name)}
+		data, err := fsys.ReadFile(name)
+		if err == nil {
+			err = parseCIConfig(data, &pipeline)
+		}
+		if err != nil {
+			pipeline.Error = err.Error()
+		}
+		if args.Job != "" {
+			pipeline.Jobs = slices.DeleteFunc(pipeline.Jobs, func(job ciJob) bool { return !strings.EqualFold(job.Name, args.Job) })
+			if len(pipeline.Jobs) == 0 && pipeline.Error == "" {
+				continue
+			}
+		}
+		if pipeline.Jobs == nil {
+			pipeline.Jobs = []ciJob{}
+		}
+		output.Pipelines = append(output.Pipelines, pipeline)
+	}
+	if args.Job != "" && len(output.Pipelines) == 0 {
+		return Result{}, fmt.Errorf("no CI job named %s", args.Job)
+	}
+	output.DurationMs = time.Since(start).Milliseconds()
+
+	text := renderCI(output.Pipelines)
+	return Result{ToolName: c.Name(), Payload: output, Preview: meta.Preview(text), LineCount: strings.Count(text, "\n") + 1, ByteCount: len(text), DurationMs: output.DurationMs}, nil
+}
+
+// findCIFiles lists the CI configuration files of the known providers.
+func findCIFiles(fsys *RepoFS) []string {
+	var files []string
+	if entries, err := fsys.ReadDir(".github/workflows"); err == nil {
+		for _, entry := range entries {
+			ext := strings.ToLower(path.Ext(entry.Name()))
+			if !entry.IsDir() && (ext == ".yml" || ext == ".yaml") {
+				files = append(files, ".github/workflows/"+entry.Name())
+			}
+		}
+	}
+	for _, name := range []string{".gitlab-ci.yml", ".circleci/config.yml"} {
+		if _, err := fsys.Stat(name); err == nil {
+			files = append(files, name)
+		}
+	}
+	return files
+}
+
+func ciProvider(name string) string {
+	switch {
+	case strings.HasPrefix(name, ".github/"):
+		return "github"
+	case strings.HasPrefix(name, ".circleci/"):
+		return "circleci"
+	case strings.Contains(path.Base(name), "gitlab-ci"):
+		return "gitlab"
+	}
+	return "github"
+}
+
+func parseCIConfig(data []byte, pipeline *ciPipeline) error {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return fmt.Errorf("parse %s: %w", pipeline.Path, err)
+	}
+	if len(doc.Content) == 0 || doc.Content[0].Kind != yaml.MappingNode {
+		return fmt.Errorf("parse %s: expected a mapping at the top level", pipeline.Path)
+	}
+	root := doc.Content[0]
+	switch pipeline.Provider {
+	case "gitlab":
+		parseGitLab(root, pipeline)
+	case "circleci":
+		parseCircleCI(root, pipeline)
+	default:
+		parseGitHubActions(root, pipeline)
+	}
+	return nil
+}
+
+func parseGitHubActions(root *yaml.Node, pipeline *ciPipeline) {
+	if name := mappingValue(root, "name"); name != nil {
+		pipeline.Name = name.Value
+	}
+	pipeline.Triggers = githubTriggers(mappingValue(root, "on"))
+	jobs := mappingValue(root, "jobs")
+	if jobs == nil || jobs.Kind != yaml.MappingNode {
+		return
+	}
+	for i := 0; i+1 < len(jobs.Content); i += 2 {
+		key, node := jobs.Content[i], jobs.Content[i+1]
+		job := ciJob{Name: key.Value, Line: key.Line, Needs: scalarOrList(mappingValue(node, "needs"))}
+		if runsOn := mappingValue(node, "runs-on"); runsOn != nil {
+			job.RunsOn = strings.Join(scalarOrList(runsOn), ",")
+		}
+		if cond := mappingValue(node, "if"); cond != nil {
+			job.When = append(job.When, "if: "+cond.Value)
+		}
+		if uses := mappingValue(node, "uses"); uses != nil {
+			job.Steps = append(job.Steps, "uses "+uses.Value)
+		}
+		for _, step := range sequenceValues(mappingValue(node, "steps")) {
+			job.Steps = append(job.Steps, githubStep(step))
+		}
+		pipeline.Jobs = append(pipeline.Jobs, job)
+	}
+}
+
+// githubTriggers renders the on: key, keeping branch and path filters.
+func githubTriggers(on *yaml.Node) []string {
+	if on == nil {
+		return nil
+	}
+	if on.Kind != yaml.MappingNode {
+		return scalarOrList(on)
+	}
+	var triggers []string
+	for i := 0; i+1 < len(on.Content); i += 2 {
+		event, config := on.Content[i].Value, on.Content[i+1]
+		var filters []string
+		for _, key := range []string{"branches", "branches-ignore", "tags", "paths", "types"} {
+			if values := scalarOrList(mappingValue(config, key)); len(values) > 0 {
+				filters = append(filters, key+": "+strings.Join(values, ","))
+			}
+		}
+		if event == "schedule" {
+			for _, item := range sequenceValues(config) {
+				if cron := mappingValue(item, "cron"); cron != nil {
+					filters = append(filters, "cron: "+cron.Value)
+				}
+			}
+		}
+		if len(filters) > 0 {
+			event += " (" + strings.Join(filters, "; ") + ")"
+		}
+		triggers = append(triggers, event)
+	}
+	return triggers
+}
+
+func githubStep(step *yaml.Node) string {
+	var text string
+	switch {
+	case mappingValue(step, "uses") != nil:
+		text = "uses " + mappingValue(step, "uses").Value
+	case mappingValue(step, "run") != nil:
+		text = "run " + firstLine(mappingValue(step, "run").Value)
+	}
+	if name := mappingValue(step, "name"); name != nil && name.Value != "" {
+		text = name.Value + ": " + text
+	}
+	return capStep(text)
+}
+
+func parseGitLab(root *yaml.Node, pipeline *ciPipeline) {
+	pipeline.Stages = scalarOrList(mappingValue(root, "stages"))
+	if workflow := mappingValue(root, "workflow"); workflow != nil {
+		pipeline.Triggers = gitlabRules(mappingValue(workflow, "rules"))
+	}
+	defaultImage := ""
+	if image := mappingValue(root, "image"); image != nil {
+		defaultImage = gitlabImage(image)
+	}
+	for i := 0; i+1 < len(root.Content); i += 2 {
+		key, node := root.Content[i], root.Content[i+1]
+		if strings.HasPrefix(key.Value, ".") || slices.Contains(gitlabReserved, key.Value) || node.Kind != yaml.MappingNode {
+			continue
+		}
+		job := ciJob{Name: key.Value, Line: key.Line, RunsOn: defaultImage}
+		if stage := mappingValue(node, "stage"); stage != nil {
+			job.Stage = stage.Value
+		}
+		if image := mappingValue(node, "image"); image != nil {
+			job.RunsOn = gitlabImage(image)
+		}
+		for _, need := range sequenceValues(mappingValue(node, "needs")) {
+			if need.Kind == yaml.MappingNode {
+				if name := mappingValue(need, "job"); name != nil {
+					job.Needs = append(job.Needs, name.Value)
+				}
+				continue
+			}
+			job.Needs = append(job.Needs, need.Value)
+		}
+		job.When = gitlabRules(mappingValue(node, "rules"))
+		for _, key := range []string{"only", "except", "when"} {
+			if values := scalarOrList(mappingValue(node, key)); len(values) > 0 {
+				job.When = append(job.When, key+": "+strings.Join(values, ","))
+			}
+		}
+		if extends := scalarOrList(mappingValue(node, "extends")); len(extends) > 0 {
+			job.Steps = append(job.Steps, "extends "+strings.Join(extends, ","))
+		}
+		for _, line := range scalarOrList(mappingValue(node, "script")) {
+			job.Steps = append(job.Steps, capStep(firstLine(line)))
+		}
+		pipeline.Jobs = append(pipeline.Jobs, job)
+	}
+}
+
+func gitlabImage(image *yaml.Node) string {
+	if name := mappingValue(image, "name"); name != nil {
+		return name.Value
+	}
+	return image.Value
+}
+
+func gitlabRules(rules *yaml.Node) []string {
+	var out []string
+	for _, rule := range sequenceValues(rules) {
+		var parts []string
+		for _, key := range []string{"if", "changes", "exists", "when"} {
+			if values := scalarOrList(mappingValue(rule, key)); len(values) > 0 {
+				parts = append(parts, key+": "+strings.Join(values, ","))
+			}
+		}
+		if len(parts) > 0 {
+			out = append(out, "rule "+strings.Join(parts, " "))
+		}
+	}
+	return out
+}
+
+func parseCircleCI(root *yaml.Node, pipeline *ciPipeline) {
+	jobs := mappingValue(root, "jobs")
+	index := map[string]int{}
+	if jobs != nil && jobs.Kind == yaml.MappingNode {
+		for i := 0; i+1 < len(jobs.Content); i += 2 {
+			key, node := jobs.Content[i], jobs.Content[i+1]
+			job := ciJob{Name: key.Value, Line: key.Line}
+			for _, executor := range []string{"docker", "machine", "macos", "executor"} {
+				value := mappingValue(node, executor)
+				if value == nil {
+					continue
+				}
+				job.RunsOn = executor
+				if images := sequenceValues(value); len(images) > 0 {
+					if image := mappingValue(images[0], "image"); image != nil {
+						job.RunsOn = image.Value
+					}
+				} else if value.Kind == yaml.ScalarNode {
+					job.RunsOn = value.Value
+				}
+				break
+			}
+			for _, step := range sequenceValues(mappingValue(node, "steps")) {
+				job.Steps = append(job.Steps, circleStep(step))
+			}
+			index[job.Name] = len(pipeline.Jobs)
+			pipeline.Jobs = append(pipeline.Jobs, job)
+		}
+	}
+	workflows := mappingValue(root, "workflows")
+	if workflows == nil || workflows.Kind != yaml.MappingNode {
+		return
+	}
+	for i := 0; i+1 < len(workflows.Content); i += 2 {
+		name, workflow := workflows.Content[i].Value, workflows.Content[i+1]
+		if workflow.Kind != yaml.MappingNode {
+			continue
+		}
+		if triggers := mappingValue(workflow, "triggers"); triggers != nil {
+			for _, trigger := range sequenceValues(triggers) {
+				if cron := mappingValue(mappingValue(trigger, "schedule"), "cron"); cron != nil {
+					pipeline.Triggers = append(pipeline.Triggers, "workflow "+name+" (cron: "+cron.Value+")")
+				}
+			}
+		} else {
+			pipeline.Triggers = append(pipeline.Triggers, "workflow "+name+" (push)")
+		}
+		for _, entry := range sequenceValues(mappingValue(workflow, "jobs")) {
+			jobName, config := entry.Value, (*yaml.Node)(nil)
+			if entry.Kind == yaml.MappingNode && len(entry.Content) == 2 {
+				jobName, config = entry.Content[0].Value, entry.Content[1]
+			}
+			i, ok := index[jobName]
+			if !ok {
+				continue
+			}
+			job := &pipeline.Jobs[i]
+			job.Needs = append(job.Needs, scalarOrList(mappingValue(config, "requires"))...)
+			when := "workflow " + name
+			if filters := mappingValue(config, "filters"); filters != nil {
+				for _, ref := range []string{"branches", "tags"} {
+					for _, rule := range []string{"only", "ignore"} {
+						if values := scalarOrList(mappingValue(mappingValue(filters, ref), rule)); len(values) > 0 {
+							when += fmt.Sprintf(" %s %s: %s", ref, rule, strings.Join(values, ","))
+						}
+					}
+				}
+			}
+			job.When = append(job.When, when)
+		}
+	}
+}
+
+func circleStep(step *yaml.Node) string {
+	if step.Kind == yaml.ScalarNode {
+		return step.Value
+	}
+	if step.Kind != yaml.MappingNode || len(step.Content) < 2 {
+		return ""
+	}
+	kind, body := step.Content[0].Value, step.Content[1]
+	if kind != "run" {
+		return capStep(kind)
+	}
+	if body.Kind == yaml.ScalarNode {
+		return capStep("run " + firstLine(body.Value))
+	}
+	text := ""
+	if command := mappingValue(body, "command"); command != nil {
+		text = "run " + firstLine(command.Value)
+	}
+	if name := mappingValue(body, "name"); name != nil {
+		text = name.Value + ": " + text
+	}
+	return capStep(text)
+}
+
+// scalarOrList reads a scalar or a sequence of scalars.
+func scalarOrList(node *yaml.Node) []string {
+	if node == nil {
+		return nil
+	}
+	if node.Kind == yaml.ScalarNode {
+		if node.Value == "" {
+			return nil
+		}
+		return []string{node.Value}
+	}
+	return scalarList(node)
+}
+
+func firstLine(text string) string {
+	text = strings.TrimSpace(text)
+	if first, _, multi := strings.Cut(text, "\n"); multi {
+		return strings.TrimSpace(first) + " ..."
+	}
+	return text
+}
+
+func capStep(text string) string {
+	if len(text) > maxStepText {
+		return util.CutBytes(text, maxStepText) + "..."
+	}
+	return text
+}
+
+func renderCI(pipelines []ciPipeline) string {
+	var b strings.Builder
+	for _, pipeline := range pipelines {
+		fmt.Fprintf(&b, "%s (%s)", pipeline.Path, pipeline.Provider)
+		if pipeline.Name != "" {
+			fmt.Fprintf(&b, " %q", pipeline.Name)
+		}
+		b.WriteString("\n")
+		if pipeline.Error != "" {
+			fmt.Fprintf(&b, "  error: %s\n", pipeline.Error)
+		}
+		if len(pipeline.Triggers) > 0 {
+			fmt.Fprintf(&b, "  on: %s\n", strings.Join(pipeline.Triggers, "; "))
+		}
+		for _, job := range pipeline.Jobs {
+			fmt.Fprintf(&b, "  job %s (line %d)", job.Name, job.Line)
+			if job.Stage != "" {
+				fmt.Fprintf(&b, " stage %s", job.Stage)
+			}
+			if len(job.Needs) > 0 {
+				fmt.Fprintf(&b, " needs %s", strings.Join(job.Needs, ","))
+			}
+			if len(job.When) > 0 {
+				fmt.Fprintf(&b, " when %s", strings.Join(job.When, "; "))
+			}
+			b.WriteString("\n")
+			for _, step := range job.Steps {
+				fmt.Fprintf(&b, "    - %s\n", step)
+			}
+		}
+	}
+	return strings.TrimSuffix(b.String(), "\n")
+}
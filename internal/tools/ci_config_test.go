@@ -0,0 +1,135 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+const testWorkflow = `name: CI
+on:
+  push:
+    branches: [main]
+  pull_request:
+  schedule:
+    - cron: "0 3 * * *"
+jobs:
+  test:
+    runs-on: ubuntu-latest
+    steps:
+      - uses: actions/checkout@v4
+      - name: Test
+        run: |
+          go vet ./...
+          go test ./...
+  deploy:
+    needs: test
+    if: github.ref == 'refs/heads/main'
+    uses: ./.github/workflows/deploy.yml
+`
+
+const testGitLab = `stages: [build, test]
+image: golang:1.24
+.common:
+  before_script: [go version]
+build:
+  stage: build
+  script:
+    - go build ./...
+lint:
+  stage: test
+  image:
+    name: golangci/golangci-lint
+  needs: [build]
+  rules:
+    - if: $CI_PIPELINE_SOURCE == "merge_request_event"
+  script: golangci-lint run
+`
+
+const testCircleCI = `version: 2.1
+jobs:
+  build:
+    docker:
+      - image: cimg/go:1.24
+    steps:
+      - checkout
+      - run: go test ./...
+workflows:
+  main:
+    jobs:
+      - build:
+          filters:
+            branches:
+              only: main
+`
+
+func TestCIConfigParsesProviders(t *testing.T) {
+	root := t.TempDir()
+	files := map[string]string{
+		".github/workflows/ci.yml": testWorkflow,
+		".gitlab-ci.yml":           testGitLab,
+		".circleci/config.yml":     testCircleCI,
+	}
+	for name, content := range files {
+		target := filepath.Join(root, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(target), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(target, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	res, err := NewCIConfigTool().Execute(context.Background(), json.RawMessage(`{}`), Meta{RepoRoot: root})
+	if err != nil {
+		t.Fatalf("execute: %v", err)
+	}
+	output := res.Payload.(ciConfigOutput)
+	if len(output.Pipelines) != 3 {
+		t.Fatalf("expected three pipelines, got %+v", output.Pipelines)
+	}
+
+	github := output.Pipelines[0]
+	wantTriggers := []string{"push (branches: main)", "pull_request", "schedule (cron: 0 3 * * *)"}
+	if github.Provider != "github" || github.Name != "CI" || !reflect.DeepEqual(github.Triggers, wantTriggers) {
+		t.Fatalf("unexpected workflow: %+v", github)
+	}
+	wantTest := ciJob{Name: "test", Line: 9, RunsOn: "ubuntu-latest", Steps: []string{"uses actions/checkout@v4", "Test: run go vet ./... ..."}}
+	if !reflect.DeepEqual(github.Jobs[0], wantTest) {
+		t.Fatalf("unexpected test job: %+v", github.Jobs[0])
+	}
+	wantDeploy := ciJob{Name: "deploy", Line: 17, Needs: []string{"test"}, When: []string{"if: github.ref == 'refs/heads/main'"}, Steps: []string{"uses ./.github/workflows/deploy.yml"}}
+	if !reflect.DeepEqual(github.Jobs[1], wantDeploy) {
+		t.Fatalf("unexpected deploy job: %+v", github.Jobs[1])
+	}
+
+	gitlab := output.Pipelines[1]
+	if gitlab.Provider != "gitlab" || !reflect.DeepEqual(gitlab.Stages, []string{"build", "test"}) || len(gitlab.Jobs) != 2 {
+		t.Fatalf("unexpected gitlab pipeline: %+v", gitlab)
+	}
+	wantLint := ciJob{Name: "lint", Line: 9, Stage: "test", RunsOn: "golangci/golangci-lint", Needs: []string{"build"}, When: []string{`rule if: $CI_PIPELINE_SOURCE == "merge_request_event"`}, Steps: []string{"golangci-lint run"}}
+	if gitlab.Jobs[0].RunsOn != "golang:1.24" || !reflect.DeepEqual(gitlab.Jobs[1], wantLint) {
+		t.Fatalf("unexpected gitlab jobs: %+v", gitlab.Jobs)
+	}
+
+	circle := output.Pipelines[2]
+	wantBuild := ciJob{Name: "build", Line: 3, RunsOn: "cimg/go:1.24", When: []string{"workflow main branches only: main"}, Steps: []string{"checkout", "run go test ./..."}}
+	if circle.Provider != "circleci" || !reflect.DeepEqual(circle.Jobs, []ciJob{wantBuild}) {
+		t.Fatalf("unexpected circleci pipeline: %+v", circle)
+	}
+
+	res, err = NewCIConfigTool().Execute(context.Background(), json.RawMessage(`{"job":"lint"}`), Meta{RepoRoot: root})
+	if err != nil {
+		t.Fatalf("execute job filter: %v", err)
+	}
+	output = res.Payload.(ciConfigOutput)
+	if len(output.Pipelines) != 1 || len(output.Pipelines[0].Jobs) != 1 || output.Pipelines[0].Jobs[0].Name != "lint" {
+		t.Fatalf("expected only the lint job, got %+v", output.Pipelines)
+	}
+	if _, err := NewCIConfigTool().Execute(context.Background(), json.RawMessage(`{"job":"missing"}`), Meta{RepoRoot: root}); err == nil {
+		t.Fatal("expected an error for an unknown job")
+	}
+}
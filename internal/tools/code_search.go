@@ -0,0 +1,329 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+
+	"fi-cli/internal/util"
+
+	retryablehttp "github.com/hashicorp/go-retryablehttp"
+)
+
+// Backends code_search can query.
+const (
+	CodeSearchGitHub  = "github"
+	CodeSearchGrepApp = "grep.app"
+)
+
+const (
+	githubAPIURL  = "https://api.github.com"
+	grepAppAPIURL = "https://grep.app"
+	// maxCodeSnippet caps one result's snippet before the result set is
+	// fitted to meta.MaxBytes.
+	maxCodeSnippet = 1500
+)
+
+var htmlTag = regexp.MustCompile(`<[^>]*>`)
+
+// CodeSearchTool searches public source code on GitHub or grep.app, for
+// "how do other projects configure X" questions.
+type CodeSearchTool struct {
+	backend string
+	baseURL string
+	token   string
+	client  *retryablehttp.Client
+}
+
+// NewGitHubCodeSearchTool constructs code_search backed by GitHub code search,
+// which needs a token.
+func NewGitHubCodeSearchTool(token string) *CodeSearchTool {
+	return newCodeSearchTool(CodeSearchGitHub, githubAPIURL, token)
+}
+
+// NewGrepAppCodeSearchTool constructs code_search backed by grep.app, which
+// needs no credentials.
+func NewGrepAppCodeSearchTool() *CodeSearchTool {
+	return newCodeSearchTool(CodeSearchGrepApp, grepAppAPIURL, "")
+}
+
+func newCodeSearchTool(backend, baseURL, token string) *CodeSearchTool {
+	client := retryablehttp.NewClient()
+	client.RetryMax = 2
+	client.Logger = nil
+	return &CodeSearchTool{backend: backend, baseURL: baseURL, token: token, client: client}
+}
+
+func (c *CodeSearchTool) Name() string { return "code_search" }
+
+func (c *CodeSearchTool) Description() string {
+	return fmt.Sprintf("Search public source code on %s for how other projects use an API or configure a tool. Returns repository, path, URL, and the matching lines. This is other people's code, not this repository.", c.backend)
+}
+
+func (c *CodeSearchTool) Schema() map[string]any {
+	return map[string]any{
+		"type": "object",
+		"properties": map[string]any{
+			"query":       map[string]any{"type": "string", "description": "Code to find, e.g. \"retryablehttp.NewClient\" or \"[tool.ruff]\""},
+			"language":    map[string]any{"type": "string", "description": "Only files in this language, e.g. Go, TypeScript, TOML"},
+			"repo":        map[string]any{"type": "string", "description": "Only this repository, as owner/name"},
+			"path":        map[string]any{"type": "string", "description": "Only files whose path contains this, e.g. .github/workflows"},
+			"num_results": map[string]any{"type": "integer", "minimum": 1, "maximum": 10},
+		},
+		"required":             []string{"query"},
+		"additionalProperties": false,
+	}
+}
+
+type codeSearchInput struct {
+	Query      string `json:"query"`
+	Language   string `json:"language"`
+	Repo       string `json:"repo"`
+	Path       string `json:"path"`
+	NumResults int    `json:"num_results"`
+}
+
+type codeSearchResult struct {
+	Repo    string `json:"repo"`
+	Path    string `json:"path"`
+	URL     string `json:"url"`
+	Snippet string `json:"snippet"`
+}
+
+type codeSearchOutput struct {
+	Backend    string             `json:"backend"`
+	Total      int                `json:"total"`
+	Results    []codeSearchResult `json:"results"`
+	Truncated  bool               `json:"truncated"`
+	DurationMs int64              `json:"duration_ms"`
+}
+
+func (c *CodeSearchTool) Execute(ctx context.Context, input json.RawMessage, meta Meta) (Result, error) {
+	var args codeSearchInput
+	if err := json.Unmarshal(input, &args); err != nil {
+		return Result{}, err
+	}
+	args.Query = strings.TrimSpace(args.Query)
+	if args.Query == "" {
+		return Result{}, errors.New("query is required")
+	}
+	if args.Repo != "" && strings.Count(args.Repo, "/") != 1 {
+		return Result{}, fmt.Errorf("invalid repo %q (expected owner/name)", args.Repo)
+	}
+	if args.NumResults <= 0 {
+		args.NumResults = 5
+	}
+	if args.NumResults > 10 {
+		args.NumResults = 10
+	}
+
+	start := time.Now()
+	ctx, cancel := context.WithTimeout(ctx, time.Duration(meta.ToolTimeoutSeconds)*time.Second)
+	defer cancel()
+
+	var output codeSearchOutput
+	var err error
+	switch c.backend {
+	case CodeSearchGitHub:
+		output, err = c.searchGitHub(ctx, args)
+	case CodeSearchGrepApp:
+		output, err = c.searchGrepApp(ctx, args)
+	default:
+		err = fmt.Errorf("unknown code search backend %q", c.backend)
+	}
+	if err != nil {
+		return Result{}, err
+	}
+	output.Backend = c.backend
+	if len(output.Results) > args.NumResults {
+		output.Results = output.Results[:args.NumResults]
+	}
+	for i := range output.Results {
+		snippet, cut := util.TruncateBytes(util.RedactSecrets(strings.TrimSpace(output.Results[i].Snippet)), maxCodeSnippet)
+		output.Results[i].Snippet = snippet
+		output.Truncated = output.Truncated || cut
+	}
+	byteCount := fitCodeResults(&output, meta.MaxBytes)
+	output.DurationMs = time.Since(start).Milliseconds()
+
+	var preview strings.Builder
+	for i, result := range output.Results {
+		if i == 3 {
+			break
+		}
+		fmt.Fprintf(&preview, "%s %s\n%s\n", result.Repo, result.Path, result.Snippet)
+	}
+	text := meta.Preview(strings.TrimSpace(preview.String()))
+	return Result{ToolName: c.Name(), Payload: output, Preview: text, LineCount: strings.Count(text, "\n") + 1, ByteCount: byteCount, Truncated: output.Truncated, DurationMs: output.DurationMs}, nil
+}
+
+// fitCodeResults drops the last results until the output fits maxBytes.
+func fitCodeResults(output *codeSearchOutput, maxBytes int) int {
+	data, _ := json.Marshal(output)
+	for maxBytes > 0 && len(data) > maxBytes && len(output.Results) > 1 {
+		output.Results = output.Results[:len(output.Results)-1]
+		output.Truncated = true
+		data, _ = json.Marshal(output)
+	}
+	return len(data)
+}
+
+func (c *CodeSearchTool) searchGitHub(ctx context.Context, args codeSearchInput) (codeSearchOutput, error) {
+	if c.token == "" {
+		return codeSearchOutput{}, errors.New("GITHUB_TOKEN is missing")
+	}
+	terms := []string{args.Query}
+	if args.Language != "" {
+		terms = append(terms, "language:"+qualifier(args.Language))
+	}
+	if args.Repo != "" {
+		terms = append(terms, "repo:"+args.Repo)
+	}
+	if args.Path != "" {
+		terms = append(terms, "path:"+qualifier(args.Path))
+	}
+	query := url.Values{"q": {strings.Join(terms, " ")}, "per_page": {fmt.Sprint(args.NumResults)}}
+	var raw struct {
+		TotalCount int `json:"total_count"`
+		Items      []struct {
+			Path       string `json:"path"`
+			HTMLURL    string `json:"html_url"`
+			Repository struct {
+				FullName string `json:"full_name"`
+			} `json:"repository"`
+			TextMatches []struct {
+				Fragment string `json:"fragment"`
+			} `json:"text_matches"`
+		} `json:"items"`
+	}
+	headers := map[string]string{
+		"Accept":               "application/vnd.github.text-match+json",
+		"Authorization":        "Bearer " + c.token,
+		"X-GitHub-Api-Version": "2022-11-28",
+	}
+	if err := c.get(ctx, "/search/code?"+query.Encode(), headers, &raw); err != nil {
+		return codeSearchOutput{}, err
+	}
+	output := codeSearchOutput{Total: raw.TotalCount, Results: []codeSearchResult{}}
+	for _, item := range raw.Items {
+		fragments := make([]string, 0, len(item.TextMatches))
+		for _, match := range item.TextMatches {
+			fragments = append(fragments, strings.TrimSpace(match.Fragment))
+		}
+		output.Results = append(output.Results, codeSearchResult{Repo: item.Repository.FullName, Path: item.Path, URL: item.HTMLURL, Snippet: strings.Join(fragments, "\n"+util.Ellipsis+"\n")})
+	}
+	return output, nil
+}
+
+func (c *CodeSearchTool) searchGrepApp(ctx context.Context, args codeSearchInput) (codeSearchOutput, error) {
+	query := url.Values{"q": {args.Query}}
+	if args.Language != "" {
+		query.Set("f.lang", args.Language)
+	}
+	if args.Repo != "" {
+		query.Set("f.repo.pattern", args.Repo)
+	}
+	if args.Path != "" {
+		query.Set("f.path.pattern", args.Path)
+	}
+	var raw struct {
+		Hits struct {
+			Total int `json:"total"`
+			Hits  []struct {
+				Repo    grepAppField `json:"repo"`
+				Path    grepAppField `json:"path"`
+				Branch  grepAppField `json:"branch"`
+				Content struct {
+					Snippet string `json:"snippet"`
+				} `json:"content"`
+			} `json:"hits"`
+		} `json:"hits"`
+	}
+	if err := c.get(ctx, "/api/search?"+query.Encode(), nil, &raw); err != nil {
+		return codeSearchOutput{}, err
+	}
+	output := codeSearchOutput{Total: raw.Hits.Total, Results: []codeSearchResult{}}
+	for _, hit := range raw.Hits.Hits {
+		branch := string(hit.Branch)
+		if branch == "" {
+			branch = "HEAD"
+		}
+		output.Results = append(output.Results, codeSearchResult{
+			Repo:    string(hit.Repo),
+			Path:    string(hit.Path),
+			URL:     "https://github.com/" + string(hit.Repo) + "/blob/" + branch + "/" + string(hit.Path),
+			Snippet: htmlSnippet(hit.Content.Snippet),
+		})
+	}
+	return output, nil
+}
+
+// grepAppField is a grep.app hit field, sent either as a string or as an
+// object with the value in "raw".
+type grepAppField string
+
+func (f *grepAppField) UnmarshalJSON(data []byte) error {
+	var text string
+	if err := json.Unmarshal(data, &text); err == nil {
+		*f = grepAppField(text)
+		return nil
+	}
+	var wrapped struct {
+		Raw string `json:"raw"`
+	}
+	if err := json.Unmarshal(data, &wrapped); err != nil {
+		return err
+	}
+	*f = grepAppField(wrapped.Raw)
+	return nil
+}
+
+// htmlSnippet turns grep.app's highlighted HTML table of lines into plain
+// text, one source line per line.
+func htmlSnippet(snippet string) string {
+	snippet = strings.NewReplacer("</tr>", "\n", "<br>", "\n", "<br/>", "\n").Replace(snippet)
+	lines := strings.Split(html.UnescapeString(htmlTag.ReplaceAllString(snippet, "")), "\n")
+	out := lines[:0]
+	for _, line := range lines {
+		if strings.TrimSpace(line) != "" {
+			out = append(out, strings.TrimRight(line, " \t"))
+		}
+	}
+	return strings.Join(out, "\n")
+}
+
+// qualifier quotes a GitHub search qualifier value that contains spaces.
+func qualifier(value string) string {
+	if strings.ContainsAny(value, " \t") {
+		return `"` + value + `"`
+	}
+	return value
+}
+
+func (c *CodeSearchTool) get(ctx context.Context, endpoint string, headers map[string]string, out any) error {
+	request, err := retryablehttp.NewRequestWithContext(ctx, http.MethodGet, c.baseURL+endpoint, nil)
+	if err != nil {
+		return err
+	}
+	for key, value := range headers {
+		request.Header.Set(key, value)
+	}
+	resp, err := c.client.Do(request)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		b, _ := io.ReadAll(io.LimitReader(resp.Body, 2048))
+		return fmt.Errorf("%s code search failed (%s): %s", c.backend, resp.Status, strings.TrimSpace(string(b)))
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
@@ -0,0 +1,89 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestCodeSearchGitHub(t *testing.T) {
+	var query string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer tok" || r.URL.Path != "/search/code" {
+			http.Error(w, `{"message":"Bad credentials"}`, http.StatusUnauthorized)
+			return
+		}
+		query = r.URL.Query().Get("q")
+		_, _ = w.Write([]byte(`{"total_count":120,"items":[
+			{"path":"sdk/client.go","html_url":"https://github.com/hashicorp/vault/blob/abc/sdk/client.go","repository":{"full_name":"hashicorp/vault"},
+			 "text_matches":[{"fragment":"c := retryablehttp.NewClient()"},{"fragment":"key := \"sk-0123456789abcdefghijklmnop\""}]},
+			{"path":"b.go","html_url":"https://github.com/a/b/blob/main/b.go","repository":{"full_name":"a/b"},"text_matches":[]}]}`))
+	}))
+	defer server.Close()
+	tool := NewGitHubCodeSearchTool("tok")
+	tool.baseURL = server.URL
+	meta := Meta{ToolTimeoutSeconds: 5, MaxBytes: 30 * 1024}
+
+	result, err := tool.Execute(context.Background(), json.RawMessage(`{"query":"retryablehttp.NewClient","language":"Go","repo":"hashicorp/vault","path":"sdk dir","num_results":1}`), meta)
+	if err != nil {
+		t.Fatalf("search failed: %v", err)
+	}
+	if query != `retryablehttp.NewClient language:Go repo:hashicorp/vault path:"sdk dir"` {
+		t.Fatalf("unexpected query %q", query)
+	}
+	output := result.Payload.(codeSearchOutput)
+	if output.Backend != CodeSearchGitHub || output.Total != 120 || len(output.Results) != 1 || output.Results[0].Repo != "hashicorp/vault" || !strings.Contains(output.Results[0].Snippet, "NewClient") {
+		t.Fatalf("unexpected output %+v", output)
+	}
+	if strings.Contains(output.Results[0].Snippet, "sk-0123456789") {
+		t.Fatalf("expected the token in the snippet to be redacted, got %q", output.Results[0].Snippet)
+	}
+
+	tool.token = "wrong"
+	if _, err := tool.Execute(context.Background(), json.RawMessage(`{"query":"x"}`), meta); err == nil || !strings.Contains(err.Error(), "Bad credentials") {
+		t.Fatalf("expected the API error, got %v", err)
+	}
+	if _, err := tool.Execute(context.Background(), json.RawMessage(`{"query":"x","repo":"vault"}`), meta); err == nil || !strings.Contains(err.Error(), "owner/name") {
+		t.Fatalf("expected an invalid repo error, got %v", err)
+	}
+}
+
+func TestCodeSearchGrepApp(t *testing.T) {
+	var params map[string][]string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		params = r.URL.Query()
+		_, _ = w.Write([]byte(`{"hits":{"total":2,"hits":[
+			{"repo":{"raw":"astral-sh/ruff"},"path":{"raw":"pyproject.toml"},"branch":{"raw":"main"},
+			 "content":{"snippet":"<table><tr><td>1</td><td><mark>[tool.ruff]</mark></td></tr><tr><td>2</td><td>line-length = 88 &amp; more</td></tr></table>"}},
+			{"repo":"x/y","path":"a.toml","content":{"snippet":"<pre>[tool.ruff]</pre>"}}]}}`))
+	}))
+	defer server.Close()
+	tool := NewGrepAppCodeSearchTool()
+	tool.baseURL = server.URL
+
+	result, err := tool.Execute(context.Background(), json.RawMessage(`{"query":"[tool.ruff]","language":"TOML"}`), Meta{ToolTimeoutSeconds: 5, MaxBytes: 30 * 1024})
+	if err != nil {
+		t.Fatalf("search failed: %v", err)
+	}
+	if params["q"][0] != "[tool.ruff]" || params["f.lang"][0] != "TOML" {
+		t.Fatalf("unexpected params %v", params)
+	}
+	output := result.Payload.(codeSearchOutput)
+	if len(output.Results) != 2 || output.Results[0].URL != "https://github.com/astral-sh/ruff/blob/main/pyproject.toml" || output.Results[1].URL != "https://github.com/x/y/blob/HEAD/a.toml" {
+		t.Fatalf("unexpected results %+v", output.Results)
+	}
+	if output.Results[0].Snippet != "1[tool.ruff]\n2line-length = 88 & more" {
+		t.Fatalf("expected plain text lines, got %q", output.Results[0].Snippet)
+	}
+
+	small, err := tool.Execute(context.Background(), json.RawMessage(`{"query":"[tool.ruff]"}`), Meta{ToolTimeoutSeconds: 5, MaxBytes: 250})
+	if err != nil {
+		t.Fatalf("search failed: %v", err)
+	}
+	if output := small.Payload.(codeSearchOutput); len(output.Results) != 1 || !output.Truncated {
+		t.Fatalf("expected results dropped to fit max bytes, got %+v", output)
+	}
+}
@@ -0,0 +1,422 @@
+package tools
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"fi-cli/internal/util"
+)
+
+const (
+	// DefaultPreviewRows is how many rows data_preview returns by default.
+	DefaultPreviewRows = 10
+	maxPreviewRows     = 100
+	// inferRows is how many rows are sampled to infer column types.
+	inferRows = 200
+	// maxCellBytes caps each value in the preview.
+	maxCellBytes = 200
+)
+
+type DataPreviewTool struct{}
+
+// NewDataPreviewTool constructs a tool that previews tabular data files.
+func NewDataPreviewTool() *DataPreviewTool {
+	return &DataPreviewTool{}
+}
+
+func (d *DataPreviewTool) Name() string { return "data_preview" }
+
+func (d *DataPreviewTool) Description() string {
+	return "Show the columns, inferred types, and first rows of a CSV, TSV, JSONL, or Parquet file in the repository. For Parquet it returns the schema and row count only."
+}
+
+func (d *DataPreviewTool) Schema() map[string]any {
+	return map[string]any{
+		"type": "object",
+		"properties": map[string]any{
+			"path": map[string]any{"type": "string"},
+			"rows": map[string]any{"type": "integer", "minimum": 0, "maximum": maxPreviewRows},
+		},
+		"required":             []string{"path"},
+		"additionalProperties": false,
+	}
+}
+
+type dataPreviewInput struct {
+	Path string `json:"path"`
+	Rows *int   `json:"rows"`
+}
+
+type dataColumn struct {
+	Name     string `json:"name"`
+	Type     string `json:"type"`
+	Nullable bool   `json:"nullable,omitempty"`
+}
+
+type dataPreviewOutput struct {
+	Format  string       `json:"format"`
+	Columns []dataColumn `json:"columns"`
+	Rows    [][]string   `json:"rows"`
+	// RowCount is exact for Parquet and omitted for text formats, which are
+	// not read to the end.
+	RowCount   int64  `json:"row_count,omitempty"`
+	Truncated  bool   `json:"truncated"`
+	Note       string `json:"note,omitempty"`
+	DurationMs int64  `json:"duration_ms"`
+}
+
+func (d *DataPreviewTool) Execute(ctx context.Context, input json.RawMessage, meta Meta) (Result, error) {
+	var args dataPreviewInput
+	if err := json.Unmarshal(input, &args); err != nil {
+		return Result{}, err
+	}
+	if strings.TrimSpace(args.Path) == "" {
+		return Result{}, errors.New("path is required")
+	}
+	rows := DefaultPreviewRows
+	if args.Rows != nil {
+		rows = min(max(*args.Rows, 0), maxPreviewRows)
+	}
+	fsys := meta.FS()
+	name, err := fsys.Name(args.Path)
+	if err != nil {
+		return Result{}, err
+	}
+	format, err := dataFormat(name)
+	if err != nil {
+		return Result{}, err
+	}
+	file, err := fsys.OpenLarge(name)
+	if err != nil {
+		return Result{}, err
+	}
+	defer file.Close()
+
+	start := time.Now()
+	output := dataPreviewOutput{Format: format}
+	switch format {
+	case "parquet":
+		info, err := file.Stat()
+		if err != nil {
+			return Result{}, err
+		}
+		schema, err := readParquetSchema(file, info.Size())
+		if err != nil {
+			return Result{}, fmt.Errorf("%s: %w", args.Path, err)
+		}
+		output.Columns, output.RowCount = schema.Columns, schema.NumRows
+		output.Note = "Parquet rows are not decoded; columns and row_count come from the file footer."
+	case "jsonl":
+		output.Columns, output.Rows, output.Truncated, err = previewJSONL(ctx, file, rows)
+	default:
+		output.Columns, output.Rows, output.Truncated, err = previewDelimited(ctx, file, format, rows)
+	}
+	if err != nil {
+		return Result{}, fmt.Errorf("%s: %w", args.Path, err)
+	}
+	if output.Rows == nil {
+		output.Rows = [][]string{}
+	}
+	output.Rows, output.Truncated = capRows(output.Rows, meta.MaxBytes, output.Truncated)
+	output.DurationMs = time.Since(start).Milliseconds()
+
+	text := renderPreview(output)
+	return Result{ToolName: d.Name(), Payload: output, Preview: meta.Preview(text), LineCount: strings.Count(text, "\n") + 1, ByteCount: len(text), Truncated: output.Truncated, DurationMs: output.DurationMs}, nil
+}
+
+// dataFormat picks the format from the file extension.
+func dataFormat(name string) (string, error) {
+	switch strings.ToLower(path.Ext(name)) {
+	case ".csv":
+		return "csv", nil
+	case ".tsv", ".tab":
+		return "tsv", nil
+	case ".jsonl", ".ndjson":
+		return "jsonl", nil
+	case ".parquet":
+		return "parquet", nil
+	}
+	return "", fmt.Errorf("unsupported data file %s: expected .csv, .tsv, .jsonl, or .parquet", name)
+}
+
+// previewDelimited reads the header and up to inferRows records, returning
+// the first rows of them.
+func previewDelimited(ctx context.Context, r io.Reader, format string, rows int) ([]dataColumn, [][]string, bool, error) {
+	reader := csv.NewReader(bufio.NewReader(r))
+	if format == "tsv" {
+		reader.Comma = '\t'
+	}
+	reader.FieldsPerRecord = -1
+	reader.LazyQuotes = true
+
+	header, err := reader.Read()
+	if errors.Is(err, io.EOF) {
+		return []dataColumn{}, nil, false, nil
+	}
+	if err != nil {
+		return nil, nil, false, err
+	}
+	columns := make([]dataColumn, len(header))
+	for i, name := range header {
+		name = strings.TrimSpace(strings.TrimPrefix(name, "\ufeff"))
+		if name == "" {
+			name = fmt.Sprintf("column_%d", i+1)
+		}
+		columns[i] = dataColumn{Name: name}
+	}
+
+	var out [][]string
+	sampled := 0
+	for sampled < max(rows, inferRows) && ctx.Err() == nil {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, nil, false, err
+		}
+		sampled++
+		for i := range columns {
+			value := ""
+			if i < len(record) {
+				value = strings.TrimSpace(record[i])
+			}
+			observe(&columns[i], inferText(value), "string")
+		}
+		if len(out) < rows {
+			out = append(out, previewCells(record, len(columns)))
+		}
+	}
+	finishColumns(columns)
+	return columns, out, sampled > len(out), ctx.Err()
+}
+
+// previewJSONL treats each line as a row; object keys become columns in the
+// order they first appear. Lines that are not objects get a "value" column.
+func previewJSONL(ctx context.Context, r io.Reader, rows int) ([]dataColumn, [][]string, bool, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 4*1024*1024)
+	var columns []dataColumn
+	index := map[string]int{}
+	var records []map[string]any
+	sampled, lineNum := 0, 0
+	for sampled < max(rows, inferRows) && ctx.Err() == nil && scanner.Scan() {
+		lineNum++
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		decoder := json.NewDecoder(bytes.NewReader(line))
+		decoder.UseNumber()
+		var value any
+		if err := decoder.Decode(&value); err != nil {
+			return nil, nil, false, fmt.Errorf("line %d: %w", lineNum, err)
+		}
+		record, ok := value.(map[string]any)
+		if !ok {
+			record = map[string]any{"value": value}
+		}
+		keys := make([]string, 0, len(record))
+		for key := range record {
+			keys = append(keys, key)
+		}
+		// map order is random; keep first-seen order stable within a line
+		keys = orderedKeys(line, keys)
+		for _, key := range keys {
+			if _, seen := index[key]; !seen {
+				index[key] = len(columns)
+				// keys missing from earlier rows were null there
+				columns = append(columns, dataColumn{Nullable: sampled > 0, Name: key})
+			}
+		}
+		for i := range columns {
+			observe(&columns[i], inferJSON(record[columns[i].Name]), "mixed")
+		}
+		sampled++
+		if len(records) < rows {
+			records = append(records, record)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, nil, false, err
+	}
+	finishColumns(columns)
+	out := make([][]string, 0, len(records))
+	for _, record := range records {
+		cells := make([]string, len(columns))
+		for i, column := range columns {
+			cells[i] = jsonCell(record[column.Name])
+		}
+		out = append(out, previewCells(cells, len(columns)))
+	}
+	if columns == nil {
+		columns = []dataColumn{}
+	}
+	return columns, out, sampled > len(out), ctx.Err()
+}
+
+// orderedKeys sorts keys by where they appear in the raw JSON line.
+func orderedKeys(line []byte, keys []string) []string {
+	position := func(key string) int {
+		quoted, _ := json.Marshal(key)
+		if i := bytes.Index(line, quoted); i >= 0 {
+			return i
+		}
+		return len(line)
+	}
+	for i := 1; i < len(keys); i++ {
+		for j := i; j > 0 && position(keys[j]) < position(keys[j-1]); j-- {
+			keys[j], keys[j-1] = keys[j-1], keys[j]
+		}
+	}
+	return keys
+}
+
+// observe folds one value's type into the column. An empty type is a null.
+// Incompatible types widen to fallback.
+func observe(column *dataColumn, typ string, fallback string) {
+	switch {
+	case typ == "":
+		column.Nullable = true
+	case column.Type == "" || column.Type == typ:
+		column.Type = typ
+	case (column.Type == "int" && typ == "float") || (column.Type == "float" && typ == "int"):
+		column.Type = "float"
+	case (column.Type == "date" && typ == "timestamp") || (column.Type == "timestamp" && typ == "date"):
+		column.Type = "timestamp"
+	default:
+		column.Type = fallback
+	}
+}
+
+// finishColumns names the type of columns that only held nulls.
+func finishColumns(columns []dataColumn) {
+	for i := range columns {
+		if columns[i].Type == "" {
+			columns[i].Type = "null"
+		}
+	}
+}
+
+// inferText infers the type of a CSV/TSV value.
+func inferText(value string) string {
+	if value == "" || strings.EqualFold(value, "null") || strings.EqualFold(value, "na") {
+		return ""
+	}
+	if _, err := strconv.ParseInt(value, 10, 64); err == nil {
+		return "int"
+	}
+	if _, err := strconv.ParseFloat(value, 64); err == nil {
+		return "float"
+	}
+	if strings.EqualFold(value, "true") || strings.EqualFold(value, "false") {
+		return "bool"
+	}
+	return inferString(value)
+}
+
+// inferString recognizes dates and timestamps in string values.
+func inferString(value string) string {
+	if _, err := time.Parse("2006-01-02", value); err == nil {
+		return "date"
+	}
+	for _, layout := range []string{time.RFC3339Nano, "2006-01-02 15:04:05", "2006-01-02T15:04:05"} {
+		if _, err := time.Parse(layout, value); err == nil {
+			return "timestamp"
+		}
+	}
+	return "string"
+}
+
+// inferJSON infers the type of a decoded JSONL value.
+func inferJSON(value any) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case bool:
+		return "bool"
+	case json.Number:
+		if _, err := v.Int64(); err == nil {
+			return "int"
+		}
+		return "float"
+	case string:
+		return inferString(v)
+	case map[string]any:
+		return "object"
+	case []any:
+		return "array"
+	}
+	return "mixed"
+}
+
+func jsonCell(value any) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case json.Number:
+		return v.String()
+	}
+	data, _ := json.Marshal(value)
+	return string(data)
+}
+
+// previewCells pads or trims a record to width and caps and redacts each cell.
+func previewCells(record []string, width int) []string {
+	cells := make([]string, width)
+	for i := range cells {
+		if i >= len(record) {
+			continue
+		}
+		cells[i], _ = util.TruncateBytes(util.RedactSecrets(record[i]), maxCellBytes)
+	}
+	return cells
+}
+
+// capRows drops trailing rows beyond maxBytes of cell text.
+func capRows(rows [][]string, maxBytes int, truncated bool) ([][]string, bool) {
+	if maxBytes <= 0 {
+		return rows, truncated
+	}
+	total := 0
+	for i, row := range rows {
+		for _, cell := range row {
+			total += len(cell) + 1
+		}
+		if total > maxBytes {
+			return rows[:i], true
+		}
+	}
+	return rows, truncated
+}
+
+func renderPreview(output dataPreviewOutput) string {
+	var b strings.Builder
+	for _, column := range output.Columns {
+		fmt.Fprintf(&b, "%s: %s", column.Name, column.Type)
+		if column.Nullable {
+			b.WriteString(" (nullable)")
+		}
+		b.WriteString("\n")
+	}
+	if output.RowCount > 0 {
+		fmt.Fprintf(&b, "rows: %d\n", output.RowCount)
+	}
+	for _, row := range output.Rows {
+		b.WriteString(strings.Join(row, "\t"))
+		b.WriteString("\n")
+	}
+	return strings.TrimSuffix(b.String(), "\n")
+}
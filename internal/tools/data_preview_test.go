@@ -0,0 +1,84 @@
+package tools
+
+import (
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// parquetFooter is a FileMetaData with columns id (required int64) and
+// name (optional UTF8 byte array) and 42 rows, in the Thrift compact protocol.
+func parquetFooter() []byte {
+	footer := []byte{0x15, 0x02, 0x19, 0x3c}
+	footer = append(footer, 0x48, 6)
+	footer = append(footer, "schema"...)
+	footer = append(footer, 0x15, 0x04, 0x00)
+	footer = append(footer, 0x15, 0x04, 0x25, 0x00, 0x18, 2)
+	footer = append(footer, "id"...)
+	footer = append(footer, 0x00)
+	footer = append(footer, 0x15, 0x0c, 0x25, 0x02, 0x18, 4)
+	footer = append(footer, "name"...)
+	footer = append(footer, 0x25, 0x00, 0x00)
+	footer = append(footer, 0x16, 84, 0x00)
+
+	file := []byte(parquetMagic)
+	file = append(file, footer...)
+	file = binary.LittleEndian.AppendUint32(file, uint32(len(footer)))
+	return append(file, parquetMagic...)
+}
+
+func TestDataPreview(t *testing.T) {
+	repoRoot := t.TempDir()
+	files := map[string][]byte{
+		"events.csv":     []byte("id,amount,day,note\n1,2.5,2024-01-02,hello\n2,3,2024-01-03,\n3,4,2024-01-04,x\n"),
+		"events.tsv":     []byte("id\tok\n1\ttrue\n2\tfalse\n"),
+		"events.jsonl":   []byte(`{"id":1,"tags":["a"],"at":"2024-01-02T10:00:00Z"}` + "\n" + `{"id":2.5,"extra":true}` + "\n"),
+		"events.parquet": parquetFooter(),
+	}
+	for name, data := range files {
+		if err := os.WriteFile(filepath.Join(repoRoot, name), data, 0o644); err != nil {
+			t.Fatalf("failed to write %s: %v", name, err)
+		}
+	}
+	cases := []struct {
+		path    string
+		rows    int
+		columns []dataColumn
+		shown   int
+	}{
+		{"events.csv", 2, []dataColumn{{Name: "id", Type: "int"}, {Name: "amount", Type: "float"}, {Name: "day", Type: "date"}, {Name: "note", Type: "string", Nullable: true}}, 2},
+		{"events.tsv", 10, []dataColumn{{Name: "id", Type: "int"}, {Name: "ok", Type: "bool"}}, 2},
+		{"events.jsonl", 10, []dataColumn{{Name: "id", Type: "float"}, {Name: "tags", Type: "array", Nullable: true}, {Name: "at", Type: "timestamp", Nullable: true}, {Name: "extra", Type: "bool", Nullable: true}}, 2},
+		{"events.parquet", 10, []dataColumn{{Name: "id", Type: "int"}, {Name: "name", Type: "string", Nullable: true}}, 0},
+	}
+	tool := NewDataPreviewTool()
+	for _, tc := range cases {
+		input, _ := json.Marshal(map[string]any{"path": tc.path, "rows": tc.rows})
+		res, err := tool.Execute(context.Background(), input, Meta{RepoRoot: repoRoot, MaxBytes: 4096})
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", tc.path, err)
+		}
+		out := res.Payload.(dataPreviewOutput)
+		if !reflect.DeepEqual(out.Columns, tc.columns) {
+			t.Fatalf("%s: columns = %+v, want %+v", tc.path, out.Columns, tc.columns)
+		}
+		if len(out.Rows) != tc.shown {
+			t.Fatalf("%s: got %d rows, want %d", tc.path, len(out.Rows), tc.shown)
+		}
+	}
+
+	input, _ := json.Marshal(map[string]any{"path": "events.parquet"})
+	res, _ := tool.Execute(context.Background(), input, Meta{RepoRoot: repoRoot})
+	if out := res.Payload.(dataPreviewOutput); out.RowCount != 42 || out.Note == "" {
+		t.Fatalf("expected the parquet row count and a note, got %+v", out)
+	}
+	input, _ = json.Marshal(map[string]any{"path": "events.csv", "rows": 2})
+	res, _ = tool.Execute(context.Background(), input, Meta{RepoRoot: repoRoot})
+	if out := res.Payload.(dataPreviewOutput); !out.Truncated || out.Rows[0][3] != "hello" {
+		t.Fatalf("expected a truncated csv preview, got %+v", out)
+	}
+}
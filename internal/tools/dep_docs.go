@@ -0,0 +1,589 @@
+package tools
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+
+	"fi-cli/internal/repo"
+	"fi-cli/internal/util"
+)
+
+const (
+	// maxDepDocs caps the doc files listed for one package.
+	maxDepDocs = 50
+	// maxDepDocDepth is how deep under the package directory docs are listed.
+	maxDepDocDepth = 3
+)
+
+// Dependency ecosystems dep_docs can locate packages in.
+const (
+	EcosystemGo     = "go"
+	EcosystemNode   = "node"
+	EcosystemPython = "python"
+)
+
+// errOutsideDeps is returned for dependency paths outside the repository,
+// the Go module cache, and $VIRTUAL_ENV, lexically or through a symlink.
+var errOutsideDeps = errors.New("dependency path is outside the repo, the Go module cache, and the virtualenv")
+
+var depDocExts = map[string]bool{".md": true, ".markdown": true, ".mdx": true, ".rst": true, ".txt": true, ".adoc": true}
+
+type DepDocsTool struct{}
+
+// NewDepDocsTool constructs a tool that reads the documentation of installed
+// dependencies.
+func NewDepDocsTool() *DepDocsTool {
+	return &DepDocsTool{}
+}
+
+func (d *DepDocsTool) Name() string { return "dep_docs" }
+
+func (d *DepDocsTool) Description() string {
+	return "Read the documentation of a dependency exactly as installed: node_modules/<pkg>, the Go module cache or vendor/ at the version in go.mod, or a Python package's metadata in the repo's virtualenv. Returns the installed version, the README (or the requested doc file), and the other doc files available. Works offline."
+}
+
+func (d *DepDocsTool) Schema() map[string]any {
+	return map[string]any{
+		"type": "object",
+		"properties": map[string]any{
+			"package":   map[string]any{"type": "string", "description": "Package as imported or declared: \"react\", \"@tanstack/query-core\", \"github.com/spf13/cobra\", \"requests\""},
+			"ecosystem": map[string]any{"type": "string", "enum": []string{EcosystemGo, EcosystemNode, EcosystemPython}, "description": "Where to look (default: every ecosystem the repo uses)"},
+			"file":      map[string]any{"type": "string", "description": "A doc file listed in an earlier result, relative to the package (default: the README)"},
+		},
+		"required":             []string{"package"},
+		"additionalProperties": false,
+	}
+}
+
+type depDocsInput struct {
+	Package   string `json:"package"`
+	Ecosystem string `json:"ecosystem"`
+	File      string `json:"file"`
+}
+
+type depDocsOutput struct {
+	Package   string `json:"package"`
+	Ecosystem string `json:"ecosystem"`
+	Version   string `json:"version,omitempty"`
+	// Location is where the package is installed, relative to the repo
+	// when it is inside it.
+	Location  string   `json:"location"`
+	File      string   `json:"file,omitempty"`
+	Content   string   `json:"content"`
+	Docs      []string `json:"docs,omitempty"`
+	Truncated bool     `json:"truncated"`
+	// DurationMs is the time to locate and read the docs.
+	DurationMs int64 `json:"duration_ms"`
+}
+
+// depPackage is an installed dependency. Python packages have no doc tree;
+// their long description, usually the README, is in metadata.
+type depPackage struct {
+	ecosystem string
+	version   string
+	dir       string
+	metadata  string
+}
+
+func (d *DepDocsTool) Execute(ctx context.Context, input json.RawMessage, meta Meta) (Result, error) {
+	var args depDocsInput
+	if err := json.Unmarshal(input, &args); err != nil {
+		return Result{}, err
+	}
+	args.Package = strings.TrimSpace(args.Package)
+	if args.Package == "" {
+		return Result{}, errors.New("package is required")
+	}
+	start := time.Now()
+
+	ecosystems := []string{EcosystemGo, EcosystemNode, EcosystemPython}
+	switch args.Ecosystem {
+	case "":
+	case EcosystemGo, EcosystemNode, EcosystemPython:
+		ecosystems = []string{args.Ecosystem}
+	default:
+		return Result{}, fmt.Errorf("unknown ecosystem %q (expected go, node, or python)", args.Ecosystem)
+	}
+	roots := newDepRoots(meta)
+	var pkg depPackage
+	var found bool
+	for _, ecosystem := range ecosystems {
+		if ctx.Err() != nil {
+			return Result{}, ctx.Err()
+		}
+		if pkg, found = locateDep(roots, ecosystem, args.Package); found {
+			break
+		}
+	}
+	if !found {
+		return Result{}, fmt.Errorf("%s is not installed in this repo (looked in %s); install dependencies or use web search", args.Package, strings.Join(ecosystems, ", "))
+	}
+
+	output := depDocsOutput{Package: args.Package, Ecosystem: pkg.ecosystem, Version: pkg.version, Location: displayPath(meta.RepoRoot, pkg.dir)}
+	if pkg.dir != "" {
+		output.Docs = listDepDocs(roots, pkg.dir)
+	}
+	switch {
+	case args.File != "":
+		name := path.Clean(strings.TrimPrefix(filepath.ToSlash(args.File), "/"))
+		if name == ".." || strings.HasPrefix(name, "../") || pkg.dir == "" {
+			return Result{}, fmt.Errorf("%s has no doc file %s", args.Package, args.File)
+		}
+		if !isDepDoc(name) {
+			return Result{}, fmt.Errorf("%s is not a doc file; dep_docs reads %s files and READMEs", args.File, strings.Join(sortedKeys(depDocExts), " "))
+		}
+		content, err := readDepFile(roots, filepath.Join(pkg.dir, filepath.FromSlash(name)))
+		if err != nil {
+			return Result{}, err
+		}
+		output.File, output.Content = name, content
+	case pkg.metadata != "":
+		output.File, output.Content = "METADATA", pkg.metadata
+	default:
+		for _, doc := range output.Docs {
+			if !strings.Contains(doc, "/") && strings.HasPrefix(strings.ToLower(doc), "readme") {
+				content, err := readDepFile(roots, filepath.Join(pkg.dir, filepath.FromSlash(doc)))
+				if err == nil {
+					output.File, output.Content = doc, content
+				}
+				break
+			}
+		}
+		if output.File == "" {
+			output.Content = "no README; see docs for the doc files installed with the package"
+		}
+	}
+
+	if meta.MaxBytes > 0 {
+		output.Content, output.Truncated = util.TruncateBytes(output.Content, meta.MaxBytes)
+	}
+	output.DurationMs = time.Since(start).Milliseconds()
+	data, _ := json.Marshal(output)
+	header := fmt.Sprintf("%s %s (%s)", output.Package, output.Version, output.Location)
+	preview := meta.Preview(strings.TrimSpace(header + "\n" + output.Content))
+	return Result{ToolName: d.Name(), Payload: output, Preview: preview, LineCount: strings.Count(output.Content, "\n") + 1, ByteCount: len(data), Truncated: output.Truncated, DurationMs: output.DurationMs}, nil
+}
+
+// depRoots is where dep_docs reads. Paths in the repository go through
+// RepoFS; outside it only the Go module cache and $VIRTUAL_ENV's
+// site-packages are readable, checked after symlinks are evaluated.
+type depRoots struct {
+	repo *RepoFS
+	root string
+	// outside are the roots beyond the repository, symlinks evaluated
+	outside []string
+}
+
+func newDepRoots(meta Meta) depRoots {
+	roots := depRoots{repo: meta.FS()}
+	roots.root = roots.repo.Root()
+	candidates := sitePackagesDirs(roots.root)
+	if cache := goModCache(); cache != "" {
+		candidates = append(candidates, cache)
+	}
+	for _, dir := range candidates {
+		// a virtualenv in the repo is read through RepoFS, wherever it links
+		if _, inRepo := within(roots.root, filepath.Clean(dir)); inRepo {
+			continue
+		}
+		if real, err := filepath.EvalSymlinks(dir); err == nil {
+			roots.outside = append(roots.outside, real)
+		}
+	}
+	return roots
+}
+
+// locate returns the file system and name that serve the absolute path p.
+func (d depRoots) locate(p string) (fs.FS, string, error) {
+	p = filepath.Clean(p)
+	if rel, ok := within(d.root, p); ok {
+		return d.repo, filepath.ToSlash(rel), nil
+	}
+	real, err := filepath.EvalSymlinks(p)
+	if err != nil {
+		return nil, "", err
+	}
+	for _, root := range d.outside {
+		if rel, ok := within(root, real); ok {
+			return os.DirFS(root), filepath.ToSlash(rel), nil
+		}
+	}
+	return nil, "", errOutsideDeps
+}
+
+func (d depRoots) readFile(p string) ([]byte, error) {
+	fsys, name, err := d.locate(p)
+	if err != nil {
+		return nil, err
+	}
+	if fsys != fs.FS(d.repo) {
+		if repo.IsDenylisted(name) {
+			return nil, ErrFileDenied
+		}
+		if info, err := fs.Stat(fsys, name); err == nil && info.Size() > MaxFileBytes {
+			return nil, ErrFileTooLarge
+		}
+	}
+	return fs.ReadFile(fsys, name)
+}
+
+func (d depRoots) readDir(p string) ([]fs.DirEntry, error) {
+	fsys, name, err := d.locate(p)
+	if err != nil {
+		return nil, err
+	}
+	return fs.ReadDir(fsys, name)
+}
+
+func (d depRoots) isDir(p string) bool {
+	fsys, name, err := d.locate(p)
+	if err != nil {
+		return false
+	}
+	info, err := fs.Stat(fsys, name)
+	return err == nil && info.IsDir()
+}
+
+func locateDep(roots depRoots, ecosystem, name string) (depPackage, bool) {
+	switch ecosystem {
+	case EcosystemGo:
+		return locateGoModule(roots, name)
+	case EcosystemNode:
+		return locateNodePackage(roots, name)
+	case EcosystemPython:
+		return locatePythonPackage(roots, name)
+	}
+	return depPackage{}, false
+}
+
+// locateNodePackage finds name in the repo's node_modules.
+func locateNodePackage(roots depRoots, name string) (depPackage, bool) {
+	if strings.Contains(name, "..") {
+		return depPackage{}, false
+	}
+	dir := filepath.Join(roots.root, "node_modules", filepath.FromSlash(name))
+	data, err := roots.readFile(filepath.Join(dir, "package.json"))
+	if err != nil {
+		return depPackage{}, false
+	}
+	var manifest struct {
+		Version string `json:"version"`
+	}
+	_ = json.Unmarshal(data, &manifest)
+	return depPackage{ecosystem: EcosystemNode, version: manifest.Version, dir: dir}, true
+}
+
+// locateGoModule finds the module providing name, a module or package path,
+// at the version go.mod requires: in vendor/ when the repo vendors, else in
+// the module cache. Local replace directives point at their directory, which
+// must be inside the repository.
+func locateGoModule(roots depRoots, name string) (depPackage, bool) {
+	requires, replaces := readGoMod(roots, filepath.Join(roots.root, "go.mod"))
+	module := ""
+	for required := range requires {
+		if (name == required || strings.HasPrefix(name, required+"/")) && len(required) > len(module) {
+			module = required
+		}
+	}
+	if module == "" {
+		return depPackage{}, false
+	}
+	version := requires[module]
+	if target, ok := replaces[module]; ok {
+		if target.version == "" {
+			dir := target.path
+			if !filepath.IsAbs(dir) {
+				dir = filepath.Join(roots.root, dir)
+			}
+			if _, inRepo := within(roots.root, filepath.Clean(dir)); !inRepo {
+				return depPackage{}, false
+			}
+			return depPackage{ecosystem: EcosystemGo, version: "replaced by " + target.path, dir: dir}, roots.isDir(dir)
+		}
+		module, version = target.path, target.version
+	}
+	if dir := filepath.Join(roots.root, "vendor", filepath.FromSlash(module)); roots.isDir(dir) {
+		return depPackage{ecosystem: EcosystemGo, version: version, dir: dir}, true
+	}
+	cache := goModCache()
+	if cache == "" {
+		return depPackage{}, false
+	}
+	dir := filepath.Join(cache, filepath.FromSlash(escapeModulePath(module))+"@"+version)
+	return depPackage{ecosystem: EcosystemGo, version: version, dir: dir}, roots.isDir(dir)
+}
+
+type goModTarget struct {
+	path    string
+	version string
+}
+
+// readGoMod returns the required module versions and the replacements of a
+// go.mod, enough of the grammar to locate dependencies.
+func readGoMod(roots depRoots, file string) (map[string]string, map[string]goModTarget) {
+	requires := map[string]string{}
+	replaces := map[string]goModTarget{}
+	data, err := roots.readFile(file)
+	if err != nil {
+		return requires, replaces
+	}
+	// block is the directive of the ( ... ) block being read, if any
+	block := ""
+	for _, line := range strings.Split(string(data), "\n") {
+		line, _, _ = strings.Cut(line, "//")
+		fields := strings.Fields(line)
+		switch {
+		case len(fields) == 0:
+			continue
+		case block != "" && fields[0] == ")":
+			block = ""
+			continue
+		case block == "" && len(fields) == 2 && fields[1] == "(":
+			block = fields[0]
+			continue
+		}
+		directive := block
+		if directive == "" {
+			directive, fields = fields[0], fields[1:]
+		}
+		switch directive {
+		case "require":
+			if len(fields) >= 2 {
+				requires[fields[0]] = fields[1]
+			}
+		case "replace":
+			// old [version] => new [version]
+			if arrow := slices.Index(fields, "=>"); arrow > 0 && arrow+1 < len(fields) {
+				target := goModTarget{path: fields[arrow+1]}
+				if arrow+2 < len(fields) {
+					target.version = fields[arrow+2]
+				}
+				replaces[fields[0]] = target
+			}
+		}
+	}
+	return requires, replaces
+}
+
+func goModCache() string {
+	if cache := os.Getenv("GOMODCACHE"); cache != "" {
+		return cache
+	}
+	if gopath := os.Getenv("GOPATH"); gopath != "" {
+		return filepath.Join(filepath.SplitList(gopath)[0], "pkg", "mod")
+	}
+	if home, err := os.UserHomeDir(); err == nil {
+		return filepath.Join(home, "go", "pkg", "mod")
+	}
+	return ""
+}
+
+// escapeModulePath applies the module cache's case encoding: each upper-case
+// letter becomes "!" and its lower-case form.
+func escapeModulePath(module string) string {
+	var b strings.Builder
+	for _, r := range module {
+		if unicode.IsUpper(r) {
+			b.WriteByte('!')
+			r = unicode.ToLower(r)
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// locatePythonPackage finds the installed distribution called name in the
+// repo's virtualenv (.venv, venv, env, or $VIRTUAL_ENV). Distribution names
+// compare case-insensitively, with "-", "_", and "." equivalent.
+func locatePythonPackage(roots depRoots, name string) (depPackage, bool) {
+	want := normalizeDistName(name)
+	for _, sitePackages := range sitePackagesDirs(roots.root) {
+		entries, err := roots.readDir(sitePackages)
+		if err != nil {
+			continue
+		}
+		for _, entry := range entries {
+			base, ok := strings.CutSuffix(entry.Name(), ".dist-info")
+			if !ok || !entry.IsDir() {
+				continue
+			}
+			dist, version, _ := strings.Cut(base, "-")
+			if normalizeDistName(dist) != want {
+				continue
+			}
+			pkg := depPackage{ecosystem: EcosystemPython, version: version, metadata: readPythonDescription(roots, filepath.Join(sitePackages, entry.Name(), "METADATA"))}
+			// the import package usually sits next to its metadata
+			for _, candidate := range []string{dist, strings.ToLower(dist), strings.ReplaceAll(strings.ToLower(dist), "-", "_")} {
+				if dir := filepath.Join(sitePackages, candidate); roots.isDir(dir) {
+					pkg.dir = dir
+					break
+				}
+			}
+			if pkg.dir == "" {
+				pkg.dir = filepath.Join(sitePackages, entry.Name())
+			}
+			return pkg, true
+		}
+	}
+	return depPackage{}, false
+}
+
+func sitePackagesDirs(repoRoot string) []string {
+	envs := []string{filepath.Join(repoRoot, ".venv"), filepath.Join(repoRoot, "venv"), filepath.Join(repoRoot, "env")}
+	if virtualEnv := os.Getenv("VIRTUAL_ENV"); virtualEnv != "" {
+		envs = append(envs, virtualEnv)
+	}
+	var dirs []string
+	for _, env := range envs {
+		matches, _ := filepath.Glob(filepath.Join(env, "lib", "python*", "site-packages"))
+		dirs = append(dirs, matches...)
+		// Windows virtualenvs have no python version directory
+		if dir := filepath.Join(env, "Lib", "site-packages"); dirExists(dir) {
+			dirs = append(dirs, dir)
+		}
+	}
+	return dirs
+}
+
+func normalizeDistName(name string) string {
+	return strings.NewReplacer("-", "_", ".", "_").Replace(strings.ToLower(strings.TrimSpace(name)))
+}
+
+// readPythonDescription returns the summary and long description of a
+// METADATA file: the headers up to the first blank line are dropped except
+// Summary and Home-page, and the body is the README the package shipped.
+func readPythonDescription(roots depRoots, file string) string {
+	data, err := roots.readFile(file)
+	if err != nil {
+		return ""
+	}
+	var head, body strings.Builder
+	inBody := false
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, 64*1024), MaxFileBytes)
+	for scanner.Scan() {
+		line := scanner.Text()
+		switch {
+		case inBody:
+			body.WriteString(line + "\n")
+		case line == "":
+			inBody = true
+		case strings.HasPrefix(line, "Summary: "), strings.HasPrefix(line, "Home-page: "), strings.HasPrefix(line, "Project-URL: "):
+			head.WriteString(line + "\n")
+		}
+	}
+	return strings.TrimSpace(head.String() + "\n" + body.String())
+}
+
+// listDepDocs lists the doc files under dir, READMEs first, at most
+// maxDepDocs of them and maxDepDocDepth directories deep.
+func listDepDocs(roots depRoots, dir string) []string {
+	fsys, base, err := roots.locate(dir)
+	if err != nil {
+		return nil
+	}
+	var docs []string
+	_ = fs.WalkDir(fsys, base, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+		rel := p
+		switch {
+		case p == base:
+			rel = "."
+		case base != ".":
+			rel = strings.TrimPrefix(p, base+"/")
+		}
+		if d.IsDir() {
+			if rel != "." && (strings.HasPrefix(d.Name(), ".") || d.Name() == "node_modules" || strings.Count(rel, "/") >= maxDepDocDepth) {
+				return fs.SkipDir
+			}
+			return nil
+		}
+		if isDepDoc(rel) {
+			docs = append(docs, rel)
+		}
+		if len(docs) >= maxDepDocs {
+			return fs.SkipAll
+		}
+		return nil
+	})
+	sort.SliceStable(docs, func(i, j int) bool {
+		return depDocRank(docs[i]) < depDocRank(docs[j])
+	})
+	return docs
+}
+
+func depDocRank(name string) int {
+	base := strings.ToLower(path.Base(name))
+	switch {
+	case !strings.Contains(name, "/") && strings.HasPrefix(base, "readme"):
+		return 0
+	case !strings.Contains(name, "/"):
+		return 1
+	default:
+		return 2
+	}
+}
+
+// isDepDoc reports whether name is documentation: a doc extension, or a
+// README, CHANGELOG, or similar without one.
+func isDepDoc(name string) bool {
+	base := strings.ToLower(path.Base(name))
+	if depDocExts[path.Ext(base)] {
+		return base != "license.txt" && !strings.HasSuffix(base, "requirements.txt")
+	}
+	for _, prefix := range []string{"readme", "changelog", "changes", "history", "upgrading", "migration"} {
+		if strings.HasPrefix(base, prefix) && path.Ext(base) == "" {
+			return true
+		}
+	}
+	return false
+}
+
+func readDepFile(roots depRoots, file string) (string, error) {
+	data, err := roots.readFile(file)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+// displayPath shows dir relative to the repo when it is inside it, and with
+// ~ for the home directory otherwise.
+func displayPath(repoRoot, dir string) string {
+	if rel, err := filepath.Rel(repoRoot, dir); err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return filepath.ToSlash(rel)
+	}
+	if home, err := os.UserHomeDir(); err == nil && strings.HasPrefix(dir, home+string(filepath.Separator)) {
+		return "~" + filepath.ToSlash(strings.TrimPrefix(dir, home))
+	}
+	return dir
+}
+
+func dirExists(dir string) bool {
+	info, err := os.Stat(dir)
+	return err == nil && info.IsDir()
+}
+
+func sortedKeys(set map[string]bool) []string {
+	keys := make([]string, 0, len(set))
+	for key := range set {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
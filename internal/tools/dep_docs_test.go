@@ -0,0 +1,132 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func writeTree(t *testing.T, root string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		p := filepath.Join(root, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestDepDocs(t *testing.T) {
+	root := t.TempDir()
+	modCache := t.TempDir()
+	t.Setenv("GOMODCACHE", modCache)
+	t.Setenv("VIRTUAL_ENV", "")
+	writeTree(t, root, map[string]string{
+		"go.mod":                      "module app\n\ngo 1.24\n\nrequire (\n\tgithub.com/BurntSushi/toml v1.4.0 // indirect\n\tgithub.com/acme/local v0.1.0\n)\n\nreplace github.com/acme/local => ./third_party/local\n",
+		"third_party/local/README.md": "# local fork\n",
+		"node_modules/@tanstack/query-core/package.json":                        `{"name":"@tanstack/query-core","version":"5.59.0"}`,
+		"node_modules/@tanstack/query-core/README.md":                           "# Query core\nUse QueryClient.\n",
+		"node_modules/@tanstack/query-core/docs/guide.md":                       "# Guide\n",
+		"node_modules/@tanstack/query-core/src/index.ts":                        "export {}\n",
+		".venv/lib/python3.12/site-packages/requests-2.32.3.dist-info/METADATA": "Metadata-Version: 2.1\nName: requests\nSummary: Python HTTP for Humans.\nAuthor: someone\n\n# Requests\nrequests.get(url, timeout=5)\n",
+		".venv/lib/python3.12/site-packages/requests/__init__.py":               "",
+	})
+	writeTree(t, modCache, map[string]string{
+		"github.com/!burnt!sushi/toml@v1.4.0/README.md":    "# TOML\nDecode with toml.Decode.\n",
+		"github.com/!burnt!sushi/toml@v1.4.0/CHANGELOG":    "v1.4.0\n",
+		"github.com/!burnt!sushi/toml@v1.4.0/decode.go":    "package toml\n",
+		"github.com/!burnt!sushi/toml@v1.4.0/.github/x.md": "hidden\n",
+	})
+	tool := NewDepDocsTool()
+	meta := Meta{RepoRoot: root, MaxBytes: 4096}
+	run := func(input string) (depDocsOutput, error) {
+		t.Helper()
+		result, err := tool.Execute(context.Background(), json.RawMessage(input), meta)
+		if err != nil {
+			return depDocsOutput{}, err
+		}
+		return result.Payload.(depDocsOutput), nil
+	}
+
+	node, err := run(`{"package":"@tanstack/query-core"}`)
+	if err != nil {
+		t.Fatalf("node lookup failed: %v", err)
+	}
+	if node.Ecosystem != EcosystemNode || node.Version != "5.59.0" || node.Location != "node_modules/@tanstack/query-core" || node.File != "README.md" || !strings.Contains(node.Content, "QueryClient") {
+		t.Fatalf("unexpected node docs %+v", node)
+	}
+	if !slices.Equal(node.Docs, []string{"README.md", "docs/guide.md"}) {
+		t.Fatalf("expected only doc files, README first, got %v", node.Docs)
+	}
+	guide, err := run(`{"package":"@tanstack/query-core","file":"docs/guide.md"}`)
+	if err != nil || guide.Content != "# Guide\n" {
+		t.Fatalf("expected the requested doc file, got %+v (%v)", guide, err)
+	}
+
+	// a package path resolves to its module, with the cache's case encoding
+	gomod, err := run(`{"package":"github.com/BurntSushi/toml/internal","ecosystem":"go"}`)
+	if err != nil {
+		t.Fatalf("go lookup failed: %v", err)
+	}
+	if gomod.Version != "v1.4.0" || !strings.Contains(gomod.Content, "toml.Decode") || !slices.Equal(gomod.Docs, []string{"README.md", "CHANGELOG"}) {
+		t.Fatalf("unexpected go docs %+v", gomod)
+	}
+	if local, err := run(`{"package":"github.com/acme/local"}`); err != nil || local.Location != "third_party/local" || local.Content != "# local fork\n" {
+		t.Fatalf("expected the local replacement, got %+v (%v)", local, err)
+	}
+
+	py, err := run(`{"package":"Requests","ecosystem":"python"}`)
+	if err != nil {
+		t.Fatalf("python lookup failed: %v", err)
+	}
+	if py.Version != "2.32.3" || py.File != "METADATA" || !strings.HasPrefix(py.Content, "Summary: Python HTTP for Humans.\n\n# Requests") || strings.Contains(py.Content, "Author") {
+		t.Fatalf("unexpected python docs %+v", py)
+	}
+
+	for input, want := range map[string]string{
+		`{"package":"left-pad"}`:                                   "not installed",
+		`{"package":"@tanstack/query-core","file":"../../go.mod"}`: "no doc file",
+		`{"package":"@tanstack/query-core","file":"src/index.ts"}`: "not a doc file",
+		`{"package":"react","ecosystem":"ruby"}`:                   "unknown ecosystem",
+	} {
+		if _, err := run(input); err == nil || !strings.Contains(err.Error(), want) {
+			t.Fatalf("%s: expected %q, got %v", input, want, err)
+		}
+	}
+}
+
+func TestDepDocsStaysInDependencyRoots(t *testing.T) {
+	root := t.TempDir()
+	outside := t.TempDir()
+	t.Setenv("GOMODCACHE", t.TempDir())
+	t.Setenv("VIRTUAL_ENV", "")
+	writeTree(t, outside, map[string]string{"notes.txt": "host secret\n", "README.md": "host secret\n", "pkg/package.json": `{"version":"1.0.0"}`, "pkg/README.md": "# outside\n"})
+	writeTree(t, root, map[string]string{
+		"go.mod": "module app\n\nrequire (\n\tx.example/y v0.1.0\n\tx.example/z v0.1.0\n)\n\nreplace x.example/y => " + outside + "\nreplace x.example/z => ../" + filepath.Base(outside) + "\n",
+	})
+	if err := os.MkdirAll(filepath.Join(root, "node_modules"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(filepath.Join(outside, "pkg"), filepath.Join(root, "node_modules", "linked")); err != nil {
+		t.Skipf("symlinks unavailable: %v", err)
+	}
+	tool := NewDepDocsTool()
+	for _, input := range []string{
+		`{"package":"x.example/y"}`,
+		`{"package":"x.example/y","file":"notes.txt"}`,
+		`{"package":"x.example/z"}`,
+		`{"package":"linked"}`,
+	} {
+		result, err := tool.Execute(context.Background(), json.RawMessage(input), Meta{RepoRoot: root})
+		if err == nil || strings.Contains(err.Error(), "host secret") {
+			t.Fatalf("%s: expected a path outside the dependency roots to be refused, got %+v", input, result.Payload)
+		}
+	}
+}
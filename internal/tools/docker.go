@@ -0,0 +1,491 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"path"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"go.yaml.in/yaml/v3"
+)
+
+// maxDockerFiles bounds how many files one docker_analyze call parses.
+const maxDockerFiles = 10
+
+type DockerAnalyzeTool struct{}
+
+// NewDockerAnalyzeTool constructs a tool that parses Dockerfiles and compose files.
+func NewDockerAnalyzeTool() *DockerAnalyzeTool {
+	return &DockerAnalyzeTool{}
+}
+
+func (d *DockerAnalyzeTool) Name() string { return "docker_analyze" }
+
+func (d *DockerAnalyzeTool) Description() string {
+	return "Parse Dockerfiles and docker-compose files into stages (base image, exposed ports, volumes, workdir, entrypoint/cmd, stages copied from) and services (image or build, ports, volumes, depends_on, environment variable names), with line numbers. path may be a file or a directory; default is the repo root. Environment and build-arg values are never returned."
+}
+
+func (d *DockerAnalyzeTool) Schema() map[string]any {
+	return map[string]any{
+		"type": "object",
+		"properties": map[string]any{
+			"path": map[string]any{"type": "string"},
+		},
+		"required":             []string{},
+		"additionalProperties": false,
+	}
+}
+
+type dockerAnalyzeInput struct {
+	Path string `json:"path"`
+}
+
+type dockerStage struct {
+	Name       string   `json:"name,omitempty"`
+	Base       string   `json:"base"`
+	Line       int      `json:"line"`
+	Expose     []string `json:"expose,omitempty"`
+	Volumes    []string `json:"volumes,omitempty"`
+	Workdir    string   `json:"workdir,omitempty"`
+	User       string   `json:"user,omitempty"`
+	Entrypoint string   `json:"entrypoint,omitempty"`
+	Cmd        string   `json:"cmd,omitempty"`
+	// Args and Env hold names only.
+	Args       []string `json:"args,omitempty"`
+	Env        []string `json:"env,omitempty"`
+	CopiesFrom []string `json:"copies_from,omitempty"`
+}
+
+type composeService struct {
+	Name        string   `json:"name"`
+	Line        int      `json:"line"`
+	Image       string   `json:"image,omitempty"`
+	Build       string   `json:"build,omitempty"`
+	Ports       []string `json:"ports,omitempty"`
+	Expose      []string `json:"expose,omitempty"`
+	Volumes     []string `json:"volumes,omitempty"`
+	DependsOn   []string `json:"depends_on,omitempty"`
+	Environment []string `json:"environment,omitempty"`
+	EnvFiles    []string `json:"env_file,omitempty"`
+	Command     string   `json:"command,omitempty"`
+	Profiles    []string `json:"profiles,omitempty"`
+}
+
+type dockerFile struct {
+	Path     string           `json:"path"`
+	Kind     string           `json:"kind"`
+	Stages   []dockerStage    `json:"stages,omitempty"`
+	Services []composeService `json:"services,omitempty"`
+	Volumes  []string         `json:"volumes,omitempty"`
+	Networks []string         `json:"networks,omitempty"`
+	Error    string           `json:"error,omitempty"`
+}
+
+type dockerAnalyzeOutput struct {
+	Files      []dockerFile `json:"files"`
+	Truncated  bool         `json:"truncated"`
+	DurationMs int64        `json:"duration_ms"`
+}
+
+func (d *DockerAnalyzeTool) Execute(ctx context.Context, input json.RawMessage, meta Meta) (Result, error) {
+	var args dockerAnalyzeInput
+	if len(input) > 0 {
+		if err := json.Unmarshal(input, &args); err != nil {
+			return Result{}, err
+		}
+	}
+	fsys := meta.FS()
+	name, err := fsys.Name(strings.TrimSpace(args.Path))
+	if err != nil {
+		return Result{}, err
+	}
+	info, err := fsys.Stat(name)
+	if err != nil {
+		return Result{}, err
+	}
+
+	start := time.Now()
+	output := dockerAnalyzeOutput{}
+	names := []string{name}
+	if info.IsDir() {
+		entries, err := fsys.ReadDir(name)
+		if err != nil {
+			return Result{}, err
+		}
+		names = nil
+		for _, entry := range entries {
+			if !entry.IsDir() && dockerFileKind(entry.Name()) != "" {
+				names = append(names, path.Join(name, entry.Name()))
+			}
+		}
+		if len(names) == 0 {
+			return Result{}, fmt.Errorf("no Dockerfile or compose file in %s", args.Path)
+		}
+		if len(names) > maxDockerFiles {
+			names, output.Truncated = names[:maxDockerFiles], true
+		}
+	}
+	for _, name := range names {
+		kind := dockerFileKind(path.Base(name))
+		if kind == "" {
+			return Result{}, fmt.Errorf("%s is not a Dockerfile or compose file", name)
+		}
+		file := dockerFile{Path: name, Kind: kind}
+		data, err := fsys.ReadFile(name)
+		if err == nil {
+			if kind == "compose" {
+				err = parseCompose(data, &file)
+			} else {
+				file.Stages = parseDockerfile(string(data))
+			}
+		}
+		if err != nil {
+			file.Error = err.Error()
+		}
+		output.Files = append(output.Files, file)
+	}
+	output.DurationMs = time.Since(start).Milliseconds()
+
+	text := renderDocker(output.Files)
+	return Result{ToolName: d.Name(), Payload: output, Preview: meta.Preview(text), LineCount: strings.Count(text, "\n") + 1, ByteCount: len(text), Truncated: output.Truncated, DurationMs: output.DurationMs}, nil
+}
+
+// dockerFileKind classifies a file name as "dockerfile", "compose", or "".
+func dockerFileKind(base string) string {
+	lower := strings.ToLower(base)
+	switch {
+	case lower == "dockerfile", strings.HasPrefix(lower, "dockerfile."), strings.HasSuffix(lower, ".dockerfile"), lower == "containerfile":
+		return "dockerfile"
+	case (strings.HasPrefix(lower, "docker-compose") || strings.HasPrefix(lower, "compose")) && (strings.HasSuffix(lower, ".yml") || strings.HasSuffix(lower, ".yaml")):
+		return "compose"
+	}
+	return ""
+}
+
+// dockerInstruction is one logical Dockerfile line with continuations joined.
+type dockerInstruction struct {
+	line    int
+	keyword string
+	args    string
+}
+
+// dockerInstructions splits a Dockerfile into instructions, honoring the
+// escape parser directive, line continuations, and comments.
+func dockerInstructions(text string) []dockerInstruction {
+	escape := `\`
+	var out []dockerInstruction
+	var current []string
+	startLine := 0
+	lines := strings.Split(strings.ReplaceAll(text, "\r\n", "\n"), "\n")
+	for i, raw := range lines {
+		trimmed := strings.TrimSpace(raw)
+		if len(out) == 0 && len(current) == 0 && strings.HasPrefix(strings.ToLower(strings.ReplaceAll(trimmed, " ", "")), "#escape=") {
+			escape = strings.TrimSpace(trimmed[strings.Index(trimmed, "=")+1:])
+			continue
+		}
+		if strings.HasPrefix(trimmed, "#") || (trimmed == "" && len(current) == 0) {
+			continue
+		}
+		if len(current) == 0 {
+			startLine = i + 1
+		}
+		if strings.HasSuffix(trimmed, escape) {
+			current = append(current, strings.TrimSuffix(trimmed, escape))
+			continue
+		}
+		current = append(current, trimmed)
+		joined := strings.TrimSpace(strings.Join(current, " "))
+		current = nil
+		keyword, rest, _ := strings.Cut(joined, " ")
+		out = append(out, dockerInstruction{line: startLine, keyword: strings.ToUpper(keyword), args: strings.TrimSpace(rest)})
+	}
+	return out
+}
+
+// parseDockerfile groups instructions into build stages. Instructions before
+// the first FROM (global ARGs) are ignored.
+func parseDockerfile(text string) []dockerStage {
+	var stages []dockerStage
+	for _, inst := range dockerInstructions(text) {
+		if inst.keyword == "FROM" {
+			fields := strings.Fields(inst.args)
+			var stage dockerStage
+			stage.Line = inst.line
+			for i := 0; i < len(fields); i++ {
+				switch {
+				case strings.HasPrefix(fields[i], "--"):
+				case strings.EqualFold(fields[i], "AS") && i+1 < len(fields):
+					stage.Name = fields[i+1]
+					i++
+				case stage.Base == "":
+					stage.Base = fields[i]
+				}
+			}
+			stages = append(stages, stage)
+			continue
+		}
+		if len(stages) == 0 {
+			continue
+		}
+		stage := &stages[len(stages)-1]
+		switch inst.keyword {
+		case "EXPOSE":
+			stage.Expose = append(stage.Expose, dockerList(inst.args)...)
+		case "VOLUME":
+			stage.Volumes = append(stage.Volumes, dockerList(inst.args)...)
+		case "WORKDIR":
+			stage.Workdir = inst.args
+		case "USER":
+			stage.User = inst.args
+		case "ENTRYPOINT":
+			stage.Entrypoint = dockerCommand(inst.args)
+		case "CMD":
+			stage.Cmd = dockerCommand(inst.args)
+		case "ARG":
+			for _, field := range strings.Fields(inst.args) {
+				name, _, _ := strings.Cut(field, "=")
+				stage.Args = append(stage.Args, name)
+			}
+		case "ENV":
+			stage.Env = append(stage.Env, dockerEnvNames(inst.args)...)
+		case "COPY", "ADD":
+			for _, field := range strings.Fields(inst.args) {
+				if from, ok := strings.CutPrefix(field, "--from="); ok && !slices.Contains(stage.CopiesFrom, from) {
+					stage.CopiesFrom = append(stage.CopiesFrom, from)
+				}
+			}
+		}
+	}
+	return stages
+}
+
+// dockerList parses the JSON or whitespace form of EXPOSE and VOLUME.
+func dockerList(args string) []string {
+	var list []string
+	if strings.HasPrefix(args, "[") && json.Unmarshal([]byte(args), &list) == nil {
+		return list
+	}
+	return strings.Fields(args)
+}
+
+// dockerCommand renders the exec (JSON) or shell form of CMD and ENTRYPOINT.
+func dockerCommand(args string) string {
+	var list []string
+	if strings.HasPrefix(args, "[") && json.Unmarshal([]byte(args), &list) == nil {
+		return strings.Join(list, " ")
+	}
+	return args
+}
+
+// dockerEnvNames returns the names set by ENV in either the KEY=value or the
+// legacy "KEY value" form.
+func dockerEnvNames(args string) []string {
+	fields := strings.Fields(args)
+	if len(fields) == 0 {
+		return nil
+	}
+	if !strings.Contains(fields[0], "=") {
+		return fields[:1]
+	}
+	var names []string
+	for _, field := range fields {
+		if name, _, ok := strings.Cut(field, "="); ok && name != "" && !strings.ContainsAny(name, `"'`) {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// parseCompose reads services, volumes, and networks from a compose file.
+func parseCompose(data []byte, file *dockerFile) error {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return fmt.Errorf("parse compose: %w", err)
+	}
+	if len(doc.Content) == 0 || doc.Content[0].Kind != yaml.MappingNode {
+		return errors.New("parse compose: expected a mapping at the top level")
+	}
+	root := doc.Content[0]
+	file.Volumes = mappingKeys(mappingValue(root, "volumes"))
+	file.Networks = mappingKeys(mappingValue(root, "networks"))
+	services := mappingValue(root, "services")
+	if services == nil || services.Kind != yaml.MappingNode {
+		return nil
+	}
+	for i := 0; i+1 < len(services.Content); i += 2 {
+		key, node := services.Content[i], services.Content[i+1]
+		service := composeService{Name: key.Value, Line: key.Line}
+		if image := mappingValue(node, "image"); image != nil {
+			service.Image = image.Value
+		}
+		if build := mappingValue(node, "build"); build != nil {
+			service.Build = composeBuild(build)
+		}
+		for _, port := range sequenceValues(mappingValue(node, "ports")) {
+			service.Ports = append(service.Ports, composePort(port))
+		}
+		service.Expose = scalarList(mappingValue(node, "expose"))
+		for _, volume := range sequenceValues(mappingValue(node, "volumes")) {
+			if volume.Kind == yaml.MappingNode {
+				source, target := mappingValue(volume, "source"), mappingValue(volume, "target")
+				if target != nil {
+					entry := target.Value
+					if source != nil {
+						entry = source.Value + ":" + entry
+					}
+					service.Volumes = append(service.Volumes, entry)
+				}
+				continue
+			}
+			service.Volumes = append(service.Volumes, volume.Value)
+		}
+		if depends := mappingValue(node, "depends_on"); depends != nil {
+			if depends.Kind == yaml.MappingNode {
+				service.DependsOn = mappingKeys(depends)
+			} else {
+				service.DependsOn = scalarList(depends)
+			}
+		}
+		if env := mappingValue(node, "environment"); env != nil {
+			if env.Kind == yaml.MappingNode {
+				service.Environment = mappingKeys(env)
+			} else {
+				for _, item := range scalarList(env) {
+					name, _, _ := strings.Cut(item, "=")
+					service.Environment = append(service.Environment, name)
+				}
+			}
+		}
+		if envFile := mappingValue(node, "env_file"); envFile != nil {
+			if envFile.Kind == yaml.ScalarNode {
+				service.EnvFiles = []string{envFile.Value}
+			} else {
+				for _, item := range sequenceValues(envFile) {
+					if item.Kind == yaml.MappingNode {
+						if p := mappingValue(item, "path"); p != nil {
+							service.EnvFiles = append(service.EnvFiles, p.Value)
+						}
+						continue
+					}
+					service.EnvFiles = append(service.EnvFiles, item.Value)
+				}
+			}
+		}
+		if command := mappingValue(node, "command"); command != nil {
+			if command.Kind == yaml.SequenceNode {
+				service.Command = strings.Join(scalarList(command), " ")
+			} else {
+				service.Command = command.Value
+			}
+		}
+		service.Profiles = scalarList(mappingValue(node, "profiles"))
+		file.Services = append(file.Services, service)
+	}
+	return nil
+}
+
+func composeBuild(build *yaml.Node) string {
+	if build.Kind == yaml.ScalarNode {
+		return build.Value
+	}
+	var parts []string
+	for _, key := range []string{"context", "dockerfile", "target"} {
+		if value := mappingValue(build, key); value != nil && value.Value != "" {
+			parts = append(parts, key+"="+value.Value)
+		}
+	}
+	return strings.Join(parts, " ")
+}
+
+// composePort renders the short ("8080:80") or long port syntax as
+// published:target/protocol.
+func composePort(port *yaml.Node) string {
+	if port.Kind != yaml.MappingNode {
+		return port.Value
+	}
+	var entry string
+	if target := mappingValue(port, "target"); target != nil {
+		entry = target.Value
+	}
+	if published := mappingValue(port, "published"); published != nil && published.Value != "" {
+		entry = published.Value + ":" + entry
+	}
+	if protocol := mappingValue(port, "protocol"); protocol != nil && protocol.Value != "" && protocol.Value != "tcp" {
+		entry += "/" + protocol.Value
+	}
+	return entry
+}
+
+func mappingKeys(node *yaml.Node) []string {
+	if node == nil || node.Kind != yaml.MappingNode {
+		return nil
+	}
+	var keys []string
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		keys = append(keys, node.Content[i].Value)
+	}
+	return keys
+}
+
+func sequenceValues(node *yaml.Node) []*yaml.Node {
+	if node == nil || node.Kind != yaml.SequenceNode {
+		return nil
+	}
+	return node.Content
+}
+
+func scalarList(node *yaml.Node) []string {
+	var out []string
+	for _, item := range sequenceValues(node) {
+		if item.Kind == yaml.ScalarNode {
+			out = append(out, item.Value)
+		}
+	}
+	return out
+}
+
+func renderDocker(files []dockerFile) string {
+	var b strings.Builder
+	for _, file := range files {
+		fmt.Fprintf(&b, "%s (%s)\n", file.Path, file.Kind)
+		if file.Error != "" {
+			fmt.Fprintf(&b, "  error: %s\n", file.Error)
+		}
+		for _, stage := range file.Stages {
+			label := stage.Base
+			if stage.Name != "" {
+				label = stage.Name + " from " + stage.Base
+			}
+			fmt.Fprintf(&b, "  stage %s (line %d)", label, stage.Line)
+			if len(stage.Expose) > 0 {
+				fmt.Fprintf(&b, " expose %s", strings.Join(stage.Expose, ","))
+			}
+			if stage.Cmd != "" || stage.Entrypoint != "" {
+				fmt.Fprintf(&b, " runs %s", strings.TrimSpace(stage.Entrypoint+" "+stage.Cmd))
+			}
+			b.WriteString("\n")
+		}
+		for _, service := range file.Services {
+			source := service.Image
+			if service.Build != "" {
+				source = "build " + service.Build
+			}
+			fmt.Fprintf(&b, "  service %s (line %d): %s", service.Name, service.Line, source)
+			if len(service.Ports) > 0 {
+				fmt.Fprintf(&b, " ports %s", strings.Join(service.Ports, ","))
+			}
+			if len(service.DependsOn) > 0 {
+				deps := append([]string{}, service.DependsOn...)
+				sort.Strings(deps)
+				fmt.Fprintf(&b, " depends_on %s", strings.Join(deps, ","))
+			}
+			b.WriteString("\n")
+		}
+	}
+	return strings.TrimSuffix(b.String(), "\n")
+}
@@ -0,0 +1,95 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const testDockerfile = `# syntax=docker/dockerfile:1
+ARG GO_VERSION=1.24
+FROM golang:${GO_VERSION} AS build
+WORKDIR /src
+ENV CGO_ENABLED=0 API_TOKEN=secret-value
+COPY . .
+RUN go build \
+      -o /out/app ./cmd/app
+
+FROM --platform=linux/amd64 gcr.io/distroless/static AS runtime
+COPY --from=build /out/app /app
+EXPOSE 8080 9090/udp
+VOLUME ["/data"]
+USER nonroot
+ENTRYPOINT ["/app"]
+CMD ["serve", "--port", "8080"]
+`
+
+const testCompose = `services:
+  api:
+    build:
+      context: .
+      target: runtime
+    ports:
+      - "8080:8080"
+      - target: 9090
+        published: 19090
+        protocol: udp
+    depends_on:
+      db:
+        condition: service_healthy
+    environment:
+      DATABASE_URL: postgres://user:hunter2@db/app
+      LOG_LEVEL: debug
+    volumes:
+      - data:/data
+  db:
+    image: postgres:16
+    environment:
+      - POSTGRES_PASSWORD=hunter2
+volumes:
+  data: {}
+`
+
+func TestDockerAnalyze(t *testing.T) {
+	repoRoot := t.TempDir()
+	for name, body := range map[string]string{"Dockerfile": testDockerfile, "docker-compose.yml": testCompose, "main.go": "package main\n"} {
+		if err := os.WriteFile(filepath.Join(repoRoot, name), []byte(body), 0o644); err != nil {
+			t.Fatalf("failed to write %s: %v", name, err)
+		}
+	}
+	res, err := NewDockerAnalyzeTool().Execute(context.Background(), json.RawMessage(`{}`), Meta{RepoRoot: repoRoot})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	out := res.Payload.(dockerAnalyzeOutput)
+	if len(out.Files) != 2 {
+		t.Fatalf("expected the Dockerfile and compose file, got %+v", out.Files)
+	}
+
+	stages := out.Files[0].Stages
+	wantStages := []dockerStage{
+		{Name: "build", Base: "golang:${GO_VERSION}", Line: 3, Workdir: "/src", Env: []string{"CGO_ENABLED", "API_TOKEN"}},
+		{Name: "runtime", Base: "gcr.io/distroless/static", Line: 10, Expose: []string{"8080", "9090/udp"}, Volumes: []string{"/data"}, User: "nonroot", Entrypoint: "/app", Cmd: "serve --port 8080", CopiesFrom: []string{"build"}},
+	}
+	if !reflect.DeepEqual(stages, wantStages) {
+		t.Fatalf("stages = %+v\nwant %+v", stages, wantStages)
+	}
+
+	compose := out.Files[1]
+	wantServices := []composeService{
+		{Name: "api", Line: 2, Build: "context=. target=runtime", Ports: []string{"8080:8080", "19090:9090/udp"}, DependsOn: []string{"db"}, Environment: []string{"DATABASE_URL", "LOG_LEVEL"}, Volumes: []string{"data:/data"}},
+		{Name: "db", Line: 19, Image: "postgres:16", Environment: []string{"POSTGRES_PASSWORD"}},
+	}
+	if !reflect.DeepEqual(compose.Services, wantServices) || !reflect.DeepEqual(compose.Volumes, []string{"data"}) {
+		t.Fatalf("compose = %+v", compose)
+	}
+
+	raw, _ := json.Marshal(out)
+	if strings.Contains(string(raw), "hunter2") || strings.Contains(string(raw), "secret-value") {
+		t.Fatalf("docker_analyze leaked an environment value: %s", raw)
+	}
+}
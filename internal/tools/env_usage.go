@@ -0,0 +1,261 @@
+package tools
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	// maxEnvScanFiles bounds how many source files one env_usage call reads.
+	maxEnvScanFiles = 20000
+	// maxEnvScanBytes skips larger files, which are rarely hand-written code.
+	maxEnvScanBytes = 1 << 20
+	// maxEnvUses caps the locations reported per variable.
+	maxEnvUses = 5
+)
+
+// envReadPatterns match environment variable reads; group 1 is the name.
+var envReadPatterns = map[string][]*regexp.Regexp{
+	".go": {regexp.MustCompile(`os\.(?:Getenv|LookupEnv)\(\s*"([A-Za-z_][A-Za-z0-9_]*)"`)},
+	".js": {
+		regexp.MustCompile(`(?:process|import\.meta)\.env\.([A-Za-z_][A-Za-z0-9_]*)`),
+		regexp.MustCompile(`(?:process|import\.meta)\.env\[\s*["']([A-Za-z_][A-Za-z0-9_]*)["']\s*\]`),
+	},
+	".py": {
+		regexp.MustCompile(`os\.environ(?:\.get)?[\[(]\s*["']([A-Za-z_][A-Za-z0-9_]*)["']`),
+		regexp.MustCompile(`os\.getenv\(\s*["']([A-Za-z_][A-Za-z0-9_]*)["']`),
+	},
+	".rb":   {regexp.MustCompile(`ENV(?:\.fetch\(\s*|\[\s*)["']([A-Za-z_][A-Za-z0-9_]*)["']`)},
+	".rs":   {regexp.MustCompile(`env::var(?:_os)?\(\s*"([A-Za-z_][A-Za-z0-9_]*)"`)},
+	".java": {regexp.MustCompile(`System\.getenv\(\s*"([A-Za-z_][A-Za-z0-9_]*)"`)},
+	".php":  {regexp.MustCompile(`(?:getenv\(|\$_ENV\[|\$_SERVER\[|env\()\s*["']([A-Za-z_][A-Za-z0-9_]*)["']`)},
+}
+
+func init() {
+	for _, ext := range []string{".jsx", ".ts", ".tsx", ".mjs", ".cjs", ".vue", ".svelte"} {
+		envReadPatterns[ext] = envReadPatterns[".js"]
+	}
+	envReadPatterns[".kt"] = envReadPatterns[".java"]
+	envReadPatterns[".scala"] = envReadPatterns[".java"]
+}
+
+// envExampleFiles declare a service's variables. Only their names are read.
+var envExampleFiles = []string{".env.example", ".env.sample", ".env.template", ".env.dist", "example.env"}
+
+type EnvUsageTool struct{}
+
+// NewEnvUsageTool constructs a tool that maps environment variable reads.
+func NewEnvUsageTool() *EnvUsageTool {
+	return &EnvUsageTool{}
+}
+
+func (e *EnvUsageTool) Name() string { return "env_usage" }
+
+func (e *EnvUsageTool) Description() string {
+	return "List the environment variables the code reads (os.Getenv, process.env.X, os.environ, ENV[...], System.getenv, env::var) with their locations, cross-referenced with the names declared in .env.example. Values are never read or returned."
+}
+
+func (e *EnvUsageTool) Schema() map[string]any {
+	return map[string]any{
+		"type": "object",
+		"properties": map[string]any{
+			"path": map[string]any{"type": "string", "description": "Directory to scan (default: repo root). .env.example is looked up there and at the root."},
+			"name": map[string]any{"type": "string", "description": "Only report this variable."},
+		},
+		"required":             []string{},
+		"additionalProperties": false,
+	}
+}
+
+type envUsageInput struct {
+	Path string `json:"path"`
+	Name string `json:"name"`
+}
+
+type envVariable struct {
+	Name string   `json:"name"`
+	Uses []string `json:"uses"`
+	// Declared reports whether an .env.example file lists the name.
+	Declared bool `json:"declared"`
+}
+
+type envUsageOutput struct {
+	Variables []envVariable `json:"variables"`
+	// DeclaredOnly are names in .env.example that no scanned code reads.
+	DeclaredOnly []string `json:"declared_only,omitempty"`
+	ExampleFiles []string `json:"example_files,omitempty"`
+	FilesScanned int      `json:"files_scanned"`
+	Truncated    bool     `json:"truncated"`
+	DurationMs   int64    `json:"duration_ms"`
+}
+
+func (e *EnvUsageTool) Execute(ctx context.Context, input json.RawMessage, meta Meta) (Result, error) {
+	var args envUsageInput
+	if len(input) > 0 {
+		if err := json.Unmarshal(input, &args); err != nil {
+			return Result{}, err
+		}
+	}
+	fsys := meta.FS()
+	root, err := fsys.Name(strings.TrimSpace(args.Path))
+	if err != nil {
+		return Result{}, err
+	}
+	limit := meta.MaxResults
+	if limit <= 0 {
+		limit = DefaultListEntries
+	}
+
+	start := time.Now()
+	output := envUsageOutput{}
+	uses := map[string][]string{}
+	err = fs.WalkDir(fsys, root, func(name string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if d.IsDir() {
+			if name != root && (strings.HasPrefix(d.Name(), ".") || skipListDir(d.Name()) || d.Name() == "vendor") {
+				return fs.SkipDir
+			}
+			return nil
+		}
+		patterns := envReadPatterns[strings.ToLower(path.Ext(name))]
+		if len(patterns) == 0 || !fsys.Allowed(name) {
+			return nil
+		}
+		if output.FilesScanned >= maxEnvScanFiles {
+			output.Truncated = true
+			return fs.SkipAll
+		}
+		if info, err := d.Info(); err != nil || info.Size() > maxEnvScanBytes {
+			return nil
+		}
+		file, err := fsys.Open(name)
+		if err != nil {
+			return nil
+		}
+		defer file.Close()
+		output.FilesScanned++
+		scanner := bufio.NewScanner(file)
+		scanner.Buffer(make([]byte, 64*1024), maxEnvScanBytes)
+		for lineNum := 1; scanner.Scan(); lineNum++ {
+			line := scanner.Text()
+			for _, pattern := range patterns {
+				for _, match := range pattern.FindAllStringSubmatch(line, -1) {
+					uses[match[1]] = append(uses[match[1]], fmt.Sprintf("%s:%d", name, lineNum))
+				}
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return Result{}, err
+	}
+
+	declared := map[string]bool{}
+	for _, dir := range uniqueStrings([]string{root, "."}) {
+		for _, example := range envExampleFiles {
+			names, ok := readEnvNames(fsys, path.Join(dir, example))
+			if !ok {
+				continue
+			}
+			output.ExampleFiles = append(output.ExampleFiles, path.Join(dir, example))
+			for _, name := range names {
+				declared[name] = true
+			}
+		}
+	}
+
+	names := make([]string, 0, len(uses))
+	for name := range uses {
+		if args.Name == "" || name == args.Name {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if len(output.Variables) == limit {
+			output.Truncated = true
+			break
+		}
+		locations := uniqueStrings(uses[name])
+		if len(locations) > maxEnvUses {
+			locations = append(locations[:maxEnvUses], fmt.Sprintf("... %d more", len(locations)-maxEnvUses))
+		}
+		output.Variables = append(output.Variables, envVariable{Name: name, Uses: locations, Declared: declared[name]})
+	}
+	for name := range declared {
+		if _, used := uses[name]; !used && (args.Name == "" || name == args.Name) {
+			output.DeclaredOnly = append(output.DeclaredOnly, name)
+		}
+	}
+	sort.Strings(output.DeclaredOnly)
+	if output.Variables == nil {
+		output.Variables = []envVariable{}
+	}
+	output.DurationMs = time.Since(start).Milliseconds()
+
+	var lines []string
+	for _, variable := range output.Variables {
+		status := "not in .env.example"
+		if variable.Declared {
+			status = "declared"
+		}
+		lines = append(lines, fmt.Sprintf("%s (%s): %s", variable.Name, status, strings.Join(variable.Uses, ", ")))
+	}
+	if len(output.DeclaredOnly) > 0 {
+		lines = append(lines, "declared but unused: "+strings.Join(output.DeclaredOnly, ", "))
+	}
+	text := strings.Join(lines, "\n")
+	return Result{ToolName: e.Name(), Payload: output, Preview: meta.Preview(text), LineCount: len(lines), ByteCount: len(text), Truncated: output.Truncated, DurationMs: output.DurationMs}, nil
+}
+
+var envAssignment = regexp.MustCompile(`^\s*(?:export\s+)?([A-Za-z_][A-Za-z0-9_]*)\s*=`)
+
+// readEnvNames returns the variable names an env example file assigns.
+// Example files match the secret denylist, so this bypasses RepoFS.Open and
+// never keeps anything after the "=".
+func readEnvNames(fsys *RepoFS, name string) ([]string, bool) {
+	full, err := fsys.resolve("open", name)
+	if err != nil {
+		return nil, false
+	}
+	file, err := os.Open(full)
+	if err != nil {
+		return nil, false
+	}
+	defer file.Close()
+	var names []string
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		if match := envAssignment.FindStringSubmatch(scanner.Text()); match != nil {
+			names = append(names, match[1])
+		}
+	}
+	return names, true
+}
+
+// uniqueStrings drops repeated values, keeping the first occurrence.
+func uniqueStrings(values []string) []string {
+	seen := map[string]bool{}
+	out := values[:0:0]
+	for _, value := range values {
+		if !seen[value] {
+			seen[value] = true
+			out = append(out, value)
+		}
+	}
+	return out
+}
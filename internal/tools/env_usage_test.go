@@ -0,0 +1,54 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestEnvUsage(t *testing.T) {
+	repoRoot := t.TempDir()
+	files := map[string]string{
+		"main.go":           "package main\n\nvar port = os.Getenv(\"PORT\")\nvar _, ok = os.LookupEnv(\"DEBUG\")\n",
+		"web/app.ts":        "const url = process.env.API_URL;\nconst key = process.env[\"API_KEY\"];\n",
+		"worker/job.py":     "import os\nqueue = os.environ[\"QUEUE\"]\nport = os.environ.get('PORT')\n",
+		"node_modules/x.js": "process.env.IGNORED\n",
+		".env.example":      "PORT=8080\nAPI_KEY=sk-live-do-not-leak\nexport UNUSED_FLAG=1\n# COMMENTED=1\n",
+	}
+	for name, body := range files {
+		path := filepath.Join(repoRoot, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatalf("failed to create dir: %v", err)
+		}
+		if err := os.WriteFile(path, []byte(body), 0o644); err != nil {
+			t.Fatalf("failed to write %s: %v", name, err)
+		}
+	}
+
+	res, err := NewEnvUsageTool().Execute(context.Background(), json.RawMessage(`{}`), Meta{RepoRoot: repoRoot, MaxResults: 50})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	out := res.Payload.(envUsageOutput)
+	want := []envVariable{
+		{Name: "API_KEY", Uses: []string{"web/app.ts:2"}, Declared: true},
+		{Name: "API_URL", Uses: []string{"web/app.ts:1"}},
+		{Name: "DEBUG", Uses: []string{"main.go:4"}},
+		{Name: "PORT", Uses: []string{"main.go:3", "worker/job.py:3"}, Declared: true},
+		{Name: "QUEUE", Uses: []string{"worker/job.py:2"}},
+	}
+	if !reflect.DeepEqual(out.Variables, want) {
+		t.Fatalf("variables = %+v, want %+v", out.Variables, want)
+	}
+	if !reflect.DeepEqual(out.DeclaredOnly, []string{"UNUSED_FLAG"}) {
+		t.Fatalf("declared only = %v", out.DeclaredOnly)
+	}
+	raw, _ := json.Marshal(out)
+	if strings.Contains(string(raw), "sk-live") || strings.Contains(res.Preview, "8080") {
+		t.Fatalf("env_usage leaked an example value: %s", raw)
+	}
+}
@@ -8,29 +8,81 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"slices"
 	"strings"
 	"time"
 
+	"fi-cli/internal/util"
+
 	retryablehttp "github.com/hashicorp/go-retryablehttp"
 )
 
-type ExaTool struct {
-	apiKey string
-	client *retryablehttp.Client
+// exaBaseURL is the Exa API the tools call.
+const exaBaseURL = "https://api.exa.ai"
+
+// exaMaxResults is the most results one Exa search returns. Exa has no
+// offset, so page N is requested as the first N pages and sliced, and pages
+// past this limit cannot be reached.
+const exaMaxResults = 100
+
+// exaMaxAgeDays caps max_age_days at ten years; anything older is no filter.
+const exaMaxAgeDays = 3650
+
+// exaCategories are the result categories Exa can restrict a search to.
+var exaCategories = []string{"company", "research paper", "news", "pdf", "github", "tweet", "personal site", "linkedin profile", "financial report"}
+
+// exaClient is the authenticated Exa API client the Exa tools share.
+type exaClient struct {
+	apiKey  string
+	baseURL string
+	client  *retryablehttp.Client
 }
 
-// NewExaTool constructs an Exa search tool.
-func NewExaTool(apiKey string) *ExaTool {
+func newExaClient(apiKey string) exaClient {
 	client := retryablehttp.NewClient()
 	client.RetryMax = 2
 	client.Logger = nil
-	return &ExaTool{apiKey: apiKey, client: client}
+	return exaClient{apiKey: apiKey, baseURL: exaBaseURL, client: client}
+}
+
+// post sends payload to the Exa endpoint and decodes the response into out.
+func (c exaClient) post(ctx context.Context, endpoint string, payload any, out any) error {
+	if strings.TrimSpace(c.apiKey) == "" {
+		return errors.New("EXA_API_KEY is missing")
+	}
+	body, _ := json.Marshal(payload)
+	request, err := retryablehttp.NewRequestWithContext(ctx, http.MethodPost, c.baseURL+endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	request.Header.Set("x-api-key", c.apiKey)
+	request.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.client.Do(request)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		b, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("exa %s failed: %s", strings.TrimPrefix(endpoint, "/"), string(b))
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+type ExaTool struct {
+	exaClient
+}
+
+// NewExaTool constructs an Exa search tool.
+func NewExaTool(apiKey string) *ExaTool {
+	return &ExaTool{exaClient: newExaClient(apiKey)}
 }
 
 func (e *ExaTool) Name() string { return "exa_search" }
 
 func (e *ExaTool) Description() string {
-	return "Search the web via Exa and return titles, URLs, and snippets."
+	return "Search the web via Exa and return titles, URLs, published dates, and snippets. Use page for more results of the same query, and exa_contents for the full text of one result."
 }
 
 func (e *ExaTool) Schema() map[string]any {
@@ -40,6 +92,32 @@ func (e *ExaTool) Schema() map[string]any {
 			"query":        map[string]any{"type": "string"},
 			"num_results":  map[string]any{"type": "integer", "minimum": 1, "maximum": 10},
 			"include_text": map[string]any{"type": "boolean"},
+			"page":         map[string]any{"type": "integer", "minimum": 1, "description": "1-based page of num_results results; has_more in the result says whether another page likely exists"},
+			"start_published_date": map[string]any{
+				"type":        "string",
+				"description": "Only results published on or after this date (YYYY-MM-DD or RFC 3339)",
+			},
+			"end_published_date": map[string]any{
+				"type":        "string",
+				"description": "Only results published on or before this date (YYYY-MM-DD or RFC 3339)",
+			},
+			"max_age_days": map[string]any{
+				"type":        "integer",
+				"minimum":     1,
+				"maximum":     exaMaxAgeDays,
+				"description": "Only results published in the last N days; use instead of start_published_date when today's date is unknown",
+			},
+			"category": map[string]any{"type": "string", "enum": exaCategories},
+			"include_domains": map[string]any{
+				"type":        "array",
+				"items":       map[string]any{"type": "string"},
+				"description": "Only results from these domains, e.g. official docs such as [\"go.dev\", \"pkg.go.dev\"]",
+			},
+			"exclude_domains": map[string]any{
+				"type":        "array",
+				"items":       map[string]any{"type": "string"},
+				"description": "No results from these domains; not combined with include_domains",
+			},
 		},
 		"required":             []string{"query"},
 		"additionalProperties": false,
@@ -47,23 +125,41 @@ func (e *ExaTool) Schema() map[string]any {
 }
 
 type exaInput struct {
-	Query       string `json:"query"`
-	NumResults  int    `json:"num_results"`
-	IncludeText *bool  `json:"include_text"`
+	Query              string   `json:"query"`
+	NumResults         int      `json:"num_results"`
+	IncludeText        *bool    `json:"include_text"`
+	Page               int      `json:"page"`
+	StartPublishedDate string   `json:"start_published_date"`
+	EndPublishedDate   string   `json:"end_published_date"`
+	MaxAgeDays         int      `json:"max_age_days"`
+	Category           string   `json:"category"`
+	IncludeDomains     []string `json:"include_domains"`
+	ExcludeDomains     []string `json:"exclude_domains"`
 }
 
 type exaResult struct {
-	Title   string `json:"title"`
-	URL     string `json:"url"`
-	Snippet string `json:"snippet"`
+	Title         string `json:"title"`
+	URL           string `json:"url"`
+	PublishedDate string `json:"published_date,omitempty"`
+	Snippet       string `json:"snippet"`
 }
 
 type exaOutput struct {
 	Results    []exaResult `json:"results"`
+	Page       int         `json:"page"`
+	HasMore    bool        `json:"has_more"`
 	DurationMs int64       `json:"duration_ms"`
 	Truncated  bool        `json:"truncated"`
 }
 
+// exaDocument is a result as the Exa API returns it.
+type exaDocument struct {
+	Title         string `json:"title"`
+	URL           string `json:"url"`
+	PublishedDate string `json:"publishedDate"`
+	Text          string `json:"text"`
+}
+
 func (e *ExaTool) Execute(ctx context.Context, input json.RawMessage, meta Meta) (Result, error) {
 	if strings.TrimSpace(e.apiKey) == "" {
 		return Result{}, errors.New("EXA_API_KEY is missing")
@@ -82,6 +178,12 @@ func (e *ExaTool) Execute(ctx context.Context, input json.RawMessage, meta Meta)
 	if args.NumResults > 10 {
 		args.NumResults = 10
 	}
+	if args.Page <= 0 {
+		args.Page = 1
+	}
+	if args.Page*args.NumResults > exaMaxResults {
+		return Result{}, fmt.Errorf("page %d of %d results is past Exa's limit of %d results per query; refine the query instead", args.Page, args.NumResults, exaMaxResults)
+	}
 	includeText := true
 	if args.IncludeText != nil {
 		includeText = *args.IncludeText
@@ -91,56 +193,198 @@ func (e *ExaTool) Execute(ctx context.Context, input json.RawMessage, meta Meta)
 	ctx, cancel := context.WithTimeout(ctx, time.Duration(meta.ToolTimeoutSeconds)*time.Second)
 	defer cancel()
 
+	requested := args.Page * args.NumResults
 	payload := map[string]any{
 		"query":      args.Query,
-		"numResults": args.NumResults,
+		"numResults": requested,
 	}
 	if includeText {
 		payload["contents"] = map[string]any{"text": true}
 	}
-
-	body, _ := json.Marshal(payload)
-	request, err := retryablehttp.NewRequestWithContext(ctx, http.MethodPost, "https://api.exa.ai/search", bytes.NewReader(body))
-	if err != nil {
-		return Result{}, err
+	if args.MaxAgeDays != 0 {
+		if args.StartPublishedDate != "" {
+			return Result{}, errors.New("pass max_age_days or start_published_date, not both")
+		}
+		if args.MaxAgeDays < 0 || args.MaxAgeDays > exaMaxAgeDays {
+			return Result{}, fmt.Errorf("max_age_days must be between 1 and %d", exaMaxAgeDays)
+		}
+		payload["startPublishedDate"] = time.Now().UTC().AddDate(0, 0, -args.MaxAgeDays).Truncate(24 * time.Hour).Format(time.RFC3339)
 	}
-	request.Header.Set("x-api-key", e.apiKey)
-	request.Header.Set("Content-Type", "application/json")
-
-	resp, err := e.client.Do(request)
-	if err != nil {
-		return Result{}, err
+	for _, filter := range []struct{ arg, key, value string }{
+		{"start_published_date", "startPublishedDate", args.StartPublishedDate},
+		{"end_published_date", "endPublishedDate", args.EndPublishedDate},
+	} {
+		if filter.value == "" {
+			continue
+		}
+		date, err := exaDate(filter.arg, filter.value)
+		if err != nil {
+			return Result{}, err
+		}
+		payload[filter.key] = date
 	}
-	defer resp.Body.Close()
-	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
-		b, _ := io.ReadAll(resp.Body)
-		return Result{}, fmt.Errorf("exa search failed: %s", string(b))
+	if args.Category != "" {
+		if !slices.Contains(exaCategories, args.Category) {
+			return Result{}, fmt.Errorf("unknown category %q (expected one of %s)", args.Category, strings.Join(exaCategories, ", "))
+		}
+		payload["category"] = args.Category
+	}
+	if len(args.IncludeDomains) > 0 && len(args.ExcludeDomains) > 0 {
+		return Result{}, errors.New("pass include_domains or exclude_domains, not both")
+	}
+	for key, domains := range map[string][]string{"includeDomains": args.IncludeDomains, "excludeDomains": args.ExcludeDomains} {
+		if len(domains) == 0 {
+			continue
+		}
+		normalized, err := exaDomains(domains)
+		if err != nil {
+			return Result{}, err
+		}
+		payload[key] = normalized
 	}
 
 	var raw struct {
-		Results []struct {
-			Title string `json:"title"`
-			URL   string `json:"url"`
-			Text  string `json:"text"`
-		} `json:"results"`
-	}
-	decoder := json.NewDecoder(resp.Body)
-	if err := decoder.Decode(&raw); err != nil {
+		Results []exaDocument `json:"results"`
+	}
+	if err := e.post(ctx, "/search", payload, &raw); err != nil {
 		return Result{}, err
 	}
 
-	results := make([]exaResult, 0, len(raw.Results))
-	for _, item := range raw.Results {
-		results = append(results, exaResult{Title: item.Title, URL: item.URL, Snippet: item.Text})
+	results := []exaResult{}
+	for i := (args.Page - 1) * args.NumResults; i < len(raw.Results); i++ {
+		item := raw.Results[i]
+		results = append(results, exaResult{Title: item.Title, URL: item.URL, PublishedDate: item.PublishedDate, Snippet: item.Text})
 	}
 
 	truncated, byteCount := fitExaResults(&results, meta.MaxBytes)
-	output := exaOutput{Results: results, DurationMs: time.Since(start).Milliseconds(), Truncated: truncated}
-	preview := buildPreview(results)
+	// a full page suggests Exa has more; a short one means the query ran dry
+	hasMore := len(raw.Results) >= requested && requested+args.NumResults <= exaMaxResults
+	output := exaOutput{Results: results, Page: args.Page, HasMore: hasMore, DurationMs: time.Since(start).Milliseconds(), Truncated: truncated}
+	preview := meta.Preview(buildPreview(results))
 	lineCount := strings.Count(preview, "\n") + 1
 	return Result{ToolName: e.Name(), Payload: output, Preview: preview, LineCount: lineCount, ByteCount: byteCount, Truncated: truncated, DurationMs: output.DurationMs}, nil
 }
 
+// exaDate accepts a date or an RFC 3339 time for the argument name and
+// returns the ISO 8601 time Exa expects.
+func exaDate(name, value string) (string, error) {
+	value = strings.TrimSpace(value)
+	if t, err := time.Parse(time.DateOnly, value); err == nil {
+		return t.Format(time.RFC3339), nil
+	}
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t.UTC().Format(time.RFC3339), nil
+	}
+	return "", fmt.Errorf("invalid %s %q (expected YYYY-MM-DD or RFC 3339)", name, value)
+}
+
+// exaDomains reduces domain arguments to bare hosts: models often pass URLs
+// such as "https://go.dev/doc/" where Exa expects "go.dev".
+func exaDomains(domains []string) ([]string, error) {
+	out := make([]string, 0, len(domains))
+	for _, domain := range domains {
+		host := strings.ToLower(strings.TrimSpace(domain))
+		if _, rest, ok := strings.Cut(host, "://"); ok {
+			host = rest
+		}
+		host, _, _ = strings.Cut(host, "/")
+		if host == "" || strings.ContainsAny(host, " ?#") {
+			return nil, fmt.Errorf("invalid domain %q", domain)
+		}
+		if !slices.Contains(out, host) {
+			out = append(out, host)
+		}
+	}
+	return out, nil
+}
+
+// ExaContentsTool fetches the full text of a page through Exa, typically a
+// result of an earlier exa_search.
+type ExaContentsTool struct {
+	exaClient
+}
+
+// NewExaContentsTool constructs the Exa contents tool.
+func NewExaContentsTool(apiKey string) *ExaContentsTool {
+	return &ExaContentsTool{exaClient: newExaClient(apiKey)}
+}
+
+func (e *ExaContentsTool) Name() string { return "exa_contents" }
+
+func (e *ExaContentsTool) Description() string {
+	return "Fetch the full text of one web page via Exa, usually a URL returned by exa_search whose snippet was not enough."
+}
+
+func (e *ExaContentsTool) Schema() map[string]any {
+	return map[string]any{
+		"type": "object",
+		"properties": map[string]any{
+			"url": map[string]any{"type": "string"},
+		},
+		"required":             []string{"url"},
+		"additionalProperties": false,
+	}
+}
+
+type exaContentsOutput struct {
+	Title         string `json:"title"`
+	URL           string `json:"url"`
+	PublishedDate string `json:"published_date,omitempty"`
+	Text          string `json:"text"`
+	DurationMs    int64  `json:"duration_ms"`
+	Truncated     bool   `json:"truncated"`
+}
+
+func (e *ExaContentsTool) Execute(ctx context.Context, input json.RawMessage, meta Meta) (Result, error) {
+	var args struct {
+		URL string `json:"url"`
+	}
+	if err := json.Unmarshal(input, &args); err != nil {
+		return Result{}, err
+	}
+	args.URL = strings.TrimSpace(args.URL)
+	if args.URL == "" {
+		return Result{}, errors.New("url is required")
+	}
+
+	start := time.Now()
+	ctx, cancel := context.WithTimeout(ctx, time.Duration(meta.ToolTimeoutSeconds)*time.Second)
+	defer cancel()
+
+	var raw struct {
+		Results  []exaDocument `json:"results"`
+		Statuses []struct {
+			ID     string `json:"id"`
+			Status string `json:"status"`
+			Error  struct {
+				Tag string `json:"tag"`
+			} `json:"error"`
+		} `json:"statuses"`
+	}
+	if err := e.post(ctx, "/contents", map[string]any{"urls": []string{args.URL}, "text": true}, &raw); err != nil {
+		return Result{}, err
+	}
+	if len(raw.Results) == 0 {
+		for _, status := range raw.Statuses {
+			if status.Status == "error" && status.Error.Tag != "" {
+				return Result{}, fmt.Errorf("exa has no content for %s: %s", args.URL, status.Error.Tag)
+			}
+		}
+		return Result{}, fmt.Errorf("exa has no content for %s", args.URL)
+	}
+
+	doc := raw.Results[0]
+	output := exaContentsOutput{Title: doc.Title, URL: doc.URL, PublishedDate: doc.PublishedDate, Text: doc.Text}
+	if meta.MaxBytes > 0 {
+		// leave room for the other fields within the byte budget
+		output.Text, output.Truncated = util.TruncateBytes(output.Text, max(meta.MaxBytes-len(output.Title)-len(output.URL)-128, 256))
+	}
+	output.DurationMs = time.Since(start).Milliseconds()
+	data, _ := json.Marshal(output)
+	preview := meta.Preview(strings.TrimSpace(output.Title + " - " + output.URL + "\n" + output.Text))
+	return Result{ToolName: e.Name(), Payload: output, Preview: preview, LineCount: strings.Count(output.Text, "\n") + 1, ByteCount: len(data), Truncated: output.Truncated, DurationMs: output.DurationMs}, nil
+}
+
 func fitExaResults(results *[]exaResult, maxBytes int) (bool, int) {
 	if maxBytes <= 0 {
 		return false, 0
@@ -0,0 +1,108 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func exaServer(t *testing.T, handle func(endpoint string, body map[string]any) any) string {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("x-api-key") != "k" {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		var body map[string]any
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		_ = json.NewEncoder(w).Encode(handle(r.URL.Path, body))
+	}))
+	t.Cleanup(server.Close)
+	return server.URL
+}
+
+func TestExaSearchPagesAndFilters(t *testing.T) {
+	var sent map[string]any
+	tool := NewExaTool("k")
+	tool.baseURL = exaServer(t, func(endpoint string, body map[string]any) any {
+		sent = body
+		var results []map[string]any
+		for i := 0; i < int(body["numResults"].(float64)); i++ {
+			results = append(results, map[string]any{"title": "r" + string(rune('a'+i)), "url": "https://example.com/" + string(rune('a'+i)), "publishedDate": "2025-02-01T00:00:00Z"})
+		}
+		return map[string]any{"results": results}
+	})
+	meta := Meta{ToolTimeoutSeconds: 5, MaxBytes: 30 * 1024}
+
+	result, err := tool.Execute(context.Background(), json.RawMessage(`{"query":"go release","num_results":2,"page":2,"category":"news","start_published_date":"2025-01-01","include_text":false}`), meta)
+	if err != nil {
+		t.Fatalf("search failed: %v", err)
+	}
+	if sent["numResults"] != float64(4) || sent["category"] != "news" || sent["startPublishedDate"] != "2025-01-01T00:00:00Z" || sent["contents"] != nil {
+		t.Fatalf("unexpected request %v", sent)
+	}
+	output := result.Payload.(exaOutput)
+	if len(output.Results) != 2 || output.Results[0].Title != "rc" || output.Page != 2 || !output.HasMore || output.Results[0].PublishedDate == "" {
+		t.Fatalf("expected the second page of two, got %+v", output)
+	}
+
+	if _, err := tool.Execute(context.Background(), json.RawMessage(`{"query":"q","include_domains":["https://Go.dev/doc/","pkg.go.dev","go.dev"]}`), meta); err != nil {
+		t.Fatalf("search failed: %v", err)
+	}
+	if domains, _ := json.Marshal(sent["includeDomains"]); string(domains) != `["go.dev","pkg.go.dev"]` || sent["excludeDomains"] != nil {
+		t.Fatalf("expected bare, deduplicated domains, got %v", sent)
+	}
+
+	if _, err := tool.Execute(context.Background(), json.RawMessage(`{"query":"openssl cve","max_age_days":30,"end_published_date":"2099-01-01"}`), meta); err != nil {
+		t.Fatalf("search failed: %v", err)
+	}
+	since, err := time.Parse(time.RFC3339, sent["startPublishedDate"].(string))
+	if age := time.Since(since); err != nil || age < 30*24*time.Hour || age > 31*24*time.Hour || sent["endPublishedDate"] != "2099-01-01T00:00:00Z" {
+		t.Fatalf("expected a start 30 days back and the end date, got %v", sent)
+	}
+
+	for input, want := range map[string]string{
+		`{"query":"q","max_age_days":30,"start_published_date":"2025-01-01"}`:    "not both",
+		`{"query":"q","max_age_days":99999}`:                                     "max_age_days",
+		`{"query":"q","end_published_date":"soon"}`:                              "invalid end_published_date",
+		`{"query":"q","include_domains":["go.dev"],"exclude_domains":["x.com"]}`: "not both",
+		`{"query":"q","exclude_domains":["  "]}`:                                 "invalid domain",
+		`{"query":"q","num_results":10,"page":11}`:                               "past Exa's limit",
+		`{"query":"q","category":"blog"}`:                                        "unknown category",
+		`{"query":"q","start_published_date":"last week"}`:                       "invalid start_published_date",
+	} {
+		if _, err := tool.Execute(context.Background(), json.RawMessage(input), meta); err == nil || !strings.Contains(err.Error(), want) {
+			t.Fatalf("%s: expected %q, got %v", input, want, err)
+		}
+	}
+}
+
+func TestExaContents(t *testing.T) {
+	tool := NewExaContentsTool("k")
+	tool.baseURL = exaServer(t, func(endpoint string, body map[string]any) any {
+		if endpoint != "/contents" {
+			t.Errorf("unexpected endpoint %s", endpoint)
+		}
+		if urls := body["urls"].([]any); urls[0] == "https://example.com/missing" {
+			return map[string]any{"results": []any{}, "statuses": []any{map[string]any{"id": urls[0], "status": "error", "error": map[string]any{"tag": "CRAWL_NOT_FOUND"}}}}
+		}
+		return map[string]any{"results": []any{map[string]any{"title": "Release notes", "url": "https://example.com/notes", "text": strings.Repeat("notes ", 200)}}}
+	})
+	meta := Meta{ToolTimeoutSeconds: 5, MaxBytes: 512}
+
+	result, err := tool.Execute(context.Background(), json.RawMessage(`{"url":"https://example.com/notes"}`), meta)
+	if err != nil {
+		t.Fatalf("contents failed: %v", err)
+	}
+	output := result.Payload.(exaContentsOutput)
+	if output.Title != "Release notes" || !output.Truncated || len(output.Text) > 512 {
+		t.Fatalf("expected the text truncated to the budget, got %+v", output)
+	}
+	if _, err := tool.Execute(context.Background(), json.RawMessage(`{"url":"https://example.com/missing"}`), meta); err == nil || !strings.Contains(err.Error(), "CRAWL_NOT_FOUND") {
+		t.Fatalf("expected the crawl error, got %v", err)
+	}
+}
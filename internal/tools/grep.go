@@ -7,16 +7,15 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
-	"io"
-	"os"
+	"io/fs"
 	"os/exec"
 	"path"
 	"path/filepath"
 	"regexp"
+	"slices"
 	"strings"
 	"time"
 
-	"fi-cli/internal/repo"
 	"fi-cli/internal/util"
 )
 
@@ -30,24 +29,34 @@ func NewGrepTool() *GrepTool {
 	return &GrepTool{rgPath: path}
 }
 
+// HasRipgrep reports whether rg was found; otherwise a slower Go fallback runs.
+func (g *GrepTool) HasRipgrep() bool { return g.rgPath != "" }
+
 func (g *GrepTool) Name() string { return "grep" }
 
 func (g *GrepTool) Description() string {
 	return "Search for a regex pattern in repository files using ripgrep when available."
 }
 
+// Schema describes glob syntax for the engine that will run: ripgrep globs
+// when rg is installed, the narrower built-in matcher otherwise.
 func (g *GrepTool) Schema() map[string]any {
+	globHelp := "ripgrep globs, e.g. \"*.go\", \"src/**/*.ts\", \"!vendor/**\" to exclude."
+	if !g.HasRipgrep() {
+		globHelp = "File globs, e.g. \"*.go\" (matches the file name at any depth) or \"src/*.ts\" (matches the repo-relative path); prefix with ! to exclude. ** is treated like *."
+	}
 	return map[string]any{
 		"type": "object",
 		"properties": map[string]any{
-			"pattern": map[string]any{"type": "string"},
+			"pattern": map[string]any{"type": "string", "description": "Regular expression (RE2 syntax)."},
 			"paths": map[string]any{
 				"type":  "array",
 				"items": map[string]any{"type": "string"},
 			},
 			"glob": map[string]any{
-				"type":  "array",
-				"items": map[string]any{"type": "string"},
+				"type":        "array",
+				"items":       map[string]any{"type": "string"},
+				"description": globHelp,
 			},
 			"case_sensitive": map[string]any{"type": "boolean"},
 			"max_results":    map[string]any{"type": "integer", "minimum": 1},
@@ -93,7 +102,7 @@ func (g *GrepTool) Execute(ctx context.Context, input json.RawMessage, meta Meta
 		redacted := redactLines(matches)
 		lines, truncated, byteCount := util.TruncateLinesAndBytes(redacted, args.MaxResults, meta.MaxBytes)
 		output := grepOutput{Matches: lines, Truncated: truncated, DurationMs: time.Since(start).Milliseconds(), Warning: warning}
-		preview := util.Preview(strings.Join(lines, "\n"), 12, 2000)
+		preview := meta.Preview(strings.Join(lines, "\n"))
 		return Result{ToolName: g.Name(), Payload: output, Preview: preview, LineCount: len(lines), ByteCount: byteCount, Truncated: truncated, DurationMs: output.DurationMs}, nil
 	}
 
@@ -104,7 +113,7 @@ func (g *GrepTool) Execute(ctx context.Context, input json.RawMessage, meta Meta
 	redacted := redactLines(matches)
 	lines, truncated, byteCount := util.TruncateLinesAndBytes(redacted, args.MaxResults, meta.MaxBytes)
 	output := grepOutput{Matches: lines, Truncated: truncated, DurationMs: time.Since(start).Milliseconds(), Warning: "rg not found; using Go fallback"}
-	preview := util.Preview(strings.Join(lines, "\n"), 12, 2000)
+	preview := meta.Preview(strings.Join(lines, "\n"))
 	return Result{ToolName: g.Name(), Payload: output, Preview: preview, LineCount: len(lines), ByteCount: byteCount, Truncated: truncated, DurationMs: output.DurationMs}, nil
 }
 
@@ -122,12 +131,22 @@ func (g *GrepTool) runRipgrep(ctx context.Context, args grepInput, meta Meta) ([
 		}
 		cmdArgs = append(cmdArgs, "--glob", glob)
 	}
-	for _, deny := range denylistGlobs() {
+	for _, deny := range append(denylistGlobs(), meta.Policy.DenyGlobs()...) {
 		cmdArgs = append(cmdArgs, "--glob", deny)
 	}
 	cmdArgs = append(cmdArgs, args.Pattern)
 
 	paths := sanitizePaths(args.Paths, meta.RepoRoot)
+	// rg follows a symlink named on its command line, past the deny globs
+	fsys := meta.FS()
+	named := len(paths)
+	paths = slices.DeleteFunc(paths, func(p string) bool {
+		info, err := fsys.Stat(filepath.ToSlash(p))
+		return err == nil && !info.IsDir() && !fsys.Allowed(filepath.ToSlash(p))
+	})
+	if named > 0 && len(paths) == 0 {
+		return []string{}, "", nil
+	}
 	if len(paths) == 0 {
 		paths = []string{"."}
 	}
@@ -170,9 +189,15 @@ func (g *GrepTool) runFallback(ctx context.Context, args grepInput, meta Meta) (
 		return nil, err
 	}
 
-	paths := sanitizePaths(args.Paths, meta.RepoRoot)
+	fsys := meta.FS()
+	var paths []string
+	for _, p := range args.Paths {
+		if name, err := fsys.Name(p); err == nil && p != "" {
+			paths = append(paths, name)
+		}
+	}
 	if len(paths) == 0 {
-		paths = []string{meta.RepoRoot}
+		paths = []string{"."}
 	}
 
 	var matches []string
@@ -182,7 +207,7 @@ func (g *GrepTool) runFallback(ctx context.Context, args grepInput, meta Meta) (
 			return matches, ctx.Err()
 		default:
 		}
-		err := filepath.WalkDir(root, func(path string, d os.DirEntry, err error) error {
+		err := fs.WalkDir(fsys, root, func(name string, d fs.DirEntry, err error) error {
 			if err != nil {
 				return nil
 			}
@@ -192,28 +217,28 @@ func (g *GrepTool) runFallback(ctx context.Context, args grepInput, meta Meta) (
 				}
 				return nil
 			}
-			if repo.IsDenylisted(path) {
+			if !fsys.Allowed(name) {
 				return nil
 			}
-			if len(args.Glob) > 0 && !matchAnyGlob(path, meta.RepoRoot, args.Glob) {
+			if len(args.Glob) > 0 && !matchAnyGlob(name, ".", args.Glob) {
 				return nil
 			}
-			file, err := os.Open(path)
+			// Open refuses symlinks out of the repo and oversized files
+			file, err := fsys.Open(name)
 			if err != nil {
 				return nil
 			}
 			defer file.Close()
-			if isBinary(file) {
+			reader := bufio.NewReader(file)
+			if isBinary(reader) {
 				return nil
 			}
-			_, _ = file.Seek(0, io.SeekStart)
-			scanner := bufio.NewScanner(file)
+			scanner := bufio.NewScanner(reader)
 			lineNum := 1
 			for scanner.Scan() {
 				line := scanner.Text()
 				if re.MatchString(line) {
-					rel, _ := filepath.Rel(meta.RepoRoot, path)
-					matches = append(matches, fmt.Sprintf("%s:%d:%s", rel, lineNum, line))
+					matches = append(matches, fmt.Sprintf("%s:%d:%s", filepath.FromSlash(name), lineNum, line))
 					if args.MaxResults > 0 && len(matches) >= args.MaxResults {
 						return stopWalk
 					}
@@ -232,18 +257,16 @@ func (g *GrepTool) runFallback(ctx context.Context, args grepInput, meta Meta) (
 	return matches, nil
 }
 
+// sanitizePaths returns the repo-relative form of each path that stays inside
+// repoRoot, dropping the rest (including symlinks that point outside).
 func sanitizePaths(paths []string, repoRoot string) []string {
 	var out []string
 	for _, p := range paths {
 		if p == "" {
 			continue
 		}
-		abs := p
-		if !filepath.IsAbs(p) {
-			abs = filepath.Join(repoRoot, p)
-		}
-		rel, err := filepath.Rel(repoRoot, abs)
-		if err != nil || strings.HasPrefix(rel, "..") {
+		_, rel, err := resolveInRepo(repoRoot, p)
+		if err != nil {
 			continue
 		}
 		out = append(out, rel)
@@ -271,19 +294,31 @@ func matchAnyGlob(pathValue string, root string, globs []string) bool {
 		return false
 	}
 	rel = filepath.ToSlash(rel)
+	included, hasInclude := false, false
 	for _, g := range globs {
-		clean := strings.ReplaceAll(g, "**", "*")
-		if ok, _ := path.Match(clean, rel); ok {
-			return true
+		negate := strings.HasPrefix(g, "!")
+		clean := strings.ReplaceAll(strings.TrimPrefix(g, "!"), "**", "*")
+		target := rel
+		if !strings.Contains(clean, "/") {
+			// like rg, a glob without a slash matches the file name at any depth
+			target = path.Base(rel)
 		}
+		ok, _ := path.Match(clean, target)
+		if negate {
+			if ok {
+				return false
+			}
+			continue
+		}
+		hasInclude = true
+		included = included || ok
 	}
-	return false
+	return included || !hasInclude
 }
 
-func isBinary(file *os.File) bool {
-	buf := make([]byte, 8000)
-	n, _ := file.Read(buf)
-	for _, b := range buf[:n] {
+func isBinary(reader *bufio.Reader) bool {
+	buf, _ := reader.Peek(8000)
+	for _, b := range buf {
 		if b == 0 {
 			return true
 		}
@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 )
 
@@ -29,3 +30,37 @@ func TestGrepFallback(t *testing.T) {
 		t.Fatalf("expected matches")
 	}
 }
+
+func TestMatchAnyGlobFallbackSemantics(t *testing.T) {
+	root := "/repo"
+	cases := []struct {
+		path  string
+		globs []string
+		want  bool
+	}{
+		{"/repo/internal/agent/agent.go", []string{"*.go"}, true},
+		{"/repo/internal/agent/agent.go", []string{"internal/*.go"}, false},
+		{"/repo/cmd/main.go", []string{"cmd/*.go"}, true},
+		{"/repo/vendor/lib.go", []string{"*.go", "!vendor/*"}, false},
+		{"/repo/main.go", []string{"!*_test.go"}, true},
+		{"/repo/main_test.go", []string{"!*_test.go"}, false},
+	}
+	for _, tc := range cases {
+		if got := matchAnyGlob(tc.path, root, tc.globs); got != tc.want {
+			t.Errorf("matchAnyGlob(%s, %v) = %v, want %v", tc.path, tc.globs, got, tc.want)
+		}
+	}
+}
+
+func TestGrepSchemaDescribesEngine(t *testing.T) {
+	tool := &GrepTool{}
+	glob := tool.Schema()["properties"].(map[string]any)["glob"].(map[string]any)
+	if desc, _ := glob["description"].(string); desc == "" || strings.Contains(desc, "ripgrep") {
+		t.Fatalf("expected built-in glob help without rg, got %q", desc)
+	}
+	tool.rgPath = "/usr/bin/rg"
+	glob = tool.Schema()["properties"].(map[string]any)["glob"].(map[string]any)
+	if desc, _ := glob["description"].(string); !strings.Contains(desc, "ripgrep") {
+		t.Fatalf("expected ripgrep glob help, got %q", desc)
+	}
+}
@@ -0,0 +1,313 @@
+package tools
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+
+	"fi-cli/internal/util"
+
+	retryablehttp "github.com/hashicorp/go-retryablehttp"
+)
+
+// Issue trackers supported by issue_lookup.
+const (
+	IssueTrackerJira   = "jira"
+	IssueTrackerLinear = "linear"
+)
+
+const (
+	linearGraphQLURL = "https://api.linear.app/graphql"
+	// maxIssueComments keeps the most recent comments of a long thread.
+	maxIssueComments = 20
+	// maxIssueDescription and maxIssueComment cap ticket text before the
+	// whole result is fitted to meta.MaxBytes.
+	maxIssueDescription = 8 * 1024
+	maxIssueComment     = 1024
+)
+
+// issueIDPattern matches tracker keys such as PAY-123 or ENG-42.
+var issueIDPattern = regexp.MustCompile(`^[A-Za-z][A-Za-z0-9_]*-[0-9]+$`)
+
+const linearIssueQuery = `query Issue($id: String!) {
+  issue(id: $id) {
+    identifier title description url
+    state { name }
+    comments(last: 50) { nodes { body createdAt user { name } } }
+  }
+}`
+
+// IssueLookupTool fetches a ticket's title, description, and comments from
+// Jira or Linear, so a question about a ticket has its requirements as
+// evidence.
+type IssueLookupTool struct {
+	tracker string
+	baseURL string
+	email   string
+	token   string
+	client  *retryablehttp.Client
+}
+
+// NewJiraIssueTool constructs issue_lookup for the Jira site at baseURL. With
+// an email the token is a Jira Cloud API token; without one it is sent as a
+// personal access token (Jira Server and Data Center).
+func NewJiraIssueTool(baseURL, email, token string) *IssueLookupTool {
+	return newIssueLookupTool(IssueTrackerJira, strings.TrimRight(baseURL, "/"), email, token)
+}
+
+// NewLinearIssueTool constructs issue_lookup for Linear with a personal API key.
+func NewLinearIssueTool(apiKey string) *IssueLookupTool {
+	return newIssueLookupTool(IssueTrackerLinear, linearGraphQLURL, "", apiKey)
+}
+
+func newIssueLookupTool(tracker, baseURL, email, token string) *IssueLookupTool {
+	client := retryablehttp.NewClient()
+	client.RetryMax = 2
+	client.Logger = nil
+	return &IssueLookupTool{tracker: tracker, baseURL: baseURL, email: email, token: token, client: client}
+}
+
+func (t *IssueLookupTool) Name() string { return "issue_lookup" }
+
+func (t *IssueLookupTool) Description() string {
+	return fmt.Sprintf("Fetch a %s ticket by ID (e.g. PAY-123): title, status, description, and recent comments.", strings.ToUpper(t.tracker[:1])+t.tracker[1:])
+}
+
+func (t *IssueLookupTool) Schema() map[string]any {
+	return map[string]any{
+		"type": "object",
+		"properties": map[string]any{
+			"id": map[string]any{"type": "string", "description": "Ticket key, e.g. PAY-123"},
+		},
+		"required":             []string{"id"},
+		"additionalProperties": false,
+	}
+}
+
+type issueInput struct {
+	ID string `json:"id"`
+}
+
+type issueComment struct {
+	Author  string `json:"author,omitempty"`
+	Created string `json:"created,omitempty"`
+	Body    string `json:"body"`
+}
+
+type issueOutput struct {
+	ID          string         `json:"id"`
+	Tracker     string         `json:"tracker"`
+	Title       string         `json:"title"`
+	Status      string         `json:"status,omitempty"`
+	URL         string         `json:"url,omitempty"`
+	Description string         `json:"description,omitempty"`
+	Comments    []issueComment `json:"comments,omitempty"`
+	Truncated   bool           `json:"truncated"`
+	DurationMs  int64          `json:"duration_ms"`
+}
+
+func (t *IssueLookupTool) Execute(ctx context.Context, input json.RawMessage, meta Meta) (Result, error) {
+	var args issueInput
+	if err := json.Unmarshal(input, &args); err != nil {
+		return Result{}, err
+	}
+	id := strings.ToUpper(strings.TrimSpace(args.ID))
+	if !issueIDPattern.MatchString(id) {
+		return Result{}, fmt.Errorf("invalid ticket id %q (expected a key like PAY-123)", args.ID)
+	}
+
+	start := time.Now()
+	ctx, cancel := context.WithTimeout(ctx, time.Duration(meta.ToolTimeoutSeconds)*time.Second)
+	defer cancel()
+
+	var output issueOutput
+	var err error
+	switch t.tracker {
+	case IssueTrackerJira:
+		output, err = t.fetchJira(ctx, id)
+	case IssueTrackerLinear:
+		output, err = t.fetchLinear(ctx, id)
+	default:
+		err = fmt.Errorf("unknown issue tracker %q", t.tracker)
+	}
+	if err != nil {
+		return Result{}, err
+	}
+	output.Tracker = t.tracker
+	fitIssue(&output, meta.MaxBytes)
+	output.DurationMs = time.Since(start).Milliseconds()
+
+	text := renderIssue(output)
+	return Result{ToolName: t.Name(), Payload: output, Preview: meta.Preview(text), LineCount: strings.Count(text, "\n") + 1, ByteCount: len(text), Truncated: output.Truncated, DurationMs: output.DurationMs}, nil
+}
+
+func (t *IssueLookupTool) fetchJira(ctx context.Context, id string) (issueOutput, error) {
+	url := t.baseURL + "/rest/api/2/issue/" + id + "?fields=summary,status,description,comment"
+	request, err := retryablehttp.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return issueOutput{}, err
+	}
+	if t.email != "" {
+		request.SetBasicAuth(t.email, t.token)
+	} else {
+		request.Header.Set("Authorization", "Bearer "+t.token)
+	}
+	request.Header.Set("Accept", "application/json")
+
+	var raw struct {
+		Key    string `json:"key"`
+		Fields struct {
+			Summary     string `json:"summary"`
+			Description string `json:"description"`
+			Status      struct {
+				Name string `json:"name"`
+			} `json:"status"`
+			Comment struct {
+				Comments []struct {
+					Author struct {
+						DisplayName string `json:"displayName"`
+					} `json:"author"`
+					Body    string `json:"body"`
+					Created string `json:"created"`
+				} `json:"comments"`
+			} `json:"comment"`
+		} `json:"fields"`
+	}
+	if err := t.do(request, &raw); err != nil {
+		return issueOutput{}, err
+	}
+	output := issueOutput{ID: raw.Key, Title: raw.Fields.Summary, Status: raw.Fields.Status.Name, URL: t.baseURL + "/browse/" + raw.Key, Description: raw.Fields.Description}
+	for _, comment := range raw.Fields.Comment.Comments {
+		output.Comments = append(output.Comments, issueComment{Author: comment.Author.DisplayName, Created: comment.Created, Body: comment.Body})
+	}
+	return output, nil
+}
+
+func (t *IssueLookupTool) fetchLinear(ctx context.Context, id string) (issueOutput, error) {
+	body, _ := json.Marshal(map[string]any{"query": linearIssueQuery, "variables": map[string]string{"id": id}})
+	request, err := retryablehttp.NewRequestWithContext(ctx, http.MethodPost, t.baseURL, bytes.NewReader(body))
+	if err != nil {
+		return issueOutput{}, err
+	}
+	request.Header.Set("Authorization", t.token)
+	request.Header.Set("Content-Type", "application/json")
+
+	var raw struct {
+		Data struct {
+			Issue *struct {
+				Identifier  string `json:"identifier"`
+				Title       string `json:"title"`
+				Description string `json:"description"`
+				URL         string `json:"url"`
+				State       struct {
+					Name string `json:"name"`
+				} `json:"state"`
+				Comments struct {
+					Nodes []struct {
+						Body      string `json:"body"`
+						CreatedAt string `json:"createdAt"`
+						User      struct {
+							Name string `json:"name"`
+						} `json:"user"`
+					} `json:"nodes"`
+				} `json:"comments"`
+			} `json:"issue"`
+		} `json:"data"`
+		Errors []struct {
+			Message string `json:"message"`
+		} `json:"errors"`
+	}
+	if err := t.do(request, &raw); err != nil {
+		return issueOutput{}, err
+	}
+	if len(raw.Errors) > 0 {
+		return issueOutput{}, fmt.Errorf("linear lookup of %s failed: %s", id, raw.Errors[0].Message)
+	}
+	issue := raw.Data.Issue
+	if issue == nil {
+		return issueOutput{}, fmt.Errorf("linear issue %s not found", id)
+	}
+	output := issueOutput{ID: issue.Identifier, Title: issue.Title, Status: issue.State.Name, URL: issue.URL, Description: issue.Description}
+	for _, comment := range issue.Comments.Nodes {
+		output.Comments = append(output.Comments, issueComment{Author: comment.User.Name, Created: comment.CreatedAt, Body: comment.Body})
+	}
+	return output, nil
+}
+
+// do sends request and decodes a successful JSON response into out.
+func (t *IssueLookupTool) do(request *retryablehttp.Request, out any) error {
+	resp, err := t.client.Do(request)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	switch {
+	case resp.StatusCode == http.StatusNotFound:
+		return errors.New("ticket not found (or not visible with the configured token)")
+	case resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden:
+		return fmt.Errorf("%s rejected the token (%s)", t.tracker, resp.Status)
+	case resp.StatusCode < 200 || resp.StatusCode >= 300:
+		b, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("%s lookup failed: %s: %s", t.tracker, resp.Status, strings.TrimSpace(string(b)))
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+// fitIssue redacts the ticket text, keeps the most recent comments, and
+// shortens the description and comments until the result fits maxBytes.
+func fitIssue(output *issueOutput, maxBytes int) {
+	if len(output.Comments) > maxIssueComments {
+		output.Comments = output.Comments[len(output.Comments)-maxIssueComments:]
+		output.Truncated = true
+	}
+	output.Description = capIssueText(output, output.Description, maxIssueDescription)
+	for i := range output.Comments {
+		output.Comments[i].Body = capIssueText(output, output.Comments[i].Body, maxIssueComment)
+	}
+	if maxBytes <= 0 {
+		return
+	}
+	for {
+		data, _ := json.Marshal(output)
+		if len(data) <= maxBytes {
+			return
+		}
+		output.Truncated = true
+		switch {
+		case len(output.Comments) > 0:
+			output.Comments = output.Comments[1:]
+		case len(output.Description) > 256:
+			output.Description, _ = util.TruncateBytes(output.Description, len(output.Description)/2)
+		default:
+			return
+		}
+	}
+}
+
+func capIssueText(output *issueOutput, text string, limit int) string {
+	text, truncated := util.TruncateBytes(util.RedactSecrets(strings.TrimSpace(text)), limit)
+	output.Truncated = output.Truncated || truncated
+	return text
+}
+
+func renderIssue(output issueOutput) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s: %s", output.ID, output.Title)
+	if output.Status != "" {
+		fmt.Fprintf(&b, " [%s]", output.Status)
+	}
+	if output.Description != "" {
+		b.WriteString("\n" + output.Description)
+	}
+	for _, comment := range output.Comments {
+		fmt.Fprintf(&b, "\n- %s: %s", comment.Author, firstLine(comment.Body))
+	}
+	return b.String()
+}
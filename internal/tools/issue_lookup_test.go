@@ -0,0 +1,101 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestIssueLookupJira(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/rest/api/2/issue/PAY-123" {
+			http.NotFound(w, r)
+			return
+		}
+		if user, pass, ok := r.BasicAuth(); !ok || user != "dev@example.com" || pass != "secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		_, _ = w.Write([]byte(`{"key":"PAY-123","fields":{"summary":"Retry failed refunds","description":"Refunds that fail with 503 must be retried three times.","status":{"name":"In Progress"},"comment":{"comments":[{"author":{"displayName":"Ana"},"body":"Use exponential backoff.","created":"2026-09-01T10:00:00.000+0000"}]}}}`))
+	}))
+	defer server.Close()
+
+	tool := NewJiraIssueTool(server.URL+"/", "dev@example.com", "secret")
+	res, err := tool.Execute(context.Background(), json.RawMessage(`{"id":"pay-123"}`), Meta{ToolTimeoutSeconds: 5, MaxBytes: 4096})
+	if err != nil {
+		t.Fatalf("execute: %v", err)
+	}
+	output := res.Payload.(issueOutput)
+	if output.ID != "PAY-123" || output.Title != "Retry failed refunds" || output.Status != "In Progress" {
+		t.Fatalf("unexpected issue: %+v", output)
+	}
+	if output.URL != server.URL+"/browse/PAY-123" {
+		t.Fatalf("unexpected url %q", output.URL)
+	}
+	if len(output.Comments) != 1 || output.Comments[0].Author != "Ana" {
+		t.Fatalf("unexpected comments: %+v", output.Comments)
+	}
+	if !strings.Contains(res.Preview, "retried three times") {
+		t.Fatalf("preview missing description: %q", res.Preview)
+	}
+
+	if _, err := tool.Execute(context.Background(), json.RawMessage(`{"id":"PAY-9"}`), Meta{ToolTimeoutSeconds: 5}); err == nil || !strings.Contains(err.Error(), "not found") {
+		t.Fatalf("expected not found, got %v", err)
+	}
+	if _, err := tool.Execute(context.Background(), json.RawMessage(`{"id":"../etc"}`), Meta{ToolTimeoutSeconds: 5}); err == nil {
+		t.Fatalf("expected an invalid id to be rejected")
+	}
+}
+
+func TestIssueLookupLinear(t *testing.T) {
+	var query struct {
+		Variables map[string]string `json:"variables"`
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "lin_key" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		_ = json.NewDecoder(r.Body).Decode(&query)
+		if query.Variables["id"] != "ENG-42" {
+			_, _ = w.Write([]byte(`{"data":{"issue":null},"errors":[{"message":"Entity not found"}]}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{"data":{"issue":{"identifier":"ENG-42","title":"Add dark mode","description":"Follow the system theme.","url":"https://linear.app/acme/issue/ENG-42","state":{"name":"Todo"},"comments":{"nodes":[{"body":"Settings toggle too.","createdAt":"2026-09-02T08:00:00Z","user":{"name":"Li"}}]}}}}`))
+	}))
+	defer server.Close()
+
+	tool := NewLinearIssueTool("lin_key")
+	tool.baseURL = server.URL
+	res, err := tool.Execute(context.Background(), json.RawMessage(`{"id":"ENG-42"}`), Meta{ToolTimeoutSeconds: 5})
+	if err != nil {
+		t.Fatalf("execute: %v", err)
+	}
+	output := res.Payload.(issueOutput)
+	if output.Title != "Add dark mode" || output.Tracker != IssueTrackerLinear || len(output.Comments) != 1 {
+		t.Fatalf("unexpected issue: %+v", output)
+	}
+
+	if _, err := tool.Execute(context.Background(), json.RawMessage(`{"id":"ENG-1"}`), Meta{ToolTimeoutSeconds: 5}); err == nil || !strings.Contains(err.Error(), "Entity not found") {
+		t.Fatalf("expected the GraphQL error, got %v", err)
+	}
+}
+
+func TestFitIssueKeepsRecentComments(t *testing.T) {
+	output := issueOutput{ID: "PAY-1", Title: "t", Description: strings.Repeat("d", 100)}
+	for i := 0; i < 40; i++ {
+		output.Comments = append(output.Comments, issueComment{Body: strings.Repeat("c", 200)})
+	}
+	output.Comments[39].Body = "latest"
+	fitIssue(&output, 2000)
+	data, _ := json.Marshal(output)
+	if len(data) > 2000 || !output.Truncated {
+		t.Fatalf("expected a truncated result within budget, got %d bytes", len(data))
+	}
+	if output.Comments[len(output.Comments)-1].Body != "latest" {
+		t.Fatalf("expected the latest comment to be kept")
+	}
+}
@@ -0,0 +1,136 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// DefaultListEntries bounds list_files output when no limit is configured.
+const DefaultListEntries = 500
+
+type ListFilesTool struct{}
+
+// NewListFilesTool constructs a directory listing tool.
+func NewListFilesTool() *ListFilesTool {
+	return &ListFilesTool{}
+}
+
+func (l *ListFilesTool) Name() string { return "list_files" }
+
+func (l *ListFilesTool) Description() string {
+	return "List files and directories under a repository path (directories end with /). Use recursive for a tree and glob to filter file names."
+}
+
+func (l *ListFilesTool) Schema() map[string]any {
+	return map[string]any{
+		"type": "object",
+		"properties": map[string]any{
+			"path":        map[string]any{"type": "string"},
+			"glob":        map[string]any{"type": "string"},
+			"recursive":   map[string]any{"type": "boolean"},
+			"max_results": map[string]any{"type": "integer", "minimum": 1},
+		},
+		"required":             []string{},
+		"additionalProperties": false,
+	}
+}
+
+type listFilesInput struct {
+	Path       string `json:"path"`
+	Glob       string `json:"glob"`
+	Recursive  bool   `json:"recursive"`
+	MaxResults int    `json:"max_results"`
+}
+
+type listFilesOutput struct {
+	Entries    []string `json:"entries"`
+	Total      int      `json:"total"`
+	Truncated  bool     `json:"truncated"`
+	DurationMs int64    `json:"duration_ms"`
+}
+
+func (l *ListFilesTool) Execute(ctx context.Context, input json.RawMessage, meta Meta) (Result, error) {
+	var args listFilesInput
+	if len(input) > 0 {
+		if err := json.Unmarshal(input, &args); err != nil {
+			return Result{}, err
+		}
+	}
+	limit := args.MaxResults
+	if limit <= 0 || (meta.MaxResults > 0 && limit > meta.MaxResults) {
+		limit = meta.MaxResults
+	}
+	if limit <= 0 {
+		limit = DefaultListEntries
+	}
+
+	fsys := meta.FS()
+	root, err := fsys.Name(strings.TrimSpace(args.Path))
+	if err != nil {
+		return Result{}, errors.New("path must stay within repo root")
+	}
+
+	start := time.Now()
+	var entries []string
+	total := 0
+	err = fs.WalkDir(fsys, root, func(rel string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if rel == root {
+				return err
+			}
+			return nil
+		}
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if rel == root {
+			return nil
+		}
+		if d.IsDir() && skipListDir(d.Name()) {
+			return fs.SkipDir
+		}
+		// a link to a denied file is not listed under another name
+		if d.Type()&fs.ModeSymlink != 0 && !fsys.Allowed(rel) {
+			return nil
+		}
+		match := args.Glob == ""
+		if !match && !d.IsDir() {
+			match, _ = filepath.Match(args.Glob, d.Name())
+		}
+		if match {
+			total++
+			if len(entries) < limit {
+				if d.IsDir() {
+					rel += "/"
+				}
+				entries = append(entries, rel)
+			}
+		}
+		if d.IsDir() && !args.Recursive {
+			return fs.SkipDir
+		}
+		return nil
+	})
+	if err != nil {
+		return Result{}, fmt.Errorf("list %s: %w", args.Path, err)
+	}
+	sort.Strings(entries)
+
+	output := listFilesOutput{Entries: entries, Total: total, Truncated: total > len(entries), DurationMs: time.Since(start).Milliseconds()}
+	if output.Entries == nil {
+		output.Entries = []string{}
+	}
+	text := strings.Join(entries, "\n")
+	return Result{ToolName: l.Name(), Payload: output, Preview: meta.Preview(text), LineCount: len(entries), ByteCount: len(text), Truncated: output.Truncated, DurationMs: output.DurationMs}, nil
+}
+
+func skipListDir(name string) bool {
+	return name == ".git" || name == "node_modules"
+}
@@ -0,0 +1,47 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestListFiles(t *testing.T) {
+	root := t.TempDir()
+	for _, name := range []string{"a.go", "b.md", "pkg/c.go", ".git/HEAD"} {
+		path := filepath.Join(root, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatalf("mkdir: %v", err)
+		}
+		if err := os.WriteFile(path, []byte("x"), 0o644); err != nil {
+			t.Fatalf("write: %v", err)
+		}
+	}
+	tool := NewListFilesTool()
+
+	res, err := tool.Execute(context.Background(), nil, Meta{RepoRoot: root})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	out := res.Payload.(listFilesOutput)
+	if len(out.Entries) != 3 || out.Entries[2] != "pkg/" {
+		t.Fatalf("unexpected top-level entries: %v", out.Entries)
+	}
+
+	input, _ := json.Marshal(map[string]any{"recursive": true, "glob": "*.go", "max_results": 1})
+	res, err = tool.Execute(context.Background(), input, Meta{RepoRoot: root})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	out = res.Payload.(listFilesOutput)
+	if !out.Truncated || len(out.Entries) != 1 || out.Total != 2 {
+		t.Fatalf("expected truncated recursive listing, got %+v", out)
+	}
+
+	input, _ = json.Marshal(map[string]any{"path": "../"})
+	if _, err := tool.Execute(context.Background(), input, Meta{RepoRoot: root}); err == nil {
+		t.Fatalf("expected path outside the repo to be rejected")
+	}
+}
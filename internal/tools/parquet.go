@@ -0,0 +1,384 @@
+package tools
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// Parquet keeps its schema and row count in a Thrift-encoded footer, so
+// data_preview can describe a file without decoding any data pages.
+
+const parquetMagic = "PAR1"
+
+// maxParquetFooter bounds the footer read from a Parquet file.
+const maxParquetFooter = 16 << 20
+
+type parquetSchema struct {
+	Columns []dataColumn
+	NumRows int64
+}
+
+// schemaElement is the subset of parquet.thrift SchemaElement used here.
+type schemaElement struct {
+	name        string
+	physical    int32
+	hasPhysical bool
+	repetition  int32
+	numChildren int32
+	converted   int32
+	hasConv     bool
+	logical     int16
+}
+
+// readParquetSchema reads the footer of a Parquet file of the given size.
+func readParquetSchema(r io.ReaderAt, size int64) (parquetSchema, error) {
+	if size < 12 {
+		return parquetSchema{}, errors.New("not a parquet file: too short")
+	}
+	tail := make([]byte, 8)
+	if _, err := r.ReadAt(tail, size-8); err != nil {
+		return parquetSchema{}, err
+	}
+	if string(tail[4:]) != parquetMagic {
+		return parquetSchema{}, errors.New("not a parquet file: missing PAR1 footer")
+	}
+	length := int64(binary.LittleEndian.Uint32(tail[:4]))
+	if length <= 0 || length > maxParquetFooter || length > size-12 {
+		return parquetSchema{}, fmt.Errorf("parquet footer length %d is invalid", length)
+	}
+	footer := make([]byte, length)
+	if _, err := r.ReadAt(footer, size-8-length); err != nil {
+		return parquetSchema{}, err
+	}
+	elements, numRows, err := decodeFileMetaData(&compactReader{data: footer})
+	if err != nil {
+		return parquetSchema{}, fmt.Errorf("parse parquet footer: %w", err)
+	}
+	if len(elements) == 0 {
+		return parquetSchema{}, errors.New("parquet footer has no schema")
+	}
+	var columns []dataColumn
+	// elements[0] is the root message; the tree is flattened depth first
+	index := 1
+	var walk func(prefix string, count int32)
+	walk = func(prefix string, count int32) {
+		for i := int32(0); i < count && index < len(elements); i++ {
+			element := elements[index]
+			index++
+			name := element.name
+			if prefix != "" {
+				name = prefix + "." + name
+			}
+			if element.numChildren > 0 {
+				walk(name, element.numChildren)
+				continue
+			}
+			columns = append(columns, dataColumn{Name: name, Type: parquetType(element), Nullable: element.repetition == 1})
+		}
+	}
+	walk("", elements[0].numChildren)
+	return parquetSchema{Columns: columns, NumRows: numRows}, nil
+}
+
+// parquetType names a leaf column's type from its converted or logical type,
+// falling back to the physical type.
+func parquetType(e schemaElement) string {
+	name := ""
+	if e.hasConv {
+		switch e.converted {
+		case 0, 4, 19: // UTF8, ENUM, JSON
+			name = "string"
+		case 5:
+			name = "decimal"
+		case 6:
+			name = "date"
+		case 7, 8:
+			name = "time"
+		case 9, 10:
+			name = "timestamp"
+		case 11, 12, 13, 14, 15, 16, 17, 18:
+			name = "int"
+		}
+	}
+	if name == "" {
+		switch e.logical {
+		case 1, 4, 12: // STRING, ENUM, JSON
+			name = "string"
+		case 5:
+			name = "decimal"
+		case 6:
+			name = "date"
+		case 7:
+			name = "time"
+		case 8:
+			name = "timestamp"
+		case 14:
+			name = "uuid"
+		}
+	}
+	if name == "" {
+		switch e.physical {
+		case 0:
+			name = "bool"
+		case 1, 2:
+			name = "int"
+		case 3:
+			name = "timestamp (int96)"
+		case 4, 5:
+			name = "float"
+		default:
+			name = "binary"
+		}
+	}
+	if e.repetition == 2 {
+		name = "list<" + name + ">"
+	}
+	return name
+}
+
+// decodeFileMetaData reads FileMetaData fields 2 (schema) and 3 (num_rows).
+func decodeFileMetaData(r *compactReader) ([]schemaElement, int64, error) {
+	var elements []schemaElement
+	var numRows int64
+	err := r.readStruct(func(id int16, typ byte) error {
+		switch {
+		case id == 2 && typ == compactList:
+			elemType, size, err := r.listHeader()
+			if err != nil {
+				return err
+			}
+			for i := 0; i < size; i++ {
+				if elemType != compactStruct {
+					if err := r.skip(elemType); err != nil {
+						return err
+					}
+					continue
+				}
+				element, err := decodeSchemaElement(r)
+				if err != nil {
+					return err
+				}
+				elements = append(elements, element)
+			}
+			return nil
+		case id == 3 && typ == compactI64:
+			v, err := r.varint()
+			numRows = v
+			return err
+		}
+		return r.skip(typ)
+	})
+	return elements, numRows, err
+}
+
+func decodeSchemaElement(r *compactReader) (schemaElement, error) {
+	var e schemaElement
+	err := r.readStruct(func(id int16, typ byte) error {
+		switch {
+		case id == 1 && typ == compactI32:
+			v, err := r.varint()
+			e.physical, e.hasPhysical = int32(v), true
+			return err
+		case id == 3 && typ == compactI32:
+			v, err := r.varint()
+			e.repetition = int32(v)
+			return err
+		case id == 4 && typ == compactBinary:
+			v, err := r.binary()
+			e.name = string(v)
+			return err
+		case id == 5 && typ == compactI32:
+			v, err := r.varint()
+			e.numChildren = int32(v)
+			return err
+		case id == 6 && typ == compactI32:
+			v, err := r.varint()
+			e.converted, e.hasConv = int32(v), true
+			return err
+		case id == 10 && typ == compactStruct:
+			// LogicalType is a union: the set field's id names the type
+			return r.readStruct(func(id int16, typ byte) error {
+				e.logical = id
+				return r.skip(typ)
+			})
+		}
+		return r.skip(typ)
+	})
+	return e, err
+}
+
+// Thrift compact protocol type ids.
+const (
+	compactTrue   = 1
+	compactFalse  = 2
+	compactByte   = 3
+	compactI16    = 4
+	compactI32    = 5
+	compactI64    = 6
+	compactDouble = 7
+	compactBinary = 8
+	compactList   = 9
+	compactSet    = 10
+	compactMap    = 11
+	compactStruct = 12
+)
+
+// compactReader decodes the Thrift compact protocol over an in-memory buffer.
+type compactReader struct {
+	data  []byte
+	pos   int
+	depth int
+}
+
+var errCompactEOF = errors.New("unexpected end of thrift data")
+
+func (r *compactReader) byte() (byte, error) {
+	if r.pos >= len(r.data) {
+		return 0, errCompactEOF
+	}
+	b := r.data[r.pos]
+	r.pos++
+	return b, nil
+}
+
+func (r *compactReader) uvarint() (uint64, error) {
+	v, n := binary.Uvarint(r.data[r.pos:])
+	if n <= 0 {
+		return 0, errCompactEOF
+	}
+	r.pos += n
+	return v, nil
+}
+
+// varint reads a zigzag-encoded i16, i32, or i64.
+func (r *compactReader) varint() (int64, error) {
+	v, err := r.uvarint()
+	return int64(v>>1) ^ -int64(v&1), err
+}
+
+func (r *compactReader) binary() ([]byte, error) {
+	n, err := r.uvarint()
+	if err != nil {
+		return nil, err
+	}
+	if n > uint64(len(r.data)-r.pos) {
+		return nil, errCompactEOF
+	}
+	v := r.data[r.pos : r.pos+int(n)]
+	r.pos += int(n)
+	return v, nil
+}
+
+func (r *compactReader) listHeader() (byte, int, error) {
+	b, err := r.byte()
+	if err != nil {
+		return 0, 0, err
+	}
+	size := int(b >> 4)
+	if size == 15 {
+		n, err := r.uvarint()
+		if err != nil {
+			return 0, 0, err
+		}
+		if n > uint64(len(r.data)) {
+			return 0, 0, errCompactEOF
+		}
+		size = int(n)
+	}
+	return b & 0x0f, size, nil
+}
+
+// readStruct calls field for each field header until the stop byte. field
+// must consume the value.
+func (r *compactReader) readStruct(field func(id int16, typ byte) error) error {
+	r.depth++
+	defer func() { r.depth-- }()
+	if r.depth > 64 {
+		return errors.New("thrift data nested too deeply")
+	}
+	var last int16
+	for {
+		b, err := r.byte()
+		if err != nil {
+			return err
+		}
+		if b == 0 {
+			return nil
+		}
+		typ := b & 0x0f
+		id := last + int16(b>>4)
+		if b>>4 == 0 {
+			v, err := r.varint()
+			if err != nil {
+				return err
+			}
+			id = int16(v)
+		}
+		last = id
+		if err := field(id, typ); err != nil {
+			return err
+		}
+	}
+}
+
+func (r *compactReader) skip(typ byte) error {
+	switch typ {
+	case compactTrue, compactFalse:
+		return nil
+	case compactByte:
+		_, err := r.byte()
+		return err
+	case compactI16, compactI32, compactI64:
+		_, err := r.uvarint()
+		return err
+	case compactDouble:
+		if len(r.data)-r.pos < 8 {
+			return errCompactEOF
+		}
+		r.pos += 8
+		return nil
+	case compactBinary:
+		_, err := r.binary()
+		return err
+	case compactList, compactSet:
+		elemType, size, err := r.listHeader()
+		if err != nil {
+			return err
+		}
+		for i := 0; i < size; i++ {
+			// booleans in lists take a byte each
+			if elemType == compactTrue || elemType == compactFalse {
+				elemType = compactByte
+			}
+			if err := r.skip(elemType); err != nil {
+				return err
+			}
+		}
+		return nil
+	case compactMap:
+		size, err := r.uvarint()
+		if err != nil || size == 0 {
+			return err
+		}
+		kinds, err := r.byte()
+		if err != nil {
+			return err
+		}
+		for i := uint64(0); i < size; i++ {
+			for _, kind := range []byte{kinds >> 4, kinds & 0x0f} {
+				if kind == compactTrue || kind == compactFalse {
+					kind = compactByte
+				}
+				if err := r.skip(kind); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	case compactStruct:
+		return r.readStruct(func(_ int16, typ byte) error { return r.skip(typ) })
+	}
+	return fmt.Errorf("unknown thrift type %d", typ)
+}
@@ -0,0 +1,77 @@
+package tools
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// errOutsideRepo is returned for paths that leave the repository, lexically
+// or through a symlink.
+var errOutsideRepo = errors.New("path must stay within repo root")
+
+// resolveInRepo resolves p, absolute or relative to repoRoot, and checks that
+// it stays inside the repository after symlinks are evaluated. It returns the
+// lexical absolute path and its repo-relative form, so callers keep reporting
+// paths the way the model wrote them. A path that does not exist yet is
+// checked through its deepest existing parent.
+func resolveInRepo(repoRoot, p string) (string, string, error) {
+	root, err := filepath.Abs(repoRoot)
+	if err != nil {
+		return "", "", err
+	}
+	abs := p
+	if !filepath.IsAbs(abs) {
+		abs = filepath.Join(root, abs)
+	}
+	abs = filepath.Clean(abs)
+	rel, ok := within(root, abs)
+	if !ok {
+		return "", "", errOutsideRepo
+	}
+	realRoot, err := filepath.EvalSymlinks(root)
+	if err != nil {
+		realRoot = root
+	}
+	real, err := evalExisting(abs)
+	if err != nil {
+		return "", "", err
+	}
+	if _, ok := within(realRoot, real); !ok {
+		return "", "", fmt.Errorf("%w (a symlink points outside it)", errOutsideRepo)
+	}
+	return abs, rel, nil
+}
+
+// evalExisting evaluates symlinks in the longest existing prefix of abs and
+// appends the rest unchanged.
+func evalExisting(abs string) (string, error) {
+	rest := ""
+	current := abs
+	for {
+		real, err := filepath.EvalSymlinks(current)
+		if err == nil {
+			return filepath.Join(real, rest), nil
+		}
+		if !errors.Is(err, os.ErrNotExist) {
+			return "", err
+		}
+		parent := filepath.Dir(current)
+		if parent == current {
+			return abs, nil
+		}
+		rest = filepath.Join(filepath.Base(current), rest)
+		current = parent
+	}
+}
+
+// within reports whether target is root or below it, returning the relative path.
+func within(root, target string) (string, bool) {
+	rel, err := filepath.Rel(root, target)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", false
+	}
+	return rel, true
+}
@@ -0,0 +1,84 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// symlinkRepo returns a repo with "inside" (a real dir), "escape" (a link to
+// a directory outside the repo), and "inner" (a link to "inside").
+func symlinkRepo(t *testing.T) string {
+	t.Helper()
+	repoRoot := t.TempDir()
+	outside := t.TempDir()
+	if err := os.WriteFile(filepath.Join(outside, "secret.txt"), []byte("FICLI secret\n"), 0o644); err != nil {
+		t.Fatalf("failed to write file: %v", err)
+	}
+	if err := os.Mkdir(filepath.Join(repoRoot, "inside"), 0o755); err != nil {
+		t.Fatalf("failed to create dir: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(repoRoot, "inside", "ok.txt"), []byte("FICLI ok\n"), 0o644); err != nil {
+		t.Fatalf("failed to write file: %v", err)
+	}
+	if err := os.Symlink(outside, filepath.Join(repoRoot, "escape")); err != nil {
+		t.Skipf("symlinks unavailable: %v", err)
+	}
+	if err := os.Symlink(filepath.Join(outside, "secret.txt"), filepath.Join(repoRoot, "leak.txt")); err != nil {
+		t.Fatalf("failed to create symlink: %v", err)
+	}
+	if err := os.Symlink(filepath.Join(repoRoot, "inside"), filepath.Join(repoRoot, "inner")); err != nil {
+		t.Fatalf("failed to create symlink: %v", err)
+	}
+	return repoRoot
+}
+
+func TestResolveInRepo(t *testing.T) {
+	repoRoot := symlinkRepo(t)
+	cases := []struct {
+		path string
+		ok   bool
+	}{
+		{"inside", true},
+		{"inner", true},
+		{"inside/new/file.go", true},
+		{"..foo", true},
+		{"../x", false},
+		{"escape", false},
+		{"escape/secret.txt", false},
+		{"escape/missing/dir", false},
+		{"leak.txt", false},
+		{filepath.Join(repoRoot, "inside"), true},
+		{filepath.Dir(repoRoot), false},
+	}
+	for _, tc := range cases {
+		_, _, err := resolveInRepo(repoRoot, tc.path)
+		if (err == nil) != tc.ok {
+			t.Fatalf("resolveInRepo(%q): err=%v, want ok=%v", tc.path, err, tc.ok)
+		}
+	}
+	if _, err := resolveCwd(repoRoot, "escape"); err == nil {
+		t.Fatalf("expected resolveCwd to reject a symlink out of the repo")
+	}
+}
+
+func TestGrepFallbackSkipsSymlinkEscapes(t *testing.T) {
+	repoRoot := symlinkRepo(t)
+	tool := NewGrepTool()
+	tool.rgPath = ""
+	for _, paths := range [][]string{nil, {"escape"}, {"leak.txt"}} {
+		input, _ := json.Marshal(map[string]any{"pattern": "FICLI", "paths": paths})
+		res, err := tool.Execute(context.Background(), input, Meta{RepoRoot: repoRoot, ToolTimeoutSeconds: 2, MaxResults: 10, MaxBytes: 4096})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		for _, match := range res.Payload.(grepOutput).Matches {
+			if strings.Contains(match, "secret") {
+				t.Fatalf("paths %v: read through a symlink out of the repo: %q", paths, match)
+			}
+		}
+	}
+}
@@ -0,0 +1,177 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"fi-cli/internal/repo"
+	"fi-cli/internal/util"
+)
+
+// DefaultReadFileBytes bounds read_file output when no limit is configured.
+const DefaultReadFileBytes = 32 * 1024
+
+type ReadFileTool struct{}
+
+// NewReadFileTool constructs a tool that reads a line or byte range of a
+// repository file.
+func NewReadFileTool() *ReadFileTool {
+	return &ReadFileTool{}
+}
+
+func (r *ReadFileTool) Name() string { return "read_file" }
+
+func (r *ReadFileTool) Description() string {
+	return "Read a repository file, or an exact range of it: start_line/end_line (1-based, inclusive) or offset/length in bytes. Output is capped; a truncated read says where to continue. Notebooks are read as their rendered cells."
+}
+
+func (r *ReadFileTool) Schema() map[string]any {
+	return map[string]any{
+		"type": "object",
+		"properties": map[string]any{
+			"path":       map[string]any{"type": "string"},
+			"start_line": map[string]any{"type": "integer", "minimum": 1},
+			"end_line":   map[string]any{"type": "integer", "minimum": 1},
+			"offset":     map[string]any{"type": "integer", "minimum": 0},
+			"length":     map[string]any{"type": "integer", "minimum": 1},
+		},
+		"required":             []string{"path"},
+		"additionalProperties": false,
+	}
+}
+
+type readFileInput struct {
+	Path      string `json:"path"`
+	StartLine int    `json:"start_line"`
+	EndLine   int    `json:"end_line"`
+	Offset    *int   `json:"offset"`
+	Length    int    `json:"length"`
+}
+
+type readFileOutput struct {
+	Path string `json:"path"`
+	// StartLine and EndLine are the lines Content spans, also for byte
+	// ranges, so answers can cite them.
+	StartLine  int    `json:"start_line"`
+	EndLine    int    `json:"end_line"`
+	TotalLines int    `json:"total_lines"`
+	Offset     int    `json:"offset"`
+	Content    string `json:"content"`
+	Truncated  bool   `json:"truncated"`
+	Note       string `json:"note,omitempty"`
+	DurationMs int64  `json:"duration_ms"`
+}
+
+func (r *ReadFileTool) Execute(ctx context.Context, input json.RawMessage, meta Meta) (Result, error) {
+	var args readFileInput
+	if err := json.Unmarshal(input, &args); err != nil {
+		return Result{}, err
+	}
+	if strings.TrimSpace(args.Path) == "" {
+		return Result{}, errors.New("path is required")
+	}
+	byteRange := args.Offset != nil || args.Length > 0
+	if byteRange && (args.StartLine > 0 || args.EndLine > 0) {
+		return Result{}, errors.New("use either start_line/end_line or offset/length, not both")
+	}
+	if args.StartLine < 0 || args.EndLine < 0 || args.Length < 0 || (args.Offset != nil && *args.Offset < 0) {
+		return Result{}, errors.New("ranges must not be negative")
+	}
+	if args.EndLine > 0 && args.EndLine < max(args.StartLine, 1) {
+		return Result{}, fmt.Errorf("end_line %d is before start_line %d", args.EndLine, args.StartLine)
+	}
+	maxBytes := meta.MaxBytes
+	if maxBytes <= 0 {
+		maxBytes = DefaultReadFileBytes
+	}
+
+	start := time.Now()
+	fsys := meta.FS()
+	name, err := fsys.Name(args.Path)
+	if err != nil {
+		return Result{}, err
+	}
+	data, err := fsys.ReadFile(name)
+	if err != nil {
+		return Result{}, err
+	}
+	text := string(data)
+	if repo.IsNotebook(name) {
+		if text, err = repo.RenderNotebook(data); err != nil {
+			return Result{}, fmt.Errorf("%s: %w", args.Path, err)
+		}
+	} else if isBinaryOutput(data) {
+		return Result{}, fmt.Errorf("%s is binary", args.Path)
+	}
+
+	output := readFileOutput{Path: name, TotalLines: countLines(text)}
+	var from, to int
+	if byteRange {
+		if args.Offset != nil {
+			from = min(*args.Offset, len(text))
+		}
+		to = len(text)
+		if args.Length > 0 {
+			to = min(from+args.Length, len(text))
+		}
+	} else {
+		from = lineOffset(text, max(args.StartLine, 1))
+		to = len(text)
+		if args.EndLine > 0 {
+			to = lineOffset(text, args.EndLine+1)
+		}
+	}
+	content := text[from:to]
+	if len(content) > maxBytes {
+		content = util.TrimPartialRune(content[:maxBytes])
+		// a line range stops at the last whole line that fits
+		if cut := strings.LastIndexByte(content, '\n'); !byteRange && cut >= 0 {
+			content = content[:cut+1]
+		}
+		output.Truncated = true
+		if byteRange {
+			output.Note = fmt.Sprintf("output is capped at %d bytes; continue with offset=%d", maxBytes, from+len(content))
+		}
+	}
+	output.Offset = from
+	output.StartLine = strings.Count(text[:from], "\n") + 1
+	output.EndLine = output.StartLine + max(countLines(content)-1, 0)
+	if output.Truncated && !byteRange {
+		output.Note = fmt.Sprintf("output is capped at %d bytes; continue with start_line=%d", maxBytes, output.EndLine+1)
+	}
+	if content == "" && text != "" {
+		output.Note = fmt.Sprintf("the range is past the end of the file (%d lines, %d bytes)", output.TotalLines, len(text))
+	}
+	output.Content = util.RedactSecrets(content)
+	output.DurationMs = time.Since(start).Milliseconds()
+
+	return Result{ToolName: r.Name(), Payload: output, Preview: meta.Preview(output.Content), LineCount: countLines(output.Content), ByteCount: len(output.Content), Truncated: output.Truncated, DurationMs: output.DurationMs}, nil
+}
+
+// lineOffset returns the byte offset where 1-based line starts, or len(text)
+// past the last line.
+func lineOffset(text string, line int) int {
+	offset := 0
+	for i := 1; i < line; i++ {
+		next := strings.IndexByte(text[offset:], '\n')
+		if next < 0 {
+			return len(text)
+		}
+		offset += next + 1
+	}
+	return offset
+}
+
+// countLines counts lines the way editors number them: a final line without
+// a newline still counts.
+func countLines(text string) int {
+	lines := strings.Count(text, "\n")
+	if text != "" && !strings.HasSuffix(text, "\n") {
+		lines++
+	}
+	return lines
+}
@@ -0,0 +1,75 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestReadFileRanges(t *testing.T) {
+	repoRoot := t.TempDir()
+	files := map[string]string{
+		"main.go":    "package main\n\nimport \"fmt\"\n\nfunc main() {\n\tfmt.Println(\"hi\")\n}\n",
+		".env":       "API_KEY=secret\n",
+		"blob.bin":   "\x00\x01\x02",
+		"nb.ipynb":   `{"cells":[{"cell_type":"code","source":["print(1)\n"]}],"metadata":{},"nbformat":4}`,
+		"config.txt": "token: sk-abcdefghijklmnopqrstuvwxyz123456\n",
+	}
+	for name, data := range files {
+		if err := os.WriteFile(filepath.Join(repoRoot, name), []byte(data), 0o644); err != nil {
+			t.Fatalf("failed to write %s: %v", name, err)
+		}
+	}
+	tool := NewReadFileTool()
+	read := func(args map[string]any, maxBytes int) (readFileOutput, error) {
+		input, _ := json.Marshal(args)
+		res, err := tool.Execute(context.Background(), input, Meta{RepoRoot: repoRoot, MaxBytes: maxBytes})
+		if err != nil {
+			return readFileOutput{}, err
+		}
+		return res.Payload.(readFileOutput), nil
+	}
+
+	out, err := read(map[string]any{"path": "main.go", "start_line": 5, "end_line": 6}, 0)
+	if err != nil || out.Content != "func main() {\n\tfmt.Println(\"hi\")\n" || out.StartLine != 5 || out.EndLine != 6 || out.TotalLines != 7 {
+		t.Fatalf("unexpected line range: %+v (%v)", out, err)
+	}
+	out, err = read(map[string]any{"path": "main.go", "offset": 14, "length": 12}, 0)
+	if err != nil || out.Content != "import \"fmt\"" || out.StartLine != 3 || out.EndLine != 3 {
+		t.Fatalf("unexpected byte range: %+v (%v)", out, err)
+	}
+	out, err = read(map[string]any{"path": "main.go"}, 20)
+	if err != nil || !out.Truncated || out.Content != "package main\n\n" || !strings.Contains(out.Note, "start_line=3") {
+		t.Fatalf("expected a read capped at whole lines, got %+v (%v)", out, err)
+	}
+	out, err = read(map[string]any{"path": "main.go", "start_line": 40}, 0)
+	if err != nil || out.Content != "" || !strings.Contains(out.Note, "past the end") {
+		t.Fatalf("expected a note for a range past the end, got %+v (%v)", out, err)
+	}
+	out, err = read(map[string]any{"path": "nb.ipynb"}, 0)
+	if err != nil || !strings.Contains(out.Content, "print(1)") || strings.Contains(out.Content, "cell_type") {
+		t.Fatalf("expected the rendered notebook, got %+v (%v)", out, err)
+	}
+	out, err = read(map[string]any{"path": "config.txt"}, 0)
+	if err != nil || strings.Contains(out.Content, "abcdefghijklmnop") {
+		t.Fatalf("expected secrets redacted, got %+v (%v)", out, err)
+	}
+
+	if _, err := read(map[string]any{"path": ".env"}, 0); !errors.Is(err, ErrFileDenied) {
+		t.Fatalf("expected .env to be denied, got %v", err)
+	}
+	for _, args := range []map[string]any{
+		{"path": "blob.bin"},
+		{"path": "../outside.go"},
+		{"path": "main.go", "start_line": 3, "offset": 0},
+		{"path": "main.go", "start_line": 4, "end_line": 2},
+	} {
+		if _, err := read(args, 0); err == nil {
+			t.Fatalf("expected %v to be rejected", args)
+		}
+	}
+}
@@ -1,6 +1,7 @@
 package tools
 
 import (
+	"slices"
 	"sort"
 
 	"github.com/openai/openai-go/v3"
@@ -28,6 +29,32 @@ func (r *Registry) Get(name string) (Tool, bool) {
 	return tool, ok
 }
 
+// Without returns a registry holding every tool except the named ones.
+func (r *Registry) Without(names ...string) *Registry {
+	reg := &Registry{tools: map[string]Tool{}}
+	for name, tool := range r.tools {
+		if !slices.Contains(names, name) {
+			reg.tools[name] = tool
+		}
+	}
+	return reg
+}
+
+// With returns a registry holding these tools plus items.
+func (r *Registry) With(items ...Tool) *Registry {
+	reg := r.Without()
+	for _, item := range items {
+		reg.tools[item.Name()] = item
+	}
+	return reg
+}
+
+// Has reports whether the registry holds a tool named name.
+func (r *Registry) Has(name string) bool {
+	_, ok := r.tools[name]
+	return ok
+}
+
 // Names returns sorted tool names.
 func (r *Registry) Names() []string {
 	names := make([]string, 0, len(r.tools))
@@ -0,0 +1,248 @@
+package tools
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"fi-cli/internal/policy"
+	"fi-cli/internal/repo"
+)
+
+// MaxFileBytes caps how large a file tools may open through RepoFS.
+const MaxFileBytes = 8 << 20
+
+var (
+	// ErrFileDenied is returned for denylisted files and files a
+	// deny_extensions rule in .fi.yaml covers.
+	ErrFileDenied = errors.New("file is denied by policy")
+	// ErrFileTooLarge is returned for files over MaxFileBytes.
+	ErrFileTooLarge = fmt.Errorf("file is larger than %d bytes", MaxFileBytes)
+	// ErrReadOnly is returned for writes through a read-only RepoFS.
+	ErrReadOnly = errors.New("repository is read-only for tools")
+)
+
+// RepoFS is the repository as tools see it: an fs.FS rooted at the repo
+// root that enforces the secret denylist, .fi.yaml deny_extensions, symlink
+// containment, and MaxFileBytes in one place. Names follow io/fs rules
+// (slash-separated, relative to the root); use Name to convert a path the
+// model supplied. Tools that shell out (rg, shell) cannot go through it and
+// check their paths with resolveInRepo instead.
+type RepoFS struct {
+	root string
+	// realRoot is root with symlinks evaluated, for naming link targets.
+	realRoot string
+	policy   policy.PathPolicy
+	writable bool
+}
+
+var (
+	_ fs.ReadDirFS  = (*RepoFS)(nil)
+	_ fs.ReadFileFS = (*RepoFS)(nil)
+	_ fs.StatFS     = (*RepoFS)(nil)
+)
+
+// NewRepoFS returns a read-only view of repoRoot.
+func NewRepoFS(repoRoot string, pathPolicy policy.PathPolicy) *RepoFS {
+	root, err := filepath.Abs(repoRoot)
+	if err != nil {
+		root = repoRoot
+	}
+	realRoot, err := filepath.EvalSymlinks(root)
+	if err != nil {
+		realRoot = root
+	}
+	return &RepoFS{root: root, realRoot: realRoot, policy: pathPolicy}
+}
+
+// FS returns the repository view for this tool call.
+func (m Meta) FS() *RepoFS {
+	return NewRepoFS(m.RepoRoot, m.Policy)
+}
+
+// WithWrites returns a copy of r that accepts WriteFile. Reads keep the same
+// checks, and writes are held to them too.
+func (r *RepoFS) WithWrites() *RepoFS {
+	copied := *r
+	copied.writable = true
+	return &copied
+}
+
+// Root returns the absolute repository root.
+func (r *RepoFS) Root() string {
+	return r.root
+}
+
+// Name converts a path the model supplied, absolute or relative to the repo
+// root, into an fs name. It fails for paths that leave the repository,
+// including through symlinks.
+func (r *RepoFS) Name(p string) (string, error) {
+	if p == "" {
+		return ".", nil
+	}
+	_, rel, err := resolveInRepo(r.root, p)
+	if err != nil {
+		return "", err
+	}
+	return filepath.ToSlash(rel), nil
+}
+
+// Allowed reports whether the file name may be read under the denylist and
+// the repo's path policy. Walkers use it to skip files without opening them.
+// A symlink must pass under its target's name too, so notes.txt -> .env is
+// denied like .env.
+func (r *RepoFS) Allowed(name string) bool {
+	if !r.allowedName(name) {
+		return false
+	}
+	target, linked := r.target(name)
+	return !linked || r.allowedName(target)
+}
+
+func (r *RepoFS) allowedName(name string) bool {
+	return !repo.IsDenylisted(name) && !r.policy.DeniesFile(name)
+}
+
+// target returns the repo-relative name that name resolves to through
+// symlinks, when it differs from name. Targets outside the repository are
+// not named here; resolve refuses them.
+func (r *RepoFS) target(name string) (string, bool) {
+	real, err := evalExisting(filepath.Join(r.root, filepath.FromSlash(name)))
+	if err != nil {
+		return "", false
+	}
+	rel, ok := within(r.realRoot, real)
+	if !ok {
+		return "", false
+	}
+	rel = filepath.ToSlash(rel)
+	return rel, rel != name
+}
+
+// Open opens name for reading. Directories are returned as-is so fs.WalkDir
+// and fs.ReadDir work; regular files must pass Allowed and MaxFileBytes.
+func (r *RepoFS) Open(name string) (fs.File, error) {
+	full, err := r.resolve("open", name)
+	if err != nil {
+		return nil, err
+	}
+	info, err := os.Stat(full)
+	if err != nil {
+		return nil, err
+	}
+	if !info.IsDir() {
+		if err := r.checkFile("open", name, info); err != nil {
+			return nil, err
+		}
+	}
+	return os.Open(full)
+}
+
+// OpenLarge opens a regular file like Open but without the MaxFileBytes cap,
+// for tools that read a bounded part of it (the first rows, a footer). The
+// denylist, path policy, and symlink checks still apply.
+func (r *RepoFS) OpenLarge(name string) (*os.File, error) {
+	full, err := r.resolve("open", name)
+	if err != nil {
+		return nil, err
+	}
+	info, err := os.Stat(full)
+	if err != nil {
+		return nil, err
+	}
+	if info.IsDir() {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: errors.New("is a directory")}
+	}
+	if !r.Allowed(name) {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: ErrFileDenied}
+	}
+	return os.Open(full)
+}
+
+// Stat returns file info for name, following symlinks that stay in the repo.
+func (r *RepoFS) Stat(name string) (fs.FileInfo, error) {
+	full, err := r.resolve("stat", name)
+	if err != nil {
+		return nil, err
+	}
+	return os.Stat(full)
+}
+
+// ReadDir lists the directory name, sorted by file name.
+func (r *RepoFS) ReadDir(name string) ([]fs.DirEntry, error) {
+	full, err := r.resolve("readdir", name)
+	if err != nil {
+		return nil, err
+	}
+	return os.ReadDir(full)
+}
+
+// ReadFile reads the whole file name.
+func (r *RepoFS) ReadFile(name string) ([]byte, error) {
+	file, err := r.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	info, err := file.Stat()
+	if err != nil {
+		return nil, err
+	}
+	if info.IsDir() {
+		return nil, &fs.PathError{Op: "read", Path: name, Err: errors.New("is a directory")}
+	}
+	data, err := io.ReadAll(io.LimitReader(file, MaxFileBytes+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(data) > MaxFileBytes {
+		// the file grew after Open checked its size
+		return nil, &fs.PathError{Op: "read", Path: name, Err: ErrFileTooLarge}
+	}
+	return data, nil
+}
+
+// WriteFile writes data to name when r was made writable with WithWrites.
+func (r *RepoFS) WriteFile(name string, data []byte, perm fs.FileMode) error {
+	if !r.writable {
+		return &fs.PathError{Op: "write", Path: name, Err: ErrReadOnly}
+	}
+	full, err := r.resolve("write", name)
+	if err != nil {
+		return err
+	}
+	if !r.Allowed(name) {
+		return &fs.PathError{Op: "write", Path: name, Err: ErrFileDenied}
+	}
+	if len(data) > MaxFileBytes {
+		return &fs.PathError{Op: "write", Path: name, Err: ErrFileTooLarge}
+	}
+	return os.WriteFile(full, data, perm)
+}
+
+// resolve validates name and returns its absolute path. It only checks
+// containment; callers that read or write also check Allowed, which covers
+// the name a symlink resolves to.
+func (r *RepoFS) resolve(op, name string) (string, error) {
+	if !fs.ValidPath(name) {
+		return "", &fs.PathError{Op: op, Path: name, Err: fs.ErrInvalid}
+	}
+	full, _, err := resolveInRepo(r.root, filepath.FromSlash(name))
+	if err != nil {
+		return "", &fs.PathError{Op: op, Path: name, Err: err}
+	}
+	return full, nil
+}
+
+func (r *RepoFS) checkFile(op, name string, info fs.FileInfo) error {
+	if !r.Allowed(name) {
+		return &fs.PathError{Op: op, Path: name, Err: ErrFileDenied}
+	}
+	if info.Size() > MaxFileBytes {
+		return &fs.PathError{Op: op, Path: name, Err: ErrFileTooLarge}
+	}
+	return nil
+}
@@ -0,0 +1,105 @@
+package tools
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"fi-cli/internal/policy"
+)
+
+func TestRepoFSEnforcesPolicy(t *testing.T) {
+	repoRoot := symlinkRepo(t)
+	if err := os.WriteFile(filepath.Join(repoRoot, ".env"), []byte("TOKEN=1\n"), 0o644); err != nil {
+		t.Fatalf("failed to write file: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(repoRoot, "inside", "state.tfstate"), []byte("{}\n"), 0o644); err != nil {
+		t.Fatalf("failed to write file: %v", err)
+	}
+	pathPolicy := policy.PathPolicy{Rules: []policy.PathRule{{Path: "", DenyExtensions: []string{".tfstate"}}}}
+	fsys := NewRepoFS(repoRoot, pathPolicy)
+
+	if entries, err := fs.ReadDir(fsys, "inner"); err != nil || len(entries) != 2 {
+		t.Fatalf("read dir through in-repo link: %v, %v", entries, err)
+	}
+	if data, err := fsys.ReadFile("inner/ok.txt"); err != nil || string(data) != "FICLI ok\n" {
+		t.Fatalf("read through in-repo link: %q, %v", data, err)
+	}
+	for name, want := range map[string]error{
+		".env":                 ErrFileDenied,
+		"inside/state.tfstate": ErrFileDenied,
+		"leak.txt":             errOutsideRepo,
+		"escape/secret.txt":    nil,
+		"../outside":           fs.ErrInvalid,
+	} {
+		_, err := fsys.ReadFile(name)
+		if err == nil {
+			t.Fatalf("ReadFile(%q) succeeded", name)
+		}
+		if want != nil && !errors.Is(err, want) {
+			t.Fatalf("ReadFile(%q) = %v, want %v", name, err, want)
+		}
+	}
+	if err := fsys.WriteFile("inside/new.txt", []byte("x"), 0o644); !errors.Is(err, ErrReadOnly) {
+		t.Fatalf("expected read-only error, got %v", err)
+	}
+	if err := fsys.WithWrites().WriteFile("inside/new.txt", []byte("x"), 0o644); err != nil {
+		t.Fatalf("writable write failed: %v", err)
+	}
+	if err := fsys.WithWrites().WriteFile("escape/new.txt", []byte("x"), 0o644); err == nil {
+		t.Fatalf("expected write through escaping link to fail")
+	}
+}
+
+func TestRepoFSDeniesLinksToDeniedFiles(t *testing.T) {
+	repoRoot := t.TempDir()
+	if err := os.WriteFile(filepath.Join(repoRoot, ".env"), []byte("API_KEY=FICLI-secret\n"), 0o644); err != nil {
+		t.Fatalf("failed to write file: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(repoRoot, "state.tfstate"), []byte("FICLI state\n"), 0o644); err != nil {
+		t.Fatalf("failed to write file: %v", err)
+	}
+	if err := os.Symlink(".env", filepath.Join(repoRoot, "notes.txt")); err != nil {
+		t.Skipf("symlinks unavailable: %v", err)
+	}
+	if err := os.Symlink("state.tfstate", filepath.Join(repoRoot, "state.json")); err != nil {
+		t.Fatalf("failed to create symlink: %v", err)
+	}
+	pathPolicy := policy.PathPolicy{Rules: []policy.PathRule{{Path: "", DenyExtensions: []string{".tfstate"}}}}
+	fsys := NewRepoFS(repoRoot, pathPolicy)
+	for _, name := range []string{"notes.txt", "state.json"} {
+		if fsys.Allowed(name) {
+			t.Fatalf("expected %s to be denied through its target", name)
+		}
+		if _, err := fsys.ReadFile(name); !errors.Is(err, ErrFileDenied) {
+			t.Fatalf("ReadFile(%q) = %v, want %v", name, err, ErrFileDenied)
+		}
+	}
+
+	meta := Meta{RepoRoot: repoRoot, Policy: pathPolicy, ToolTimeoutSeconds: 5}
+	if _, err := NewReadFileTool().Execute(context.Background(), []byte(`{"path":"notes.txt"}`), meta); !errors.Is(err, ErrFileDenied) {
+		t.Fatalf("expected read_file to refuse the link, got %v", err)
+	}
+	fallback := &GrepTool{}
+	for _, input := range []string{`{"pattern":"FICLI"}`, `{"pattern":"FICLI","paths":["notes.txt"]}`} {
+		res, err := fallback.Execute(context.Background(), []byte(input), meta)
+		if err != nil {
+			t.Fatalf("grep failed: %v", err)
+		}
+		if matches := res.Payload.(grepOutput).Matches; len(matches) != 0 {
+			t.Fatalf("expected no matches through links, got %v", matches)
+		}
+	}
+	res, err := NewListFilesTool().Execute(context.Background(), []byte(`{}`), meta)
+	if err != nil {
+		t.Fatalf("list_files failed: %v", err)
+	}
+	if strings.Contains(fmt.Sprint(res.Payload), "notes.txt") {
+		t.Fatalf("expected the link to .env to be left out, got %+v", res.Payload)
+	}
+}
@@ -28,7 +28,11 @@ func NewShellTool(allowlist []string) *ShellTool {
 func (s *ShellTool) Name() string { return "shell" }
 
 func (s *ShellTool) Description() string {
-	return "Run a local shell command from the configured allowlist with timeouts."
+	desc := "Run a local shell command from the configured allowlist with timeouts."
+	if missing := DetectCapabilities().Missing(); len(missing) > 0 {
+		desc += " Not installed on this machine: " + strings.Join(missing, ", ") + "."
+	}
+	return desc
 }
 
 func (s *ShellTool) Schema() map[string]any {
@@ -49,11 +53,13 @@ type shellInput struct {
 }
 
 type shellOutput struct {
-	Stdout     string `json:"stdout"`
-	Stderr     string `json:"stderr"`
-	ExitCode   int    `json:"exit_code"`
-	DurationMs int64  `json:"duration_ms"`
-	Truncated  bool   `json:"truncated"`
+	Stdout       string        `json:"stdout"`
+	Stderr       string        `json:"stderr"`
+	ExitCode     int           `json:"exit_code"`
+	DurationMs   int64         `json:"duration_ms"`
+	Truncated    bool          `json:"truncated"`
+	StdoutBinary *binaryOutput `json:"stdout_binary,omitempty"`
+	StderrBinary *binaryOutput `json:"stderr_binary,omitempty"`
 }
 
 func (s *ShellTool) Execute(ctx context.Context, input json.RawMessage, meta Meta) (Result, error) {
@@ -80,6 +86,11 @@ func (s *ShellTool) Execute(ctx context.Context, input json.RawMessage, meta Met
 		}
 		cwd = resolved
 	}
+	if rel, err := filepath.Rel(meta.RepoRoot, cwd); err == nil {
+		if ok, reason := meta.Policy.ShellAllowed(rel); !ok {
+			return Result{}, errors.New(reason)
+		}
+	}
 
 	ctx, cancel := context.WithTimeout(ctx, time.Duration(meta.ToolTimeoutSeconds)*time.Second)
 	defer cancel()
@@ -105,8 +116,8 @@ func (s *ShellTool) Execute(ctx context.Context, input json.RawMessage, meta Met
 		}
 	}
 
-	outStr := util.RedactSecrets(stdout.String())
-	errStr := util.RedactSecrets(stderr.String())
+	outStr, outBinary := shellText(stdout.Bytes())
+	errStr, errBinary := shellText(stderr.Bytes())
 	truncated := false
 	if meta.MaxBytes > 0 {
 		if trimmed, did := util.TruncateBytes(outStr, meta.MaxBytes); did {
@@ -120,13 +131,15 @@ func (s *ShellTool) Execute(ctx context.Context, input json.RawMessage, meta Met
 	}
 
 	output := shellOutput{
-		Stdout:     outStr,
-		Stderr:     errStr,
-		ExitCode:   exitCode,
-		DurationMs: duration,
-		Truncated:  truncated,
+		Stdout:       outStr,
+		Stderr:       errStr,
+		ExitCode:     exitCode,
+		DurationMs:   duration,
+		Truncated:    truncated,
+		StdoutBinary: outBinary,
+		StderrBinary: errBinary,
 	}
-	preview := util.Preview(strings.TrimSpace(outStr+"\n"+errStr), 12, 2000)
+	preview := meta.Preview(strings.TrimSpace(outStr + "\n" + errStr))
 	lineCount := 0
 	if preview != "" {
 		lineCount = strings.Count(preview, "\n") + 1
@@ -135,18 +148,12 @@ func (s *ShellTool) Execute(ctx context.Context, input json.RawMessage, meta Met
 	return Result{ToolName: s.Name(), Payload: output, Preview: preview, LineCount: lineCount, ByteCount: byteCount, Truncated: truncated, DurationMs: duration}, nil
 }
 
+// resolveCwd resolves a shell cwd inside repoRoot; symlinks leading out of
+// the repository are rejected.
 func resolveCwd(repoRoot, cwd string) (string, error) {
-	if filepath.IsAbs(cwd) {
-		rel, err := filepath.Rel(repoRoot, cwd)
-		if err != nil || strings.HasPrefix(rel, "..") {
-			return "", fmt.Errorf("cwd must stay within repo root")
-		}
-		return cwd, nil
-	}
-	abs := filepath.Join(repoRoot, cwd)
-	rel, err := filepath.Rel(repoRoot, abs)
-	if err != nil || strings.HasPrefix(rel, "..") {
-		return "", fmt.Errorf("cwd must stay within repo root")
+	abs, _, err := resolveInRepo(repoRoot, cwd)
+	if err != nil {
+		return "", fmt.Errorf("cwd must stay within repo root: %w", err)
 	}
 	return abs, nil
 }
@@ -157,3 +164,13 @@ func minimalEnv() []string {
 	}
 	return nil
 }
+
+// shellText prepares one output stream for the model. Binary output is
+// replaced by a hexdump preview and described by the returned metadata.
+func shellText(data []byte) (string, *binaryOutput) {
+	if isBinaryOutput(data) {
+		info, text := describeBinary(data)
+		return text, &info
+	}
+	return util.RedactSecrets(util.SanitizeTerminal(string(data))), nil
+}
@@ -3,6 +3,9 @@ package tools
 import (
 	"context"
 	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 
 	"fi-cli/internal/policy"
@@ -46,3 +49,41 @@ func TestShellToolAllowlistPrefix(t *testing.T) {
 		t.Fatalf("expected git commit to be blocked by allowlist")
 	}
 }
+
+func TestShellToolHonorsPathPolicy(t *testing.T) {
+	root := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(root, "infra"), 0o755); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+	disabled := false
+	meta := Meta{RepoRoot: root, ToolTimeoutSeconds: 1, MaxBytes: 1024, Policy: policy.PathPolicy{Rules: []policy.PathRule{{Path: "infra", Shell: &disabled}}}}
+	tool := NewShellTool([]string{"pwd"})
+
+	input, _ := json.Marshal(map[string]any{"command": "pwd", "cwd": "infra"})
+	if _, err := tool.Execute(context.Background(), input, meta); err == nil || !strings.Contains(err.Error(), "infra/") {
+		t.Fatalf("expected shell to be denied under infra/, got %v", err)
+	}
+	input, _ = json.Marshal(map[string]any{"command": "pwd"})
+	if _, err := tool.Execute(context.Background(), input, meta); err != nil {
+		t.Fatalf("expected shell at the repo root to be allowed, got %v", err)
+	}
+}
+
+func TestShellTextReplacesBinary(t *testing.T) {
+	png := append([]byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR"), make([]byte, 600)...)
+	text, info := shellText(png)
+	if info == nil || info.Size != len(png) || info.Type != "png image" || info.Magic != "89504e470d0a1a0a" {
+		t.Fatalf("expected png metadata, got %+v", info)
+	}
+	if !strings.HasPrefix(text, "[binary output: 616 bytes, png image") || strings.ContainsRune(text, 0) || !strings.Contains(text, "|.PNG........IHDR|") {
+		t.Fatalf("expected a hexdump preview, got %q", text)
+	}
+
+	text, info = shellText([]byte("\x1b[32mok\x1b[0m héllo\n"))
+	if info != nil || text != "ok héllo\n" {
+		t.Fatalf("expected colored text kept as text, got %q %+v", text, info)
+	}
+	if _, info := shellText([]byte{0xff, 0xfe, 'a', 'b'}); info == nil || info.Type != "" {
+		t.Fatalf("expected invalid UTF-8 to be treated as binary, got %+v", info)
+	}
+}
@@ -3,6 +3,9 @@ package tools
 import (
 	"context"
 	"encoding/json"
+
+	"fi-cli/internal/policy"
+	"fi-cli/internal/util"
 )
 
 // Meta provides execution context to tools.
@@ -12,6 +15,27 @@ type Meta struct {
 	ToolTimeoutSeconds int
 	MaxBytes           int
 	MaxResults         int
+	Policy             policy.PathPolicy
+	// Workspace is a scratch directory for this run's large outputs,
+	// downloaded pages, and extracted archives. It is removed when the run
+	// ends and is empty if it could not be created.
+	Workspace string
+	// PreviewLines and PreviewBytes bound Result.Preview; zero keeps the
+	// 12-line, 2000-byte default.
+	PreviewLines int
+	PreviewBytes int
+}
+
+// Preview renders text as a Result.Preview within the meta's preview bounds.
+func (m Meta) Preview(text string) string {
+	lines, bytes := m.PreviewLines, m.PreviewBytes
+	if lines <= 0 {
+		lines = 12
+	}
+	if bytes <= 0 {
+		bytes = 2000
+	}
+	return util.Preview(text, lines, bytes)
 }
 
 // Result is a structured tool execution result.
@@ -23,6 +47,9 @@ type Result struct {
 	ByteCount  int
 	Truncated  bool
 	DurationMs int64
+	// TouchedFiles lists repo-relative files the call created, modified, or
+	// deleted, so the agent can refresh their snippets on later steps.
+	TouchedFiles []string
 }
 
 // Tool describes a callable tool.
@@ -0,0 +1,79 @@
+package util
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"time"
+)
+
+// HistorySourceAtuin reads atuin's SQLite history via the sqlite3 CLI.
+const HistorySourceAtuin = "atuin"
+
+// atuinScanRows bounds how many recent rows are read before filtering.
+const atuinScanRows = 2000
+
+const atuinQuery = "SELECT command, cwd, exit FROM history WHERE deleted_at IS NULL ORDER BY timestamp DESC LIMIT %d;"
+
+type atuinRow struct {
+	Command string `json:"command"`
+	Cwd     string `json:"cwd"`
+	Exit    int    `json:"exit"`
+}
+
+func readAtuinHistory(home string) []historyEntry {
+	path := atuinDBPath(home)
+	if path == "" {
+		return nil
+	}
+	sqlite, err := exec.LookPath("sqlite3")
+	if err != nil {
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+	out, err := exec.CommandContext(ctx, sqlite, "-readonly", "-json", path, fmt.Sprintf(atuinQuery, atuinScanRows)).Output()
+	if err != nil {
+		return nil
+	}
+	return parseAtuinRows(out)
+}
+
+func atuinDBPath(home string) string {
+	candidates := []string{}
+	if path := os.Getenv("ATUIN_DB_PATH"); path != "" {
+		candidates = append(candidates, path)
+	}
+	if xdg := os.Getenv("XDG_DATA_HOME"); xdg != "" {
+		candidates = append(candidates, filepath.Join(xdg, "atuin", "history.db"))
+	}
+	candidates = append(candidates, filepath.Join(home, ".local", "share", "atuin", "history.db"))
+	for _, path := range candidates {
+		if _, err := os.Stat(path); err == nil {
+			return path
+		}
+	}
+	return ""
+}
+
+// parseAtuinRows converts sqlite3 -json output (newest first) into entries
+// ordered oldest first, annotating failed commands with their exit code.
+func parseAtuinRows(data []byte) []historyEntry {
+	var rows []atuinRow
+	if err := json.Unmarshal(data, &rows); err != nil {
+		return nil
+	}
+	entries := make([]historyEntry, 0, len(rows))
+	for i := len(rows) - 1; i >= 0; i-- {
+		row := rows[i]
+		command := row.Command
+		if row.Exit != 0 {
+			command = fmt.Sprintf("%s  # exit %d", command, row.Exit)
+		}
+		entries = append(entries, historyEntry{Command: command, Cwd: row.Cwd})
+	}
+	return entries
+}
@@ -7,38 +7,124 @@ import (
 	"strings"
 )
 
+// History sources understood by LoadHistory.
+const (
+	HistorySourceZsh  = "zsh"
+	HistorySourceBash = "bash"
+	HistorySourceFish = "fish"
+)
+
+// HistoryOptions selects and filters shell history.
+type HistoryOptions struct {
+	MaxLines int
+	// Sources lists history sources in priority order. Empty means atuin
+	// when its database is available, else HISTFILE or the first history
+	// file found.
+	Sources []string
+	// Exclude drops commands containing any of these substrings (case-insensitive).
+	Exclude []string
+	// Dir keeps only commands run inside this directory subtree, for sources
+	// that record a working directory.
+	Dir string
+}
+
+// historyEntry is a single command with optional metadata.
+type historyEntry struct {
+	Command string
+	Cwd     string
+}
+
 // LoadShellHistory returns the last N commands from shell history.
 func LoadShellHistory(maxLines int) []string {
-	if maxLines <= 0 {
+	return LoadHistory(HistoryOptions{MaxLines: maxLines})
+}
+
+// LoadHistory returns the last MaxLines commands from the selected sources,
+// filtered and redacted.
+func LoadHistory(opts HistoryOptions) []string {
+	if opts.MaxLines <= 0 {
 		return nil
 	}
-	path := historyPath()
-	if path == "" {
+	var entries []historyEntry
+	if len(opts.Sources) == 0 {
+		entries = readDefaultHistory()
+	} else {
+		for _, source := range opts.Sources {
+			entries = append(entries, readHistorySource(source)...)
+		}
+	}
+
+	lines := make([]string, 0, opts.MaxLines)
+	for _, entry := range entries {
+		if entry.Command == "" || excludedCommand(entry.Command, opts.Exclude) {
+			continue
+		}
+		if opts.Dir != "" && entry.Cwd != "" && !withinDir(entry.Cwd, opts.Dir) {
+			continue
+		}
+		lines = append(lines, entry.Command)
+	}
+	if len(lines) > opts.MaxLines {
+		lines = lines[len(lines)-opts.MaxLines:]
+	}
+
+	for i, line := range lines {
+		lines[i] = RedactSecrets(line)
+	}
+	return lines
+}
+
+// readDefaultHistory prefers atuin, whose cwd metadata lets Dir keep only
+// commands run in the current repo, unless HISTFILE points elsewhere.
+func readDefaultHistory() []historyEntry {
+	if os.Getenv("HISTFILE") == "" {
+		if home, err := os.UserHomeDir(); err == nil {
+			if entries := readAtuinHistory(home); len(entries) > 0 {
+				return entries
+			}
+		}
+	}
+	if path := historyPath(); path != "" {
+		return readHistoryFile(path)
+	}
+	return nil
+}
+
+func readHistorySource(source string) []historyEntry {
+	home, err := os.UserHomeDir()
+	if err != nil {
 		return nil
 	}
+	switch strings.ToLower(strings.TrimSpace(source)) {
+	case HistorySourceZsh:
+		return readHistoryFile(filepath.Join(home, ".zsh_history"))
+	case HistorySourceBash:
+		return readHistoryFile(filepath.Join(home, ".bash_history"))
+	case HistorySourceFish:
+		return readHistoryFile(filepath.Join(home, ".local", "share", "fish", "fish_history"))
+	case HistorySourceAtuin:
+		return readAtuinHistory(home)
+	}
+	return nil
+}
+
+func readHistoryFile(path string) []historyEntry {
 	file, err := os.Open(path)
 	if err != nil {
 		return nil
 	}
 	defer file.Close()
 
+	var entries []historyEntry
 	scanner := bufio.NewScanner(file)
-	lines := make([]string, 0, maxLines)
 	for scanner.Scan() {
 		line := strings.TrimSpace(scanner.Text())
-		if line == "" {
+		if line == "" || isFishMetadata(line) {
 			continue
 		}
-		lines = append(lines, normalizeHistoryLine(line))
-		if len(lines) > maxLines {
-			lines = lines[len(lines)-maxLines:]
-		}
+		entries = append(entries, historyEntry{Command: normalizeHistoryLine(line)})
 	}
-
-	for i, line := range lines {
-		lines[i] = RedactSecrets(line)
-	}
-	return lines
+	return entries
 }
 
 func historyPath() string {
@@ -52,6 +138,7 @@ func historyPath() string {
 	candidates := []string{
 		filepath.Join(home, ".zsh_history"),
 		filepath.Join(home, ".bash_history"),
+		filepath.Join(home, ".local", "share", "fish", "fish_history"),
 		filepath.Join(home, ".config", "fish", "fish_history"),
 	}
 	for _, path := range candidates {
@@ -75,3 +162,27 @@ func normalizeHistoryLine(line string) string {
 	}
 	return line
 }
+
+// isFishMetadata reports fish history fields that follow a "- cmd:" entry.
+func isFishMetadata(line string) bool {
+	return strings.HasPrefix(line, "when: ") || line == "paths:" || strings.HasPrefix(line, "- /")
+}
+
+func excludedCommand(command string, patterns []string) bool {
+	lower := strings.ToLower(command)
+	for _, pattern := range patterns {
+		pattern = strings.ToLower(strings.TrimSpace(pattern))
+		if pattern != "" && strings.Contains(lower, pattern) {
+			return true
+		}
+	}
+	return false
+}
+
+func withinDir(path, dir string) bool {
+	rel, err := filepath.Rel(filepath.Clean(dir), filepath.Clean(path))
+	if err != nil {
+		return false
+	}
+	return rel == "." || (rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)))
+}
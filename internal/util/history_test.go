@@ -37,3 +37,49 @@ func TestLoadShellHistory(t *testing.T) {
 		t.Fatalf("expected normalized history")
 	}
 }
+
+func TestLoadHistoryFiltering(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	if err := os.WriteFile(filepath.Join(home, ".bash_history"), []byte("make test\nmysql --password hunter2\n"), 0o600); err != nil {
+		t.Fatalf("write bash history: %v", err)
+	}
+	fishDir := filepath.Join(home, ".local", "share", "fish")
+	if err := os.MkdirAll(fishDir, 0o755); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+	fish := "- cmd: go build ./...\n  when: 1680000000\n  paths:\n    - /tmp/x\n"
+	if err := os.WriteFile(filepath.Join(fishDir, "fish_history"), []byte(fish), 0o600); err != nil {
+		t.Fatalf("write fish history: %v", err)
+	}
+
+	lines := LoadHistory(HistoryOptions{MaxLines: 10, Sources: []string{"bash", "fish"}, Exclude: []string{"PASSWORD"}})
+	if strings.Join(lines, "|") != "make test|go build ./..." {
+		t.Fatalf("unexpected history: %q", lines)
+	}
+
+	lines = LoadHistory(HistoryOptions{MaxLines: 10, Sources: []string{"fish"}})
+	if len(lines) != 1 {
+		t.Fatalf("expected fish metadata to be skipped, got %q", lines)
+	}
+}
+
+func TestWithinDir(t *testing.T) {
+	if !withinDir("/repo/sub", "/repo") || !withinDir("/repo", "/repo") {
+		t.Fatalf("expected subtree match")
+	}
+	if withinDir("/repository", "/repo") || withinDir("/", "/repo") {
+		t.Fatalf("expected paths outside subtree to be rejected")
+	}
+}
+
+func TestParseAtuinRows(t *testing.T) {
+	data := []byte(`[{"command":"go test ./...","cwd":"/repo","exit":1},{"command":"ls","cwd":"/elsewhere","exit":0}]`)
+	entries := parseAtuinRows(data)
+	if len(entries) != 2 {
+		t.Fatalf("expected 2 entries, got %d", len(entries))
+	}
+	if entries[0].Command != "ls" || entries[1].Command != "go test ./...  # exit 1" || entries[1].Cwd != "/repo" {
+		t.Fatalf("unexpected entries: %+v", entries)
+	}
+}
@@ -0,0 +1,81 @@
+package util
+
+import (
+	"regexp"
+	"strings"
+)
+
+// ansiPattern matches CSI/OSC escape sequences (colors, cursor moves, window
+// titles), C1 control characters, and stray control bytes other than tab,
+// newline, and carriage return.
+var ansiPattern = regexp.MustCompile(`\x1b\[[0-9;?]*[ -/]*[@-~]|\x1b\][^\x07\x1b]*(\x07|\x1b\\)|\x1b[@-Z\\-_]|[\x00-\x08\x0b\x0c\x0e-\x1f\x7f]|\x{9b}[0-9;?]*[ -/]*[@-~]|[\x{80}-\x{9f}]`)
+
+// partialEscapePattern matches the start of an escape sequence that has not
+// ended yet.
+var partialEscapePattern = regexp.MustCompile(`^\x1b(\[[0-9;?]*[ -/]*|\][^\x07\x1b]*\x1b?)?$`)
+
+// maxEscapeBytes bounds how much of a stream TerminalFilter holds back while
+// waiting for an escape sequence to end; longer runs are treated as text.
+const maxEscapeBytes = 256
+
+// StripANSI removes escape sequences and control bytes from text, keeping
+// tabs, newlines, and carriage returns.
+func StripANSI(text string) string {
+	return ansiPattern.ReplaceAllString(text, "")
+}
+
+// SanitizeTerminal makes captured output safe to print: escape sequences are
+// stripped and carriage returns are resolved the way a terminal shows them,
+// so a progress bar that redraws a line keeps only its last state.
+func SanitizeTerminal(text string) string {
+	text = StripANSI(text)
+	if !strings.Contains(text, "\r") {
+		return text
+	}
+	lines := strings.Split(strings.ReplaceAll(text, "\r\n", "\n"), "\n")
+	for i, line := range lines {
+		line = strings.TrimRight(line, "\r")
+		if idx := strings.LastIndexByte(line, '\r'); idx != -1 {
+			line = line[idx+1:]
+		}
+		lines[i] = line
+	}
+	return strings.Join(lines, "\n")
+}
+
+// TerminalFilter sanitizes streamed text whose escape sequences may be split
+// across writes. Text already printed cannot be redrawn, so a lone carriage
+// return becomes a newline instead of overwriting the line.
+type TerminalFilter struct {
+	pending string
+}
+
+// Write returns the printable part of delta, holding back a trailing escape
+// sequence or carriage return until the next write completes it.
+func (f *TerminalFilter) Write(delta string) string {
+	text := f.pending + delta
+	f.pending = ""
+	cut := len(text)
+	for idx := max(0, len(text)-maxEscapeBytes); idx < len(text); idx++ {
+		if text[idx] == '\x1b' && partialEscapePattern.MatchString(text[idx:]) {
+			cut = idx
+			break
+		}
+	}
+	if cut > 0 && cut == len(text) && text[cut-1] == '\r' {
+		cut--
+	}
+	text, f.pending = text[:cut], text[cut:]
+	return normalizeCR(StripANSI(text))
+}
+
+// Flush returns whatever Write held back, sanitized.
+func (f *TerminalFilter) Flush() string {
+	text := f.pending
+	f.pending = ""
+	return normalizeCR(StripANSI(text))
+}
+
+func normalizeCR(text string) string {
+	return strings.ReplaceAll(strings.ReplaceAll(text, "\r\n", "\n"), "\r", "\n")
+}
@@ -0,0 +1,23 @@
+package util
+
+import "testing"
+
+func TestSanitizeTerminal(t *testing.T) {
+	input := "\x1b]0;evil title\x07\x1b[1;31mFAIL\x1b[0m pkg\r\nfetch 10%\rfetch 55%\rfetch 100%\nbell\x07 done\u009b2J"
+	want := "FAIL pkg\nfetch 100%\nbell done"
+	if got := SanitizeTerminal(input); got != want {
+		t.Fatalf("expected %q, got %q", want, got)
+	}
+}
+
+func TestTerminalFilterSplitSequences(t *testing.T) {
+	var f TerminalFilter
+	var out string
+	for _, delta := range []string{"ok \x1b[3", "2mgreen\x1b[0m\r", "\nnext\x1b]0;ti", "tle\x1b", "\\ line\rmore"} {
+		out += f.Write(delta)
+	}
+	out += f.Flush()
+	if want := "ok green\nnext line\nmore"; out != want {
+		t.Fatalf("expected %q, got %q", want, out)
+	}
+}
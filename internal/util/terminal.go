@@ -0,0 +1,91 @@
+package util
+
+import (
+	"context"
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// ErrNoMultiplexer reports that neither tmux nor screen is running.
+var ErrNoMultiplexer = errors.New("not running inside tmux or screen")
+
+// paneScrollbackLines is how far back tmux/screen scrollback is read.
+const paneScrollbackLines = 2000
+
+// CapturePane returns the current tmux pane or screen window scrollback,
+// redacted and trimmed to its last maxBytes, along with the source name.
+func CapturePane(ctx context.Context, maxBytes int) (string, string, error) {
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	var (
+		text   string
+		source string
+		err    error
+	)
+	switch {
+	case os.Getenv("TMUX") != "":
+		source = "tmux"
+		text, err = captureTmux(ctx)
+	case os.Getenv("STY") != "":
+		source = "screen"
+		text, err = captureScreen(ctx)
+	default:
+		return "", "", ErrNoMultiplexer
+	}
+	if err != nil {
+		return "", source, err
+	}
+	return TailBytes(RedactSecrets(strings.TrimRight(text, "\n ")), maxBytes), source, nil
+}
+
+func captureTmux(ctx context.Context) (string, error) {
+	args := []string{"capture-pane", "-p", "-J", "-S", "-" + strconv.Itoa(paneScrollbackLines)}
+	if pane := os.Getenv("TMUX_PANE"); pane != "" {
+		args = append(args, "-t", pane)
+	}
+	out, err := exec.CommandContext(ctx, "tmux", args...).Output()
+	return string(out), err
+}
+
+func captureScreen(ctx context.Context) (string, error) {
+	dir, err := os.MkdirTemp("", "fi-screen-")
+	if err != nil {
+		return "", err
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "hardcopy")
+	if err := exec.CommandContext(ctx, "screen", "-X", "hardcopy", "-h", path).Run(); err != nil {
+		return "", err
+	}
+	// screen writes the file asynchronously
+	for i := 0; i < 20; i++ {
+		if data, err := os.ReadFile(path); err == nil && len(data) > 0 {
+			return string(data), nil
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+	return "", errors.New("screen hardcopy produced no output")
+}
+
+// TailBytes keeps the last maxBytes of input, starting at a line boundary
+// when one is available.
+func TailBytes(input string, maxBytes int) string {
+	if maxBytes <= 0 || len(input) <= maxBytes {
+		return input
+	}
+	tail := input[len(input)-maxBytes:]
+	for tail != "" && !utf8.RuneStart(tail[0]) {
+		tail = tail[1:]
+	}
+	if idx := strings.IndexByte(tail, '\n'); idx != -1 && idx < len(tail)-1 {
+		tail = tail[idx+1:]
+	}
+	return tail
+}
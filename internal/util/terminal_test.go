@@ -0,0 +1,25 @@
+package util
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestTailBytes(t *testing.T) {
+	input := "first line\nsecond line\nthird line"
+	if got := TailBytes(input, 15); got != "third line" {
+		t.Fatalf("expected tail aligned to a line, got %q", got)
+	}
+	if got := TailBytes(input, 0); got != input {
+		t.Fatalf("expected no limit to keep input")
+	}
+}
+
+func TestCapturePaneWithoutMultiplexer(t *testing.T) {
+	t.Setenv("TMUX", "")
+	t.Setenv("STY", "")
+	if _, _, err := CapturePane(context.Background(), 1024); !errors.Is(err, ErrNoMultiplexer) {
+		t.Fatalf("expected ErrNoMultiplexer, got %v", err)
+	}
+}
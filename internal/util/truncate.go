@@ -2,17 +2,75 @@ package util
 
 import (
 	"strings"
+	"unicode"
+	"unicode/utf8"
 )
 
-// TruncateBytes trims a string to maxBytes if needed.
+// Ellipsis marks text that was cut short.
+const Ellipsis = "…"
+
+// TruncateBytes trims a string to maxBytes if needed, cutting on a character
+// boundary and ending the result with Ellipsis.
 func TruncateBytes(input string, maxBytes int) (string, bool) {
 	if maxBytes <= 0 || len(input) <= maxBytes {
 		return input, false
 	}
-	return input[:maxBytes], true
+	return CutBytes(input, maxBytes-len(Ellipsis)) + Ellipsis, true
+}
+
+// CutBytes returns the longest prefix of input that fits in maxBytes without
+// splitting a rune or separating a character from the combining marks,
+// joiners, and modifiers that follow it.
+func CutBytes(input string, maxBytes int) string {
+	if len(input) <= maxBytes {
+		return input
+	}
+	if maxBytes <= 0 {
+		return ""
+	}
+	cut := maxBytes
+	for cut > 0 && !utf8.RuneStart(input[cut]) {
+		cut--
+	}
+	for cut > 0 {
+		next, _ := utf8.DecodeRuneInString(input[cut:])
+		prev, size := utf8.DecodeLastRuneInString(input[:cut])
+		if !extendsGrapheme(next) && prev != '\u200d' {
+			break
+		}
+		cut -= size
+	}
+	return input[:cut]
+}
+
+// extendsGrapheme reports whether r attaches to the character before it.
+func extendsGrapheme(r rune) bool {
+	switch {
+	case unicode.In(r, unicode.Mn, unicode.Me, unicode.Mc):
+		return true
+	case r == '\u200d', r >= '\ufe00' && r <= '\ufe0f':
+		return true
+	case r >= 0x1f3fb && r <= 0x1f3ff:
+		return true
+	}
+	return false
+}
+
+// TrimPartialRune drops an incomplete UTF-8 sequence left at the end of input
+// by a byte-limited read.
+func TrimPartialRune(input string) string {
+	for i := 0; i < utf8.UTFMax-1 && input != ""; i++ {
+		if r, size := utf8.DecodeLastRuneInString(input); r != utf8.RuneError || size != 1 {
+			return input
+		}
+		input = input[:len(input)-1]
+	}
+	return input
 }
 
-// TruncateLinesAndBytes limits lines and total byte count.
+// TruncateLinesAndBytes limits lines and total byte count. The first line
+// that does not fit is kept, cut short with Ellipsis, when the byte budget
+// has room for part of it.
 func TruncateLinesAndBytes(lines []string, maxLines int, maxBytes int) (out []string, truncated bool, byteCount int) {
 	if maxLines <= 0 && maxBytes <= 0 {
 		return lines, false, len(strings.Join(lines, "\n"))
@@ -29,6 +87,12 @@ func TruncateLinesAndBytes(lines []string, maxLines int, maxBytes int) (out []st
 		}
 		if maxBytes > 0 && byteCount+sep+lineBytes > maxBytes {
 			truncated = true
+			if room := maxBytes - byteCount - sep; room > len(Ellipsis) {
+				if cut := CutBytes(line, room-len(Ellipsis)); cut != "" {
+					out = append(out, cut+Ellipsis)
+					byteCount += sep + len(cut) + len(Ellipsis)
+				}
+			}
 			break
 		}
 		if sep == 1 {
@@ -40,12 +104,13 @@ func TruncateLinesAndBytes(lines []string, maxLines int, maxBytes int) (out []st
 	return out, truncated, byteCount
 }
 
-// Preview returns a short preview of text by limiting lines and bytes.
+// Preview returns a short preview of text by limiting lines and bytes. The
+// text is sanitized first, since previews are printed to the terminal.
 func Preview(text string, maxLines int, maxBytes int) string {
 	if text == "" {
 		return ""
 	}
-	lines := strings.Split(text, "\n")
+	lines := strings.Split(SanitizeTerminal(text), "\n")
 	trimmed, _, _ := TruncateLinesAndBytes(lines, maxLines, maxBytes)
 	return strings.Join(trimmed, "\n")
 }
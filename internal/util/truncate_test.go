@@ -0,0 +1,55 @@
+package util
+
+import (
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestTruncateBytesRuneSafe(t *testing.T) {
+	out, truncated := TruncateBytes("naïve café façade", 10)
+	if !truncated || !utf8.ValidString(out) || len(out) > 10 || !strings.HasSuffix(out, Ellipsis) {
+		t.Fatalf("expected a valid cut with ellipsis, got %q", out)
+	}
+	if out, truncated := TruncateBytes("short", 10); out != "short" || truncated {
+		t.Fatalf("expected short input untouched, got %q", out)
+	}
+}
+
+func TestCutBytesKeepsGraphemes(t *testing.T) {
+	cases := []struct {
+		input string
+		max   int
+		want  string
+	}{
+		{"cafe\u0301s", 5, "caf"},
+		{"ok 👩‍💻 done", 9, "ok "},
+		{"thumb 👍🏽", 10, "thumb "},
+		{"日本語", 7, "日本"},
+	}
+	for _, tc := range cases {
+		if got := CutBytes(tc.input, tc.max); got != tc.want {
+			t.Fatalf("CutBytes(%q, %d): expected %q, got %q", tc.input, tc.max, tc.want, got)
+		}
+	}
+}
+
+func TestTruncateLinesAndBytesCutsLongLine(t *testing.T) {
+	lines := []string{"a.go:1:short", "b.go:2:" + strings.Repeat("é", 50)}
+	out, truncated, byteCount := TruncateLinesAndBytes(lines, 10, 40)
+	if !truncated || len(out) != 2 || !strings.HasSuffix(out[1], Ellipsis) || !utf8.ValidString(out[1]) {
+		t.Fatalf("expected the long line cut with ellipsis, got %q", out)
+	}
+	if byteCount > 40 || byteCount != len(strings.Join(out, "\n")) {
+		t.Fatalf("unexpected byte count %d for %q", byteCount, out)
+	}
+}
+
+func TestTrimPartialRune(t *testing.T) {
+	if got := TrimPartialRune("héllo"[:2]); got != "h" {
+		t.Fatalf("expected partial rune dropped, got %q", got)
+	}
+	if got := TailBytes("ééé", 5); !utf8.ValidString(got) {
+		t.Fatalf("expected a valid tail, got %q", got)
+	}
+}
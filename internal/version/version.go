@@ -1,3 +1,36 @@
 package version
 
+import "runtime/debug"
+
 const Version = "0.6.0"
+
+// Commit and BuildDate are set at build time with
+// -ldflags "-X fi-cli/internal/version.Commit=... -X fi-cli/internal/version.BuildDate=...".
+// When unset, Build falls back to the VCS stamp Go records in the binary.
+var (
+	Commit    string
+	BuildDate string
+)
+
+// Build returns the commit and build date of the running binary, either of
+// which may be empty.
+func Build() (commit, date string) {
+	commit, date = Commit, BuildDate
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return commit, date
+	}
+	for _, setting := range info.Settings {
+		switch setting.Key {
+		case "vcs.revision":
+			if commit == "" {
+				commit = setting.Value
+			}
+		case "vcs.time":
+			if date == "" {
+				date = setting.Value
+			}
+		}
+	}
+	return commit, date
+}
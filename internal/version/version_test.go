@@ -0,0 +1,12 @@
+package version
+
+import "testing"
+
+func TestBuildPrefersLinkerValues(t *testing.T) {
+	Commit, BuildDate = "abc123", "2026-10-01T09:00:00Z"
+	t.Cleanup(func() { Commit, BuildDate = "", "" })
+	commit, date := Build()
+	if commit != "abc123" || date != "2026-10-01T09:00:00Z" {
+		t.Fatalf("expected the -X values, got %q %q", commit, date)
+	}
+}
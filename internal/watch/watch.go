@@ -0,0 +1,136 @@
+package watch
+
+import (
+	"context"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// skipDirs are never watched; they churn constantly or hold tool state.
+var skipDirs = map[string]struct{}{
+	".git": {}, ".fi": {}, "node_modules": {}, "vendor": {}, "dist": {}, "build": {}, "target": {},
+}
+
+// Watcher reports batches of changed files under a repository root.
+type Watcher struct {
+	root     string
+	debounce time.Duration
+	fs       *fsnotify.Watcher
+}
+
+// New watches root recursively, skipping hidden and generated directories.
+func New(root string, debounce time.Duration) (*Watcher, error) {
+	inner, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, err
+	}
+	w := &Watcher{root: root, debounce: debounce, fs: inner}
+	if err := w.addTree(root); err != nil {
+		_ = inner.Close()
+		return nil, err
+	}
+	return w, nil
+}
+
+// Changes emits sorted, repo-relative paths after events settle for the
+// debounce interval. The channel closes when ctx is done.
+func (w *Watcher) Changes(ctx context.Context) <-chan []string {
+	out := make(chan []string)
+	go func() {
+		defer close(out)
+		pending := map[string]struct{}{}
+		var timer *time.Timer
+		var fire <-chan time.Time
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case event, ok := <-w.fs.Events:
+				if !ok {
+					return
+				}
+				if ignored(w.root, event.Name) {
+					continue
+				}
+				if event.Op&fsnotify.Create != 0 {
+					if info, err := os.Stat(event.Name); err == nil && info.IsDir() {
+						_ = w.addTree(event.Name)
+					}
+				}
+				if event.Op&fsnotify.Chmod == event.Op {
+					continue
+				}
+				rel, err := filepath.Rel(w.root, event.Name)
+				if err != nil {
+					rel = event.Name
+				}
+				pending[filepath.ToSlash(rel)] = struct{}{}
+				if timer == nil {
+					timer = time.NewTimer(w.debounce)
+				} else {
+					timer.Reset(w.debounce)
+				}
+				fire = timer.C
+			case <-fire:
+				fire = nil
+				batch := make([]string, 0, len(pending))
+				for path := range pending {
+					batch = append(batch, path)
+				}
+				sort.Strings(batch)
+				pending = map[string]struct{}{}
+				select {
+				case out <- batch:
+				case <-ctx.Done():
+					return
+				}
+			case <-w.fs.Errors:
+			}
+		}
+	}()
+	return out
+}
+
+// Close stops watching.
+func (w *Watcher) Close() error {
+	return w.fs.Close()
+}
+
+func (w *Watcher) addTree(start string) error {
+	return filepath.WalkDir(start, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || !d.IsDir() {
+			return nil
+		}
+		if path != w.root && skipDir(d.Name()) {
+			return filepath.SkipDir
+		}
+		return w.fs.Add(path)
+	})
+}
+
+func skipDir(name string) bool {
+	if _, ok := skipDirs[name]; ok {
+		return true
+	}
+	return strings.HasPrefix(name, ".")
+}
+
+func ignored(root, path string) bool {
+	rel, err := filepath.Rel(root, path)
+	if err != nil {
+		return true
+	}
+	for _, part := range strings.Split(filepath.ToSlash(rel), "/") {
+		if part != "." && skipDir(part) {
+			return true
+		}
+	}
+	base := filepath.Base(path)
+	return strings.HasSuffix(base, "~") || strings.HasSuffix(base, ".swp")
+}
@@ -0,0 +1,41 @@
+package watch
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestWatcherBatchesChanges(t *testing.T) {
+	root := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(root, "src"), 0o755); err != nil {
+		t.Fatalf("mkdir failed: %v", err)
+	}
+	w, err := New(root, 50*time.Millisecond)
+	if err != nil {
+		t.Fatalf("new watcher: %v", err)
+	}
+	defer w.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	changes := w.Changes(ctx)
+
+	if err := os.WriteFile(filepath.Join(root, "src", "main.go"), []byte("package main\n"), 0o644); err != nil {
+		t.Fatalf("write failed: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(root, ".hidden"), []byte("x"), 0o644); err != nil {
+		t.Fatalf("write failed: %v", err)
+	}
+
+	select {
+	case batch := <-changes:
+		if len(batch) != 1 || batch[0] != "src/main.go" {
+			t.Fatalf("unexpected batch: %v", batch)
+		}
+	case <-ctx.Done():
+		t.Fatalf("timed out waiting for changes")
+	}
+}
@@ -0,0 +1,81 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"time"
+)
+
+// Environment variables holding Slack credentials. A bot token posts to any
+// channel the bot is in; an incoming webhook posts to the channel it was
+// created for.
+const (
+	SlackBotTokenEnv   = "SLACK_BOT_TOKEN"
+	SlackWebhookURLEnv = "SLACK_WEBHOOK_URL"
+)
+
+// slackPostMessageURL is a variable so tests can point it at a local server.
+var slackPostMessageURL = "https://slack.com/api/chat.postMessage"
+
+// SlackMessage is a plain mrkdwn message for one channel.
+type SlackMessage struct {
+	Channel string `json:"channel,omitempty"`
+	Text    string `json:"text"`
+}
+
+// PostSlack sends msg with SLACK_BOT_TOKEN, or else SLACK_WEBHOOK_URL.
+func PostSlack(ctx context.Context, msg SlackMessage) error {
+	if token := os.Getenv(SlackBotTokenEnv); token != "" {
+		return postSlackAPI(ctx, token, msg)
+	}
+	if url := os.Getenv(SlackWebhookURLEnv); url != "" {
+		return Post(ctx, url, msg)
+	}
+	return fmt.Errorf("slack credentials missing: set %s or %s", SlackBotTokenEnv, SlackWebhookURLEnv)
+}
+
+// postSlackAPI calls chat.postMessage, which answers 200 with ok=false on
+// failures such as an unknown channel.
+func postSlackAPI(ctx context.Context, token string, msg SlackMessage) error {
+	body, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(ctx, 15*time.Second)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, slackPostMessageURL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json; charset=utf-8")
+	req.Header.Set("Authorization", "Bearer "+token)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	b, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("slack post failed: %s: %s", resp.Status, string(b))
+	}
+	var reply struct {
+		OK    bool   `json:"ok"`
+		Error string `json:"error"`
+	}
+	if err := json.Unmarshal(b, &reply); err != nil {
+		return fmt.Errorf("slack post failed: unreadable response: %w", err)
+	}
+	if !reply.OK {
+		if reply.Error == "" {
+			return errors.New("slack post failed")
+		}
+		return fmt.Errorf("slack post failed: %s", reply.Error)
+	}
+	return nil
+}
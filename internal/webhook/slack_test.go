@@ -0,0 +1,64 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestPostSlackWithBotToken(t *testing.T) {
+	var got SlackMessage
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer xoxb-test" {
+			_, _ = w.Write([]byte(`{"ok":false,"error":"invalid_auth"}`))
+			return
+		}
+		_ = json.NewDecoder(r.Body).Decode(&got)
+		if got.Channel != "#triage" {
+			_, _ = w.Write([]byte(`{"ok":false,"error":"channel_not_found"}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{"ok":true}`))
+	}))
+	defer server.Close()
+	defer func(url string) { slackPostMessageURL = url }(slackPostMessageURL)
+	slackPostMessageURL = server.URL
+
+	t.Setenv(SlackBotTokenEnv, "xoxb-test")
+	if err := PostSlack(context.Background(), SlackMessage{Channel: "#triage", Text: "answer"}); err != nil {
+		t.Fatalf("post: %v", err)
+	}
+	if got.Text != "answer" {
+		t.Fatalf("unexpected message: %+v", got)
+	}
+	err := PostSlack(context.Background(), SlackMessage{Channel: "#nope", Text: "answer"})
+	if err == nil || !strings.Contains(err.Error(), "channel_not_found") {
+		t.Fatalf("expected the Slack error, got %v", err)
+	}
+}
+
+func TestPostSlackWithWebhook(t *testing.T) {
+	var got SlackMessage
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewDecoder(r.Body).Decode(&got)
+		_, _ = w.Write([]byte("ok"))
+	}))
+	defer server.Close()
+
+	t.Setenv(SlackBotTokenEnv, "")
+	t.Setenv(SlackWebhookURLEnv, server.URL)
+	if err := PostSlack(context.Background(), SlackMessage{Channel: "#triage", Text: "answer"}); err != nil {
+		t.Fatalf("post: %v", err)
+	}
+	if got.Text != "answer" {
+		t.Fatalf("unexpected message: %+v", got)
+	}
+
+	t.Setenv(SlackWebhookURLEnv, "")
+	if err := PostSlack(context.Background(), SlackMessage{Text: "answer"}); err == nil {
+		t.Fatalf("expected an error without credentials")
+	}
+}
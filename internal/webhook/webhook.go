@@ -0,0 +1,94 @@
+package webhook
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"time"
+
+	retryablehttp "github.com/hashicorp/go-retryablehttp"
+)
+
+// SecretEnv holds the shared secret that signs webhook deliveries.
+const SecretEnv = "WEBHOOK_SECRET"
+
+// Headers sent with every delivery. SignatureHeader is "sha256=" and the hex
+// HMAC-SHA256 of the body under the secret, as GitHub signs its webhooks;
+// DeliveryHeader stays the same across retries so receivers can drop
+// duplicates.
+const (
+	SignatureHeader = "X-Fi-Signature-256"
+	DeliveryHeader  = "X-Fi-Delivery"
+)
+
+// maxRetries is how many times a delivery is retried after a network error,
+// a 429, or a 5xx. retryWait is the first backoff; it doubles each attempt.
+var (
+	maxRetries = 3
+	retryWait  = time.Second
+)
+
+// Post sends payload as JSON to url and fails on non-2xx responses. Failed
+// deliveries are retried with backoff, and with WEBHOOK_SECRET set the body is
+// signed.
+func Post(ctx context.Context, url string, payload any) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	req, err := retryablehttp.NewRequestWithContext(ctx, http.MethodPost, url, body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "fi-cli")
+	req.Header.Set(DeliveryHeader, deliveryID())
+	if secret := os.Getenv(SecretEnv); secret != "" {
+		req.Header.Set(SignatureHeader, Sign(secret, body))
+	}
+
+	client := retryablehttp.NewClient()
+	client.HTTPClient.Timeout = 15 * time.Second
+	client.RetryMax = maxRetries
+	client.RetryWaitMin = retryWait
+	client.RetryWaitMax = 8 * retryWait
+	client.Logger = nil
+	// hand back the last response so its status and body reach the error
+	client.ErrorHandler = retryablehttp.PassthroughErrorHandler
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		b, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("webhook failed: %s: %s", resp.Status, string(b))
+	}
+	return nil
+}
+
+// Sign returns the SignatureHeader value for body.
+func Sign(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Verify reports whether signature is a valid SignatureHeader value for body,
+// comparing in constant time.
+func Verify(secret string, body []byte, signature string) bool {
+	return hmac.Equal([]byte(Sign(secret, body)), []byte(signature))
+}
+
+func deliveryID() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,74 @@
+package webhook
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestPostSignsAndRetries(t *testing.T) {
+	defer func(wait time.Duration) { retryWait = wait }(retryWait)
+	retryWait = time.Millisecond
+
+	var attempts int
+	var deliveries []string
+	var body []byte
+	var signature string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		deliveries = append(deliveries, r.Header.Get(DeliveryHeader))
+		body, _ = io.ReadAll(r.Body)
+		signature = r.Header.Get(SignatureHeader)
+		if attempts < 3 {
+			http.Error(w, "busy", http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	t.Setenv(SecretEnv, "s3cret")
+	if err := Post(context.Background(), server.URL, map[string]string{"run_id": "r1"}); err != nil {
+		t.Fatalf("post: %v", err)
+	}
+	if attempts != 3 || deliveries[0] == "" || deliveries[0] != deliveries[2] {
+		t.Fatalf("expected three attempts of one delivery, got %d %v", attempts, deliveries)
+	}
+	if string(body) != `{"run_id":"r1"}` || !Verify("s3cret", body, signature) || Verify("other", body, signature) {
+		t.Fatalf("expected a valid signature for %s, got %q", body, signature)
+	}
+
+	t.Setenv(SecretEnv, "")
+	attempts = 2
+	if err := Post(context.Background(), server.URL, "x"); err != nil || signature != "" {
+		t.Fatalf("expected an unsigned delivery without a secret, got %q (%v)", signature, err)
+	}
+}
+
+func TestPostGivesUp(t *testing.T) {
+	defer func(wait time.Duration) { retryWait = wait }(retryWait)
+	retryWait = time.Millisecond
+
+	var attempts int
+	status := http.StatusBadGateway
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		http.Error(w, "nope", status)
+	}))
+	defer server.Close()
+
+	err := Post(context.Background(), server.URL, "x")
+	if err == nil || !strings.Contains(err.Error(), "502") || attempts != maxRetries+1 {
+		t.Fatalf("expected the last 502 after %d attempts, got %d: %v", maxRetries+1, attempts, err)
+	}
+
+	attempts = 0
+	status = http.StatusBadRequest
+	if err := Post(context.Background(), server.URL, "x"); err == nil || attempts != 1 {
+		t.Fatalf("expected a 400 to fail without retrying, got %d: %v", attempts, err)
+	}
+}